@@ -10,6 +10,7 @@ import (
 	"github.com/APTrust/registry/constants"
 	"github.com/APTrust/registry/helpers"
 	"github.com/APTrust/registry/middleware"
+	"github.com/APTrust/registry/scheduler"
 	admin_api "github.com/APTrust/registry/web/api/admin"
 	common_api "github.com/APTrust/registry/web/api/common"
 	"github.com/APTrust/registry/web/webui"
@@ -21,7 +22,7 @@ import (
 // the app.
 func Run() {
 	r := InitAppEngine(false)
-	initCronJobs(common.Context())
+	scheduler.Default().Start()
 	r.Run()
 }
 
@@ -44,6 +45,7 @@ func InitAppEngine(discardStdOut bool) *gin.Engine {
 	initTemplates(r)
 	initMiddleware(r)
 	initRoutes(r)
+	registerJobs(scheduler.Default())
 	return r
 }
 
@@ -61,6 +63,7 @@ func initTemplates(router *gin.Engine) {
 		"dict":           helpers.Dict,
 		"escapeAttr":     helpers.EscapeAttr,
 		"escapeHTML":     helpers.EscapeHTML,
+		"exportUrl":      helpers.ExportUrl,
 		"formatFloat":    helpers.FormatFloat,
 		"formatInt":      helpers.FormatInt,
 		"formatInt64":    helpers.FormatInt64,
@@ -152,6 +155,9 @@ func initRoutes(router *gin.Engine) {
 		webRoutes.POST("/alerts/mark_all_as_read", webui.AlertMarkAllAsRead)
 		webRoutes.PUT("/alerts/mark_as_unread", webui.AlertMarkAsUnreadXHR)
 
+		// Audit Events
+		webRoutes.GET("/audit_events", webui.AuditEventIndex)
+
 		// Deletion Requests
 		// Note that these routes are for read-only views.
 		// Routes for initiating, approving and rejecting deletions
@@ -168,6 +174,35 @@ func initRoutes(router *gin.Engine) {
 		// Reports
 		webRoutes.GET("/reports/deposits", webui.DepositReportShow)
 		webRoutes.GET("/reports/billing", webui.BillingReportShow)
+		webRoutes.GET("/reports/fixity", webui.FixityReportShow)
+		webRoutes.GET("/reports/fixity_overdue", webui.FixityOverdueReportShow)
+		webRoutes.GET("/reports/replication", webui.ReplicationReportShow)
+		webRoutes.GET("/reports/format_risk", webui.FormatRiskReportShow)
+		webRoutes.GET("/reports/format_risk_objects", webui.FormatRiskObjectsReportShow)
+
+		// Format Registry
+		webRoutes.GET("/format_risks", webui.FormatRiskIndex)
+		webRoutes.GET("/format_risks/new", webui.FormatRiskNew)
+		webRoutes.POST("/format_risks/new", webui.FormatRiskCreate)
+		webRoutes.GET("/format_risks/edit/:id", webui.FormatRiskEdit)
+		webRoutes.PUT("/format_risks/edit/:id", webui.FormatRiskUpdate)
+		webRoutes.POST("/format_risks/edit/:id", webui.FormatRiskUpdate)
+		webRoutes.DELETE("/format_risks/delete/:id", webui.FormatRiskDelete)
+		webRoutes.POST("/format_risks/delete/:id", webui.FormatRiskDelete)
+
+		// Search
+		webRoutes.GET("/search", webui.SearchIndex)
+
+		// Saved Searches
+		webRoutes.GET("/saved_searches", webui.SavedSearchIndex)
+		webRoutes.GET("/saved_searches/new", webui.SavedSearchNew)
+		webRoutes.POST("/saved_searches/new", webui.SavedSearchCreate)
+		webRoutes.GET("/saved_searches/show/:id", webui.SavedSearchShow)
+		webRoutes.GET("/saved_searches/edit/:id", webui.SavedSearchEdit)
+		webRoutes.PUT("/saved_searches/edit/:id", webui.SavedSearchUpdate)
+		webRoutes.POST("/saved_searches/edit/:id", webui.SavedSearchUpdate)
+		webRoutes.DELETE("/saved_searches/delete/:id", webui.SavedSearchDelete)
+		webRoutes.POST("/saved_searches/delete/:id", webui.SavedSearchDelete)
 
 		// GenericFiles
 		webRoutes.GET("/files", webui.GenericFileIndex)
@@ -176,6 +211,12 @@ func initRoutes(router *gin.Engine) {
 		webRoutes.GET("/files/request_restore/:id", webui.GenericFileRequestRestore)
 		webRoutes.POST("/files/init_delete/:id", webui.GenericFileInitDelete)
 		webRoutes.POST("/files/init_restore/:id", webui.GenericFileInitRestore)
+		webRoutes.GET("/files/request_fixity/:id", webui.GenericFileRequestFixity)
+		webRoutes.POST("/files/init_fixity/:id", webui.GenericFileInitFixity)
+		webRoutes.GET("/files/request_bulk_fixity", webui.GenericFileRequestBulkFixity)
+		webRoutes.POST("/files/init_bulk_fixity", webui.GenericFileInitBulkFixity)
+		webRoutes.GET("/files/request_bulk_delete", webui.GenericFileRequestBulkDelete)
+		webRoutes.POST("/files/init_bulk_delete", webui.GenericFileInitBulkDelete)
 
 		// Institutions
 		webRoutes.POST("/institutions/new", webui.InstitutionCreate)
@@ -191,25 +232,66 @@ func initRoutes(router *gin.Engine) {
 		webRoutes.POST("/institutions/edit/:id", webui.InstitutionUpdate)
 		webRoutes.PUT("/institutions/edit_preferences/:id", webui.InstitutionUpdatePrefs)
 		webRoutes.POST("/institutions/edit_preferences/:id", webui.InstitutionUpdatePrefs)
+		webRoutes.GET("/institutions/sso/:id", webui.InstitutionSSOEdit)
+		webRoutes.POST("/institutions/sso/:id", webui.InstitutionSSOUpdate)
 
 		// IntellectualObjects
 		webRoutes.GET("/objects", webui.IntellectualObjectIndex)
 		webRoutes.GET("/objects/show/:id", webui.IntellectualObjectShow)
 		webRoutes.GET("/objects/request_delete/:id", webui.IntellectualObjectRequestDelete)
 		webRoutes.POST("/objects/init_delete/:id", webui.IntellectualObjectInitDelete)
+		webRoutes.GET("/objects/request_bulk_delete", webui.IntellectualObjectRequestBulkDelete)
+		webRoutes.POST("/objects/init_bulk_delete", webui.IntellectualObjectInitBulkDelete)
+		webRoutes.GET("/objects/request_bulk_restore", webui.IntellectualObjectRequestBulkRestore)
+		webRoutes.POST("/objects/init_bulk_restore", webui.IntellectualObjectInitBulkRestore)
 		webRoutes.GET("/objects/request_restore/:id", webui.IntellectualObjectRequestRestore)
 		webRoutes.POST("/objects/init_restore/:id", webui.IntellectualObjectInitRestore)
+		webRoutes.GET("/objects/request_fixity/:id", webui.IntellectualObjectRequestFixity)
+		webRoutes.POST("/objects/init_fixity/:id", webui.IntellectualObjectInitFixity)
 		webRoutes.GET("/objects/events/:id", webui.IntellectualObjectEvents)
 		webRoutes.GET("/objects/files/:id", webui.IntellectualObjectFiles)
+		webRoutes.GET("/objects/versions/:id", webui.IntellectualObjectVersions)
+
+		// Restoration Batches
+		webRoutes.GET("/restoration_batches", webui.RestorationBatchIndex)
+		webRoutes.GET("/restoration_batches/show/:id", webui.RestorationBatchShow)
 
 		// InternalMetadata
 		webRoutes.GET("/internal_metadata", webui.InternalMetadataIndex)
 
+		// Invoices
+		webRoutes.GET("/invoices", webui.InvoiceIndex)
+		webRoutes.GET("/invoices/show/:id", webui.InvoiceShow)
+		webRoutes.GET("/invoices/download/:id", webui.InvoiceDownload)
+
+		// Scheduled Jobs
+		webRoutes.GET("/jobs", webui.JobIndex)
+		webRoutes.POST("/jobs/run/:name", webui.JobRunNow)
+
 		// PremisEvents
 		webRoutes.GET("/events", webui.PremisEventIndex)
 		webRoutes.GET("/events/show/:id", webui.PremisEventShow)
 		webRoutes.GET("/events/show_xhr/:id", webui.PremisEventShowXHR)
 
+		// Storage Prices
+		webRoutes.GET("/storage_prices", webui.StoragePriceIndex)
+		webRoutes.GET("/storage_prices/new", webui.StoragePriceNew)
+		webRoutes.POST("/storage_prices/new", webui.StoragePriceCreate)
+		webRoutes.DELETE("/storage_prices/delete/:id", webui.StoragePriceDelete)
+		webRoutes.POST("/storage_prices/delete/:id", webui.StoragePriceDelete)
+
+		// Webhooks
+		webRoutes.GET("/webhooks/new", webui.WebhookNew)
+		webRoutes.POST("/webhooks/new", webui.WebhookCreate)
+		webRoutes.GET("/webhooks/show/:id", webui.WebhookShow)
+		webRoutes.GET("/webhooks/edit/:id", webui.WebhookEdit)
+		webRoutes.PUT("/webhooks/edit/:id", webui.WebhookUpdate)
+		webRoutes.POST("/webhooks/edit/:id", webui.WebhookUpdate)
+		webRoutes.DELETE("/webhooks/delete/:id", webui.WebhookDelete)
+		webRoutes.POST("/webhooks/delete/:id", webui.WebhookDelete)
+		webRoutes.GET("/webhook_deliveries/show/:id", webui.WebhookDeliveryShow)
+		webRoutes.POST("/webhook_deliveries/replay/:id", webui.WebhookDeliveryReplay)
+
 		// WorkItems - Web UI allows only list, show, and limited editing for admin only
 		webRoutes.GET("/work_items", webui.WorkItemIndex)
 		webRoutes.GET("/work_items/show/:id", webui.WorkItemShow)
@@ -228,6 +310,8 @@ func initRoutes(router *gin.Engine) {
 		webRoutes.POST("/users/delete/:id", webui.UserDelete)
 		webRoutes.POST("/users/undelete/:id", webui.UserUndelete)
 		webRoutes.PUT("/users/undelete/:id", webui.UserUndelete)
+		webRoutes.POST("/users/unlock/:id", webui.UserUnlock)
+		webRoutes.POST("/users/sign_out_everywhere/:id", webui.UserSignOutEverywhere)
 		webRoutes.GET("/users", webui.UserIndex)
 		webRoutes.GET("/users/new", webui.UserNew)
 		webRoutes.GET("/users/show/:id", webui.UserShow)
@@ -236,6 +320,7 @@ func initRoutes(router *gin.Engine) {
 		webRoutes.PUT("/users/edit_xhr/:id", webui.UserUpdateXHR)
 		webRoutes.POST("/users/edit/:id", webui.UserUpdate)
 		webRoutes.GET("/users/my_account", webui.UserMyAccount)
+		webRoutes.POST("/users/alert_preferences", webui.UserUpdateAlertPreferences)
 		webRoutes.GET("/users/change_password/:id", webui.UserShowChangePassword)
 		webRoutes.POST("/users/change_password/:id", webui.UserChangePassword)
 		webRoutes.GET("/users/init_password_reset/:id", webui.UserInitPasswordReset)
@@ -243,16 +328,35 @@ func initRoutes(router *gin.Engine) {
 		webRoutes.POST("/users/complete_password_reset/:id", webui.UserCompletePasswordReset)
 		webRoutes.POST("/users/get_api_key/:id", webui.UserGetAPIKey)
 
+		// API Keys
+		webRoutes.GET("/api_keys/new", webui.APIKeyNew)
+		webRoutes.POST("/api_keys/new", webui.APIKeyCreate)
+		webRoutes.POST("/api_keys/revoke/:id", webui.APIKeyRevoke)
+
+		// User Sessions
+		webRoutes.POST("/user_sessions/revoke/:id", webui.UserSessionRevoke)
+
 		// User two-factor setup
 		webRoutes.GET("/users/2fa_setup", webui.UserInit2FASetup)
 		webRoutes.POST("/users/2fa_setup", webui.UserComplete2FASetup)
 		webRoutes.POST("/users/confirm_phone", webui.UserConfirmPhone)
+		webRoutes.GET("/users/2fa_totp_qr", webui.UserTOTPQRCode)
+		webRoutes.POST("/users/confirm_totp", webui.UserConfirmTOTP)
+		webRoutes.GET("/users/webauthn_register", webui.UserWebAuthnRegisterShow)
+		webRoutes.POST("/users/webauthn_register/begin", webui.UserWebAuthnRegisterBegin)
+		webRoutes.POST("/users/webauthn_register/finish", webui.UserWebAuthnRegisterFinish)
+		webRoutes.POST("/webauthn_credentials/rename/:id", webui.WebAuthnCredentialRename)
+		webRoutes.POST("/webauthn_credentials/revoke/:id", webui.WebAuthnCredentialRevoke)
 		webRoutes.POST("/users/backup_codes", webui.UserGenerateBackupCodes)
 
 		// User two-factor login
 		webRoutes.GET("/users/2fa_backup", webui.UserTwoFactorBackup)
 		webRoutes.GET("/users/2fa_choose", webui.UserTwoFactorChoose)
 		webRoutes.POST("/users/2fa_sms", webui.UserTwoFactorGenerateSMS)
+		webRoutes.GET("/users/2fa_totp", webui.UserTwoFactorTOTP)
+		webRoutes.GET("/users/2fa_webauthn", webui.UserTwoFactorWebAuthn)
+		webRoutes.POST("/users/2fa_webauthn/begin", webui.UserTwoFactorWebAuthnBegin)
+		webRoutes.POST("/users/2fa_webauthn/finish", webui.UserTwoFactorWebAuthnFinish)
 		webRoutes.POST("/users/2fa_push", webui.UserTwoFactorPush)
 		webRoutes.POST("/users/2fa_verify", webui.UserTwoFactorVerify)
 
@@ -265,6 +369,13 @@ func initRoutes(router *gin.Engine) {
 		webRoutes.POST("/users/sign_in", webui.UserSignIn)
 		webRoutes.GET("/users/sign_out", webui.UserSignOut) // should be delete?
 
+		// User single sign-on
+		webRoutes.POST("/users/sso", webui.UserSSOStart)
+		webRoutes.POST("/users/sso/complete", webui.UserSSOComplete)
+		webRoutes.GET("/users/sso/oidc/callback", webui.UserSSOOIDCCallback)
+		webRoutes.GET("/users/sso/saml/metadata", webui.UserSSOSAMLMetadata)
+		webRoutes.POST("/users/sso/saml/acs", webui.UserSSOSAMLACS)
+
 		// NSQ
 		webRoutes.GET("/nsq", webui.NsqShow)
 		webRoutes.POST("/nsq/init", webui.NsqInit)
@@ -332,6 +443,9 @@ func initRoutes(router *gin.Engine) {
 		memberAPI.GET("/events/show/*id", common_api.PremisEventShow)
 		memberAPI.GET("/events", common_api.PremisEventIndex)
 
+		// Search
+		memberAPI.GET("/search", common_api.SearchIndex)
+
 		// Work Items
 		memberAPI.GET("/items/show/:id", common_api.WorkItemShow)
 		memberAPI.GET("/items", common_api.WorkItemIndex)
@@ -350,6 +464,10 @@ func initRoutes(router *gin.Engine) {
 		adminAPI.GET("/alerts", common_api.AlertIndex)
 		adminAPI.GET("/alerts/show/:id/:user_id", common_api.AlertShow)
 
+		// Audit Events
+		adminAPI.GET("/audit_events", admin_api.AuditEventIndex)
+		adminAPI.GET("/audit_events/show/:id", admin_api.AuditEventShow)
+
 		// Checksums
 		adminAPI.GET("/checksums", common_api.ChecksumIndex)
 		adminAPI.GET("/checksums/show/:id", common_api.ChecksumShow)
@@ -367,6 +485,7 @@ func initRoutes(router *gin.Engine) {
 		adminAPI.POST("/files/create/:institution_id", admin_api.GenericFileCreate)
 		adminAPI.POST("/files/create_batch/:institution_id", admin_api.GenericFileCreateBatch)
 		adminAPI.PUT("/files/update/:id", admin_api.GenericFileUpdate)
+		adminAPI.POST("/files/init_fixity", admin_api.GenericFileInitFixity)
 
 		// Institutions
 		adminAPI.GET("/institutions", admin_api.InstitutionIndex)
@@ -379,12 +498,16 @@ func initRoutes(router *gin.Engine) {
 		adminAPI.PUT("/objects/update/:id", admin_api.IntellectualObjectUpdate)
 		adminAPI.DELETE("/objects/delete/:id", admin_api.IntellectualObjectDelete)
 		adminAPI.POST("/objects/init_restore/:id", admin_api.IntellectualObjectInitRestore)
+		adminAPI.POST("/objects/init_fixity/:id", admin_api.IntellectualObjectInitFixity)
 
 		// Premis Events
 		adminAPI.POST("/events/create", admin_api.PremisEventCreate)
 		adminAPI.GET("/events/show/*id", common_api.PremisEventShow)
 		adminAPI.GET("/events", common_api.PremisEventIndex)
 
+		// Search
+		adminAPI.GET("/search", common_api.SearchIndex)
+
 		// Storage Records
 		adminAPI.POST("/storage_records/create/:institution_id", admin_api.StorageRecordCreate)
 		adminAPI.GET("/storage_records/show/:id", admin_api.StorageRecordShow)
@@ -1,31 +1,143 @@
 package app
 
 import (
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/APTrust/registry/common"
 	"github.com/APTrust/registry/constants"
 	"github.com/APTrust/registry/pgmodels"
+	"github.com/APTrust/registry/scheduler"
 )
 
-var cronJobsInitialized = false
+var registerJobsOnce sync.Once
 
-// initCronJobs initializes
-func initCronJobs(ctx *common.APTContext) {
-	if !cronJobsInitialized {
-		ctx.Log.Info().Msg("Initializing cron jobs")
-		updateSlowCounts(ctx)
-		updateCurrentDepositStats(ctx)
-		updateHistoricalDepositStats(ctx)
-		populateEmptyDepositStats(ctx)
-		initRestorationSpotTests(ctx)
-		cronJobsInitialized = true
-	}
+// registerJobs registers Registry's periodic jobs with the scheduler.
+// We do this when building the app engine, even in tests, so that the
+// admin jobs page can list jobs and run them on demand. The jobs don't
+// start running on their schedules until Run() calls Start().
+//
+// Times in these schedules are UTC. Minutes are staggered so the
+// expensive jobs don't all hit the DB at once.
+func registerJobs(s *scheduler.Scheduler) {
+	registerJobsOnce.Do(func() {
+		jobs := []*scheduler.Job{
+			{
+				Name:        "update_counts",
+				Description: "Refreshes the materialized views that hold counts for our largest tables.",
+				Schedule:    "0 * * * *",
+				CatchUp:     true,
+				Run:         updateSlowCounts,
+			},
+			{
+				Name:        "update_current_deposit_stats",
+				Description: "Updates current deposit stats for the dashboard and reports.",
+				Schedule:    "12 * * * *",
+				CatchUp:     true,
+				Run:         updateCurrentDepositStats,
+			},
+			{
+				Name:        "update_format_risk_stats",
+				Description: "Updates the format risk report's summary of each object's files by risk level.",
+				Schedule:    "18 * * * *",
+				CatchUp:     true,
+				Run:         updateFormatRiskStats,
+			},
+			{
+				Name:        "populate_all_historical_deposit_stats",
+				Description: "Adds last month's deposit stats to the historical deposit stats table.",
+				Schedule:    "30 0 1 * *",
+				CatchUp:     true,
+				Run:         updateHistoricalDepositStats,
+			},
+			{
+				Name:        "populate_empty_deposit_stats",
+				Description: "Fills in empty timeline stats for months in which depositors had no data.",
+				Schedule:    "45 1 1 * *",
+				CatchUp:     true,
+				Run:         populateEmptyDepositStats,
+			},
+			{
+				Name:        "apply_storage_prices",
+				Description: "Updates storage options to show prices that take effect today.",
+				Schedule:    "5 0 * * *",
+				CatchUp:     true,
+				Run:         applyStoragePrices,
+			},
+			{
+				Name:        "generate_invoices",
+				Description: "Creates last month's storage invoices for member institutions.",
+				Schedule:    "0 8 2 * *",
+				CatchUp:     true,
+				Run:         generateInvoices,
+			},
+			{
+				Name:        "restoration_spot_tests",
+				Description: "Queues restoration spot tests for institutions that are due for one.",
+				Schedule:    "0 6 * * *",
+				CatchUp:     true,
+				Run:         runRestorationSpotTest,
+			},
+			{
+				Name:        "stalled_work_item_alerts",
+				Description: "Alerts APTrust admins about work items that have stalled.",
+				Schedule:    "24 * * * *",
+				Run:         alertOnStalledWorkItems,
+			},
+			{
+				Name:        "daily_alert_digests",
+				Description: "Emails daily digests to users who chose to receive some alerts that way.",
+				Schedule:    "0 11 * * *",
+				CatchUp:     true,
+				Run:         sendDailyAlertDigests,
+			},
+			{
+				Name:        "weekly_alert_digests",
+				Description: "Emails weekly digests to users who chose to receive some alerts that way.",
+				Schedule:    "15 11 * * 1",
+				CatchUp:     true,
+				Run:         sendWeeklyAlertDigests,
+			},
+			{
+				Name:        "saved_search_notifications",
+				Description: "Emails users whose saved searches now match a different number of records.",
+				Schedule:    "30 11 * * *",
+				CatchUp:     true,
+				Run:         sendSavedSearchNotifications,
+			},
+			{
+				Name:        "verify_storage_replication",
+				Description: "Checks that each active file's storage records match its storage option.",
+				Schedule:    "40 3 * * *",
+				CatchUp:     true,
+				Run:         verifyStorageReplication,
+			},
+			{
+				Name:        "record_object_versions",
+				Description: "Records a version of each object that was successfully ingested since the last run.",
+				Schedule:    "*/5 * * * *",
+				Run:         recordObjectVersions,
+			},
+			{
+				Name:        "webhook_deliveries",
+				Description: "Sends pending and retried webhook notifications to institutions.",
+				Schedule:    "* * * * *",
+				Run:         deliverWebhooks,
+			},
+		}
+		for _, job := range jobs {
+			err := s.Register(job)
+			if err != nil {
+				common.Context().Log.Error().Msgf("Error registering job %s: %v", job.Name, err)
+			}
+		}
+	})
 }
 
-// updateSlowCounts runs hourly, calling our custom postgres function
-// update_counts(), which refreshes materialized views that hold count
-// data for our largest tables.
+// updateSlowCounts calls our custom postgres function update_counts(),
+// which refreshes materialized views that hold count data for our
+// largest tables.
 //
 // Count queries on the big tables (IntellectualObjects, GenericFiles,
 // WorkItems, and PremisEvents) are some of our most frequently run queries,
@@ -34,63 +146,33 @@ func initCronJobs(ctx *common.APTContext) {
 //
 // To combat this, we refresh the materialized views premis_event_counts,
 // intellectual_object_counts, generic_file_counts and work_item_counts
-// every hour in an async go routine that will not block requests.
+// every hour in a scheduled job that will not block requests.
 //
 // In all our use cases, hour-old counts are tolerable. For more on these
 // views, see db/migrations/001_deposit_stats.sql.
 //
-// If we have multiple instance of Registry running in multiple containers,
-// the DB ensures that this function runs no more than once per hour.
-//
 // Note that the SQL function also contains a guard against multiple
 // instances of Registry running the stats update at the same time.
-func updateSlowCounts(ctx *common.APTContext) {
-	if !cronJobsInitialized {
-		go func() {
-			for {
-				ctx.Log.Info().Msg("cron: starting update_counts() to refresh views")
-				start := time.Now().UTC()
-				_, err := ctx.DB.Exec("select update_counts()")
-				end := time.Now().UTC()
-				duration := end.Sub(start).Seconds()
-				if err != nil {
-					ctx.Log.Error().Msgf("cron: update_counts failed after %f seconds: %s", duration, err.Error())
-				} else {
-					ctx.Log.Info().Msgf("cron: update_counts completed after %f seconds.  (Less than one second indicates counts did not need to be updated.)", duration)
-				}
-				time.Sleep(1 * time.Hour)
-			}
-		}()
-	}
+func updateSlowCounts(ctx *common.APTContext) error {
+	_, err := ctx.DB.Exec("select update_counts()")
+	return err
 }
 
 // updateCurrentDepositStats updates info about the quantity of depositor
 // data in the system. This data appears on the dashboard after login,
 // and in the "Reports" section. These queries take way too long to run,
-// so we run them asynchronously once every hour.
-//
-// If we have multiple instance of Registry running in multiple containers,
-// the DB ensures that this function runs no more than once per hour.
-func updateCurrentDepositStats(ctx *common.APTContext) {
-	if !cronJobsInitialized {
-		go func() {
-			// Stagger this, so it doesn't overlap with update slow counts
-			time.Sleep(12 * time.Minute)
-			for {
-				ctx.Log.Info().Msg("cron: starting update_current_deposit_stats()")
-				start := time.Now().UTC()
-				_, err := ctx.DB.Exec("select update_current_deposit_stats()")
-				end := time.Now().UTC()
-				duration := end.Sub(start).Seconds()
-				if err != nil {
-					ctx.Log.Error().Msgf("cron: update_current_deposit_stats failed after %f seconds: %s", duration, err.Error())
-				} else {
-					ctx.Log.Info().Msgf("cron: update_current_deposit_stats completed after %f seconds. (Less than one second indicates stats did not need to be updated.)", duration)
-				}
-				time.Sleep(1 * time.Hour)
-			}
-		}()
-	}
+// so we run them in a scheduled job once every hour.
+func updateCurrentDepositStats(ctx *common.APTContext) error {
+	_, err := ctx.DB.Exec("select update_current_deposit_stats()")
+	return err
+}
+
+// updateFormatRiskStats refreshes the summary of each object's active
+// files by format risk level, which the format risk report reads.
+// Like the current deposit stats, this is too slow to compute on each
+// request, and hour-old numbers are fine for this report.
+func updateFormatRiskStats(ctx *common.APTContext) error {
+	return pgmodels.RefreshFormatRiskStats()
 }
 
 // updateHistoricalDepositStats ensure that the historical_deposit_stats
@@ -105,68 +187,142 @@ func updateCurrentDepositStats(ctx *common.APTContext) {
 // table and does not try to fill in data that already exists. It just adds stats
 // for the prior month.
 //
-// Note that this job runs every 24 hours and does nothing at all unless it's
-// the first of the month.
-func updateHistoricalDepositStats(ctx *common.APTContext) {
-	if !cronJobsInitialized {
-		go func() {
-			for {
-				// We usually use UTC dates in Registry, but here, we'll check whether
-				// it's the first of the month in the local timezone.
-				if time.Now().Day() == 1 {
-					ctx.Log.Info().Msg("cron: starting populate_all_historical_deposit_stats() because it's the first of the month")
-					start := time.Now().UTC()
-					_, err := ctx.DB.Exec("select populate_all_historical_deposit_stats()")
-					end := time.Now().UTC()
-					duration := end.Sub(start).Seconds()
-					if err != nil {
-						ctx.Log.Error().Msgf("cron: populate_all_historical_deposit_stats failed after %f seconds: %s", duration, err.Error())
-					} else {
-						ctx.Log.Info().Msgf("cron: populate_all_historical_deposit_stats completed after %f seconds. (Less than one second indicates stats did not need to be updated.)", duration)
-					}
-				} else {
-					ctx.Log.Info().Msg("cron: no need to run populate_all_historical_deposit_stats() because it's not the first of the month")
-				}
-				time.Sleep(24 * time.Hour)
-			}
-		}()
-	}
+// This job is scheduled for the first of the month. If no Registry instance
+// runs it then, the scheduler's catch-up run picks it up the next time
+// Registry starts, so we don't silently skip a month.
+func updateHistoricalDepositStats(ctx *common.APTContext) error {
+	_, err := ctx.DB.Exec("select populate_all_historical_deposit_stats()")
+	return err
 }
 
 // This fills in stats for timeline reports where depositors had no
-// data in the system in a given month. We only need to run this once.
-// Afterwards, it will be called on the first of each month when we
-// populate historical deposit stats.
-func populateEmptyDepositStats(ctx *common.APTContext) {
-	if !cronJobsInitialized {
-		ctx.Log.Info().Msg("cron: starting populate_empty_deposit_stats()")
-		start := time.Now().UTC()
-		_, err := ctx.DB.Exec("select populate_empty_deposit_stats()")
-		end := time.Now().UTC()
-		duration := end.Sub(start).Seconds()
-		if err != nil {
-			ctx.Log.Error().Msgf("cron: populate_empty_deposit_stats failed after %f seconds: %s", duration, err.Error())
-		} else {
-			ctx.Log.Info().Msgf("cron: populate_empty_deposit_stats completed after %f seconds. (Less than one second indicates stats did not need to be updated.)", duration)
-		}
+// data in the system in a given month. populate_all_historical_deposit_stats
+// also calls this, so this job is mostly a safety net. It catches up
+// on startup if it has never run.
+func populateEmptyDepositStats(ctx *common.APTContext) error {
+	_, err := ctx.DB.Exec("select populate_empty_deposit_stats()")
+	return err
+}
+
+// applyStoragePrices copies each storage option's current price from
+// the storage price history into the storage option. Scheduled prices
+// take effect at midnight UTC on the first of the month, so this runs
+// just after midnight. Reports and invoices look up prices by date and
+// don't depend on this job.
+func applyStoragePrices(ctx *common.APTContext) error {
+	count, err := pgmodels.ApplyStoragePrices()
+	if err == nil && count > 0 {
+		ctx.Log.Info().Msgf("scheduler: applied new prices to %d storage options", count)
 	}
+	return err
 }
 
-func initRestorationSpotTests(ctx *common.APTContext) {
-	if !cronJobsInitialized {
-		ctx.Log.Info().Msg("cron: initializing restoration spot tests. These will run every 24 hours.")
-		go func() {
-			for {
-				// Run restoration spot tests once a day.
-				// The function will run only if needed.
-				runRestorationSpotTest(ctx)
-				time.Sleep(24 * time.Hour)
-			}
-		}()
+// generateInvoices creates last month's invoices for all active member
+// institutions. It runs on the second of the month, after
+// populate_all_historical_deposit_stats has recorded last month's
+// stats. Institutions that already have an invoice for the month
+// keep it, so catch-up and manual runs are safe.
+func generateInvoices(ctx *common.APTContext) error {
+	lastMonth := pgmodels.BillingMonthOf(time.Now().UTC()).AddDate(0, -1, 0)
+	count, err := pgmodels.GenerateInvoices(lastMonth)
+	if err == nil {
+		ctx.Log.Info().Msgf("scheduler: created %d invoices for %s", count, lastMonth.Format("January 2006"))
+	}
+	return err
+}
+
+// alertOnStalledWorkItems looks for WorkItems that have been pending or
+// started in a single stage for too long. See pgmodels.StalledItemThresholds
+// for the per-stage limits.
+//
+// Items that stall generate one Stalled Work Items alert per institution,
+// which goes to APTrust admins. Items that have already been included
+// in a stalled items alert will not be included in subsequent alerts,
+// unless they move to a new stage and stall again.
+func alertOnStalledWorkItems(ctx *common.APTContext) error {
+	alerts, err := pgmodels.AlertOnStalledWorkItems()
+	if err == nil {
+		ctx.Log.Info().Msgf("scheduler: created %d stalled work item alerts", len(alerts))
+	}
+	return err
+}
+
+// sendDailyAlertDigests emails a digest of unsent alerts to each user
+// who has chosen to receive some types of alerts daily. The digest
+// includes all unsent alerts of those types, so if a run is missed,
+// the next one catches up. 11:00 UTC is early morning in the US.
+func sendDailyAlertDigests(ctx *common.APTContext) error {
+	return sendAlertDigests(ctx, constants.AlertDeliveryDaily)
+}
+
+// sendWeeklyAlertDigests is like sendDailyAlertDigests, but for users
+// who want a weekly digest. It runs on Monday mornings.
+func sendWeeklyAlertDigests(ctx *common.APTContext) error {
+	return sendAlertDigests(ctx, constants.AlertDeliveryWeekly)
+}
+
+func sendAlertDigests(ctx *common.APTContext, frequency string) error {
+	count, err := pgmodels.SendAlertDigests(frequency)
+	if err == nil {
+		ctx.Log.Info().Msgf("scheduler: sent %d %s alert digests", count, frequency)
 	}
+	return err
 }
 
-func runRestorationSpotTest(ctx *common.APTContext) {
+// sendSavedSearchNotifications counts the results of saved searches
+// whose owners asked to hear about changes, and emails the owners of
+// searches whose counts changed since yesterday. It runs after the
+// daily alert digests.
+func sendSavedSearchNotifications(ctx *common.APTContext) error {
+	count, err := pgmodels.SendSavedSearchNotifications()
+	if err == nil {
+		ctx.Log.Info().Msgf("scheduler: sent %d saved search notifications", count)
+	}
+	return err
+}
+
+// recordObjectVersions records versions of objects whose ingests have
+// succeeded since the last run. Recording a version reads all of the
+// object's files and checksums, so we do it here rather than when
+// preservation services marks the ingest successful. Each run picks up
+// where the last one left off, so this doesn't need to catch up.
+func recordObjectVersions(ctx *common.APTContext) error {
+	count, err := pgmodels.RecordPendingObjectVersions(500)
+	if err == nil && count > 0 {
+		ctx.Log.Info().Msgf("scheduler: recorded %d object versions", count)
+	}
+	return err
+}
+
+// deliverWebhooks sends webhook deliveries that are due, including
+// retries of earlier failed attempts. Each run sends at most 100
+// deliveries. Anything left over goes out on the next run. If slow
+// endpoints make a run take longer than a minute, the scheduler's
+// advisory lock keeps the next run from overlapping it.
+func deliverWebhooks(ctx *common.APTContext) error {
+	count, err := pgmodels.DeliverPendingWebhooks(pgmodels.WebhookClient, 100)
+	if err == nil && count > 0 {
+		ctx.Log.Info().Msgf("scheduler: attempted %d webhook deliveries", count)
+	}
+	return err
+}
+
+// verifyStorageReplication compares every active file's storage records
+// with the buckets configured for its storage option and rebuilds the
+// replication report. It reads every storage record, so it runs in the
+// middle of the night, US time.
+func verifyStorageReplication(ctx *common.APTContext) error {
+	count, err := pgmodels.VerifyStorageReplication()
+	if err == nil {
+		ctx.Log.Info().Msgf("scheduler: storage replication check found %d problems", count)
+	}
+	return err
+}
+
+// runRestorationSpotTest queues a restoration spot test for each
+// institution that is due for one. The scheduler runs this once a day
+// and ensures that only one Registry instance runs it at a time.
+func runRestorationSpotTest(ctx *common.APTContext) error {
 	// for each inst:
 	// if inst needs spot test
 	// find appropriate object
@@ -175,28 +331,15 @@ func runRestorationSpotTest(ctx *common.APTContext) {
 	//
 	// later, after restoration is complete, send restoration completed alert
 
-	if !shouldRunSpotTest(ctx) {
-		return
-	}
-
-	err := spotTestLock(ctx)
-	if err != nil {
-		ctx.Log.Error().Msgf("runRestorationSpotTest: error setting spot test lock: %v", err)
-		return
-	}
-	defer spotTestUnlock(ctx)
-
 	systemUser, err := pgmodels.UserByEmail(constants.SystemUser)
 	if err != nil {
-		ctx.Log.Error().Msgf("runRestorationSpotTest: error getting system user: %v", err)
-		return
+		return fmt.Errorf("error getting system user: %v", err)
 	}
 
 	query := pgmodels.NewQuery().Limit(100).Offset(0)
 	institutions, err := pgmodels.InstitutionSelect(query)
 	if err != nil {
-		ctx.Log.Error().Msgf("runRestorationSpotTest: error getting institutions list for restoration spot test: %v", err)
-		return
+		return fmt.Errorf("error getting institutions list for restoration spot test: %v", err)
 	}
 	for _, inst := range institutions {
 		isDue, err := inst.DueForSpotRestore()
@@ -208,6 +351,7 @@ func runRestorationSpotTest(ctx *common.APTContext) {
 			scheduleSpotRestoration(ctx, inst, systemUser)
 		}
 	}
+	return nil
 }
 
 func scheduleSpotRestoration(ctx *common.APTContext, inst *pgmodels.Institution, systemUser *pgmodels.User) error {
@@ -249,65 +393,3 @@ func scheduleSpotRestoration(ctx *common.APTContext, inst *pgmodels.Institution,
 	}
 	return err
 }
-
-func shouldRunSpotTest(ctx *common.APTContext) bool {
-	metadata, err := pgmodels.InternalMetadataByKey(constants.MetaSpotTestsRunning)
-	if err != nil {
-		ctx.Log.Error().Msgf("shouldRunSpotTest: error getting metadata '%s': %v", constants.MetaSpotTestsRunning, err)
-		return false
-	}
-	if metadata.Value == "true" {
-		ctx.Log.Info().Msgf("shouldRunSpotTest: no, because spot test is currently running from another process")
-		return false
-	}
-
-	metadata, err = pgmodels.InternalMetadataByKey(constants.MetaSpotTestsLastRun)
-	if err != nil {
-		ctx.Log.Error().Msgf("shouldRunSpotTest: error getting metadata '%s': %v", constants.MetaSpotTestsLastRun, err)
-		return false
-	}
-	lastRunDate, err := time.Parse(time.RFC3339, metadata.Value)
-	if err != nil {
-		ctx.Log.Error().Msgf("shouldRunSpotTest: error parsing last run date '%s': %v", metadata.Value, err)
-		return false
-	}
-	if lastRunDate.After(time.Now().AddDate(0, 0, -1)) {
-		ctx.Log.Info().Msgf("shouldRunSpotTest: no, because spot test has run within the past 24 hours at %s", metadata.Value)
-		return false
-	}
-	ctx.Log.Info().Msgf("shouldRunSpotTest: yes, because it's not currently running and last run was at %s", metadata.Value)
-	return true
-}
-
-func spotTestLock(ctx *common.APTContext) error {
-	metadata, err := pgmodels.InternalMetadataByKey(constants.MetaSpotTestsRunning)
-	if err != nil {
-		return err
-	}
-	metadata.Value = "true"
-	return metadata.Save()
-}
-
-func spotTestUnlock(ctx *common.APTContext) {
-	metadata, err := pgmodels.InternalMetadataByKey(constants.MetaSpotTestsRunning)
-	if err != nil {
-		ctx.Log.Error().Msgf("spotTestUnlock: error getting spot test metadata: %v", err)
-		return
-	}
-	metadata.Value = "false"
-	err = metadata.Save()
-	if err != nil {
-		ctx.Log.Error().Msgf("spotTestUnlock: error releasing spot test lock on %s: %v", constants.MetaSpotTestsRunning, err)
-	}
-
-	metadata, err = pgmodels.InternalMetadataByKey(constants.MetaSpotTestsLastRun)
-	if err != nil {
-		ctx.Log.Error().Msgf("spotTestUnlock: error getting metadata '%s': %v", constants.MetaSpotTestsLastRun, err)
-		return
-	}
-	metadata.Value = time.Now().UTC().Format(time.RFC3339)
-	err = metadata.Save()
-	if err != nil {
-		ctx.Log.Error().Msgf("spotTestUnlock: error releasing spot test lock on %s: %v", constants.MetaSpotTestsLastRun, err)
-	}
-}
@@ -11,12 +11,15 @@ import (
 )
 
 var templateNames = []string{
+	"alerts/alert_digest.txt",
 	"alerts/deletion_cancelled.txt",
 	"alerts/deletion_completed.txt",
 	"alerts/deletion_confirmed.txt",
 	"alerts/deletion_requested.txt",
 	"alerts/failed_fixity.txt",
+	"alerts/restoration_batch_completed.txt",
 	"alerts/restoration_completed.txt",
+	"alerts/saved_search_changed.txt",
 }
 
 // Make sure these templates are loaded, and that they have
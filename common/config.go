@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/APTrust/registry/constants"
 	"github.com/asaskevich/govalidator"
 	"github.com/gorilla/securecookie"
 	"github.com/rs/zerolog"
@@ -57,11 +58,64 @@ type LoggingConfig struct {
 }
 
 type TwoFactorConfig struct {
-	AuthyEnabled  bool
-	AuthyAPIKey   string `json:"-"`
-	AWSRegion     string
-	SMSEnabled    bool
-	OTPExpiration time.Duration
+	AuthyEnabled      bool
+	AuthyAPIKey       string `json:"-"`
+	AWSRegion         string
+	SMSEnabled        bool
+	OTPExpiration     time.Duration
+	TOTPEnabled       bool
+	TOTPEncryptionKey []byte `json:"-"`
+}
+
+// LockoutConfig controls login throttling. After each failed password
+// or two-factor attempt, a user must wait BaseDelay, doubling with each
+// further failure, before trying again. MaxFailures in a row locks the
+// account for LockoutDuration. MaxFailuresPerIP failures from a single
+// IP address within IPWindow blocks sign-in from that address until
+// the window passes. A zero BaseDelay turns off the progressive delay.
+type LockoutConfig struct {
+	MaxFailures      int
+	LockoutDuration  time.Duration
+	BaseDelay        time.Duration
+	MaxFailuresPerIP int
+	IPWindow         time.Duration
+}
+
+// Delay returns how long a client must wait after the specified number
+// of consecutive failures before trying again. This doubles with each
+// failure, up to max.
+func (lc *LockoutConfig) Delay(failures int, max time.Duration) time.Duration {
+	if failures < 1 || lc.BaseDelay <= 0 {
+		return 0
+	}
+	delay := lc.BaseDelay
+	for i := 1; i < failures && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// FixityConfig describes our fixity-checking policy. A file that
+// hasn't had a fixity check in more than OverdueDays days is overdue.
+type FixityConfig struct {
+	OverdueDays int
+}
+
+// ReplicationConfig lists the preservation buckets that should hold
+// a copy of each file in each storage option. Standard storage keeps
+// one copy in Virginia and one in Oregon. Every other option keeps a
+// single copy. Storage options whose buckets aren't all configured
+// are left out, and the replication check skips them.
+type ReplicationConfig struct {
+	Buckets map[string][]string
+}
+
+type SSOConfig struct {
+	Enabled       bool
+	EncryptionKey []byte `json:"-"`
 }
 
 type EmailConfig struct {
@@ -79,14 +133,18 @@ type RedisConfig struct {
 }
 
 type Config struct {
-	Cookies   *CookieConfig
-	DB        *DBConfig
-	EnvName   string
-	Logging   *LoggingConfig
-	NsqUrl    string
-	TwoFactor *TwoFactorConfig
-	Email     *EmailConfig
-	Redis     *RedisConfig
+	Cookies     *CookieConfig
+	DB          *DBConfig
+	EnvName     string
+	Logging     *LoggingConfig
+	NsqUrl      string
+	TwoFactor   *TwoFactorConfig
+	Email       *EmailConfig
+	Redis       *RedisConfig
+	SSO         *SSOConfig
+	Lockout     *LockoutConfig
+	Fixity      *FixityConfig
+	Replication *ReplicationConfig
 }
 
 // Returns a new config based on APT_ENV
@@ -126,6 +184,15 @@ func loadConfig() *Config {
 	v.SetConfigName(configFile)
 	v.SetConfigType("env")
 	v.AutomaticEnv() // override config file vars with ENV vars
+
+	// Don't let a missing setting turn off account lockout.
+	v.SetDefault("LOCKOUT_MAX_FAILURES", 5)
+	v.SetDefault("LOCKOUT_DURATION", "30m")
+	v.SetDefault("LOCKOUT_BASE_DELAY", "1s")
+	v.SetDefault("LOCKOUT_MAX_FAILURES_PER_IP", 50)
+	v.SetDefault("LOCKOUT_IP_WINDOW", "15m")
+	v.SetDefault("FIXITY_OVERDUE_DAYS", 90)
+
 	err := v.ReadInConfig()
 	if err != nil {
 		PrintAndExit(fmt.Sprintf("Fatal error config file: %v \n", err))
@@ -138,6 +205,20 @@ func loadConfig() *Config {
 	}
 	var secureCookie = securecookie.New(hashKey, blockKey)
 
+	// TOTP (authenticator app) two-factor is available only if we
+	// have a key to encrypt users' TOTP secrets.
+	totpKey := []byte(v.GetString("TOTP_ENCRYPTION_KEY"))
+	if len(totpKey) > 0 && len(totpKey) < 32 {
+		PrintAndExit("TOTP_ENCRYPTION_KEY must be >= 32 bytes")
+	}
+
+	// Single sign-on is available only if we have a key to encrypt
+	// identity providers' client secrets.
+	ssoKey := []byte(v.GetString("SSO_ENCRYPTION_KEY"))
+	if len(ssoKey) > 0 && len(ssoKey) < 32 {
+		PrintAndExit("SSO_ENCRYPTION_KEY must be >= 32 bytes")
+	}
+
 	nsqUrl := v.GetString("NSQ_URL")
 	if !govalidator.IsURL(nsqUrl) {
 		PrintAndExit("NSQ_URL is missing or invalid")
@@ -183,11 +264,13 @@ func loadConfig() *Config {
 		},
 		NsqUrl: nsqUrl,
 		TwoFactor: &TwoFactorConfig{
-			AuthyAPIKey:   v.GetString("AUTHY_API_KEY"),
-			AuthyEnabled:  v.GetBool("ENABLE_TWO_FACTOR_AUTHY"),
-			AWSRegion:     v.GetString("AWS_REGION"),
-			SMSEnabled:    v.GetBool("ENABLE_TWO_FACTOR_SMS"),
-			OTPExpiration: v.GetDuration("OTP_EXPIRATION"),
+			AuthyAPIKey:       v.GetString("AUTHY_API_KEY"),
+			AuthyEnabled:      v.GetBool("ENABLE_TWO_FACTOR_AUTHY"),
+			AWSRegion:         v.GetString("AWS_REGION"),
+			SMSEnabled:        v.GetBool("ENABLE_TWO_FACTOR_SMS"),
+			OTPExpiration:     v.GetDuration("OTP_EXPIRATION"),
+			TOTPEnabled:       len(totpKey) > 0,
+			TOTPEncryptionKey: totpKey,
 		},
 		Email: &EmailConfig{
 			AWSRegion:   v.GetString("AWS_REGION"),
@@ -201,7 +284,53 @@ func loadConfig() *Config {
 			Password:  v.GetString("REDIS_PASSWORD"),
 			URL:       v.GetString("REDIS_URL"),
 		},
+		SSO: &SSOConfig{
+			Enabled:       len(ssoKey) > 0,
+			EncryptionKey: ssoKey,
+		},
+		Lockout: &LockoutConfig{
+			MaxFailures:      v.GetInt("LOCKOUT_MAX_FAILURES"),
+			LockoutDuration:  v.GetDuration("LOCKOUT_DURATION"),
+			BaseDelay:        v.GetDuration("LOCKOUT_BASE_DELAY"),
+			MaxFailuresPerIP: v.GetInt("LOCKOUT_MAX_FAILURES_PER_IP"),
+			IPWindow:         v.GetDuration("LOCKOUT_IP_WINDOW"),
+		},
+		Fixity: &FixityConfig{
+			OverdueDays: v.GetInt("FIXITY_OVERDUE_DAYS"),
+		},
+		Replication: &ReplicationConfig{
+			Buckets: replicationBuckets(v),
+		},
+	}
+}
+
+// replicationBuckets maps each storage option to the buckets that
+// should hold copies of its files.
+func replicationBuckets(v *viper.Viper) map[string][]string {
+	settings := map[string][]string{
+		constants.StorageOptionStandard:      {"BUCKET_STANDARD_VA", "BUCKET_STANDARD_OR"},
+		constants.StorageOptionGlacierOH:     {"BUCKET_GLACIER_OH"},
+		constants.StorageOptionGlacierOR:     {"BUCKET_GLACIER_OR"},
+		constants.StorageOptionGlacierVA:     {"BUCKET_GLACIER_VA"},
+		constants.StorageOptionGlacierDeepOH: {"BUCKET_GLACIER_DEEP_OH"},
+		constants.StorageOptionGlacierDeepOR: {"BUCKET_GLACIER_DEEP_OR"},
+		constants.StorageOptionGlacierDeepVA: {"BUCKET_GLACIER_DEEP_VA"},
+		constants.StorageOptionWasabiOR:      {"BUCKET_WASABI_OR"},
+		constants.StorageOptionWasabiVA:      {"BUCKET_WASABI_VA"},
+	}
+	buckets := make(map[string][]string)
+	for option, names := range settings {
+		values := make([]string, 0, len(names))
+		for _, name := range names {
+			if bucket := v.GetString(name); bucket != "" {
+				values = append(values, bucket)
+			}
+		}
+		if len(values) == len(names) {
+			buckets[option] = values
+		}
 	}
+	return buckets
 }
 
 func getLogLevel(level int) zerolog.Level {
@@ -5,8 +5,10 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -45,6 +47,14 @@ func TestNewConfig(t *testing.T) {
 		require.False(t, true, "Wrong APT_ENV environment for testing")
 	}
 
+	assert.Equal(t, 5, config.Lockout.MaxFailures)
+	assert.Equal(t, 30*time.Minute, config.Lockout.LockoutDuration)
+	assert.Equal(t, 15*time.Minute, config.Lockout.IPWindow)
+	assert.Equal(t, 90, config.Fixity.OverdueDays)
+	assert.Equal(t, []string{"preservation-va", "preservation-or"}, config.Replication.Buckets[constants.StorageOptionStandard])
+	assert.Equal(t, []string{"aptrust.preservation.glacier-deep-oh"}, config.Replication.Buckets[constants.StorageOptionGlacierDeepOH])
+	assert.Equal(t, len(constants.StorageOptions), len(config.Replication.Buckets))
+
 	assert.False(t, config.Email.Enabled)
 	assert.Equal(t, "help@aptrust.org", config.Email.FromAddress)
 
@@ -127,3 +137,18 @@ func TestHTTPScheme(t *testing.T) {
 	config.EnvName = "production"
 	assert.Equal(t, "https", config.HTTPScheme())
 }
+
+func TestLockoutDelay(t *testing.T) {
+	lockout := &common.LockoutConfig{BaseDelay: time.Second}
+	max := 10 * time.Second
+	assert.Equal(t, time.Duration(0), lockout.Delay(0, max))
+	assert.Equal(t, time.Second, lockout.Delay(1, max))
+	assert.Equal(t, 2*time.Second, lockout.Delay(2, max))
+	assert.Equal(t, 8*time.Second, lockout.Delay(4, max))
+	assert.Equal(t, max, lockout.Delay(5, max))
+	assert.Equal(t, max, lockout.Delay(500, max))
+
+	// Zero base delay turns off the progressive delay.
+	lockout.BaseDelay = 0
+	assert.Equal(t, time.Duration(0), lockout.Delay(4, max))
+}
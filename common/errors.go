@@ -21,6 +21,14 @@ var ErrInvalidAPICredentials = errors.New("invalid api credentials")
 // account.
 var ErrAccountDeactivated = errors.New("account deactivated")
 
+// ErrAccountLocked means the user tried to sign in to an account that
+// is temporarily locked after too many failed sign-in attempts.
+var ErrAccountLocked = errors.New("this account is temporarily locked after too many failed sign-in attempts; try again later or ask your institutional administrator to unlock it")
+
+// ErrTooManyAttempts means the user or IP address tried to sign in
+// again too soon after a failed attempt.
+var ErrTooManyAttempts = errors.New("too many failed sign-in attempts; please wait a moment and try again")
+
 // ErrPermissionDenied means the user tried to access a resource
 // withouth sufficient permission.
 var ErrPermissionDenied = errors.New("permission denied")
@@ -63,6 +71,19 @@ var ErrResourcePermission = errors.New("cannot determine permission type for req
 // and old versions of a bag's files.
 var ErrPendingWorkItems = errors.New("task cannot be completed because this object has pending work items")
 
+// ErrFixityCheckPending occurs when a user requests a fixity check on a
+// file or object that already has a fixity check waiting in the queue.
+var ErrFixityCheckPending = errors.New("a fixity check is already pending for this item")
+
+// ErrGlacierFixity occurs when a user requests a fixity check on a file
+// or object stored only in Glacier. The fixity checker can't read those
+// files without first restoring them.
+var ErrGlacierFixity = errors.New("fixity checks are not available for items stored only in glacier")
+
+// ErrTooManyItems occurs when a bulk request covers more items than we
+// allow in a single request. The user should narrow their filters.
+var ErrTooManyItems = errors.New("request includes too many items")
+
 // ErrInvalidToken means that the token presented for an action like
 // password reset or deletion confirmation does not match the encrypted
 // token in the database. When this error occurs, the user may not
@@ -95,6 +116,54 @@ var ErrAlreadyHasAuthyID = errors.New("user is already registered with authy")
 // but does not have an Authy ID.
 var ErrNoAuthyID = errors.New("user does not have an authy id")
 
+// ErrTOTPNotEnabled occurs when a user tries to set up an authenticator
+// app but TOTP_ENCRYPTION_KEY is not set, so we can't store the secret.
+var ErrTOTPNotEnabled = errors.New("authenticator app two-factor is not enabled on this server")
+
+// ErrNoTOTPSecret occurs when a user tries to verify an authenticator
+// app code but has not set up an authenticator app.
+var ErrNoTOTPSecret = errors.New("user has not set up an authenticator app")
+
+// ErrSecretCiphertext means we could not decrypt a stored secret, such
+// as a user's TOTP secret or an identity provider's client secret.
+var ErrSecretCiphertext = errors.New("encrypted secret is invalid")
+
+// ErrWebAuthnOrigin occurs when a security key request comes in on a
+// host that isn't COOKIE_DOMAIN or one of its subdomains. WebAuthn
+// credentials are bound to a domain, so we can't verify them here.
+var ErrWebAuthnOrigin = errors.New("security keys can't be used on this host")
+
+// ErrWebAuthnVerification means a security key registration or login
+// response failed verification. The underlying error from the WebAuthn
+// library is logged, but not shown to the user.
+var ErrWebAuthnVerification = errors.New("security key verification failed")
+
+// ErrNoWebAuthnCredentials occurs when a user tries to log in with a
+// security key but has not registered one.
+var ErrNoWebAuthnCredentials = errors.New("user has no security keys")
+
+// ErrSSONotEnabled occurs when someone tries to use or configure single
+// sign-on but SSO_ENCRYPTION_KEY is not set.
+var ErrSSONotEnabled = errors.New("single sign-on is not enabled on this server")
+
+// ErrSSONotConfigured occurs when a user tries to sign in through their
+// institution, but the institution has no active identity provider.
+var ErrSSONotConfigured = errors.New("single sign-on is not available for that email address")
+
+// ErrSSOFailed means an identity provider's response failed verification,
+// or we could not reach the provider. The underlying error is logged,
+// but not shown to the user.
+var ErrSSOFailed = errors.New("single sign-on failed, please try again")
+
+// ErrSSONoAccount occurs when an institution's identity provider signs
+// a user in, but no active Registry account at that institution has the
+// user's email address.
+var ErrSSONoAccount = errors.New("your institution signed you in, but you don't have a Registry account; please ask your institutional administrator to create one")
+
+// ErrSSORequired occurs when a user tries to sign in with a password,
+// but their institution requires single sign-on.
+var ErrSSORequired = errors.New("your institution requires you to sign in through your institution's login page")
+
 // ErrWrongAPI occurs when a non-admin user tries to access the admin API.
 // While the member and admin APIs share some common handlers, and members
 // do technically have access to a number of read-only operations in both
@@ -144,6 +213,15 @@ var ErrCountTypeNotSupported = errors.New("type is not supported for view count"
 // but they shouldn't be doing it in this context.
 var ErrMustCompleteReset = errors.New("you must complete your own password reset")
 
+// ErrInvalidCursor means a pagination cursor could not be decoded, or
+// was created for a different sort order than the current request.
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+// ErrNoDepositStats occurs when we try to generate an invoice for a
+// month that has no historical deposit stats, usually because the job
+// that records them hasn't run or failed.
+var ErrNoDepositStats = errors.New("no deposit stats have been recorded for this month")
+
 type ValidationError struct {
 	Errors map[string]string
 }
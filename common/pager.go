@@ -19,6 +19,20 @@ type Pager struct {
 	PreviousLink     string
 	NextLink         string
 	URL              *url.URL
+
+	// UsesCursor is true if the request included a cursor param,
+	// asking for keyset pagination instead of page numbers. An empty
+	// cursor means the first page.
+	UsesCursor bool
+
+	// Cursor is the cursor token from the request.
+	Cursor string
+
+	// NextCursor and PreviousCursor are the tokens for the next and
+	// previous pages of a cursor-paged list. These are empty if there
+	// is no next or previous page.
+	NextCursor     string
+	PreviousCursor string
 }
 
 func NewPager(c *gin.Context, baseURL string, defaultPerPage int) (*Pager, error) {
@@ -33,6 +47,11 @@ func NewPager(c *gin.Context, baseURL string, defaultPerPage int) (*Pager, error
 	perPage := c.DefaultQuery("per_page", strconv.Itoa(defaultPerPage))
 	pager.Page, _ = strconv.Atoi(page)
 	pager.PerPage, _ = strconv.Atoi(perPage)
+	pager.Cursor, pager.UsesCursor = c.GetQuery("cursor")
+	if pager.UsesCursor {
+		// Page numbers don't apply to cursor pagination.
+		pager.Page = 1
+	}
 	if pager.Page > 1 {
 		pager.QueryOffset = (pager.Page - 1) * pager.PerPage
 	}
@@ -48,6 +67,10 @@ func (pager *Pager) SetCounts(totalItems, itemsInResultSet int) {
 	pager.TotalItems = totalItems
 	pager.ItemsInResultSet = itemsInResultSet
 	pager.ItemLast = pager.QueryOffset + itemsInResultSet
+	if pager.UsesCursor {
+		// Links come from SetCursors.
+		return
+	}
 
 	queryValues := pager.URL.Query()
 	queryValues["per_page"] = []string{strconv.Itoa(pager.PerPage)}
@@ -61,3 +84,22 @@ func (pager *Pager) SetCounts(totalItems, itemsInResultSet int) {
 		pager.NextLink = fmt.Sprintf("%s?%s", pager.URL.Path, queryValues.Encode())
 	}
 }
+
+// SetCursors sets the next and previous cursor tokens for a
+// cursor-paged list, along with the next and previous links.
+// Pass an empty string if there is no next or previous page.
+func (pager *Pager) SetCursors(nextCursor, previousCursor string) {
+	pager.NextCursor = nextCursor
+	pager.PreviousCursor = previousCursor
+	queryValues := pager.URL.Query()
+	delete(queryValues, "page")
+	queryValues["per_page"] = []string{strconv.Itoa(pager.PerPage)}
+	if previousCursor != "" {
+		queryValues["cursor"] = []string{previousCursor}
+		pager.PreviousLink = fmt.Sprintf("%s?%s", pager.URL.Path, queryValues.Encode())
+	}
+	if nextCursor != "" {
+		queryValues["cursor"] = []string{nextCursor}
+		pager.NextLink = fmt.Sprintf("%s?%s", pager.URL.Path, queryValues.Encode())
+	}
+}
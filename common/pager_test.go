@@ -57,3 +57,54 @@ func TestPagerPerPageLimit(t *testing.T) {
 	pager = getPager(t, 999999)
 	assert.Equal(t, 1000, pager.PerPage)
 }
+
+func TestPagerCursor(t *testing.T) {
+	var _url = "http://example.com/events?cursor=abc&page=4&per_page=10&sort=date_time__desc"
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = &http.Request{}
+	var err error
+	c.Request.URL, err = url.Parse(_url)
+	require.Nil(t, err)
+	pager, err := common.NewPager(c, _url, 20)
+	require.Nil(t, err)
+
+	// Cursor pagination ignores page number.
+	assert.True(t, pager.UsesCursor)
+	assert.Equal(t, "abc", pager.Cursor)
+	assert.Equal(t, 1, pager.Page)
+	assert.Equal(t, 0, pager.QueryOffset)
+
+	// SetCounts should not set page links for cursor pagination.
+	pager.SetCounts(200, 10)
+	assert.Equal(t, 200, pager.TotalItems)
+	assert.Empty(t, pager.NextLink)
+	assert.Empty(t, pager.PreviousLink)
+
+	pager.SetCursors("next", "prev")
+	assert.Equal(t, "next", pager.NextCursor)
+	assert.Equal(t, "prev", pager.PreviousCursor)
+	assert.Equal(t, "/events?cursor=next&per_page=10&sort=date_time__desc", pager.NextLink)
+	assert.Equal(t, "/events?cursor=prev&per_page=10&sort=date_time__desc", pager.PreviousLink)
+
+	// No links when there are no more pages.
+	pager.NextLink = ""
+	pager.PreviousLink = ""
+	pager.SetCursors("", "")
+	assert.Empty(t, pager.NextLink)
+	assert.Empty(t, pager.PreviousLink)
+
+	// Empty cursor param means first page of a cursor-paged list.
+	_url = "http://example.com/events?cursor="
+	c, _ = gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = &http.Request{}
+	c.Request.URL, err = url.Parse(_url)
+	require.Nil(t, err)
+	pager, err = common.NewPager(c, _url, 20)
+	require.Nil(t, err)
+	assert.True(t, pager.UsesCursor)
+	assert.Empty(t, pager.Cursor)
+
+	// No cursor param means regular pagination.
+	pager = getPager(t, 10)
+	assert.False(t, pager.UsesCursor)
+}
@@ -0,0 +1,59 @@
+package common
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+)
+
+// EncryptSecret encrypts plaintext with AES-256-GCM and returns
+// it base64-encoded, with the nonce prepended. The AES key is the
+// SHA-256 digest of param key, so key may be any length, though it
+// should have at least 32 bytes of entropy.
+//
+// We use this for secrets we must be able to recover, such as users'
+// TOTP secrets and identity providers' OIDC client secrets. Passwords
+// and backup codes, which we only need to compare, are hashed with
+// bcrypt instead.
+func EncryptSecret(key []byte, plaintext string) (string, error) {
+	gcm, err := secretCipher(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret decrypts a value encrypted by EncryptSecret.
+func DecryptSecret(key []byte, ciphertext string) (string, error) {
+	gcm, err := secretCipher(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", ErrSecretCiphertext
+	}
+	nonce, data := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, data, nil)
+	if err != nil {
+		return "", ErrSecretCiphertext
+	}
+	return string(plaintext), nil
+}
+
+func secretCipher(key []byte) (cipher.AEAD, error) {
+	digest := sha256.Sum256(key)
+	block, err := aes.NewCipher(digest[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,34 @@
+package common_test
+
+import (
+	"testing"
+
+	"github.com/APTrust/registry/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptDecryptSecret(t *testing.T) {
+	encKey := []byte("0123456789abcdef0123456789abcdef")
+	plaintext := "otpauth://totp/APTrust%20Registry:user@example.com?secret=ABC"
+
+	encrypted, err := common.EncryptSecret(encKey, plaintext)
+	require.Nil(t, err)
+	assert.NotContains(t, encrypted, "secret")
+
+	// Nonce is random, so encrypting twice gives different results.
+	encrypted2, err := common.EncryptSecret(encKey, plaintext)
+	require.Nil(t, err)
+	assert.NotEqual(t, encrypted, encrypted2)
+
+	decrypted, err := common.DecryptSecret(encKey, encrypted)
+	require.Nil(t, err)
+	assert.Equal(t, plaintext, decrypted)
+
+	_, err = common.DecryptSecret([]byte("the wrong key, which is 32 bytes"), encrypted)
+	assert.Equal(t, common.ErrSecretCiphertext, err)
+	_, err = common.DecryptSecret(encKey, "not base64!")
+	assert.Equal(t, common.ErrSecretCiphertext, err)
+	_, err = common.DecryptSecret(encKey, encrypted[:len(encrypted)-4]+"AAAA")
+	assert.Equal(t, common.ErrSecretCiphertext, err)
+}
@@ -0,0 +1,234 @@
+package common
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/crewjam/saml"
+	"github.com/crewjam/saml/samlsp"
+	"golang.org/x/oauth2"
+)
+
+// These are the Registry endpoints that institutions' identity providers
+// talk to. Institutional IT staff will need the full URLs when they
+// register the Registry with their IdP.
+const (
+	// OIDCCallbackPath is the OpenID Connect redirect URI.
+	OIDCCallbackPath = "/users/sso/oidc/callback"
+
+	// SAMLMetadataPath serves our SAML service provider metadata. This
+	// URL is also our SAML entity ID.
+	SAMLMetadataPath = "/users/sso/saml/metadata"
+
+	// SAMLACSPath is our SAML assertion consumer service, which receives
+	// the IdP's response via HTTP POST.
+	SAMLACSPath = "/users/sso/saml/acs"
+)
+
+// SSOIdentity describes a user whom an institution's identity provider
+// has just authenticated. Attributes contains all of the OIDC claims or
+// SAML attributes the IdP sent, keyed by name. We use these to map the
+// user to a Registry role.
+type SSOIdentity struct {
+	Subject    string
+	Email      string
+	Attributes map[string][]string
+}
+
+// newSSOIdentity returns an identity with the specified subject and
+// attributes. Email comes from the emailAttribute value, lowercased,
+// or from the subject if the IdP sent no such attribute and the
+// subject looks like an email address.
+func newSSOIdentity(subject string, attributes map[string][]string, emailAttribute string) *SSOIdentity {
+	email := ""
+	if values := attributes[emailAttribute]; len(values) > 0 {
+		email = values[0]
+	} else if strings.Contains(subject, "@") {
+		email = subject
+	}
+	return &SSOIdentity{
+		Subject:    subject,
+		Email:      strings.ToLower(strings.TrimSpace(email)),
+		Attributes: attributes,
+	}
+}
+
+// OIDCClient signs users in through an OpenID Connect provider using
+// the authorization code flow.
+type OIDCClient struct {
+	oauth    oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// NewOIDCClient returns a client for the OpenID Connect provider at
+// issuerURL. This fetches the provider's discovery document, so it
+// makes a network call.
+func NewOIDCClient(ctx context.Context, issuerURL, clientID, clientSecret, redirectURL string) (*OIDCClient, error) {
+	provider, err := oidc.NewProvider(ctx, issuerURL)
+	if err != nil {
+		return nil, err
+	}
+	return &OIDCClient{
+		oauth: oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			Endpoint:     provider.Endpoint(),
+			RedirectURL:  redirectURL,
+			Scopes:       []string{oidc.ScopeOpenID, "email", "profile"},
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: clientID}),
+	}, nil
+}
+
+// AuthCodeURL returns the URL of the provider's login page. The
+// provider sends state back to us unchanged, and includes nonce in
+// the signed ID token.
+func (client *OIDCClient) AuthCodeURL(state, nonce string) string {
+	return client.oauth.AuthCodeURL(state, oidc.Nonce(nonce))
+}
+
+// Exchange trades an authorization code for an ID token, verifies the
+// token's signature, audience, expiration and nonce, and returns the
+// identity it describes. If the token includes an email_verified claim,
+// it must be true.
+func (client *OIDCClient) Exchange(ctx context.Context, code, nonce, emailAttribute string) (*SSOIdentity, error) {
+	token, err := client.oauth.Exchange(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, fmt.Errorf("token response has no id_token")
+	}
+	idToken, err := client.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+	if idToken.Nonce != nonce {
+		return nil, fmt.Errorf("id_token nonce does not match")
+	}
+	claims := make(map[string]interface{})
+	if err = idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+	if verified, ok := claims["email_verified"].(bool); ok && !verified {
+		return nil, fmt.Errorf("provider says email is not verified")
+	}
+	attributes := make(map[string][]string)
+	for name, value := range claims {
+		attributes[name] = claimValues(value)
+	}
+	return newSSOIdentity(idToken.Subject, attributes, emailAttribute), nil
+}
+
+// claimValues converts a JSON claim value to a list of strings, so
+// single-valued and multi-valued claims such as groups can be
+// handled the same way as SAML attributes.
+func claimValues(value interface{}) []string {
+	switch v := value.(type) {
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			values = append(values, fmt.Sprintf("%v", item))
+		}
+		return values
+	case nil:
+		return []string{}
+	default:
+		return []string{fmt.Sprintf("%v", v)}
+	}
+}
+
+// NewSAMLServiceProvider returns a SAML service provider for the
+// Registry at baseURL, which trusts the identity provider described by
+// idpMetadata. Param idpMetadata may be empty if the caller only needs
+// our own metadata.
+//
+// We don't sign authentication requests, and our metadata doesn't
+// include an encryption certificate, so IdPs will send assertions
+// signed but unencrypted. They travel over TLS in any case.
+func NewSAMLServiceProvider(baseURL string, idpMetadata []byte) (*saml.ServiceProvider, error) {
+	metadataURL, err := url.Parse(baseURL + SAMLMetadataPath)
+	if err != nil {
+		return nil, err
+	}
+	acsURL, err := url.Parse(baseURL + SAMLACSPath)
+	if err != nil {
+		return nil, err
+	}
+	sp := &saml.ServiceProvider{
+		EntityID:          metadataURL.String(),
+		MetadataURL:       *metadataURL,
+		AcsURL:            *acsURL,
+		AuthnNameIDFormat: saml.UnspecifiedNameIDFormat,
+	}
+	if len(idpMetadata) > 0 {
+		sp.IDPMetadata, err = samlsp.ParseMetadata(idpMetadata)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sp, nil
+}
+
+// SAMLAuthRequestURL returns the URL of the identity provider's login
+// page, with a new authentication request for the IdP's redirect
+// binding. The IdP sends relayState back to us unchanged. The returned
+// request ID must match the InResponseTo of the IdP's response.
+func SAMLAuthRequestURL(sp *saml.ServiceProvider, relayState string) (string, string, error) {
+	req, err := sp.MakeAuthenticationRequest(
+		sp.GetSSOBindingLocation(saml.HTTPRedirectBinding),
+		saml.HTTPRedirectBinding,
+		saml.HTTPPostBinding,
+	)
+	if err != nil {
+		return "", "", err
+	}
+	redirectURL, err := req.Redirect(relayState, sp)
+	if err != nil {
+		return "", "", err
+	}
+	return redirectURL.String(), req.ID, nil
+}
+
+// SAMLIdentityFromResponse verifies the base64-encoded SAMLResponse an
+// identity provider posted to our ACS, and returns the identity in its
+// assertion. This checks the signature, audience, destination, validity
+// period, and that the response answers the request with requestID.
+func SAMLIdentityFromResponse(sp *saml.ServiceProvider, samlResponse, requestID, emailAttribute string) (*SSOIdentity, error) {
+	responseXML, err := base64.StdEncoding.DecodeString(samlResponse)
+	if err != nil {
+		return nil, err
+	}
+	assertion, err := sp.ParseXMLResponse(responseXML, []string{requestID})
+	if err != nil {
+		// The library hides the details behind a generic message,
+		// so dig them out for the log.
+		if invalid, ok := err.(*saml.InvalidResponseError); ok && invalid.PrivateErr != nil {
+			return nil, invalid.PrivateErr
+		}
+		return nil, err
+	}
+	subject := ""
+	if assertion.Subject != nil && assertion.Subject.NameID != nil {
+		subject = assertion.Subject.NameID.Value
+	}
+	attributes := make(map[string][]string)
+	for _, statement := range assertion.AttributeStatements {
+		for _, attr := range statement.Attributes {
+			values := make([]string, len(attr.Values))
+			for i, value := range attr.Values {
+				values[i] = value.Value
+			}
+			attributes[attr.Name] = append(attributes[attr.Name], values...)
+			if attr.FriendlyName != "" && attr.FriendlyName != attr.Name {
+				attributes[attr.FriendlyName] = append(attributes[attr.FriendlyName], values...)
+			}
+		}
+	}
+	return newSSOIdentity(subject, attributes, emailAttribute), nil
+}
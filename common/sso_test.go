@@ -0,0 +1,127 @@
+package common_test
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/web/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ssoBaseURL = "http://localhost"
+
+func TestOIDCClient(t *testing.T) {
+	idp, err := testutil.NewStubIdP(ssoBaseURL)
+	require.Nil(t, err)
+	defer idp.Close()
+	ctx := context.Background()
+
+	client, err := common.NewOIDCClient(ctx, idp.IssuerURL(), idp.ClientID, idp.ClientSecret, ssoBaseURL+common.OIDCCallbackPath)
+	require.Nil(t, err)
+
+	authURL := client.AuthCodeURL("state-123", "nonce-456")
+	assert.True(t, strings.HasPrefix(authURL, idp.IssuerURL()+"/authorize?"))
+
+	claims := map[string]interface{}{
+		"sub":    "jdoe",
+		"email":  "JDoe@Example.edu",
+		"groups": []string{"staff", "registry-admins"},
+	}
+	callback, err := idp.OIDCLogin(authURL, claims)
+	require.Nil(t, err)
+	u, err := url.Parse(callback)
+	require.Nil(t, err)
+	assert.Equal(t, common.OIDCCallbackPath, u.Path)
+	assert.Equal(t, "state-123", u.Query().Get("state"))
+	code := u.Query().Get("code")
+
+	identity, err := client.Exchange(ctx, code, "nonce-456", "email")
+	require.Nil(t, err)
+	assert.Equal(t, "jdoe", identity.Subject)
+	assert.Equal(t, "jdoe@example.edu", identity.Email)
+	assert.Equal(t, []string{"staff", "registry-admins"}, identity.Attributes["groups"])
+
+	// Codes can be used only once.
+	_, err = client.Exchange(ctx, code, "nonce-456", "email")
+	assert.NotNil(t, err)
+
+	// Nonce must match.
+	callback, err = idp.OIDCLogin(authURL, claims)
+	require.Nil(t, err)
+	u, _ = url.Parse(callback)
+	_, err = client.Exchange(ctx, u.Query().Get("code"), "some-other-nonce", "email")
+	assert.NotNil(t, err)
+
+	// Unverified email is rejected.
+	claims["email_verified"] = false
+	callback, err = idp.OIDCLogin(authURL, claims)
+	require.Nil(t, err)
+	u, _ = url.Parse(callback)
+	_, err = client.Exchange(ctx, u.Query().Get("code"), "nonce-456", "email")
+	assert.NotNil(t, err)
+
+	// Wrong secret fails.
+	badClient, err := common.NewOIDCClient(ctx, idp.IssuerURL(), idp.ClientID, "wrong", ssoBaseURL+common.OIDCCallbackPath)
+	require.Nil(t, err)
+	delete(claims, "email_verified")
+	callback, err = idp.OIDCLogin(authURL, claims)
+	require.Nil(t, err)
+	u, _ = url.Parse(callback)
+	_, err = badClient.Exchange(ctx, u.Query().Get("code"), "nonce-456", "email")
+	assert.NotNil(t, err)
+}
+
+func TestSAMLServiceProvider(t *testing.T) {
+	idp, err := testutil.NewStubIdP(ssoBaseURL)
+	require.Nil(t, err)
+	defer idp.Close()
+
+	sp, err := common.NewSAMLServiceProvider(ssoBaseURL, []byte(idp.SAMLMetadata()))
+	require.Nil(t, err)
+	assert.Equal(t, ssoBaseURL+common.SAMLMetadataPath, sp.EntityID)
+	assert.Equal(t, ssoBaseURL+common.SAMLACSPath, sp.AcsURL.String())
+
+	authURL, requestID, err := common.SAMLAuthRequestURL(sp, "state-123")
+	require.Nil(t, err)
+	assert.True(t, strings.HasPrefix(authURL, idp.IssuerURL()+"/saml/sso?"))
+	assert.NotEmpty(t, requestID)
+
+	attrs := map[string][]string{
+		"mail":                 {"JDoe@Example.edu"},
+		"eduPersonAffiliation": {"staff", "member"},
+	}
+	form, err := idp.SAMLLogin(authURL, "jdoe@example.edu", attrs)
+	require.Nil(t, err)
+	assert.Equal(t, "state-123", form.Get("RelayState"))
+
+	identity, err := common.SAMLIdentityFromResponse(sp, form.Get("SAMLResponse"), requestID, "mail")
+	require.Nil(t, err)
+	assert.Equal(t, "jdoe@example.edu", identity.Subject)
+	assert.Equal(t, "jdoe@example.edu", identity.Email)
+	assert.Equal(t, []string{"staff", "member"}, identity.Attributes["eduPersonAffiliation"])
+
+	// If the email attribute is missing, we fall back to NameID.
+	identity, err = common.SAMLIdentityFromResponse(sp, form.Get("SAMLResponse"), requestID, "no-such-attribute")
+	require.Nil(t, err)
+	assert.Equal(t, "jdoe@example.edu", identity.Email)
+
+	// Response must answer our request.
+	_, err = common.SAMLIdentityFromResponse(sp, form.Get("SAMLResponse"), "some-other-request", "mail")
+	assert.NotNil(t, err)
+
+	// Response must be signed by the IdP in our metadata.
+	otherIdP, err := testutil.NewStubIdP(ssoBaseURL)
+	require.Nil(t, err)
+	defer otherIdP.Close()
+	otherSP, err := common.NewSAMLServiceProvider(ssoBaseURL, []byte(otherIdP.SAMLMetadata()))
+	require.Nil(t, err)
+	_, err = common.SAMLIdentityFromResponse(otherSP, form.Get("SAMLResponse"), requestID, "mail")
+	assert.NotNil(t, err)
+
+	_, err = common.SAMLIdentityFromResponse(sp, "not base64!", requestID, "mail")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,63 @@
+package common
+
+import (
+	"crypto/subtle"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+// TOTPIssuer is the name authenticator apps display next to
+// the user's email address.
+const TOTPIssuer = "APTrust Registry"
+
+// TOTPPeriod is the number of seconds each TOTP code is valid.
+const TOTPPeriod = 30
+
+// TOTPSkew is the number of periods before or after the current one
+// for which we'll accept a code. This allows for clock drift on the
+// user's device and for codes entered just as they roll over.
+const TOTPSkew = 1
+
+// NewTOTPKey generates a new RFC 6238 TOTP key for the specified
+// account, which is typically the user's email address. The key's
+// String() method returns the otpauth:// provisioning URI, which is
+// what we encrypt and store.
+func NewTOTPKey(accountName string) (*otp.Key, error) {
+	return totp.Generate(totp.GenerateOpts{
+		Issuer:      TOTPIssuer,
+		AccountName: accountName,
+		Period:      TOTPPeriod,
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+}
+
+// TOTPStep returns the TOTP time step that code matches for the
+// specified key at time now, within TOTPSkew steps either side.
+// The bool return is false if the code doesn't match, or if it
+// matches a step at or before lastStep. Rejecting old steps
+// prevents a code from being used twice.
+func TOTPStep(key *otp.Key, code string, now time.Time, lastStep int64) (int64, bool) {
+	opts := totp.ValidateOpts{
+		Period:    uint(key.Period()),
+		Digits:    key.Digits(),
+		Algorithm: key.Algorithm(),
+	}
+	current := now.Unix() / int64(key.Period())
+	for step := current - TOTPSkew; step <= current+TOTPSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		t := time.Unix(step*int64(key.Period()), 0)
+		expected, err := totp.GenerateCodeCustom(key.Secret(), t, opts)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,60 @@
+package common_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTOTPKey(t *testing.T) {
+	key, err := common.NewTOTPKey("user@example.com")
+	require.Nil(t, err)
+	assert.Equal(t, common.TOTPIssuer, key.Issuer())
+	assert.Equal(t, "user@example.com", key.AccountName())
+	assert.Equal(t, uint64(common.TOTPPeriod), key.Period())
+	assert.NotEmpty(t, key.Secret())
+	assert.Contains(t, key.URL(), "otpauth://totp/")
+}
+
+func TestTOTPStep(t *testing.T) {
+	key, err := common.NewTOTPKey("user@example.com")
+	require.Nil(t, err)
+
+	now := time.Date(2026, time.March, 4, 12, 0, 10, 0, time.UTC)
+	currentStep := now.Unix() / common.TOTPPeriod
+
+	code, err := totp.GenerateCode(key.Secret(), now)
+	require.Nil(t, err)
+	step, ok := common.TOTPStep(key, code, now, 0)
+	assert.True(t, ok)
+	assert.Equal(t, currentStep, step)
+
+	// Codes can't be reused.
+	_, ok = common.TOTPStep(key, code, now, step)
+	assert.False(t, ok)
+
+	// Codes from one step either side are accepted, to allow for
+	// clock drift. Codes from further away are not.
+	for offset := -1; offset <= 1; offset++ {
+		code, err = totp.GenerateCode(key.Secret(), now.Add(time.Duration(offset*common.TOTPPeriod)*time.Second))
+		require.Nil(t, err)
+		step, ok = common.TOTPStep(key, code, now, 0)
+		assert.True(t, ok, offset)
+		assert.Equal(t, currentStep+int64(offset), step)
+	}
+	for _, offset := range []int{-3, 3} {
+		code, err = totp.GenerateCode(key.Secret(), now.Add(time.Duration(offset*common.TOTPPeriod)*time.Second))
+		require.Nil(t, err)
+		_, ok = common.TOTPStep(key, code, now, 0)
+		assert.False(t, ok, offset)
+	}
+
+	_, ok = common.TOTPStep(key, "", now, 0)
+	assert.False(t, ok)
+	_, ok = common.TOTPStep(key, "abcdef", now, 0)
+	assert.False(t, ok)
+}
@@ -0,0 +1,43 @@
+package common
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// WebAuthnRPDisplayName is the name browsers show when asking users
+// to touch their security key or confirm their passkey.
+const WebAuthnRPDisplayName = "APTrust Registry"
+
+// NewWebAuthn returns a WebAuthn relying party for requests coming
+// from origin, which should be the scheme, host and port of the
+// current request (e.g. https://repo.aptrust.org).
+//
+// The relying party ID is COOKIE_DOMAIN. Browsers bind each credential
+// to the relying party ID, so users can't be tricked into signing in
+// to a look-alike domain with their security key. It also means that
+// credentials registered on one Registry host won't work on another
+// unless both share the same COOKIE_DOMAIN.
+//
+// This returns ErrWebAuthnOrigin if origin's host is not COOKIE_DOMAIN
+// or a subdomain of it.
+func NewWebAuthn(origin string) (*webauthn.WebAuthn, error) {
+	rpID := Context().Config.Cookies.Domain
+	originURL, err := url.Parse(origin)
+	if err != nil {
+		return nil, ErrWebAuthnOrigin
+	}
+	host := originURL.Hostname()
+	if host != rpID && !strings.HasSuffix(host, "."+rpID) {
+		return nil, ErrWebAuthnOrigin
+	}
+	return webauthn.New(&webauthn.Config{
+		RPDisplayName:         WebAuthnRPDisplayName,
+		RPID:                  rpID,
+		RPOrigin:              origin,
+		AttestationPreference: protocol.PreferNoAttestation,
+	})
+}
@@ -0,0 +1,26 @@
+package common_test
+
+import (
+	"testing"
+
+	"github.com/APTrust/registry/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWebAuthn(t *testing.T) {
+	// COOKIE_DOMAIN is localhost in the test config.
+	web, err := common.NewWebAuthn("http://localhost:8080")
+	require.Nil(t, err)
+	assert.Equal(t, "localhost", web.Config.RPID)
+	assert.Equal(t, "http://localhost:8080", web.Config.RPOrigin)
+	assert.Equal(t, common.WebAuthnRPDisplayName, web.Config.RPDisplayName)
+
+	_, err = common.NewWebAuthn("https://registry.localhost")
+	assert.Nil(t, err)
+
+	for _, origin := range []string{"https://example.com", "https://notlocalhost", "://"} {
+		_, err = common.NewWebAuthn(origin)
+		assert.Equal(t, common.ErrWebAuthnOrigin, err, origin)
+	}
+}
@@ -20,6 +20,11 @@ const (
 	ActionRequestDelete        = "RequestDelete"
 	ActionRestoreObject        = "Restore Object"
 	ActionUpdate               = "Update"
+	AlertAccountLocked         = "Account Locked"
+	AlertDeliveryDaily         = "daily"
+	AlertDeliveryImmediate     = "immediate"
+	AlertDeliveryInApp         = "in_app"
+	AlertDeliveryWeekly        = "weekly"
 	AlertDeletionCancelled     = "Deletion Cancelled"
 	AlertDeletionCompleted     = "Deletion Completed"
 	AlertDeletionConfirmed     = "Deletion Confirmed"
@@ -35,7 +40,21 @@ const (
 	AlgSha256                  = "sha256"
 	AlgSha512                  = "sha512"
 	APIUserHeader              = "X-Pharos-API-User"
+	AuditAccessDenied          = "Access Denied"
+	AuditAccountLocked         = "Account Locked"
+	AuditAccountUnlocked       = "Account Unlocked"
+	AuditAPIAuthentication     = "API Authentication"
+	AuditPasswordChanged       = "Password Changed"
+	AuditPasswordReset         = "Password Reset"
+	AuditPasswordResetRequest  = "Password Reset Requested"
+	AuditRoleChanged           = "Role Changed"
+	AuditSecondFactor          = "Second Factor"
+	AuditSignIn                = "Sign In"
+	AuditSignInBlocked         = "Sign In Blocked"
+	AuditSignOut               = "Sign Out"
 	APIKeyHeader               = "X-Pharos-API-Key"
+	APIKeyScopeReadOnly        = "read_only"
+	APIKeyScopeReadWrite       = "read_write"
 	APIPrefixAdmin             = "/admin-api/"
 	APIPrefixMember            = "/member-api/"
 	BTRProfileIdentifier       = "https://github.com/dpscollaborative/btr_bagit_profile/releases/download/1.0/btr-bagit-profile.json"
@@ -61,6 +80,13 @@ const (
 	EventSignatureValidation   = "digital signature validation"
 	EventValidation            = "validation"
 	EventVirusCheck            = "virus check"
+	FileAdded                  = "added"
+	FileRemoved                = "removed"
+	FileReplaced               = "replaced"
+	FormatRiskObsolete         = "obsolete"
+	FormatRiskOpen             = "open"
+	FormatRiskProprietary      = "proprietary"
+	FormatRiskUnknown          = "unknown"
 	IngestPreFetch             = "ingest01_prefetch"
 	IngestValidation           = "ingest02_bag_validation"
 	IngestReingestCheck        = "ingest03_reingest_check"
@@ -72,17 +98,28 @@ const (
 	IngestCleanup              = "ingest09_cleanup"
 	InstTypeMember             = "MemberInstitution"
 	InstTypeSubscriber         = "SubscriptionInstitution"
-	MetaSpotTestsRunning       = "spot restore is running"
-	MetaSpotTestsLastRun       = "spot restore last run"
+	JobTriggerCatchUp          = "Catch Up"
+	JobTriggerManual           = "Manual"
+	JobTriggerSchedule         = "Schedule"
 	OutcomeFailure             = "Failure"
 	OutcomeSuccess             = "Success"
+	ReplicationDuplicateCopy   = "Duplicate Copy"
+	ReplicationMissingCopy     = "Missing Copy"
+	ReplicationUnexpectedCopy  = "Unexpected Bucket"
 	RoleInstAdmin              = "institutional_admin"
 	RoleInstUser               = "institutional_user"
 	RoleNone                   = "none"
 	RoleSysAdmin               = "admin"
+	SearchTypeEvent            = "event"
+	SearchTypeFile             = "file"
+	SearchTypeObject           = "object"
 	SecondFactorAuthy          = "Authy"
 	SecondFactorBackupCode     = "Backup Code"
 	SecondFactorSMS            = "SMS"
+	SecondFactorWebAuthn       = "WebAuthn"
+	SSOCookieName              = "sso_session"
+	SSOProtocolOIDC            = "oidc"
+	SSOProtocolSAML            = "saml"
 	StageAvailableInS3         = "Available in S3"
 	StageCleanup               = "Cleanup"
 	StageCopyToStaging         = "Copy To Staging"
@@ -130,6 +167,15 @@ const (
 	TwoFactorAuthy             = "onetouch"
 	TwoFactorNone              = "none"
 	TwoFactorSMS               = "sms"
+	TwoFactorTOTP              = "totp"
+	TwoFactorWebAuthn          = "webauthn"
+	WebAuthnCookieName         = "webauthn_session"
+	WebhookDeletionRequest     = "deletion_request.updated"
+	WebhookAlertCreated        = "alert.created"
+	WebhookWorkItemCompleted   = "work_item.completed"
+	WebhookSignatureHeader     = "X-Registry-Signature"
+	WebhookEventHeader         = "X-Registry-Event"
+	WebhookDeliveryHeader      = "X-Registry-Delivery"
 )
 
 var AccessSettings = []string{
@@ -138,7 +184,39 @@ var AccessSettings = []string{
 	AccessRestricted,
 }
 
+// APIKeyScopes lists the valid scopes for an API key. Read-only keys
+// can make only GET and HEAD requests.
+var APIKeyScopes = []string{
+	APIKeyScopeReadOnly,
+	APIKeyScopeReadWrite,
+}
+
+// AuditActions lists the kinds of events we record in the security
+// audit log. See pgmodels.AuditEvent.
+var AuditActions = []string{
+	AuditAccessDenied,
+	AuditAccountLocked,
+	AuditAccountUnlocked,
+	AuditAPIAuthentication,
+	AuditPasswordChanged,
+	AuditPasswordReset,
+	AuditPasswordResetRequest,
+	AuditRoleChanged,
+	AuditSecondFactor,
+	AuditSignIn,
+	AuditSignInBlocked,
+	AuditSignOut,
+}
+
+// SSOProtocols lists the single sign-on protocols an institution's
+// identity provider may use.
+var SSOProtocols = []string{
+	SSOProtocolOIDC,
+	SSOProtocolSAML,
+}
+
 var AlertTypes = []string{
+	AlertAccountLocked,
 	AlertDeletionCancelled,
 	AlertDeletionCompleted,
 	AlertDeletionConfirmed,
@@ -151,6 +229,25 @@ var AlertTypes = []string{
 	AlertWelcome,
 }
 
+// AlertDeliveryModes lists the ways a user can choose to receive
+// alerts of a given type. See User.AlertPreferences.
+var AlertDeliveryModes = []string{
+	AlertDeliveryImmediate,
+	AlertDeliveryDaily,
+	AlertDeliveryWeekly,
+	AlertDeliveryInApp,
+}
+
+// ImmediateAlertTypes are always emailed as soon as they're created,
+// regardless of user preferences, because they contain time-sensitive
+// links or security information.
+var ImmediateAlertTypes = []string{
+	AlertAccountLocked,
+	AlertPasswordChanged,
+	AlertPasswordReset,
+	AlertWelcome,
+}
+
 var APIPrefixes = []string{
 	APIPrefixAdmin,
 	APIPrefixMember,
@@ -186,6 +283,24 @@ var EventTypes = []string{
 	EventValidation,
 }
 
+// FormatRiskLevels lists the preservation risk levels an admin can
+// assign to a file format, from lowest risk to highest. See
+// pgmodels.FormatRisk.
+var FormatRiskLevels = []string{
+	FormatRiskOpen,
+	FormatRiskProprietary,
+	FormatRiskObsolete,
+}
+
+// FormatRiskReportLevels lists the risk levels in the format risk
+// report. Formats that aren't in the format registry are unknown.
+var FormatRiskReportLevels = []string{
+	FormatRiskOpen,
+	FormatRiskProprietary,
+	FormatRiskObsolete,
+	FormatRiskUnknown,
+}
+
 var GlacierOnlyOptions = []string{
 	StorageOptionGlacierDeepOH,
 	StorageOptionGlacierDeepOR,
@@ -217,6 +332,21 @@ var InstTypes = []string{
 	InstTypeSubscriber,
 }
 
+var JobTriggers = []string{
+	JobTriggerCatchUp,
+	JobTriggerManual,
+	JobTriggerSchedule,
+}
+
+// ReplicationProblems lists the problems the storage replication
+// check can find with a file's storage records. See
+// pgmodels.ReplicationProblem.
+var ReplicationProblems = []string{
+	ReplicationDuplicateCopy,
+	ReplicationMissingCopy,
+	ReplicationUnexpectedCopy,
+}
+
 var Roles = []string{
 	RoleInstAdmin,
 	RoleInstUser,
@@ -224,10 +354,19 @@ var Roles = []string{
 	RoleSysAdmin,
 }
 
+// SearchTypes lists the kinds of records full-text search returns,
+// in the order results of equal rank appear. See pgmodels.Search.
+var SearchTypes = []string{
+	SearchTypeObject,
+	SearchTypeFile,
+	SearchTypeEvent,
+}
+
 var SecondFactorTypes = []string{
 	SecondFactorAuthy,
 	SecondFactorBackupCode,
 	SecondFactorSMS,
+	SecondFactorWebAuthn,
 }
 
 var Stages = []string{
@@ -287,8 +426,16 @@ var UserActions = []string{
 	ActionRestoreFile,
 }
 
+// WebhookEvents lists the events institutions can subscribe to.
+var WebhookEvents = []string{
+	WebhookAlertCreated,
+	WebhookDeletionRequest,
+	WebhookWorkItemCompleted,
+}
+
 var WorkItemActions = []string{
 	ActionDelete,
+	ActionFixityCheck,
 	ActionGlacierRestore,
 	ActionIngest,
 	ActionRestoreFile,
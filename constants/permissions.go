@@ -20,6 +20,7 @@ const (
 	AlertDelete                        = "AlertDelete"
 	AlertRead                          = "AlertRead"
 	AlertUpdate                        = "AlertUpdate"
+	AuditEventRead                     = "AuditEventRead"
 	BillingReportShow                  = "BillingReportShow"
 	ChecksumCreate                     = "ChecksumCreate"
 	ChecksumDelete                     = "ChecksumDelete"
@@ -39,8 +40,15 @@ const (
 	FileFinishBulkDelete               = "FileFinishBulkDelete"
 	FileRead                           = "FileRead"
 	FileRequestDelete                  = "FileRequestDelete"
+	FileRequestFixity                  = "FileRequestFixity"
 	FileRestore                        = "FileRestore"
 	FileUpdate                         = "FileUpdate"
+	FixityReportShow                   = "FixityReportShow"
+	FormatRiskCreate                   = "FormatRiskCreate"
+	FormatRiskDelete                   = "FormatRiskDelete"
+	FormatRiskRead                     = "FormatRiskRead"
+	FormatRiskReportShow               = "FormatRiskReportShow"
+	FormatRiskUpdate                   = "FormatRiskUpdate"
 	InstitutionCreate                  = "InstitutionCreate"
 	InstitutionDelete                  = "InstitutionDelete"
 	InstitutionList                    = "InstitutionList"
@@ -52,15 +60,29 @@ const (
 	IntellectualObjectFinishBulkDelete = "IntellectualObjectFinishBulkDelete"
 	IntellectualObjectRead             = "IntellectualObjectRead"
 	IntellectualObjectRequestDelete    = "IntellectualObjectRequestDelete"
+	IntellectualObjectRequestFixity    = "IntellectualObjectRequestFixity"
 	IntellectualObjectRestore          = "IntellectualObjectRestore"
 	IntellectualObjectUpdate           = "IntellectualObjectUpdate"
 	InternalMetadataRead               = "InternalMetadataRead"
+	InvoiceRead                        = "InvoiceRead"
+	JobRead                            = "JobRead"
+	JobRun                             = "JobRun"
 	NsqAdmin                           = "NsqAdmin"
 	PrepareFileDelete                  = "PrepareFileDelete"
 	PrepareObjectDelete                = "PrepareObjectDelete"
+	ReplicationReportShow              = "ReplicationReportShow"
 	ReportRead                         = "ReportRead"
 	RedisList                          = "RedisList"
 	RedisRead                          = "RedisRead"
+	RestorationBatchRead               = "RestorationBatchRead"
+	SavedSearchCreate                  = "SavedSearchCreate"
+	SavedSearchDelete                  = "SavedSearchDelete"
+	SavedSearchRead                    = "SavedSearchRead"
+	SavedSearchUpdate                  = "SavedSearchUpdate"
+	SearchRead                         = "SearchRead"
+	StoragePriceCreate                 = "StoragePriceCreate"
+	StoragePriceDelete                 = "StoragePriceDelete"
+	StoragePriceRead                   = "StoragePriceRead"
 	StorageRecordCreate                = "StorageRecordCreate"
 	StorageRecordDelete                = "StorageRecordDelete"
 	StorageRecordRead                  = "StorageRecordRead"
@@ -84,6 +106,10 @@ const (
 	UserTwoFactorVerify                = "UserTwoFactorVerify"
 	UserUpdate                         = "UserUpdate"
 	UserUpdateSelf                     = "UserUpdateSelf"
+	WebhookCreate                      = "WebhookCreate"
+	WebhookDelete                      = "WebhookDelete"
+	WebhookRead                        = "WebhookRead"
+	WebhookUpdate                      = "WebhookUpdate"
 	WorkItemCreate                     = "WorkItemCreate"
 	WorkItemDelete                     = "WorkItemDelete"
 	WorkItemRead                       = "WorkItemRead"
@@ -97,6 +123,7 @@ var Permissions = []Permission{
 	AlertDelete,
 	AlertRead,
 	AlertUpdate,
+	AuditEventRead,
 	BillingReportShow,
 	ChecksumCreate,
 	ChecksumDelete,
@@ -116,8 +143,15 @@ var Permissions = []Permission{
 	FileFinishBulkDelete,
 	FileRead,
 	FileRequestDelete,
+	FileRequestFixity,
 	FileRestore,
 	FileUpdate,
+	FixityReportShow,
+	FormatRiskCreate,
+	FormatRiskDelete,
+	FormatRiskRead,
+	FormatRiskReportShow,
+	FormatRiskUpdate,
 	InstitutionCreate,
 	InstitutionDelete,
 	InstitutionList,
@@ -129,15 +163,29 @@ var Permissions = []Permission{
 	IntellectualObjectFinishBulkDelete,
 	IntellectualObjectRead,
 	IntellectualObjectRequestDelete,
+	IntellectualObjectRequestFixity,
 	IntellectualObjectRestore,
 	IntellectualObjectUpdate,
 	InternalMetadataRead,
+	InvoiceRead,
+	JobRead,
+	JobRun,
 	NsqAdmin,
 	PrepareFileDelete,
 	PrepareObjectDelete,
+	ReplicationReportShow,
 	ReportRead,
 	RedisList,
 	RedisRead,
+	RestorationBatchRead,
+	SavedSearchCreate,
+	SavedSearchDelete,
+	SavedSearchRead,
+	SavedSearchUpdate,
+	SearchRead,
+	StoragePriceCreate,
+	StoragePriceDelete,
+	StoragePriceRead,
 	StorageRecordCreate,
 	StorageRecordDelete,
 	StorageRecordRead,
@@ -161,6 +209,10 @@ var Permissions = []Permission{
 	UserTwoFactorVerify,
 	UserUpdate,
 	UserUpdateSelf,
+	WebhookCreate,
+	WebhookDelete,
+	WebhookRead,
+	WebhookUpdate,
 	WorkItemCreate,
 	WorkItemDelete,
 	WorkItemRead,
@@ -216,10 +268,19 @@ func initPermissions() {
 	instUser[EventRead] = true
 	instUser[FileRead] = true
 	instUser[FileRestore] = true
+	instUser[FixityReportShow] = true
+	instUser[FormatRiskReportShow] = true
 	instUser[InstitutionRead] = true
 	instUser[IntellectualObjectRead] = true
 	instUser[IntellectualObjectRestore] = true
+	instUser[ReplicationReportShow] = true
 	instUser[ReportRead] = true
+	instUser[RestorationBatchRead] = true
+	instUser[SavedSearchCreate] = true
+	instUser[SavedSearchDelete] = true
+	instUser[SavedSearchRead] = true
+	instUser[SavedSearchUpdate] = true
+	instUser[SearchRead] = true
 	instUser[StorageRecordRead] = true
 	instUser[UserComplete2FASetup] = true
 	instUser[UserConfirmPhone] = true
@@ -240,6 +301,7 @@ func initPermissions() {
 	// Institutional Admin Role
 	instAdmin[AlertRead] = true
 	instAdmin[AlertUpdate] = true
+	instAdmin[AuditEventRead] = true
 	instAdmin[ChecksumRead] = true
 	instAdmin[DashboardShow] = true
 	instAdmin[DeletionRequestApprove] = true
@@ -250,14 +312,26 @@ func initPermissions() {
 	instAdmin[FileDelete] = true
 	instAdmin[FileRead] = true
 	instAdmin[FileRequestDelete] = true
+	instAdmin[FileRequestFixity] = true
 	instAdmin[FileRestore] = true
+	instAdmin[FixityReportShow] = true
+	instAdmin[FormatRiskReportShow] = true
 	instAdmin[InstitutionRead] = true
 	instAdmin[InstitutionUpdatePrefs] = true
 	instAdmin[IntellectualObjectDelete] = true
 	instAdmin[IntellectualObjectRead] = true
 	instAdmin[IntellectualObjectRequestDelete] = true
+	instAdmin[IntellectualObjectRequestFixity] = true
 	instAdmin[IntellectualObjectRestore] = true
+	instAdmin[InvoiceRead] = true
+	instAdmin[ReplicationReportShow] = true
 	instAdmin[ReportRead] = true
+	instAdmin[RestorationBatchRead] = true
+	instAdmin[SavedSearchCreate] = true
+	instAdmin[SavedSearchDelete] = true
+	instAdmin[SavedSearchRead] = true
+	instAdmin[SavedSearchUpdate] = true
+	instAdmin[SearchRead] = true
 	instAdmin[StorageRecordRead] = true
 	instAdmin[UserComplete2FASetup] = true
 	instAdmin[UserConfirmPhone] = true
@@ -277,6 +351,10 @@ func initPermissions() {
 	instAdmin[UserTwoFactorVerify] = true
 	instAdmin[UserUpdateSelf] = true
 	instAdmin[UserUpdate] = true
+	instAdmin[WebhookCreate] = true
+	instAdmin[WebhookDelete] = true
+	instAdmin[WebhookRead] = true
+	instAdmin[WebhookUpdate] = true
 	instAdmin[WorkItemRead] = true
 
 	// Sys Admin Role
@@ -284,6 +362,7 @@ func initPermissions() {
 	sysAdmin[AlertDelete] = true
 	sysAdmin[AlertRead] = true
 	sysAdmin[AlertUpdate] = true
+	sysAdmin[AuditEventRead] = true
 	sysAdmin[BillingReportShow] = true
 	sysAdmin[ChecksumCreate] = true
 	sysAdmin[ChecksumDelete] = false // no one can do this
@@ -303,8 +382,15 @@ func initPermissions() {
 	sysAdmin[FileFinishBulkDelete] = true // not implemented yet
 	sysAdmin[FileRead] = true
 	sysAdmin[FileRequestDelete] = false
+	sysAdmin[FileRequestFixity] = true
 	sysAdmin[FileRestore] = true
 	sysAdmin[FileUpdate] = true
+	sysAdmin[FixityReportShow] = true
+	sysAdmin[FormatRiskCreate] = true
+	sysAdmin[FormatRiskDelete] = true
+	sysAdmin[FormatRiskRead] = true
+	sysAdmin[FormatRiskReportShow] = true
+	sysAdmin[FormatRiskUpdate] = true
 	sysAdmin[InstitutionCreate] = true
 	sysAdmin[InstitutionDelete] = true
 	sysAdmin[InstitutionList] = true
@@ -316,15 +402,29 @@ func initPermissions() {
 	sysAdmin[IntellectualObjectFinishBulkDelete] = true // not implemented yet
 	sysAdmin[IntellectualObjectRead] = true
 	sysAdmin[IntellectualObjectRequestDelete] = false // inst admin only
+	sysAdmin[IntellectualObjectRequestFixity] = true
 	sysAdmin[IntellectualObjectRestore] = true
 	sysAdmin[IntellectualObjectUpdate] = true
 	sysAdmin[InternalMetadataRead] = true
+	sysAdmin[InvoiceRead] = true
+	sysAdmin[JobRead] = true
+	sysAdmin[JobRun] = true
 	sysAdmin[NsqAdmin] = true
 	sysAdmin[PrepareFileDelete] = true
 	sysAdmin[PrepareObjectDelete] = true
+	sysAdmin[ReplicationReportShow] = true
 	sysAdmin[ReportRead] = true
 	sysAdmin[RedisList] = true
 	sysAdmin[RedisRead] = true
+	sysAdmin[RestorationBatchRead] = true
+	sysAdmin[SavedSearchCreate] = true
+	sysAdmin[SavedSearchDelete] = true
+	sysAdmin[SavedSearchRead] = true
+	sysAdmin[SavedSearchUpdate] = true
+	sysAdmin[SearchRead] = true
+	sysAdmin[StoragePriceCreate] = true
+	sysAdmin[StoragePriceDelete] = true
+	sysAdmin[StoragePriceRead] = true
 	sysAdmin[StorageRecordCreate] = true
 	sysAdmin[StorageRecordDelete] = true
 	sysAdmin[StorageRecordRead] = true
@@ -348,6 +448,10 @@ func initPermissions() {
 	sysAdmin[UserTwoFactorVerify] = true
 	sysAdmin[UserUpdateSelf] = true
 	sysAdmin[UserUpdate] = true
+	sysAdmin[WebhookCreate] = true
+	sysAdmin[WebhookDelete] = true
+	sysAdmin[WebhookRead] = true
+	sysAdmin[WebhookUpdate] = true
 	sysAdmin[WorkItemCreate] = true
 	sysAdmin[WorkItemDelete] = true
 	sysAdmin[WorkItemRead] = true
@@ -0,0 +1,33 @@
+-- 011_job_runs.sql
+-- 
+-- This migration adds the job_runs table, which records the
+-- history of jobs run by Registry's job scheduler. Each row
+-- records when a job started and finished, how long it took,
+-- which host ran it, and the error, if any.
+--
+-- The unique index on job_name and scheduled_for ensures that
+-- when multiple Registry containers wake up for the same scheduled
+-- run, only one of them actually runs the job. Manual runs have
+-- a null scheduled_for, so they're not constrained by this index.
+
+-- Note that we're starting the migration.
+insert into schema_migrations ("version", started_at) values ('011_job_runs', now())
+on conflict ("version") do update set started_at = now();
+
+create table if not exists job_runs (
+	id bigserial NOT NULL,
+	job_name varchar NOT NULL,
+	"trigger" varchar NOT NULL,
+	host varchar NULL,
+	scheduled_for timestamp NULL,
+	started_at timestamp NOT NULL,
+	finished_at timestamp NULL,
+	duration_ms int8 NULL,
+	error_message text NULL,
+	CONSTRAINT job_runs_pkey PRIMARY KEY (id)
+);
+create index if not exists ix_job_runs_job_name_started_at on public.job_runs using btree (job_name, started_at desc);
+create unique index if not exists ix_job_runs_uniq_job_name_scheduled_for on public.job_runs using btree (job_name, scheduled_for);
+
+-- Now note that the migration is complete.
+update schema_migrations set finished_at = now() where "version" = '011_job_runs';
@@ -0,0 +1,34 @@
+-- 012_bulk_deletion.sql
+-- 
+-- This migration adds the deletion_requests_work_items table to
+-- support bulk deletion requests.
+--
+-- A single-item deletion request creates one WorkItem when it's
+-- approved, and records it in deletion_requests.work_item_id.
+-- A bulk deletion request covering many objects or files creates
+-- one WorkItem per item. This table links each of those WorkItems
+-- back to the request, so preservation services can verify that
+-- each deletion was approved.
+--
+-- We also backfill this table from existing deletion requests,
+-- so all approved deletions can be found the same way.
+
+-- Note that we're starting the migration.
+insert into schema_migrations ("version", started_at) values ('012_bulk_deletion', now())
+on conflict ("version") do update set started_at = now();
+
+create table if not exists deletion_requests_work_items (
+	deletion_request_id int4 NOT NULL,
+	work_item_id int4 NOT NULL,
+	CONSTRAINT deletion_requests_work_items_deletion_request_id_fkey FOREIGN KEY (deletion_request_id) REFERENCES deletion_requests(id),
+	CONSTRAINT deletion_requests_work_items_work_item_id_fkey FOREIGN KEY (work_item_id) REFERENCES work_items(id)
+);
+create unique index if not exists index_drwi_unique on public.deletion_requests_work_items using btree (deletion_request_id, work_item_id);
+create index if not exists index_drwi_work_item_id on public.deletion_requests_work_items using btree (work_item_id);
+
+insert into deletion_requests_work_items (deletion_request_id, work_item_id)
+select id, work_item_id from deletion_requests where work_item_id is not null
+on conflict do nothing;
+
+-- Now note that the migration is complete.
+update schema_migrations set finished_at = now() where "version" = '012_bulk_deletion';
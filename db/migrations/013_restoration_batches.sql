@@ -0,0 +1,41 @@
+-- 013_restoration_batches.sql
+-- 
+-- This migration adds the restoration_batches table and its join
+-- table, restoration_batches_work_items, to support batch restoration.
+--
+-- When a user asks to restore many objects at once, Registry creates
+-- one restoration WorkItem per object, plus a single restoration batch
+-- record that links to all of them. The batch lets the user track
+-- overall progress, and lets us send a single Restoration Completed
+-- alert when every item in the batch is done, instead of one per item.
+--
+-- completed_at is set once, when the last item in the batch finishes.
+
+-- Note that we're starting the migration.
+insert into schema_migrations ("version", started_at) values ('013_restoration_batches', now())
+on conflict ("version") do update set started_at = now();
+
+create table if not exists restoration_batches (
+	id bigserial NOT NULL,
+	institution_id int4 NOT NULL,
+	requested_by_id int4 NOT NULL,
+	created_at timestamp NOT NULL,
+	updated_at timestamp NOT NULL,
+	completed_at timestamp NULL,
+	CONSTRAINT restoration_batches_pkey PRIMARY KEY (id),
+	CONSTRAINT restoration_batches_institution_id_fkey FOREIGN KEY (institution_id) REFERENCES institutions(id),
+	CONSTRAINT restoration_batches_requested_by_id_fkey FOREIGN KEY (requested_by_id) REFERENCES users(id)
+);
+create index if not exists index_restoration_batches_institution_id on public.restoration_batches using btree (institution_id);
+
+create table if not exists restoration_batches_work_items (
+	restoration_batch_id int4 NOT NULL,
+	work_item_id int4 NOT NULL,
+	CONSTRAINT restoration_batches_work_items_restoration_batch_id_fkey FOREIGN KEY (restoration_batch_id) REFERENCES restoration_batches(id),
+	CONSTRAINT restoration_batches_work_items_work_item_id_fkey FOREIGN KEY (work_item_id) REFERENCES work_items(id)
+);
+create unique index if not exists index_rbwi_unique on public.restoration_batches_work_items using btree (restoration_batch_id, work_item_id);
+create index if not exists index_rbwi_work_item_id on public.restoration_batches_work_items using btree (work_item_id);
+
+-- Now note that the migration is complete.
+update schema_migrations set finished_at = now() where "version" = '013_restoration_batches';
@@ -0,0 +1,57 @@
+-- 014_webhooks.sql
+--
+-- This migration adds the webhooks and webhook_deliveries tables.
+--
+-- Institutions can register webhook endpoints to hear about ingest
+-- and restoration completion, new alerts, and deletion request status
+-- changes without waiting for email. Each webhook has a URL, a secret
+-- for signing payloads, and a list of the event types it wants.
+--
+-- Each event sent to each webhook gets a row in webhook_deliveries,
+-- which records the payload, the number of attempts, and the result
+-- of the last attempt. Failed deliveries are retried with backoff.
+-- event_key identifies the event (e.g. work_item/1234/Success), and
+-- the unique index on webhook_id + event_key ensures we send each
+-- event to each webhook only once, even if the underlying record is
+-- saved many times.
+
+-- Note that we're starting the migration.
+insert into schema_migrations ("version", started_at) values ('014_webhooks', now())
+on conflict ("version") do update set started_at = now();
+
+create table if not exists webhooks (
+	id bigserial NOT NULL,
+	institution_id int4 NOT NULL,
+	url varchar NOT NULL,
+	secret varchar NOT NULL,
+	event_types _varchar NOT NULL DEFAULT '{}'::character varying[],
+	enabled bool NOT NULL DEFAULT true,
+	created_at timestamp NOT NULL,
+	updated_at timestamp NOT NULL,
+	CONSTRAINT webhooks_pkey PRIMARY KEY (id),
+	CONSTRAINT webhooks_institution_id_fkey FOREIGN KEY (institution_id) REFERENCES institutions(id)
+);
+create index if not exists index_webhooks_institution_id on public.webhooks using btree (institution_id);
+
+create table if not exists webhook_deliveries (
+	id bigserial NOT NULL,
+	webhook_id int4 NOT NULL,
+	event_type varchar NOT NULL,
+	event_key varchar NOT NULL,
+	payload text NOT NULL,
+	status varchar NOT NULL,
+	attempts int4 NOT NULL DEFAULT 0,
+	next_attempt_at timestamp NULL,
+	last_attempt_at timestamp NULL,
+	response_code int4 NOT NULL DEFAULT 0,
+	error_message text NULL,
+	created_at timestamp NOT NULL,
+	updated_at timestamp NOT NULL,
+	CONSTRAINT webhook_deliveries_pkey PRIMARY KEY (id),
+	CONSTRAINT webhook_deliveries_webhook_id_fkey FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE
+);
+create unique index if not exists index_webhook_deliveries_event on public.webhook_deliveries using btree (webhook_id, event_key);
+create index if not exists index_webhook_deliveries_next_attempt_at on public.webhook_deliveries using btree (next_attempt_at) where (status = 'Pending');
+
+-- Now note that the migration is complete.
+update schema_migrations set finished_at = now() where "version" = '014_webhooks';
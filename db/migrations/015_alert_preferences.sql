@@ -0,0 +1,20 @@
+-- 015_alert_preferences.sql
+--
+-- This migration adds users.alert_preferences, which records how each
+-- user wants to hear about each type of alert: an immediate email,
+-- a daily or weekly digest, or in the Registry only.
+--
+-- The column is a JSON object mapping alert type to delivery mode,
+-- e.g. {"Deletion Completed": "daily"}. Alert types that aren't in
+-- the map are sent immediately, which was the only option before
+-- this migration, so existing users see no change until they edit
+-- their preferences.
+
+-- Note that we're starting the migration.
+insert into schema_migrations ("version", started_at) values ('015_alert_preferences', now())
+on conflict ("version") do update set started_at = now();
+
+alter table users add column if not exists alert_preferences jsonb not null default '{}'::jsonb;
+
+-- Now note that the migration is complete.
+update schema_migrations set finished_at = now() where "version" = '015_alert_preferences';
@@ -0,0 +1,45 @@
+-- 016_api_keys.sql
+--
+-- This migration adds the api_keys table.
+--
+-- Until now, each user had a single API key, stored in
+-- users.encrypted_api_secret_key, and getting a new key replaced the
+-- old one. The api_keys table lets each user hold several named keys
+-- (e.g. one for CI, one for reporting scripts), each with its own
+-- optional expiration date, scope and permission list. Keys can be
+-- revoked individually, so users can rotate keys by creating the new
+-- one, updating their clients, then revoking the old one.
+--
+-- We store only a bcrypt hash of each key. key_prefix holds the first
+-- few characters of the plaintext key, so we can find the key's row
+-- without comparing the hash of every key the user has, and so users
+-- can tell their keys apart on the My Account page.
+--
+-- The legacy users.encrypted_api_secret_key still works, so existing
+-- clients don't break when this is deployed.
+
+-- Note that we're starting the migration.
+insert into schema_migrations ("version", started_at) values ('016_api_keys', now())
+on conflict ("version") do update set started_at = now();
+
+create table if not exists api_keys (
+	id bigserial NOT NULL,
+	user_id int4 NOT NULL,
+	"name" varchar NOT NULL,
+	key_prefix varchar NOT NULL,
+	encrypted_key varchar NOT NULL,
+	"scope" varchar NOT NULL,
+	permissions _varchar NOT NULL DEFAULT '{}'::character varying[],
+	expires_at timestamp NULL,
+	last_used_at timestamp NULL,
+	last_used_ip varchar NULL,
+	revoked_at timestamp NULL,
+	created_at timestamp NOT NULL,
+	updated_at timestamp NOT NULL,
+	CONSTRAINT api_keys_pkey PRIMARY KEY (id),
+	CONSTRAINT api_keys_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id)
+);
+create index if not exists index_api_keys_user_id_key_prefix on public.api_keys using btree (user_id, key_prefix);
+
+-- Now note that the migration is complete.
+update schema_migrations set finished_at = now() where "version" = '016_api_keys';
@@ -0,0 +1,21 @@
+-- 017_totp.sql
+--
+-- This migration adds users.encrypted_totp_secret, which holds the
+-- secret a user's authenticator app uses to generate RFC 6238 TOTP
+-- codes. The value is the otpauth:// provisioning URI, encrypted
+-- with AES-GCM using TOTP_ENCRYPTION_KEY. Unlike SMS codes and backup
+-- codes, which we hash, this must be recoverable, because we need
+-- the secret to check the codes users enter.
+--
+-- We reuse the legacy Devise column users.consumed_timestep to record
+-- the last time step for which a user entered a valid code, so each
+-- code can be used only once.
+
+-- Note that we're starting the migration.
+insert into schema_migrations ("version", started_at) values ('017_totp', now())
+on conflict ("version") do update set started_at = now();
+
+alter table users add column if not exists encrypted_totp_secret varchar null;
+
+-- Now note that the migration is complete.
+update schema_migrations set finished_at = now() where "version" = '017_totp';
@@ -0,0 +1,44 @@
+-- 018_webauthn_credentials.sql
+--
+-- This migration adds the webauthn_credentials table, which holds the
+-- hardware security keys and platform passkeys users register as a
+-- second factor.
+--
+-- Unlike SMS, Authy and authenticator app codes, WebAuthn credentials
+-- are bound to the Registry's domain, so they can't be phished. Users
+-- can register several (e.g. a YubiKey and a laptop passkey), name
+-- them, and revoke them individually.
+--
+-- credential_id is the authenticator's credential ID, base64url
+-- encoded. public_key is the COSE-encoded public key we use to verify
+-- signatures. sign_count is the authenticator's signature counter,
+-- which should increase with each use. If it doesn't, the key may have
+-- been cloned, and we set clone_warning.
+
+-- Note that we're starting the migration.
+insert into schema_migrations ("version", started_at) values ('018_webauthn_credentials', now())
+on conflict ("version") do update set started_at = now();
+
+create table if not exists webauthn_credentials (
+	id bigserial NOT NULL,
+	user_id int4 NOT NULL,
+	"name" varchar NOT NULL,
+	credential_id varchar NOT NULL,
+	public_key bytea NOT NULL,
+	attestation_type varchar NULL,
+	transports _varchar NOT NULL DEFAULT '{}'::character varying[],
+	aaguid bytea NULL,
+	sign_count int8 NOT NULL DEFAULT 0,
+	clone_warning bool NOT NULL DEFAULT false,
+	last_used_at timestamp NULL,
+	revoked_at timestamp NULL,
+	created_at timestamp NOT NULL,
+	updated_at timestamp NOT NULL,
+	CONSTRAINT webauthn_credentials_pkey PRIMARY KEY (id),
+	CONSTRAINT webauthn_credentials_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id)
+);
+create index if not exists index_webauthn_credentials_user_id on public.webauthn_credentials using btree (user_id);
+create unique index if not exists index_webauthn_credentials_credential_id on public.webauthn_credentials using btree (credential_id);
+
+-- Now note that the migration is complete.
+update schema_migrations set finished_at = now() where "version" = '018_webauthn_credentials';
@@ -0,0 +1,47 @@
+-- 019_identity_providers.sql
+--
+-- This migration adds the identity_providers table, which lets an
+-- institution sign its users in through its own SAML 2.0 or OpenID
+-- Connect identity provider (IdP) instead of Registry passwords.
+--
+-- Each institution has at most one IdP. For OIDC, we discover the
+-- provider's endpoints from issuer_url, and authenticate to it with
+-- client_id and encrypted_client_secret. The secret is encrypted with
+-- SSO_ENCRYPTION_KEY. For SAML, metadata_xml is the IdP's metadata,
+-- which includes its login URL and signing certificate.
+--
+-- When the IdP signs a user in, we match the user to an existing
+-- Registry account at the same institution by email_attribute. If
+-- role_attribute is set, users whose role_attribute includes one of
+-- admin_role_values become institutional admins, and everyone else
+-- becomes an institutional user.
+--
+-- If required is true, the institution's users can no longer sign in
+-- with a Registry password.
+
+-- Note that we're starting the migration.
+insert into schema_migrations ("version", started_at) values ('019_identity_providers', now())
+on conflict ("version") do update set started_at = now();
+
+create table if not exists identity_providers (
+	id bigserial NOT NULL,
+	institution_id int4 NOT NULL,
+	protocol varchar NOT NULL,
+	enabled bool NOT NULL DEFAULT false,
+	required bool NOT NULL DEFAULT false,
+	issuer_url varchar NULL,
+	client_id varchar NULL,
+	encrypted_client_secret varchar NULL,
+	metadata_xml text NULL,
+	email_attribute varchar NOT NULL,
+	role_attribute varchar NULL,
+	admin_role_values _varchar NOT NULL DEFAULT '{}'::character varying[],
+	created_at timestamp NOT NULL,
+	updated_at timestamp NOT NULL,
+	CONSTRAINT identity_providers_pkey PRIMARY KEY (id),
+	CONSTRAINT identity_providers_institution_id_fkey FOREIGN KEY (institution_id) REFERENCES institutions(id)
+);
+create unique index if not exists index_identity_providers_institution_id on public.identity_providers using btree (institution_id);
+
+-- Now note that the migration is complete.
+update schema_migrations set finished_at = now() where "version" = '019_identity_providers';
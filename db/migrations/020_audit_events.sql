@@ -0,0 +1,60 @@
+-- 020_audit_events.sql
+--
+-- This migration adds the audit_events table, a security audit log of
+-- authentication and authorization events: sign-ins, failed logins,
+-- two-factor failures, API key use, password resets, role changes and
+-- requests denied by the authorization middleware.
+--
+-- The table is append-only. A trigger rejects updates and deletes, so
+-- a compromised application account can't quietly edit the record.
+--
+-- user_id and institution_id are deliberately not foreign keys. We log
+-- failed logins for email addresses that match no user, and we want
+-- the log to outlive the users and institutions it mentions. user_email
+-- records the address as it was at the time of the event.
+--
+-- institution_id is the institution of the account the event is about:
+-- the user signing in, the user whose role or password changed, or the
+-- user who was denied access. Institutional admins see only their own
+-- institution's events.
+
+-- Note that we're starting the migration.
+insert into schema_migrations ("version", started_at) values ('020_audit_events', now())
+on conflict ("version") do update set started_at = now();
+
+create table if not exists audit_events (
+	id bigserial NOT NULL,
+	user_id int4 NULL,
+	user_email varchar NULL,
+	institution_id int4 NULL,
+	"action" varchar NOT NULL,
+	outcome varchar NOT NULL,
+	resource_type varchar NULL,
+	resource_id int8 NULL,
+	detail text NULL,
+	ip_address varchar NULL,
+	user_agent varchar NULL,
+	created_at timestamp NOT NULL,
+	CONSTRAINT audit_events_pkey PRIMARY KEY (id)
+);
+create index if not exists index_audit_events_created_at on public.audit_events using btree (created_at);
+create index if not exists index_audit_events_institution_id on public.audit_events using btree (institution_id, created_at);
+create index if not exists index_audit_events_user_id on public.audit_events using btree (user_id);
+create index if not exists index_audit_events_action on public.audit_events using btree ("action");
+
+create or replace function audit_events_append_only()
+returns trigger
+language plpgsql
+as $$
+begin
+	raise exception 'audit_events is append-only';
+end;
+$$;
+
+drop trigger if exists audit_events_append_only on audit_events;
+create trigger audit_events_append_only
+	before update or delete on audit_events
+	for each row execute procedure audit_events_append_only();
+
+-- Now note that the migration is complete.
+update schema_migrations set finished_at = now() where "version" = '020_audit_events';
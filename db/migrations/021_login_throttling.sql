@@ -0,0 +1,28 @@
+-- 021_login_throttling.sql
+--
+-- This migration adds the columns we need to throttle sign-in attempts
+-- and lock accounts after too many failures.
+--
+-- failed_login_attempts counts consecutive failed password and
+-- two-factor attempts. It goes back to zero when the user signs in
+-- successfully or an admin unlocks the account. last_failed_login_at
+-- is the time of the most recent failure, from which we calculate
+-- the progressive delay before the next attempt. locked_until is set
+-- when failed_login_attempts reaches the limit, and the user can't
+-- sign in until it passes.
+--
+-- Per-IP failure counts come from the audit_events table, which is why
+-- we add an index on ip_address here.
+
+-- Note that we're starting the migration.
+insert into schema_migrations ("version", started_at) values ('021_login_throttling', now())
+on conflict ("version") do update set started_at = now();
+
+alter table users add column if not exists failed_login_attempts int4 not null default 0;
+alter table users add column if not exists last_failed_login_at timestamp null;
+alter table users add column if not exists locked_until timestamp null;
+
+create index if not exists index_audit_events_ip_address on public.audit_events using btree (ip_address, created_at);
+
+-- Now note that the migration is complete.
+update schema_migrations set finished_at = now() where "version" = '021_login_throttling';
@@ -0,0 +1,37 @@
+-- 022_user_sessions.sql
+--
+-- This migration adds the user_sessions table, which tracks each
+-- browser session a user has signed in with.
+--
+-- Until now, the session cookie held the user's id, so the server had
+-- no record of where a user was signed in, and there was no way to end
+-- a session before its cookie expired. The cookie now holds a random
+-- session token. Each request must match an existing session, so
+-- deleting a row signs that browser out.
+--
+-- We store a one-way hash of the token, never the token itself, so a
+-- copy of this table can't be used to hijack sessions.
+--
+-- last_seen_at and ip_address are updated as the session is used, so
+-- users can see where and when each of their sessions was last active.
+
+-- Note that we're starting the migration.
+insert into schema_migrations ("version", started_at) values ('022_user_sessions', now())
+on conflict ("version") do update set started_at = now();
+
+create table if not exists user_sessions (
+	id bigserial NOT NULL,
+	user_id int4 NOT NULL,
+	token_digest varchar NOT NULL,
+	ip_address varchar NULL,
+	user_agent varchar NULL,
+	created_at timestamp NOT NULL,
+	last_seen_at timestamp NOT NULL,
+	CONSTRAINT user_sessions_pkey PRIMARY KEY (id),
+	CONSTRAINT user_sessions_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id)
+);
+create unique index if not exists index_user_sessions_token_digest on public.user_sessions using btree (token_digest);
+create index if not exists index_user_sessions_user_id on public.user_sessions using btree (user_id);
+
+-- Now note that the migration is complete.
+update schema_migrations set finished_at = now() where "version" = '022_user_sessions';
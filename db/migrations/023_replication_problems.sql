@@ -0,0 +1,42 @@
+-- 023_replication_problems.sql
+--
+-- This migration adds the replication_problems table, which holds the
+-- results of the most recent storage replication check.
+--
+-- Each active file's storage records should match its storage option.
+-- Standard files should have one copy in Virginia and one in Oregon,
+-- and files in every other storage option should have exactly one copy
+-- in that option's bucket. The verify_storage_replication job compares
+-- each file's storage records with the buckets in the BUCKET_* settings
+-- and records one row here for each missing copy, duplicate copy, or
+-- copy in an unexpected bucket.
+--
+-- The job replaces the table's contents on each run, so this table
+-- always describes the latest check. identifier and storage_option
+-- are copied from generic_files so the report doesn't need joins.
+
+-- Note that we're starting the migration.
+insert into schema_migrations ("version", started_at) values ('023_replication_problems', now())
+on conflict ("version") do update set started_at = now();
+
+create table if not exists replication_problems (
+	id bigserial NOT NULL,
+	generic_file_id int4 NOT NULL,
+	intellectual_object_id int4 NOT NULL,
+	institution_id int4 NOT NULL,
+	identifier varchar NOT NULL,
+	storage_option varchar NOT NULL,
+	problem varchar NOT NULL,
+	bucket varchar NOT NULL,
+	url varchar NULL,
+	verified_at timestamp NOT NULL,
+	CONSTRAINT replication_problems_pkey PRIMARY KEY (id),
+	CONSTRAINT replication_problems_generic_file_id_fkey FOREIGN KEY (generic_file_id) REFERENCES generic_files(id),
+	CONSTRAINT replication_problems_institution_id_fkey FOREIGN KEY (institution_id) REFERENCES institutions(id)
+);
+create index if not exists index_replication_problems_generic_file_id on public.replication_problems using btree (generic_file_id);
+create index if not exists index_replication_problems_institution_id on public.replication_problems using btree (institution_id);
+create index if not exists index_replication_problems_problem on public.replication_problems using btree (problem);
+
+-- Now note that the migration is complete.
+update schema_migrations set finished_at = now() where "version" = '023_replication_problems';
@@ -0,0 +1,88 @@
+-- 024_invoices.sql
+--
+-- This migration adds monthly invoices for member institutions.
+--
+-- Until now, the billing report hard-coded a 10 TB free allowance, and
+-- finance staff built invoices by hand. institutions.free_allowance_tb
+-- makes the allowance a per-institution setting, defaulting to the old
+-- 10 TB. Sub-accounts share their parent's allowance.
+--
+-- The invoices table holds one invoice per member institution per month.
+-- invoice_number is unique and never changes, so finance can refer to
+-- an invoice long after it's issued. billing_month is the first day of
+-- the month billed.
+--
+-- invoice_line_items holds the invoice's lines. Billed lines have one
+-- storage option's usage for the member and all its sub-accounts, with
+-- the free allowance applied. Breakdown lines show each account's share
+-- of that usage and aren't part of the total. We copy rates and names
+-- into these tables, so invoices don't change when prices or names do.
+
+-- Note that we're starting the migration.
+insert into schema_migrations ("version", started_at) values ('024_invoices', now())
+on conflict ("version") do update set started_at = now();
+
+alter table institutions add column if not exists free_allowance_tb float8 NOT NULL DEFAULT 10.0;
+
+create or replace view institutions_view as
+select i.id,
+	i.name,
+	i.identifier,
+	i.state,
+	i.type,
+	i.deactivated_at,
+	i.otp_enabled,
+	i.receiving_bucket,
+	i.restore_bucket,
+	i.spot_restore_frequency,
+	i.last_spot_restore_work_item_id,
+	i.created_at,
+	i.updated_at,
+	i.member_institution_id as parent_id,
+	parent.name as parent_name,
+	parent.identifier as parent_identifier,
+	parent.state as parent_state,
+	parent.deactivated_at as parent_deactivated_at,
+	i.free_allowance_tb
+from institutions i
+	left join institutions parent on i.member_institution_id = parent.id;
+
+create table if not exists invoices (
+	id bigserial NOT NULL,
+	invoice_number varchar NOT NULL,
+	institution_id int4 NOT NULL,
+	institution_name varchar NOT NULL,
+	billing_month date NOT NULL,
+	free_allowance_tb float8 NOT NULL DEFAULT 0,
+	total_gb float8 NOT NULL DEFAULT 0,
+	total_tb float8 NOT NULL DEFAULT 0,
+	billable_gb float8 NOT NULL DEFAULT 0,
+	amount_due float8 NOT NULL DEFAULT 0,
+	created_at timestamp NOT NULL,
+	updated_at timestamp NOT NULL,
+	CONSTRAINT invoices_pkey PRIMARY KEY (id),
+	CONSTRAINT invoices_institution_id_fkey FOREIGN KEY (institution_id) REFERENCES institutions(id)
+);
+create unique index if not exists index_invoices_invoice_number on public.invoices using btree (invoice_number);
+create unique index if not exists index_invoices_institution_month on public.invoices using btree (institution_id, billing_month);
+
+create table if not exists invoice_line_items (
+	id bigserial NOT NULL,
+	invoice_id int8 NOT NULL,
+	institution_id int4 NOT NULL,
+	institution_name varchar NOT NULL,
+	storage_option varchar NOT NULL,
+	breakdown bool NOT NULL DEFAULT false,
+	total_gb float8 NOT NULL DEFAULT 0,
+	total_tb float8 NOT NULL DEFAULT 0,
+	free_gb float8 NOT NULL DEFAULT 0,
+	billable_gb float8 NOT NULL DEFAULT 0,
+	cost_gb_per_month float8 NOT NULL DEFAULT 0,
+	amount float8 NOT NULL DEFAULT 0,
+	CONSTRAINT invoice_line_items_pkey PRIMARY KEY (id),
+	CONSTRAINT invoice_line_items_invoice_id_fkey FOREIGN KEY (invoice_id) REFERENCES invoices(id)
+);
+create index if not exists index_invoice_line_items_invoice_id on public.invoice_line_items using btree (invoice_id);
+
+-- Now note that the migration is complete.
+update schema_migrations set finished_at = now() where "version" = '024_invoices';
@@ -0,0 +1,272 @@
+-- 025_storage_prices.sql
+--
+-- This migration adds storage_prices, a history of what each storage
+-- option costs per GB per month, with the dates each price was in effect.
+--
+-- Until now, storage_options.cost_gb_per_month held a single price, and
+-- migrations like 009_update_storage_pricing.sql overwrote it and rebuilt
+-- historical_deposit_stats, so every past month was costed at today's
+-- price. Now each price has an effective_from date, and effective_to is
+-- the date the next price for the same option takes effect, or null for
+-- the latest price. Price changes always take effect on the first of a
+-- month, so a month is never split between two prices.
+--
+-- We seed the history with the prices we launched with (priced as of
+-- July 29, 2021), effective from 2014, the start of our deposit stats.
+-- If an option's price has changed since then, as 009_update_storage_pricing
+-- did for Standard, Glacier and Glacier-Deep, its current price takes
+-- effect from the first of the month in which it was set, which is
+-- storage_options.updated_at. Options we added after launch get their
+-- current price, effective from 2014. New prices are scheduled through
+-- the Storage Prices admin page.
+--
+-- storage_price_on() returns the price in effect on a given date. The
+-- functions that populate historical_deposit_stats now use the price
+-- in effect during the month each row covers (the day before its
+-- end_date), and current_deposit_stats uses today's price. The
+-- apply_storage_prices job copies each option's current price into
+-- storage_options.cost_gb_per_month when a scheduled price takes effect.
+
+-- Note that we're starting the migration.
+insert into schema_migrations ("version", started_at) values ('025_storage_prices', now())
+on conflict ("version") do update set started_at = now();
+
+create table if not exists storage_prices (
+	id bigserial NOT NULL,
+	storage_option_id int8 NOT NULL,
+	cost_gb_per_month numeric(12, 8) NOT NULL,
+	effective_from date NOT NULL,
+	effective_to date NULL,
+	"comment" varchar NULL,
+	created_at timestamp NOT NULL,
+	updated_at timestamp NOT NULL,
+	CONSTRAINT storage_prices_pkey PRIMARY KEY (id),
+	CONSTRAINT storage_prices_storage_option_id_fkey FOREIGN KEY (storage_option_id) REFERENCES storage_options(id)
+);
+create unique index if not exists index_storage_prices_option_effective_from on public.storage_prices using btree (storage_option_id, effective_from);
+
+-- Launch prices.
+insert into storage_prices (storage_option_id, cost_gb_per_month, effective_from, "comment", created_at, updated_at)
+select so.id, launch.cost_gb_per_month, '2014-01-01', 'Price as of July 29, 2021', now(), now()
+from storage_options so
+join (values
+	('Standard', 0.027),
+	('S3-VA', 0.023),
+	('S3-OH', 0.023),
+	('S3-OR', 0.023),
+	('Glacier-VA', 0.004),
+	('Glacier-OH', 0.004),
+	('Glacier-OR', 0.004),
+	('Glacier-Deep-VA', 0.00099),
+	('Glacier-Deep-OH', 0.00099),
+	('Glacier-Deep-OR', 0.00099),
+	('Wasabi-VA', 0.0059),
+	('Wasabi-OR', 0.0059)) as launch ("name", cost_gb_per_month) on launch."name" = so."name"
+where not exists (select 1 from storage_prices sp where sp.storage_option_id = so.id);
+
+-- Options added after launch.
+insert into storage_prices (storage_option_id, cost_gb_per_month, effective_from, "comment", created_at, updated_at)
+select so.id, so.cost_gb_per_month, '2014-01-01', 'Price when price history began', now(), now()
+from storage_options so
+where not exists (select 1 from storage_prices sp where sp.storage_option_id = so.id);
+
+-- Prices that changed after launch.
+insert into storage_prices (storage_option_id, cost_gb_per_month, effective_from, "comment", created_at, updated_at)
+select so.id, so.cost_gb_per_month, date_trunc('month', so.updated_at)::date,
+	'Price as of ' || to_char(so.updated_at, 'FMMonth FMDD, YYYY'), now(), now()
+from storage_options so
+where date_trunc('month', so.updated_at)::date > '2014-01-01'
+and so.cost_gb_per_month::numeric(12, 8) <> (
+	select sp.cost_gb_per_month from storage_prices sp
+	where sp.storage_option_id = so.id
+	and sp.effective_from < date_trunc('month', so.updated_at)::date
+	order by sp.effective_from desc
+	limit 1)
+on conflict (storage_option_id, effective_from) do nothing;
+
+update storage_prices sp set effective_to = (
+	select min(next.effective_from) from storage_prices next
+	where next.storage_option_id = sp.storage_option_id
+	and next.effective_from > sp.effective_from);
+
+-- Returns the price of the named storage option on the specified date,
+-- or the option's current price if it has no price history.
+CREATE OR REPLACE FUNCTION public.storage_price_on(option_name character varying, on_date date)
+ RETURNS numeric
+ LANGUAGE sql
+ STABLE
+AS $function$
+	select coalesce(
+		(select sp.cost_gb_per_month
+		 from storage_prices sp
+		 join storage_options so on so.id = sp.storage_option_id
+		 where so."name" = option_name
+		 and sp.effective_from <= on_date
+		 and (sp.effective_to is null or sp.effective_to > on_date)
+		 order by sp.effective_from desc
+		 limit 1),
+		(select so.cost_gb_per_month from storage_options so where so."name" = option_name));
+$function$
+;
+
+CREATE OR REPLACE FUNCTION public.populate_historical_deposit_stats(stop_date date)
+ RETURNS integer
+ LANGUAGE plpgsql
+AS $function$
+	begin
+		if not exists (select 1 from historical_deposit_stats where end_date = stop_date) then 
+			insert into historical_deposit_stats (
+			  institution_id,
+              member_institution_id,
+			  institution_name,
+			  storage_option,
+			  file_count,
+			  object_count,
+			  total_bytes,
+			  total_gb,
+			  total_tb,
+			  cost_gb_per_month,
+			  monthly_cost,
+			  end_date, 
+              primary_sort,
+              secondary_sort
+            )
+			select
+			  i2.id as institution_id,
+              i2.member_institution_id as member_institution_id,
+			  coalesce(stats.institution_name, 'All Institutions') as institution_name,
+			  coalesce(stats.storage_option, 'Total') as storage_option,
+			  coalesce(stats.file_count, 0) as file_count,
+			  coalesce(stats.object_count, 0) as object_count,
+			  coalesce(stats.total_bytes, 0) as total_bytes,
+			  coalesce((stats.total_bytes / 1073741824), 0) as total_gb,
+			  coalesce((stats.total_bytes / 1099511627776), 0) as total_tb,
+			  coalesce(storage_price_on(stats.storage_option, stop_date - 1), 0) as cost_gb_per_month,
+			  coalesce(((stats.total_bytes / 1073741824) * storage_price_on(stats.storage_option, stop_date - 1)), 0) as monthly_cost,
+			  stop_date as end_date,
+			  coalesce(stats.institution_name, 'zzz') as primary_sort,
+			  coalesce(stats.storage_option, 'zzz') as secondary_sort
+			from
+			  (select
+				i."name" as institution_name,
+				count(gf.id) as file_count,
+				count(distinct(gf.intellectual_object_id)) as object_count,
+				sum(gf.size) as total_bytes,
+				gf.storage_option
+			  from generic_files gf
+			  left join institutions i on i.id = gf.institution_id
+			  where gf.state = 'A'
+			  and gf.created_at < stop_date
+			  group by cube (i."name", gf.storage_option)) stats
+			left join institutions i2 on i2."name" = stats.institution_name;
+
+			select populate_empty_deposit_stats();
+		
+			return 1;
+		else
+			return 0;
+		end if;
+	end;
+$function$
+;
+
+CREATE OR REPLACE FUNCTION public.populate_historical_deposit_stats(stop_date timestamp without time zone)
+ RETURNS integer
+ LANGUAGE plpgsql
+AS $function$
+	begin
+		if not exists (select 1 from historical_deposit_stats where end_date = stop_date) then 
+			insert into historical_deposit_stats (
+			  institution_id,
+              member_institution_id,
+			  institution_name,
+			  storage_option,
+			  file_count,
+			  object_count,
+			  total_bytes,
+			  total_gb,
+			  total_tb,
+			  cost_gb_per_month,
+			  monthly_cost,
+			  end_date, 
+              primary_sort,
+              secondary_sort
+            )
+			select
+			  i2.id as institution_id,
+              i2.member_institution_id as member_institution_id,
+			  coalesce(stats.institution_name, 'All Institutions') as institution_name,
+			  coalesce(stats.storage_option, 'Total') as storage_option,
+			  coalesce(stats.file_count, 0) as file_count,
+			  coalesce(stats.object_count, 0) as object_count,
+			  coalesce(stats.total_bytes, 0) as total_bytes,
+			  coalesce((stats.total_bytes / 1073741824), 0) as total_gb,
+			  coalesce((stats.total_bytes / 1099511627776), 0) as total_tb,
+			  coalesce(storage_price_on(stats.storage_option, (stop_date - interval '1 day')::date), 0) as cost_gb_per_month,
+			  coalesce(((stats.total_bytes / 1073741824) * storage_price_on(stats.storage_option, (stop_date - interval '1 day')::date)), 0) as monthly_cost,
+			  stop_date as end_date,
+			  coalesce(stats.institution_name, 'zzz') as primary_sort,
+			  coalesce(stats.storage_option, 'zzz') as secondary_sort
+			from
+			  (select
+				i."name" as institution_name,
+				count(gf.id) as file_count,
+				count(distinct(gf.intellectual_object_id)) as object_count,
+				sum(gf.size) as total_bytes,
+				gf.storage_option
+			  from generic_files gf
+			  left join institutions i on i.id = gf.institution_id
+			  where gf.state = 'A'
+			  and gf.created_at < stop_date
+			  group by cube (i."name", gf.storage_option)) stats
+			left join institutions i2 on i2."name" = stats.institution_name;
+		
+			return 1;
+		else
+			return 0;
+		end if;
+	end;
+$function$
+;
+
+drop materialized view if exists current_deposit_stats;
+
+CREATE MATERIALIZED VIEW public.current_deposit_stats
+TABLESPACE pg_default
+AS SELECT i2.id AS institution_id,
+    i2.member_institution_id,
+    COALESCE(stats.institution_name, 'All Institutions'::character varying) AS institution_name,
+    COALESCE(stats.storage_option, 'Total'::character varying) AS storage_option,
+    stats.file_count,
+    stats.object_count,
+    stats.total_bytes,
+    stats.total_bytes / 1073741824::numeric AS total_gb,
+    stats.total_bytes / '1099511627776'::bigint::numeric AS total_tb,
+    storage_price_on(stats.storage_option, CURRENT_DATE) AS cost_gb_per_month,
+    stats.total_bytes / 1073741824::numeric * storage_price_on(stats.storage_option, CURRENT_DATE) AS monthly_cost,
+    now() AS end_date,
+    COALESCE(stats.institution_name, 'zzz'::character varying) AS primary_sort,
+    COALESCE(stats.storage_option, 'zzz'::character varying) AS secondary_sort
+   FROM ( SELECT i.name AS institution_name,
+            count(gf.id) AS file_count,
+            count(DISTINCT gf.intellectual_object_id) AS object_count,
+            sum(gf.size) AS total_bytes,
+            gf.storage_option
+           FROM generic_files gf
+             LEFT JOIN institutions i ON i.id = gf.institution_id
+          WHERE gf.state::text = 'A'::text
+          GROUP BY CUBE(i.name, gf.storage_option)) stats
+     LEFT JOIN institutions i2 ON i2.name::text = stats.institution_name::text
+  ORDER BY stats.institution_name, stats.storage_option
+WITH DATA;
+
+CREATE UNIQUE INDEX ix_current_deposits_inst_id_storage_option ON public.current_deposit_stats USING btree (institution_id, storage_option);
+
+-- Re-cost existing historical stats at the price in effect for each month.
+update historical_deposit_stats set
+	cost_gb_per_month = coalesce(storage_price_on(storage_option, (end_date - interval '1 day')::date), 0),
+	monthly_cost = total_gb * coalesce(storage_price_on(storage_option, (end_date - interval '1 day')::date), 0)
+where storage_option != 'Total';
+
+-- Now note that the migration is complete.
+update schema_migrations set finished_at = now() where "version" = '025_storage_prices';
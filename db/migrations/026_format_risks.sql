@@ -0,0 +1,109 @@
+-- 026_format_risks.sql
+--
+-- This migration adds a registry of file formats and their preservation
+-- risk, for the format risk report.
+--
+-- format_risks maps each MIME type to one of three risk levels: open
+-- (openly documented formats that many tools can read), proprietary
+-- (formats controlled by one vendor, which may need migration someday)
+-- and obsolete (formats current software can barely read, if at all).
+-- APTrust admins maintain this list in the web UI. Formats that aren't
+-- in the list show up in the report as unknown. mime_type is always
+-- lower case, to match what format identification records in
+-- generic_files.file_format.
+--
+-- format_risk_objects_view summarizes each object's active files by
+-- risk level. Members use it to find the objects that need
+-- normalization or migration.
+
+-- Note that we're starting the migration.
+insert into schema_migrations ("version", started_at) values ('026_format_risks', now())
+on conflict ("version") do update set started_at = now();
+
+create table if not exists format_risks (
+	id bigserial NOT NULL,
+	mime_type varchar NOT NULL,
+	risk_level varchar NOT NULL,
+	notes varchar NULL,
+	created_at timestamp NOT NULL,
+	updated_at timestamp NOT NULL,
+	CONSTRAINT format_risks_pkey PRIMARY KEY (id)
+);
+create unique index if not exists index_format_risks_mime_type on public.format_risks using btree (mime_type);
+create index if not exists index_format_risks_risk_level on public.format_risks using btree (risk_level);
+
+-- Start with the formats we see most often. Admins can change these
+-- and add more in the web UI.
+insert into format_risks (mime_type, risk_level, notes, created_at, updated_at) values
+	('application/epub+zip', 'open', 'EPUB', now(), now()),
+	('application/gzip', 'open', 'Gzip archive', now(), now()),
+	('application/json', 'open', 'JSON', now(), now()),
+	('application/pdf', 'open', 'PDF', now(), now()),
+	('application/vnd.oasis.opendocument.presentation', 'open', 'OpenDocument presentation', now(), now()),
+	('application/vnd.oasis.opendocument.spreadsheet', 'open', 'OpenDocument spreadsheet', now(), now()),
+	('application/vnd.oasis.opendocument.text', 'open', 'OpenDocument text', now(), now()),
+	('application/x-tar', 'open', 'Tar archive', now(), now()),
+	('application/xml', 'open', 'XML', now(), now()),
+	('application/zip', 'open', 'Zip archive', now(), now()),
+	('audio/flac', 'open', 'FLAC audio', now(), now()),
+	('audio/mp3', 'open', 'MP3 audio', now(), now()),
+	('audio/mpeg', 'open', 'MP3 audio', now(), now()),
+	('audio/ogg', 'open', 'Ogg audio', now(), now()),
+	('audio/wav', 'open', 'WAVE audio', now(), now()),
+	('audio/x-midi', 'open', 'MIDI', now(), now()),
+	('audio/x-wav', 'open', 'WAVE audio', now(), now()),
+	('image/gif', 'open', 'GIF image', now(), now()),
+	('image/jp2', 'open', 'JPEG 2000 image', now(), now()),
+	('image/jpeg', 'open', 'JPEG image', now(), now()),
+	('image/png', 'open', 'PNG image', now(), now()),
+	('image/svg+xml', 'open', 'SVG image', now(), now()),
+	('image/tiff', 'open', 'TIFF image', now(), now()),
+	('text/csv', 'open', 'Comma-separated values', now(), now()),
+	('text/html', 'open', 'HTML', now(), now()),
+	('text/plain', 'open', 'Plain text', now(), now()),
+	('text/xml', 'open', 'XML', now(), now()),
+	('video/mp4', 'open', 'MPEG-4 video', now(), now()),
+	('video/ogg', 'open', 'Ogg video', now(), now()),
+	('video/x-matroska', 'open', 'Matroska video', now(), now()),
+	('application/msword', 'proprietary', 'Microsoft Word 97-2003', now(), now()),
+	('application/vnd.amazon.ebook', 'proprietary', 'Amazon Kindle ebook', now(), now()),
+	('application/vnd.ms-excel', 'proprietary', 'Microsoft Excel 97-2003', now(), now()),
+	('application/vnd.ms-powerpoint', 'proprietary', 'Microsoft PowerPoint 97-2003', now(), now()),
+	('application/vnd.openxmlformats-officedocument.presentationml.presentation', 'proprietary', 'Microsoft PowerPoint', now(), now()),
+	('application/vnd.openxmlformats-officedocument.spreadsheetml.sheet', 'proprietary', 'Microsoft Excel', now(), now()),
+	('application/vnd.openxmlformats-officedocument.wordprocessingml.document', 'proprietary', 'Microsoft Word', now(), now()),
+	('application/x-rar-compressed', 'proprietary', 'RAR archive', now(), now()),
+	('audio/x-ms-wma', 'proprietary', 'Windows Media audio', now(), now()),
+	('image/vnd.adobe.photoshop', 'proprietary', 'Photoshop image', now(), now()),
+	('video/quicktime', 'proprietary', 'QuickTime video', now(), now()),
+	('video/x-ms-wmv', 'proprietary', 'Windows Media video', now(), now()),
+	('video/x-msvideo', 'proprietary', 'AVI video', now(), now()),
+	('application/vnd.lotus-1-2-3', 'obsolete', 'Lotus 1-2-3 spreadsheet', now(), now()),
+	('application/vnd.ms-works', 'obsolete', 'Microsoft Works', now(), now()),
+	('application/vnd.rn-realmedia', 'obsolete', 'RealMedia', now(), now()),
+	('application/vnd.wordperfect', 'obsolete', 'WordPerfect document', now(), now()),
+	('application/x-director', 'obsolete', 'Macromedia Director', now(), now()),
+	('application/x-shockwave-flash', 'obsolete', 'Adobe Flash', now(), now()),
+	('audio/vnd.rn-realaudio', 'obsolete', 'RealAudio', now(), now()),
+	('text/sgml', 'obsolete', 'SGML', now(), now())
+on conflict (mime_type) do nothing;
+
+create or replace view format_risk_objects_view as
+select
+	gf.intellectual_object_id,
+	io.identifier,
+	gf.institution_id,
+	i."name" as institution_name,
+	coalesce(fr.risk_level, 'unknown') as risk_level,
+	string_agg(distinct gf.file_format::text, ', ' order by gf.file_format::text) as file_formats,
+	count(*) as file_count,
+	sum(gf."size") as total_bytes
+from generic_files gf
+inner join intellectual_objects io on io.id = gf.intellectual_object_id
+inner join institutions i on i.id = gf.institution_id
+left join format_risks fr on fr.mime_type = gf.file_format
+where gf.state = 'A'
+group by gf.intellectual_object_id, io.identifier, gf.institution_id, i."name", coalesce(fr.risk_level, 'unknown');
+
+-- Now note that the migration is complete.
+update schema_migrations set finished_at = now() where "version" = '026_format_risks';
@@ -0,0 +1,74 @@
+-- 027_full_text_search.sql
+--
+-- This migration adds full-text search across intellectual objects,
+-- generic files and premis events.
+--
+-- Until now, the only way to find an object by anything other than its
+-- exact identifier was a filter like identifier__contains or
+-- alt_identifier__starts_with, which becomes an ILIKE scan over millions
+-- of rows. Now each of these tables has a search_vector column, with a
+-- GIN index, that Postgres keeps up to date as a generated column. We
+-- never write to these columns. Note that adding a stored generated
+-- column rewrites the table, so this migration takes a while on large
+-- databases.
+--
+-- Identifiers, bag names and other machine-generated values are indexed
+-- with the 'simple' configuration, which doesn't stem words or drop stop
+-- words. search_terms() indexes each of them whole and broken into parts
+-- at slashes, dots, dashes, underscores and colons, so that a search for
+-- "photos" finds test.edu/photos-2019 and test.edu/photos-2019/data/a.jpg.
+-- Titles, descriptions and other prose use the 'english' configuration.
+--
+-- Object fields have weights, so that matches in identifiers and titles
+-- rank above matches in the bag group and source organization, which
+-- rank above matches in internal sender fields and descriptions:
+--
+-- A: identifier, alt_identifier, bag_name, title
+-- B: bag_group_identifier, source_organization
+-- C: internal_sender_identifier, internal_sender_description, description
+--
+-- Searches should match search_vector against both configurations, e.g.
+-- search_vector @@ (websearch_to_tsquery('english', 'photos') ||
+-- websearch_to_tsquery('simple', 'photos')). See pgmodels.Search.
+
+-- Note that we're starting the migration.
+insert into schema_migrations ("version", started_at) values ('027_full_text_search', now())
+on conflict ("version") do update set started_at = now();
+
+-- search_terms returns val followed by its parts, for indexing
+-- identifiers. Generated columns require immutable functions.
+create or replace function search_terms(val text)
+returns text
+language sql
+immutable
+as $$
+	select coalesce(val, '') || ' ' || regexp_replace(coalesce(val, ''), '[/._:-]+', ' ', 'g')
+$$;
+
+alter table intellectual_objects add column if not exists search_vector tsvector
+generated always as (
+	setweight(to_tsvector('simple', search_terms(identifier) || ' ' || search_terms(alt_identifier) || ' ' || search_terms(bag_name)), 'A') ||
+	setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+	setweight(to_tsvector('simple', search_terms(bag_group_identifier)), 'B') ||
+	setweight(to_tsvector('english', coalesce(source_organization, '')), 'B') ||
+	setweight(to_tsvector('simple', search_terms(internal_sender_identifier)), 'C') ||
+	setweight(to_tsvector('english', coalesce(internal_sender_description, '') || ' ' || coalesce(description, '')), 'C')
+) stored;
+
+alter table generic_files add column if not exists search_vector tsvector
+generated always as (
+	to_tsvector('simple', search_terms(identifier))
+) stored;
+
+alter table premis_events add column if not exists search_vector tsvector
+generated always as (
+	setweight(to_tsvector('simple', coalesce(identifier, '') || ' ' || coalesce(event_type, '')), 'A') ||
+	setweight(to_tsvector('english', coalesce(outcome_detail, '') || ' ' || coalesce(outcome_information, '') || ' ' || coalesce(detail, '')), 'C')
+) stored;
+
+create index if not exists ix_intellectual_objects_search_vector on intellectual_objects using gin (search_vector);
+create index if not exists ix_generic_files_search_vector on generic_files using gin (search_vector);
+create index if not exists ix_premis_events_search_vector on premis_events using gin (search_vector);
+
+-- Now note that the migration is complete.
+update schema_migrations set finished_at = now() where "version" = '027_full_text_search';
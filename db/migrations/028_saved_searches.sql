@@ -0,0 +1,48 @@
+-- 028_saved_searches.sql
+--
+-- This migration adds the saved_searches table.
+--
+-- The object, file, event and work item index pages are driven by
+-- query-string filters, and users re-enter the same combinations of
+-- filters every day, e.g. failed ingests at one institution in the last
+-- week, or Glacier-only objects in one bag group. A saved search is a
+-- named set of those filters for one resource type.
+--
+-- resource_type is the type the index page lists, as in the filter
+-- lists in pgmodels (e.g. IntellectualObject, WorkItem). query_string
+-- holds the page's filter and sort params, without paging params.
+--
+-- Each search belongs to a user. The owner can pin it to their side
+-- nav, share it with the other users at their institution, and ask
+-- for an email when the number of matching records changes. The
+-- saved_search_notifications job counts the results of those searches
+-- once a day, and records the count and time in last_count and
+-- last_checked_at.
+
+-- Note that we're starting the migration.
+insert into schema_migrations ("version", started_at) values ('028_saved_searches', now())
+on conflict ("version") do update set started_at = now();
+
+create table if not exists saved_searches (
+	id bigserial NOT NULL,
+	user_id int4 NOT NULL,
+	institution_id int4 NOT NULL,
+	"name" varchar NOT NULL,
+	resource_type varchar NOT NULL,
+	query_string varchar NOT NULL,
+	pinned bool NOT NULL DEFAULT false,
+	shared bool NOT NULL DEFAULT false,
+	notify bool NOT NULL DEFAULT false,
+	last_count int8 NULL,
+	last_checked_at timestamp NULL,
+	created_at timestamp NOT NULL,
+	updated_at timestamp NOT NULL,
+	CONSTRAINT saved_searches_pkey PRIMARY KEY (id),
+	CONSTRAINT saved_searches_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT saved_searches_institution_id_fkey FOREIGN KEY (institution_id) REFERENCES institutions(id)
+);
+create unique index if not exists index_saved_searches_user_id_name on public.saved_searches using btree (user_id, "name");
+create index if not exists index_saved_searches_institution_id_shared on public.saved_searches using btree (institution_id, shared);
+
+-- Now note that the migration is complete.
+update schema_migrations set finished_at = now() where "version" = '028_saved_searches';
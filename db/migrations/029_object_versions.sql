@@ -0,0 +1,62 @@
+-- 029_object_versions.sql
+--
+-- This migration adds the object_versions and object_version_files
+-- tables, which record the history of an object across re-ingests.
+--
+-- When a bag is re-ingested, preservation services updates the
+-- intellectual_objects row and its generic_files in place, so the
+-- registry has no record of what the object looked like before.
+-- Each time an ingest work item succeeds, we now record a version
+-- of the object: one object_versions row, plus one object_version_files
+-- row for each of the object's active files at that moment, with the
+-- file's size and its latest md5 and sha256 digests.
+--
+-- Comparing a version's files with those of the version before it
+-- tells us which files the ingest added, replaced or removed. We keep
+-- those counts in object_versions for the timeline on the object page.
+-- version_number counts all successful ingests of the object, including
+-- those from before this migration, which have no recorded versions.
+--
+-- Note that generic_file_id has no foreign key, since files in old
+-- versions may since have been deleted.
+
+-- Note that we're starting the migration.
+insert into schema_migrations ("version", started_at) values ('029_object_versions', now())
+on conflict ("version") do update set started_at = now();
+
+create table if not exists object_versions (
+	id bigserial NOT NULL,
+	intellectual_object_id int4 NOT NULL,
+	institution_id int4 NOT NULL,
+	work_item_id int4 NOT NULL,
+	version_number int4 NOT NULL,
+	file_count int4 NOT NULL DEFAULT 0,
+	"size" int8 NOT NULL DEFAULT 0,
+	files_added int4 NOT NULL DEFAULT 0,
+	files_replaced int4 NOT NULL DEFAULT 0,
+	files_removed int4 NOT NULL DEFAULT 0,
+	created_at timestamp NOT NULL,
+	updated_at timestamp NOT NULL,
+	CONSTRAINT object_versions_pkey PRIMARY KEY (id),
+	CONSTRAINT object_versions_intellectual_object_id_fkey FOREIGN KEY (intellectual_object_id) REFERENCES intellectual_objects(id),
+	CONSTRAINT object_versions_institution_id_fkey FOREIGN KEY (institution_id) REFERENCES institutions(id),
+	CONSTRAINT object_versions_work_item_id_fkey FOREIGN KEY (work_item_id) REFERENCES work_items(id)
+);
+create index if not exists index_object_versions_intellectual_object_id on public.object_versions using btree (intellectual_object_id);
+create unique index if not exists index_object_versions_work_item_id on public.object_versions using btree (work_item_id);
+
+create table if not exists object_version_files (
+	id bigserial NOT NULL,
+	object_version_id int8 NOT NULL,
+	generic_file_id int4 NOT NULL,
+	identifier varchar NOT NULL,
+	"size" int8 NOT NULL,
+	md5 varchar NULL,
+	sha256 varchar NULL,
+	CONSTRAINT object_version_files_pkey PRIMARY KEY (id),
+	CONSTRAINT object_version_files_object_version_id_fkey FOREIGN KEY (object_version_id) REFERENCES object_versions(id) ON DELETE CASCADE
+);
+create index if not exists index_object_version_files_object_version_id on public.object_version_files using btree (object_version_id);
+
+-- Now note that the migration is complete.
+update schema_migrations set finished_at = now() where "version" = '029_object_versions';
@@ -0,0 +1,18 @@
+-- 030_pending_totp_secret.sql
+--
+-- This migration adds users.pending_totp_secret, which holds the
+-- authenticator app secret for a setup the user hasn't confirmed yet.
+-- Before this, we wrote new secrets straight to encrypted_totp_secret,
+-- so a user who already used an authenticator app and started setup
+-- again lost their working secret before proving the new one worked.
+-- ConfirmTOTP now moves the pending secret into encrypted_totp_secret
+-- only after the user enters a valid code from the new secret.
+
+-- Note that we're starting the migration.
+insert into schema_migrations ("version", started_at) values ('030_pending_totp_secret', now())
+on conflict ("version") do update set started_at = now();
+
+alter table users add column if not exists pending_totp_secret varchar null;
+
+-- Now note that the migration is complete.
+update schema_migrations set finished_at = now() where "version" = '030_pending_totp_secret';
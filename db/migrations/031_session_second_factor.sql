@@ -0,0 +1,31 @@
+-- 031_session_second_factor.sql
+--
+-- This migration adds user_sessions.awaiting_second_factor.
+--
+-- Until now, we tracked whether a two-factor user had completed their
+-- second factor in users.awaiting_second_factor. Because that flag
+-- belonged to the user rather than to a session, completing the second
+-- factor in one browser cleared it for every other browser signed in
+-- with the same password, and signing in anywhere set it again for
+-- sessions that had already completed it.
+--
+-- We now set the flag on the session the user signs in with, and clear
+-- it only on the session that verified the second factor. Existing
+-- sessions of users who were still awaiting their second factor get
+-- true, so no one who was partway through sign-in skips the second
+-- step. The registry no longer reads users.awaiting_second_factor, but
+-- we leave the column in place for older code that may still be
+-- running during deployment.
+
+-- Note that we're starting the migration.
+insert into schema_migrations ("version", started_at) values ('031_session_second_factor', now())
+on conflict ("version") do update set started_at = now();
+
+alter table user_sessions add column if not exists awaiting_second_factor bool not null default false;
+
+update user_sessions s set awaiting_second_factor = true
+from users u
+where u.id = s.user_id and u.awaiting_second_factor = true;
+
+-- Now note that the migration is complete.
+update schema_migrations set finished_at = now() where "version" = '031_session_second_factor';
@@ -0,0 +1,45 @@
+-- 032_format_risk_object_stats.sql
+--
+-- This migration replaces format_risk_objects_view with the materialized
+-- view format_risk_object_stats.
+--
+-- The format risk report summed the plain view on every request, which
+-- meant grouping every active file in generic_files each time someone
+-- opened the report. Like current_deposit_stats, the summary is now
+-- materialized and refreshed by a scheduled job (update_format_risk_stats),
+-- so the report and its object drill-down read precomputed rows. Changes
+-- to the format registry show up in the report after the next refresh.
+--
+-- The unique index on intellectual_object_id and risk_level lets the
+-- job refresh the view concurrently, without blocking readers.
+
+-- Note that we're starting the migration.
+insert into schema_migrations ("version", started_at) values ('032_format_risk_object_stats', now())
+on conflict ("version") do update set started_at = now();
+
+drop view if exists format_risk_objects_view;
+
+create materialized view if not exists format_risk_object_stats as
+select
+	gf.intellectual_object_id,
+	io.identifier,
+	gf.institution_id,
+	i."name" as institution_name,
+	coalesce(fr.risk_level, 'unknown') as risk_level,
+	string_agg(distinct gf.file_format::text, ', ' order by gf.file_format::text) as file_formats,
+	count(*) as file_count,
+	sum(gf."size") as total_bytes,
+	current_timestamp as updated_at
+from generic_files gf
+inner join intellectual_objects io on io.id = gf.intellectual_object_id
+inner join institutions i on i.id = gf.institution_id
+left join format_risks fr on fr.mime_type = gf.file_format
+where gf.state = 'A'
+group by gf.intellectual_object_id, io.identifier, gf.institution_id, i."name", coalesce(fr.risk_level, 'unknown')
+with data;
+
+create unique index if not exists ix_format_risk_object_stats_object_risk_level on public.format_risk_object_stats using btree (intellectual_object_id, risk_level);
+create index if not exists ix_format_risk_object_stats_institution_risk_level on public.format_risk_object_stats using btree (institution_id, risk_level);
+
+-- Now note that the migration is complete.
+update schema_migrations set finished_at = now() where "version" = '032_format_risk_object_stats';
@@ -0,0 +1,21 @@
+-- 033_pending_object_versions.sql
+--
+-- We used to record object versions inside WorkItem.Save, which made
+-- preservation services wait for a query over all of an object's files
+-- and checksums each time it marked an ingest successful. The
+-- record_object_versions job now records them in the background, for
+-- successful ingest work items that don't yet have a version.
+--
+-- This partial index lets the job find recently updated successful
+-- ingests without scanning all of work_items. The job only considers
+-- ingests updated since 029_object_versions ran, since we can't
+-- reconstruct versions for older ones.
+
+-- Note that we're starting the migration.
+insert into schema_migrations ("version", started_at) values ('033_pending_object_versions', now())
+on conflict ("version") do update set started_at = now();
+
+create index if not exists index_work_items_successful_ingests_on_updated_at on public.work_items using btree (updated_at) where action = 'Ingest' and status = 'Success';
+
+-- Now note that the migration is complete.
+update schema_migrations set finished_at = now() where "version" = '033_pending_object_versions';
@@ -1,5 +1,19 @@
 -- Registry Schema - 2023-04-10
 
+-- search_terms returns val followed by its parts, split at slashes,
+-- dots, dashes, underscores and colons, for full-text indexing of
+-- identifiers. It's defined here, rather than with the other functions
+-- below, because the search_vector columns of intellectual_objects,
+-- generic_files and premis_events use it.
+CREATE OR REPLACE FUNCTION public.search_terms(val text)
+ RETURNS text
+ LANGUAGE sql
+ IMMUTABLE
+AS $function$
+	select coalesce(val, '') || ' ' || regexp_replace(coalesce(val, ''), '[/._:-]+', ' ', 'g')
+$function$
+;
+
 -- public.ar_internal_metadata definition
 
 -- Drop table
@@ -208,6 +222,7 @@ CREATE TABLE generic_files (
 	institution_id int4 NOT NULL,
 	storage_option varchar NOT NULL DEFAULT 'Standard'::character varying,
 	uuid varchar NOT NULL,
+	search_vector tsvector NULL GENERATED ALWAYS AS (to_tsvector('simple'::regconfig, search_terms(identifier::text))) STORED,
 	CONSTRAINT generic_files_pkey PRIMARY KEY (id)
 );
 CREATE INDEX index_generic_files_on_created_at ON public.generic_files USING btree (created_at);
@@ -217,6 +232,7 @@ CREATE INDEX index_generic_files_on_institution_id_and_state ON public.generic_f
 CREATE INDEX index_generic_files_on_institution_id_and_updated_at ON public.generic_files USING btree (institution_id, updated_at);
 CREATE INDEX index_generic_files_on_intellectual_object_id ON public.generic_files USING btree (intellectual_object_id);
 CREATE INDEX index_generic_files_on_updated_at ON public.generic_files USING btree (updated_at);
+CREATE INDEX ix_generic_files_search_vector ON public.generic_files USING gin (search_vector);
 CREATE UNIQUE INDEX index_generic_files_on_uuid ON public.generic_files USING btree (uuid);
 CREATE INDEX ix_generic_files_state_opt_fixity ON public.generic_files USING btree (state, storage_option, last_fixity_check);
 CREATE INDEX ix_gf_last_fixity_check ON public.generic_files USING btree (last_fixity_check);
@@ -275,6 +291,7 @@ CREATE TABLE intellectual_objects (
 	source_organization varchar NULL,
 	internal_sender_identifier varchar NULL,
 	internal_sender_description text NULL,
+	search_vector tsvector NULL GENERATED ALWAYS AS (((((setweight(to_tsvector('simple'::regconfig, (((search_terms(identifier::text) || ' '::text) || search_terms(alt_identifier::text)) || ' '::text) || search_terms(bag_name::text)), 'A'::"char") || setweight(to_tsvector('english'::regconfig, COALESCE(title, ''::character varying)::text), 'A'::"char")) || setweight(to_tsvector('simple'::regconfig, search_terms(bag_group_identifier::text)), 'B'::"char")) || setweight(to_tsvector('english'::regconfig, COALESCE(source_organization, ''::character varying)::text), 'B'::"char")) || setweight(to_tsvector('simple'::regconfig, search_terms(internal_sender_identifier::text)), 'C'::"char")) || setweight(to_tsvector('english'::regconfig, (COALESCE(internal_sender_description, ''::text) || ' '::text) || COALESCE(description, ''::text)), 'C'::"char")) STORED,
 	CONSTRAINT intellectual_objects_pkey PRIMARY KEY (id)
 );
 CREATE INDEX index_intellectual_objects_on_bag_name ON public.intellectual_objects USING btree (bag_name);
@@ -282,6 +299,29 @@ CREATE INDEX index_intellectual_objects_on_created_at ON public.intellectual_obj
 CREATE UNIQUE INDEX index_intellectual_objects_on_identifier ON public.intellectual_objects USING btree (identifier);
 CREATE INDEX index_intellectual_objects_on_institution_id ON public.intellectual_objects USING btree (institution_id);
 CREATE INDEX index_intellectual_objects_on_updated_at ON public.intellectual_objects USING btree (updated_at);
+CREATE INDEX ix_intellectual_objects_search_vector ON public.intellectual_objects USING gin (search_vector);
+
+
+-- public.job_runs definition
+
+-- Drop table
+
+-- DROP TABLE job_runs;
+
+CREATE TABLE job_runs (
+	id bigserial NOT NULL,
+	job_name varchar NOT NULL,
+	"trigger" varchar NOT NULL,
+	host varchar NULL,
+	scheduled_for timestamp NULL,
+	started_at timestamp NOT NULL,
+	finished_at timestamp NULL,
+	duration_ms int8 NULL,
+	error_message text NULL,
+	CONSTRAINT job_runs_pkey PRIMARY KEY (id)
+);
+CREATE INDEX ix_job_runs_job_name_started_at ON public.job_runs USING btree (job_name, started_at DESC);
+CREATE UNIQUE INDEX ix_job_runs_uniq_job_name_scheduled_for ON public.job_runs USING btree (job_name, scheduled_for);
 
 
 -- public.old_passwords definition
@@ -325,6 +365,7 @@ CREATE TABLE premis_events (
 	outcome varchar NULL,
 	institution_id int4 NULL,
 	old_uuid varchar NULL,
+	search_vector tsvector NULL GENERATED ALWAYS AS (setweight(to_tsvector('simple'::regconfig, (COALESCE(identifier, ''::character varying)::text || ' '::text) || COALESCE(event_type, ''::character varying)::text), 'A'::"char") || setweight(to_tsvector('english'::regconfig, (((COALESCE(outcome_detail, ''::character varying)::text || ' '::text) || COALESCE(outcome_information, ''::character varying)::text) || ' '::text) || COALESCE(detail, ''::character varying)::text), 'C'::"char")) STORED,
 	CONSTRAINT premis_events_pkey PRIMARY KEY (id)
 );
 CREATE INDEX index_premis_events_date_time_desc ON public.premis_events USING btree (date_time DESC);
@@ -335,6 +376,7 @@ CREATE UNIQUE INDEX index_premis_events_on_identifier ON public.premis_events US
 CREATE INDEX index_premis_events_on_institution_id ON public.premis_events USING btree (institution_id);
 CREATE INDEX index_premis_events_on_intellectual_object_id ON public.premis_events USING btree (intellectual_object_id);
 CREATE INDEX index_premis_events_on_outcome ON public.premis_events USING btree (outcome);
+CREATE INDEX ix_premis_events_search_vector ON public.premis_events USING gin (search_vector);
 
 
 -- public.schema_migrations definition
@@ -453,6 +495,7 @@ CREATE INDEX index_work_items_on_institution_id ON public.work_items USING btree
 CREATE INDEX index_work_items_on_intellectual_object_id ON public.work_items USING btree (intellectual_object_id);
 CREATE INDEX index_work_items_on_stage ON public.work_items USING btree (stage);
 CREATE INDEX index_work_items_on_status ON public.work_items USING btree (status);
+CREATE INDEX index_work_items_successful_ingests_on_updated_at ON public.work_items USING btree (updated_at) WHERE (((action)::text = 'Ingest'::text) AND ((status)::text = 'Success'::text));
 
 
 -- public.checksums definition
@@ -496,6 +539,7 @@ CREATE TABLE institutions (
 	restore_bucket varchar NOT NULL,
 	spot_restore_frequency int4 NOT NULL DEFAULT 0,
 	last_spot_restore_work_item_id int8 NULL,
+	free_allowance_tb float8 NOT NULL DEFAULT 10.0,
 	CONSTRAINT institutions_pkey PRIMARY KEY (id),
 	CONSTRAINT fk_institutions_last_spot_restore FOREIGN KEY (last_spot_restore_work_item_id) REFERENCES work_items(id)
 );
@@ -566,6 +610,12 @@ CREATE TABLE users (
 	grace_period timestamp NULL,
 	awaiting_second_factor bool NOT NULL DEFAULT false,
 	"role" varchar(50) NOT NULL DEFAULT 'none'::character varying,
+	alert_preferences jsonb NOT NULL DEFAULT '{}'::jsonb,
+	encrypted_totp_secret varchar NULL,
+	pending_totp_secret varchar NULL,
+	failed_login_attempts int4 NOT NULL DEFAULT 0,
+	last_failed_login_at timestamp NULL,
+	locked_until timestamp NULL,
 	CONSTRAINT users_pkey PRIMARY KEY (id),
 	CONSTRAINT fk_rails_7fcf39ca13 FOREIGN KEY (institution_id) REFERENCES institutions(id)
 );
@@ -633,6 +683,464 @@ CREATE TABLE deletion_requests_intellectual_objects (
 CREATE UNIQUE INDEX index_drio_unique ON public.deletion_requests_intellectual_objects USING btree (deletion_request_id, intellectual_object_id);
 
 
+-- public.deletion_requests_work_items definition
+
+-- Drop table
+
+-- DROP TABLE deletion_requests_work_items;
+
+CREATE TABLE deletion_requests_work_items (
+	deletion_request_id int4 NOT NULL,
+	work_item_id int4 NOT NULL,
+	CONSTRAINT deletion_requests_work_items_deletion_request_id_fkey FOREIGN KEY (deletion_request_id) REFERENCES deletion_requests(id),
+	CONSTRAINT deletion_requests_work_items_work_item_id_fkey FOREIGN KEY (work_item_id) REFERENCES work_items(id)
+);
+CREATE UNIQUE INDEX index_drwi_unique ON public.deletion_requests_work_items USING btree (deletion_request_id, work_item_id);
+CREATE INDEX index_drwi_work_item_id ON public.deletion_requests_work_items USING btree (work_item_id);
+
+
+-- public.restoration_batches definition
+
+-- Drop table
+
+-- DROP TABLE restoration_batches;
+
+CREATE TABLE restoration_batches (
+	id bigserial NOT NULL,
+	institution_id int4 NOT NULL,
+	requested_by_id int4 NOT NULL,
+	created_at timestamp NOT NULL,
+	updated_at timestamp NOT NULL,
+	completed_at timestamp NULL,
+	CONSTRAINT restoration_batches_pkey PRIMARY KEY (id),
+	CONSTRAINT restoration_batches_institution_id_fkey FOREIGN KEY (institution_id) REFERENCES institutions(id),
+	CONSTRAINT restoration_batches_requested_by_id_fkey FOREIGN KEY (requested_by_id) REFERENCES users(id)
+);
+CREATE INDEX index_restoration_batches_institution_id ON public.restoration_batches USING btree (institution_id);
+
+
+-- public.restoration_batches_work_items definition
+
+-- Drop table
+
+-- DROP TABLE restoration_batches_work_items;
+
+CREATE TABLE restoration_batches_work_items (
+	restoration_batch_id int4 NOT NULL,
+	work_item_id int4 NOT NULL,
+	CONSTRAINT restoration_batches_work_items_restoration_batch_id_fkey FOREIGN KEY (restoration_batch_id) REFERENCES restoration_batches(id),
+	CONSTRAINT restoration_batches_work_items_work_item_id_fkey FOREIGN KEY (work_item_id) REFERENCES work_items(id)
+);
+CREATE UNIQUE INDEX index_rbwi_unique ON public.restoration_batches_work_items USING btree (restoration_batch_id, work_item_id);
+CREATE INDEX index_rbwi_work_item_id ON public.restoration_batches_work_items USING btree (work_item_id);
+
+
+-- public.webhooks definition
+
+-- Drop table
+
+-- DROP TABLE webhooks;
+
+CREATE TABLE webhooks (
+	id bigserial NOT NULL,
+	institution_id int4 NOT NULL,
+	url varchar NOT NULL,
+	secret varchar NOT NULL,
+	event_types _varchar NOT NULL DEFAULT '{}'::character varying[],
+	enabled bool NOT NULL DEFAULT true,
+	created_at timestamp NOT NULL,
+	updated_at timestamp NOT NULL,
+	CONSTRAINT webhooks_pkey PRIMARY KEY (id),
+	CONSTRAINT webhooks_institution_id_fkey FOREIGN KEY (institution_id) REFERENCES institutions(id)
+);
+CREATE INDEX index_webhooks_institution_id ON public.webhooks USING btree (institution_id);
+
+
+-- public.webhook_deliveries definition
+
+-- Drop table
+
+-- DROP TABLE webhook_deliveries;
+
+CREATE TABLE webhook_deliveries (
+	id bigserial NOT NULL,
+	webhook_id int4 NOT NULL,
+	event_type varchar NOT NULL,
+	event_key varchar NOT NULL,
+	payload text NOT NULL,
+	status varchar NOT NULL,
+	attempts int4 NOT NULL DEFAULT 0,
+	next_attempt_at timestamp NULL,
+	last_attempt_at timestamp NULL,
+	response_code int4 NOT NULL DEFAULT 0,
+	error_message text NULL,
+	created_at timestamp NOT NULL,
+	updated_at timestamp NOT NULL,
+	CONSTRAINT webhook_deliveries_pkey PRIMARY KEY (id),
+	CONSTRAINT webhook_deliveries_webhook_id_fkey FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX index_webhook_deliveries_event ON public.webhook_deliveries USING btree (webhook_id, event_key);
+CREATE INDEX index_webhook_deliveries_next_attempt_at ON public.webhook_deliveries USING btree (next_attempt_at) WHERE ((status)::text = 'Pending'::text);
+
+
+-- public.api_keys definition
+
+-- Drop table
+
+-- DROP TABLE api_keys;
+
+CREATE TABLE api_keys (
+	id bigserial NOT NULL,
+	user_id int4 NOT NULL,
+	"name" varchar NOT NULL,
+	key_prefix varchar NOT NULL,
+	encrypted_key varchar NOT NULL,
+	"scope" varchar NOT NULL,
+	permissions _varchar NOT NULL DEFAULT '{}'::character varying[],
+	expires_at timestamp NULL,
+	last_used_at timestamp NULL,
+	last_used_ip varchar NULL,
+	revoked_at timestamp NULL,
+	created_at timestamp NOT NULL,
+	updated_at timestamp NOT NULL,
+	CONSTRAINT api_keys_pkey PRIMARY KEY (id),
+	CONSTRAINT api_keys_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE INDEX index_api_keys_user_id_key_prefix ON public.api_keys USING btree (user_id, key_prefix);
+
+
+-- public.saved_searches definition
+
+-- Drop table
+
+-- DROP TABLE saved_searches;
+
+CREATE TABLE saved_searches (
+	id bigserial NOT NULL,
+	user_id int4 NOT NULL,
+	institution_id int4 NOT NULL,
+	"name" varchar NOT NULL,
+	resource_type varchar NOT NULL,
+	query_string varchar NOT NULL,
+	pinned bool NOT NULL DEFAULT false,
+	shared bool NOT NULL DEFAULT false,
+	notify bool NOT NULL DEFAULT false,
+	last_count int8 NULL,
+	last_checked_at timestamp NULL,
+	created_at timestamp NOT NULL,
+	updated_at timestamp NOT NULL,
+	CONSTRAINT saved_searches_pkey PRIMARY KEY (id),
+	CONSTRAINT saved_searches_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT saved_searches_institution_id_fkey FOREIGN KEY (institution_id) REFERENCES institutions(id)
+);
+CREATE UNIQUE INDEX index_saved_searches_user_id_name ON public.saved_searches USING btree (user_id, name);
+CREATE INDEX index_saved_searches_institution_id_shared ON public.saved_searches USING btree (institution_id, shared);
+
+
+-- public.object_versions definition
+
+-- Drop table
+
+-- DROP TABLE object_versions;
+
+CREATE TABLE object_versions (
+	id bigserial NOT NULL,
+	intellectual_object_id int4 NOT NULL,
+	institution_id int4 NOT NULL,
+	work_item_id int4 NOT NULL,
+	version_number int4 NOT NULL,
+	file_count int4 NOT NULL DEFAULT 0,
+	"size" int8 NOT NULL DEFAULT 0,
+	files_added int4 NOT NULL DEFAULT 0,
+	files_replaced int4 NOT NULL DEFAULT 0,
+	files_removed int4 NOT NULL DEFAULT 0,
+	created_at timestamp NOT NULL,
+	updated_at timestamp NOT NULL,
+	CONSTRAINT object_versions_pkey PRIMARY KEY (id),
+	CONSTRAINT object_versions_intellectual_object_id_fkey FOREIGN KEY (intellectual_object_id) REFERENCES intellectual_objects(id),
+	CONSTRAINT object_versions_institution_id_fkey FOREIGN KEY (institution_id) REFERENCES institutions(id),
+	CONSTRAINT object_versions_work_item_id_fkey FOREIGN KEY (work_item_id) REFERENCES work_items(id)
+);
+CREATE INDEX index_object_versions_intellectual_object_id ON public.object_versions USING btree (intellectual_object_id);
+CREATE UNIQUE INDEX index_object_versions_work_item_id ON public.object_versions USING btree (work_item_id);
+
+
+-- public.object_version_files definition
+
+-- Drop table
+
+-- DROP TABLE object_version_files;
+
+CREATE TABLE object_version_files (
+	id bigserial NOT NULL,
+	object_version_id int8 NOT NULL,
+	generic_file_id int4 NOT NULL,
+	identifier varchar NOT NULL,
+	"size" int8 NOT NULL,
+	md5 varchar NULL,
+	sha256 varchar NULL,
+	CONSTRAINT object_version_files_pkey PRIMARY KEY (id),
+	CONSTRAINT object_version_files_object_version_id_fkey FOREIGN KEY (object_version_id) REFERENCES object_versions(id) ON DELETE CASCADE
+);
+CREATE INDEX index_object_version_files_object_version_id ON public.object_version_files USING btree (object_version_id);
+
+
+-- public.webauthn_credentials definition
+
+-- Drop table
+
+-- DROP TABLE webauthn_credentials;
+
+CREATE TABLE webauthn_credentials (
+	id bigserial NOT NULL,
+	user_id int4 NOT NULL,
+	"name" varchar NOT NULL,
+	credential_id varchar NOT NULL,
+	public_key bytea NOT NULL,
+	attestation_type varchar NULL,
+	transports _varchar NOT NULL DEFAULT '{}'::character varying[],
+	aaguid bytea NULL,
+	sign_count int8 NOT NULL DEFAULT 0,
+	clone_warning bool NOT NULL DEFAULT false,
+	last_used_at timestamp NULL,
+	revoked_at timestamp NULL,
+	created_at timestamp NOT NULL,
+	updated_at timestamp NOT NULL,
+	CONSTRAINT webauthn_credentials_pkey PRIMARY KEY (id),
+	CONSTRAINT webauthn_credentials_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE INDEX index_webauthn_credentials_user_id ON public.webauthn_credentials USING btree (user_id);
+CREATE UNIQUE INDEX index_webauthn_credentials_credential_id ON public.webauthn_credentials USING btree (credential_id);
+
+
+-- public.identity_providers definition
+
+-- Drop table
+
+-- DROP TABLE identity_providers;
+
+CREATE TABLE identity_providers (
+	id bigserial NOT NULL,
+	institution_id int4 NOT NULL,
+	protocol varchar NOT NULL,
+	enabled bool NOT NULL DEFAULT false,
+	required bool NOT NULL DEFAULT false,
+	issuer_url varchar NULL,
+	client_id varchar NULL,
+	encrypted_client_secret varchar NULL,
+	metadata_xml text NULL,
+	email_attribute varchar NOT NULL,
+	role_attribute varchar NULL,
+	admin_role_values _varchar NOT NULL DEFAULT '{}'::character varying[],
+	created_at timestamp NOT NULL,
+	updated_at timestamp NOT NULL,
+	CONSTRAINT identity_providers_pkey PRIMARY KEY (id),
+	CONSTRAINT identity_providers_institution_id_fkey FOREIGN KEY (institution_id) REFERENCES institutions(id)
+);
+CREATE UNIQUE INDEX index_identity_providers_institution_id ON public.identity_providers USING btree (institution_id);
+
+
+-- public.audit_events definition
+
+-- Drop table
+
+-- DROP TABLE audit_events;
+
+CREATE TABLE audit_events (
+	id bigserial NOT NULL,
+	user_id int4 NULL,
+	user_email varchar NULL,
+	institution_id int4 NULL,
+	"action" varchar NOT NULL,
+	outcome varchar NOT NULL,
+	resource_type varchar NULL,
+	resource_id int8 NULL,
+	detail text NULL,
+	ip_address varchar NULL,
+	user_agent varchar NULL,
+	created_at timestamp NOT NULL,
+	CONSTRAINT audit_events_pkey PRIMARY KEY (id)
+);
+CREATE INDEX index_audit_events_created_at ON public.audit_events USING btree (created_at);
+CREATE INDEX index_audit_events_institution_id ON public.audit_events USING btree (institution_id, created_at);
+CREATE INDEX index_audit_events_user_id ON public.audit_events USING btree (user_id);
+CREATE INDEX index_audit_events_action ON public.audit_events USING btree (action);
+CREATE INDEX index_audit_events_ip_address ON public.audit_events USING btree (ip_address, created_at);
+
+CREATE OR REPLACE FUNCTION public.audit_events_append_only()
+ RETURNS trigger
+ LANGUAGE plpgsql
+AS $function$
+begin
+	raise exception 'audit_events is append-only';
+end;
+$function$
+;
+
+CREATE TRIGGER audit_events_append_only BEFORE UPDATE OR DELETE ON public.audit_events FOR EACH ROW EXECUTE PROCEDURE audit_events_append_only();
+
+
+-- public.user_sessions definition
+
+-- Drop table
+
+-- DROP TABLE user_sessions;
+
+CREATE TABLE user_sessions (
+	id bigserial NOT NULL,
+	user_id int4 NOT NULL,
+	token_digest varchar NOT NULL,
+	ip_address varchar NULL,
+	user_agent varchar NULL,
+	created_at timestamp NOT NULL,
+	last_seen_at timestamp NOT NULL,
+	awaiting_second_factor bool NOT NULL DEFAULT false,
+	CONSTRAINT user_sessions_pkey PRIMARY KEY (id),
+	CONSTRAINT user_sessions_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE UNIQUE INDEX index_user_sessions_token_digest ON public.user_sessions USING btree (token_digest);
+CREATE INDEX index_user_sessions_user_id ON public.user_sessions USING btree (user_id);
+
+
+-- public.replication_problems definition
+
+-- Drop table
+
+-- DROP TABLE replication_problems;
+
+CREATE TABLE replication_problems (
+	id bigserial NOT NULL,
+	generic_file_id int4 NOT NULL,
+	intellectual_object_id int4 NOT NULL,
+	institution_id int4 NOT NULL,
+	identifier varchar NOT NULL,
+	storage_option varchar NOT NULL,
+	problem varchar NOT NULL,
+	bucket varchar NOT NULL,
+	url varchar NULL,
+	verified_at timestamp NOT NULL,
+	CONSTRAINT replication_problems_pkey PRIMARY KEY (id),
+	CONSTRAINT replication_problems_generic_file_id_fkey FOREIGN KEY (generic_file_id) REFERENCES generic_files(id),
+	CONSTRAINT replication_problems_institution_id_fkey FOREIGN KEY (institution_id) REFERENCES institutions(id)
+);
+CREATE INDEX index_replication_problems_generic_file_id ON public.replication_problems USING btree (generic_file_id);
+CREATE INDEX index_replication_problems_institution_id ON public.replication_problems USING btree (institution_id);
+CREATE INDEX index_replication_problems_problem ON public.replication_problems USING btree (problem);
+
+
+-- public.invoices definition
+
+-- Drop table
+
+-- DROP TABLE invoices;
+
+CREATE TABLE invoices (
+	id bigserial NOT NULL,
+	invoice_number varchar NOT NULL,
+	institution_id int4 NOT NULL,
+	institution_name varchar NOT NULL,
+	billing_month date NOT NULL,
+	free_allowance_tb float8 NOT NULL DEFAULT 0,
+	total_gb float8 NOT NULL DEFAULT 0,
+	total_tb float8 NOT NULL DEFAULT 0,
+	billable_gb float8 NOT NULL DEFAULT 0,
+	amount_due float8 NOT NULL DEFAULT 0,
+	created_at timestamp NOT NULL,
+	updated_at timestamp NOT NULL,
+	CONSTRAINT invoices_pkey PRIMARY KEY (id),
+	CONSTRAINT invoices_institution_id_fkey FOREIGN KEY (institution_id) REFERENCES institutions(id)
+);
+CREATE UNIQUE INDEX index_invoices_invoice_number ON public.invoices USING btree (invoice_number);
+CREATE UNIQUE INDEX index_invoices_institution_month ON public.invoices USING btree (institution_id, billing_month);
+
+
+-- public.invoice_line_items definition
+
+-- Drop table
+
+-- DROP TABLE invoice_line_items;
+
+CREATE TABLE invoice_line_items (
+	id bigserial NOT NULL,
+	invoice_id int8 NOT NULL,
+	institution_id int4 NOT NULL,
+	institution_name varchar NOT NULL,
+	storage_option varchar NOT NULL,
+	breakdown bool NOT NULL DEFAULT false,
+	total_gb float8 NOT NULL DEFAULT 0,
+	total_tb float8 NOT NULL DEFAULT 0,
+	free_gb float8 NOT NULL DEFAULT 0,
+	billable_gb float8 NOT NULL DEFAULT 0,
+	cost_gb_per_month float8 NOT NULL DEFAULT 0,
+	amount float8 NOT NULL DEFAULT 0,
+	CONSTRAINT invoice_line_items_pkey PRIMARY KEY (id),
+	CONSTRAINT invoice_line_items_invoice_id_fkey FOREIGN KEY (invoice_id) REFERENCES invoices(id)
+);
+CREATE INDEX index_invoice_line_items_invoice_id ON public.invoice_line_items USING btree (invoice_id);
+
+
+-- public.storage_prices definition
+
+-- Drop table
+
+-- DROP TABLE storage_prices;
+
+CREATE TABLE storage_prices (
+	id bigserial NOT NULL,
+	storage_option_id int8 NOT NULL,
+	cost_gb_per_month numeric(12, 8) NOT NULL,
+	effective_from date NOT NULL,
+	effective_to date NULL,
+	"comment" varchar NULL,
+	created_at timestamp NOT NULL,
+	updated_at timestamp NOT NULL,
+	CONSTRAINT storage_prices_pkey PRIMARY KEY (id),
+	CONSTRAINT storage_prices_storage_option_id_fkey FOREIGN KEY (storage_option_id) REFERENCES storage_options(id)
+);
+CREATE UNIQUE INDEX index_storage_prices_option_effective_from ON public.storage_prices USING btree (storage_option_id, effective_from);
+
+-- storage_price_on returns the price of the named storage option on the
+-- specified date, or the option's current price if it has no price
+-- history. It's defined here, rather than with the other functions
+-- below, because the current_deposit_stats view uses it.
+CREATE OR REPLACE FUNCTION public.storage_price_on(option_name character varying, on_date date)
+ RETURNS numeric
+ LANGUAGE sql
+ STABLE
+AS $function$
+	select coalesce(
+		(select sp.cost_gb_per_month
+		 from storage_prices sp
+		 join storage_options so on so.id = sp.storage_option_id
+		 where so."name" = option_name
+		 and sp.effective_from <= on_date
+		 and (sp.effective_to is null or sp.effective_to > on_date)
+		 order by sp.effective_from desc
+		 limit 1),
+		(select so.cost_gb_per_month from storage_options so where so."name" = option_name));
+$function$
+;
+
+
+-- public.format_risks definition
+
+-- Drop table
+
+-- DROP TABLE format_risks;
+
+CREATE TABLE format_risks (
+	id bigserial NOT NULL,
+	mime_type varchar NOT NULL,
+	risk_level varchar NOT NULL,
+	notes varchar NULL,
+	created_at timestamp NOT NULL,
+	updated_at timestamp NOT NULL,
+	CONSTRAINT format_risks_pkey PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX index_format_risks_mime_type ON public.format_risks USING btree (mime_type);
+CREATE INDEX index_format_risks_risk_level ON public.format_risks USING btree (risk_level);
+
+
 -- public.alerts definition
 
 -- Drop table
@@ -760,8 +1268,8 @@ AS SELECT i2.id AS institution_id,
     stats.total_bytes,
     stats.total_bytes / 1073741824::numeric AS total_gb,
     stats.total_bytes / '1099511627776'::bigint::numeric AS total_tb,
-    so.cost_gb_per_month,
-    stats.total_bytes / 1073741824::numeric * so.cost_gb_per_month AS monthly_cost,
+    storage_price_on(stats.storage_option, CURRENT_DATE) AS cost_gb_per_month,
+    stats.total_bytes / 1073741824::numeric * storage_price_on(stats.storage_option, CURRENT_DATE) AS monthly_cost,
     now() AS end_date,
     COALESCE(stats.institution_name, 'zzz'::character varying) AS primary_sort,
     COALESCE(stats.storage_option, 'zzz'::character varying) AS secondary_sort
@@ -774,7 +1282,6 @@ AS SELECT i2.id AS institution_id,
              LEFT JOIN institutions i ON i.id = gf.institution_id
           WHERE gf.state::text = 'A'::text
           GROUP BY CUBE(i.name, gf.storage_option)) stats
-     LEFT JOIN storage_options so ON so.name::text = stats.storage_option::text
      LEFT JOIN institutions i2 ON i2.name::text = stats.institution_name::text
   ORDER BY stats.institution_name, stats.storage_option
 WITH DATA;
@@ -822,6 +1329,32 @@ AS SELECT dr.id,
      LEFT JOIN work_items wi ON dr.work_item_id = wi.id;
 
 
+-- public.format_risk_object_stats source
+
+CREATE MATERIALIZED VIEW public.format_risk_object_stats
+TABLESPACE pg_default
+AS SELECT gf.intellectual_object_id,
+    io.identifier,
+    gf.institution_id,
+    i.name AS institution_name,
+    COALESCE(fr.risk_level, 'unknown'::character varying) AS risk_level,
+    string_agg(DISTINCT gf.file_format::text, ', '::text ORDER BY (gf.file_format::text)) AS file_formats,
+    count(*) AS file_count,
+    sum(gf.size) AS total_bytes,
+    CURRENT_TIMESTAMP AS updated_at
+   FROM generic_files gf
+     JOIN intellectual_objects io ON io.id = gf.intellectual_object_id
+     JOIN institutions i ON i.id = gf.institution_id
+     LEFT JOIN format_risks fr ON fr.mime_type::text = gf.file_format::text
+  WHERE gf.state::text = 'A'::text
+  GROUP BY gf.intellectual_object_id, io.identifier, gf.institution_id, i.name, (COALESCE(fr.risk_level, 'unknown'::character varying))
+WITH DATA;
+
+-- View indexes:
+CREATE UNIQUE INDEX ix_format_risk_object_stats_object_risk_level ON public.format_risk_object_stats USING btree (intellectual_object_id, risk_level);
+CREATE INDEX ix_format_risk_object_stats_institution_risk_level ON public.format_risk_object_stats USING btree (institution_id, risk_level);
+
+
 -- public.generic_file_counts source
 
 CREATE MATERIALIZED VIEW public.generic_file_counts
@@ -903,7 +1436,8 @@ AS SELECT i.id,
     parent.name AS parent_name,
     parent.identifier AS parent_identifier,
     parent.state AS parent_state,
-    parent.deactivated_at AS parent_deactivated_at
+    parent.deactivated_at AS parent_deactivated_at,
+    i.free_allowance_tb
    FROM institutions i
      LEFT JOIN institutions parent ON i.member_institution_id = parent.id;
 
@@ -1350,8 +1884,8 @@ AS $function$
 			  coalesce(stats.total_bytes, 0) as total_bytes,
 			  coalesce((stats.total_bytes / 1073741824), 0) as total_gb,
 			  coalesce((stats.total_bytes / 1099511627776), 0) as total_tb,
-			  coalesce(so.cost_gb_per_month, 0) as cost_gb_per_month,
-			  coalesce(((stats.total_bytes / 1073741824) * so.cost_gb_per_month), 0) as monthly_cost,
+			  coalesce(storage_price_on(stats.storage_option, stop_date - 1), 0) as cost_gb_per_month,
+			  coalesce(((stats.total_bytes / 1073741824) * storage_price_on(stats.storage_option, stop_date - 1)), 0) as monthly_cost,
 			  stop_date as end_date,
 			  coalesce(stats.institution_name, 'zzz') as primary_sort,
 			  coalesce(stats.storage_option, 'zzz') as secondary_sort
@@ -1367,7 +1901,6 @@ AS $function$
 			  where gf.state = 'A'
 			  and gf.created_at < stop_date
 			  group by cube (i."name", gf.storage_option)) stats
-			left join storage_options so on so."name" = stats.storage_option
 			left join institutions i2 on i2."name" = stats.institution_name;
 
 			select populate_empty_deposit_stats();
@@ -1412,8 +1945,8 @@ AS $function$
 			  coalesce(stats.total_bytes, 0) as total_bytes,
 			  coalesce((stats.total_bytes / 1073741824), 0) as total_gb,
 			  coalesce((stats.total_bytes / 1099511627776), 0) as total_tb,
-			  coalesce(so.cost_gb_per_month, 0) as cost_gb_per_month,
-			  coalesce(((stats.total_bytes / 1073741824) * so.cost_gb_per_month), 0) as monthly_cost,
+			  coalesce(storage_price_on(stats.storage_option, (stop_date - interval '1 day')::date), 0) as cost_gb_per_month,
+			  coalesce(((stats.total_bytes / 1073741824) * storage_price_on(stats.storage_option, (stop_date - interval '1 day')::date)), 0) as monthly_cost,
 			  stop_date as end_date,
 			  coalesce(stats.institution_name, 'zzz') as primary_sort,
 			  coalesce(stats.storage_option, 'zzz') as secondary_sort
@@ -1429,7 +1962,6 @@ AS $function$
 			  where gf.state = 'A'
 			  and gf.created_at < stop_date
 			  group by cube (i."name", gf.storage_option)) stats
-			left join storage_options so on so."name" = stats.storage_option
 			left join institutions i2 on i2."name" = stats.institution_name;
 		
 			return 1;
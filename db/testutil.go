@@ -14,6 +14,7 @@ import (
 )
 
 var fixturesLoaded = false
+var fixtureLoadCount = 0
 
 // SafeEnvironments lists which APT_ENV environments are safe for data loading.
 // Since data loading DELETES THE ENTIRE DB before reloading fixtures, we want
@@ -29,6 +30,7 @@ var SafeEnvironments = []string{
 // (csv files) in the order they should be loaded.
 var LoadOrder = []string{
 	"storage_options",
+	"storage_prices",
 	"institutions",
 	"users",
 	"intellectual_objects",
@@ -52,6 +54,8 @@ var HasNoIDColumn = []string{
 	"roles_users",
 	"deletion_requests_generic_files",
 	"deletion_requests_intellectual_objects",
+	"deletion_requests_work_items",
+	"restoration_batches_work_items",
 	"alerts_premis_events",
 	"alerts_users",
 	"alerts_work_items",
@@ -60,7 +64,9 @@ var HasNoIDColumn = []string{
 // DropOrder lists tables to be dropped, in the order they should be
 // dropped so we don't violate foreign key constraints.
 var DropOrder = []string{
+	"api_keys",
 	"ar_internal_metadata",
+	"audit_events",
 	"bulk_delete_jobs",
 	"bulk_delete_jobs_emails",
 	"bulk_delete_jobs_generic_files",
@@ -72,17 +78,33 @@ var DropOrder = []string{
 	"emails_intellectual_objects",
 	"emails_premis_events",
 	"emails_work_items",
+	"format_risks",
+	"identity_providers",
+	"job_runs",
 	"old_passwords",
+	"saved_searches",
 	"schema_migrations",
 	"snapshots",
 	"usage_samples",
+	"user_sessions",
+	"webauthn_credentials",
+	"webhook_deliveries",
+	"webhooks",
 	"alerts_work_items",
 	"alerts_users",
 	"alerts_premis_events",
 	"alerts",
 	"deletion_requests_generic_files",
 	"deletion_requests_intellectual_objects",
+	"deletion_requests_work_items",
 	"deletion_requests",
+	"invoice_line_items",
+	"invoices",
+	"replication_problems",
+	"restoration_batches_work_items",
+	"restoration_batches",
+	"object_version_files",
+	"object_versions",
 	"work_items",
 	"premis_events",
 	"storage_records",
@@ -93,12 +115,14 @@ var DropOrder = []string{
 	"users",
 	"institutions",
 	"roles",
+	"storage_prices",
 	"storage_options",
 	"historical_deposit_stats",
 }
 
 var MaterializedViewDropOrder = []string{
 	"current_deposit_stats",
+	"format_risk_object_stats",
 	"premis_event_counts",
 	"intellectual_object_counts",
 	"generic_file_counts",
@@ -144,10 +168,18 @@ func LoadFixtures() error {
 			return err
 		}
 		fixturesLoaded = true
+		fixtureLoadCount++
 	}
 	return nil
 }
 
+// FixtureLoadCount returns the number of times we've loaded fixtures
+// in this test run. Loading fixtures deletes all user sessions, so
+// HTTP tests use this to tell when to sign their clients in again.
+func FixtureLoadCount() int {
+	return fixtureLoadCount
+}
+
 // ForceFixtureReload forces reloading of all test fixtures.
 // This is useful when you want to ensure a clean slate, wiping
 // out all records left by prior tests.
@@ -311,6 +343,12 @@ func populateCountsAndStats(db *pg.DB) error {
 		return err
 	}
 
+	// Populate format risk stats (materialized view).
+	_, err = db.Exec("refresh materialized view format_risk_object_stats;")
+	if err != nil {
+		return err
+	}
+
 	// Since historical_deposit_stats is a table and not a
 	// materialized view, we need to empty it before re-populating it.
 	// This table is initially populated by the migration 001_deposit_stats.sql
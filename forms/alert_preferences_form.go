@@ -0,0 +1,57 @@
+package forms
+
+import (
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/stretchr/stew/slice"
+)
+
+// AlertPreferencesForm lets users choose how to receive each type of
+// alert: immediately, in a daily or weekly digest, or only in the
+// Registry. This appears on the user's My Account page. Alert types
+// in constants.ImmediateAlertTypes are not included, because those
+// always go out immediately.
+type AlertPreferencesForm struct {
+	Form
+}
+
+func NewAlertPreferencesForm(user *pgmodels.User) *AlertPreferencesForm {
+	prefsForm := &AlertPreferencesForm{
+		Form: NewForm(user, "users/my_account.html", "/users"),
+	}
+	prefsForm.init()
+	prefsForm.SetValues()
+	return prefsForm
+}
+
+func (f *AlertPreferencesForm) init() {
+	for _, alertType := range constants.AlertTypes {
+		if slice.Contains(constants.ImmediateAlertTypes, alertType) {
+			continue
+		}
+		f.Fields[alertType] = &Field{
+			Name:    "AlertPreferences[" + alertType + "]",
+			Label:   alertType,
+			ErrMsg:  pgmodels.ErrUserAlertPrefs,
+			Options: AlertDeliveryList,
+		}
+	}
+}
+
+// SetValues sets the form values to match the user's preferences.
+func (f *AlertPreferencesForm) SetValues() {
+	user := f.Model.(*pgmodels.User)
+	for alertType, field := range f.Fields {
+		field.Value = user.AlertDeliveryFor(alertType)
+	}
+}
+
+// Action returns the html form.action attribute for this form.
+func (f *AlertPreferencesForm) Action() string {
+	return f.BaseURL + "/alert_preferences"
+}
+
+// PostSaveURL returns the URL to redirect to after a successful save.
+func (f *AlertPreferencesForm) PostSaveURL() string {
+	return f.BaseURL + "/my_account"
+}
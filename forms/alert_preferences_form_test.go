@@ -0,0 +1,34 @@
+package forms_test
+
+import (
+	"testing"
+
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/forms"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlertPreferencesForm(t *testing.T) {
+	user := &pgmodels.User{
+		AlertPreferences: map[string]string{
+			constants.AlertFailedFixity: constants.AlertDeliveryDaily,
+		},
+	}
+	form := forms.NewAlertPreferencesForm(user)
+	require.NotNil(t, form)
+	assert.Equal(t, "/users/alert_preferences", form.Action())
+	assert.Equal(t, "/users/my_account", form.PostSaveURL())
+
+	assert.Equal(t, len(constants.AlertTypes)-len(constants.ImmediateAlertTypes), len(form.Fields))
+	for _, alertType := range constants.ImmediateAlertTypes {
+		assert.Nil(t, form.Fields[alertType])
+	}
+
+	field := form.Fields[constants.AlertFailedFixity]
+	require.NotNil(t, field)
+	assert.Equal(t, "AlertPreferences[Failed Fixity Check]", field.Name)
+	assert.Equal(t, constants.AlertDeliveryDaily, field.Value)
+	assert.Equal(t, constants.AlertDeliveryImmediate, form.Fields[constants.AlertDeletionCompleted].Value)
+}
@@ -0,0 +1,83 @@
+package forms
+
+import (
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/stretchr/stew/slice"
+)
+
+// APIKeyForm lets users create a named API key for themselves. The
+// permission list includes only permissions the user's role grants,
+// since a key can never do more than its owner can.
+type APIKeyForm struct {
+	Form
+	role string
+}
+
+func NewAPIKeyForm(key *pgmodels.APIKey, role string) *APIKeyForm {
+	keyForm := &APIKeyForm{
+		Form: NewForm(key, "api_keys/form.html", "/api_keys"),
+		role: role,
+	}
+	keyForm.init()
+	keyForm.SetValues()
+	return keyForm
+}
+
+func (f *APIKeyForm) init() {
+	f.Fields["Name"] = &Field{
+		Name:        "Name",
+		Label:       "Name",
+		Placeholder: "e.g. Nightly ingest reports",
+		ErrMsg:      pgmodels.ErrAPIKeyName,
+		Attrs: map[string]string{
+			"required": "",
+		},
+	}
+	f.Fields["Scope"] = &Field{
+		Name:    "Scope",
+		Label:   "Scope",
+		ErrMsg:  pgmodels.ErrAPIKeyScope,
+		Options: APIKeyScopeList,
+		Attrs: map[string]string{
+			"required": "",
+		},
+	}
+	f.Fields["Permissions"] = &Field{
+		Name:    "Permissions",
+		Label:   "Limit to these permissions (leave blank to allow everything your role allows)",
+		ErrMsg:  pgmodels.ErrAPIKeyPermissions,
+		Options: f.permissionOptions(),
+	}
+	f.Fields["ExpiresAt"] = &Field{
+		Name:        "ExpiresAt",
+		Label:       "Expires (optional)",
+		Placeholder: "mm/dd/yyyy",
+		ErrMsg:      pgmodels.ErrAPIKeyExpiresAt,
+	}
+}
+
+// permissionOptions returns the permissions granted to the user's role.
+func (f *APIKeyForm) permissionOptions() []*ListOption {
+	options := make([]*ListOption, 0)
+	for _, perm := range constants.Permissions {
+		if constants.CheckPermission(f.role, perm) {
+			options = append(options, &ListOption{string(perm), string(perm), false})
+		}
+	}
+	return options
+}
+
+// SetValues sets the form values to match the APIKey values.
+func (f *APIKeyForm) SetValues() {
+	key := f.Model.(*pgmodels.APIKey)
+	f.Fields["Name"].Value = key.Name
+	f.Fields["Scope"].Value = key.Scope
+	f.Fields["Permissions"].Value = key.Permissions
+	for _, option := range f.Fields["Permissions"].Options {
+		option.Selected = slice.Contains(key.Permissions, option.Value)
+	}
+	if !key.ExpiresAt.IsZero() {
+		f.Fields["ExpiresAt"].Value = key.ExpiresAt.Format("2006-01-02")
+	}
+}
@@ -0,0 +1,38 @@
+package forms_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/forms"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyForm(t *testing.T) {
+	key := &pgmodels.APIKey{
+		UserID:      3,
+		Name:        "CI",
+		Scope:       constants.APIKeyScopeReadOnly,
+		Permissions: []string{constants.WorkItemRead},
+		ExpiresAt:   time.Date(2030, time.June, 1, 0, 0, 0, 0, time.UTC),
+	}
+	form := forms.NewAPIKeyForm(key, constants.RoleInstUser)
+	require.NotNil(t, form)
+	assert.Equal(t, "/api_keys/new", form.Action())
+	assert.Equal(t, "CI", form.Fields["Name"].Value)
+	assert.Equal(t, constants.APIKeyScopeReadOnly, form.Fields["Scope"].Value)
+	assert.Equal(t, "2030-06-01", form.Fields["ExpiresAt"].Value)
+
+	// Permission list includes only what the user's role allows.
+	options := form.Fields["Permissions"].Options
+	require.NotEmpty(t, options)
+	for _, option := range options {
+		assert.True(t, constants.CheckPermission(constants.RoleInstUser, constants.Permission(option.Value)), option.Value)
+		assert.Equal(t, option.Value == constants.WorkItemRead, option.Selected, option.Value)
+	}
+	adminForm := forms.NewAPIKeyForm(key, constants.RoleSysAdmin)
+	assert.True(t, len(adminForm.Fields["Permissions"].Options) > len(options))
+}
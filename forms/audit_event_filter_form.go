@@ -0,0 +1,85 @@
+package forms
+
+import (
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/pgmodels"
+)
+
+// AuditEventFilterForm is the form that displays filtering options for
+// the security audit log.
+type AuditEventFilterForm struct {
+	Form
+	FilterCollection *pgmodels.FilterCollection
+	instOptions      []*ListOption
+}
+
+func NewAuditEventFilterForm(fc *pgmodels.FilterCollection, actingUser *pgmodels.User) (FilterForm, error) {
+	f := &AuditEventFilterForm{
+		Form:             NewForm(nil, "audit_events/_filters.html", "/audit_events"),
+		FilterCollection: fc,
+	}
+	var err error
+	if actingUser.IsAdmin() {
+		// SysAdmin can view audit events for all institutions.
+		f.instOptions, err = ListInstitutions(false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	f.init()
+	f.SetValues()
+	return f, nil
+}
+
+func (f *AuditEventFilterForm) init() {
+	f.Fields["action"] = &Field{
+		Name:        "action",
+		Label:       "Action",
+		Placeholder: "Action",
+		Options:     Options(constants.AuditActions),
+	}
+	f.Fields["created_at__gteq"] = &Field{
+		Name:        "created_at__gteq",
+		Label:       "Date on or After",
+		Placeholder: "Date on or After",
+	}
+	f.Fields["created_at__lteq"] = &Field{
+		Name:        "created_at__lteq",
+		Label:       "Date on or Before",
+		Placeholder: "Date on or Before",
+	}
+	f.Fields["institution_id"] = &Field{
+		Name:        "institution_id",
+		Label:       "Institution",
+		Placeholder: "Institution",
+		Options:     f.instOptions,
+	}
+	f.Fields["ip_address"] = &Field{
+		Name:        "ip_address",
+		Label:       "IP Address",
+		Placeholder: "IP Address",
+	}
+	f.Fields["outcome"] = &Field{
+		Name:        "outcome",
+		Label:       "Outcome",
+		Placeholder: "Outcome",
+		Options:     Options(constants.EventOutcomes),
+	}
+	f.Fields["user_email__contains"] = &Field{
+		Name:        "user_email__contains",
+		Label:       "User Email",
+		Placeholder: "User Email",
+	}
+}
+
+// SetValues sets the form values to match the filter values.
+func (f *AuditEventFilterForm) SetValues() {
+	for _, fieldName := range pgmodels.AuditEventFilters {
+		if f.Fields[fieldName] == nil {
+			common.ConsoleDebug("No filter for %s", fieldName)
+			continue
+		}
+		f.Fields[fieldName].Value = f.FilterCollection.ValueOf(fieldName)
+	}
+}
@@ -0,0 +1,62 @@
+package forms_test
+
+import (
+	"testing"
+
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/forms"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/APTrust/registry/web/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getAuditEventFilters() *pgmodels.FilterCollection {
+	fc := pgmodels.NewFilterCollection()
+	fc.Add("action", []string{constants.AuditSignIn})
+	fc.Add("created_at__gteq", []string{"2020-01-01"})
+	fc.Add("created_at__lteq", []string{"2024-12-31"})
+	fc.Add("institution_id", []string{"2"})
+	fc.Add("ip_address", []string{"10.0.0.1"})
+	fc.Add("outcome", []string{constants.OutcomeFailure})
+	fc.Add("user_email__contains", []string{"inst1.edu"})
+	return fc
+}
+
+func getAuditEventFilterForm(t *testing.T, user *pgmodels.User) (*pgmodels.FilterCollection, forms.FilterForm) {
+	fc := getAuditEventFilters()
+	form, err := forms.NewAuditEventFilterForm(fc, user)
+	require.Nil(t, err)
+	require.NotNil(t, form)
+	return fc, form
+}
+
+func TestAuditEventFilterFormSysAdmin(t *testing.T) {
+	sysAdmin := testutil.InitUser(t, "system@aptrust.org")
+	fc, form := getAuditEventFilterForm(t, sysAdmin)
+	fields := form.GetFields()
+	testAuditEventFields(t, fc, fields)
+	assert.True(t, len(fields["institution_id"].Options) > 1)
+	assert.Equal(t, len(constants.AuditActions), len(fields["action"].Options))
+	assert.True(t, len(fields["outcome"].Options) > 1)
+}
+
+func TestAuditEventFilterFormInstAdmin(t *testing.T) {
+	instAdmin := testutil.InitUser(t, "admin@inst1.edu")
+	fc, form := getAuditEventFilterForm(t, instAdmin)
+	fields := form.GetFields()
+	testAuditEventFields(t, fc, fields)
+	// Inst admins see only their own institution's events.
+	assert.Empty(t, fields["institution_id"].Options)
+	assert.Equal(t, len(constants.AuditActions), len(fields["action"].Options))
+}
+
+func testAuditEventFields(t *testing.T, fc *pgmodels.FilterCollection, fields map[string]*forms.Field) {
+	for _, name := range pgmodels.AuditEventFilters {
+		if name == "user_id" {
+			continue // no form field; used in links from user pages
+		}
+		require.NotNil(t, fields[name], name)
+		assert.Equal(t, fc.ValueOf(name), fields[name].Value, name)
+	}
+}
@@ -0,0 +1,59 @@
+package forms
+
+import (
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/pgmodels"
+)
+
+// FixityReportFilterForm is the form that displays filtering options
+// for the fixity report and the overdue fixity list.
+type FixityReportFilterForm struct {
+	Form
+	FilterCollection *pgmodels.FilterCollection
+	instOptions      []*ListOption
+}
+
+func NewFixityReportFilterForm(fc *pgmodels.FilterCollection, actingUser *pgmodels.User) (FilterForm, error) {
+	f := &FixityReportFilterForm{
+		Form:             NewForm(nil, "reports/_fixity_filters.html", "/reports/fixity"),
+		FilterCollection: fc,
+	}
+	var err error
+	if actingUser.IsAdmin() {
+		// SysAdmin can view fixity stats for all institutions.
+		f.instOptions, err = ListInstitutions(false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	f.init()
+	f.SetValues()
+	return f, nil
+}
+
+func (f *FixityReportFilterForm) init() {
+	f.Fields["institution_id"] = &Field{
+		Name:        "institution_id",
+		Label:       "Institution",
+		Placeholder: "Institution",
+		Options:     f.instOptions,
+	}
+	f.Fields["storage_option"] = &Field{
+		Name:        "storage_option",
+		Label:       "Storage Option",
+		Placeholder: "Storage Option",
+		Options:     Options(constants.StorageOptions),
+	}
+}
+
+// SetValues sets the form values to match the filter values.
+func (f *FixityReportFilterForm) SetValues() {
+	for _, fieldName := range pgmodels.FixityStatsFilters {
+		if f.Fields[fieldName] == nil {
+			common.ConsoleDebug("No filter for %s", fieldName)
+			continue
+		}
+		f.Fields[fieldName].Value = f.FilterCollection.ValueOf(fieldName)
+	}
+}
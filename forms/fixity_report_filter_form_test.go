@@ -0,0 +1,43 @@
+package forms_test
+
+import (
+	"testing"
+
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/forms"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/APTrust/registry/web/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getFixityReportFilterForm(t *testing.T, user *pgmodels.User) (*pgmodels.FilterCollection, forms.FilterForm) {
+	fc := pgmodels.NewFilterCollection()
+	fc.Add("institution_id", []string{"2"})
+	fc.Add("storage_option", []string{constants.StorageOptionStandard})
+	form, err := forms.NewFixityReportFilterForm(fc, user)
+	require.Nil(t, err)
+	require.NotNil(t, form)
+	return fc, form
+}
+
+func TestFixityReportFilterFormSysAdmin(t *testing.T) {
+	sysAdmin := testutil.InitUser(t, "system@aptrust.org")
+	_, form := getFixityReportFilterForm(t, sysAdmin)
+	fields := form.GetFields()
+	assert.Equal(t, "2", fields["institution_id"].Value)
+	assert.Equal(t, constants.StorageOptionStandard, fields["storage_option"].Value)
+	assert.True(t, len(fields["institution_id"].Options) > 1)
+	assert.Equal(t, len(constants.StorageOptions), len(fields["storage_option"].Options))
+}
+
+func TestFixityReportFilterFormNonAdmin(t *testing.T) {
+	for _, email := range []string{"admin@inst1.edu", "user@inst1.edu"} {
+		user := testutil.InitUser(t, email)
+		_, form := getFixityReportFilterForm(t, user)
+		fields := form.GetFields()
+		assert.Equal(t, constants.StorageOptionStandard, fields["storage_option"].Value)
+		// Non-admins see only their own institution.
+		assert.Empty(t, fields["institution_id"].Options)
+	}
+}
@@ -0,0 +1,49 @@
+package forms
+
+import (
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/pgmodels"
+)
+
+// FormatRiskFilterForm is the form that displays filtering options
+// for the format registry.
+type FormatRiskFilterForm struct {
+	Form
+	FilterCollection *pgmodels.FilterCollection
+}
+
+func NewFormatRiskFilterForm(fc *pgmodels.FilterCollection, actingUser *pgmodels.User) (FilterForm, error) {
+	f := &FormatRiskFilterForm{
+		Form:             NewForm(nil, "format_risks/_filters.html", "/format_risks"),
+		FilterCollection: fc,
+	}
+	f.init()
+	f.SetValues()
+	return f, nil
+}
+
+func (f *FormatRiskFilterForm) init() {
+	f.Fields["mime_type__starts_with"] = &Field{
+		Name:        "mime_type__starts_with",
+		Label:       "MIME Type Starts With",
+		Placeholder: "MIME Type Starts With",
+	}
+	f.Fields["risk_level"] = &Field{
+		Name:        "risk_level",
+		Label:       "Risk Level",
+		Placeholder: "Risk Level",
+		Options:     Options(constants.FormatRiskLevels),
+	}
+}
+
+// SetValues sets the form values to match the filter values.
+func (f *FormatRiskFilterForm) SetValues() {
+	for _, fieldName := range pgmodels.FormatRiskFilters {
+		if f.Fields[fieldName] == nil {
+			common.ConsoleDebug("No filter for %s", fieldName)
+			continue
+		}
+		f.Fields[fieldName].Value = f.FilterCollection.ValueOf(fieldName)
+	}
+}
@@ -0,0 +1,62 @@
+package forms
+
+import (
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/pgmodels"
+)
+
+// FormatRiskForm lets APTrust admins add and edit entries in the
+// format registry.
+type FormatRiskForm struct {
+	Form
+}
+
+func NewFormatRiskForm(formatRisk *pgmodels.FormatRisk) *FormatRiskForm {
+	formatRiskForm := &FormatRiskForm{
+		Form: NewForm(formatRisk, "format_risks/form.html", "/format_risks"),
+	}
+	formatRiskForm.init()
+	formatRiskForm.SetValues()
+	return formatRiskForm
+}
+
+func (f *FormatRiskForm) init() {
+	f.Fields["MimeType"] = &Field{
+		Name:        "MimeType",
+		Label:       "MIME Type",
+		Placeholder: "application/pdf",
+		ErrMsg:      pgmodels.ErrFormatRiskMimeType,
+		Attrs: map[string]string{
+			"required": "",
+		},
+	}
+	f.Fields["RiskLevel"] = &Field{
+		Name:        "RiskLevel",
+		Label:       "Risk Level",
+		Placeholder: "Risk Level",
+		ErrMsg:      pgmodels.ErrFormatRiskLevel,
+		Options:     Options(constants.FormatRiskLevels),
+		Attrs: map[string]string{
+			"required": "",
+		},
+	}
+	f.Fields["Notes"] = &Field{
+		Name:        "Notes",
+		Label:       "Notes",
+		Placeholder: "Format name, or reason for the risk level",
+	}
+}
+
+// PostSaveURL returns the registry list. Entries don't have their own
+// show page.
+func (f *FormatRiskForm) PostSaveURL() string {
+	return f.BaseURL
+}
+
+// SetValues sets the form values to match the FormatRisk values.
+func (f *FormatRiskForm) SetValues() {
+	formatRisk := f.Model.(*pgmodels.FormatRisk)
+	f.Fields["MimeType"].Value = formatRisk.MimeType
+	f.Fields["RiskLevel"].Value = formatRisk.RiskLevel
+	f.Fields["Notes"].Value = formatRisk.Notes
+}
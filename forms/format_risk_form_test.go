@@ -0,0 +1,43 @@
+package forms_test
+
+import (
+	"testing"
+
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/forms"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatRiskForm(t *testing.T) {
+	formatRisk := &pgmodels.FormatRisk{
+		MimeType:  "text/sgml",
+		RiskLevel: constants.FormatRiskObsolete,
+		Notes:     "SGML",
+	}
+	formatRisk.ID = 8
+	form := forms.NewFormatRiskForm(formatRisk)
+	assert.Equal(t, "format_risks/form.html", form.Template)
+	assert.Equal(t, "/format_risks/edit/8", form.Action())
+	assert.Equal(t, "/format_risks", form.PostSaveURL())
+
+	fields := form.GetFields()
+	assert.Equal(t, "text/sgml", fields["MimeType"].Value)
+	assert.Equal(t, constants.FormatRiskObsolete, fields["RiskLevel"].Value)
+	assert.Equal(t, "SGML", fields["Notes"].Value)
+	assert.Equal(t, len(constants.FormatRiskLevels), len(fields["RiskLevel"].Options))
+}
+
+func TestFormatRiskReportFilterForm(t *testing.T) {
+	fc := pgmodels.NewFilterCollection()
+	fc.Add("identifier__starts_with", []string{"institution1.edu/"})
+	fc.Add("risk_level", []string{constants.FormatRiskObsolete})
+	user := &pgmodels.User{Role: constants.RoleInstAdmin, InstitutionID: 2}
+	form, err := forms.NewFormatRiskReportFilterForm(fc, user)
+	assert.Nil(t, err)
+	fields := form.GetFields()
+	assert.Equal(t, "institution1.edu/", fields["identifier__starts_with"].Value)
+	assert.Equal(t, constants.FormatRiskObsolete, fields["risk_level"].Value)
+	assert.Equal(t, len(constants.FormatRiskReportLevels), len(fields["risk_level"].Options))
+	assert.Empty(t, fields["institution_id"].Options)
+}
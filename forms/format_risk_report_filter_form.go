@@ -0,0 +1,64 @@
+package forms
+
+import (
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/pgmodels"
+)
+
+// FormatRiskReportFilterForm is the form that displays filtering
+// options for the format risk report and its list of objects.
+type FormatRiskReportFilterForm struct {
+	Form
+	FilterCollection *pgmodels.FilterCollection
+	instOptions      []*ListOption
+}
+
+func NewFormatRiskReportFilterForm(fc *pgmodels.FilterCollection, actingUser *pgmodels.User) (FilterForm, error) {
+	f := &FormatRiskReportFilterForm{
+		Form:             NewForm(nil, "reports/_format_risk_filters.html", "/reports/format_risk"),
+		FilterCollection: fc,
+	}
+	var err error
+	if actingUser.IsAdmin() {
+		// SysAdmin can view format risks for all institutions.
+		f.instOptions, err = ListInstitutions(false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	f.init()
+	f.SetValues()
+	return f, nil
+}
+
+func (f *FormatRiskReportFilterForm) init() {
+	f.Fields["identifier__starts_with"] = &Field{
+		Name:        "identifier__starts_with",
+		Label:       "Object Identifier",
+		Placeholder: "Object Identifier",
+	}
+	f.Fields["institution_id"] = &Field{
+		Name:        "institution_id",
+		Label:       "Institution",
+		Placeholder: "Institution",
+		Options:     f.instOptions,
+	}
+	f.Fields["risk_level"] = &Field{
+		Name:        "risk_level",
+		Label:       "Risk Level",
+		Placeholder: "Risk Level",
+		Options:     Options(constants.FormatRiskReportLevels),
+	}
+}
+
+// SetValues sets the form values to match the filter values.
+func (f *FormatRiskReportFilterForm) SetValues() {
+	for _, fieldName := range pgmodels.FormatRiskObjectFilters {
+		if f.Fields[fieldName] == nil {
+			common.ConsoleDebug("No filter for %s", fieldName)
+			continue
+		}
+		f.Fields[fieldName].Value = f.FilterCollection.ValueOf(fieldName)
+	}
+}
@@ -0,0 +1,119 @@
+package forms
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/APTrust/registry/pgmodels"
+)
+
+// IdentityProviderForm lets APTrust admins configure an institution's
+// single sign-on provider. The OIDC client secret is write-only: we
+// never display it, and leaving it blank keeps the current secret.
+type IdentityProviderForm struct {
+	Form
+}
+
+func NewIdentityProviderForm(idp *pgmodels.IdentityProvider) *IdentityProviderForm {
+	idpForm := &IdentityProviderForm{
+		Form: NewForm(idp, "institutions/sso_form.html", "/institutions"),
+	}
+	idpForm.init()
+	idpForm.SetValues()
+	return idpForm
+}
+
+// Action returns the html form.action attribute for this form.
+func (f *IdentityProviderForm) Action() string {
+	idp := f.Model.(*pgmodels.IdentityProvider)
+	return fmt.Sprintf("%s/sso/%d", f.BaseURL, idp.InstitutionID)
+}
+
+// PostSaveURL returns the institution's detail page, which shows
+// the provider's settings.
+func (f *IdentityProviderForm) PostSaveURL() string {
+	idp := f.Model.(*pgmodels.IdentityProvider)
+	return fmt.Sprintf("%s/show/%d", f.BaseURL, idp.InstitutionID)
+}
+
+func (f *IdentityProviderForm) init() {
+	f.Fields["Protocol"] = &Field{
+		Name:    "Protocol",
+		Label:   "Protocol",
+		ErrMsg:  pgmodels.ErrIdPProtocol,
+		Options: SSOProtocolList,
+		Attrs: map[string]string{
+			"required": "",
+		},
+	}
+	f.Fields["Enabled"] = &Field{
+		Name:  "Enabled",
+		Label: "Let users sign in through this identity provider",
+	}
+	f.Fields["Required"] = &Field{
+		Name:   "Required",
+		Label:  "Require single sign-on (disables Registry passwords for institutional users)",
+		ErrMsg: pgmodels.ErrIdPRequired,
+	}
+	f.Fields["IssuerURL"] = &Field{
+		Name:        "IssuerURL",
+		Label:       "OIDC Issuer URL",
+		Placeholder: "https://login.example.edu",
+		ErrMsg:      pgmodels.ErrIdPIssuerURL,
+	}
+	f.Fields["ClientID"] = &Field{
+		Name:   "ClientID",
+		Label:  "OIDC Client ID",
+		ErrMsg: pgmodels.ErrIdPClientID,
+	}
+	f.Fields["ClientSecret"] = &Field{
+		Name:   "ClientSecret",
+		Label:  "OIDC Client Secret",
+		ErrMsg: pgmodels.ErrIdPClientSecret,
+	}
+	f.Fields["MetadataXML"] = &Field{
+		Name:        "MetadataXML",
+		Label:       "SAML IdP Metadata",
+		Placeholder: "<EntityDescriptor ...>",
+		ErrMsg:      pgmodels.ErrIdPMetadataXML,
+		Attrs: map[string]string{
+			"rows": "8",
+		},
+	}
+	f.Fields["EmailAttribute"] = &Field{
+		Name:        "EmailAttribute",
+		Label:       "Email attribute or claim",
+		Placeholder: "email",
+		ErrMsg:      pgmodels.ErrIdPEmailAttribute,
+		Attrs: map[string]string{
+			"required": "",
+		},
+	}
+	f.Fields["RoleAttribute"] = &Field{
+		Name:        "RoleAttribute",
+		Label:       "Role attribute or claim (leave blank to manage roles in the Registry)",
+		Placeholder: "eduPersonEntitlement",
+	}
+	f.Fields["AdminRoleValues"] = &Field{
+		Name:        "AdminRoleValues",
+		Label:       "Role values for institutional admins (comma-separated)",
+		Placeholder: "registry-admin",
+	}
+}
+
+// SetValues sets the form values to match the IdentityProvider values.
+func (f *IdentityProviderForm) SetValues() {
+	idp := f.Model.(*pgmodels.IdentityProvider)
+	f.Fields["Protocol"].Value = idp.Protocol
+	f.Fields["Enabled"].Value = idp.Enabled
+	f.Fields["Required"].Value = idp.Required
+	f.Fields["IssuerURL"].Value = idp.IssuerURL
+	f.Fields["ClientID"].Value = idp.ClientID
+	if idp.EncryptedClientSecret != "" {
+		f.Fields["ClientSecret"].Placeholder = "Leave blank to keep the current secret"
+	}
+	f.Fields["MetadataXML"].Value = idp.MetadataXML
+	f.Fields["EmailAttribute"].Value = idp.EmailAttribute
+	f.Fields["RoleAttribute"].Value = idp.RoleAttribute
+	f.Fields["AdminRoleValues"].Value = strings.Join(idp.AdminRoleValues, ", ")
+}
@@ -0,0 +1,39 @@
+package forms_test
+
+import (
+	"testing"
+
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/forms"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentityProviderForm(t *testing.T) {
+	idp := pgmodels.NewIdentityProvider(4)
+	form := forms.NewIdentityProviderForm(idp)
+	require.NotNil(t, form)
+	assert.Equal(t, "/institutions/sso/4", form.Action())
+	assert.Equal(t, "/institutions/show/4", form.PostSaveURL())
+	assert.Equal(t, "institutions/sso_form.html", form.Template)
+	assert.Equal(t, constants.SSOProtocolOIDC, form.Fields["Protocol"].Value)
+	assert.Equal(t, "email", form.Fields["EmailAttribute"].Value)
+	assert.Equal(t, false, form.Fields["Enabled"].Value)
+	assert.Empty(t, form.Fields["ClientSecret"].Placeholder)
+
+	idp.ID = 7
+	idp.Protocol = constants.SSOProtocolSAML
+	idp.Enabled = true
+	idp.RoleAttribute = "groups"
+	idp.AdminRoleValues = []string{"registry-admins", "it-staff"}
+	idp.EncryptedClientSecret = "encrypted"
+	form = forms.NewIdentityProviderForm(idp)
+	assert.Equal(t, "/institutions/sso/4", form.Action())
+	assert.Equal(t, constants.SSOProtocolSAML, form.Fields["Protocol"].Value)
+	assert.Equal(t, true, form.Fields["Enabled"].Value)
+	assert.Equal(t, "groups", form.Fields["RoleAttribute"].Value)
+	assert.Equal(t, "registry-admins, it-staff", form.Fields["AdminRoleValues"].Value)
+	assert.NotEmpty(t, form.Fields["ClientSecret"].Placeholder)
+	assert.Nil(t, form.Fields["ClientSecret"].Value)
+}
@@ -83,6 +83,17 @@ func (f *InstitutionForm) init() {
 			"required": "",
 		},
 	}
+	f.Fields["FreeAllowanceTB"] = &Field{
+		Name:        "FreeAllowanceTB",
+		Label:       "Free storage allowance (TB)",
+		Placeholder: "",
+		ErrMsg:      pgmodels.ErrInstAllowance,
+		Attrs: map[string]string{
+			"required": "",
+			"min":      "0",
+			"step":     "any",
+		},
+	}
 	f.Fields["ReceivingBucket"] = &Field{
 		Name:        "Receiving Bucket",
 		Label:       "Receiving Bucket",
@@ -112,6 +123,7 @@ func (f *InstitutionForm) SetValues() {
 	f.Fields["MemberInstitutionID"].Value = institution.MemberInstitutionID
 	f.Fields["OTPEnabled"].Value = institution.OTPEnabled
 	f.Fields["SpotRestoreFrequency"].Value = institution.SpotRestoreFrequency
+	f.Fields["FreeAllowanceTB"].Value = institution.FreeAllowanceTB
 	f.Fields["ReceivingBucket"].Value = institution.ReceivingBucket
 	f.Fields["RestoreBucket"].Value = institution.RestoreBucket
 
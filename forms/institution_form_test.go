@@ -36,4 +36,5 @@ func TestInstitutionForm(t *testing.T) {
 	assert.Equal(t, inst.SpotRestoreFrequency, form.Fields["SpotRestoreFrequency"].Value)
 	assert.Equal(t, inst.ReceivingBucket, form.Fields["ReceivingBucket"].Value)
 	assert.Equal(t, inst.RestoreBucket, form.Fields["RestoreBucket"].Value)
+	assert.Equal(t, inst.FreeAllowanceTB, form.Fields["FreeAllowanceTB"].Value)
 }
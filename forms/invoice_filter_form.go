@@ -0,0 +1,67 @@
+package forms
+
+import (
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/pgmodels"
+)
+
+// InvoiceFilterForm is the form that displays filtering options for
+// the invoice list.
+type InvoiceFilterForm struct {
+	Form
+	FilterCollection *pgmodels.FilterCollection
+	instOptions      []*ListOption
+}
+
+func NewInvoiceFilterForm(fc *pgmodels.FilterCollection, actingUser *pgmodels.User) (FilterForm, error) {
+	f := &InvoiceFilterForm{
+		Form:             NewForm(nil, "invoices/_filters.html", "/invoices"),
+		FilterCollection: fc,
+	}
+	var err error
+	if actingUser.IsAdmin() {
+		// SysAdmin can view invoices for all member institutions.
+		f.instOptions, err = ListInstitutions(true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	f.init()
+	f.SetValues()
+	return f, nil
+}
+
+func (f *InvoiceFilterForm) init() {
+	f.Fields["billing_month__gteq"] = &Field{
+		Name:        "billing_month__gteq",
+		Label:       "Billing Month on or After",
+		Placeholder: "Billing Month on or After",
+	}
+	f.Fields["billing_month__lteq"] = &Field{
+		Name:        "billing_month__lteq",
+		Label:       "Billing Month on or Before",
+		Placeholder: "Billing Month on or Before",
+	}
+	f.Fields["institution_id"] = &Field{
+		Name:        "institution_id",
+		Label:       "Institution",
+		Placeholder: "Institution",
+		Options:     f.instOptions,
+	}
+	f.Fields["invoice_number"] = &Field{
+		Name:        "invoice_number",
+		Label:       "Invoice Number",
+		Placeholder: "Invoice Number",
+	}
+}
+
+// SetValues sets the form values to match the filter values.
+func (f *InvoiceFilterForm) SetValues() {
+	for _, fieldName := range pgmodels.InvoiceFilters {
+		if f.Fields[fieldName] == nil {
+			common.ConsoleDebug("No filter for %s", fieldName)
+			continue
+		}
+		f.Fields[fieldName].Value = f.FilterCollection.ValueOf(fieldName)
+	}
+}
@@ -0,0 +1,38 @@
+package forms_test
+
+import (
+	"testing"
+
+	"github.com/APTrust/registry/forms"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/APTrust/registry/web/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getInvoiceFilterForm(t *testing.T, user *pgmodels.User) forms.FilterForm {
+	fc := pgmodels.NewFilterCollection()
+	fc.Add("billing_month__gteq", []string{"2023-01-01"})
+	fc.Add("billing_month__lteq", []string{"2023-06-01"})
+	fc.Add("institution_id", []string{"2"})
+	fc.Add("invoice_number", []string{"APT-202301-00002"})
+	form, err := forms.NewInvoiceFilterForm(fc, user)
+	require.Nil(t, err)
+	require.NotNil(t, form)
+	return form
+}
+
+func TestInvoiceFilterForm(t *testing.T) {
+	sysAdmin := testutil.InitUser(t, "system@aptrust.org")
+	fields := getInvoiceFilterForm(t, sysAdmin).GetFields()
+	assert.Equal(t, "2023-01-01", fields["billing_month__gteq"].Value)
+	assert.Equal(t, "2023-06-01", fields["billing_month__lteq"].Value)
+	assert.Equal(t, "2", fields["institution_id"].Value)
+	assert.Equal(t, "APT-202301-00002", fields["invoice_number"].Value)
+	assert.True(t, len(fields["institution_id"].Options) > 1)
+
+	// Non-admins see only their own institution.
+	admin := testutil.InitUser(t, "admin@inst1.edu")
+	fields = getInvoiceFilterForm(t, admin).GetFields()
+	assert.Empty(t, fields["institution_id"].Options)
+}
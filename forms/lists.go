@@ -25,6 +25,22 @@ var Months = []string{
 	"December",
 }
 
+// AlertDeliveryList describes the ways a user can choose to receive
+// each type of alert.
+var AlertDeliveryList = []*ListOption{
+	{constants.AlertDeliveryImmediate, "Email immediately", false},
+	{constants.AlertDeliveryDaily, "Daily digest", false},
+	{constants.AlertDeliveryWeekly, "Weekly digest", false},
+	{constants.AlertDeliveryInApp, "In Registry only", false},
+}
+
+// APIKeyScopeList describes the scopes a user can choose for
+// an API key.
+var APIKeyScopeList = []*ListOption{
+	{constants.APIKeyScopeReadOnly, "Read only", false},
+	{constants.APIKeyScopeReadWrite, "Read and write", false},
+}
+
 // AllRolesList is a list of assignable user roles. Hard-coded instead
 // of using Options() function for formatting reasons and because we
 // don't want to include the "none" role.
@@ -65,6 +81,13 @@ var ObjectStateList = []*ListOption{
 	{constants.StateDeleted, "Deleted", false},
 }
 
+// SSOProtocolList describes the protocols an institution's identity
+// provider can use for single sign-on.
+var SSOProtocolList = []*ListOption{
+	{constants.SSOProtocolOIDC, "OpenID Connect", false},
+	{constants.SSOProtocolSAML, "SAML 2.0", false},
+}
+
 var StorageOptionList = []*ListOption{
 	{constants.StorageOptionGlacierDeepOH, "Glacier Deep - Ohio", false},
 	{constants.StorageOptionGlacierDeepOR, "Glacier Deep - Oregon", false},
@@ -81,6 +104,8 @@ var TwoFactorMethodList = []*ListOption{
 	{constants.TwoFactorNone, "None (Turn Off Two-Factor Authentication)", false},
 	{constants.TwoFactorAuthy, "Authy OneTouch", false},
 	{constants.TwoFactorSMS, "Text Message", false},
+	{constants.TwoFactorTOTP, "Authenticator App", false},
+	{constants.TwoFactorWebAuthn, "Security Key or Passkey", false},
 }
 
 var YesNoList = []*ListOption{
@@ -120,6 +145,20 @@ func ListUsers(institutionID int64) ([]*ListOption, error) {
 	return options, nil
 }
 
+// ListStorageOptions returns a list of storage options. Option values
+// are storage option IDs.
+func ListStorageOptions() ([]*ListOption, error) {
+	storageOptions, err := pgmodels.StorageOptionGetAll()
+	if err != nil {
+		return nil, err
+	}
+	options := make([]*ListOption, len(storageOptions))
+	for i, option := range storageOptions {
+		options[i] = &ListOption{strconv.FormatInt(option.ID, 10), option.Name, false}
+	}
+	return options, nil
+}
+
 // ListDepositReportDates returns a list of dates for deposit reports.
 // Note that for each option except "Today", the label is a month and
 // year and the value is the first day of the following month. For example,
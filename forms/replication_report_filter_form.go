@@ -0,0 +1,70 @@
+package forms
+
+import (
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/pgmodels"
+)
+
+// ReplicationReportFilterForm is the form that displays filtering
+// options for the storage replication report.
+type ReplicationReportFilterForm struct {
+	Form
+	FilterCollection *pgmodels.FilterCollection
+	instOptions      []*ListOption
+}
+
+func NewReplicationReportFilterForm(fc *pgmodels.FilterCollection, actingUser *pgmodels.User) (FilterForm, error) {
+	f := &ReplicationReportFilterForm{
+		Form:             NewForm(nil, "reports/_replication_filters.html", "/reports/replication"),
+		FilterCollection: fc,
+	}
+	var err error
+	if actingUser.IsAdmin() {
+		// SysAdmin can view replication problems for all institutions.
+		f.instOptions, err = ListInstitutions(false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	f.init()
+	f.SetValues()
+	return f, nil
+}
+
+func (f *ReplicationReportFilterForm) init() {
+	f.Fields["identifier__starts_with"] = &Field{
+		Name:        "identifier__starts_with",
+		Label:       "File Identifier",
+		Placeholder: "File Identifier",
+	}
+	f.Fields["institution_id"] = &Field{
+		Name:        "institution_id",
+		Label:       "Institution",
+		Placeholder: "Institution",
+		Options:     f.instOptions,
+	}
+	f.Fields["problem"] = &Field{
+		Name:        "problem",
+		Label:       "Problem",
+		Placeholder: "Problem",
+		Options:     Options(constants.ReplicationProblems),
+	}
+	f.Fields["storage_option"] = &Field{
+		Name:        "storage_option",
+		Label:       "Storage Option",
+		Placeholder: "Storage Option",
+		Options:     Options(constants.StorageOptions),
+	}
+}
+
+// SetValues sets the form values to match the filter values.
+func (f *ReplicationReportFilterForm) SetValues() {
+	for _, fieldName := range pgmodels.ReplicationProblemFilters {
+		if f.Fields[fieldName] == nil {
+			common.ConsoleDebug("No filter for %s", fieldName)
+			continue
+		}
+		f.Fields[fieldName].Value = f.FilterCollection.ValueOf(fieldName)
+	}
+}
@@ -0,0 +1,40 @@
+package forms_test
+
+import (
+	"testing"
+
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/forms"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/APTrust/registry/web/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getReplicationReportFilterForm(t *testing.T, user *pgmodels.User) forms.FilterForm {
+	fc := pgmodels.NewFilterCollection()
+	fc.Add("identifier__starts_with", []string{"institution1.edu/gl-or"})
+	fc.Add("institution_id", []string{"2"})
+	fc.Add("problem", []string{constants.ReplicationMissingCopy})
+	fc.Add("storage_option", []string{constants.StorageOptionGlacierOR})
+	form, err := forms.NewReplicationReportFilterForm(fc, user)
+	require.Nil(t, err)
+	require.NotNil(t, form)
+	return form
+}
+
+func TestReplicationReportFilterForm(t *testing.T) {
+	sysAdmin := testutil.InitUser(t, "system@aptrust.org")
+	fields := getReplicationReportFilterForm(t, sysAdmin).GetFields()
+	assert.Equal(t, "institution1.edu/gl-or", fields["identifier__starts_with"].Value)
+	assert.Equal(t, "2", fields["institution_id"].Value)
+	assert.Equal(t, constants.ReplicationMissingCopy, fields["problem"].Value)
+	assert.Equal(t, constants.StorageOptionGlacierOR, fields["storage_option"].Value)
+	assert.True(t, len(fields["institution_id"].Options) > 1)
+	assert.Equal(t, len(constants.ReplicationProblems), len(fields["problem"].Options))
+
+	// Non-admins see only their own institution.
+	user := testutil.InitUser(t, "user@inst1.edu")
+	fields = getReplicationReportFilterForm(t, user).GetFields()
+	assert.Empty(t, fields["institution_id"].Options)
+}
@@ -0,0 +1,71 @@
+package forms
+
+import (
+	"github.com/APTrust/registry/pgmodels"
+)
+
+// SavedSearchForm lets users name a search and choose whether to pin
+// it, share it, and get emails when its result count changes. The
+// resource type and filters come from the page the user was on when
+// they saved the search, and can't be edited here.
+type SavedSearchForm struct {
+	Form
+}
+
+func NewSavedSearchForm(search *pgmodels.SavedSearch) *SavedSearchForm {
+	searchForm := &SavedSearchForm{
+		Form: NewForm(search, "saved_searches/form.html", "/saved_searches"),
+	}
+	searchForm.init()
+	searchForm.SetValues()
+	return searchForm
+}
+
+func (f *SavedSearchForm) init() {
+	f.Fields["Name"] = &Field{
+		Name:        "Name",
+		Label:       "Name",
+		Placeholder: "e.g. Failed ingests this week",
+		ErrMsg:      pgmodels.ErrSavedSearchName,
+		Attrs: map[string]string{
+			"required": "",
+		},
+	}
+	f.Fields["ResourceType"] = &Field{
+		Name:   "ResourceType",
+		ErrMsg: pgmodels.ErrSavedSearchResourceType,
+	}
+	f.Fields["QueryString"] = &Field{
+		Name:   "QueryString",
+		ErrMsg: pgmodels.ErrSavedSearchQueryString,
+	}
+	f.Fields["Pinned"] = &Field{
+		Name:  "Pinned",
+		Label: "Pin to side menu",
+	}
+	f.Fields["Shared"] = &Field{
+		Name:  "Shared",
+		Label: "Share with other users at my institution",
+	}
+	f.Fields["Notify"] = &Field{
+		Name:  "Notify",
+		Label: "Email me when the number of results changes",
+	}
+}
+
+// PostSaveURL returns the saved search list. The show page runs the
+// search, which isn't what users expect after editing one.
+func (f *SavedSearchForm) PostSaveURL() string {
+	return f.BaseURL
+}
+
+// SetValues sets the form values to match the SavedSearch values.
+func (f *SavedSearchForm) SetValues() {
+	search := f.Model.(*pgmodels.SavedSearch)
+	f.Fields["Name"].Value = search.Name
+	f.Fields["ResourceType"].Value = search.ResourceType
+	f.Fields["QueryString"].Value = search.QueryString
+	f.Fields["Pinned"].Value = search.Pinned
+	f.Fields["Shared"].Value = search.Shared
+	f.Fields["Notify"].Value = search.Notify
+}
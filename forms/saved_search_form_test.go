@@ -0,0 +1,34 @@
+package forms_test
+
+import (
+	"testing"
+
+	"github.com/APTrust/registry/forms"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSavedSearchForm(t *testing.T) {
+	user := &pgmodels.User{InstitutionID: 2}
+	user.ID = 3
+	search := pgmodels.NewSavedSearch(user, "WorkItem", "action=Ingest&page=2")
+	form := forms.NewSavedSearchForm(search)
+	require.NotNil(t, form)
+	assert.Equal(t, "/saved_searches/new", form.Action())
+	assert.Equal(t, "WorkItem", form.Fields["ResourceType"].Value)
+	assert.Equal(t, "action=Ingest", form.Fields["QueryString"].Value)
+	assert.Equal(t, false, form.Fields["Pinned"].Value)
+
+	search.ID = 12
+	search.Name = "Ingests"
+	search.Pinned = true
+	search.Notify = true
+	form = forms.NewSavedSearchForm(search)
+	assert.Equal(t, "/saved_searches/edit/12", form.Action())
+	assert.Equal(t, "/saved_searches", form.PostSaveURL())
+	assert.Equal(t, "Ingests", form.Fields["Name"].Value)
+	assert.Equal(t, true, form.Fields["Pinned"].Value)
+	assert.Equal(t, false, form.Fields["Shared"].Value)
+	assert.Equal(t, true, form.Fields["Notify"].Value)
+}
@@ -0,0 +1,68 @@
+package forms
+
+import (
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/pgmodels"
+)
+
+// SearchFilterForm is the search form at the top of the search
+// results page.
+type SearchFilterForm struct {
+	Form
+	FilterCollection *pgmodels.FilterCollection
+	instOptions      []*ListOption
+}
+
+func NewSearchFilterForm(fc *pgmodels.FilterCollection, actingUser *pgmodels.User) (FilterForm, error) {
+	f := &SearchFilterForm{
+		Form:             NewForm(nil, "search/_filters.html", "/search"),
+		FilterCollection: fc,
+	}
+	var err error
+	if actingUser.IsAdmin() {
+		// SysAdmin can search all institutions.
+		f.instOptions, err = ListInstitutions(false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	f.init()
+	f.SetValues()
+	return f, nil
+}
+
+func (f *SearchFilterForm) init() {
+	f.Fields["q"] = &Field{
+		Name:        "q",
+		Label:       "Search",
+		Placeholder: "Identifier, title, bag group, source organization...",
+	}
+	f.Fields["type"] = &Field{
+		Name:        "type",
+		Label:       "Show",
+		Placeholder: "Objects, Files and Events",
+		Options: []*ListOption{
+			{constants.SearchTypeObject, "Objects", false},
+			{constants.SearchTypeFile, "Files", false},
+			{constants.SearchTypeEvent, "Events", false},
+		},
+	}
+	f.Fields["institution_id"] = &Field{
+		Name:        "institution_id",
+		Label:       "Institution",
+		Placeholder: "Institution",
+		Options:     f.instOptions,
+	}
+}
+
+// SetValues sets the form values to match the filter values.
+func (f *SearchFilterForm) SetValues() {
+	for _, fieldName := range pgmodels.SearchFilters {
+		if f.Fields[fieldName] == nil {
+			common.ConsoleDebug("No filter for %s", fieldName)
+			continue
+		}
+		f.Fields[fieldName].Value = f.FilterCollection.ValueOf(fieldName)
+	}
+}
@@ -0,0 +1,37 @@
+package forms_test
+
+import (
+	"testing"
+
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/forms"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/APTrust/registry/web/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getSearchFilterForm(t *testing.T, user *pgmodels.User) forms.FilterForm {
+	fc := pgmodels.NewFilterCollection()
+	fc.Add("q", []string{"photos"})
+	fc.Add("type", []string{constants.SearchTypeFile})
+	form, err := forms.NewSearchFilterForm(fc, user)
+	require.Nil(t, err)
+	require.NotNil(t, form)
+	return form
+}
+
+func TestSearchFilterForm(t *testing.T) {
+	sysAdmin := testutil.InitUser(t, "system@aptrust.org")
+	fields := getSearchFilterForm(t, sysAdmin).GetFields()
+	assert.Equal(t, "photos", fields["q"].Value)
+	assert.Equal(t, constants.SearchTypeFile, fields["type"].Value)
+	assert.Equal(t, len(constants.SearchTypes), len(fields["type"].Options))
+	assert.True(t, len(fields["institution_id"].Options) > 1)
+
+	// Non-admins search only their own institution.
+	user := testutil.InitUser(t, "user@inst1.edu")
+	fields = getSearchFilterForm(t, user).GetFields()
+	assert.Equal(t, "photos", fields["q"].Value)
+	assert.Empty(t, fields["institution_id"].Options)
+}
@@ -0,0 +1,80 @@
+package forms
+
+import (
+	"time"
+
+	"github.com/APTrust/registry/pgmodels"
+)
+
+// StoragePriceForm lets APTrust admins schedule a new price for a
+// storage option.
+type StoragePriceForm struct {
+	Form
+	storageOptions []*ListOption
+}
+
+func NewStoragePriceForm(price *pgmodels.StoragePrice) (*StoragePriceForm, error) {
+	priceForm := &StoragePriceForm{
+		Form: NewForm(price, "storage_prices/form.html", "/storage_prices"),
+	}
+	var err error
+	priceForm.storageOptions, err = ListStorageOptions()
+	if err != nil {
+		return nil, err
+	}
+	priceForm.init()
+	priceForm.SetValues()
+	return priceForm, nil
+}
+
+func (f *StoragePriceForm) init() {
+	now := time.Now().UTC()
+	firstOfNextMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+	f.Fields["StorageOptionID"] = &Field{
+		Name:        "StorageOptionID",
+		Label:       "Storage Option",
+		Placeholder: "Storage Option",
+		ErrMsg:      pgmodels.ErrStoragePriceOption,
+		Options:     f.storageOptions,
+		Attrs: map[string]string{
+			"required": "",
+		},
+	}
+	f.Fields["CostGBPerMonth"] = &Field{
+		Name:        "CostGBPerMonth",
+		Label:       "Cost per GB per month (USD)",
+		Placeholder: "0.00",
+		ErrMsg:      pgmodels.ErrStoragePriceCost,
+		Attrs: map[string]string{
+			"required": "",
+			"min":      "0",
+			"step":     "any",
+		},
+	}
+	f.Fields["EffectiveFrom"] = &Field{
+		Name:        "EffectiveFrom",
+		Label:       "Effective From",
+		Placeholder: "Effective From",
+		ErrMsg:      pgmodels.ErrStoragePriceEffective,
+		Attrs: map[string]string{
+			"required": "",
+			"min":      firstOfNextMonth.Format("2006-01-02"),
+		},
+	}
+	f.Fields["Comment"] = &Field{
+		Name:        "Comment",
+		Label:       "Comment",
+		Placeholder: "Reason for the change",
+	}
+}
+
+// SetValues sets the form values to match the StoragePrice values.
+func (f *StoragePriceForm) SetValues() {
+	price := f.Model.(*pgmodels.StoragePrice)
+	f.Fields["StorageOptionID"].Value = price.StorageOptionID
+	f.Fields["CostGBPerMonth"].Value = price.CostGBPerMonth
+	if !price.EffectiveFrom.IsZero() {
+		f.Fields["EffectiveFrom"].Value = price.EffectiveFrom.Format("2006-01-02")
+	}
+	f.Fields["Comment"].Value = price.Comment
+}
@@ -0,0 +1,38 @@
+package forms_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/forms"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoragePriceForm(t *testing.T) {
+	db.LoadFixtures()
+	price := &pgmodels.StoragePrice{
+		StorageOptionID: 1,
+		CostGBPerMonth:  0.025,
+		EffectiveFrom:   time.Date(2030, 2, 1, 0, 0, 0, 0, time.UTC),
+		Comment:         "Price drop",
+	}
+	form, err := forms.NewStoragePriceForm(price)
+	require.Nil(t, err)
+	require.NotNil(t, form)
+	assert.Equal(t, "storage_prices/form.html", form.Template)
+	assert.Equal(t, "/storage_prices/new", form.Action())
+
+	fields := form.GetFields()
+	assert.Equal(t, int64(1), fields["StorageOptionID"].Value)
+	assert.Equal(t, 12, len(fields["StorageOptionID"].Options))
+	assert.Equal(t, 0.025, fields["CostGBPerMonth"].Value)
+	assert.Equal(t, "2030-02-01", fields["EffectiveFrom"].Value)
+	assert.Equal(t, "Price drop", fields["Comment"].Value)
+
+	now := time.Now().UTC()
+	nextMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+	assert.Equal(t, nextMonth.Format("2006-01-02"), fields["EffectiveFrom"].Attrs["min"])
+}
@@ -1,6 +1,8 @@
 package forms
 
 import (
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
 	"github.com/APTrust/registry/pgmodels"
 )
 
@@ -23,7 +25,7 @@ func (f *TwoFactorSetupForm) init() {
 		Label:       "Preferred Method for Two-Factor Auth",
 		Placeholder: "",
 		ErrMsg:      "Please choose your preferred method.",
-		Options:     TwoFactorMethodList,
+		Options:     f.methodOptions(),
 		Attrs: map[string]string{
 			"required": "",
 		},
@@ -37,6 +39,31 @@ func (f *TwoFactorSetupForm) init() {
 			"required": "",
 		},
 	}
+	// Users who already use an authenticator app can set up a new
+	// one, for example, when they get a new phone. Otherwise, saving
+	// this form keeps their current app.
+	if f.Model.(*pgmodels.User).IsTOTPUser() {
+		f.Fields["ResetTOTP"] = &Field{
+			Name:  "ResetTOTP",
+			Label: "Set up a new authenticator app",
+		}
+	}
+}
+
+// methodOptions returns the two-factor methods users can choose.
+// Authenticator apps are available only if the server has a
+// TOTP encryption key.
+func (f *TwoFactorSetupForm) methodOptions() []*ListOption {
+	if common.Context().Config.TwoFactor.TOTPEnabled {
+		return TwoFactorMethodList
+	}
+	options := make([]*ListOption, 0)
+	for _, option := range TwoFactorMethodList {
+		if option.Value != constants.TwoFactorTOTP {
+			options = append(options, option)
+		}
+	}
+	return options
 }
 
 // setValues sets the form values
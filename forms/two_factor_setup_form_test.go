@@ -3,6 +3,7 @@ package forms_test
 import (
 	"testing"
 
+	"github.com/APTrust/registry/common"
 	"github.com/APTrust/registry/constants"
 	"github.com/APTrust/registry/forms"
 	"github.com/APTrust/registry/pgmodels"
@@ -27,3 +28,21 @@ func TestTwoFactorSetupForm(t *testing.T) {
 	assert.Equal(t, "/users/edit/99999", form.Action())
 	assert.Equal(t, "/users/show/99999", form.PostSaveURL())
 }
+
+func TestTwoFactorSetupFormTOTPOption(t *testing.T) {
+	user := &pgmodels.User{}
+	user.ID = 99999
+	form := forms.NewTwoFactorSetupForm(user)
+	require.NotNil(t, form)
+
+	// The test config sets TOTP_ENCRYPTION_KEY, so users
+	// should be able to choose an authenticator app.
+	require.True(t, common.Context().Config.TwoFactor.TOTPEnabled)
+	found := false
+	for _, option := range form.Fields["AuthyStatus"].Options {
+		if option.Value == constants.TwoFactorTOTP {
+			found = true
+		}
+	}
+	assert.True(t, found)
+}
@@ -0,0 +1,60 @@
+package forms
+
+import (
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/stretchr/stew/slice"
+)
+
+// WebhookForm lets institutional admins register and edit webhook
+// endpoints. The secret is generated on save and never edited
+// directly, though admins can ask for a new one.
+type WebhookForm struct {
+	Form
+}
+
+func NewWebhookForm(webhook *pgmodels.Webhook) *WebhookForm {
+	webhookForm := &WebhookForm{
+		Form: NewForm(webhook, "webhooks/form.html", "/webhooks"),
+	}
+	webhookForm.init()
+	webhookForm.SetValues()
+	return webhookForm
+}
+
+func (f *WebhookForm) init() {
+	f.Fields["URL"] = &Field{
+		Name:        "URL",
+		Label:       "Endpoint URL",
+		Placeholder: "https://example.edu/aptrust/webhook",
+		ErrMsg:      pgmodels.ErrWebhookURL,
+		Attrs: map[string]string{
+			"required": "",
+		},
+	}
+	f.Fields["EventTypes"] = &Field{
+		Name:    "EventTypes",
+		Label:   "Events",
+		ErrMsg:  pgmodels.ErrWebhookEventTypes,
+		Options: Options(constants.WebhookEvents),
+	}
+	f.Fields["Enabled"] = &Field{
+		Name:  "Enabled",
+		Label: "Enabled",
+	}
+	f.Fields["RegenerateSecret"] = &Field{
+		Name:  "RegenerateSecret",
+		Label: "Generate a new secret",
+	}
+}
+
+// SetValues sets the form values to match the Webhook values.
+func (f *WebhookForm) SetValues() {
+	webhook := f.Model.(*pgmodels.Webhook)
+	f.Fields["URL"].Value = webhook.URL
+	f.Fields["EventTypes"].Value = webhook.EventTypes
+	for _, option := range f.Fields["EventTypes"].Options {
+		option.Selected = slice.Contains(webhook.EventTypes, option.Value)
+	}
+	f.Fields["Enabled"].Value = webhook.Enabled
+}
@@ -0,0 +1,31 @@
+package forms_test
+
+import (
+	"testing"
+
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/forms"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookForm(t *testing.T) {
+	webhook := pgmodels.NewWebhook(2)
+	form := forms.NewWebhookForm(webhook)
+	require.NotNil(t, form)
+	assert.Equal(t, "/webhooks/new", form.Action())
+	assert.Equal(t, len(constants.WebhookEvents), len(form.Fields["EventTypes"].Options))
+	assert.Equal(t, true, form.Fields["Enabled"].Value)
+
+	webhook.ID = 12
+	webhook.URL = "https://example.com/hook"
+	webhook.EventTypes = []string{constants.WebhookAlertCreated}
+	form = forms.NewWebhookForm(webhook)
+	assert.Equal(t, "/webhooks/edit/12", form.Action())
+	assert.Equal(t, "/webhooks/show/12", form.PostSaveURL())
+	assert.Equal(t, webhook.URL, form.Fields["URL"].Value)
+	for _, option := range form.Fields["EventTypes"].Options {
+		assert.Equal(t, option.Value == constants.WebhookAlertCreated, option.Selected, option.Value)
+	}
+}
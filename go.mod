@@ -6,24 +6,29 @@ require (
 	github.com/asaskevich/govalidator v0.0.0-20210307081110-f21760c49a8d
 	github.com/aws/aws-sdk-go v1.40.25
 	github.com/brianvoe/gofakeit/v6 v6.9.0
+	github.com/coreos/go-oidc/v3 v3.4.0
+	github.com/crewjam/saml v0.4.13
 	github.com/dcu/go-authy v1.0.1
 	github.com/gavv/httpexpect/v2 v2.14.0
 	github.com/gin-contrib/logger v0.0.2
 	github.com/gin-gonic/gin v1.7.7
 	github.com/go-pg/pg/v10 v10.9.1
 	github.com/go-redis/redis/v7 v7.4.1
+	github.com/go-webauthn/webauthn v0.3.4
 	github.com/gojektech/heimdall v5.0.2+incompatible // indirect
 	github.com/gojektech/valkyrie v0.0.0-20190210220504-8f62c1e7ba45 // indirect
-	github.com/google/uuid v1.2.0
+	github.com/google/uuid v1.3.0
 	github.com/gorilla/securecookie v1.1.1
 	github.com/jinzhu/copier v0.3.0
 	github.com/k0kubun/colorstring v0.0.0-20150214042306-9440f1994b88 // indirect
 	github.com/nsqio/nsq v1.2.0
 	github.com/nyaruka/phonenumbers v1.0.71
+	github.com/pquerna/otp v1.4.0
 	github.com/rs/zerolog v1.20.0
 	github.com/spf13/viper v1.7.1
 	github.com/stretchr/stew v0.0.0-20130812190256-80ef0842b48b
 	github.com/stretchr/testify v1.8.2
-	golang.org/x/crypto v0.0.0-20220214200702-86341886e292
+	golang.org/x/crypto v0.0.0-20220622213112-05595931fe9d
+	golang.org/x/oauth2 v0.0.0-20220822191816-0ebed06d0094
 	golang.org/x/text v0.7.0
 )
@@ -0,0 +1,34 @@
+package helpers
+
+import (
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/gin-gonic/gin"
+)
+
+// NewAuditEvent returns a new, unsaved audit event about the specified
+// user, with the client's IP address and user agent taken from the
+// current request. Param user may be nil.
+func NewAuditEvent(c *gin.Context, action, outcome string, user *pgmodels.User, detail string) *pgmodels.AuditEvent {
+	event := pgmodels.NewAuditEvent(action, outcome, user)
+	event.Detail = detail
+	event.IPAddress = c.ClientIP()
+	event.UserAgent = c.Request.UserAgent()
+	return event
+}
+
+// RecordAuditEvent saves an audit event. Failure to write to the audit
+// log should not break the request that triggered it, so this logs
+// errors rather than returning them.
+func RecordAuditEvent(event *pgmodels.AuditEvent) {
+	err := event.Save()
+	if err != nil {
+		common.Context().Log.Error().Msgf("Could not save audit event %s/%s for user %s: %v", event.Action, event.Outcome, event.UserEmail, err)
+	}
+}
+
+// Audit records an audit event about the specified user. This is a
+// shortcut for NewAuditEvent followed by RecordAuditEvent.
+func Audit(c *gin.Context, action, outcome string, user *pgmodels.User, detail string) {
+	RecordAuditEvent(NewAuditEvent(c, action, outcome, user, detail))
+}
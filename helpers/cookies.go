@@ -1,13 +1,12 @@
 package helpers
 
 import (
-	"fmt"
 	"net/http"
 
 	"github.com/APTrust/registry/common"
 	"github.com/APTrust/registry/constants"
 	"github.com/APTrust/registry/pgmodels"
-	//"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin"
 )
 
 // CookieSetter defines the methods used by gin.Context
@@ -54,10 +53,28 @@ func DeleteCookie(c CookieSetter, name string) {
 	)
 }
 
-func SetSessionCookie(c CookieSetter, user *pgmodels.User) error {
+// SetSessionCookie sets the session cookie to the new session's token.
+// The session must be new, since we don't store the token for existing
+// sessions.
+func SetSessionCookie(c CookieSetter, session *pgmodels.UserSession) error {
 	ctx := common.Context()
-	id := fmt.Sprintf("%d", user.ID)
-	return SetCookie(c, ctx.Config.Cookies.SessionCookie, id)
+	return SetCookie(c, ctx.Config.Cookies.SessionCookie, session.Token)
+}
+
+// SessionToken returns the decoded session token from the session
+// cookie. This returns http.ErrNoCookie if there's no session cookie,
+// and common.ErrDecodeCookie if the cookie is invalid.
+func SessionToken(c *gin.Context) (string, error) {
+	ctx := common.Context()
+	cookie, err := c.Cookie(ctx.Config.Cookies.SessionCookie)
+	if err != nil {
+		return "", err
+	}
+	token := ""
+	if err = ctx.Config.Cookies.Secure.Decode(ctx.Config.Cookies.SessionCookie, cookie, &token); err != nil {
+		return "", common.ErrDecodeCookie
+	}
+	return token, nil
 }
 
 func DeleteSessionCookie(c CookieSetter) {
@@ -100,3 +117,13 @@ func CurrentUser(c CookieSetter) *pgmodels.User {
 	}
 	return nil
 }
+
+// CurrentSession returns the session the current user authenticated
+// with, or nil if they authenticated some other way, such as with
+// API headers.
+func CurrentSession(c CookieSetter) *pgmodels.UserSession {
+	if session, ok := c.Get("UserSession"); ok && session != nil {
+		return session.(*pgmodels.UserSession)
+	}
+	return nil
+}
@@ -64,7 +64,8 @@ func getSetter(t *testing.T) *TestCookieSetter {
 
 func TestSessionCookie(t *testing.T) {
 	setter := getSetter(t)
-	helpers.SetSessionCookie(setter, cookieUser)
+	session := pgmodels.NewUserSession(cookieUser.ID, "127.0.0.1", "Test Agent")
+	helpers.SetSessionCookie(setter, session)
 
 	name := common.Context().Config.Cookies.SessionCookie
 	cookie := setter.Cookies[name]
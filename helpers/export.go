@@ -0,0 +1,209 @@
+package helpers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/APTrust/registry/common"
+)
+
+const (
+	ExportFormatCSV   = "csv"
+	ExportFormatJSONL = "jsonl"
+)
+
+// exportMediaTypes maps Accept header media types to export formats.
+var exportMediaTypes = map[string]string{
+	"text/csv":                ExportFormatCSV,
+	"application/x-ndjson":    ExportFormatJSONL,
+	"application/jsonl":       ExportFormatJSONL,
+	"application/jsonlines":   ExportFormatJSONL,
+	"application/x-jsonlines": ExportFormatJSONL,
+}
+
+// ExportFormat returns the export format the client asked for, or
+// an empty string if the client wants a regular paged response.
+// An explicit format=csv or format=jsonl on the query string takes
+// precedence over the Accept header. The Accept header counts only
+// if it names text/csv or one of the JSON-lines media types.
+func ExportFormat(r *http.Request) string {
+	switch strings.ToLower(r.URL.Query().Get("format")) {
+	case ExportFormatCSV:
+		return ExportFormatCSV
+	case ExportFormatJSONL, "ndjson":
+		return ExportFormatJSONL
+	}
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+		if format, ok := exportMediaTypes[mediaType]; ok {
+			return format
+		}
+	}
+	return ""
+}
+
+// ExportContentType returns the Content-Type header for the
+// specified export format.
+func ExportContentType(format string) string {
+	if format == ExportFormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson; charset=utf-8"
+}
+
+// ExportWriter writes lists of records as CSV or JSON lines. For CSV,
+// the columns are the exported fields of the record type that have
+// a json tag and a scalar value (strings, numbers, booleans and
+// timestamps). Timestamps are written in RFC3339 format, and zero
+// timestamps are written as empty strings. JSON lines include each
+// record's full JSON representation.
+type ExportWriter struct {
+	format  string
+	csv     *csv.Writer
+	json    *json.Encoder
+	columns []exportColumn
+}
+
+type exportColumn struct {
+	name  string
+	index []int
+}
+
+// NewExportWriter returns an ExportWriter that writes records in
+// the specified format to w. It returns common.ErrInvalidParam
+// if format is not csv or jsonl.
+func NewExportWriter(w io.Writer, format string) (*ExportWriter, error) {
+	ew := &ExportWriter{format: format}
+	switch format {
+	case ExportFormatCSV:
+		ew.csv = csv.NewWriter(w)
+	case ExportFormatJSONL:
+		ew.json = json.NewEncoder(w)
+	default:
+		return nil, common.ErrInvalidParam
+	}
+	return ew, nil
+}
+
+// WriteList writes each record in items, which must be a slice of
+// pointers to structs. For CSV, the first call also writes the
+// header row, even if items is empty.
+func (ew *ExportWriter) WriteList(items interface{}) error {
+	list := reflect.ValueOf(items)
+	if list.Kind() == reflect.Ptr {
+		list = list.Elem()
+	}
+	if list.Kind() != reflect.Slice {
+		return common.ErrInvalidParam
+	}
+	if ew.format == ExportFormatCSV && ew.columns == nil {
+		if err := ew.writeHeader(list.Type().Elem()); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < list.Len(); i++ {
+		var err error
+		if ew.format == ExportFormatCSV {
+			err = ew.writeCSV(list.Index(i))
+		} else {
+			err = ew.json.Encode(list.Index(i).Interface())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush writes any buffered CSV data to the underlying writer.
+func (ew *ExportWriter) Flush() error {
+	if ew.csv != nil {
+		ew.csv.Flush()
+		return ew.csv.Error()
+	}
+	return nil
+}
+
+func (ew *ExportWriter) writeHeader(recordType reflect.Type) error {
+	if recordType.Kind() == reflect.Ptr {
+		recordType = recordType.Elem()
+	}
+	if recordType.Kind() != reflect.Struct {
+		return common.ErrInvalidParam
+	}
+	ew.columns = exportColumns(recordType)
+	header := make([]string, len(ew.columns))
+	for i, col := range ew.columns {
+		header[i] = col.name
+	}
+	return ew.csv.Write(header)
+}
+
+func (ew *ExportWriter) writeCSV(record reflect.Value) error {
+	record = reflect.Indirect(record)
+	row := make([]string, len(ew.columns))
+	for i, col := range ew.columns {
+		row[i] = exportValue(record.FieldByIndex(col.index))
+	}
+	return ew.csv.Write(row)
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// exportColumns returns the CSV columns for struct type t.
+func exportColumns(t reflect.Type) []exportColumn {
+	columns := make([]exportColumn, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			for _, col := range exportColumns(field.Type) {
+				col.index = append([]int{i}, col.index...)
+				columns = append(columns, col)
+			}
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" || !isExportable(field.Type) {
+			continue
+		}
+		columns = append(columns, exportColumn{name: name, index: []int{i}})
+	}
+	return columns
+}
+
+func isExportable(t reflect.Type) bool {
+	if t == timeType {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func exportValue(v reflect.Value) string {
+	if t, ok := v.Interface().(time.Time); ok {
+		if t.IsZero() {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+	return fmt.Sprint(v.Interface())
+}
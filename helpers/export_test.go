@@ -0,0 +1,129 @@
+package helpers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/helpers"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportFormat(t *testing.T) {
+	testCases := []struct {
+		url      string
+		accept   string
+		expected string
+	}{
+		{"/objects", "", ""},
+		{"/objects", "application/json", ""},
+		{"/objects?format=csv", "", helpers.ExportFormatCSV},
+		{"/objects?format=CSV", "", helpers.ExportFormatCSV},
+		{"/objects?format=jsonl", "", helpers.ExportFormatJSONL},
+		{"/objects?format=ndjson", "", helpers.ExportFormatJSONL},
+		{"/objects?format=xml", "", ""},
+		{"/objects", "text/csv", helpers.ExportFormatCSV},
+		{"/objects", "text/html, text/csv;q=0.9", helpers.ExportFormatCSV},
+		{"/objects", "application/x-ndjson", helpers.ExportFormatJSONL},
+		{"/objects", "application/jsonl", helpers.ExportFormatJSONL},
+
+		// Query string overrides Accept header
+		{"/objects?format=jsonl", "text/csv", helpers.ExportFormatJSONL},
+	}
+	for _, tc := range testCases {
+		req, err := http.NewRequest(http.MethodGet, tc.url, nil)
+		require.Nil(t, err)
+		if tc.accept != "" {
+			req.Header.Set("Accept", tc.accept)
+		}
+		assert.Equal(t, tc.expected, helpers.ExportFormat(req), "%s / %s", tc.url, tc.accept)
+	}
+}
+
+func TestExportContentType(t *testing.T) {
+	assert.Equal(t, "text/csv; charset=utf-8", helpers.ExportContentType(helpers.ExportFormatCSV))
+	assert.Equal(t, "application/x-ndjson; charset=utf-8", helpers.ExportContentType(helpers.ExportFormatJSONL))
+}
+
+func getExportItems() []*pgmodels.WorkItemView {
+	return []*pgmodels.WorkItemView{
+		{
+			ID:            1,
+			Name:          "bag1.tar",
+			InstitutionID: 2,
+			Action:        constants.ActionIngest,
+			Note:          "Note with a comma, and \"quotes\"",
+			DateProcessed: testDate,
+			Retry:         true,
+		},
+		{
+			ID:            2,
+			Name:          "bag2.tar",
+			InstitutionID: 2,
+			Action:        constants.ActionRestoreObject,
+		},
+	}
+}
+
+func TestExportWriterCSV(t *testing.T) {
+	buf := &bytes.Buffer{}
+	writer, err := helpers.NewExportWriter(buf, helpers.ExportFormatCSV)
+	require.Nil(t, err)
+
+	items := getExportItems()
+	require.Nil(t, writer.WriteList(&items))
+	require.Nil(t, writer.Flush())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Equal(t, 3, len(lines))
+	assert.True(t, strings.HasPrefix(lines[0], "id,name,etag,institution_id,"))
+	assert.True(t, strings.HasPrefix(lines[1], "1,bag1.tar,,2,"))
+	assert.Contains(t, lines[1], `"Note with a comma, and ""quotes"""`)
+	assert.Contains(t, lines[1], "2021-04-16T12:24:16Z")
+	assert.True(t, strings.HasPrefix(lines[2], "2,bag2.tar,,2,"))
+
+	// Empty lists get a header row and nothing else.
+	buf = &bytes.Buffer{}
+	writer, err = helpers.NewExportWriter(buf, helpers.ExportFormatCSV)
+	require.Nil(t, err)
+	empty := make([]*pgmodels.WorkItemView, 0)
+	require.Nil(t, writer.WriteList(&empty))
+	require.Nil(t, writer.Flush())
+	lines = strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Equal(t, 1, len(lines))
+	assert.True(t, strings.HasPrefix(lines[0], "id,name,etag,"))
+}
+
+func TestExportWriterJSONL(t *testing.T) {
+	buf := &bytes.Buffer{}
+	writer, err := helpers.NewExportWriter(buf, helpers.ExportFormatJSONL)
+	require.Nil(t, err)
+
+	items := getExportItems()
+	require.Nil(t, writer.WriteList(&items))
+	require.Nil(t, writer.Flush())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Equal(t, 2, len(lines))
+	for i, line := range lines {
+		item := &pgmodels.WorkItemView{}
+		require.Nil(t, json.Unmarshal([]byte(line), item))
+		assert.Equal(t, items[i].ID, item.ID)
+		assert.Equal(t, items[i].Name, item.Name)
+	}
+}
+
+func TestExportWriterInvalid(t *testing.T) {
+	_, err := helpers.NewExportWriter(&bytes.Buffer{}, "xml")
+	assert.Equal(t, common.ErrInvalidParam, err)
+
+	writer, err := helpers.NewExportWriter(&bytes.Buffer{}, helpers.ExportFormatCSV)
+	require.Nil(t, err)
+	assert.Equal(t, common.ErrInvalidParam, writer.WriteList("not a list"))
+}
@@ -0,0 +1,135 @@
+package helpers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	"github.com/APTrust/registry/pgmodels"
+)
+
+// invoiceCSVHeader lists the columns of an invoice CSV file.
+var invoiceCSVHeader = []string{
+	"invoice_number",
+	"institution",
+	"billing_month",
+	"line_type",
+	"account",
+	"storage_option",
+	"total_gb",
+	"free_gb",
+	"billable_gb",
+	"cost_gb_per_month",
+	"amount",
+}
+
+// WriteInvoiceCSV writes an invoice as CSV, with one row per line item
+// and a final row with the amount due. Each row repeats the invoice
+// number, so finance staff can combine several invoices in one sheet.
+func WriteInvoiceCSV(w io.Writer, invoice *pgmodels.Invoice) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write(invoiceCSVHeader)
+	if err != nil {
+		return err
+	}
+	month := invoice.BillingMonth.Format("2006-01")
+	for _, item := range invoice.LineItems {
+		lineType := "billed"
+		if item.Breakdown {
+			lineType = "breakdown"
+		}
+		err = writer.Write([]string{
+			invoice.InvoiceNumber,
+			invoice.InstitutionName,
+			month,
+			lineType,
+			item.InstitutionName,
+			item.StorageOption,
+			fmt.Sprintf("%.2f", item.TotalGB),
+			fmt.Sprintf("%.2f", item.FreeGB),
+			fmt.Sprintf("%.2f", item.BillableGB),
+			fmt.Sprintf("%g", item.CostGBPerMonth),
+			fmt.Sprintf("%.2f", item.Amount),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	err = writer.Write([]string{
+		invoice.InvoiceNumber,
+		invoice.InstitutionName,
+		month,
+		"total",
+		invoice.InstitutionName,
+		"",
+		fmt.Sprintf("%.2f", invoice.TotalGB),
+		fmt.Sprintf("%.2f", invoice.TotalGB-invoice.BillableGB),
+		fmt.Sprintf("%.2f", invoice.BillableGB),
+		"",
+		fmt.Sprintf("%.2f", invoice.AmountDue),
+	})
+	if err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// InvoicePDF returns an invoice as a PDF document.
+func InvoicePDF(invoice *pgmodels.Invoice) []byte {
+	left := PDFMargin
+	right := PDFPageWidth - PDFMargin
+	doc := NewPDFDocument()
+	doc.WriteRow(18, true, PDFColumn{X: left, Text: "APTrust Storage Invoice"})
+	doc.Space(8)
+	doc.WriteRow(10, false, PDFColumn{X: left, Text: "Invoice Number"}, PDFColumn{X: left + 110, Text: invoice.InvoiceNumber})
+	doc.WriteRow(10, false, PDFColumn{X: left, Text: "Institution"}, PDFColumn{X: left + 110, Text: invoice.InstitutionName})
+	doc.WriteRow(10, false, PDFColumn{X: left, Text: "Billing Month"}, PDFColumn{X: left + 110, Text: invoice.BillingMonth.Format("January 2006")})
+	doc.WriteRow(10, false, PDFColumn{X: left, Text: "Issued"}, PDFColumn{X: left + 110, Text: DateUS(invoice.CreatedAt)})
+	doc.WriteRow(10, false, PDFColumn{X: left, Text: "Free Allowance"}, PDFColumn{X: left + 110, Text: fmt.Sprintf("%s TB", FormatFloat(invoice.FreeAllowanceTB, 2))})
+	doc.Space(12)
+
+	writeInvoiceLines(doc, "Storage Option", invoice.BilledLines(), false)
+	doc.Space(4)
+	doc.WriteRow(11, true,
+		PDFColumn{X: left, Text: "Amount Due"},
+		PDFColumn{X: right, Text: fmt.Sprintf("$%s", FormatFloat(invoice.AmountDue, 2)), AlignRight: true})
+
+	breakdown := invoice.BreakdownLines()
+	if len(breakdown) > 0 {
+		doc.Space(20)
+		doc.WriteRow(12, true, PDFColumn{X: left, Text: "Usage by Account"})
+		doc.WriteRow(9, false, PDFColumn{X: left, Text: "For information only. Amounts are before the free allowance and are included in the lines above."})
+		doc.Space(4)
+		writeInvoiceLines(doc, "Account / Storage Option", breakdown, true)
+	}
+	return doc.Bytes()
+}
+
+// writeInvoiceLines writes a table of invoice line items.
+func writeInvoiceLines(doc *PDFDocument, label string, items []*pgmodels.InvoiceLineItem, showAccount bool) {
+	left := PDFMargin
+	right := PDFPageWidth - PDFMargin
+	doc.WriteRow(9, true,
+		PDFColumn{X: left, Text: label},
+		PDFColumn{X: 300, Text: "Total GB", AlignRight: true},
+		PDFColumn{X: 370, Text: "Free GB", AlignRight: true},
+		PDFColumn{X: 440, Text: "Billable GB", AlignRight: true},
+		PDFColumn{X: 495, Text: "$/GB", AlignRight: true},
+		PDFColumn{X: right, Text: "Amount", AlignRight: true})
+	doc.Rule()
+	for _, item := range items {
+		name := item.StorageOption
+		if showAccount {
+			name = fmt.Sprintf("%s / %s", item.InstitutionName, item.StorageOption)
+		}
+		doc.WriteRow(9, false,
+			PDFColumn{X: left, Text: name},
+			PDFColumn{X: 300, Text: FormatFloat(item.TotalGB, 2), AlignRight: true},
+			PDFColumn{X: 370, Text: FormatFloat(item.FreeGB, 2), AlignRight: true},
+			PDFColumn{X: 440, Text: FormatFloat(item.BillableGB, 2), AlignRight: true},
+			PDFColumn{X: 495, Text: fmt.Sprintf("%g", item.CostGBPerMonth), AlignRight: true},
+			PDFColumn{X: right, Text: fmt.Sprintf("$%s", FormatFloat(item.Amount, 2)), AlignRight: true})
+	}
+	doc.Rule()
+}
@@ -0,0 +1,57 @@
+package helpers_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/APTrust/registry/helpers"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestInvoice() *pgmodels.Invoice {
+	invoice := &pgmodels.Invoice{
+		InvoiceNumber:   "APT-202301-00003",
+		InstitutionID:   3,
+		InstitutionName: "Institution Two",
+		BillingMonth:    time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		FreeAllowanceTB: 1,
+		TotalGB:         3584,
+		TotalTB:         3.5,
+		BillableGB:      2560,
+		AmountDue:       58.32,
+		LineItems: []*pgmodels.InvoiceLineItem{
+			{InstitutionName: "Institution Two", StorageOption: "Standard", TotalGB: 3072, FreeGB: 1024, BillableGB: 2048, CostGBPerMonth: 0.027, Amount: 55.30},
+			{InstitutionName: "Institution Two", StorageOption: "Wasabi-OR", TotalGB: 512, BillableGB: 512, CostGBPerMonth: 0.0059, Amount: 3.02},
+			{InstitutionName: "Sub-Account", StorageOption: "Standard", Breakdown: true, TotalGB: 3072, CostGBPerMonth: 0.027, Amount: 82.94},
+		},
+	}
+	invoice.CreatedAt = time.Date(2023, 2, 2, 8, 0, 0, 0, time.UTC)
+	return invoice
+}
+
+func TestWriteInvoiceCSV(t *testing.T) {
+	var buf bytes.Buffer
+	require.Nil(t, helpers.WriteInvoiceCSV(&buf, getTestInvoice()))
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.Nil(t, err)
+	require.Len(t, rows, 5)
+	assert.Equal(t, "invoice_number", rows[0][0])
+	assert.Equal(t, []string{"APT-202301-00003", "Institution Two", "2023-01", "billed", "Institution Two", "Standard", "3072.00", "1024.00", "2048.00", "0.027", "55.30"}, rows[1])
+	assert.Equal(t, "billed", rows[2][3])
+	assert.Equal(t, "breakdown", rows[3][3])
+	assert.Equal(t, "Sub-Account", rows[3][4])
+	assert.Equal(t, []string{"APT-202301-00003", "Institution Two", "2023-01", "total", "Institution Two", "", "3584.00", "1024.00", "2560.00", "", "58.32"}, rows[4])
+}
+
+func TestInvoicePDF(t *testing.T) {
+	pdf := string(helpers.InvoicePDF(getTestInvoice()))
+	assert.Contains(t, pdf, "(APT-202301-00003) Tj")
+	assert.Contains(t, pdf, "(January 2023) Tj")
+	assert.Contains(t, pdf, "($58.32) Tj")
+	assert.Contains(t, pdf, "(Usage by Account) Tj")
+	assert.Contains(t, pdf, "(Sub-Account / Standard) Tj")
+}
@@ -0,0 +1,154 @@
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Page geometry for PDF documents, in points. Pages are US Letter.
+const (
+	PDFPageWidth  = 612.0
+	PDFPageHeight = 792.0
+	PDFMargin     = 54.0
+)
+
+// PDFColumn is one piece of text in a PDF row. X is the distance from
+// the left edge of the page. If AlignRight is true, the text ends at X
+// instead of starting there.
+type PDFColumn struct {
+	X          float64
+	Text       string
+	AlignRight bool
+}
+
+// PDFDocument builds simple, text-only PDF documents, such as invoices,
+// one row at a time. It uses the standard Helvetica fonts, which every
+// PDF reader has, so documents don't need embedded fonts. Characters
+// outside of printable ASCII are written as question marks. Rows that
+// don't fit on the current page start a new one.
+type PDFDocument struct {
+	pages   []*bytes.Buffer
+	current *bytes.Buffer
+	y       float64
+}
+
+// NewPDFDocument returns a new document with one empty page.
+func NewPDFDocument() *PDFDocument {
+	doc := &PDFDocument{}
+	doc.newPage()
+	return doc
+}
+
+// WriteRow writes a row of text in the specified font size, then moves
+// down to the next row.
+func (doc *PDFDocument) WriteRow(fontSize float64, bold bool, columns ...PDFColumn) {
+	lineHeight := fontSize * 1.4
+	if doc.y-lineHeight < PDFMargin {
+		doc.newPage()
+	}
+	doc.y -= lineHeight
+	font := "/F1"
+	if bold {
+		font = "/F2"
+	}
+	for _, col := range columns {
+		text := pdfText(col.Text)
+		x := col.X
+		if col.AlignRight {
+			x -= pdfTextWidth(text, fontSize)
+		}
+		fmt.Fprintf(doc.current, "BT %s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, fontSize, x, doc.y, pdfEscape(text))
+	}
+}
+
+// Space moves down the page by the specified number of points.
+func (doc *PDFDocument) Space(points float64) {
+	doc.y -= points
+}
+
+// Rule draws a thin horizontal line across the page, between the
+// margins, just below the last row.
+func (doc *PDFDocument) Rule() {
+	doc.y -= 4
+	fmt.Fprintf(doc.current, "0.5 w %.2f %.2f m %.2f %.2f l S\n", PDFMargin, doc.y, PDFPageWidth-PDFMargin, doc.y)
+}
+
+// Bytes returns the complete PDF document.
+func (doc *PDFDocument) Bytes() []byte {
+	var out bytes.Buffer
+	offsets := make([]int, 0)
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// Objects 1-4 are the catalog, page tree, and fonts. Each page
+	// then has a page object followed by its content stream.
+	kids := make([]string, len(doc.pages))
+	for i := range doc.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+i*2)
+	}
+	out.WriteString("%PDF-1.4\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(doc.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, page := range doc.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			PDFPageWidth, PDFPageHeight, 6+i*2))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
+
+func (doc *PDFDocument) newPage() {
+	doc.current = &bytes.Buffer{}
+	doc.pages = append(doc.pages, doc.current)
+	doc.y = PDFPageHeight - PDFMargin
+}
+
+// pdfText replaces characters the standard fonts can't show.
+func pdfText(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 32 || r > 126 {
+			return '?'
+		}
+		return r
+	}, text)
+}
+
+// pdfEscape escapes the characters that have special meaning in
+// PDF string literals.
+func pdfEscape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`).Replace(text)
+}
+
+// pdfTextWidth returns the approximate width of text in points. We
+// right-align only numbers and amounts, so this has exact widths for
+// digits and the punctuation that goes with them, which are the same
+// in Helvetica and Helvetica-Bold, and uses an average width for
+// everything else.
+func pdfTextWidth(text string, fontSize float64) float64 {
+	units := 0.0
+	for _, r := range text {
+		switch {
+		case r >= '0' && r <= '9', r == '$':
+			units += 556
+		case r == '.' || r == ',' || r == ' ':
+			units += 278
+		case r == '-':
+			units += 333
+		default:
+			units += 600
+		}
+	}
+	return units * fontSize / 1000
+}
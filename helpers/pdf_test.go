@@ -0,0 +1,54 @@
+package helpers_test
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/APTrust/registry/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPDFDocument(t *testing.T) {
+	doc := helpers.NewPDFDocument()
+	doc.WriteRow(18, true, helpers.PDFColumn{X: helpers.PDFMargin, Text: "Title (with parens)"})
+	doc.Rule()
+	doc.WriteRow(10, false,
+		helpers.PDFColumn{X: helpers.PDFMargin, Text: "Café"},
+		helpers.PDFColumn{X: 500, Text: "$1,234.56", AlignRight: true})
+	pdf := doc.Bytes()
+
+	assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")))
+	assert.True(t, bytes.HasSuffix(pdf, []byte("%%EOF\n")))
+	assert.Contains(t, string(pdf), `(Title \(with parens\)) Tj`)
+	assert.Contains(t, string(pdf), "(Caf?) Tj")
+	assert.Contains(t, string(pdf), "/Count 1")
+
+	// startxref should point to the xref table, and each xref entry
+	// should point to the object it lists.
+	match := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+	require.NotNil(t, match)
+	xref, err := strconv.Atoi(string(match[1]))
+	require.Nil(t, err)
+	assert.True(t, bytes.HasPrefix(pdf[xref:], []byte("xref\n0 7\n")))
+	offsets := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(pdf, -1)
+	require.Len(t, offsets, 6)
+	for i, offset := range offsets {
+		pos, err := strconv.Atoi(string(offset[1]))
+		require.Nil(t, err)
+		assert.True(t, bytes.HasPrefix(pdf[pos:], []byte(fmt.Sprintf("%d 0 obj", i+1))))
+	}
+}
+
+func TestPDFDocumentPages(t *testing.T) {
+	doc := helpers.NewPDFDocument()
+	for i := 0; i < 100; i++ {
+		doc.WriteRow(10, false, helpers.PDFColumn{X: helpers.PDFMargin, Text: fmt.Sprintf("Row %d", i)})
+	}
+	pdf := string(doc.Bytes())
+	assert.Contains(t, pdf, "/Count 3")
+	assert.Contains(t, pdf, "(Row 99) Tj")
+}
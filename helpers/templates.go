@@ -246,6 +246,18 @@ func SortUrl(currentUrl *url.URL, colName string) string {
 	return fmt.Sprintf("%s?%s", currentUrl.Path, vals.Encode())
 }
 
+// ExportUrl returns a URL that downloads all records matching the
+// current page's filters in the specified format (csv or jsonl).
+// The URL keeps the current filters and sort order, but not the
+// page number, since exports include every matching record.
+func ExportUrl(currentUrl *url.URL, format string) string {
+	vals := currentUrl.Query()
+	vals.Del("page")
+	vals.Del("per_page")
+	vals.Set("format", format)
+	return fmt.Sprintf("%s?%s", currentUrl.Path, vals.Encode())
+}
+
 // SortIcon returns the name of the sort icon to display at the
 // top of a table column. This will be either "keyboard_arrow_up"
 // or "keyboard_arrow_down"
@@ -216,6 +216,14 @@ func TestSortURL(t *testing.T) {
 	assert.Equal(t, "/objects?age=39&name=homer&sort=zip_code__asc", helpers.SortUrl(currentUrl, "zip_code"))
 }
 
+func TestExportUrl(t *testing.T) {
+	currentUrl, err := url.Parse("https://example.com/objects?name=homer&page=3&per_page=20&sort=salary__asc")
+	require.Nil(t, err)
+
+	assert.Equal(t, "/objects?format=csv&name=homer&sort=salary__asc", helpers.ExportUrl(currentUrl, "csv"))
+	assert.Equal(t, "/objects?format=jsonl&name=homer&sort=salary__asc", helpers.ExportUrl(currentUrl, "jsonl"))
+}
+
 func TestLinkifyUrl(t *testing.T) {
 	text := `Sample alert text.
 	This is a local link: http://localhost/alerts/yadda and this
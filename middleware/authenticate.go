@@ -3,8 +3,8 @@ package middleware
 import (
 	"fmt"
 	"net/http"
-	"strconv"
 	"strings"
+	"time"
 
 	"github.com/APTrust/registry/common"
 	"github.com/APTrust/registry/constants"
@@ -57,32 +57,47 @@ func GetUser(c *gin.Context) (user *pgmodels.User, err error) {
 	return GetUserFromSession(c)
 }
 
-// GetUserFromSession returns the User for the current session.
+// GetUserFromSession returns the User for the current session. The
+// session cookie holds a session token, which must match an unexpired
+// session in the user_sessions table. Deleting a user's sessions
+// signs them out, even if their browser still has the cookie.
 func GetUserFromSession(c *gin.Context) (user *pgmodels.User, err error) {
 	ctx := common.Context()
-	cookie, err := c.Cookie(ctx.Config.Cookies.SessionCookie)
-	if err == nil {
-		value := ""
-		if err = ctx.Config.Cookies.Secure.Decode(ctx.Config.Cookies.SessionCookie, cookie, &value); err != nil {
-			ctx.Log.Error().Msgf("GetUserFromSession: Error decoding session cookie: %v", err)
-			return nil, common.ErrDecodeCookie
-		}
-		var userID int64
-		userID, err = strconv.ParseInt(value, 10, 64)
-		if err != nil {
-			ctx.Log.Error().Msgf("GetUserFromSession: Session cookie contains non-numeric user id: %s", value)
-			return nil, common.ErrWrongDataType
-		}
-		user, err = pgmodels.UserByID(userID)
-		if err != nil {
-			ctx.Log.Error().Msgf("GetUserFromSession: Got user id from session cookie but user lookup returned error: %v", err)
-		}
-	} else {
+	token, err := helpers.SessionToken(c)
+	if err == common.ErrDecodeCookie {
+		ctx.Log.Error().Msgf("GetUserFromSession: Error decoding session cookie")
+		return nil, err
+	} else if err != nil {
 		// This is for a specific and recurrent auth error.
 		// https://trello.com/c/rHKhPkau
-		err = fmt.Errorf("%v - missing cookie is %s", err, ctx.Config.Cookies.SessionCookie)
+		return nil, fmt.Errorf("%v - missing cookie is %s", err, ctx.Config.Cookies.SessionCookie)
+	}
+	session, err := pgmodels.UserSessionByToken(token)
+	if err != nil {
+		if pgmodels.IsNoRowError(err) {
+			ctx.Log.Warn().Msgf("GetUserFromSession: Session cookie matches no session. It may have been revoked.")
+			return nil, common.ErrNotSignedIn
+		}
+		ctx.Log.Error().Msgf("GetUserFromSession: Session lookup returned error: %v", err)
+		return nil, err
+	}
+	if session.IsExpired() {
+		ctx.Log.Info().Msgf("GetUserFromSession: Session %d for user %d expired at %s", session.ID, session.UserID, session.LastSeenAt.Format(time.RFC3339))
+		if err = session.Delete(); err != nil {
+			ctx.Log.Warn().Msgf("GetUserFromSession: Could not delete expired session %d: %v", session.ID, err)
+		}
+		return nil, common.ErrNotSignedIn
+	}
+	user, err = pgmodels.UserByID(session.UserID)
+	if err != nil {
+		ctx.Log.Error().Msgf("GetUserFromSession: Got user id from session but user lookup returned error: %v", err)
+		return nil, err
 	}
-	return user, err
+	if err = session.Touch(c.ClientIP()); err != nil {
+		ctx.Log.Warn().Msgf("GetUserFromSession: Could not update last seen time for session %d: %v", session.ID, err)
+	}
+	c.Set("UserSession", session)
+	return user, nil
 }
 
 // GetUserFromAPIHeaders returns the current user based on the API
@@ -94,9 +109,32 @@ func GetUserFromAPIHeaders(c *gin.Context) (user *pgmodels.User, err error) {
 	user, err = pgmodels.UserByEmail(apiUserEmail)
 	if err != nil {
 		ctx.Log.Error().Msgf("GetUserFromAPIHeaders: Attempt to look up user %s failed with error %v", apiUserEmail, err)
+		if pgmodels.IsNoRowError(err) {
+			event := helpers.NewAuditEvent(c, constants.AuditAPIAuthentication, constants.OutcomeFailure, nil, "Unknown user")
+			event.UserEmail = apiUserEmail
+			helpers.RecordAuditEvent(event)
+		}
+		return nil, err
+	}
+	// Check the user's named API keys first. If none match, fall back
+	// to the legacy single key on the user record, so existing clients
+	// keep working while users move to named keys.
+	apiKey, err := pgmodels.APIKeyFor(user.ID, apiUserKey)
+	if err != nil {
+		ctx.Log.Error().Msgf("GetUserFromAPIHeaders: Attempt to look up API keys for user %s failed with error %v", apiUserEmail, err)
 		return nil, err
 	}
-	if common.ComparePasswords(user.EncryptedAPISecretKey, apiUserKey) {
+	if apiKey != nil {
+		err = apiKey.RecordUse(c.ClientIP())
+		if err != nil {
+			ctx.Log.Warn().Msgf("GetUserFromAPIHeaders: Could not record use of API key %d: %v", apiKey.ID, err)
+		}
+		// The authorization middleware checks the key's scope
+		// and permissions.
+		c.Set("APIKey", apiKey)
+	}
+	if apiKey != nil || common.ComparePasswords(user.EncryptedAPISecretKey, apiUserKey) {
+		auditAPIKeyUse(c, user, apiKey)
 		// Set this because API requests bypass CSRF protection and
 		// we want to ensure user passed valid auth headers. This
 		// prevents a CSRF hijack where bad actor sends XHR PUT/POST
@@ -107,10 +145,34 @@ func GetUserFromAPIHeaders(c *gin.Context) (user *pgmodels.User, err error) {
 		return user, nil
 	}
 	ctx.Log.Warn().Msgf("Invalid API token from user %s at %s.", apiUserEmail, c.Request.RemoteAddr)
+	helpers.Audit(c, constants.AuditAPIAuthentication, constants.OutcomeFailure, user, "Invalid API key")
 	helpers.DeleteSessionCookie(c) // just to be extra safe
 	return nil, common.ErrInvalidAPICredentials
 }
 
+// auditAPIKeyUse records successful API authentication in the audit
+// log. API clients authenticate on every request, so we record each
+// combination of user, key and IP address at most once an hour.
+// Param apiKey is nil if the user authenticated with the legacy key.
+func auditAPIKeyUse(c *gin.Context, user *pgmodels.User, apiKey *pgmodels.APIKey) {
+	detail := "Legacy API key"
+	if apiKey != nil {
+		detail = fmt.Sprintf("API key %s", apiKey.Name)
+	}
+	event := helpers.NewAuditEvent(c, constants.AuditAPIAuthentication, constants.OutcomeSuccess, user, detail)
+	if apiKey != nil {
+		event.ResourceType = "APIKey"
+		event.ResourceID = apiKey.ID
+	}
+	alreadyRecorded, err := event.HasRecentAuditEvent(time.Now().UTC().Add(-1 * time.Hour))
+	if err != nil {
+		common.Context().Log.Warn().Msgf("Could not check audit log for API key use by %s: %v", user.Email, err)
+	}
+	if !alreadyRecorded {
+		helpers.RecordAuditEvent(event)
+	}
+}
+
 // LoadCookies loads the user's flash and preference cookes into
 // the request context.
 func LoadCookies(c *gin.Context) error {
@@ -180,17 +242,26 @@ func logPasswordChangeIncomplete(c *gin.Context, currentUser *pgmodels.User) {
 	common.Context().Log.Warn().Msgf("Password change incomplete. User %s tried to access URL [%s]. Forcing user to complete password change.", currentUser.Email, c.Request.RequestURI)
 }
 
+// forceCompletionOfTwoFactorAuth returns true if the current session
+// hasn't completed its second factor and the request is for anything
+// other than the two-factor pages. API requests have no session.
 func forceCompletionOfTwoFactorAuth(c *gin.Context, currentUser *pgmodels.User) bool {
 	if currentUser == nil {
 		return false // user isn't even signed in
 	}
+	session := helpers.CurrentSession(c)
+	if session == nil {
+		return false
+	}
 	p := c.FullPath()
-	return currentUser.AwaitingSecondFactor &&
+	return session.AwaitingSecondFactor &&
 		!strings.HasPrefix(p, "/users/2fa_backup") &&
 		!strings.HasPrefix(p, "/users/2fa_choose") &&
 		!strings.HasPrefix(p, "/users/2fa_push") &&
 		!strings.HasPrefix(p, "/users/2fa_sms") &&
-		!strings.HasPrefix(p, "/users/2fa_verify")
+		!strings.HasPrefix(p, "/users/2fa_totp") &&
+		!strings.HasPrefix(p, "/users/2fa_verify") &&
+		!strings.HasPrefix(p, "/users/2fa_webauthn")
 }
 
 func log2FAIncomplete(c *gin.Context, currentUser *pgmodels.User) {
@@ -227,5 +298,6 @@ func ExemptFromAuth(c *gin.Context) bool {
 		strings.HasPrefix(p, "/static") ||
 		strings.HasPrefix(p, "/favicon") ||
 		strings.HasPrefix(p, "/error") ||
-		strings.HasPrefix(p, "/users/complete_password_reset/")
+		strings.HasPrefix(p, "/users/complete_password_reset/") ||
+		strings.HasPrefix(p, "/users/sso")
 }
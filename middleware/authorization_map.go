@@ -31,119 +31,192 @@ type AuthMetadata struct {
 // requests that hit an unguarded route will return  an internal server
 // error.
 var AuthMap = map[string]AuthMetadata{
-	"AlertCreate":                 {"Alert", constants.AlertCreate},
-	"AlertDelete":                 {"Alert", constants.AlertDelete},
-	"AlertIndex":                  {"Alert", constants.AlertRead},
-	"AlertNew":                    {"Alert", constants.AlertCreate},
-	"AlertShow":                   {"Alert", constants.AlertRead},
-	"AlertUpdate":                 {"Alert", constants.AlertUpdate},
-	"AlertMarkAsReadXHR":          {"Alert", constants.AlertUpdate},
-	"AlertMarkAllAsRead":          {"Alert", constants.AlertUpdate},
-	"AlertMarkAsUnreadXHR":        {"Alert", constants.AlertUpdate},
-	"BillingReportShow":           {"DepositStats", constants.BillingReportShow},
-	"ChecksumCreate":              {"Checksum", constants.ChecksumCreate},
-	"ChecksumDelete":              {"Checksum", constants.ChecksumDelete},
-	"ChecksumIndex":               {"Checksum", constants.ChecksumRead},
-	"ChecksumNew":                 {"Checksum", constants.ChecksumCreate},
-	"ChecksumShow":                {"Checksum", constants.ChecksumRead},
-	"ChecksumUpdate":              {"Checksum", constants.ChecksumUpdate},
-	"DashboardShow":               {"Dashboard", constants.DashboardShow},
-	"DeletionRequestApprove":      {"DeletionRequest", constants.DeletionRequestApprove},
-	"DeletionRequestCancel":       {"DeletionRequest", constants.DeletionRequestApprove},
-	"DeletionRequestIndex":        {"DeletionRequest", constants.DeletionRequestList},
-	"DeletionRequestReview":       {"DeletionRequest", constants.DeletionRequestApprove},
-	"DeletionRequestShow":         {"DeletionRequest", constants.DeletionRequestShow},
-	"DepositReportShow":           {"DepositStats", constants.DepositReportShow},
-	"GenericFileCreate":           {"GenericFile", constants.FileCreate},
-	"GenericFileCreateBatch":      {"GenericFile", constants.FileCreate},
-	"GenericFileDelete":           {"GenericFile", constants.FileDelete},
-	"GenericFileFinishBulkDelete": {"GenericFile", constants.FileFinishBulkDelete},
-	"GenericFileIndex":            {"GenericFile", constants.FileRead},
-	"GenericFileInitDelete":       {"GenericFile", constants.FileRequestDelete},
-	"GenericFileInitRestore":      {"GenericFile", constants.FileRestore},
-	"GenericFileNew":              {"GenericFile", constants.FileCreate},
-	"GenericFileRequestDelete":    {"GenericFile", constants.FileRequestDelete},
-	"GenericFileRequestRestore":   {"GenericFile", constants.FileRestore},
-	"GenericFileShow":             {"GenericFile", constants.FileRead},
-	"GenericFileUpdate":           {"GenericFile", constants.FileUpdate},
-	"InstitutionCreate":           {"Institution", constants.InstitutionCreate},
-	"InstitutionDelete":           {"Institution", constants.InstitutionDelete},
-	"InstitutionEdit":             {"Institution", constants.InstitutionUpdate},
-	"InstitutionEditPrefs":        {"Institution", constants.InstitutionUpdatePrefs},
-	"InstitutionIndex":            {"Institution", constants.InstitutionList},
-	"InstitutionNew":              {"Institution", constants.InstitutionCreate},
-	"InstitutionShow":             {"Institution", constants.InstitutionRead},
-	"InstitutionUndelete":         {"Institution", constants.InstitutionUpdate},
-	"InstitutionUpdate":           {"Institution", constants.InstitutionUpdate},
-	"InstitutionUpdatePrefs":      {"Institution", constants.InstitutionUpdatePrefs},
-	"IntellectualObjectCreate":    {"IntellectualObject", constants.IntellectualObjectCreate},
-	"IntellectualObjectDelete":    {"IntellectualObject", constants.IntellectualObjectDelete},
-	"IntellectualObjectEvents":    {"PremisEvent", constants.EventRead},
+	"APIKeyCreate":                 {"APIKey", constants.UserUpdateSelf},
+	"APIKeyNew":                    {"APIKey", constants.UserUpdateSelf},
+	"APIKeyRevoke":                 {"APIKey", constants.UserUpdateSelf},
+	"AlertCreate":                  {"Alert", constants.AlertCreate},
+	"AlertDelete":                  {"Alert", constants.AlertDelete},
+	"AlertIndex":                   {"Alert", constants.AlertRead},
+	"AlertNew":                     {"Alert", constants.AlertCreate},
+	"AlertShow":                    {"Alert", constants.AlertRead},
+	"AlertUpdate":                  {"Alert", constants.AlertUpdate},
+	"AlertMarkAsReadXHR":           {"Alert", constants.AlertUpdate},
+	"AlertMarkAllAsRead":           {"Alert", constants.AlertUpdate},
+	"AlertMarkAsUnreadXHR":         {"Alert", constants.AlertUpdate},
+	"AuditEventIndex":              {"AuditEvent", constants.AuditEventRead},
+	"AuditEventShow":               {"AuditEvent", constants.AuditEventRead},
+	"BillingReportShow":            {"DepositStats", constants.BillingReportShow},
+	"ChecksumCreate":               {"Checksum", constants.ChecksumCreate},
+	"ChecksumDelete":               {"Checksum", constants.ChecksumDelete},
+	"ChecksumIndex":                {"Checksum", constants.ChecksumRead},
+	"ChecksumNew":                  {"Checksum", constants.ChecksumCreate},
+	"ChecksumShow":                 {"Checksum", constants.ChecksumRead},
+	"ChecksumUpdate":               {"Checksum", constants.ChecksumUpdate},
+	"DashboardShow":                {"Dashboard", constants.DashboardShow},
+	"DeletionRequestApprove":       {"DeletionRequest", constants.DeletionRequestApprove},
+	"DeletionRequestCancel":        {"DeletionRequest", constants.DeletionRequestApprove},
+	"DeletionRequestIndex":         {"DeletionRequest", constants.DeletionRequestList},
+	"DeletionRequestReview":        {"DeletionRequest", constants.DeletionRequestApprove},
+	"DeletionRequestShow":          {"DeletionRequest", constants.DeletionRequestShow},
+	"DepositReportShow":            {"DepositStats", constants.DepositReportShow},
+	"FixityOverdueReportShow":      {"FixityStats", constants.FixityReportShow},
+	"FixityReportShow":             {"FixityStats", constants.FixityReportShow},
+	"FormatRiskCreate":             {"FormatRisk", constants.FormatRiskCreate},
+	"FormatRiskDelete":             {"FormatRisk", constants.FormatRiskDelete},
+	"FormatRiskEdit":               {"FormatRisk", constants.FormatRiskUpdate},
+	"FormatRiskIndex":              {"FormatRisk", constants.FormatRiskRead},
+	"FormatRiskNew":                {"FormatRisk", constants.FormatRiskCreate},
+	"FormatRiskObjectsReportShow":  {"FormatRiskObject", constants.FormatRiskReportShow},
+	"FormatRiskReportShow":         {"FormatRiskStats", constants.FormatRiskReportShow},
+	"FormatRiskUpdate":             {"FormatRisk", constants.FormatRiskUpdate},
+	"GenericFileCreate":            {"GenericFile", constants.FileCreate},
+	"GenericFileCreateBatch":       {"GenericFile", constants.FileCreate},
+	"GenericFileDelete":            {"GenericFile", constants.FileDelete},
+	"GenericFileFinishBulkDelete":  {"GenericFile", constants.FileFinishBulkDelete},
+	"GenericFileIndex":             {"GenericFile", constants.FileRead},
+	"GenericFileInitBulkDelete":    {"GenericFile", constants.FileRequestDelete},
+	"GenericFileInitBulkFixity":    {"GenericFile", constants.FileRequestFixity},
+	"GenericFileInitDelete":        {"GenericFile", constants.FileRequestDelete},
+	"GenericFileInitFixity":        {"GenericFile", constants.FileRequestFixity},
+	"GenericFileInitRestore":       {"GenericFile", constants.FileRestore},
+	"GenericFileNew":               {"GenericFile", constants.FileCreate},
+	"GenericFileRequestBulkDelete": {"GenericFile", constants.FileRequestDelete},
+	"GenericFileRequestBulkFixity": {"GenericFile", constants.FileRequestFixity},
+	"GenericFileRequestDelete":     {"GenericFile", constants.FileRequestDelete},
+	"GenericFileRequestFixity":     {"GenericFile", constants.FileRequestFixity},
+	"GenericFileRequestRestore":    {"GenericFile", constants.FileRestore},
+	"GenericFileShow":              {"GenericFile", constants.FileRead},
+	"GenericFileUpdate":            {"GenericFile", constants.FileUpdate},
+	"InstitutionCreate":            {"Institution", constants.InstitutionCreate},
+	"InstitutionDelete":            {"Institution", constants.InstitutionDelete},
+	"InstitutionEdit":              {"Institution", constants.InstitutionUpdate},
+	"InstitutionEditPrefs":         {"Institution", constants.InstitutionUpdatePrefs},
+	"InstitutionIndex":             {"Institution", constants.InstitutionList},
+	"InstitutionNew":               {"Institution", constants.InstitutionCreate},
+	"InstitutionShow":              {"Institution", constants.InstitutionRead},
+	"InstitutionSSOEdit":           {"Institution", constants.InstitutionUpdate},
+	"InstitutionSSOUpdate":         {"Institution", constants.InstitutionUpdate},
+	"InstitutionUndelete":          {"Institution", constants.InstitutionUpdate},
+	"InstitutionUpdate":            {"Institution", constants.InstitutionUpdate},
+	"InstitutionUpdatePrefs":       {"Institution", constants.InstitutionUpdatePrefs},
+	"IntellectualObjectCreate":     {"IntellectualObject", constants.IntellectualObjectCreate},
+	"IntellectualObjectDelete":     {"IntellectualObject", constants.IntellectualObjectDelete},
+	"IntellectualObjectEvents":     {"PremisEvent", constants.EventRead},
 	// IntellectualObjectFiles gets an object ID and will look up that object to check
 	// it's institution. The permission, however, is FileReade, because this endpoint
 	// returns files. https://trello.com/c/n5asx3bj
-	"IntellectualObjectFiles":            {"IntellectualObject", constants.FileRead},
-	"IntellectualObjectFinishBulkDelete": {"IntellectualObject", constants.IntellectualObjectFinishBulkDelete},
-	"IntellectualObjectIndex":            {"IntellectualObject", constants.IntellectualObjectRead},
-	"IntellectualObjectInitDelete":       {"IntellectualObject", constants.IntellectualObjectRequestDelete},
-	"IntellectualObjectInitRestore":      {"IntellectualObject", constants.IntellectualObjectRestore},
-	"IntellectualObjectNew":              {"IntellectualObject", constants.IntellectualObjectCreate},
-	"IntellectualObjectRequestDelete":    {"IntellectualObject", constants.IntellectualObjectRequestDelete},
-	"IntellectualObjectRequestRestore":   {"IntellectualObject", constants.IntellectualObjectRestore},
-	"IntellectualObjectShow":             {"IntellectualObject", constants.IntellectualObjectRead},
-	"IntellectualObjectUpdate":           {"IntellectualObject", constants.IntellectualObjectUpdate},
-	"InternalMetadataIndex":              {"InternalMetadata", constants.InternalMetadataRead},
-	"NsqShow":                            {"NSQ", constants.NsqAdmin},
-	"NsqAdmin":                           {"NSQ", constants.NsqAdmin},
-	"NsqInit":                            {"NSQ", constants.NsqAdmin},
-	"PremisEventCreate":                  {"PremisEvent", constants.EventCreate},
-	"PremisEventIndex":                   {"PremisEvent", constants.EventRead},
-	"PremisEventShow":                    {"PremisEvent", constants.EventRead},
-	"PremisEventShowXHR":                 {"PremisEvent", constants.EventRead},
-	"PrepareFileDelete":                  {"GenericFile", constants.PrepareFileDelete},
-	"PrepareObjectDelete":                {"IntellectualObject", constants.PrepareObjectDelete},
-	"StorageRecordCreate":                {"StorageRecord", constants.StorageRecordCreate},
-	"StorageRecordDelete":                {"StorageRecord", constants.StorageRecordDelete},
-	"StorageRecordIndex":                 {"StorageRecord", constants.StorageRecordRead},
-	"StorageRecordNew":                   {"StorageRecord", constants.StorageRecordCreate},
-	"StorageRecordShow":                  {"StorageRecord", constants.StorageRecordRead},
-	"StorageRecordUpdate":                {"StorageRecord", constants.StorageRecordUpdate},
-	"UserChangePassword":                 {"User", constants.UserUpdateSelf},
-	"UserComplete2FASetup":               {"User", constants.UserComplete2FASetup},
-	"UserConfirmPhone":                   {"User", constants.UserConfirmPhone},
-	"UserCreate":                         {"User", constants.UserCreate},
-	"UserDelete":                         {"User", constants.UserDelete},
-	"UserDeleteSelf":                     {"User", constants.UserDeleteSelf},
-	"UserEdit":                           {"User", constants.UserUpdate},
-	"UserGenerateBackupCodes":            {"User", constants.UserGenerateBackupCodes},
-	"UserGetAPIKey":                      {"User", constants.UserUpdateSelf},
-	"UserIndex":                          {"User", constants.UserRead},
-	"UserInit2FASetup":                   {"User", constants.UserInit2FASetup},
-	"UserInitPasswordReset":              {"User", constants.UserUpdate},
-	"UserMyAccount":                      {"User", constants.UserUpdateSelf},
-	"UserNew":                            {"User", constants.UserCreate},
-	"UserReadSelf":                       {"User", constants.UserReadSelf},
-	"UserShow":                           {"User", constants.UserRead},
-	"UserShowChangePassword":             {"User", constants.UserUpdateSelf},
-	"UserTwoFactorBackup":                {"User", constants.UserTwoFactorBackup},
-	"UserTwoFactorChoose":                {"User", constants.UserTwoFactorChoose},
-	"UserTwoFactorGenerateSMS":           {"User", constants.UserTwoFactorGenerateSMS},
-	"UserTwoFactorPush":                  {"User", constants.UserTwoFactorPush},
-	"UserTwoFactorResend":                {"User", constants.UserTwoFactorResend},
-	"UserTwoFactorVerify":                {"User", constants.UserTwoFactorVerify},
-	"UserUndelete":                       {"User", constants.UserUpdate},
-	"UserUpdate":                         {"User", constants.UserUpdate},
-	"UserUpdateXHR":                      {"User", constants.UserUpdate},
-	"UserUpdateSelf":                     {"User", constants.UserUpdateSelf},
-	"WorkItemCreate":                     {"WorkItem", constants.WorkItemCreate},
-	"WorkItemDelete":                     {"WorkItem", constants.WorkItemDelete},
-	"WorkItemEdit":                       {"WorkItem", constants.WorkItemUpdate},
-	"WorkItemIndex":                      {"WorkItem", constants.WorkItemRead},
-	"WorkItemNew":                        {"WorkItem", constants.WorkItemCreate},
-	"WorkItemRedisDelete":                {"WorkItem", constants.WorkItemRedisDelete},
-	"WorkItemRedisIndex":                 {"WorkItem", constants.RedisList},
-	"WorkItemRequeue":                    {"WorkItem", constants.WorkItemRequeue},
-	"WorkItemShow":                       {"WorkItem", constants.WorkItemRead},
-	"WorkItemShowRequeue":                {"WorkItem", constants.WorkItemRequeue},
-	"WorkItemUpdate":                     {"WorkItem", constants.WorkItemUpdate},
+	"IntellectualObjectFiles":              {"IntellectualObject", constants.FileRead},
+	"IntellectualObjectFinishBulkDelete":   {"IntellectualObject", constants.IntellectualObjectFinishBulkDelete},
+	"IntellectualObjectIndex":              {"IntellectualObject", constants.IntellectualObjectRead},
+	"IntellectualObjectInitBulkDelete":     {"IntellectualObject", constants.IntellectualObjectRequestDelete},
+	"IntellectualObjectInitBulkRestore":    {"IntellectualObject", constants.IntellectualObjectRestore},
+	"IntellectualObjectInitDelete":         {"IntellectualObject", constants.IntellectualObjectRequestDelete},
+	"IntellectualObjectInitFixity":         {"IntellectualObject", constants.IntellectualObjectRequestFixity},
+	"IntellectualObjectInitRestore":        {"IntellectualObject", constants.IntellectualObjectRestore},
+	"IntellectualObjectNew":                {"IntellectualObject", constants.IntellectualObjectCreate},
+	"IntellectualObjectRequestBulkDelete":  {"IntellectualObject", constants.IntellectualObjectRequestDelete},
+	"IntellectualObjectRequestBulkRestore": {"IntellectualObject", constants.IntellectualObjectRestore},
+	"IntellectualObjectRequestDelete":      {"IntellectualObject", constants.IntellectualObjectRequestDelete},
+	"IntellectualObjectRequestFixity":      {"IntellectualObject", constants.IntellectualObjectRequestFixity},
+	"IntellectualObjectRequestRestore":     {"IntellectualObject", constants.IntellectualObjectRestore},
+	"IntellectualObjectShow":               {"IntellectualObject", constants.IntellectualObjectRead},
+	"IntellectualObjectVersions":           {"IntellectualObject", constants.IntellectualObjectRead},
+	"IntellectualObjectUpdate":             {"IntellectualObject", constants.IntellectualObjectUpdate},
+	"InternalMetadataIndex":                {"InternalMetadata", constants.InternalMetadataRead},
+	"InvoiceDownload":                      {"Invoice", constants.InvoiceRead},
+	"InvoiceIndex":                         {"Invoice", constants.InvoiceRead},
+	"InvoiceShow":                          {"Invoice", constants.InvoiceRead},
+	"JobIndex":                             {"Job", constants.JobRead},
+	"JobRunNow":                            {"Job", constants.JobRun},
+	"NsqShow":                              {"NSQ", constants.NsqAdmin},
+	"NsqAdmin":                             {"NSQ", constants.NsqAdmin},
+	"NsqInit":                              {"NSQ", constants.NsqAdmin},
+	"PremisEventCreate":                    {"PremisEvent", constants.EventCreate},
+	"PremisEventIndex":                     {"PremisEvent", constants.EventRead},
+	"PremisEventShow":                      {"PremisEvent", constants.EventRead},
+	"PremisEventShowXHR":                   {"PremisEvent", constants.EventRead},
+	"PrepareFileDelete":                    {"GenericFile", constants.PrepareFileDelete},
+	"PrepareObjectDelete":                  {"IntellectualObject", constants.PrepareObjectDelete},
+	"ReplicationReportShow":                {"ReplicationProblem", constants.ReplicationReportShow},
+	"RestorationBatchIndex":                {"RestorationBatch", constants.RestorationBatchRead},
+	"RestorationBatchShow":                 {"RestorationBatch", constants.RestorationBatchRead},
+	"SavedSearchCreate":                    {"SavedSearch", constants.SavedSearchCreate},
+	"SavedSearchDelete":                    {"SavedSearch", constants.SavedSearchDelete},
+	"SavedSearchEdit":                      {"SavedSearch", constants.SavedSearchUpdate},
+	"SavedSearchIndex":                     {"SavedSearch", constants.SavedSearchRead},
+	"SavedSearchNew":                       {"SavedSearch", constants.SavedSearchCreate},
+	"SavedSearchShow":                      {"SavedSearch", constants.SavedSearchRead},
+	"SavedSearchUpdate":                    {"SavedSearch", constants.SavedSearchUpdate},
+	"SearchIndex":                          {"SearchResult", constants.SearchRead},
+	"StoragePriceCreate":                   {"StoragePrice", constants.StoragePriceCreate},
+	"StoragePriceDelete":                   {"StoragePrice", constants.StoragePriceDelete},
+	"StoragePriceIndex":                    {"StoragePrice", constants.StoragePriceRead},
+	"StoragePriceNew":                      {"StoragePrice", constants.StoragePriceCreate},
+	"StorageRecordCreate":                  {"StorageRecord", constants.StorageRecordCreate},
+	"StorageRecordDelete":                  {"StorageRecord", constants.StorageRecordDelete},
+	"StorageRecordIndex":                   {"StorageRecord", constants.StorageRecordRead},
+	"StorageRecordNew":                     {"StorageRecord", constants.StorageRecordCreate},
+	"StorageRecordShow":                    {"StorageRecord", constants.StorageRecordRead},
+	"StorageRecordUpdate":                  {"StorageRecord", constants.StorageRecordUpdate},
+	"UserChangePassword":                   {"User", constants.UserUpdateSelf},
+	"UserComplete2FASetup":                 {"User", constants.UserComplete2FASetup},
+	"UserConfirmPhone":                     {"User", constants.UserConfirmPhone},
+	"UserConfirmTOTP":                      {"User", constants.UserComplete2FASetup},
+	"UserCreate":                           {"User", constants.UserCreate},
+	"UserDelete":                           {"User", constants.UserDelete},
+	"UserDeleteSelf":                       {"User", constants.UserDeleteSelf},
+	"UserEdit":                             {"User", constants.UserUpdate},
+	"UserGenerateBackupCodes":              {"User", constants.UserGenerateBackupCodes},
+	"UserGetAPIKey":                        {"User", constants.UserUpdateSelf},
+	"UserIndex":                            {"User", constants.UserRead},
+	"UserInit2FASetup":                     {"User", constants.UserInit2FASetup},
+	"UserInitPasswordReset":                {"User", constants.UserUpdate},
+	"UserMyAccount":                        {"User", constants.UserUpdateSelf},
+	"UserNew":                              {"User", constants.UserCreate},
+	"UserReadSelf":                         {"User", constants.UserReadSelf},
+	"UserShow":                             {"User", constants.UserRead},
+	"UserShowChangePassword":               {"User", constants.UserUpdateSelf},
+	"UserTwoFactorBackup":                  {"User", constants.UserTwoFactorBackup},
+	"UserTwoFactorChoose":                  {"User", constants.UserTwoFactorChoose},
+	"UserTwoFactorGenerateSMS":             {"User", constants.UserTwoFactorGenerateSMS},
+	"UserTwoFactorPush":                    {"User", constants.UserTwoFactorPush},
+	"UserTwoFactorResend":                  {"User", constants.UserTwoFactorResend},
+	"UserTwoFactorTOTP":                    {"User", constants.UserTwoFactorVerify},
+	"UserTOTPQRCode":                       {"User", constants.UserInit2FASetup},
+	"UserTwoFactorWebAuthn":                {"User", constants.UserTwoFactorVerify},
+	"UserTwoFactorWebAuthnBegin":           {"User", constants.UserTwoFactorVerify},
+	"UserTwoFactorWebAuthnFinish":          {"User", constants.UserTwoFactorVerify},
+	"UserTwoFactorVerify":                  {"User", constants.UserTwoFactorVerify},
+	"UserUpdateAlertPreferences":           {"User", constants.UserUpdateSelf},
+	"UserUndelete":                         {"User", constants.UserUpdate},
+	"UserSessionRevoke":                    {"UserSession", constants.UserUpdateSelf},
+	"UserSignOutEverywhere":                {"User", constants.UserUpdate},
+	"UserUnlock":                           {"User", constants.UserUpdate},
+	"UserUpdate":                           {"User", constants.UserUpdate},
+	"UserUpdateXHR":                        {"User", constants.UserUpdate},
+	"UserUpdateSelf":                       {"User", constants.UserUpdateSelf},
+	"UserWebAuthnRegisterBegin":            {"User", constants.UserInit2FASetup},
+	"UserWebAuthnRegisterFinish":           {"User", constants.UserComplete2FASetup},
+	"UserWebAuthnRegisterShow":             {"User", constants.UserInit2FASetup},
+	"WebAuthnCredentialRename":             {"WebAuthnCredential", constants.UserUpdateSelf},
+	"WebAuthnCredentialRevoke":             {"WebAuthnCredential", constants.UserUpdateSelf},
+	"WebhookCreate":                        {"Webhook", constants.WebhookCreate},
+	"WebhookDelete":                        {"Webhook", constants.WebhookDelete},
+	"WebhookDeliveryReplay":                {"WebhookDelivery", constants.WebhookUpdate},
+	"WebhookDeliveryShow":                  {"WebhookDelivery", constants.WebhookRead},
+	"WebhookEdit":                          {"Webhook", constants.WebhookUpdate},
+	"WebhookNew":                           {"Webhook", constants.WebhookCreate},
+	"WebhookShow":                          {"Webhook", constants.WebhookRead},
+	"WebhookUpdate":                        {"Webhook", constants.WebhookUpdate},
+	"WorkItemCreate":                       {"WorkItem", constants.WorkItemCreate},
+	"WorkItemDelete":                       {"WorkItem", constants.WorkItemDelete},
+	"WorkItemEdit":                         {"WorkItem", constants.WorkItemUpdate},
+	"WorkItemIndex":                        {"WorkItem", constants.WorkItemRead},
+	"WorkItemNew":                          {"WorkItem", constants.WorkItemCreate},
+	"WorkItemRedisDelete":                  {"WorkItem", constants.WorkItemRedisDelete},
+	"WorkItemRedisIndex":                   {"WorkItem", constants.RedisList},
+	"WorkItemRequeue":                      {"WorkItem", constants.WorkItemRequeue},
+	"WorkItemShow":                         {"WorkItem", constants.WorkItemRead},
+	"WorkItemShowRequeue":                  {"WorkItem", constants.WorkItemRequeue},
+	"WorkItemUpdate":                       {"WorkItem", constants.WorkItemUpdate},
 }
@@ -7,6 +7,7 @@ import (
 
 	"github.com/APTrust/registry/common"
 	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/helpers"
 	"github.com/APTrust/registry/pgmodels"
 	"github.com/gin-gonic/gin"
 )
@@ -87,9 +88,21 @@ func showAuthFailedError(c *gin.Context, auth *ResourceAuthorization) {
 	if auth.Error != nil {
 		errMsg = fmt.Sprintf("%s %s", errMsg, auth.Error.Error())
 	}
+	auditAccessDenied(c, auth)
 	showError(c, auth, errMsg, http.StatusForbidden)
 }
 
+// auditAccessDenied records a denied request in the audit log. The event
+// belongs to the requesting user's institution, so institutional admins
+// can see when their own users try to reach things they shouldn't.
+func auditAccessDenied(c *gin.Context, auth *ResourceAuthorization) {
+	detail := fmt.Sprintf("%s %s (handler %s, permission %s)", c.Request.Method, c.Request.URL.Path, auth.Handler, auth.Permission)
+	event := helpers.NewAuditEvent(c, constants.AuditAccessDenied, constants.OutcomeFailure, auth.CurrentUser(), detail)
+	event.ResourceType = auth.ResourceType
+	event.ResourceID = auth.ResourceID
+	helpers.RecordAuditEvent(event)
+}
+
 func showNotFoundError(c *gin.Context, auth *ResourceAuthorization) {
 	common.Context().Log.Error().Msgf(auth.GetError())
 	errMsg := fmt.Sprintf("Not found: %s", c.Request.URL.Path)
@@ -88,11 +88,27 @@ func (r *ResourceAuthorization) getPermissionType() {
 }
 
 func (r *ResourceAuthorization) checkPermission() {
-	currentUser := r.CurrentUser()
-	r.Approved = currentUser != nil && currentUser.HasPermission(r.Permission, r.ResourceInstID)
+	r.Approved = r.HasPermission(r.Permission)
 	r.Checked = true
 }
 
+// HasPermission returns true if the current user has the specified
+// permission at the requested resource's institution, and the API key
+// they authenticated with, if any, allows it. Handlers that return more
+// than one kind of resource, such as search, use this to check each kind.
+func (r *ResourceAuthorization) HasPermission(permission constants.Permission) bool {
+	currentUser := r.CurrentUser()
+	if currentUser == nil || !currentUser.HasPermission(permission, r.ResourceInstID) {
+		return false
+	}
+	// If the user authenticated with a named API key, the
+	// key must allow this request as well.
+	if apiKey := r.APIKey(); apiKey != nil {
+		return apiKey.Allows(permission, r.ginCtx.Request.Method)
+	}
+	return true
+}
+
 func (r *ResourceAuthorization) readRequestIds() {
 	id := r.idFromRequest("id")
 	r.ResourceID = id
@@ -179,6 +195,15 @@ func (r *ResourceAuthorization) CurrentUser() *pgmodels.User {
 	return nil
 }
 
+// APIKey returns the named API key the current user authenticated
+// with, or nil if they authenticated some other way.
+func (r *ResourceAuthorization) APIKey() *pgmodels.APIKey {
+	if apiKey, ok := r.ginCtx.Get("APIKey"); ok && apiKey != nil {
+		return apiKey.(*pgmodels.APIKey)
+	}
+	return nil
+}
+
 // GetError returns an error message with detailed information.
 // This is primarily for logging.
 func (r *ResourceAuthorization) GetError() string {
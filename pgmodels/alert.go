@@ -2,6 +2,7 @@ package pgmodels
 
 import (
 	"bytes"
+	"fmt"
 	"time"
 
 	"github.com/APTrust/registry/common"
@@ -221,8 +222,16 @@ func CreateAlert(alert *Alert, templateName string, alertData map[string]interfa
 		return nil, err
 	}
 
-	// Send the alert & mark as sent
+	alert.NotifyWebhooks()
+
+	// Send the alert & mark as sent. Recipients who want this type
+	// of alert in a digest, or in the Registry only, don't get an
+	// email now. The alert stays unsent for them, and the digest job
+	// picks it up later. See SendAlertDigests.
 	for _, recipient := range alert.Users {
+		if recipient.AlertDeliveryFor(alert.Type) != constants.AlertDeliveryImmediate {
+			continue
+		}
 		err := common.Context().SESClient.Send(recipient.Email, alert.Subject, alert.Content)
 		if err == nil {
 			err = alert.MarkAsSent(recipient.ID)
@@ -245,3 +254,22 @@ func CreateAlert(alert *Alert, templateName string, alertData map[string]interfa
 
 	return alert, err
 }
+
+// NotifyWebhooks queues an alert.created event for the institution's
+// webhooks. The payload omits the alert content, which may include
+// deletion confirmation tokens and other things that should go only
+// to the alert's recipients. Errors are logged rather than returned.
+func (alert *Alert) NotifyWebhooks() {
+	data := map[string]interface{}{
+		"id":                  alert.ID,
+		"type":                alert.Type,
+		"subject":             alert.Subject,
+		"deletion_request_id": alert.DeletionRequestID,
+		"created_at":          alert.CreatedAt,
+	}
+	key := fmt.Sprintf("alert/%d", alert.ID)
+	_, err := QueueWebhookEvent(alert.InstitutionID, constants.WebhookAlertCreated, key, data)
+	if err != nil {
+		common.Context().Log.Error().Msgf("Error queueing webhook event for alert %d: %v", alert.ID, err)
+	}
+}
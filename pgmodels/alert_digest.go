@@ -0,0 +1,151 @@
+package pgmodels
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/go-pg/pg/v10"
+)
+
+// AlertDigest is a single email summarizing the alerts a user has
+// chosen to receive in a daily or weekly digest rather than one at
+// a time.
+type AlertDigest struct {
+	Frequency string
+	User      *User
+	Alerts    []*Alert
+}
+
+type alertDigestRow struct {
+	UserID  int64
+	AlertID int64
+}
+
+// AlertDigestsFor returns one digest for each active user who has
+// unsent alerts of a type they've chosen to receive at the specified
+// frequency. Param frequency should be constants.AlertDeliveryDaily
+// or constants.AlertDeliveryWeekly.
+func AlertDigestsFor(frequency string) ([]*AlertDigest, error) {
+	var rows []alertDigestRow
+	sql := `select au.user_id, au.alert_id
+		from alerts_users au
+		join alerts a on a.id = au.alert_id
+		join users u on u.id = au.user_id
+		where au.sent_at is null
+		and u.deactivated_at is null
+		and coalesce(u.alert_preferences->>a.type, ?) = ?
+		and a.type not in (?)
+		order by au.user_id, a.created_at, a.id`
+	_, err := common.Context().DB.Query(&rows, sql,
+		constants.AlertDeliveryImmediate,
+		frequency,
+		pg.In(constants.ImmediateAlertTypes))
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]int64, 0)
+	alertIDs := make(map[int64][]int64)
+	for _, row := range rows {
+		if _, ok := alertIDs[row.UserID]; !ok {
+			userIDs = append(userIDs, row.UserID)
+		}
+		alertIDs[row.UserID] = append(alertIDs[row.UserID], row.AlertID)
+	}
+
+	digests := make([]*AlertDigest, len(userIDs))
+	for i, userID := range userIDs {
+		user, err := UserByID(userID)
+		if err != nil {
+			return nil, err
+		}
+		var alerts []*Alert
+		err = common.Context().DB.Model(&alerts).
+			Where("id in (?)", pg.In(alertIDs[userID])).
+			Order("created_at", "id").
+			Select()
+		if err != nil {
+			return nil, err
+		}
+		digests[i] = &AlertDigest{
+			Frequency: frequency,
+			User:      user,
+			Alerts:    alerts,
+		}
+	}
+	return digests, nil
+}
+
+// SendAlertDigests sends digest emails to everyone who has unsent
+// alerts of a type they've chosen to receive at the specified
+// frequency, then marks those alerts as sent. It returns the number
+// of digests sent.
+//
+// A failure to send one user's digest does not stop us from sending
+// the others. Those alerts stay unsent and will be included in the
+// user's next digest.
+func SendAlertDigests(frequency string) (int, error) {
+	digests, err := AlertDigestsFor(frequency)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, digest := range digests {
+		err = digest.Send()
+		if err != nil {
+			common.Context().Log.Error().Msgf("Error sending %s alert digest to %s: %v", frequency, digest.User.Email, err)
+			continue
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// Subject returns the subject line for this digest's email.
+func (digest *AlertDigest) Subject() string {
+	noun := "alerts"
+	if len(digest.Alerts) == 1 {
+		noun = "alert"
+	}
+	return fmt.Sprintf("Your %s APTrust Registry digest: %d %s", digest.Frequency, len(digest.Alerts), noun)
+}
+
+// Body returns the text of this digest's email.
+func (digest *AlertDigest) Body() (string, error) {
+	ctx := common.Context()
+	data := map[string]interface{}{
+		"UserName":    digest.User.Name,
+		"UserID":      digest.User.ID,
+		"Frequency":   digest.Frequency,
+		"Alerts":      digest.Alerts,
+		"RegistryURL": fmt.Sprintf("%s://%s", ctx.Config.HTTPScheme(), ctx.Config.Cookies.Domain),
+	}
+	var buf bytes.Buffer
+	err := common.TextTemplates["alerts/alert_digest.txt"].Execute(&buf, data)
+	return buf.String(), err
+}
+
+// Send emails this digest to its user and marks each of its alerts
+// as sent to that user.
+func (digest *AlertDigest) Send() error {
+	body, err := digest.Body()
+	if err != nil {
+		return err
+	}
+	err = common.Context().SESClient.Send(digest.User.Email, digest.Subject(), body)
+	if err != nil {
+		return err
+	}
+	for _, alert := range digest.Alerts {
+		err = alert.MarkAsSent(digest.User.ID)
+		if err != nil {
+			return err
+		}
+	}
+	common.ConsoleDebug("***********************")
+	common.ConsoleDebug(body)
+	common.ConsoleDebug("***********************")
+	return nil
+}
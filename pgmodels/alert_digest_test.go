@@ -0,0 +1,68 @@
+package pgmodels_test
+
+import (
+	"testing"
+
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlertDigests(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+
+	dailyUser, err := pgmodels.UserByEmail("admin@inst1.edu")
+	require.Nil(t, err)
+	dailyUser.AlertPreferences = map[string]string{constants.AlertFailedFixity: constants.AlertDeliveryDaily}
+	require.Nil(t, dailyUser.Save())
+
+	inAppUser, err := pgmodels.UserByEmail("user@inst1.edu")
+	require.Nil(t, err)
+	inAppUser.AlertPreferences = map[string]string{constants.AlertFailedFixity: constants.AlertDeliveryInApp}
+	require.Nil(t, inAppUser.Save())
+
+	immediateUser, err := pgmodels.UserByEmail("system@aptrust.org")
+	require.Nil(t, err)
+
+	for i := 0; i < 2; i++ {
+		alert := &pgmodels.Alert{
+			InstitutionID: 2,
+			Type:          constants.AlertFailedFixity,
+			Subject:       "Failed Fixity Check",
+			Users:         []*pgmodels.User{dailyUser, inAppUser, immediateUser},
+		}
+		_, err = pgmodels.CreateAlert(alert, "alerts/failed_fixity.txt", map[string]interface{}{})
+		require.Nil(t, err)
+	}
+
+	// Only the immediate user got an email, so there's nothing
+	// for the weekly digest and one daily digest. The daily digest
+	// includes our two new alerts plus the unsent Failed Fixity
+	// alert from the fixtures.
+	weekly, err := pgmodels.AlertDigestsFor(constants.AlertDeliveryWeekly)
+	require.Nil(t, err)
+	assert.Empty(t, weekly)
+
+	daily, err := pgmodels.AlertDigestsFor(constants.AlertDeliveryDaily)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(daily))
+	assert.Equal(t, dailyUser.ID, daily[0].User.ID)
+	assert.Equal(t, 3, len(daily[0].Alerts))
+	assert.Equal(t, "Your daily APTrust Registry digest: 3 alerts", daily[0].Subject())
+	body, err := daily[0].Body()
+	require.Nil(t, err)
+	assert.Contains(t, body, "Failed Fixity Check")
+	assert.Contains(t, body, "/users/my_account")
+
+	count, err := pgmodels.SendAlertDigests(constants.AlertDeliveryDaily)
+	require.Nil(t, err)
+	assert.Equal(t, 1, count)
+
+	// Sent alerts aren't included in the next digest.
+	daily, err = pgmodels.AlertDigestsFor(constants.AlertDeliveryDaily)
+	require.Nil(t, err)
+	assert.Empty(t, daily)
+}
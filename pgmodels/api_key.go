@@ -0,0 +1,245 @@
+package pgmodels
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/stretchr/stew/slice"
+)
+
+const (
+	ErrAPIKeyUserID      = "UserID is required."
+	ErrAPIKeyName        = "Please give this key a name."
+	ErrAPIKeyPrefix      = "Key prefix is missing."
+	ErrAPIKeyEncrypted   = "Encrypted key is missing."
+	ErrAPIKeyScope       = "Scope must be read-only or read-write."
+	ErrAPIKeyPermissions = "One or more permissions are invalid."
+	ErrAPIKeyExpiresAt   = "Expiration date must be in the future."
+)
+
+// APIKeyPrefixLength is the number of characters from the start of
+// the plaintext key that we store in APIKey.KeyPrefix.
+const APIKeyPrefixLength = 8
+
+// APIKeyUseInterval is how often we record a key's last use.
+// Clients may call the API many times a second, and recording
+// every call would mean a database write for each one.
+const APIKeyUseInterval = time.Minute
+
+// APIKey is one of possibly several named keys a user can use to access
+// the API. Each key has a scope (read-only or read-write) and may be
+// further limited to a list of permissions. A key can also have an
+// expiration date, and can be revoked at any time.
+//
+// Keys only ever narrow what a user can do. A request made with a key
+// must be permitted by both the user's role and the key.
+//
+// We store a bcrypt hash of the key, never the key itself.
+type APIKey struct {
+	tableName struct{} `pg:"api_keys"`
+	TimestampModel
+	UserID       int64     `json:"user_id"`
+	Name         string    `json:"name"`
+	KeyPrefix    string    `json:"key_prefix"`
+	EncryptedKey string    `json:"-"`
+	Scope        string    `json:"scope"`
+	Permissions  []string  `json:"permissions" pg:"permissions,array"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	LastUsedAt   time.Time `json:"last_used_at"`
+	LastUsedIP   string    `json:"last_used_ip" pg:"last_used_ip"`
+	RevokedAt    time.Time `json:"revoked_at"`
+	User         *User     `json:"-" pg:"rel:has-one"`
+}
+
+// NewAPIKey returns a new, unsaved API key for the specified user,
+// along with the plaintext key. The plaintext key is not stored
+// anywhere, so the caller must show it to the user now or never.
+// Param expiresAt may be the zero time, which means the key
+// does not expire.
+func NewAPIKey(userID int64, name, scope string, permissions []string, expiresAt time.Time) (*APIKey, string, error) {
+	plaintext := common.RandomToken()
+	encrypted, err := common.EncryptPassword(plaintext)
+	if err != nil {
+		return nil, "", err
+	}
+	if permissions == nil {
+		permissions = make([]string, 0)
+	}
+	key := &APIKey{
+		UserID:       userID,
+		Name:         name,
+		KeyPrefix:    plaintext[:APIKeyPrefixLength],
+		EncryptedKey: encrypted,
+		Scope:        scope,
+		Permissions:  permissions,
+		ExpiresAt:    expiresAt,
+	}
+	return key, plaintext, nil
+}
+
+// APIKeyByID returns the API key with the specified id.
+// Returns pg.ErrNoRows if there is no match.
+func APIKeyByID(id int64) (*APIKey, error) {
+	query := NewQuery().Where("id", "=", id)
+	return APIKeyGet(query)
+}
+
+// APIKeyGet returns the first API key matching the query.
+func APIKeyGet(query *Query) (*APIKey, error) {
+	var key APIKey
+	err := query.Select(&key)
+	return &key, err
+}
+
+// APIKeySelect returns all API keys matching the query.
+func APIKeySelect(query *Query) ([]*APIKey, error) {
+	var keys []*APIKey
+	err := query.Select(&keys)
+	return keys, err
+}
+
+// APIKeysForUser returns all of the user's API keys, including
+// expired and revoked keys, newest first.
+func APIKeysForUser(userID int64) ([]*APIKey, error) {
+	query := NewQuery().
+		Where("user_id", "=", userID).
+		OrderBy("created_at", "desc").
+		OrderBy("id", "desc")
+	return APIKeySelect(query)
+}
+
+// APIKeyFor returns the user's active API key matching the plaintext
+// key, or nil if there's no match. Revoked and expired keys never
+// match. The error return is for database errors only.
+func APIKeyFor(userID int64, plaintext string) (*APIKey, error) {
+	if len(plaintext) < APIKeyPrefixLength {
+		return nil, nil
+	}
+	query := NewQuery().
+		Where("user_id", "=", userID).
+		Where("key_prefix", "=", plaintext[:APIKeyPrefixLength]).
+		IsNull("revoked_at")
+	keys, err := APIKeySelect(query)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if key.IsActive() && common.ComparePasswords(key.EncryptedKey, plaintext) {
+			return key, nil
+		}
+	}
+	return nil, nil
+}
+
+// Save saves this key to the database. This will peform an insert
+// if APIKey.ID is zero. Otherwise, it updates.
+func (key *APIKey) Save() error {
+	key.SetTimestamps()
+	err := key.Validate()
+	if err != nil {
+		return err
+	}
+	if key.ID == int64(0) {
+		return insert(key)
+	}
+	return update(key)
+}
+
+// Validate validates the model. This is called automatically on insert
+// and update.
+func (key *APIKey) Validate() *common.ValidationError {
+	errors := make(map[string]string)
+	if key.UserID < 1 {
+		errors["UserID"] = ErrAPIKeyUserID
+	}
+	if common.IsEmptyString(key.Name) {
+		errors["Name"] = ErrAPIKeyName
+	}
+	if len(key.KeyPrefix) != APIKeyPrefixLength {
+		errors["KeyPrefix"] = ErrAPIKeyPrefix
+	}
+	if common.IsEmptyString(key.EncryptedKey) {
+		errors["EncryptedKey"] = ErrAPIKeyEncrypted
+	}
+	if !slice.Contains(constants.APIKeyScopes, key.Scope) {
+		errors["Scope"] = ErrAPIKeyScope
+	}
+	for _, perm := range key.Permissions {
+		if !slice.Contains(constants.Permissions, constants.Permission(perm)) {
+			errors["Permissions"] = ErrAPIKeyPermissions
+		}
+	}
+	// Check expiration on new keys only, so existing keys
+	// can still be revoked after they expire.
+	if key.ID == 0 && !key.ExpiresAt.IsZero() && key.ExpiresAt.Before(time.Now().UTC()) {
+		errors["ExpiresAt"] = ErrAPIKeyExpiresAt
+	}
+	if len(errors) > 0 {
+		return &common.ValidationError{Errors: errors}
+	}
+	return nil
+}
+
+// IsExpired returns true if this key has an expiration date
+// and that date has passed.
+func (key *APIKey) IsExpired() bool {
+	return !key.ExpiresAt.IsZero() && key.ExpiresAt.Before(time.Now().UTC())
+}
+
+// IsRevoked returns true if this key has been revoked.
+func (key *APIKey) IsRevoked() bool {
+	return !key.RevokedAt.IsZero()
+}
+
+// IsActive returns true if this key can be used to authenticate.
+func (key *APIKey) IsActive() bool {
+	return !key.IsRevoked() && !key.IsExpired()
+}
+
+// Allows returns true if this key permits a request using the specified
+// HTTP method that requires the specified permission. Read-only keys
+// allow only GET and HEAD requests. Keys with an empty permission list
+// allow anything the user's role allows.
+//
+// This does not check the user's role. The auth middleware checks that
+// separately.
+func (key *APIKey) Allows(permission constants.Permission, method string) bool {
+	if !key.IsActive() {
+		return false
+	}
+	if key.Scope != constants.APIKeyScopeReadWrite && method != http.MethodGet && method != http.MethodHead {
+		return false
+	}
+	return len(key.Permissions) == 0 || slice.Contains(key.Permissions, string(permission))
+}
+
+// Revoke revokes this key, so it can no longer be used. Revoking
+// a key that's already revoked is a no-op.
+func (key *APIKey) Revoke() error {
+	if key.IsRevoked() {
+		return nil
+	}
+	key.RevokedAt = time.Now().UTC()
+	return key.Save()
+}
+
+// RecordUse records when and from where this key was last used.
+// This updates only the last_used columns, and does not run
+// validation, since it's called on every API request. Like
+// UserSession.Touch, it writes at most once per APIKeyUseInterval,
+// so the recorded time and IP address may be up to a minute old.
+func (key *APIKey) RecordUse(ipAddress string) error {
+	now := time.Now().UTC()
+	if now.Sub(key.LastUsedAt) < APIKeyUseInterval {
+		return nil
+	}
+	key.LastUsedAt = now
+	key.LastUsedIP = ipAddress
+	_, err := common.Context().DB.Model(key).
+		Column("last_used_at", "last_used_ip").
+		WherePK().
+		Update()
+	return err
+}
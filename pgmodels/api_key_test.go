@@ -0,0 +1,137 @@
+package pgmodels_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAPIKey(t *testing.T) {
+	key, plaintext, err := pgmodels.NewAPIKey(3, "CI", constants.APIKeyScopeReadOnly, nil, time.Time{})
+	require.Nil(t, err)
+	require.NotNil(t, key)
+	assert.Equal(t, 32, len(plaintext))
+	assert.Equal(t, plaintext[:pgmodels.APIKeyPrefixLength], key.KeyPrefix)
+	assert.NotEqual(t, plaintext, key.EncryptedKey)
+	assert.NotNil(t, key.Permissions)
+	assert.Nil(t, key.Validate())
+}
+
+func TestAPIKeyValidate(t *testing.T) {
+	key := &pgmodels.APIKey{}
+	err := key.Validate()
+	require.NotNil(t, err)
+	assert.Equal(t, pgmodels.ErrAPIKeyUserID, err.Errors["UserID"])
+	assert.Equal(t, pgmodels.ErrAPIKeyName, err.Errors["Name"])
+	assert.Equal(t, pgmodels.ErrAPIKeyPrefix, err.Errors["KeyPrefix"])
+	assert.Equal(t, pgmodels.ErrAPIKeyEncrypted, err.Errors["EncryptedKey"])
+	assert.Equal(t, pgmodels.ErrAPIKeyScope, err.Errors["Scope"])
+
+	key, _, _ = pgmodels.NewAPIKey(3, "CI", constants.APIKeyScopeReadWrite, []string{constants.WorkItemRead, "FlyToTheMoon"}, time.Now().Add(-time.Hour))
+	err = key.Validate()
+	require.NotNil(t, err)
+	assert.Equal(t, pgmodels.ErrAPIKeyPermissions, err.Errors["Permissions"])
+	assert.Equal(t, pgmodels.ErrAPIKeyExpiresAt, err.Errors["ExpiresAt"])
+
+	// Existing keys may be saved after they expire, so
+	// they can be revoked.
+	key.ID = 100
+	key.Permissions = []string{constants.WorkItemRead}
+	assert.Nil(t, key.Validate())
+}
+
+func TestAPIKeyAllows(t *testing.T) {
+	key, _, err := pgmodels.NewAPIKey(3, "Reports", constants.APIKeyScopeReadOnly, nil, time.Time{})
+	require.Nil(t, err)
+	assert.True(t, key.Allows(constants.IntellectualObjectRead, http.MethodGet))
+	assert.True(t, key.Allows(constants.IntellectualObjectRead, http.MethodHead))
+	assert.False(t, key.Allows(constants.IntellectualObjectRestore, http.MethodPost))
+	assert.False(t, key.Allows(constants.IntellectualObjectUpdate, http.MethodPut))
+
+	key.Scope = constants.APIKeyScopeReadWrite
+	assert.True(t, key.Allows(constants.IntellectualObjectRestore, http.MethodPost))
+
+	key.Permissions = []string{constants.WorkItemRead}
+	assert.True(t, key.Allows(constants.WorkItemRead, http.MethodGet))
+	assert.False(t, key.Allows(constants.IntellectualObjectRead, http.MethodGet))
+
+	key.ExpiresAt = time.Now().Add(-time.Minute)
+	assert.True(t, key.IsExpired())
+	assert.False(t, key.IsActive())
+	assert.False(t, key.Allows(constants.WorkItemRead, http.MethodGet))
+
+	key.ExpiresAt = time.Now().Add(time.Hour)
+	assert.True(t, key.IsActive())
+	key.RevokedAt = time.Now()
+	assert.True(t, key.IsRevoked())
+	assert.False(t, key.Allows(constants.WorkItemRead, http.MethodGet))
+}
+
+func TestAPIKeyFor(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+
+	key1, plaintext1, err := pgmodels.NewAPIKey(3, "CI", constants.APIKeyScopeReadOnly, nil, time.Time{})
+	require.Nil(t, err)
+	require.Nil(t, key1.Save())
+	key2, plaintext2, err := pgmodels.NewAPIKey(3, "Tooling", constants.APIKeyScopeReadWrite, []string{constants.WorkItemRead}, time.Now().Add(time.Hour))
+	require.Nil(t, err)
+	require.Nil(t, key2.Save())
+
+	// Both keys work at the same time.
+	found, err := pgmodels.APIKeyFor(3, plaintext1)
+	require.Nil(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, key1.ID, found.ID)
+
+	found, err = pgmodels.APIKeyFor(3, plaintext2)
+	require.Nil(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, key2.ID, found.ID)
+	assert.Equal(t, []string{constants.WorkItemRead}, found.Permissions)
+
+	// Keys belong to one user only.
+	found, err = pgmodels.APIKeyFor(2, plaintext1)
+	require.Nil(t, err)
+	assert.Nil(t, found)
+
+	// Bad keys don't match.
+	for _, bad := range []string{"", "short", plaintext1[:8] + "0000000000000000000000000"} {
+		found, err = pgmodels.APIKeyFor(3, bad)
+		require.Nil(t, err)
+		assert.Nil(t, found, bad)
+	}
+
+	// Record use.
+	require.Nil(t, key1.RecordUse("10.0.0.1"))
+	reloaded, err := pgmodels.APIKeyByID(key1.ID)
+	require.Nil(t, err)
+	assert.Equal(t, "10.0.0.1", reloaded.LastUsedIP)
+	assert.False(t, reloaded.LastUsedAt.IsZero())
+
+	// Uses within APIKeyUseInterval aren't written.
+	require.Nil(t, reloaded.RecordUse("10.0.0.2"))
+	reloaded, err = pgmodels.APIKeyByID(key1.ID)
+	require.Nil(t, err)
+	assert.Equal(t, "10.0.0.1", reloaded.LastUsedIP)
+
+	// Revoked keys don't match, and revoking one key
+	// doesn't affect the other.
+	require.Nil(t, key1.Revoke())
+	found, err = pgmodels.APIKeyFor(3, plaintext1)
+	require.Nil(t, err)
+	assert.Nil(t, found)
+	found, err = pgmodels.APIKeyFor(3, plaintext2)
+	require.Nil(t, err)
+	assert.NotNil(t, found)
+
+	keys, err := pgmodels.APIKeysForUser(3)
+	require.Nil(t, err)
+	assert.Equal(t, 2, len(keys))
+}
@@ -0,0 +1,155 @@
+package pgmodels
+
+import (
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/go-pg/pg/v10"
+	"github.com/stretchr/stew/slice"
+)
+
+const (
+	ErrAuditEventAction  = "Action is missing or invalid."
+	ErrAuditEventOutcome = "Outcome must be Success or Failure."
+)
+
+var AuditEventFilters = []string{
+	"action",
+	"created_at__gteq",
+	"created_at__lteq",
+	"institution_id",
+	"ip_address",
+	"outcome",
+	"user_email__contains",
+	"user_id",
+}
+
+// AuditEvent is an entry in the security audit log. We record sign-ins,
+// failed logins, two-factor failures, API key use, password resets,
+// role changes, and requests the authorization middleware denied.
+//
+// The log is append-only. Save refuses to update an existing event,
+// and a database trigger rejects updates and deletes.
+//
+// UserID and UserEmail describe the account the event is about. For a
+// failed login with an unknown email address, UserID is zero and
+// UserEmail is the address that was tried. InstitutionID is that
+// account's institution, which is what limits institutional admins
+// to their own institution's events.
+type AuditEvent struct {
+	BaseModel
+	UserID        int64     `json:"user_id"`
+	UserEmail     string    `json:"user_email"`
+	InstitutionID int64     `json:"institution_id"`
+	Action        string    `json:"action"`
+	Outcome       string    `json:"outcome"`
+	ResourceType  string    `json:"resource_type"`
+	ResourceID    int64     `json:"resource_id"`
+	Detail        string    `json:"detail"`
+	IPAddress     string    `json:"ip_address" pg:"ip_address"`
+	UserAgent     string    `json:"user_agent"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// NewAuditEvent returns a new, unsaved audit event about the specified
+// user. Param user may be nil if we don't know who the event is about.
+func NewAuditEvent(action, outcome string, user *User) *AuditEvent {
+	event := &AuditEvent{
+		Action:  action,
+		Outcome: outcome,
+	}
+	if user != nil {
+		event.UserID = user.ID
+		event.UserEmail = user.Email
+		event.InstitutionID = user.InstitutionID
+	}
+	return event
+}
+
+// AuditEventByID returns the audit event with the specified id.
+// Returns pg.ErrNoRows if there is no match.
+func AuditEventByID(id int64) (*AuditEvent, error) {
+	query := NewQuery().Where("id", "=", id)
+	return AuditEventGet(query)
+}
+
+// AuditEventGet returns the first audit event matching the query.
+func AuditEventGet(query *Query) (*AuditEvent, error) {
+	var event AuditEvent
+	err := query.Select(&event)
+	return &event, err
+}
+
+// AuditEventSelect returns all audit events matching the query.
+func AuditEventSelect(query *Query) ([]*AuditEvent, error) {
+	var events []*AuditEvent
+	err := query.Select(&events)
+	return events, err
+}
+
+// HasRecentAuditEvent returns true if there's already an event with the
+// same user, action, outcome, resource and IP address as this one since
+// the specified time. We use this to record routine events, such as
+// successful API requests, once an hour rather than on every request.
+func (event *AuditEvent) HasRecentAuditEvent(since time.Time) (bool, error) {
+	return common.Context().DB.Model((*AuditEvent)(nil)).
+		Where("user_id = ?", event.UserID).
+		Where("action = ?", event.Action).
+		Where("outcome = ?", event.Outcome).
+		Where("coalesce(resource_type, '') = ?", event.ResourceType).
+		Where("coalesce(resource_id, 0) = ?", event.ResourceID).
+		Where("coalesce(ip_address, '') = ?", event.IPAddress).
+		Where("created_at >= ?", since).
+		Exists()
+}
+
+// AuditFailuresFromIP returns the number of failed sign-in and two-factor
+// attempts from the specified IP address since the specified time, and
+// the time of the most recent one. We use this to throttle sign-in
+// attempts by IP address.
+func AuditFailuresFromIP(ipAddress string, since time.Time) (int, time.Time, error) {
+	var count int
+	var latest time.Time
+	err := common.Context().DB.Model((*AuditEvent)(nil)).
+		ColumnExpr("count(*)").
+		ColumnExpr("max(created_at)").
+		Where("ip_address = ?", ipAddress).
+		Where("outcome = ?", constants.OutcomeFailure).
+		Where("action in (?)", pg.In([]string{constants.AuditSignIn, constants.AuditSecondFactor})).
+		Where("created_at >= ?", since).
+		Select(&count, &latest)
+	return count, latest, err
+}
+
+// Save inserts this event into the audit log. Audit events can't be
+// changed, so this returns common.ErrNotSupported if the event has
+// already been saved.
+func (event *AuditEvent) Save() error {
+	if event.ID != 0 {
+		return common.ErrNotSupported
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now().UTC()
+	}
+	err := event.Validate()
+	if err != nil {
+		return err
+	}
+	return insert(event)
+}
+
+// Validate validates the model. This is called automatically on insert.
+func (event *AuditEvent) Validate() *common.ValidationError {
+	errors := make(map[string]string)
+	if !slice.Contains(constants.AuditActions, event.Action) {
+		errors["Action"] = ErrAuditEventAction
+	}
+	if !slice.Contains(constants.EventOutcomes, event.Outcome) {
+		errors["Outcome"] = ErrAuditEventOutcome
+	}
+	if len(errors) > 0 {
+		return &common.ValidationError{Errors: errors}
+	}
+	return nil
+}
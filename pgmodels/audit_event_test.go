@@ -0,0 +1,105 @@
+package pgmodels_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAuditEvent(t *testing.T) {
+	user := &pgmodels.User{Email: "user@inst1.edu", InstitutionID: 2}
+	user.ID = 3
+	event := pgmodels.NewAuditEvent(constants.AuditSignIn, constants.OutcomeSuccess, user)
+	assert.Equal(t, int64(3), event.UserID)
+	assert.Equal(t, "user@inst1.edu", event.UserEmail)
+	assert.Equal(t, int64(2), event.InstitutionID)
+	assert.Nil(t, event.Validate())
+
+	event = pgmodels.NewAuditEvent(constants.AuditSignIn, constants.OutcomeFailure, nil)
+	assert.Empty(t, event.UserID)
+	assert.Empty(t, event.InstitutionID)
+	assert.Nil(t, event.Validate())
+}
+
+func TestAuditEventValidate(t *testing.T) {
+	event := &pgmodels.AuditEvent{}
+	err := event.Validate()
+	require.NotNil(t, err)
+	assert.Equal(t, pgmodels.ErrAuditEventAction, err.Errors["Action"])
+	assert.Equal(t, pgmodels.ErrAuditEventOutcome, err.Errors["Outcome"])
+
+	event.Action = "Launch Missiles"
+	event.Outcome = "Maybe"
+	err = event.Validate()
+	require.NotNil(t, err)
+	assert.Equal(t, pgmodels.ErrAuditEventAction, err.Errors["Action"])
+	assert.Equal(t, pgmodels.ErrAuditEventOutcome, err.Errors["Outcome"])
+}
+
+func TestAuditEventSaveIsAppendOnly(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+
+	user, err := pgmodels.UserByEmail("user@inst1.edu")
+	require.Nil(t, err)
+	event := pgmodels.NewAuditEvent(constants.AuditAPIAuthentication, constants.OutcomeSuccess, user)
+	event.IPAddress = "10.0.0.1"
+	event.ResourceType = "APIKey"
+	event.ResourceID = 99
+	require.Nil(t, event.Save())
+	assert.True(t, event.ID > 0)
+	assert.False(t, event.CreatedAt.IsZero())
+
+	saved, err := pgmodels.AuditEventByID(event.ID)
+	require.Nil(t, err)
+	assert.Equal(t, user.ID, saved.UserID)
+	assert.Equal(t, user.InstitutionID, saved.InstitutionID)
+	assert.Equal(t, "10.0.0.1", saved.IPAddress)
+
+	instID, err := pgmodels.InstIDFor("AuditEvent", event.ID)
+	require.Nil(t, err)
+	assert.Equal(t, user.InstitutionID, instID)
+
+	// Saved events can't be changed, either through the model
+	// or directly in the database.
+	saved.Outcome = constants.OutcomeFailure
+	assert.Equal(t, common.ErrNotSupported, saved.Save())
+	_, err = common.Context().DB.Model(saved).WherePK().Update()
+	assert.NotNil(t, err)
+	_, err = common.Context().DB.Model(saved).WherePK().Delete()
+	assert.NotNil(t, err)
+
+	// Routine events are recorded once per period.
+	recent, err := event.HasRecentAuditEvent(time.Now().Add(-time.Hour))
+	require.Nil(t, err)
+	assert.True(t, recent)
+	event.IPAddress = "10.0.0.2"
+	recent, err = event.HasRecentAuditEvent(time.Now().Add(-time.Hour))
+	require.Nil(t, err)
+	assert.False(t, recent)
+
+	// Only sign-in and two-factor failures count against an IP.
+	count, _, err := pgmodels.AuditFailuresFromIP("10.0.0.1", time.Now().Add(-time.Hour))
+	require.Nil(t, err)
+	assert.Equal(t, 0, count)
+	for _, action := range []string{constants.AuditSignIn, constants.AuditSecondFactor, constants.AuditAccessDenied, constants.AuditSignInBlocked} {
+		failure := pgmodels.NewAuditEvent(action, constants.OutcomeFailure, user)
+		failure.IPAddress = "10.0.0.1"
+		require.Nil(t, failure.Save())
+	}
+	count, latest, err := pgmodels.AuditFailuresFromIP("10.0.0.1", time.Now().Add(-time.Hour))
+	require.Nil(t, err)
+	assert.Equal(t, 2, count)
+	assert.InDelta(t, time.Now().Unix(), latest.Unix(), 5)
+
+	query := pgmodels.NewQuery().Where("institution_id", "=", user.InstitutionID)
+	events, err := pgmodels.AuditEventSelect(query)
+	require.Nil(t, err)
+	assert.Equal(t, 5, len(events))
+}
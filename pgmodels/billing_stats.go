@@ -15,28 +15,37 @@ type BillingStats struct {
 	TotalGB         float64   `json:"total_gb"`
 	TotalTB         float64   `json:"total_tb"`
 	Overage         float64   `json:"overage"`
+	CostGBPerMonth  float64   `json:"cost_gb_per_month" pg:"cost_gb_per_month"`
+	MonthlyCost     float64   `json:"monthly_cost"`
 }
 
+// Overage is the amount over the institution's free allowance. Stats
+// for institutions that no longer exist use the default allowance.
+// Cost per GB and monthly cost are at the price in effect during each
+// month, as recorded in historical_deposit_stats.
 var billingStatsQuery = `select
-	institution_id,
-	institution_name,
-	end_date,
-	to_char((end_date - interval '1 day'), 'Month YYYY') as month_and_year,
-	storage_option,
-	total_gb,
-	total_tb,
-	greatest((total_tb - 10.0), 0.0) as overage
-	from historical_deposit_stats
-	where institution_id = ?
-	and end_date > ?
-	and end_date <= ?
-	and (? = '' or storage_option = ?)
-	and total_tb > 0
-	and storage_option != 'Total'
-	order by end_date, storage_option`
+	hds.institution_id,
+	hds.institution_name,
+	hds.end_date,
+	to_char((hds.end_date - interval '1 day'), 'Month YYYY') as month_and_year,
+	hds.storage_option,
+	hds.total_gb,
+	hds.total_tb,
+	greatest((hds.total_tb - coalesce(i.free_allowance_tb, ?)), 0.0) as overage,
+	hds.cost_gb_per_month,
+	hds.monthly_cost
+	from historical_deposit_stats hds
+	left join institutions i on i.id = hds.institution_id
+	where hds.institution_id = ?
+	and hds.end_date > ?
+	and hds.end_date <= ?
+	and (? = '' or hds.storage_option = ?)
+	and hds.total_tb > 0
+	and hds.storage_option != 'Total'
+	order by hds.end_date, hds.storage_option`
 
 func BillingStatsSelect(institutionID int64, startDate, endDate time.Time, storageOption string) ([]*BillingStats, error) {
 	var stats []*BillingStats
-	_, err := common.Context().DB.Query(&stats, billingStatsQuery, institutionID, startDate, endDate, storageOption, storageOption)
+	_, err := common.Context().DB.Query(&stats, billingStatsQuery, DefaultFreeAllowanceTB, institutionID, startDate, endDate, storageOption, storageOption)
 	return stats, err
 }
@@ -0,0 +1,284 @@
+package pgmodels
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+)
+
+// Cursor marks a position in a list of records sorted by Column, with
+// id as a tie-breaker. Keyset pagination uses cursors instead of
+// limit/offset, so fetching page 5,000 of a large table costs no more
+// than fetching page 1. Postgres can jump straight to the cursor's
+// position using the index on the sort column, instead of reading
+// and discarding all of the preceding rows.
+//
+// Clients see cursors only as opaque tokens. See Encode and
+// DecodeCursor.
+type Cursor struct {
+	// Column is the sort column.
+	Column string `json:"c"`
+
+	// Direction is the sort direction, "asc" or "desc".
+	Direction string `json:"d"`
+
+	// Value is the value of the sort column in the record at the
+	// cursor position. Postgres converts it back to the column's
+	// type in the where clause.
+	Value string `json:"v,omitempty"`
+
+	// Null is true if the sort column is null in the record at the
+	// cursor position. Nulls sort after all other values in both
+	// directions, so they come at the end of the list.
+	Null bool `json:"n,omitempty"`
+
+	// ID is the id of the record at the cursor position. Zero means
+	// the start of the list.
+	ID int64 `json:"i,omitempty"`
+
+	// Before is true if the cursor points to the page that comes
+	// before this record, rather than the one after it.
+	Before bool `json:"b,omitempty"`
+
+	// Total is the number of matching records when the client
+	// requested the first page. We carry it forward so we don't
+	// have to count on every page.
+	Total int `json:"t,omitempty"`
+}
+
+// NewCursor returns a cursor pointing to the start of a list sorted
+// by column in the specified direction.
+func NewCursor(column, direction string) *Cursor {
+	return &Cursor{
+		Column:    common.SanitizeIdentifier(column),
+		Direction: normalizeDirection(direction),
+	}
+}
+
+// DecodeCursor decodes a cursor token created by Cursor.Encode.
+// It returns common.ErrInvalidCursor if the token is not valid.
+func DecodeCursor(token string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, common.ErrInvalidCursor
+	}
+	cursor := &Cursor{}
+	err = json.Unmarshal(data, cursor)
+	if err != nil || cursor.Column == "" || cursor.Column != common.SanitizeIdentifier(cursor.Column) {
+		return nil, common.ErrInvalidCursor
+	}
+	if cursor.Direction != "asc" && cursor.Direction != "desc" {
+		return nil, common.ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// Encode returns this cursor as an opaque, URL-safe token.
+func (cursor *Cursor) Encode() string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// IsStart returns true if this cursor points to the start of a list.
+func (cursor *Cursor) IsStart() bool {
+	return cursor.ID == 0
+}
+
+// cursorAt returns a cursor pointing after (or before) item, which
+// must be a pointer to a model struct with an id column.
+func (cursor *Cursor) cursorAt(item reflect.Value, before bool) (*Cursor, error) {
+	table := orm.GetTable(item.Elem().Type())
+	idField, ok := table.FieldsMap["id"]
+	if !ok {
+		return nil, common.ErrInvalidParam
+	}
+	sortField, ok := table.FieldsMap[cursor.Column]
+	if !ok {
+		return nil, common.ErrInvalidCursor
+	}
+	var err error
+	strct := item.Elem()
+	next := &Cursor{
+		Column:    cursor.Column,
+		Direction: cursor.Direction,
+		ID:        idField.Value(strct).Int(),
+		Before:    before,
+		Total:     cursor.Total,
+	}
+	value := sortField.Value(strct)
+	if value.IsZero() {
+		// Nulls load as zero values, so ask the database whether
+		// this one is really null.
+		next.Null, err = cursor.isNull(item, next.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !next.Null {
+		next.Value = cursorValue(value)
+	}
+	return next, nil
+}
+
+// isNull returns true if the sort column is null in the record
+// with the specified id. item is a pointer to a model struct.
+func (cursor *Cursor) isNull(item reflect.Value, id int64) (bool, error) {
+	var isNull bool
+	err := common.Context().DB.Model(item.Interface()).
+		ColumnExpr("? is null", pg.Ident(cursor.Column)).
+		Where("id = ?", id).
+		Select(&isNull)
+	return isNull, err
+}
+
+func cursorValue(v reflect.Value) string {
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.UTC().Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// SortColumn returns the column and direction of this query's first
+// order by clause. If the query has no order by, this returns id asc.
+func (q *Query) SortColumn() (string, string) {
+	if len(q.orderBy) == 0 {
+		return "id", "asc"
+	}
+	parts := strings.Fields(q.orderBy[0])
+	if len(parts) < 2 {
+		return parts[0], "asc"
+	}
+	return parts[0], normalizeDirection(parts[1])
+}
+
+// SelectPage loads one page of up to limit records at the position of
+// cursor into items, which must be a pointer to a slice of pointers.
+// The cursor determines the sort order, replacing any order by clauses
+// already in the query. This ignores the query's limit and offset.
+// It adds the cursor's position to the query's where clause, so use
+// a fresh query for each page.
+//
+// SelectPage returns cursors for the next and previous pages, or nil
+// if there is no next or previous page.
+//
+// Rows with a null value in the sort column come last, whichever way
+// the list is sorted, ordered by id among themselves.
+func (q *Query) SelectPage(items interface{}, cursor *Cursor, limit int) (next *Cursor, previous *Cursor, err error) {
+	if items == nil || !strings.HasPrefix(reflect.TypeOf(items).String(), "*[]*") {
+		return nil, nil, common.ErrInvalidParam
+	}
+	dir := cursor.Direction
+	op := ">"
+	if dir == "desc" {
+		op = "<"
+	}
+	nulls := "nulls last"
+	if cursor.Before {
+		dir = reverseDirection(dir)
+		op = map[string]string{">": "<", "<": ">"}[op]
+		nulls = "nulls first"
+	}
+	if !cursor.IsStart() {
+		if cursor.Column == "id" {
+			q.conditions = append(q.conditions, fmt.Sprintf("(id %s ?)", op))
+			q.params = append(q.params, cursor.ID)
+		} else {
+			q.conditions = append(q.conditions, cursor.condition(op))
+			if cursor.Null {
+				q.params = append(q.params, cursor.ID)
+			} else {
+				q.params = append(q.params, cursor.Value, cursor.ID)
+			}
+			q.whereColumns = append(q.whereColumns, cursor.Column)
+		}
+	}
+	q.orderBy = []string{fmt.Sprintf("%s %s", cursor.Column, dir)}
+	if cursor.Column != "id" {
+		q.orderBy = []string{
+			fmt.Sprintf("%s %s %s", cursor.Column, dir, nulls),
+			fmt.Sprintf("id %s", dir),
+		}
+	}
+
+	// Fetch one extra record to see whether there's another page.
+	q.Offset(-1).Limit(limit + 1)
+	err = q.Select(items)
+	if err != nil {
+		return nil, nil, err
+	}
+	list := reflect.ValueOf(items).Elem()
+	hasMore := list.Len() > limit
+	if hasMore {
+		list.Set(list.Slice(0, limit))
+	}
+	if cursor.Before {
+		reverseList(list)
+	}
+	if list.Len() == 0 {
+		return nil, nil, nil
+	}
+
+	// When paging backward, we came from the next page, so it exists.
+	// When paging forward, the previous page exists unless we started
+	// at the beginning.
+	if hasMore || cursor.Before {
+		next, err = cursor.cursorAt(list.Index(list.Len()-1), false)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if (hasMore && cursor.Before) || (!cursor.Before && !cursor.IsStart()) {
+		previous, err = cursor.cursorAt(list.Index(0), true)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return next, previous, nil
+}
+
+// condition returns the where condition that selects records on the
+// cursor's side of its position, where op is the comparison operator
+// for the direction we're paging in. Since nulls come last, paging
+// forward from a non-null value includes all of the nulls, and paging
+// back from a null includes all of the non-null values.
+func (cursor *Cursor) condition(op string) string {
+	col := cursor.Column
+	switch {
+	case cursor.Null && cursor.Before:
+		return fmt.Sprintf("(%s is not null or id %s ?)", col, op)
+	case cursor.Null:
+		return fmt.Sprintf("(%s is null and id %s ?)", col, op)
+	case cursor.Before:
+		return fmt.Sprintf("((%s, id) %s (?, ?))", col, op)
+	default:
+		return fmt.Sprintf("((%s, id) %s (?, ?) or %s is null)", col, op, col)
+	}
+}
+
+func normalizeDirection(direction string) string {
+	if strings.ToLower(direction) == "desc" {
+		return "desc"
+	}
+	return "asc"
+}
+
+func reverseDirection(direction string) string {
+	if direction == "desc" {
+		return "asc"
+	}
+	return "desc"
+}
+
+func reverseList(list reflect.Value) {
+	swap := reflect.Swapper(list.Interface())
+	for i, j := 0, list.Len()-1; i < j; i, j = i+1, j-1 {
+		swap(i, j)
+	}
+}
@@ -0,0 +1,178 @@
+package pgmodels_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursorEncodeDecode(t *testing.T) {
+	cursor := &pgmodels.Cursor{
+		Column:    "date_time",
+		Direction: "desc",
+		Value:     "2021-06-16T10:24:16Z",
+		ID:        88,
+		Before:    true,
+		Total:     500,
+	}
+	token := cursor.Encode()
+	assert.NotEmpty(t, token)
+	assert.NotContains(t, token, "date_time")
+
+	decoded, err := pgmodels.DecodeCursor(token)
+	require.Nil(t, err)
+	assert.Equal(t, cursor, decoded)
+
+	invalid := []string{
+		"",
+		"not a cursor!",
+		(&pgmodels.Cursor{Column: "id", Direction: "sideways"}).Encode(),
+		(&pgmodels.Cursor{Column: "id; drop table users", Direction: "asc"}).Encode(),
+	}
+	for _, token := range invalid {
+		_, err = pgmodels.DecodeCursor(token)
+		assert.Equal(t, common.ErrInvalidCursor, err, token)
+	}
+}
+
+func TestNewCursor(t *testing.T) {
+	cursor := pgmodels.NewCursor("updated_at", "DESC")
+	assert.Equal(t, "updated_at", cursor.Column)
+	assert.Equal(t, "desc", cursor.Direction)
+	assert.True(t, cursor.IsStart())
+
+	cursor = pgmodels.NewCursor("name", "whatever")
+	assert.Equal(t, "asc", cursor.Direction)
+}
+
+func TestQuerySortColumn(t *testing.T) {
+	col, dir := pgmodels.NewQuery().SortColumn()
+	assert.Equal(t, "id", col)
+	assert.Equal(t, "asc", dir)
+
+	col, dir = pgmodels.NewQuery().OrderBy("date_time", "desc").OrderBy("id", "asc").SortColumn()
+	assert.Equal(t, "date_time", col)
+	assert.Equal(t, "desc", dir)
+}
+
+func TestQuerySelectPage(t *testing.T) {
+	db.LoadFixtures()
+
+	query := pgmodels.NewQuery().OrderBy("date_time", "desc").OrderBy("id", "desc")
+	expected, err := pgmodels.PremisEventViewSelect(query)
+	require.Nil(t, err)
+	require.True(t, len(expected) > 10)
+
+	// Walk forward through the whole list, three items at a time.
+	cursor := pgmodels.NewCursor("date_time", "desc")
+	forward := make([]*pgmodels.PremisEventView, 0)
+	var lastPrevious *pgmodels.Cursor
+	for cursor != nil {
+		var events []*pgmodels.PremisEventView
+		next, previous, err := pgmodels.NewQuery().SelectPage(&events, cursor, 3)
+		require.Nil(t, err)
+		assert.True(t, len(events) <= 3)
+		if cursor.IsStart() {
+			assert.Nil(t, previous)
+		} else {
+			assert.NotNil(t, previous)
+		}
+		forward = append(forward, events...)
+		lastPrevious = previous
+		cursor = next
+	}
+	require.Equal(t, len(expected), len(forward))
+	for i := range expected {
+		assert.Equal(t, expected[i].ID, forward[i].ID)
+	}
+
+	// Walk back from the last page.
+	require.NotNil(t, lastPrevious)
+	var events []*pgmodels.PremisEventView
+	next, _, err := pgmodels.NewQuery().SelectPage(&events, lastPrevious, 3)
+	require.Nil(t, err)
+	require.Equal(t, 3, len(events))
+	assert.NotNil(t, next)
+	lastPageSize := len(expected) % 3
+	if lastPageSize == 0 {
+		lastPageSize = 3
+	}
+	start := len(expected) - lastPageSize - 3
+	for i, event := range events {
+		assert.Equal(t, expected[start+i].ID, event.ID)
+	}
+
+	// Cursor pages honor the query's filters.
+	query = pgmodels.NewQuery().Where("institution_id", "=", 2)
+	cursor = pgmodels.NewCursor("id", "asc")
+	for cursor != nil {
+		var filtered []*pgmodels.PremisEventView
+		cursor, _, err = query.SelectPage(&filtered, cursor, 5)
+		require.Nil(t, err)
+		for _, event := range filtered {
+			assert.EqualValues(t, 2, event.InstitutionID)
+		}
+		query = pgmodels.NewQuery().Where("institution_id", "=", 2)
+	}
+}
+
+func TestQuerySelectPageNulls(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+
+	// Null out queued_at on every third item, so nulls are mixed
+	// in with the other values.
+	_, err := common.Context().DB.Exec("update work_items set queued_at = null where id % 3 = 0")
+	require.Nil(t, err)
+
+	for _, dir := range []string{"asc", "desc"} {
+		var expected []int64
+		_, err = common.Context().DB.Query(&expected, fmt.Sprintf("select id from work_items order by queued_at %s nulls last, id %s", dir, dir))
+		require.Nil(t, err)
+		require.True(t, len(expected) > 10)
+
+		// Walk forward through the whole list, four items at a
+		// time. We should get every item, with nulls at the end.
+		cursor := pgmodels.NewCursor("queued_at", dir)
+		forward := make([]int64, 0)
+		cursors := make([]*pgmodels.Cursor, 0)
+		for cursor != nil {
+			var items []*pgmodels.WorkItemView
+			next, previous, err := pgmodels.NewQuery().SelectPage(&items, cursor, 4)
+			require.Nil(t, err)
+			for _, item := range items {
+				forward = append(forward, item.ID)
+			}
+			cursors = append(cursors, previous)
+			cursor = next
+		}
+		assert.Equal(t, expected, forward, dir)
+
+		// Walk back from the last page to the first, using the
+		// previous cursor from each page.
+		backward := make([]int64, 0)
+		cursor = cursors[len(cursors)-1]
+		for cursor != nil {
+			var items []*pgmodels.WorkItemView
+			_, previous, err := pgmodels.NewQuery().SelectPage(&items, cursor, 4)
+			require.Nil(t, err)
+			require.Equal(t, 4, len(items))
+			ids := make([]int64, len(items))
+			for i, item := range items {
+				ids[i] = item.ID
+			}
+			backward = append(ids, backward...)
+			cursor = previous
+		}
+		lastPageSize := len(expected) % 4
+		if lastPageSize == 0 {
+			lastPageSize = 4
+		}
+		assert.Equal(t, expected[:len(expected)-lastPageSize], backward, dir)
+	}
+}
@@ -1,6 +1,7 @@
 package pgmodels
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/APTrust/registry/common"
@@ -28,6 +29,7 @@ const (
 func init() {
 	orm.RegisterTable((*DeletionRequestsGenericFiles)(nil))
 	orm.RegisterTable((*DeletionRequestsIntellectualObjects)(nil))
+	orm.RegisterTable((*DeletionRequestsWorkItems)(nil))
 }
 
 type DeletionRequest struct {
@@ -48,6 +50,7 @@ type DeletionRequest struct {
 	GenericFiles               []*GenericFile        `json:"generic_files" pg:"many2many:deletion_requests_generic_files"`
 	IntellectualObjects        []*IntellectualObject `json:"intellectual_objects" pg:"many2many:deletion_requests_intellectual_objects"`
 	WorkItem                   *WorkItem             `json:"work_item" pg:"rel:has-one"`
+	WorkItems                  []*WorkItem           `json:"work_items" pg:"many2many:deletion_requests_work_items"`
 }
 
 type DeletionRequestsGenericFiles struct {
@@ -62,6 +65,12 @@ type DeletionRequestsIntellectualObjects struct {
 	IntellectualObjectID int64
 }
 
+type DeletionRequestsWorkItems struct {
+	tableName         struct{} `pg:"deletion_requests_work_items"`
+	DeletionRequestID int64
+	WorkItemID        int64
+}
+
 func NewDeletionRequest() (*DeletionRequest, error) {
 	confToken := common.RandomToken()
 	encConfToken, err := common.EncryptPassword(confToken)
@@ -73,13 +82,14 @@ func NewDeletionRequest() (*DeletionRequest, error) {
 		EncryptedConfirmationToken: encConfToken,
 		GenericFiles:               make([]*GenericFile, 0),
 		IntellectualObjects:        make([]*IntellectualObject, 0),
+		WorkItems:                  make([]*WorkItem, 0),
 	}, nil
 }
 
 // DeletionRequestByID returns the institution with the specified id.
 // Returns pg.ErrNoRows if there is no match.
 func DeletionRequestByID(id int64) (*DeletionRequest, error) {
-	query := NewQuery().Relations("RequestedBy", "ConfirmedBy", "CancelledBy", "GenericFiles", "IntellectualObjects", "WorkItem").Where(`"deletion_request"."id"`, "=", id)
+	query := NewQuery().Relations("RequestedBy", "ConfirmedBy", "CancelledBy", "GenericFiles", "IntellectualObjects", "WorkItem", "WorkItems").Where(`"deletion_request"."id"`, "=", id)
 	return DeletionRequestGet(query)
 }
 
@@ -100,13 +110,13 @@ func DeletionRequestSelect(query *Query) ([]*DeletionRequest, error) {
 // Save saves this requestitution to the database. This will peform an insert
 // if DeletionRequest.ID is zero. Otherwise, it updates.
 func (request *DeletionRequest) Save() error {
-	err := request.Validate()
-	if err != nil {
-		return err
+	validationErr := request.Validate()
+	if validationErr != nil {
+		return validationErr
 	}
 	registryContext := common.Context()
 	db := registryContext.DB
-	return db.RunInTransaction(db.Context(), func(tx *pg.Tx) error {
+	err := db.RunInTransaction(db.Context(), func(tx *pg.Tx) error {
 		var err error
 		if request.ID == 0 {
 			_, err = tx.Model(request).Insert()
@@ -119,6 +129,59 @@ func (request *DeletionRequest) Save() error {
 		}
 		return request.saveRelations(tx)
 	})
+	if err == nil {
+		request.NotifyWebhooks()
+	}
+	return err
+}
+
+// State returns "requested", "approved", or "cancelled", describing
+// where this request is in the approval process. This is the state
+// we report to webhooks.
+func (request *DeletionRequest) State() string {
+	if !request.CancelledAt.IsZero() {
+		return "cancelled"
+	}
+	if !request.ConfirmedAt.IsZero() {
+		return "approved"
+	}
+	return "requested"
+}
+
+// StateChangedAt returns the time this request entered its current
+// State.
+func (request *DeletionRequest) StateChangedAt() time.Time {
+	if !request.CancelledAt.IsZero() {
+		return request.CancelledAt
+	}
+	if !request.ConfirmedAt.IsZero() {
+		return request.ConfirmedAt
+	}
+	return request.RequestedAt
+}
+
+// NotifyWebhooks queues a deletion_request.updated event for the
+// institution's webhooks. The event key includes the state and the
+// time the request entered it, so each webhook hears about each state
+// change once, no matter how many times the request is saved. Errors
+// are logged rather than returned, because they should not prevent the
+// request from being saved.
+func (request *DeletionRequest) NotifyWebhooks() {
+	ctx := common.Context()
+	view, err := DeletionRequestViewByID(request.ID)
+	if err != nil {
+		ctx.Log.Error().Msgf("Error loading deletion request %d for webhooks: %v", request.ID, err)
+		return
+	}
+	data := map[string]interface{}{
+		"state":            request.State(),
+		"deletion_request": view,
+	}
+	key := fmt.Sprintf("deletion_request/%d/%s/%s", request.ID, request.State(), request.StateChangedAt().Format(time.RFC3339Nano))
+	_, err = QueueWebhookEvent(request.InstitutionID, constants.WebhookDeletionRequest, key, data)
+	if err != nil {
+		ctx.Log.Error().Msgf("Error queueing webhook event for deletion request %d: %v", request.ID, err)
+	}
 }
 
 // Validation enforces business rules, including who can request and
@@ -247,7 +310,11 @@ func (request *DeletionRequest) saveRelations(tx *pg.Tx) error {
 	if err != nil {
 		return err
 	}
-	return request.saveWorkItem(tx)
+	err = request.saveWorkItem(tx)
+	if err != nil {
+		return err
+	}
+	return request.saveWorkItems(tx)
 }
 
 func (request *DeletionRequest) saveFiles(tx *pg.Tx) error {
@@ -284,12 +351,53 @@ func (request *DeletionRequest) saveWorkItem(tx *pg.Tx) error {
 			request.WorkItemID = request.WorkItem.ID
 			sql := "update deletion_requests set work_item_id = ? where id = ?"
 			_, err = tx.Exec(sql, request.WorkItem.ID, request.ID)
+			if err != nil {
+				return err
+			}
 		}
+		// Record this in deletion_requests_work_items as well,
+		// so we can find single and bulk deletions the same way.
+		sql := "insert into deletion_requests_work_items (deletion_request_id, work_item_id) values (?, ?) on conflict do nothing"
+		_, err = tx.Exec(sql, request.ID, request.WorkItem.ID)
 		return err
 	}
 	return nil
 }
 
+// saveWorkItems links the WorkItems for a bulk deletion to this request.
+// Unlike saveWorkItem, this does not save the WorkItems themselves.
+// The caller should have already done that.
+func (request *DeletionRequest) saveWorkItems(tx *pg.Tx) error {
+	// Note: on conflict refers to unique index index_drwi_unique
+	sql := "insert into deletion_requests_work_items (deletion_request_id, work_item_id) values (?, ?) on conflict do nothing"
+	for _, item := range request.WorkItems {
+		if item.ID == 0 {
+			return common.ErrInvalidParam
+		}
+		_, err := tx.Exec(sql, request.ID, item.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsBulk returns true if this request covers more than one file
+// or object. Bulk requests create one deletion WorkItem per item
+// when approved. See WorkItems.
+func (request *DeletionRequest) IsBulk() bool {
+	return len(request.GenericFiles)+len(request.IntellectualObjects) > 1
+}
+
+// AddWorkItem adds a deletion WorkItem to this request. Use this for
+// bulk deletions. For single-item deletions, set WorkItem instead.
+func (request *DeletionRequest) AddWorkItem(item *WorkItem) {
+	if request.WorkItems == nil {
+		request.WorkItems = make([]*WorkItem, 0)
+	}
+	request.WorkItems = append(request.WorkItems, item)
+}
+
 // FirstFile returns the first GenericFile associated with this deletion
 // request. Use this for simple, single-file deletions.
 func (request *DeletionRequest) FirstFile() *GenericFile {
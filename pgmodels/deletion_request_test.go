@@ -2,6 +2,7 @@ package pgmodels_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/APTrust/registry/common"
 	"github.com/APTrust/registry/constants"
@@ -232,6 +233,77 @@ func TestDeletionRequestAddObject(t *testing.T) {
 	assert.Equal(t, req.IntellectualObjects[0], req.FirstObject())
 }
 
+func TestDeletionRequestIsBulk(t *testing.T) {
+	req, err := pgmodels.NewDeletionRequest()
+	require.Nil(t, err)
+	assert.False(t, req.IsBulk())
+
+	req.AddObject(pgmodels.RandomObject())
+	assert.False(t, req.IsBulk())
+
+	req.AddFile(pgmodels.RandomGenericFile(9999, "obj/identifier"))
+	assert.True(t, req.IsBulk())
+
+	req.GenericFiles = nil
+	req.AddObject(pgmodels.RandomObject())
+	assert.True(t, req.IsBulk())
+}
+
+func TestDeletionRequestAddWorkItem(t *testing.T) {
+	req, err := pgmodels.NewDeletionRequest()
+	require.Nil(t, err)
+	assert.Empty(t, req.WorkItems)
+
+	req.WorkItems = nil
+	for i := 0; i < 3; i++ {
+		req.AddWorkItem(&pgmodels.WorkItem{})
+	}
+	assert.Equal(t, 3, len(req.WorkItems))
+}
+
+func TestDeletionRequestSaveWorkItems(t *testing.T) {
+	db.LoadFixtures()
+	user, err := pgmodels.UserByEmail("admin@inst1.edu")
+	require.Nil(t, err)
+	obj1, err := pgmodels.IntellectualObjectByID(1)
+	require.Nil(t, err)
+	obj2, err := pgmodels.IntellectualObjectByID(2)
+	require.Nil(t, err)
+
+	req, err := pgmodels.NewDeletionRequest()
+	require.Nil(t, err)
+	req.InstitutionID = user.InstitutionID
+	req.RequestedByID = user.ID
+	req.RequestedAt = time.Now().UTC()
+	req.AddObject(obj1)
+	req.AddObject(obj2)
+	require.Nil(t, req.Save())
+
+	// WorkItems must be saved before we can link them.
+	req.AddWorkItem(&pgmodels.WorkItem{})
+	assert.Equal(t, common.ErrInvalidParam, req.Save())
+	req.WorkItems = nil
+
+	item1, err := pgmodels.WorkItemByID(1)
+	require.Nil(t, err)
+	item2, err := pgmodels.WorkItemByID(2)
+	require.Nil(t, err)
+	req.AddWorkItem(item1)
+	req.AddWorkItem(item2)
+	require.Nil(t, req.Save())
+
+	reloaded, err := pgmodels.DeletionRequestByID(req.ID)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(reloaded.WorkItems))
+	assert.True(t, reloaded.IsBulk())
+
+	for _, item := range []*pgmodels.WorkItem{item1, item2} {
+		view, err := pgmodels.DeletionRequestViewForWorkItem(item.ID)
+		require.Nil(t, err)
+		assert.Equal(t, req.ID, view.ID)
+	}
+}
+
 func TestDeletionRequestConfirm(t *testing.T) {
 	user, err := pgmodels.UserByID(5)
 	require.Nil(t, err)
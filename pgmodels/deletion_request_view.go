@@ -2,6 +2,9 @@ package pgmodels
 
 import (
 	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/go-pg/pg/v10"
 )
 
 var DeletionRequestFilters = []string{
@@ -49,6 +52,26 @@ func DeletionRequestViewByID(id int64) (*DeletionRequestView, error) {
 	return DeletionRequestViewGet(query)
 }
 
+// DeletionRequestViewForWorkItem returns the DeletionRequestView record
+// for the request that created the specified deletion WorkItem. Single-item
+// requests record their WorkItem in deletion_requests.work_item_id. Bulk
+// requests create one WorkItem per object or file and record each one in
+// deletion_requests_work_items, so we check both. Returns pg.ErrNoRows if
+// there is no match.
+func DeletionRequestViewForWorkItem(workItemID int64) (*DeletionRequestView, error) {
+	var ids []int64
+	sql := `select deletion_request_id from deletion_requests_work_items where work_item_id = ?
+	        union select id from deletion_requests where work_item_id = ?`
+	_, err := common.Context().DB.Query(&ids, sql, workItemID, workItemID)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, pg.ErrNoRows
+	}
+	return DeletionRequestViewByID(ids[0])
+}
+
 // DeletionRequestViewSelect returns all DeletionRequestView records matching
 // the query.
 func DeletionRequestViewSelect(query *Query) ([]*DeletionRequestView, error) {
@@ -76,5 +99,10 @@ func (request *DeletionRequestView) DisplayStatus() string {
 	if request.Status != "" {
 		return request.Status
 	}
+	// Bulk requests have one WorkItem per object or file,
+	// so there's no single status to show here.
+	if request.ConfirmedByID > 0 {
+		return "Approved"
+	}
 	return "Awaiting Approval"
 }
@@ -36,3 +36,11 @@ func TestDeletionRequestViewDisplayStatus(t *testing.T) {
 		assert.Equal(t, "Rejected", req.DisplayStatus())
 	}
 }
+
+func TestDeletionRequestViewDisplayStatusBulk(t *testing.T) {
+	// Approved bulk requests have no single WorkItem status.
+	req := &pgmodels.DeletionRequestView{}
+	assert.Equal(t, "Awaiting Approval", req.DisplayStatus())
+	req.ConfirmedByID = 1000
+	assert.Equal(t, "Approved", req.DisplayStatus())
+}
@@ -4,12 +4,15 @@ import (
 	"github.com/APTrust/registry/common"
 )
 
+// DepositFormatStats is the number and size of files in one format.
+// RiskLevel is set only by FormatRiskStatsByFormat.
 type DepositFormatStats struct {
 	FileFormat string  `json:"file_format"`
 	FileCount  int64   `json:"file_count"`
 	TotalBytes int64   `json:"total_bytes"`
 	TotalGB    float64 `json:"total_gb" pg:"total_gb"`
 	TotalTB    float64 `json:"total_tb" pg:"total_tb"`
+	RiskLevel  string  `json:"risk_level,omitempty" pg:"-"`
 }
 
 // DepositFormatStatsSelect returns summary stats on the
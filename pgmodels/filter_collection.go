@@ -2,6 +2,7 @@ package pgmodels
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 
 	"github.com/APTrust/registry/common"
@@ -76,6 +77,30 @@ func (fc *FilterCollection) ToQuery() (*Query, error) {
 	return query, nil
 }
 
+// ToQueryFor is like ToQuery, but it limits results to the records
+// user may see in a list. Non-admins see only their own institution's
+// records, and only their own alerts. Param items is a pointer to the
+// slice the results will be loaded into. If the collection has no
+// explicit sort params, results are sorted by orderByColumn in the
+// specified direction.
+func (fc *FilterCollection) ToQueryFor(user *User, items interface{}, orderByColumn, direction string) (*Query, error) {
+	query, err := fc.ToQuery()
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsAdmin() {
+		query.Where("institution_id", "=", user.InstitutionID)
+		objType := reflect.ValueOf(items).Elem().Type()
+		if objType == reflect.TypeOf([]*AlertView{}) || objType == reflect.TypeOf([]*Alert{}) {
+			query.Where("user_id", "=", user.ID)
+		}
+	}
+	if !fc.HasExplicitSorting() {
+		query.OrderBy(orderByColumn, direction)
+	}
+	return query, nil
+}
+
 // ValueOf returns the value of the filter with the specified name.
 // Returns an empty string if the specified filter is missing or
 // has no value.
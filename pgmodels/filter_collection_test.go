@@ -3,6 +3,7 @@ package pgmodels_test
 import (
 	"testing"
 
+	"github.com/APTrust/registry/constants"
 	"github.com/APTrust/registry/pgmodels"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -221,6 +222,37 @@ func TestFCOrderBy(t *testing.T) {
 	assert.Equal(t, []string{"name asc", "email asc", "created_at desc"}, query.GetOrderBy())
 }
 
+func TestFCToQueryFor(t *testing.T) {
+	fc := pgmodels.NewFilterCollection()
+	_, err := fc.Add("name", []string{"Homer"})
+	require.Nil(t, err)
+
+	admin := &pgmodels.User{Role: constants.RoleSysAdmin, InstitutionID: 1}
+	query, err := fc.ToQueryFor(admin, &[]*pgmodels.WorkItem{}, "id", "desc")
+	require.Nil(t, err)
+	assert.Equal(t, `(name = ?)`, query.WhereClause())
+	assert.Equal(t, []string{"id desc"}, query.GetOrderBy())
+
+	// Non-admins see only their own institution's records,
+	// and only their own alerts.
+	user := &pgmodels.User{Role: constants.RoleInstUser, InstitutionID: 2}
+	user.ID = 3
+	query, err = fc.ToQueryFor(user, &[]*pgmodels.WorkItem{}, "id", "desc")
+	require.Nil(t, err)
+	assert.Equal(t, `(name = ?) AND (institution_id = ?)`, query.WhereClause())
+	assert.Equal(t, []interface{}{"Homer", int64(2)}, query.Params())
+
+	query, err = fc.ToQueryFor(user, &[]*pgmodels.AlertView{}, "id", "desc")
+	require.Nil(t, err)
+	assert.Equal(t, `(name = ?) AND (institution_id = ?) AND (user_id = ?)`, query.WhereClause())
+
+	// Explicit sorting overrides the default.
+	fc.AddOrderBy("name__asc")
+	query, err = fc.ToQueryFor(user, &[]*pgmodels.WorkItem{}, "id", "desc")
+	require.Nil(t, err)
+	assert.Equal(t, []string{"name asc"}, query.GetOrderBy())
+}
+
 func TestFilterString(t *testing.T) {
 	fc := pgmodels.NewFilterCollection()
 	filter, err := fc.Add("name", []string{"Homer"})
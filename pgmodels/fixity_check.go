@@ -0,0 +1,172 @@
+package pgmodels
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/go-pg/pg/v10"
+)
+
+// MaxBulkFixityFiles is the maximum number of files an admin can queue
+// for fixity checks in a single request.
+const MaxBulkFixityFiles = 1000
+
+// NewFixityCheckItem creates and saves a new WorkItem for a fixity
+// check on a file or object.
+//
+// Param obj (required) is the object to be checked. Param gf is the
+// file to be checked, or nil to check all of the object's active files.
+// Param user is the user requesting the check.
+//
+// The fixity_check topic takes GenericFile IDs, not WorkItem IDs, so
+// the fixity checker never sees this WorkItem. It exists so depositors
+// and admins can see that a check was requested and when it finished.
+// See CompleteFixityCheckItems.
+//
+// Before creating a fixity WorkItem, the caller should ensure that the
+// object and file have no pending fixity checks. See FixityCheckPending().
+func NewFixityCheckItem(obj *IntellectualObject, gf *GenericFile, user *User) (*WorkItem, error) {
+	if obj == nil || user == nil {
+		return nil, common.ErrInvalidParam
+	}
+	fixityItem, err := NewItemFromLastSuccessfulIngest(obj.ID)
+	if err != nil {
+		return nil, err
+	}
+	if gf != nil {
+		fixityItem.GenericFileID = gf.ID
+		fixityItem.Size = gf.Size
+	}
+	fixityItem.Action = constants.ActionFixityCheck
+	fixityItem.User = user.Email
+	err = fixityItem.Save()
+	return fixityItem, err
+}
+
+// FixityCheckPending returns true if there's a pending fixity check
+// for the specified file. That includes checks on the file itself and
+// checks on the whole object the file belongs to.
+func FixityCheckPending(gf *GenericFile) (bool, error) {
+	items, err := pendingFixityItems(gf.IntellectualObjectID, gf.ID)
+	return len(items) > 0, err
+}
+
+// FixityCheckPendingForObject returns true if there's a pending fixity
+// check for the whole object. Pending checks on individual files in the
+// object don't count.
+func FixityCheckPendingForObject(objID int64) (bool, error) {
+	items, err := pendingFixityItems(objID, 0)
+	return len(items) > 0, err
+}
+
+// FixityCheckableFileIDs returns the IDs of the object's active files
+// that can be queued for a fixity check. This excludes files stored only
+// in Glacier, and files that already have a pending file-level check.
+func FixityCheckableFileIDs(objID int64) ([]int64, error) {
+	var ids []int64
+	completed := common.InterfaceList(constants.CompletedStatusValues)
+	pendingFiles := common.Context().DB.Model((*WorkItem)(nil)).
+		Column("generic_file_id").
+		Where("intellectual_object_id = ?", objID).
+		Where("action = ?", constants.ActionFixityCheck).
+		Where("generic_file_id is not null").
+		Where("status not in (?)", pg.In(completed))
+	err := common.Context().DB.Model((*GenericFile)(nil)).
+		Column("id").
+		Where("intellectual_object_id = ?", objID).
+		Where("state = ?", constants.StateActive).
+		Where("storage_option not in (?)", pg.In(constants.GlacierOnlyOptions)).
+		Where("id not in (?)", pendingFiles).
+		Order("id asc").
+		Select(&ids)
+	return ids, err
+}
+
+// pendingFixityItems returns pending fixity WorkItems for the object
+// as a whole and, if fileID is non-zero, for that specific file.
+func pendingFixityItems(objID, fileID int64) ([]*WorkItem, error) {
+	items, err := WorkItemSelect(pendingFixityQuery(objID).IsNull("generic_file_id"))
+	if err != nil || fileID == 0 {
+		return items, err
+	}
+	fileItems, err := WorkItemSelect(pendingFixityQuery(objID).Where("generic_file_id", "=", fileID))
+	return append(items, fileItems...), err
+}
+
+func pendingFixityQuery(objID int64) *Query {
+	completed := common.InterfaceList(constants.CompletedStatusValues)
+	return NewQuery().
+		Where("intellectual_object_id", "=", objID).
+		Where("action", "=", constants.ActionFixityCheck).
+		WhereNotIn("status", completed...).
+		OrderBy("id", "asc")
+}
+
+// CompleteFixityCheckItems marks pending fixity WorkItems as complete
+// once the fixity checker has checked every file they cover. We call
+// this each time the fixity checker records a fixity check event for
+// file gf. Param outcome is the event's outcome.
+//
+// A file-level item is complete when the file has been checked since
+// the item was queued. An object-level item is complete when all of the
+// object's active files have been checked since the item was queued.
+// Files stored only in Glacier don't count, because we never queue
+// them for fixity checks.
+//
+// If the check failed, every pending item that covers the file fails
+// right away, including object-level items whose other files haven't
+// been checked yet. The failed fixity alert has the details. This
+// returns the items it completed.
+func CompleteFixityCheckItems(gf *GenericFile, outcome string) ([]*WorkItem, error) {
+	items, err := pendingFixityItems(gf.IntellectualObjectID, gf.ID)
+	if err != nil {
+		return nil, err
+	}
+	failed := outcome != constants.OutcomeSuccess
+	completed := make([]*WorkItem, 0)
+	for _, item := range items {
+		if item.QueuedAt.IsZero() || gf.LastFixityCheck.Before(item.QueuedAt) {
+			continue
+		}
+		if item.GenericFileID == 0 && !failed {
+			remaining, err := filesAwaitingFixity(gf.IntellectualObjectID, item.QueuedAt)
+			if err != nil {
+				return completed, err
+			}
+			if remaining > 0 {
+				continue
+			}
+		}
+		item.Stage = constants.StageResolve
+		if failed {
+			item.Status = constants.StatusFailed
+			item.Note = fmt.Sprintf("Fixity check failed for %s", gf.Identifier)
+			item.Outcome = "Fixity check failed"
+		} else {
+			item.Status = constants.StatusSuccess
+			item.Note = "Fixity check completed"
+			item.Outcome = "Fixity check completed"
+		}
+		item.DateProcessed = time.Now().UTC()
+		err = item.Save()
+		if err != nil {
+			return completed, err
+		}
+		completed = append(completed, item)
+	}
+	return completed, nil
+}
+
+// filesAwaitingFixity returns the number of the object's active,
+// non-Glacier files that have not been checked since checkedSince.
+func filesAwaitingFixity(objID int64, checkedSince time.Time) (int, error) {
+	glacierOnly := common.InterfaceList(constants.GlacierOnlyOptions)
+	query := NewQuery().
+		Where("intellectual_object_id", "=", objID).
+		Where("state", "=", constants.StateActive).
+		Where("last_fixity_check", "<", checkedSince).
+		WhereNotIn("storage_option", glacierOnly...)
+	return query.Count(&GenericFile{})
+}
@@ -0,0 +1,188 @@
+package pgmodels_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFixityCheckItem(t *testing.T) {
+	db.LoadFixtures()
+
+	// Object id #4 from fixtures, institution2.edu/chocolate,
+	// has a successful ingest WorkItem and three active files.
+	obj, err := pgmodels.IntellectualObjectByID(4)
+	require.Nil(t, err)
+	file, err := pgmodels.GenericFileByID(11)
+	require.Nil(t, err)
+	user := &pgmodels.User{
+		Email: "unittest@example.com",
+	}
+
+	item, err := pgmodels.NewFixityCheckItem(obj, file, user)
+	require.Nil(t, err)
+	require.NotNil(t, item)
+	assert.True(t, item.ID > 0)
+	assert.Equal(t, obj.ID, item.IntellectualObjectID)
+	assert.Equal(t, file.ID, item.GenericFileID)
+	assert.Equal(t, file.Size, item.Size)
+	assert.Equal(t, constants.ActionFixityCheck, item.Action)
+	assert.Equal(t, user.Email, item.User)
+	assert.Equal(t, constants.StageRequested, item.Stage)
+	assert.Equal(t, constants.StatusPending, item.Status)
+
+	item, err = pgmodels.NewFixityCheckItem(obj, nil, user)
+	require.Nil(t, err)
+	require.NotNil(t, item)
+	assert.EqualValues(t, 0, item.GenericFileID)
+
+	_, err = pgmodels.NewFixityCheckItem(nil, file, user)
+	assert.NotNil(t, err)
+}
+
+func TestFixityCheckPending(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+
+	obj, err := pgmodels.IntellectualObjectByID(4)
+	require.Nil(t, err)
+	file11, err := pgmodels.GenericFileByID(11)
+	require.Nil(t, err)
+	file12, err := pgmodels.GenericFileByID(12)
+	require.Nil(t, err)
+	user := &pgmodels.User{
+		Email: "unittest@example.com",
+	}
+
+	pending, err := pgmodels.FixityCheckPending(file11)
+	require.Nil(t, err)
+	assert.False(t, pending)
+	ids, err := pgmodels.FixityCheckableFileIDs(obj.ID)
+	require.Nil(t, err)
+	assert.Equal(t, []int64{11, 12, 13}, ids)
+
+	// A file-level check is pending only for that file,
+	// and not for the object as a whole.
+	_, err = pgmodels.NewFixityCheckItem(obj, file11, user)
+	require.Nil(t, err)
+	pending, err = pgmodels.FixityCheckPending(file11)
+	require.Nil(t, err)
+	assert.True(t, pending)
+	pending, err = pgmodels.FixityCheckPending(file12)
+	require.Nil(t, err)
+	assert.False(t, pending)
+	pending, err = pgmodels.FixityCheckPendingForObject(obj.ID)
+	require.Nil(t, err)
+	assert.False(t, pending)
+	ids, err = pgmodels.FixityCheckableFileIDs(obj.ID)
+	require.Nil(t, err)
+	assert.Equal(t, []int64{12, 13}, ids)
+
+	// An object-level check is pending for every file.
+	_, err = pgmodels.NewFixityCheckItem(obj, nil, user)
+	require.Nil(t, err)
+	pending, err = pgmodels.FixityCheckPending(file12)
+	require.Nil(t, err)
+	assert.True(t, pending)
+	pending, err = pgmodels.FixityCheckPendingForObject(obj.ID)
+	require.Nil(t, err)
+	assert.True(t, pending)
+}
+
+func TestCompleteFixityCheckItems(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+
+	obj, err := pgmodels.IntellectualObjectByID(4)
+	require.Nil(t, err)
+	user := &pgmodels.User{
+		Email: "unittest@example.com",
+	}
+	files := make([]*pgmodels.GenericFile, 3)
+	for i, id := range []int64{11, 12, 13} {
+		files[i], err = pgmodels.GenericFileByID(id)
+		require.Nil(t, err)
+	}
+
+	queuedAt := time.Now().UTC().Add(-1 * time.Hour)
+	fileItem, err := pgmodels.NewFixityCheckItem(obj, files[0], user)
+	require.Nil(t, err)
+	fileItem.QueuedAt = queuedAt
+	require.Nil(t, fileItem.Save())
+	objItem, err := pgmodels.NewFixityCheckItem(obj, nil, user)
+	require.Nil(t, err)
+	objItem.QueuedAt = queuedAt
+	require.Nil(t, objItem.Save())
+
+	// Fixture files were last checked long before the items were
+	// queued, so nothing is complete yet.
+	completed, err := pgmodels.CompleteFixityCheckItems(files[0], constants.OutcomeSuccess)
+	require.Nil(t, err)
+	assert.Empty(t, completed)
+
+	// Checking the first file completes the file-level item,
+	// but not the object-level item.
+	files[0].LastFixityCheck = time.Now().UTC()
+	require.Nil(t, files[0].Save())
+	completed, err = pgmodels.CompleteFixityCheckItems(files[0], constants.OutcomeSuccess)
+	require.Nil(t, err)
+	require.Len(t, completed, 1)
+	assert.Equal(t, fileItem.ID, completed[0].ID)
+	assert.Equal(t, constants.StatusSuccess, completed[0].Status)
+
+	// The object-level item completes when the last file is checked.
+	files[1].LastFixityCheck = time.Now().UTC()
+	require.Nil(t, files[1].Save())
+	completed, err = pgmodels.CompleteFixityCheckItems(files[1], constants.OutcomeSuccess)
+	require.Nil(t, err)
+	assert.Empty(t, completed)
+
+	files[2].LastFixityCheck = time.Now().UTC()
+	require.Nil(t, files[2].Save())
+	completed, err = pgmodels.CompleteFixityCheckItems(files[2], constants.OutcomeSuccess)
+	require.Nil(t, err)
+	require.Len(t, completed, 1)
+	assert.Equal(t, objItem.ID, completed[0].ID)
+
+	item, err := pgmodels.WorkItemByID(objItem.ID)
+	require.Nil(t, err)
+	assert.Equal(t, constants.StatusSuccess, item.Status)
+	assert.Equal(t, constants.StageResolve, item.Stage)
+}
+
+func TestCompleteFixityCheckItemsFailure(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+
+	obj, err := pgmodels.IntellectualObjectByID(4)
+	require.Nil(t, err)
+	user := &pgmodels.User{
+		Email: "unittest@example.com",
+	}
+	gf, err := pgmodels.GenericFileByID(11)
+	require.Nil(t, err)
+
+	objItem, err := pgmodels.NewFixityCheckItem(obj, nil, user)
+	require.Nil(t, err)
+	objItem.QueuedAt = time.Now().UTC().Add(-1 * time.Hour)
+	require.Nil(t, objItem.Save())
+
+	// A failed check on one file fails the object-level item,
+	// even though the object's other files haven't been checked.
+	gf.LastFixityCheck = time.Now().UTC()
+	require.Nil(t, gf.Save())
+	completed, err := pgmodels.CompleteFixityCheckItems(gf, constants.OutcomeFailure)
+	require.Nil(t, err)
+	require.Len(t, completed, 1)
+
+	item, err := pgmodels.WorkItemByID(objItem.ID)
+	require.Nil(t, err)
+	assert.Equal(t, constants.StatusFailed, item.Status)
+	assert.Equal(t, constants.StageResolve, item.Stage)
+	assert.Contains(t, item.Note, gf.Identifier)
+}
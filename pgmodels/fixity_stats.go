@@ -0,0 +1,91 @@
+package pgmodels
+
+import (
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+)
+
+var FixityStatsFilters = []string{
+	"institution_id",
+	"storage_option",
+}
+
+// FixityStats summarizes how recently an institution's active files
+// in one storage option were checked for fixity. Checked30, Checked60
+// and Checked90 count files checked in the last 30, 60 and 90 days.
+// Overdue counts files not checked in more than the configured number
+// of days. See FIXITY_OVERDUE_DAYS in the .env files.
+type FixityStats struct {
+	InstitutionID   int64     `json:"institution_id"`
+	InstitutionName string    `json:"institution_name"`
+	StorageOption   string    `json:"storage_option"`
+	FileCount       int64     `json:"file_count"`
+	Checked30       int64     `json:"checked_30"`
+	Checked60       int64     `json:"checked_60"`
+	Checked90       int64     `json:"checked_90"`
+	Overdue         int64     `json:"overdue"`
+	OldestCheck     time.Time `json:"oldest_check"`
+}
+
+var fixityStatsQuery = `select
+	gf.institution_id,
+	i."name" as institution_name,
+	gf.storage_option,
+	count(*) as file_count,
+	count(*) filter (where gf.last_fixity_check >= ?) as checked_30,
+	count(*) filter (where gf.last_fixity_check >= ?) as checked_60,
+	count(*) filter (where gf.last_fixity_check >= ?) as checked_90,
+	count(*) filter (where gf.last_fixity_check < ?) as overdue,
+	min(gf.last_fixity_check) as oldest_check
+	from generic_files gf
+	inner join institutions i on i.id = gf.institution_id
+	where gf.state = ?
+	and (? = 0 or gf.institution_id = ?)
+	and (? = '' or gf.storage_option = ?)
+	group by gf.institution_id, i."name", gf.storage_option
+	order by i."name", gf.storage_option`
+
+// FixityStatsSelect returns fixity stats for active files, grouped by
+// institution and storage option. Param institutionID may be zero to
+// include all institutions, and storageOption may be empty to include
+// all storage options.
+func FixityStatsSelect(institutionID int64, storageOption string) ([]*FixityStats, error) {
+	var stats []*FixityStats
+	now := time.Now().UTC()
+	_, err := common.Context().DB.Query(&stats, fixityStatsQuery,
+		now.AddDate(0, 0, -30),
+		now.AddDate(0, 0, -60),
+		now.AddDate(0, 0, -90),
+		FixityOverdueCutoff(),
+		constants.StateActive,
+		institutionID, institutionID,
+		storageOption, storageOption)
+	return stats, err
+}
+
+// FixityOverdueCutoff returns the time before which a file's last
+// fixity check is overdue.
+func FixityOverdueCutoff() time.Time {
+	return time.Now().UTC().AddDate(0, 0, -common.Context().Config.Fixity.OverdueDays)
+}
+
+// FixityOverdueQuery returns a query for active files whose fixity
+// checks are overdue, oldest check first. Param institutionID may be
+// zero to include all institutions, and storageOption may be empty to
+// include all storage options. Run this query against GenericFileView.
+func FixityOverdueQuery(institutionID int64, storageOption string) *Query {
+	query := NewQuery().
+		Where("state", "=", constants.StateActive).
+		Where("last_fixity_check", "<", FixityOverdueCutoff()).
+		OrderBy("last_fixity_check", "asc").
+		OrderBy("id", "asc")
+	if institutionID > 0 {
+		query.Where("institution_id", "=", institutionID)
+	}
+	if storageOption != "" {
+		query.Where("storage_option", "=", storageOption)
+	}
+	return query
+}
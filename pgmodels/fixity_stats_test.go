@@ -0,0 +1,92 @@
+package pgmodels_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFixityStatsSelect(t *testing.T) {
+	db.LoadFixtures()
+	defer db.ForceFixtureReload()
+	setUpFixityAges(t)
+
+	stats, err := pgmodels.FixityStatsSelect(InstOne, constants.StorageOptionStandard)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(stats))
+	item := stats[0]
+	assert.EqualValues(t, InstOne, item.InstitutionID)
+	assert.Equal(t, "Institution One", item.InstitutionName)
+	assert.Equal(t, constants.StorageOptionStandard, item.StorageOption)
+	require.True(t, item.FileCount > 3)
+	assert.EqualValues(t, 1, item.Checked30)
+	assert.EqualValues(t, 2, item.Checked60)
+	assert.EqualValues(t, 3, item.Checked90)
+	assert.Equal(t, item.FileCount-3, item.Overdue)
+	assert.Equal(t, 2000, item.OldestCheck.Year())
+
+	// All institutions and storage options
+	stats, err = pgmodels.FixityStatsSelect(0, "")
+	require.Nil(t, err)
+	institutions := make(map[int64]bool)
+	for _, item := range stats {
+		institutions[item.InstitutionID] = true
+		assert.True(t, item.Checked30 <= item.Checked60)
+		assert.True(t, item.Checked60 <= item.Checked90)
+		assert.True(t, item.Overdue <= item.FileCount)
+	}
+	assert.True(t, len(institutions) > 1)
+}
+
+func TestFixityOverdueQuery(t *testing.T) {
+	db.LoadFixtures()
+	defer db.ForceFixtureReload()
+	setUpFixityAges(t)
+
+	stats, err := pgmodels.FixityStatsSelect(InstOne, constants.StorageOptionStandard)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(stats))
+
+	query := pgmodels.FixityOverdueQuery(InstOne, constants.StorageOptionStandard)
+	var files []*pgmodels.GenericFileView
+	require.Nil(t, query.Select(&files))
+	assert.EqualValues(t, stats[0].Overdue, len(files))
+	cutoff := pgmodels.FixityOverdueCutoff()
+	for i, gf := range files {
+		assert.Equal(t, constants.StateActive, gf.State)
+		assert.True(t, gf.LastFixityCheck.Before(cutoff))
+		if i > 0 {
+			assert.False(t, gf.LastFixityCheck.Before(files[i-1].LastFixityCheck))
+		}
+	}
+}
+
+// setUpFixityAges sets all of Institution One's active Standard files
+// to a very old fixity check date, then sets three of them to checks
+// 10, 45 and 75 days ago.
+func setUpFixityAges(t *testing.T) {
+	_, err := common.Context().DB.Exec(`update generic_files set last_fixity_check = '2000-01-01'
+		where institution_id = ? and storage_option = ? and state = ?`,
+		InstOne, constants.StorageOptionStandard, constants.StateActive)
+	require.Nil(t, err)
+	query := pgmodels.NewQuery().
+		Where("institution_id", "=", InstOne).
+		Where("storage_option", "=", constants.StorageOptionStandard).
+		Where("state", "=", constants.StateActive).
+		OrderBy("id", "asc").
+		Limit(3)
+	files, err := pgmodels.GenericFileSelect(query)
+	require.Nil(t, err)
+	require.Equal(t, 3, len(files))
+	for i, daysAgo := range []int{10, 45, 75} {
+		files[i].LastFixityCheck = time.Now().UTC().AddDate(0, 0, -daysAgo)
+		_, err = common.Context().DB.Model(files[i]).Column("last_fixity_check").WherePK().Update()
+		require.Nil(t, err)
+	}
+}
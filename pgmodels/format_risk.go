@@ -0,0 +1,225 @@
+package pgmodels
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/go-pg/pg/v10"
+	"github.com/stretchr/stew/slice"
+)
+
+const (
+	ErrFormatRiskMimeType  = "Please enter a valid MIME type, such as application/pdf."
+	ErrFormatRiskLevel     = "Please choose a risk level."
+	ErrFormatRiskDuplicate = "This MIME type is already in the format registry."
+)
+
+var FormatRiskFilters = []string{
+	"mime_type__starts_with",
+	"risk_level",
+}
+
+var FormatRiskStatsFilters = []string{
+	"institution_id",
+}
+
+var FormatRiskObjectFilters = []string{
+	"identifier__starts_with",
+	"institution_id",
+	"risk_level",
+}
+
+var mimeTypeRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9!#$&^_.+-]*/[a-z0-9][a-z0-9!#$&^_.+-]*$`)
+
+// FormatRisk is an entry in the format registry. It assigns a file
+// format, identified by MIME type, one of the preservation risk levels
+// in constants.FormatRiskLevels. The format risk report uses these to
+// show how much of each institution's content may need normalization
+// or migration. Formats not in the registry are reported as
+// constants.FormatRiskUnknown.
+//
+// MimeType is always lower case, to match generic_files.file_format.
+type FormatRisk struct {
+	TimestampModel
+	MimeType  string `json:"mime_type"`
+	RiskLevel string `json:"risk_level"`
+	Notes     string `json:"notes"`
+}
+
+// FormatRiskObjectView summarizes the active files of one object at
+// one risk level. FileFormats is a comma-separated list of the
+// object's formats at that level. This is the drill-down from the
+// format risk report.
+//
+// These rows come from the materialized view format_risk_object_stats,
+// which the update_format_risk_stats job refreshes every hour. UpdatedAt
+// is the time of the last refresh.
+type FormatRiskObjectView struct {
+	tableName            struct{}  `pg:"format_risk_object_stats"`
+	IntellectualObjectID int64     `json:"intellectual_object_id"`
+	Identifier           string    `json:"identifier"`
+	InstitutionID        int64     `json:"institution_id"`
+	InstitutionName      string    `json:"institution_name"`
+	RiskLevel            string    `json:"risk_level"`
+	FileFormats          string    `json:"file_formats"`
+	FileCount            int64     `json:"file_count"`
+	TotalBytes           int64     `json:"total_bytes"`
+	UpdatedAt            time.Time `json:"updated_at"`
+}
+
+// FormatRiskByID returns the format registry entry with the specified
+// id. Returns pg.ErrNoRows if there is no match.
+func FormatRiskByID(id int64) (*FormatRisk, error) {
+	query := NewQuery().Where("id", "=", id)
+	return FormatRiskGet(query)
+}
+
+// FormatRiskGet returns the first format registry entry matching the
+// query.
+func FormatRiskGet(query *Query) (*FormatRisk, error) {
+	var formatRisk FormatRisk
+	err := query.Select(&formatRisk)
+	return &formatRisk, err
+}
+
+// FormatRiskSelect returns all format registry entries matching the
+// query.
+func FormatRiskSelect(query *Query) ([]*FormatRisk, error) {
+	var formatRisks []*FormatRisk
+	err := query.Select(&formatRisks)
+	return formatRisks, err
+}
+
+// FormatRiskLevels returns the risk level of each format in the
+// registry, keyed by MIME type.
+func FormatRiskLevels() (map[string]string, error) {
+	formatRisks, err := FormatRiskSelect(NewQuery())
+	if err != nil {
+		return nil, err
+	}
+	levels := make(map[string]string, len(formatRisks))
+	for _, formatRisk := range formatRisks {
+		levels[formatRisk.MimeType] = formatRisk.RiskLevel
+	}
+	return levels, nil
+}
+
+// Save saves this format registry entry to the database. This will
+// peform an insert if FormatRisk.ID is zero. Otherwise, it updates.
+func (formatRisk *FormatRisk) Save() error {
+	formatRisk.MimeType = strings.ToLower(strings.TrimSpace(formatRisk.MimeType))
+	formatRisk.SetTimestamps()
+	err := formatRisk.Validate()
+	if err != nil {
+		return err
+	}
+	if formatRisk.ID == int64(0) {
+		return insert(formatRisk)
+	}
+	return update(formatRisk)
+}
+
+// Delete removes this format from the registry. The report will show
+// files in this format as unknown.
+func (formatRisk *FormatRisk) Delete() error {
+	_, err := common.Context().DB.Model(formatRisk).WherePK().Delete()
+	return err
+}
+
+// Validate validates the model. This is called automatically on insert
+// and update.
+func (formatRisk *FormatRisk) Validate() *common.ValidationError {
+	errors := make(map[string]string)
+	if !mimeTypeRegex.MatchString(formatRisk.MimeType) {
+		errors["MimeType"] = ErrFormatRiskMimeType
+	} else {
+		exists, err := common.Context().DB.Model((*FormatRisk)(nil)).
+			Where("mime_type = ?", formatRisk.MimeType).
+			Where("id != ?", formatRisk.ID).
+			Exists()
+		if err != nil || exists {
+			errors["MimeType"] = ErrFormatRiskDuplicate
+		}
+	}
+	if !slice.Contains(constants.FormatRiskLevels, formatRisk.RiskLevel) {
+		errors["RiskLevel"] = ErrFormatRiskLevel
+	}
+	if len(errors) > 0 {
+		return &common.ValidationError{Errors: errors}
+	}
+	return nil
+}
+
+// FormatRiskStats is the number and total size of an institution's
+// active files at one risk level.
+type FormatRiskStats struct {
+	InstitutionID   int64   `json:"institution_id"`
+	InstitutionName string  `json:"institution_name"`
+	RiskLevel       string  `json:"risk_level"`
+	FileCount       int64   `json:"file_count"`
+	TotalBytes      int64   `json:"total_bytes"`
+	TotalGB         float64 `json:"total_gb" pg:"total_gb"`
+}
+
+var formatRiskStatsQuery = `select
+	institution_id,
+	institution_name,
+	risk_level,
+	sum(file_count) as file_count,
+	sum(total_bytes) as total_bytes,
+	(sum(total_bytes) / 1073741824) as total_gb
+	from format_risk_object_stats
+	where (? = 0 or institution_id = ?)
+	group by institution_id, institution_name, risk_level
+	order by institution_name, array_position(?::varchar[], risk_level)`
+
+// FormatRiskStatsSelect returns the number and size of active files
+// at each risk level, for each institution. Param institutionID may be
+// zero to include all institutions. Within each institution, stats are
+// ordered from lowest risk to highest, with unknown formats last.
+func FormatRiskStatsSelect(institutionID int64) ([]*FormatRiskStats, error) {
+	var stats []*FormatRiskStats
+	_, err := common.Context().DB.Query(&stats, formatRiskStatsQuery,
+		institutionID, institutionID,
+		pg.Array(constants.FormatRiskReportLevels))
+	return stats, err
+}
+
+// RefreshFormatRiskStats refreshes the materialized view behind
+// FormatRiskStatsSelect and the FormatRiskObjectView drill-down. The
+// update_format_risk_stats job calls this every hour. Refreshing
+// concurrently lets the reports keep reading the old rows until the
+// new ones are ready.
+func RefreshFormatRiskStats() error {
+	_, err := common.Context().DB.Exec("refresh materialized view concurrently format_risk_object_stats")
+	return err
+}
+
+// FormatRiskStatsByFormat returns the deposit format stats for an
+// institution's active files, with each format's risk level. See
+// DepositFormatStatsSelect. This omits the Total row.
+func FormatRiskStatsByFormat(institutionID int64) ([]*DepositFormatStats, error) {
+	stats, err := DepositFormatStatsSelect(institutionID, 0)
+	if err != nil {
+		return nil, err
+	}
+	levels, err := FormatRiskLevels()
+	if err != nil {
+		return nil, err
+	}
+	formatStats := make([]*DepositFormatStats, 0, len(stats))
+	for _, s := range stats {
+		if s.FileFormat == "Total" {
+			continue
+		}
+		s.RiskLevel = levels[s.FileFormat]
+		if s.RiskLevel == "" {
+			s.RiskLevel = constants.FormatRiskUnknown
+		}
+		formatStats = append(formatStats, s)
+	}
+	return formatStats, nil
+}
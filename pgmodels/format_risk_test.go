@@ -0,0 +1,154 @@
+package pgmodels_test
+
+import (
+	"testing"
+
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getFormatRisk(t *testing.T, mimeType string) *pgmodels.FormatRisk {
+	formatRisk, err := pgmodels.FormatRiskGet(pgmodels.NewQuery().Where("mime_type", "=", mimeType))
+	require.Nil(t, err)
+	require.NotNil(t, formatRisk)
+	return formatRisk
+}
+
+func TestFormatRiskByID(t *testing.T) {
+	db.LoadFixtures()
+	sgml := getFormatRisk(t, "text/sgml")
+	formatRisk, err := pgmodels.FormatRiskByID(sgml.ID)
+	require.Nil(t, err)
+	assert.Equal(t, "text/sgml", formatRisk.MimeType)
+	assert.Equal(t, constants.FormatRiskObsolete, formatRisk.RiskLevel)
+	assert.Equal(t, "SGML", formatRisk.Notes)
+}
+
+func TestFormatRiskLevels(t *testing.T) {
+	db.LoadFixtures()
+	levels, err := pgmodels.FormatRiskLevels()
+	require.Nil(t, err)
+	assert.Equal(t, constants.FormatRiskOpen, levels["application/pdf"])
+	assert.Equal(t, constants.FormatRiskProprietary, levels["video/x-msvideo"])
+	assert.Equal(t, constants.FormatRiskObsolete, levels["text/sgml"])
+	assert.Empty(t, levels["application/binary"])
+}
+
+func TestFormatRiskSaveAndDelete(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+
+	formatRisk := &pgmodels.FormatRisk{
+		MimeType:  " Application/Binary ",
+		RiskLevel: constants.FormatRiskObsolete,
+		Notes:     "Unidentified binary",
+	}
+	require.Nil(t, formatRisk.Save())
+	assert.True(t, formatRisk.ID > 0)
+	assert.Equal(t, "application/binary", formatRisk.MimeType)
+
+	formatRisk.RiskLevel = constants.FormatRiskProprietary
+	require.Nil(t, formatRisk.Save())
+	saved, err := pgmodels.FormatRiskByID(formatRisk.ID)
+	require.Nil(t, err)
+	assert.Equal(t, constants.FormatRiskProprietary, saved.RiskLevel)
+
+	require.Nil(t, formatRisk.Delete())
+	_, err = pgmodels.FormatRiskByID(formatRisk.ID)
+	assert.True(t, pgmodels.IsNoRowError(err))
+}
+
+func TestFormatRiskValidate(t *testing.T) {
+	db.LoadFixtures()
+	formatRisk := &pgmodels.FormatRisk{
+		MimeType:  "not a mime type",
+		RiskLevel: "dangerous",
+	}
+	err := formatRisk.Validate()
+	require.NotNil(t, err)
+	assert.Equal(t, pgmodels.ErrFormatRiskMimeType, err.Errors["MimeType"])
+	assert.Equal(t, pgmodels.ErrFormatRiskLevel, err.Errors["RiskLevel"])
+
+	formatRisk.MimeType = "application/pdf"
+	formatRisk.RiskLevel = constants.FormatRiskOpen
+	err = formatRisk.Validate()
+	require.NotNil(t, err)
+	assert.Equal(t, pgmodels.ErrFormatRiskDuplicate, err.Errors["MimeType"])
+
+	// An existing entry isn't a duplicate of itself.
+	pdf := getFormatRisk(t, "application/pdf")
+	assert.Nil(t, pdf.Validate())
+
+	formatRisk.MimeType = "application/x-new-format"
+	assert.Nil(t, formatRisk.Validate())
+}
+
+func TestFormatRiskStatsSelect(t *testing.T) {
+	db.LoadFixtures()
+	stats, err := pgmodels.FormatRiskStatsSelect(InstOne)
+	require.Nil(t, err)
+	require.Equal(t, 4, len(stats))
+
+	// Lowest risk first, then unknown.
+	expected := []struct {
+		level string
+		files int64
+		bytes int64
+	}{
+		{constants.FormatRiskOpen, 12, 255852985000},
+		{constants.FormatRiskProprietary, 2, 410360935000},
+		{constants.FormatRiskObsolete, 2, 620275000},
+		{constants.FormatRiskUnknown, 1, 11169445000},
+	}
+	for i, item := range stats {
+		assert.EqualValues(t, InstOne, item.InstitutionID)
+		assert.Equal(t, "Institution One", item.InstitutionName)
+		assert.Equal(t, expected[i].level, item.RiskLevel)
+		assert.Equal(t, expected[i].files, item.FileCount)
+		assert.Equal(t, expected[i].bytes, item.TotalBytes)
+	}
+
+	// All institutions
+	stats, err = pgmodels.FormatRiskStatsSelect(0)
+	require.Nil(t, err)
+	institutions := make(map[int64]bool)
+	for _, item := range stats {
+		institutions[item.InstitutionID] = true
+	}
+	assert.True(t, len(institutions) > 1)
+}
+
+func TestFormatRiskStatsByFormat(t *testing.T) {
+	db.LoadFixtures()
+	stats, err := pgmodels.FormatRiskStatsByFormat(InstOne)
+	require.Nil(t, err)
+	levels := make(map[string]string)
+	for _, item := range stats {
+		assert.NotEqual(t, "Total", item.FileFormat)
+		levels[item.FileFormat] = item.RiskLevel
+	}
+	assert.Equal(t, constants.FormatRiskOpen, levels["application/pdf"])
+	assert.Equal(t, constants.FormatRiskObsolete, levels["text/sgml"])
+	assert.Equal(t, constants.FormatRiskUnknown, levels["application/binary"])
+}
+
+func TestFormatRiskObjectView(t *testing.T) {
+	db.LoadFixtures()
+	query := pgmodels.NewQuery().
+		Where("institution_id", "=", InstOne).
+		Where("risk_level", "=", constants.FormatRiskProprietary).
+		OrderBy("total_bytes", "desc")
+	var objects []*pgmodels.FormatRiskObjectView
+	require.Nil(t, query.Select(&objects))
+	require.Equal(t, 2, len(objects))
+	assert.EqualValues(t, 9, objects[0].IntellectualObjectID)
+	assert.Equal(t, "institution1.edu/gl-dp-oh", objects[0].Identifier)
+	assert.Equal(t, "video/x-msvideo", objects[0].FileFormats)
+	assert.EqualValues(t, 1, objects[0].FileCount)
+	assert.EqualValues(t, 396117060000, objects[0].TotalBytes)
+	assert.EqualValues(t, 10, objects[1].IntellectualObjectID)
+	assert.Equal(t, "application/vnd.amazon.ebook", objects[1].FileFormats)
+}
@@ -474,8 +474,7 @@ func (gf *GenericFile) ActiveDeletionWorkItem() (*WorkItem, error) {
 }
 
 func (gf *GenericFile) DeletionRequest(workItemID int64) (*DeletionRequestView, error) {
-	query := NewQuery().Where("work_item_id", "=", workItemID)
-	return DeletionRequestViewGet(query)
+	return DeletionRequestViewForWorkItem(workItemID)
 }
 
 func (gf *GenericFile) AssertDeletionPreconditions() error {
@@ -0,0 +1,285 @@
+package pgmodels
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/crewjam/saml"
+	"github.com/stretchr/stew/slice"
+)
+
+const (
+	ErrIdPInstitutionID  = "InstitutionID is required."
+	ErrIdPProtocol       = "Choose OpenID Connect or SAML."
+	ErrIdPIssuerURL      = "Issuer URL must be an https URL."
+	ErrIdPClientID       = "Client ID is required for OpenID Connect."
+	ErrIdPClientSecret   = "Client secret is required for OpenID Connect."
+	ErrIdPMetadataXML    = "Please paste your identity provider's SAML metadata. It must include a login URL for the HTTP-Redirect binding."
+	ErrIdPEmailAttribute = "Email attribute is required."
+	ErrIdPRequired       = "Single sign-on can be required only if it's enabled."
+)
+
+// IdentityProvider is an institution's SAML 2.0 or OpenID Connect
+// identity provider (IdP). When it's enabled, the institution's users
+// can sign in through their campus login page instead of with a
+// Registry password. If it's also required, they can't use a Registry
+// password at all.
+//
+// The IdP authenticates users, but doesn't create them. We match each
+// user the IdP signs in to an existing Registry account at the same
+// institution by email address. If RoleAttribute is set, we also set
+// the user's role from the IdP's attributes. See RoleFor.
+//
+// For OIDC, we discover the provider's endpoints from IssuerURL. The
+// client secret is encrypted with SSO_ENCRYPTION_KEY. For SAML,
+// MetadataXML describes the IdP's login URL and signing certificate.
+type IdentityProvider struct {
+	tableName struct{} `pg:"identity_providers"`
+	TimestampModel
+	InstitutionID         int64        `json:"institution_id"`
+	Protocol              string       `json:"protocol"`
+	Enabled               bool         `json:"enabled" pg:",use_zero"`
+	Required              bool         `json:"required" pg:",use_zero"`
+	IssuerURL             string       `json:"issuer_url"`
+	ClientID              string       `json:"client_id"`
+	EncryptedClientSecret string       `json:"-"`
+	MetadataXML           string       `json:"metadata_xml" pg:"metadata_xml"`
+	EmailAttribute        string       `json:"email_attribute"`
+	RoleAttribute         string       `json:"role_attribute"`
+	AdminRoleValues       []string     `json:"admin_role_values" pg:"admin_role_values,array"`
+	Institution           *Institution `json:"-" pg:"rel:has-one"`
+}
+
+// NewIdentityProvider returns a new, unsaved, disabled OIDC identity
+// provider for the specified institution.
+func NewIdentityProvider(institutionID int64) *IdentityProvider {
+	return &IdentityProvider{
+		InstitutionID:   institutionID,
+		Protocol:        constants.SSOProtocolOIDC,
+		EmailAttribute:  "email",
+		AdminRoleValues: make([]string, 0),
+	}
+}
+
+// IdentityProviderByID returns the identity provider with the specified
+// id. Returns pg.ErrNoRows if there is no match.
+func IdentityProviderByID(id int64) (*IdentityProvider, error) {
+	query := NewQuery().Where("id", "=", id)
+	return IdentityProviderGet(query)
+}
+
+// IdentityProviderForInstitution returns the specified institution's
+// identity provider. Returns pg.ErrNoRows if the institution has none.
+func IdentityProviderForInstitution(institutionID int64) (*IdentityProvider, error) {
+	query := NewQuery().Where("institution_id", "=", institutionID)
+	return IdentityProviderGet(query)
+}
+
+// IdentityProviderForEmail returns the enabled identity provider of the
+// active institution whose identifier matches the domain of the email
+// address, or the nearest parent domain that matches an institution.
+// For example, for jane@med.example.edu, we'd check med.example.edu and
+// then example.edu.
+//
+// This returns common.ErrSSONotConfigured if there's no match. We look
+// up the IdP by domain rather than by user so this doesn't reveal
+// whether an account exists.
+func IdentityProviderForEmail(email string) (*IdentityProvider, error) {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return nil, common.ErrSSONotConfigured
+	}
+	domain := strings.ToLower(strings.TrimSpace(email[at+1:]))
+	for strings.Contains(domain, ".") {
+		inst, err := InstitutionByIdentifier(domain)
+		if err == nil {
+			if inst.State != constants.StateActive {
+				return nil, common.ErrSSONotConfigured
+			}
+			idp, err := IdentityProviderForInstitution(inst.ID)
+			if IsNoRowError(err) || (err == nil && !idp.Enabled) {
+				return nil, common.ErrSSONotConfigured
+			}
+			return idp, err
+		} else if !IsNoRowError(err) {
+			return nil, err
+		}
+		domain = domain[strings.Index(domain, ".")+1:]
+	}
+	return nil, common.ErrSSONotConfigured
+}
+
+// IdentityProviderGet returns the first identity provider matching
+// the query.
+func IdentityProviderGet(query *Query) (*IdentityProvider, error) {
+	var idp IdentityProvider
+	err := query.Select(&idp)
+	return &idp, err
+}
+
+// IdentityProviderSelect returns all identity providers matching
+// the query.
+func IdentityProviderSelect(query *Query) ([]*IdentityProvider, error) {
+	var idps []*IdentityProvider
+	err := query.Select(&idps)
+	return idps, err
+}
+
+// Save saves this identity provider to the database. This will peform
+// an insert if IdentityProvider.ID is zero. Otherwise, it updates.
+func (idp *IdentityProvider) Save() error {
+	idp.SetTimestamps()
+	if idp.AdminRoleValues == nil {
+		idp.AdminRoleValues = make([]string, 0)
+	}
+	err := idp.Validate()
+	if err != nil {
+		return err
+	}
+	if idp.ID == int64(0) {
+		return insert(idp)
+	}
+	return update(idp)
+}
+
+// Delete deletes this identity provider. The institution's users go
+// back to signing in with Registry passwords.
+func (idp *IdentityProvider) Delete() error {
+	_, err := common.Context().DB.Model(idp).WherePK().Delete()
+	return err
+}
+
+// Validate validates the model. This is called automatically on insert
+// and update. We check only the settings for the selected protocol, so
+// admins can switch protocols without clearing the other settings.
+func (idp *IdentityProvider) Validate() *common.ValidationError {
+	errors := make(map[string]string)
+	if idp.InstitutionID < 1 {
+		errors["InstitutionID"] = ErrIdPInstitutionID
+	}
+	if !slice.Contains(constants.SSOProtocols, idp.Protocol) {
+		errors["Protocol"] = ErrIdPProtocol
+	}
+	if common.IsEmptyString(idp.EmailAttribute) {
+		errors["EmailAttribute"] = ErrIdPEmailAttribute
+	}
+	if idp.Required && !idp.Enabled {
+		errors["Required"] = ErrIdPRequired
+	}
+	if idp.Protocol == constants.SSOProtocolOIDC {
+		if !isValidIssuerURL(idp.IssuerURL) {
+			errors["IssuerURL"] = ErrIdPIssuerURL
+		}
+		if common.IsEmptyString(idp.ClientID) {
+			errors["ClientID"] = ErrIdPClientID
+		}
+		if common.IsEmptyString(idp.EncryptedClientSecret) {
+			errors["ClientSecret"] = ErrIdPClientSecret
+		}
+	}
+	if idp.Protocol == constants.SSOProtocolSAML {
+		sp, err := common.NewSAMLServiceProvider("", []byte(idp.MetadataXML))
+		if err != nil || sp.IDPMetadata == nil || sp.GetSSOBindingLocation(saml.HTTPRedirectBinding) == "" {
+			errors["MetadataXML"] = ErrIdPMetadataXML
+		}
+	}
+	if len(errors) > 0 {
+		return &common.ValidationError{Errors: errors}
+	}
+	return nil
+}
+
+// isValidIssuerURL returns true if issuer is an https URL. The OIDC
+// spec requires https, but we allow http on localhost for development
+// and testing.
+func isValidIssuerURL(issuer string) bool {
+	u, err := url.Parse(issuer)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if u.Scheme == "https" {
+		return true
+	}
+	host := u.Hostname()
+	return u.Scheme == "http" && (host == "localhost" || host == "127.0.0.1")
+}
+
+// IsRequired returns true if this institution's users must sign in
+// through this identity provider.
+func (idp *IdentityProvider) IsRequired() bool {
+	return idp.Enabled && idp.Required
+}
+
+// ProtocolName returns a human-readable name for this provider's
+// protocol.
+func (idp *IdentityProvider) ProtocolName() string {
+	if idp.Protocol == constants.SSOProtocolSAML {
+		return "SAML 2.0"
+	}
+	return "OpenID Connect"
+}
+
+// SetClientSecret encrypts the OIDC client secret and stores it in
+// EncryptedClientSecret. Returns common.ErrSSONotEnabled if
+// SSO_ENCRYPTION_KEY is not set.
+func (idp *IdentityProvider) SetClientSecret(secret string) error {
+	config := common.Context().Config.SSO
+	if !config.Enabled {
+		return common.ErrSSONotEnabled
+	}
+	encrypted, err := common.EncryptSecret(config.EncryptionKey, secret)
+	if err != nil {
+		return err
+	}
+	idp.EncryptedClientSecret = encrypted
+	return nil
+}
+
+// ClientSecret returns the decrypted OIDC client secret.
+func (idp *IdentityProvider) ClientSecret() (string, error) {
+	config := common.Context().Config.SSO
+	if !config.Enabled {
+		return "", common.ErrSSONotEnabled
+	}
+	return common.DecryptSecret(config.EncryptionKey, idp.EncryptedClientSecret)
+}
+
+// OIDCClient returns a client for this OpenID Connect provider, which
+// will send users back to the Registry at baseURL.
+func (idp *IdentityProvider) OIDCClient(ctx context.Context, baseURL string) (*common.OIDCClient, error) {
+	secret, err := idp.ClientSecret()
+	if err != nil {
+		return nil, err
+	}
+	return common.NewOIDCClient(ctx, idp.IssuerURL, idp.ClientID, secret, baseURL+common.OIDCCallbackPath)
+}
+
+// SAMLServiceProvider returns a SAML service provider for the Registry
+// at baseURL that trusts this identity provider.
+func (idp *IdentityProvider) SAMLServiceProvider(baseURL string) (*saml.ServiceProvider, error) {
+	return common.NewSAMLServiceProvider(baseURL, []byte(idp.MetadataXML))
+}
+
+// RoleFor returns the Registry role for a user this provider signed in.
+// If the user's RoleAttribute includes any of AdminRoleValues, they're
+// an institutional admin. Otherwise, they're an institutional user.
+// Values are compared case-insensitively.
+//
+// This returns an empty string if RoleAttribute is not set, which
+// means the provider doesn't manage roles.
+func (idp *IdentityProvider) RoleFor(identity *common.SSOIdentity) string {
+	if common.IsEmptyString(idp.RoleAttribute) {
+		return ""
+	}
+	for _, value := range identity.Attributes[idp.RoleAttribute] {
+		for _, adminValue := range idp.AdminRoleValues {
+			if strings.EqualFold(strings.TrimSpace(value), strings.TrimSpace(adminValue)) {
+				return constants.RoleInstAdmin
+			}
+		}
+	}
+	return constants.RoleInstUser
+}
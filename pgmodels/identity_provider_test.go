@@ -0,0 +1,190 @@
+package pgmodels_test
+
+import (
+	"testing"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/APTrust/registry/web/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentityProviderValidate(t *testing.T) {
+	idp := &pgmodels.IdentityProvider{}
+	err := idp.Validate()
+	require.NotNil(t, err)
+	assert.Equal(t, pgmodels.ErrIdPInstitutionID, err.Errors["InstitutionID"])
+	assert.Equal(t, pgmodels.ErrIdPProtocol, err.Errors["Protocol"])
+	assert.Equal(t, pgmodels.ErrIdPEmailAttribute, err.Errors["EmailAttribute"])
+
+	idp = pgmodels.NewIdentityProvider(4)
+	idp.Required = true
+	err = idp.Validate()
+	require.NotNil(t, err)
+	assert.Equal(t, pgmodels.ErrIdPRequired, err.Errors["Required"])
+	assert.Equal(t, pgmodels.ErrIdPIssuerURL, err.Errors["IssuerURL"])
+	assert.Equal(t, pgmodels.ErrIdPClientID, err.Errors["ClientID"])
+	assert.Equal(t, pgmodels.ErrIdPClientSecret, err.Errors["ClientSecret"])
+	assert.Empty(t, err.Errors["MetadataXML"])
+
+	idp.Enabled = true
+	idp.ClientID = "registry"
+	require.Nil(t, idp.SetClientSecret("shh"))
+	for _, issuer := range []string{"https://login.example.edu", "http://localhost:8080", "http://127.0.0.1:5555"} {
+		idp.IssuerURL = issuer
+		assert.Nil(t, idp.Validate(), issuer)
+	}
+	for _, issuer := range []string{"http://login.example.edu", "login.example.edu", "https://", ""} {
+		idp.IssuerURL = issuer
+		err = idp.Validate()
+		require.NotNil(t, err, issuer)
+		assert.Equal(t, pgmodels.ErrIdPIssuerURL, err.Errors["IssuerURL"])
+	}
+
+	// SAML checks only the metadata, so admins can switch
+	// protocols without clearing the OIDC settings.
+	idp.Protocol = constants.SSOProtocolSAML
+	err = idp.Validate()
+	require.NotNil(t, err)
+	assert.Equal(t, pgmodels.ErrIdPMetadataXML, err.Errors["MetadataXML"])
+	assert.Empty(t, err.Errors["IssuerURL"])
+
+	stub, stubErr := testutil.NewStubIdP("http://localhost")
+	require.Nil(t, stubErr)
+	defer stub.Close()
+	idp.MetadataXML = stub.SAMLMetadata()
+	assert.Nil(t, idp.Validate())
+}
+
+func TestIdentityProviderClientSecret(t *testing.T) {
+	idp := pgmodels.NewIdentityProvider(4)
+	require.Nil(t, idp.SetClientSecret("client-secret"))
+	assert.NotEmpty(t, idp.EncryptedClientSecret)
+	assert.NotContains(t, idp.EncryptedClientSecret, "client-secret")
+	secret, err := idp.ClientSecret()
+	require.Nil(t, err)
+	assert.Equal(t, "client-secret", secret)
+}
+
+func TestIdentityProviderRoleFor(t *testing.T) {
+	identity := &common.SSOIdentity{
+		Email: "jdoe@test.edu",
+		Attributes: map[string][]string{
+			"groups": {"staff", "Registry-Admins"},
+		},
+	}
+	idp := pgmodels.NewIdentityProvider(4)
+	assert.Equal(t, "", idp.RoleFor(identity))
+
+	idp.RoleAttribute = "groups"
+	assert.Equal(t, constants.RoleInstUser, idp.RoleFor(identity))
+
+	idp.AdminRoleValues = []string{"registry-admins"}
+	assert.Equal(t, constants.RoleInstAdmin, idp.RoleFor(identity))
+
+	idp.RoleAttribute = "eduPersonEntitlement"
+	assert.Equal(t, constants.RoleInstUser, idp.RoleFor(identity))
+}
+
+func TestIdentityProviderProtocolName(t *testing.T) {
+	idp := pgmodels.NewIdentityProvider(4)
+	assert.Equal(t, "OpenID Connect", idp.ProtocolName())
+	idp.Protocol = constants.SSOProtocolSAML
+	assert.Equal(t, "SAML 2.0", idp.ProtocolName())
+	assert.False(t, idp.IsRequired())
+	idp.Required = true
+	assert.False(t, idp.IsRequired())
+	idp.Enabled = true
+	assert.True(t, idp.IsRequired())
+}
+
+func TestIdentityProviderForEmail(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+
+	_, err := pgmodels.IdentityProviderForEmail("user@test.edu")
+	assert.Equal(t, common.ErrSSONotConfigured, err)
+
+	idp := pgmodels.NewIdentityProvider(4)
+	idp.IssuerURL = "https://login.test.edu"
+	idp.ClientID = "registry"
+	require.Nil(t, idp.SetClientSecret("shh"))
+	require.Nil(t, idp.Save())
+	assert.True(t, idp.ID > 0)
+
+	// Disabled providers don't count.
+	_, err = pgmodels.IdentityProviderForEmail("user@test.edu")
+	assert.Equal(t, common.ErrSSONotConfigured, err)
+
+	idp.Enabled = true
+	require.Nil(t, idp.Save())
+	for _, email := range []string{"user@test.edu", "User@TEST.edu", "someone@med.test.edu"} {
+		found, err := pgmodels.IdentityProviderForEmail(email)
+		require.Nil(t, err, email)
+		assert.Equal(t, idp.ID, found.ID, email)
+	}
+	for _, email := range []string{"user@inst1.edu", "user@test.edu.example.com", "test.edu", ""} {
+		_, err = pgmodels.IdentityProviderForEmail(email)
+		assert.Equal(t, common.ErrSSONotConfigured, err, email)
+	}
+
+	found, err := pgmodels.IdentityProviderForInstitution(4)
+	require.Nil(t, err)
+	assert.Equal(t, idp.ID, found.ID)
+
+	require.Nil(t, idp.Delete())
+	_, err = pgmodels.IdentityProviderByID(idp.ID)
+	assert.True(t, pgmodels.IsNoRowError(err))
+}
+
+func TestUserSSOSignIn(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+
+	idp := pgmodels.NewIdentityProvider(4)
+	idp.Enabled = true
+	idp.IssuerURL = "https://login.test.edu"
+	idp.ClientID = "registry"
+	idp.RoleAttribute = "groups"
+	idp.AdminRoleValues = []string{"registry-admins"}
+	require.Nil(t, idp.SetClientSecret("shh"))
+	require.Nil(t, idp.Save())
+
+	identity := &common.SSOIdentity{
+		Subject: "user",
+		Email:   "user@test.edu",
+		Attributes: map[string][]string{
+			"groups": {"registry-admins"},
+		},
+	}
+	user, err := pgmodels.UserSSOSignIn(idp, identity, "10.0.0.1")
+	require.Nil(t, err)
+	assert.Equal(t, "user@test.edu", user.Email)
+	assert.Equal(t, constants.RoleInstAdmin, user.Role)
+	assert.Equal(t, "10.0.0.1", user.CurrentSignInIP)
+
+	identity.Attributes["groups"] = []string{"staff"}
+	user, err = pgmodels.UserSSOSignIn(idp, identity, "10.0.0.1")
+	require.Nil(t, err)
+	assert.Equal(t, constants.RoleInstUser, user.Role)
+
+	// Users at other institutions and unknown users can't sign in.
+	identity.Email = "user@inst1.edu"
+	_, err = pgmodels.UserSSOSignIn(idp, identity, "10.0.0.1")
+	assert.Equal(t, common.ErrSSONoAccount, err)
+	identity.Email = "nobody@test.edu"
+	_, err = pgmodels.UserSSOSignIn(idp, identity, "10.0.0.1")
+	assert.Equal(t, common.ErrSSONoAccount, err)
+	identity.Email = ""
+	_, err = pgmodels.UserSSOSignIn(idp, identity, "10.0.0.1")
+	assert.Equal(t, common.ErrSSONoAccount, err)
+
+	// When SSO is required, users can't sign in with passwords.
+	idp.Required = true
+	require.Nil(t, idp.Save())
+	_, err = pgmodels.UserSignIn("user@test.edu", "password", "10.0.0.1")
+	assert.Equal(t, common.ErrSSORequired, err)
+}
@@ -18,8 +18,14 @@ const (
 	ErrInstReceiving  = "Receiving bucket name is not valid."
 	ErrInstRestore    = "Restoration bucket name is not valid."
 	ErrInstMemberID   = "Please choose a parent institution."
+	ErrInstAllowance  = "Free allowance must be zero or more terabytes."
 )
 
+// DefaultFreeAllowanceTB is the number of terabytes a new member
+// institution may store each month before it's billed for storage.
+// See Institution.FreeAllowanceTB.
+const DefaultFreeAllowanceTB = 10.0
+
 var InstitutionFilters = []string{
 	"name__contains",
 	"type",
@@ -38,6 +44,7 @@ type Institution struct {
 	LastSpotRestoreWorkItemID int64     `json:"last_spot_restore_work_item_id"`
 	ReceivingBucket           string    `json:"receiving_bucket"`
 	RestoreBucket             string    `json:"restore_bucket"`
+	FreeAllowanceTB           float64   `json:"free_allowance_tb" pg:",use_zero"`
 }
 
 // InstitutionByID returns the institution with the specified id.
@@ -179,6 +186,9 @@ func (inst *Institution) Validate() *common.ValidationError {
 	if inst.Type == constants.InstTypeSubscriber && inst.MemberInstitutionID < int64(1) {
 		errors["MemberInstitutionID"] = ErrInstMemberID
 	}
+	if inst.FreeAllowanceTB < 0 {
+		errors["FreeAllowanceTB"] = ErrInstAllowance
+	}
 	if len(errors) > 0 {
 		return &common.ValidationError{Errors: errors}
 	}
@@ -29,6 +29,7 @@ type InstitutionView struct {
 	ParentIdentifier          string    `json:"parent_identifier"`
 	ParentState               string    `json:"parent_state"`
 	ParentDeactivatedAt       time.Time `json:"parent_deactivated_at"`
+	FreeAllowanceTB           float64   `json:"free_allowance_tb"`
 }
 
 // InstitutionViewByID returns the InstitutionView record
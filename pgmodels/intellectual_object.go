@@ -254,14 +254,17 @@ func (obj *IntellectualObject) ActiveDeletionWorkItem() (*WorkItem, error) {
 
 func (obj *IntellectualObject) DeletionRequest(workItemID int64) (*DeletionRequestView, error) {
 	// web/webui/deletion_request_controller.go method DeletionRequestApprove
-	// creates a WorkItem when deletion request is approved. This is a
-	// one-to-one relationship. If we can't find the deletion request view
-	// for a valid deletion WorkItem, something is wrong!
-	query := NewQuery().
-		Where("work_item_id", "=", workItemID).
-		Where("object_count", ">", 0).
-		Where("file_count", "=", 0)
-	return DeletionRequestViewGet(query)
+	// creates a WorkItem for each object when deletion request is approved.
+	// If we can't find the deletion request view for a valid deletion
+	// WorkItem, something is wrong!
+	request, err := DeletionRequestViewForWorkItem(workItemID)
+	if err != nil {
+		return nil, err
+	}
+	if request.ObjectCount == 0 || request.FileCount > 0 {
+		return nil, pg.ErrNoRows
+	}
+	return request, nil
 }
 
 func (obj *IntellectualObject) AssertDeletionPreconditions() error {
@@ -280,6 +283,16 @@ func (obj *IntellectualObject) AssertDeletionPreconditions() error {
 	return err
 }
 
+// AssertCanRequestDeletion returns an error if a user should not be
+// able to request deletion of this object, because it has already been
+// deleted or was never ingested. This is a subset of the checks in
+// AssertDeletionPreconditions, which also requires an approved deletion
+// WorkItem, and which preservation services check before actually
+// deleting anything.
+func (obj *IntellectualObject) AssertCanRequestDeletion() error {
+	return obj.assertNotAlreadyDeleted()
+}
+
 func (obj *IntellectualObject) assertNoActiveFiles() error {
 	hasFiles, err := obj.HasActiveFiles()
 	if err != nil {
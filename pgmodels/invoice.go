@@ -0,0 +1,342 @@
+package pgmodels
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/go-pg/pg/v10"
+)
+
+// gbPerTB converts our deposit stats, which use binary units, from
+// gigabytes to terabytes.
+const gbPerTB = 1024.0
+
+var InvoiceFilters = []string{
+	"billing_month__gteq",
+	"billing_month__lteq",
+	"institution_id",
+	"invoice_number",
+}
+
+// Invoice is a monthly storage invoice for a member institution. It
+// covers the member's deposits and those of all its sub-accounts.
+//
+// BillingMonth is the first day of the month billed. InvoiceNumber is
+// derived from the month and institution, so it never changes. See
+// InvoiceNumberFor.
+//
+// Line items use the storage prices in effect during the billing month.
+// We copy the institution's name, free allowance, and those prices
+// into the invoice when we create it, so invoices already issued don't
+// change when those do.
+type Invoice struct {
+	TimestampModel
+	InvoiceNumber   string             `json:"invoice_number"`
+	InstitutionID   int64              `json:"institution_id"`
+	InstitutionName string             `json:"institution_name"`
+	BillingMonth    time.Time          `json:"billing_month"`
+	FreeAllowanceTB float64            `json:"free_allowance_tb" pg:",use_zero"`
+	TotalGB         float64            `json:"total_gb" pg:"total_gb,use_zero"`
+	TotalTB         float64            `json:"total_tb" pg:"total_tb,use_zero"`
+	BillableGB      float64            `json:"billable_gb" pg:"billable_gb,use_zero"`
+	AmountDue       float64            `json:"amount_due" pg:",use_zero"`
+	LineItems       []*InvoiceLineItem `json:"line_items" pg:"rel:has-many"`
+}
+
+// InvoiceLineItem is one line of an invoice.
+//
+// Billed lines (Breakdown = false) have the total usage in one storage
+// option for the member and all of its sub-accounts, the portion of the
+// free allowance applied to that usage, and the amount billed.
+//
+// Breakdown lines show one account's share of that usage, so members
+// can see what each sub-account stored. Their amounts are at the full
+// rate, before the free allowance, and are not part of the amount due.
+// Invoices for members without sub-accounts have no breakdown lines.
+type InvoiceLineItem struct {
+	ID              int64   `json:"id" pg:"id"`
+	InvoiceID       int64   `json:"invoice_id"`
+	InstitutionID   int64   `json:"institution_id"`
+	InstitutionName string  `json:"institution_name"`
+	StorageOption   string  `json:"storage_option"`
+	Breakdown       bool    `json:"breakdown" pg:",use_zero"`
+	TotalGB         float64 `json:"total_gb" pg:"total_gb,use_zero"`
+	TotalTB         float64 `json:"total_tb" pg:"total_tb,use_zero"`
+	FreeGB          float64 `json:"free_gb" pg:"free_gb,use_zero"`
+	BillableGB      float64 `json:"billable_gb" pg:"billable_gb,use_zero"`
+	CostGBPerMonth  float64 `json:"cost_gb_per_month" pg:"cost_gb_per_month,use_zero"`
+	Amount          float64 `json:"amount" pg:",use_zero"`
+}
+
+// InvoiceByID returns the invoice with the specified id, including
+// its line items. Returns pg.ErrNoRows if there is no match.
+func InvoiceByID(id int64) (*Invoice, error) {
+	query := NewQuery().Where("id", "=", id).Relations("LineItems")
+	return InvoiceGet(query)
+}
+
+// InvoiceByNumber returns the invoice with the specified invoice
+// number, including its line items. Returns pg.ErrNoRows if there
+// is no match.
+func InvoiceByNumber(invoiceNumber string) (*Invoice, error) {
+	query := NewQuery().Where("invoice_number", "=", invoiceNumber).Relations("LineItems")
+	return InvoiceGet(query)
+}
+
+// InvoiceGet returns the first invoice matching the query. Line items,
+// if the query loads them, are in the order they were created: billed
+// lines first, then breakdown lines.
+func InvoiceGet(query *Query) (*Invoice, error) {
+	var invoice Invoice
+	err := query.Select(&invoice)
+	sort.Slice(invoice.LineItems, func(i, j int) bool {
+		return invoice.LineItems[i].ID < invoice.LineItems[j].ID
+	})
+	return &invoice, err
+}
+
+// InvoiceSelect returns all invoices matching the query.
+func InvoiceSelect(query *Query) ([]*Invoice, error) {
+	var invoices []*Invoice
+	err := query.Select(&invoices)
+	return invoices, err
+}
+
+// InvoiceNumberFor returns the invoice number for an institution's
+// invoice for the specified month, e.g. APT-202301-00002 for
+// institution 2's January 2023 invoice.
+func InvoiceNumberFor(institutionID int64, billingMonth time.Time) string {
+	return fmt.Sprintf("APT-%s-%05d", billingMonth.Format("200601"), institutionID)
+}
+
+// BillingMonthOf returns the first day of the month containing ts.
+func BillingMonthOf(ts time.Time) time.Time {
+	return time.Date(ts.Year(), ts.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// BilledLines returns the invoice's billed line items.
+func (invoice *Invoice) BilledLines() []*InvoiceLineItem {
+	return invoice.lines(false)
+}
+
+// BreakdownLines returns the invoice's per-account breakdown lines.
+func (invoice *Invoice) BreakdownLines() []*InvoiceLineItem {
+	return invoice.lines(true)
+}
+
+func (invoice *Invoice) lines(breakdown bool) []*InvoiceLineItem {
+	lines := make([]*InvoiceLineItem, 0)
+	for _, item := range invoice.LineItems {
+		if item.Breakdown == breakdown {
+			lines = append(lines, item)
+		}
+	}
+	return lines
+}
+
+// GenerateInvoice creates and saves the invoice for a member
+// institution's storage in the specified month, based on the
+// historical deposit stats for the end of that month. If the
+// invoice already exists, this returns it unchanged.
+//
+// Sub-accounts aren't invoiced separately. Their deposits appear on
+// their parent's invoice. This returns common.ErrInvalidParam if inst
+// is not a member institution.
+//
+// The member's free allowance applies to its total usage across all
+// storage options. It's used first against Standard storage, then
+// against the other options in alphabetical order.
+func GenerateInvoice(inst *Institution, month time.Time) (*Invoice, error) {
+	if inst == nil || inst.Type != constants.InstTypeMember {
+		return nil, common.ErrInvalidParam
+	}
+	billingMonth := BillingMonthOf(month)
+	existing, err := InvoiceByNumber(InvoiceNumberFor(inst.ID, billingMonth))
+	if err == nil {
+		return existing, nil
+	}
+	if !IsNoRowError(err) {
+		return nil, err
+	}
+	invoice := &Invoice{
+		InvoiceNumber:   InvoiceNumberFor(inst.ID, billingMonth),
+		InstitutionID:   inst.ID,
+		InstitutionName: inst.Name,
+		BillingMonth:    billingMonth,
+		FreeAllowanceTB: inst.FreeAllowanceTB,
+	}
+	err = invoice.buildLineItems(inst)
+	if err != nil {
+		return nil, err
+	}
+	return invoice, invoice.Save()
+}
+
+// GenerateInvoices creates invoices for the specified month for all
+// active member institutions that don't have one yet. This returns
+// the number of invoices it created.
+func GenerateInvoices(month time.Time) (int, error) {
+	query := NewQuery().
+		Where("type", "=", constants.InstTypeMember).
+		Where("state", "=", constants.StateActive).
+		OrderBy("id", "asc")
+	institutions, err := InstitutionSelect(query)
+	if err != nil {
+		return 0, err
+	}
+	created := 0
+	for _, inst := range institutions {
+		exists, err := common.Context().DB.Model((*Invoice)(nil)).
+			Where("invoice_number = ?", InvoiceNumberFor(inst.ID, BillingMonthOf(month))).
+			Exists()
+		if err != nil {
+			return created, err
+		}
+		if exists {
+			continue
+		}
+		_, err = GenerateInvoice(inst, month)
+		if err != nil {
+			return created, fmt.Errorf("error generating invoice for %s: %v", inst.Identifier, err)
+		}
+		created++
+	}
+	return created, nil
+}
+
+// buildLineItems adds the invoice's line items from the deposit stats
+// at the end of the billing month, and sets the invoice totals.
+//
+// This returns common.ErrNoDepositStats if no stats were recorded for
+// the end of the month. Without them, we'd issue an invoice for
+// nothing, and issued invoices can't be changed.
+func (invoice *Invoice) buildLineItems(inst *Institution) error {
+	endDate := invoice.BillingMonth.AddDate(0, 1, 0)
+	haveStats, err := common.Context().DB.Model().
+		Table("historical_deposit_stats").
+		Where("end_date = ?", endDate).
+		Exists()
+	if err != nil {
+		return err
+	}
+	if !haveStats {
+		return common.ErrNoDepositStats
+	}
+	stats, err := DepositStatsSelect(inst.ID, "", endDate)
+	if err != nil {
+		return err
+	}
+	hasSubAccounts, err := inst.HasSubAccounts()
+	if err != nil {
+		return err
+	}
+	rates, err := StoragePricesOn(invoice.BillingMonth)
+	if err != nil {
+		return err
+	}
+
+	// With sub-accounts, DepositStatsSelect appends the rollup from
+	// calculateSubAccountRollup, which has institution id zero. That's
+	// what we bill. Without sub-accounts, we bill the member's own stats.
+	billed := make([]*InvoiceLineItem, 0)
+	breakdown := make([]*InvoiceLineItem, 0)
+	for _, s := range stats {
+		if s.StorageOption == "Total" || s.TotalGB <= 0 {
+			continue
+		}
+		item := &InvoiceLineItem{
+			InstitutionID:   s.InstitutionID,
+			InstitutionName: s.InstitutionName,
+			StorageOption:   s.StorageOption,
+			TotalGB:         s.TotalGB,
+			TotalTB:         s.TotalGB / gbPerTB,
+			CostGBPerMonth:  rates[s.StorageOption],
+		}
+		if !hasSubAccounts || s.InstitutionID == 0 {
+			item.InstitutionID = inst.ID
+			item.InstitutionName = inst.Name
+			billed = append(billed, item)
+		} else {
+			item.Breakdown = true
+			item.Amount = roundCents(item.TotalGB * item.CostGBPerMonth)
+			breakdown = append(breakdown, item)
+		}
+	}
+
+	sort.SliceStable(billed, func(i, j int) bool {
+		if billed[i].StorageOption == constants.StorageOptionStandard || billed[j].StorageOption == constants.StorageOptionStandard {
+			return billed[i].StorageOption == constants.StorageOptionStandard
+		}
+		return billed[i].StorageOption < billed[j].StorageOption
+	})
+	freeGB := invoice.FreeAllowanceTB * gbPerTB
+	for _, item := range billed {
+		item.FreeGB = math.Min(freeGB, item.TotalGB)
+		freeGB -= item.FreeGB
+		item.BillableGB = item.TotalGB - item.FreeGB
+		item.Amount = roundCents(item.BillableGB * item.CostGBPerMonth)
+		invoice.TotalGB += item.TotalGB
+		invoice.BillableGB += item.BillableGB
+		invoice.AmountDue += item.Amount
+	}
+	invoice.TotalTB = invoice.TotalGB / gbPerTB
+	invoice.AmountDue = roundCents(invoice.AmountDue)
+	invoice.LineItems = append(billed, breakdown...)
+	return nil
+}
+
+// Save inserts this invoice and its line items. Invoices can't be
+// changed once issued, so this returns common.ErrNotSupported if the
+// invoice has already been saved.
+func (invoice *Invoice) Save() error {
+	if invoice.ID != 0 {
+		return common.ErrNotSupported
+	}
+	invoice.SetTimestamps()
+	err := invoice.Validate()
+	if err != nil {
+		return err
+	}
+	registryContext := common.Context()
+	db := registryContext.DB
+	return db.RunInTransaction(db.Context(), func(tx *pg.Tx) error {
+		_, err := tx.Model(invoice).Insert()
+		if err != nil {
+			registryContext.Log.Error().Msgf("Transaction failed. Model: %v. Error: %v", invoice, err)
+			return err
+		}
+		for _, item := range invoice.LineItems {
+			item.InvoiceID = invoice.ID
+		}
+		if len(invoice.LineItems) > 0 {
+			_, err = tx.Model(&invoice.LineItems).Insert()
+		}
+		return err
+	})
+}
+
+// Validate validates the model. This is called automatically on insert.
+func (invoice *Invoice) Validate() *common.ValidationError {
+	errors := make(map[string]string)
+	if invoice.InstitutionID <= 0 {
+		errors["InstitutionID"] = "Invoice requires a valid institution id"
+	}
+	if invoice.BillingMonth.IsZero() {
+		errors["BillingMonth"] = "Invoice requires a billing month"
+	}
+	if invoice.InvoiceNumber != InvoiceNumberFor(invoice.InstitutionID, invoice.BillingMonth) {
+		errors["InvoiceNumber"] = "Invoice number does not match institution and month"
+	}
+	if len(errors) > 0 {
+		return &common.ValidationError{Errors: errors}
+	}
+	return nil
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,188 @@
+package pgmodels_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var invoiceMonth = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// addInvoiceStats adds deposit stats for the end of invoiceMonth.
+func addInvoiceStats(t *testing.T, instID, memberID int64, instName, storageOption string, totalGB float64) {
+	insert := `INSERT INTO historical_deposit_stats (institution_id, institution_name, storage_option, object_count, file_count, total_bytes, total_gb, total_tb, cost_gb_per_month, monthly_cost, end_date, member_institution_id, primary_sort, secondary_sort) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?)`
+	endDate := invoiceMonth.AddDate(0, 1, 0)
+	totalBytes := int64(totalGB * 1024 * 1024 * 1024)
+	_, err := common.Context().DB.Exec(insert, instID, instName, storageOption, 10, 100, totalBytes, totalGB, totalGB/1024, 0, 0, endDate, memberID, instName, storageOption)
+	require.Nil(t, err)
+}
+
+func deleteInvoiceStats(t *testing.T) {
+	_, err := common.Context().DB.Exec("delete from historical_deposit_stats where end_date = ?", invoiceMonth.AddDate(0, 1, 0))
+	assert.Nil(t, err)
+}
+
+func TestInvoiceNumberFor(t *testing.T) {
+	assert.Equal(t, "APT-202301-00002", pgmodels.InvoiceNumberFor(2, invoiceMonth))
+	assert.Equal(t, "APT-202312-00145", pgmodels.InvoiceNumberFor(145, time.Date(2023, 12, 31, 23, 0, 0, 0, time.UTC)))
+}
+
+func TestBillingMonthOf(t *testing.T) {
+	ts := time.Date(2023, 1, 17, 14, 30, 0, 0, time.UTC)
+	assert.Equal(t, invoiceMonth, pgmodels.BillingMonthOf(ts))
+}
+
+func TestGenerateInvoice(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+	defer deleteInvoiceStats(t)
+
+	inst, err := pgmodels.InstitutionByID(2)
+	require.Nil(t, err)
+	assert.Equal(t, pgmodels.DefaultFreeAllowanceTB, inst.FreeAllowanceTB)
+
+	// 20 TB in Standard and 1 TB in Glacier Deep. The 10 TB
+	// allowance applies to Standard.
+	addInvoiceStats(t, inst.ID, 0, inst.Name, constants.StorageOptionStandard, 20480)
+	addInvoiceStats(t, inst.ID, 0, inst.Name, constants.StorageOptionGlacierDeepOR, 1024)
+
+	invoice, err := pgmodels.GenerateInvoice(inst, invoiceMonth.AddDate(0, 0, 12))
+	require.Nil(t, err)
+	require.NotNil(t, invoice)
+	assert.True(t, invoice.ID > 0)
+	assert.Equal(t, "APT-202301-00002", invoice.InvoiceNumber)
+	assert.Equal(t, invoiceMonth, invoice.BillingMonth)
+	assert.Equal(t, inst.Name, invoice.InstitutionName)
+	assert.EqualValues(t, 21504, invoice.TotalGB)
+	assert.EqualValues(t, 21, invoice.TotalTB)
+	assert.EqualValues(t, 11264, invoice.BillableGB)
+	assert.Equal(t, 277.49, invoice.AmountDue)
+	assert.Empty(t, invoice.BreakdownLines())
+
+	lines := invoice.BilledLines()
+	require.Len(t, lines, 2)
+	assert.Equal(t, constants.StorageOptionStandard, lines[0].StorageOption)
+	assert.EqualValues(t, 10240, lines[0].FreeGB)
+	assert.EqualValues(t, 10240, lines[0].BillableGB)
+	assert.Equal(t, 0.027, lines[0].CostGBPerMonth)
+	assert.Equal(t, 276.48, lines[0].Amount)
+	assert.Equal(t, constants.StorageOptionGlacierDeepOR, lines[1].StorageOption)
+	assert.EqualValues(t, 0, lines[1].FreeGB)
+	assert.EqualValues(t, 1024, lines[1].BillableGB)
+	assert.Equal(t, 1.01, lines[1].Amount)
+
+	// Invoice should be saved with its line items.
+	saved, err := pgmodels.InvoiceByID(invoice.ID)
+	require.Nil(t, err)
+	assert.Equal(t, invoice.InvoiceNumber, saved.InvoiceNumber)
+	assert.Equal(t, invoice.AmountDue, saved.AmountDue)
+	require.Len(t, saved.LineItems, 2)
+	assert.Equal(t, constants.StorageOptionStandard, saved.LineItems[0].StorageOption)
+
+	// Changing the allowance doesn't change an issued invoice,
+	// and generating it again returns the original.
+	inst.FreeAllowanceTB = 0
+	require.Nil(t, inst.Save())
+	again, err := pgmodels.GenerateInvoice(inst, invoiceMonth)
+	require.Nil(t, err)
+	assert.Equal(t, invoice.ID, again.ID)
+	assert.Equal(t, 277.49, again.AmountDue)
+	assert.Equal(t, common.ErrNotSupported, again.Save())
+
+	// Only member institutions get invoices.
+	aptrust, err := pgmodels.InstitutionByID(1)
+	require.Nil(t, err)
+	_, err = pgmodels.GenerateInvoice(aptrust, invoiceMonth)
+	assert.Equal(t, common.ErrInvalidParam, err)
+
+	// We don't have stats for this month yet, so we can't bill it.
+	thisMonth := pgmodels.BillingMonthOf(time.Now().UTC())
+	_, err = pgmodels.GenerateInvoice(inst, thisMonth)
+	assert.Equal(t, common.ErrNoDepositStats, err)
+	_, err = pgmodels.InvoiceByNumber(pgmodels.InvoiceNumberFor(inst.ID, thisMonth))
+	assert.True(t, pgmodels.IsNoRowError(err))
+}
+
+func TestGenerateInvoiceWithSubAccounts(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+	defer deleteInvoiceStats(t)
+
+	parent, err := pgmodels.InstitutionByID(3)
+	require.Nil(t, err)
+	parent.FreeAllowanceTB = 1
+	require.Nil(t, parent.Save())
+
+	sub := &pgmodels.Institution{
+		Name:                "Invoice Sub-Account",
+		Identifier:          "invoicesub.institution2.edu",
+		State:               constants.StateActive,
+		Type:                constants.InstTypeSubscriber,
+		MemberInstitutionID: parent.ID,
+		ReceivingBucket:     "aptrust.receiving.test.invoicesub.institution2.edu",
+		RestoreBucket:       "aptrust.restore.test.invoicesub.institution2.edu",
+	}
+	require.Nil(t, sub.Save())
+
+	addInvoiceStats(t, parent.ID, 0, parent.Name, constants.StorageOptionStandard, 1024)
+	addInvoiceStats(t, sub.ID, parent.ID, sub.Name, constants.StorageOptionStandard, 2048)
+	addInvoiceStats(t, sub.ID, parent.ID, sub.Name, constants.StorageOptionWasabiOR, 512)
+
+	invoice, err := pgmodels.GenerateInvoice(parent, invoiceMonth)
+	require.Nil(t, err)
+
+	// The parent is billed for the rollup of its own deposits
+	// and its sub-account's, less its own allowance.
+	lines := invoice.BilledLines()
+	require.Len(t, lines, 2)
+	assert.Equal(t, constants.StorageOptionStandard, lines[0].StorageOption)
+	assert.Equal(t, parent.ID, lines[0].InstitutionID)
+	assert.EqualValues(t, 3072, lines[0].TotalGB)
+	assert.EqualValues(t, 1024, lines[0].FreeGB)
+	assert.Equal(t, 55.30, lines[0].Amount)
+	assert.Equal(t, constants.StorageOptionWasabiOR, lines[1].StorageOption)
+	assert.EqualValues(t, 512, lines[1].BillableGB)
+	assert.Equal(t, 3.02, lines[1].Amount)
+	assert.Equal(t, 58.32, invoice.AmountDue)
+
+	// Breakdown lines show each account's share at the full rate.
+	breakdown := invoice.BreakdownLines()
+	require.Len(t, breakdown, 3)
+	for _, item := range breakdown {
+		if item.InstitutionID == sub.ID && item.StorageOption == constants.StorageOptionStandard {
+			assert.EqualValues(t, 2048, item.TotalGB)
+			assert.Equal(t, 55.30, item.Amount)
+		}
+	}
+
+	// Sub-accounts aren't invoiced on their own.
+	_, err = pgmodels.GenerateInvoice(sub, invoiceMonth)
+	assert.Equal(t, common.ErrInvalidParam, err)
+}
+
+func TestGenerateInvoices(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+
+	// Four active member institutions in fixtures.
+	count, err := pgmodels.GenerateInvoices(invoiceMonth)
+	require.Nil(t, err)
+	assert.Equal(t, 4, count)
+
+	invoices, err := pgmodels.InvoiceSelect(pgmodels.NewQuery().Where("billing_month", "=", invoiceMonth).OrderBy("institution_id", "asc"))
+	require.Nil(t, err)
+	require.Len(t, invoices, 4)
+	assert.EqualValues(t, 2, invoices[0].InstitutionID)
+	assert.EqualValues(t, 0, invoices[0].AmountDue)
+
+	// Running again creates nothing new.
+	count, err = pgmodels.GenerateInvoices(invoiceMonth)
+	require.Nil(t, err)
+	assert.Equal(t, 0, count)
+}
@@ -0,0 +1,153 @@
+package pgmodels
+
+import (
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	v "github.com/asaskevich/govalidator"
+)
+
+const (
+	ErrJobRunName    = "Job name is required."
+	ErrJobRunTrigger = "Trigger must be a valid job trigger."
+	ErrJobRunStart   = "Start time is required."
+)
+
+// JobRun records a single run of one of Registry's scheduled jobs.
+// The scheduler creates a JobRun when it starts a job and updates
+// it with the finish time, duration, and error (if any) when the
+// job completes. A JobRun with no FinishedAt is either still running
+// or was interrupted when its container shut down.
+//
+// ScheduledFor is the time slot the run was scheduled for. This is
+// nil for manual and catch-up runs.
+type JobRun struct {
+	BaseModel
+	JobName      string     `json:"job_name" pg:"job_name"`
+	Trigger      string     `json:"trigger" pg:"trigger"`
+	Host         string     `json:"host" pg:"host"`
+	ScheduledFor *time.Time `json:"scheduled_for" pg:"scheduled_for"`
+	StartedAt    time.Time  `json:"started_at" pg:"started_at"`
+	FinishedAt   time.Time  `json:"finished_at" pg:"finished_at"`
+	DurationMs   int64      `json:"duration_ms" pg:"duration_ms"`
+	ErrorMessage string     `json:"error_message" pg:"error_message"`
+}
+
+// NewJobRun returns a new JobRun for the named job, with StartedAt
+// set to the current time.
+func NewJobRun(jobName, trigger, host string, scheduledFor *time.Time) *JobRun {
+	return &JobRun{
+		JobName:      jobName,
+		Trigger:      trigger,
+		Host:         host,
+		ScheduledFor: scheduledFor,
+		StartedAt:    time.Now().UTC(),
+	}
+}
+
+// JobRunByID returns the JobRun with the specified id.
+// Returns pg.ErrNoRows if there is no match.
+func JobRunByID(id int64) (*JobRun, error) {
+	query := NewQuery().Where("id", "=", id)
+	return JobRunGet(query)
+}
+
+// JobRunGet returns the first JobRun matching the query.
+func JobRunGet(query *Query) (*JobRun, error) {
+	var run JobRun
+	err := query.Select(&run)
+	return &run, err
+}
+
+// JobRunSelect returns all JobRuns matching the query.
+func JobRunSelect(query *Query) ([]*JobRun, error) {
+	var runs []*JobRun
+	err := query.Select(&runs)
+	return runs, err
+}
+
+// LastJobRun returns the most recent run of the named job, or nil
+// if the job has never run. If successfulOnly is true, this returns
+// the most recent run that completed without error.
+func LastJobRun(jobName string, successfulOnly bool) (*JobRun, error) {
+	query := NewQuery().Where("job_name", "=", jobName).OrderBy("started_at", "desc").Limit(1)
+	if successfulOnly {
+		query.IsNotNull("finished_at").IsNull("error_message")
+	}
+	run, err := JobRunGet(query)
+	if IsNoRowError(err) {
+		return nil, nil
+	}
+	return run, err
+}
+
+// Start inserts this JobRun into the database. It returns false if
+// another JobRun already exists for the same job and ScheduledFor
+// time, which means another Registry instance has already claimed
+// this scheduled run. Manual and catch-up runs, which have no
+// ScheduledFor time, always return true unless there's an error.
+func (run *JobRun) Start() (bool, error) {
+	valErr := run.Validate()
+	if valErr != nil {
+		return false, valErr
+	}
+	result, err := common.Context().DB.Model(run).OnConflict("DO NOTHING").Insert()
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// Finish records the end time, duration and error (if any) for this
+// run.
+func (run *JobRun) Finish(jobErr error) error {
+	run.FinishedAt = time.Now().UTC()
+	run.DurationMs = run.FinishedAt.Sub(run.StartedAt).Milliseconds()
+	if jobErr != nil {
+		run.ErrorMessage = jobErr.Error()
+	}
+	return run.Save()
+}
+
+// Succeeded returns true if this run finished without error.
+func (run *JobRun) Succeeded() bool {
+	return !run.FinishedAt.IsZero() && run.ErrorMessage == ""
+}
+
+// Duration returns the duration of this run. This will be zero if
+// the run has not finished.
+func (run *JobRun) Duration() time.Duration {
+	return time.Duration(run.DurationMs) * time.Millisecond
+}
+
+// Save saves this JobRun to the database. This will peform an insert
+// if JobRun.ID is zero. Otherwise, it updates.
+func (run *JobRun) Save() error {
+	err := run.Validate()
+	if err != nil {
+		return err
+	}
+	if run.ID == int64(0) {
+		return insert(run)
+	}
+	return update(run)
+}
+
+// Validate returns errors if this JobRun is not valid.
+func (run *JobRun) Validate() *common.ValidationError {
+	errors := make(map[string]string)
+	if common.IsEmptyString(run.JobName) {
+		errors["JobName"] = ErrJobRunName
+	}
+	if !v.IsIn(run.Trigger, constants.JobTriggers...) {
+		errors["Trigger"] = ErrJobRunTrigger
+	}
+	if run.StartedAt.IsZero() {
+		errors["StartedAt"] = ErrJobRunStart
+	}
+	if len(errors) > 0 {
+		return &common.ValidationError{Errors: errors}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package pgmodels_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobRunValidate(t *testing.T) {
+	run := &pgmodels.JobRun{}
+	err := run.Validate()
+	require.NotNil(t, err)
+	assert.Equal(t, pgmodels.ErrJobRunName, err.Errors["JobName"])
+	assert.Equal(t, pgmodels.ErrJobRunTrigger, err.Errors["Trigger"])
+	assert.Equal(t, pgmodels.ErrJobRunStart, err.Errors["StartedAt"])
+
+	run = pgmodels.NewJobRun("update_counts", constants.JobTriggerManual, "host1", nil)
+	assert.Nil(t, run.Validate())
+}
+
+func TestJobRunStartAndFinish(t *testing.T) {
+	db.LoadFixtures()
+	jobName := fmt.Sprintf("job_run_test_%d", time.Now().UnixNano())
+
+	lastRun, err := pgmodels.LastJobRun(jobName, false)
+	require.Nil(t, err)
+	assert.Nil(t, lastRun)
+
+	slot := time.Date(2023, 6, 1, 0, 30, 0, 0, time.UTC)
+	run := pgmodels.NewJobRun(jobName, constants.JobTriggerSchedule, "host1", &slot)
+	started, err := run.Start()
+	require.Nil(t, err)
+	assert.True(t, started)
+	assert.True(t, run.ID > 0)
+	assert.False(t, run.Succeeded())
+
+	// A second instance should not be able to claim the same slot.
+	run2 := pgmodels.NewJobRun(jobName, constants.JobTriggerSchedule, "host2", &slot)
+	started, err = run2.Start()
+	require.Nil(t, err)
+	assert.False(t, started)
+
+	// Unfinished runs don't count as successful.
+	lastRun, err = pgmodels.LastJobRun(jobName, true)
+	require.Nil(t, err)
+	assert.Nil(t, lastRun)
+
+	require.Nil(t, run.Finish(nil))
+	assert.True(t, run.Succeeded())
+	assert.False(t, run.FinishedAt.IsZero())
+
+	lastRun, err = pgmodels.LastJobRun(jobName, true)
+	require.Nil(t, err)
+	require.NotNil(t, lastRun)
+	assert.Equal(t, run.ID, lastRun.ID)
+
+	// Failed run is last, but not last successful.
+	run3 := pgmodels.NewJobRun(jobName, constants.JobTriggerManual, "host1", nil)
+	started, err = run3.Start()
+	require.Nil(t, err)
+	require.True(t, started)
+	require.Nil(t, run3.Finish(fmt.Errorf("oops")))
+	assert.False(t, run3.Succeeded())
+
+	reloaded, err := pgmodels.JobRunByID(run3.ID)
+	require.Nil(t, err)
+	assert.Equal(t, "oops", reloaded.ErrorMessage)
+
+	lastRun, err = pgmodels.LastJobRun(jobName, false)
+	require.Nil(t, err)
+	assert.Equal(t, run3.ID, lastRun.ID)
+
+	lastRun, err = pgmodels.LastJobRun(jobName, true)
+	require.Nil(t, err)
+	assert.Equal(t, run.ID, lastRun.ID)
+
+	runs, err := pgmodels.JobRunSelect(pgmodels.NewQuery().Where("job_name", "=", jobName))
+	require.Nil(t, err)
+	assert.Equal(t, 2, len(runs))
+
+	// Validation error
+	_, err = pgmodels.NewJobRun("", "", "", nil).Start()
+	require.NotNil(t, err)
+	_, isValidationErr := err.(*common.ValidationError)
+	assert.True(t, isValidationErr)
+}
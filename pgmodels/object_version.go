@@ -0,0 +1,367 @@
+package pgmodels
+
+import (
+	"sort"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/go-pg/pg/v10"
+)
+
+// ObjectVersion records what an object looked like after one successful
+// ingest. When a bag is re-ingested, preservation services updates the
+// object and its files in place, so these versions are the only record
+// of what each ingest changed.
+//
+// Files lists the object's active files as of this version. FilesAdded,
+// FilesReplaced and FilesRemoved compare those files with the files in
+// the previous version. VersionNumber counts all of the object's
+// successful ingests, including those from before we started recording
+// versions, so an object's first recorded version may not be version 1.
+type ObjectVersion struct {
+	TimestampModel
+	IntellectualObjectID int64                `json:"intellectual_object_id"`
+	InstitutionID        int64                `json:"institution_id"`
+	WorkItemID           int64                `json:"work_item_id"`
+	VersionNumber        int                  `json:"version_number"`
+	FileCount            int                  `json:"file_count" pg:",use_zero"`
+	Size                 int64                `json:"size" pg:",use_zero"`
+	FilesAdded           int                  `json:"files_added" pg:",use_zero"`
+	FilesReplaced        int                  `json:"files_replaced" pg:",use_zero"`
+	FilesRemoved         int                  `json:"files_removed" pg:",use_zero"`
+	Files                []*ObjectVersionFile `json:"files,omitempty" pg:"rel:has-many"`
+}
+
+// ObjectVersionFile is a file as it was in one version of an object.
+// The digests are the file's latest md5 and sha256 at the time we
+// recorded the version. Either may be empty if the file had no
+// checksum of that type.
+type ObjectVersionFile struct {
+	BaseModel
+	ObjectVersionID int64  `json:"object_version_id"`
+	GenericFileID   int64  `json:"generic_file_id"`
+	Identifier      string `json:"identifier"`
+	Size            int64  `json:"size" pg:",use_zero"`
+	Md5             string `json:"md5" pg:"md5"`
+	Sha256          string `json:"sha256" pg:"sha256"`
+}
+
+// FileChange describes how one file differs between two versions
+// of an object. Change is constants.FileAdded, FileReplaced or
+// FileRemoved. Old is nil for added files, and New is nil for
+// removed files.
+type FileChange struct {
+	Change     string             `json:"change"`
+	Identifier string             `json:"identifier"`
+	Old        *ObjectVersionFile `json:"old"`
+	New        *ObjectVersionFile `json:"new"`
+}
+
+// Md5Changed returns true if a replaced file's md5 digest changed.
+func (change *FileChange) Md5Changed() bool {
+	return change.Old != nil && change.New != nil && digestChanged(change.Old.Md5, change.New.Md5)
+}
+
+// Sha256Changed returns true if a replaced file's sha256 digest changed.
+func (change *FileChange) Sha256Changed() bool {
+	return change.Old != nil && change.New != nil && digestChanged(change.Old.Sha256, change.New.Sha256)
+}
+
+// SizeChanged returns true if a replaced file's size changed.
+func (change *FileChange) SizeChanged() bool {
+	return change.Old != nil && change.New != nil && change.Old.Size != change.New.Size
+}
+
+// digestChanged returns true if we have both digests and they differ.
+// We can't tell whether a file changed by comparing a digest with a
+// missing one.
+func digestChanged(oldDigest, newDigest string) bool {
+	return oldDigest != "" && newDigest != "" && oldDigest != newDigest
+}
+
+// ObjectVersionDiff lists the files added, replaced and removed between
+// two versions of an object. From is nil when To is the object's first
+// recorded version, in which case all of To's files count as added.
+type ObjectVersionDiff struct {
+	From     *ObjectVersion `json:"from"`
+	To       *ObjectVersion `json:"to"`
+	Added    []*FileChange  `json:"added"`
+	Replaced []*FileChange  `json:"replaced"`
+	Removed  []*FileChange  `json:"removed"`
+}
+
+// IsEmpty returns true if the two versions have the same files.
+func (diff *ObjectVersionDiff) IsEmpty() bool {
+	return len(diff.Added) == 0 && len(diff.Replaced) == 0 && len(diff.Removed) == 0
+}
+
+// ObjectVersionByID returns the object version with the specified id.
+// Returns pg.ErrNoRows if there is no match.
+func ObjectVersionByID(id int64) (*ObjectVersion, error) {
+	query := NewQuery().Where("id", "=", id)
+	return ObjectVersionGet(query)
+}
+
+// ObjectVersionGet returns the first object version matching the query.
+func ObjectVersionGet(query *Query) (*ObjectVersion, error) {
+	var version ObjectVersion
+	err := query.Select(&version)
+	return &version, err
+}
+
+// ObjectVersionSelect returns all object versions matching the query.
+func ObjectVersionSelect(query *Query) ([]*ObjectVersion, error) {
+	var versions []*ObjectVersion
+	err := query.Select(&versions)
+	return versions, err
+}
+
+// ObjectVersionsFor returns the recorded versions of an object,
+// newest first. This does not load the versions' files.
+func ObjectVersionsFor(objID int64) ([]*ObjectVersion, error) {
+	query := NewQuery().
+		Where("intellectual_object_id", "=", objID).
+		OrderBy("version_number", "desc").
+		OrderBy("id", "desc")
+	return ObjectVersionSelect(query)
+}
+
+// LoadFiles loads this version's files, in identifier order.
+func (version *ObjectVersion) LoadFiles() error {
+	var files []*ObjectVersionFile
+	err := common.Context().DB.Model(&files).
+		Where("object_version_id = ?", version.ID).
+		Order("identifier", "id").
+		Select()
+	version.Files = files
+	return err
+}
+
+// Previous returns the version recorded before this one, or nil if
+// this is the object's first recorded version.
+func (version *ObjectVersion) Previous() (*ObjectVersion, error) {
+	query := NewQuery().
+		Where("intellectual_object_id", "=", version.IntellectualObjectID).
+		Where("id", "<", version.ID).
+		OrderBy("id", "desc").
+		Limit(1)
+	versions, err := ObjectVersionSelect(query)
+	if err != nil || len(versions) == 0 {
+		return nil, err
+	}
+	return versions[0], nil
+}
+
+// pendingObjectVersionsQuery selects successful ingests that don't yet
+// have a recorded version, oldest first, so each object's versions are
+// recorded in order. We can't reconstruct versions for ingests from
+// before migration 029_object_versions, so we skip those.
+var pendingObjectVersionsQuery = `select wi.* from work_items wi
+	where wi.action = ? and wi.status = ?
+	and wi.intellectual_object_id is not null
+	and wi.updated_at >= (select started_at from schema_migrations where "version" = '029_object_versions')
+	and not exists (select 1 from object_versions ov where ov.work_item_id = wi.id)
+	order by wi.id
+	limit ?`
+
+// objectVersionFilesQuery selects an object's files as they were when
+// an ingest finished, with their latest md5 and sha256 digests as of
+// that time. Since we record versions some time after the ingest, the
+// object may have changed since then. We include files that were
+// created by then and were either still active or deleted afterward,
+// and ignore checksums recorded afterward. It reads the object's
+// checksums once, keeping the latest of each algorithm for each file.
+//
+// Sizes are the files' current sizes, since we don't keep a history
+// of those. The digests tell us whether a file was replaced.
+var objectVersionFilesQuery = `with latest as (
+		select distinct on (c.generic_file_id, c.algorithm) c.generic_file_id, c.algorithm, c.digest
+		from checksums c
+		inner join generic_files f on f.id = c.generic_file_id
+		where f.intellectual_object_id = ? and c.algorithm in (?, ?) and c.created_at <= ?
+		order by c.generic_file_id, c.algorithm, c.datetime desc, c.id desc)
+	select gf.id as generic_file_id, gf.identifier, gf.size, md5.digest as md5, sha256.digest as sha256
+	from generic_files gf
+	left join latest md5 on md5.generic_file_id = gf.id and md5.algorithm = ?
+	left join latest sha256 on sha256.generic_file_id = gf.id and sha256.algorithm = ?
+	where gf.intellectual_object_id = ? and gf.created_at <= ?
+	and (gf.state = ? or gf.updated_at > ?)
+	order by gf.identifier, gf.id`
+
+// RecordPendingObjectVersions records a version for each successful
+// ingest WorkItem that doesn't have one, up to limit items. The
+// record_object_versions job calls this every few minutes. It returns
+// the number of versions recorded.
+//
+// If we can't record a version for one item, we log the error and skip
+// the object's later items until the next run, so its versions are
+// always recorded in order.
+func RecordPendingObjectVersions(limit int) (int, error) {
+	ctx := common.Context()
+	var items []*WorkItem
+	_, err := ctx.DB.Query(&items, pendingObjectVersionsQuery,
+		constants.ActionIngest, constants.StatusSuccess, limit)
+	if err != nil {
+		return 0, err
+	}
+	recorded := 0
+	failedObjects := make(map[int64]bool)
+	for _, item := range items {
+		if failedObjects[item.IntellectualObjectID] {
+			continue
+		}
+		_, err := RecordObjectVersion(item)
+		if err != nil {
+			ctx.Log.Error().Msgf("Error recording object version for WorkItem %d: %v", item.ID, err)
+			failedObjects[item.IntellectualObjectID] = true
+			continue
+		}
+		recorded++
+	}
+	return recorded, nil
+}
+
+// RecordObjectVersion records a new version of the object that the
+// ingest WorkItem item created or updated. This should be called after
+// the ingest succeeds, when preservation services has saved the
+// object's files. See RecordPendingObjectVersions. The version shows
+// the object as it was at item.UpdatedAt, when the ingest finished,
+// even if it has been re-ingested since. Recording is idempotent: if
+// we already have a version for this WorkItem, this returns that
+// version.
+func RecordObjectVersion(item *WorkItem) (*ObjectVersion, error) {
+	existing, err := ObjectVersionGet(NewQuery().Where("work_item_id", "=", item.ID))
+	if err == nil {
+		return existing, nil
+	}
+	if !IsNoRowError(err) {
+		return nil, err
+	}
+
+	db := common.Context().DB
+	var files []*ObjectVersionFile
+	_, err = db.Query(&files, objectVersionFilesQuery,
+		item.IntellectualObjectID, constants.AlgMd5, constants.AlgSha256, item.UpdatedAt,
+		constants.AlgMd5, constants.AlgSha256,
+		item.IntellectualObjectID, item.UpdatedAt,
+		constants.StateActive, item.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	version := &ObjectVersion{
+		IntellectualObjectID: item.IntellectualObjectID,
+		InstitutionID:        item.InstitutionID,
+		WorkItemID:           item.ID,
+		FileCount:            len(files),
+		Files:                files,
+	}
+	for _, file := range files {
+		version.Size += file.Size
+	}
+
+	versions, err := ObjectVersionsFor(item.IntellectualObjectID)
+	if err != nil {
+		return nil, err
+	}
+	var previous *ObjectVersion
+	if len(versions) > 0 {
+		previous = versions[0]
+		err = previous.LoadFiles()
+		if err != nil {
+			return nil, err
+		}
+	}
+	diff := diffObjectVersions(previous, version)
+	version.FilesAdded = len(diff.Added)
+	version.FilesReplaced = len(diff.Replaced)
+	version.FilesRemoved = len(diff.Removed)
+
+	ingestCount, err := db.Model((*WorkItem)(nil)).
+		Where("intellectual_object_id = ?", item.IntellectualObjectID).
+		Where("action = ?", constants.ActionIngest).
+		Where("status = ?", constants.StatusSuccess).
+		Where("id <= ?", item.ID).
+		Count()
+	if err != nil {
+		return nil, err
+	}
+	version.VersionNumber = ingestCount
+	if previous != nil && version.VersionNumber <= previous.VersionNumber {
+		version.VersionNumber = previous.VersionNumber + 1
+	}
+
+	version.SetTimestamps()
+	err = db.RunInTransaction(db.Context(), func(tx *pg.Tx) error {
+		_, err := tx.Model(version).Insert()
+		if err != nil || len(files) == 0 {
+			return err
+		}
+		for _, file := range files {
+			file.ObjectVersionID = version.ID
+		}
+		_, err = tx.Model(&files).Insert()
+		return err
+	})
+	return version, err
+}
+
+// DiffObjectVersions compares the files in two versions of the same
+// object, loading their files if necessary. Param from may be nil to
+// compare to with an empty object. If from is newer than to, this
+// swaps them, so the diff always reads from older to newer.
+func DiffObjectVersions(from, to *ObjectVersion) (*ObjectVersionDiff, error) {
+	if from != nil && from.IntellectualObjectID != to.IntellectualObjectID {
+		return nil, common.ErrInvalidParam
+	}
+	if from != nil && from.ID > to.ID {
+		from, to = to, from
+	}
+	for _, version := range []*ObjectVersion{from, to} {
+		if version != nil && version.Files == nil {
+			err := version.LoadFiles()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return diffObjectVersions(from, to), nil
+}
+
+func diffObjectVersions(from, to *ObjectVersion) *ObjectVersionDiff {
+	diff := &ObjectVersionDiff{
+		From:     from,
+		To:       to,
+		Added:    make([]*FileChange, 0),
+		Replaced: make([]*FileChange, 0),
+		Removed:  make([]*FileChange, 0),
+	}
+	oldFiles := make(map[string]*ObjectVersionFile)
+	if from != nil {
+		for _, file := range from.Files {
+			oldFiles[file.Identifier] = file
+		}
+	}
+	newFiles := make(map[string]*ObjectVersionFile)
+	for _, file := range to.Files {
+		newFiles[file.Identifier] = file
+		old, ok := oldFiles[file.Identifier]
+		if !ok {
+			diff.Added = append(diff.Added, &FileChange{Change: constants.FileAdded, Identifier: file.Identifier, New: file})
+			continue
+		}
+		change := &FileChange{Change: constants.FileReplaced, Identifier: file.Identifier, Old: old, New: file}
+		if change.SizeChanged() || change.Md5Changed() || change.Sha256Changed() {
+			diff.Replaced = append(diff.Replaced, change)
+		}
+	}
+	for identifier, file := range oldFiles {
+		if _, ok := newFiles[identifier]; !ok {
+			diff.Removed = append(diff.Removed, &FileChange{Change: constants.FileRemoved, Identifier: identifier, Old: file})
+		}
+	}
+	sort.Slice(diff.Removed, func(i, j int) bool {
+		return diff.Removed[i].Identifier < diff.Removed[j].Identifier
+	})
+	return diff
+}
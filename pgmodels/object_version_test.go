@@ -0,0 +1,168 @@
+package pgmodels_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newIngestItemForObject1(etag string) *pgmodels.WorkItem {
+	return &pgmodels.WorkItem{
+		Name:                 "photos.tar",
+		ETag:                 etag,
+		InstitutionID:        2,
+		IntellectualObjectID: 1,
+		User:                 "system@aptrust.org",
+		Bucket:               "aptrust.receiving.test.inst1.edu",
+		Action:               constants.ActionIngest,
+		Stage:                constants.StageCleanup,
+		Status:               constants.StatusSuccess,
+		Note:                 "Finished cleanup. Ingest complete.",
+		Outcome:              "Ingest complete",
+		BagDate:              TestDate,
+		DateProcessed:        TestDate,
+		Size:                 8000,
+	}
+}
+
+func TestRecordObjectVersion(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+
+	// Saving a successful ingest doesn't record a version. The
+	// record_object_versions job does that later.
+	item := newIngestItemForObject1("12345678901234567890123456789001")
+	require.Nil(t, item.Save())
+	versions, err := pgmodels.ObjectVersionsFor(1)
+	require.Nil(t, err)
+	require.Empty(t, versions)
+
+	// Re-ingest with one file replaced and one removed, before the
+	// job records the first ingest. The second ingest finishes a
+	// minute later.
+	ctx := common.Context()
+	changedAt := item.UpdatedAt.Add(30 * time.Second)
+	_, err = ctx.DB.Exec(`insert into checksums (algorithm, datetime, digest, generic_file_id, created_at, updated_at)
+		values (?, ?, 'replaced-sha256', 1, ?, ?)`, constants.AlgSha256, changedAt, changedAt, changedAt)
+	require.Nil(t, err)
+	_, err = ctx.DB.Exec(`update generic_files set state = ?, updated_at = ? where id = 3`, constants.StateDeleted, changedAt)
+	require.Nil(t, err)
+	item2 := newIngestItemForObject1("12345678901234567890123456789002")
+	require.Nil(t, item2.Save())
+	_, err = ctx.DB.Exec(`update work_items set updated_at = ? where id = ?`, item.UpdatedAt.Add(time.Minute), item2.ID)
+	require.Nil(t, err)
+
+	// One run records both versions, each as the object was when
+	// its ingest finished.
+	recorded, err := pgmodels.RecordPendingObjectVersions(100)
+	require.Nil(t, err)
+	assert.Equal(t, 2, recorded)
+	versions, err = pgmodels.ObjectVersionsFor(1)
+	require.Nil(t, err)
+	require.Len(t, versions, 2)
+
+	first := versions[1]
+	assert.Equal(t, item.ID, first.WorkItemID)
+	assert.EqualValues(t, 2, first.InstitutionID)
+	assert.Equal(t, 3, first.FileCount)
+	assert.Equal(t, 3, first.FilesAdded)
+	assert.Equal(t, 0, first.FilesReplaced)
+	assert.Equal(t, 0, first.FilesRemoved)
+	assert.EqualValues(t, 243855000+1169355000+243855000, first.Size)
+
+	second := versions[0]
+	assert.Equal(t, item2.ID, second.WorkItemID)
+	assert.True(t, second.VersionNumber > first.VersionNumber)
+	assert.Equal(t, 2, second.FileCount)
+	assert.Equal(t, 0, second.FilesAdded)
+	assert.Equal(t, 1, second.FilesReplaced)
+	assert.Equal(t, 1, second.FilesRemoved)
+
+	// The next run doesn't record them again.
+	require.Nil(t, item.Save())
+	recorded, err = pgmodels.RecordPendingObjectVersions(100)
+	require.Nil(t, err)
+	assert.Equal(t, 0, recorded)
+	versions, err = pgmodels.ObjectVersionsFor(1)
+	require.Nil(t, err)
+	require.Len(t, versions, 2)
+
+	previous, err := second.Previous()
+	require.Nil(t, err)
+	require.NotNil(t, previous)
+	assert.Equal(t, first.ID, previous.ID)
+
+	// The diff reads from older to newer, whatever order we pass.
+	diff, err := pgmodels.DiffObjectVersions(second, first)
+	require.Nil(t, err)
+	assert.Equal(t, first.ID, diff.From.ID)
+	assert.Equal(t, second.ID, diff.To.ID)
+	assert.Empty(t, diff.Added)
+	require.Len(t, diff.Replaced, 1)
+	assert.Equal(t, "institution1.edu/photos/picture1", diff.Replaced[0].Identifier)
+	assert.True(t, diff.Replaced[0].Sha256Changed())
+	assert.Equal(t, "replaced-sha256", diff.Replaced[0].New.Sha256)
+	assert.False(t, diff.Replaced[0].SizeChanged())
+	require.Len(t, diff.Removed, 1)
+	assert.Equal(t, "institution1.edu/photos/picture3", diff.Removed[0].Identifier)
+}
+
+func TestDiffObjectVersions(t *testing.T) {
+	from := &pgmodels.ObjectVersion{
+		IntellectualObjectID: 1,
+		Files: []*pgmodels.ObjectVersionFile{
+			{Identifier: "obj/same", Size: 10, Md5: "aaa", Sha256: "bbb"},
+			{Identifier: "obj/resized", Size: 10, Md5: "aaa", Sha256: "bbb"},
+			{Identifier: "obj/no-old-md5", Size: 10, Sha256: "bbb"},
+			{Identifier: "obj/gone-b", Size: 10},
+			{Identifier: "obj/gone-a", Size: 10},
+		},
+	}
+	from.ID = 1
+	to := &pgmodels.ObjectVersion{
+		IntellectualObjectID: 1,
+		Files: []*pgmodels.ObjectVersionFile{
+			{Identifier: "obj/same", Size: 10, Md5: "aaa", Sha256: "bbb"},
+			{Identifier: "obj/resized", Size: 20, Md5: "aaa", Sha256: "bbb"},
+			{Identifier: "obj/no-old-md5", Size: 10, Md5: "ccc", Sha256: "bbb"},
+			{Identifier: "obj/new", Size: 5},
+		},
+	}
+	to.ID = 2
+
+	diff, err := pgmodels.DiffObjectVersions(from, to)
+	require.Nil(t, err)
+	require.Len(t, diff.Added, 1)
+	assert.Equal(t, "obj/new", diff.Added[0].Identifier)
+	assert.Equal(t, constants.FileAdded, diff.Added[0].Change)
+
+	// A digest we didn't have before doesn't count as a change.
+	require.Len(t, diff.Replaced, 1)
+	assert.Equal(t, "obj/resized", diff.Replaced[0].Identifier)
+	assert.True(t, diff.Replaced[0].SizeChanged())
+	assert.False(t, diff.Replaced[0].Md5Changed())
+
+	require.Len(t, diff.Removed, 2)
+	assert.Equal(t, "obj/gone-a", diff.Removed[0].Identifier)
+	assert.Equal(t, "obj/gone-b", diff.Removed[1].Identifier)
+	assert.Equal(t, constants.FileRemoved, diff.Removed[0].Change)
+	assert.False(t, diff.IsEmpty())
+
+	// With no previous version, everything is new.
+	diff, err = pgmodels.DiffObjectVersions(nil, to)
+	require.Nil(t, err)
+	assert.Len(t, diff.Added, 4)
+	assert.Empty(t, diff.Replaced)
+	assert.Empty(t, diff.Removed)
+
+	// Versions must belong to the same object.
+	other := &pgmodels.ObjectVersion{IntellectualObjectID: 2}
+	_, err = pgmodels.DiffObjectVersions(other, to)
+	assert.Equal(t, common.ErrInvalidParam, err)
+}
@@ -87,6 +87,16 @@ func InstIDFor(resourceType string, resourceID int64) (id int64, err error) {
 		alert := &Alert{}
 		err = db.Model(alert).Column("institution_id").Where("id = ?", resourceID).Select()
 		id = alert.InstitutionID
+	case "APIKey":
+		key := &APIKey{}
+		err = db.Model(key).Column("_").Relation("User.institution_id").Where(`"api_key"."id" = ?`, resourceID).Select()
+		if key != nil && key.User != nil {
+			id = key.User.InstitutionID
+		}
+	case "AuditEvent":
+		event := &AuditEvent{}
+		err = db.Model(event).Column("institution_id").Where("id = ?", resourceID).Select()
+		id = event.InstitutionID
 	case "Checksum":
 		cs := &Checksum{}
 		err = db.Model(cs).Column("_").Relation("GenericFile.institution_id").Where(`"checksum"."id" = ?`, resourceID).Select()
@@ -97,6 +107,11 @@ func InstIDFor(resourceType string, resourceID int64) (id int64, err error) {
 		req := &DeletionRequest{}
 		err = db.Model(req).Column("institution_id").Where("id = ?", resourceID).Select()
 		id = req.InstitutionID
+	case "FormatRisk":
+		// The format registry doesn't belong to any institution. This
+		// just checks that the entry exists.
+		formatRisk := &FormatRisk{}
+		err = db.Model(formatRisk).Column("id").Where("id = ?", resourceID).Select()
 	case "GenericFile":
 		gf := &GenericFile{}
 		err = db.Model(gf).Column("institution_id").Where("id = ?", resourceID).Select()
@@ -107,10 +122,31 @@ func InstIDFor(resourceType string, resourceID int64) (id int64, err error) {
 		obj := &IntellectualObject{}
 		err = db.Model(obj).Column("institution_id").Where("id = ?", resourceID).Select()
 		id = obj.InstitutionID
+	case "Invoice":
+		invoice := &Invoice{}
+		err = db.Model(invoice).Column("institution_id").Where("id = ?", resourceID).Select()
+		id = invoice.InstitutionID
 	case "PremisEvent":
 		pe := &PremisEvent{}
 		err = db.Model(pe).Column("institution_id").Where("id = ?", resourceID).Select()
 		id = pe.InstitutionID
+	case "ReplicationProblem":
+		problem := &ReplicationProblem{}
+		err = db.Model(problem).Column("institution_id").Where("id = ?", resourceID).Select()
+		id = problem.InstitutionID
+	case "RestorationBatch":
+		batch := &RestorationBatch{}
+		err = db.Model(batch).Column("institution_id").Where("id = ?", resourceID).Select()
+		id = batch.InstitutionID
+	case "SavedSearch":
+		search := &SavedSearch{}
+		err = db.Model(search).Column("institution_id").Where("id = ?", resourceID).Select()
+		id = search.InstitutionID
+	case "StoragePrice":
+		// Storage prices don't belong to any institution. This just
+		// checks that the price exists.
+		price := &StoragePrice{}
+		err = db.Model(price).Column("id").Where("id = ?", resourceID).Select()
 	case "StorageRecord":
 		sr := &StorageRecord{}
 		err = db.Model(sr).Column("_").Relation("GenericFile.institution_id").Where(`"storage_record"."id" = ?`, resourceID).Select()
@@ -121,6 +157,28 @@ func InstIDFor(resourceType string, resourceID int64) (id int64, err error) {
 		user := &User{}
 		err = db.Model(user).Column("institution_id").Where("id = ?", resourceID).Select()
 		id = user.InstitutionID
+	case "UserSession":
+		session := &UserSession{}
+		err = db.Model(session).Column("_").Relation("User.institution_id").Where(`"user_session"."id" = ?`, resourceID).Select()
+		if session != nil && session.User != nil {
+			id = session.User.InstitutionID
+		}
+	case "WebAuthnCredential":
+		cred := &WebAuthnCredential{}
+		err = db.Model(cred).Column("_").Relation("User.institution_id").Where(`"webauthn_credential"."id" = ?`, resourceID).Select()
+		if cred != nil && cred.User != nil {
+			id = cred.User.InstitutionID
+		}
+	case "Webhook":
+		webhook := &Webhook{}
+		err = db.Model(webhook).Column("institution_id").Where("id = ?", resourceID).Select()
+		id = webhook.InstitutionID
+	case "WebhookDelivery":
+		delivery := &WebhookDelivery{}
+		err = db.Model(delivery).Column("_").Relation("Webhook.institution_id").Where(`"webhook_delivery"."id" = ?`, resourceID).Select()
+		if delivery != nil && delivery.Webhook != nil {
+			id = delivery.Webhook.InstitutionID
+		}
 	case "WorkItem":
 		item := &WorkItem{}
 		err = db.Model(item).Column("institution_id").Where("id = ?", resourceID).Select()
@@ -146,13 +204,21 @@ func FiltersFor(typeName string) []string {
 func initFilters() {
 	filters = make(map[string][]string)
 	filters["Alert"] = AlertFilters
+	filters["AuditEvent"] = AuditEventFilters
 	filters["Checksum"] = ChecksumFilters
 	filters["DeletionRequest"] = DeletionRequestFilters
 	filters["DepositStats"] = DepositStatsFilters
+	filters["FixityStats"] = FixityStatsFilters
+	filters["FormatRisk"] = FormatRiskFilters
+	filters["FormatRiskObject"] = FormatRiskObjectFilters
+	filters["FormatRiskStats"] = FormatRiskStatsFilters
 	filters["GenericFile"] = GenericFileFilters
 	filters["IntellectualObject"] = IntellectualObjectFilters
+	filters["Invoice"] = InvoiceFilters
 	filters["Institution"] = InstitutionFilters
 	filters["PremisEvent"] = PremisEventFilters
+	filters["ReplicationProblem"] = ReplicationProblemFilters
+	filters["SearchResult"] = SearchFilters
 	filters["StorageRecord"] = StorageRecordFilters
 	filters["User"] = UserFilters
 	filters["WorkItem"] = WorkItemFilters
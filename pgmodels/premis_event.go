@@ -1,6 +1,8 @@
 package pgmodels
 
 import (
+	"fmt"
+	"net/url"
 	"time"
 
 	"github.com/APTrust/registry/common"
@@ -127,3 +129,90 @@ func (event *PremisEvent) Validate() *common.ValidationError {
 func ObjectEventCount(intellectualObjectID int64) (int, error) {
 	return common.Context().DB.Model((*PremisEvent)(nil)).Where(`intellectual_object_id = ? and generic_file_id is null`, intellectualObjectID).Count()
 }
+
+// IsFailedFixityCheck returns true if this event records a fixity
+// check that failed.
+func (event *PremisEvent) IsFailedFixityCheck() bool {
+	return event.EventType == constants.EventFixityCheck && event.Outcome == constants.OutcomeFailure
+}
+
+// AlertOnFailedFixity creates a Failed Fixity Check alert if this event
+// records a failed fixity check. The alert goes to the institutional
+// admins at the institution that owns the file, and to APTrust admins.
+//
+// To avoid flooding inboxes when many files in a single object fail,
+// we create at most one failed fixity alert per object per day. If an
+// alert for this event's object was already created today, we link this
+// event to the existing alert instead of creating a new one, and we
+// don't send another email.
+//
+// This returns nil and no error if the event is not a failed fixity
+// check.
+func (event *PremisEvent) AlertOnFailedFixity() (*Alert, error) {
+	if !event.IsFailedFixityCheck() {
+		return nil, nil
+	}
+	ctx := common.Context()
+	now := time.Now().UTC()
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	existingAlert, err := failedFixityAlertForObject(event.IntellectualObjectID, startOfDay)
+	if err != nil {
+		return nil, err
+	}
+	if existingAlert != nil {
+		existingAlert.PremisEvents = append(existingAlert.PremisEvents, event)
+		err = existingAlert.Save()
+		if err == nil {
+			ctx.Log.Info().Msgf("Added failed fixity event %d to existing alert %d", event.ID, existingAlert.ID)
+		}
+		return existingAlert, err
+	}
+
+	instAdmins, err := ActiveInstAdmins(event.InstitutionID)
+	if err != nil {
+		return nil, err
+	}
+	aptrustAdmins, err := ActiveAPTrustAdmins()
+	if err != nil {
+		return nil, err
+	}
+
+	registryURL := fmt.Sprintf("%s://%s", ctx.Config.HTTPScheme(), ctx.Config.Cookies.Domain)
+	params := url.Values{}
+	params.Set("intellectual_object_id", fmt.Sprintf("%d", event.IntellectualObjectID))
+	params.Set("event_type", constants.EventFixityCheck)
+	params.Set("outcome", constants.OutcomeFailure)
+	alertData := map[string]interface{}{
+		"AlertURL": fmt.Sprintf("%s/events?%s", registryURL, params.Encode()),
+	}
+	alert := &Alert{
+		InstitutionID: event.InstitutionID,
+		Type:          constants.AlertFailedFixity,
+		Subject:       constants.AlertFailedFixity,
+		PremisEvents:  []*PremisEvent{event},
+		Users:         append(instAdmins, aptrustAdmins...),
+	}
+	fixityAlert, err := CreateAlert(alert, "alerts/failed_fixity.txt", alertData)
+	if err == nil {
+		ctx.Log.Info().Msgf("Created failed fixity alert %d for event %d going to %d users", fixityAlert.ID, event.ID, len(alert.Users))
+	}
+	return fixityAlert, err
+}
+
+// failedFixityAlertForObject returns the first failed fixity alert
+// created on or after the specified time that includes an event
+// belonging to the specified object. Returns nil if there is no
+// such alert.
+func failedFixityAlertForObject(objID int64, since time.Time) (*Alert, error) {
+	var alertIDs []int64
+	sql := `select distinct a.id from alerts a
+		inner join alerts_premis_events ape on ape.alert_id = a.id
+		inner join premis_events pe on pe.id = ape.premis_event_id
+		where a.type = ? and a.created_at >= ? and pe.intellectual_object_id = ?
+		order by a.id limit 1`
+	_, err := common.Context().DB.Query(&alertIDs, sql, constants.AlertFailedFixity, since, objID)
+	if err != nil || len(alertIDs) == 0 {
+		return nil, err
+	}
+	return AlertByID(alertIDs[0])
+}
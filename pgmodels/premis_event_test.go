@@ -137,3 +137,73 @@ func TestEventValidate(t *testing.T) {
 	valErr = event.Validate()
 	require.Nil(t, valErr)
 }
+
+func TestAlertOnFailedFixity(t *testing.T) {
+	db.LoadFixtures()
+	gf, err := pgmodels.GenericFileByID(21)
+	require.Nil(t, err)
+	require.NotNil(t, gf)
+
+	// Successful fixity checks should not generate alerts.
+	event := pgmodels.RandomPremisEvent(constants.EventFixityCheck)
+	event.GenericFileID = gf.ID
+	event.IntellectualObjectID = gf.IntellectualObjectID
+	event.InstitutionID = gf.InstitutionID
+	event.Outcome = constants.OutcomeSuccess
+	require.Nil(t, event.Save())
+	assert.False(t, event.IsFailedFixityCheck())
+	alert, err := event.AlertOnFailedFixity()
+	assert.Nil(t, err)
+	assert.Nil(t, alert)
+
+	// Failed fixity checks should.
+	event = pgmodels.RandomPremisEvent(constants.EventFixityCheck)
+	event.GenericFileID = gf.ID
+	event.IntellectualObjectID = gf.IntellectualObjectID
+	event.InstitutionID = gf.InstitutionID
+	event.Outcome = constants.OutcomeFailure
+	require.Nil(t, event.Save())
+	assert.True(t, event.IsFailedFixityCheck())
+	alert, err = event.AlertOnFailedFixity()
+	require.Nil(t, err)
+	require.NotNil(t, alert)
+	assert.True(t, alert.ID > 0)
+	assert.Equal(t, constants.AlertFailedFixity, alert.Type)
+	assert.Equal(t, gf.InstitutionID, alert.InstitutionID)
+	assert.Contains(t, alert.Content, "/events?")
+	require.Equal(t, 1, len(alert.PremisEvents))
+
+	// Recipients should be inst admins and APTrust admins.
+	foundInstAdmin := false
+	foundSysAdmin := false
+	for _, user := range alert.Users {
+		if user.Role == constants.RoleInstAdmin {
+			assert.Equal(t, gf.InstitutionID, user.InstitutionID)
+			foundInstAdmin = true
+		} else if user.Role == constants.RoleSysAdmin {
+			foundSysAdmin = true
+		}
+	}
+	assert.True(t, foundInstAdmin)
+	assert.True(t, foundSysAdmin)
+
+	// A second failure in the same object on the same day
+	// should be added to the existing alert.
+	gf2, err := pgmodels.GenericFileByID(22)
+	require.Nil(t, err)
+	require.Equal(t, gf.IntellectualObjectID, gf2.IntellectualObjectID)
+	event2 := pgmodels.RandomPremisEvent(constants.EventFixityCheck)
+	event2.GenericFileID = gf2.ID
+	event2.IntellectualObjectID = gf2.IntellectualObjectID
+	event2.InstitutionID = gf2.InstitutionID
+	event2.Outcome = constants.OutcomeFailure
+	require.Nil(t, event2.Save())
+	alert2, err := event2.AlertOnFailedFixity()
+	require.Nil(t, err)
+	require.NotNil(t, alert2)
+	assert.Equal(t, alert.ID, alert2.ID)
+
+	alert, err = pgmodels.AlertByID(alert.ID)
+	require.Nil(t, err)
+	assert.Equal(t, 2, len(alert.PremisEvents))
+}
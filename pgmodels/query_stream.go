@@ -0,0 +1,77 @@
+package pgmodels
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/APTrust/registry/common"
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+)
+
+// StreamBatchSize is the number of rows Query.Stream fetches from
+// its server-side cursor at a time.
+const StreamBatchSize = 500
+
+// Stream runs this query through a server-side cursor, loading up to
+// batchSize rows at a time into model, which must be a pointer to a
+// slice of pointers. It calls fn after each batch is loaded. The
+// slice is reset before every fetch, so fn should finish with the
+// batch's items before returning.
+//
+// Use this instead of Select when the result set may be too large
+// to page through with limit and offset, as in CSV and JSON-lines
+// exports. Stream ignores limit and offset. It does not support
+// has-many relations, since go-pg loads those with separate queries.
+//
+// If fn returns an error, Stream stops and returns that error.
+func (q *Query) Stream(model interface{}, batchSize int, fn func() error) error {
+	if model == nil || !strings.HasPrefix(reflect.TypeOf(model).String(), "*[]*") {
+		return common.ErrInvalidParam
+	}
+	if batchSize < 1 {
+		batchSize = StreamBatchSize
+	}
+	db := common.Context().DB
+	return db.RunInTransaction(db.Context(), func(tx *pg.Tx) error {
+		ormQuery := tx.Model(model)
+		if !common.ListIsEmpty(q.GetColumns()) {
+			ormQuery.Column(q.GetColumns()...)
+		}
+		if q.WhereClause() != "" {
+			ormQuery.Where(q.WhereClause(), q.Params()...)
+		}
+		for _, orderBy := range q.GetOrderBy() {
+			ormQuery.Order(orderBy)
+		}
+		selectSQL, err := orm.NewSelectQuery(ormQuery).AppendQuery(tx.Formatter(), nil)
+		if err != nil {
+			return err
+		}
+
+		// The select statement is already formatted, and a statement
+		// with no params passes through the formatter untouched.
+		_, err = tx.Exec("declare registry_stream no scroll cursor for " + string(selectSQL))
+		if err != nil {
+			return err
+		}
+		fetch := fmt.Sprintf("fetch %d from registry_stream", batchSize)
+		items := reflect.ValueOf(model).Elem()
+		for {
+			items.Set(reflect.MakeSlice(items.Type(), 0, batchSize))
+			result, err := tx.Query(model, fetch)
+			if err != nil {
+				return err
+			}
+			if result.RowsReturned() == 0 {
+				break
+			}
+			if err = fn(); err != nil {
+				return err
+			}
+		}
+		_, err = tx.Exec("close registry_stream")
+		return err
+	})
+}
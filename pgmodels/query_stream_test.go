@@ -0,0 +1,58 @@
+package pgmodels_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryStream(t *testing.T) {
+	db.LoadFixtures()
+
+	query := pgmodels.NewQuery().
+		Where("institution_id", "=", 2).
+		OrderBy("id", "asc")
+	expected, err := pgmodels.IntellectualObjectViewSelect(query)
+	require.Nil(t, err)
+	require.True(t, len(expected) > 2)
+
+	// Small batch size forces several fetches.
+	var objects []*pgmodels.IntellectualObjectView
+	batchSizes := make([]int, 0)
+	streamed := make([]int64, 0)
+	err = query.Stream(&objects, 2, func() error {
+		batchSizes = append(batchSizes, len(objects))
+		for _, obj := range objects {
+			streamed = append(streamed, obj.ID)
+		}
+		return nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, len(expected), len(streamed))
+	for i, obj := range expected {
+		assert.Equal(t, obj.ID, streamed[i])
+	}
+	for _, size := range batchSizes {
+		assert.True(t, size > 0 && size <= 2)
+	}
+
+	// Stream stops at the first error from the callback.
+	calls := 0
+	err = query.Stream(&objects, 2, func() error {
+		calls++
+		return fmt.Errorf("stop")
+	})
+	require.NotNil(t, err)
+	assert.Equal(t, "stop", err.Error())
+	assert.Equal(t, 1, calls)
+
+	// Model must be a pointer to a slice of pointers.
+	var notASlice pgmodels.IntellectualObjectView
+	err = query.Stream(&notASlice, 2, func() error { return nil })
+	assert.Equal(t, common.ErrInvalidParam, err)
+}
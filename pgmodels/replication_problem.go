@@ -0,0 +1,195 @@
+package pgmodels
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/go-pg/pg/v10"
+	"github.com/stretchr/stew/slice"
+)
+
+// replicationBatchSize is the number of files VerifyStorageReplication
+// loads and checks at a time.
+const replicationBatchSize = 1000
+
+var ReplicationProblemFilters = []string{
+	"identifier__starts_with",
+	"institution_id",
+	"problem",
+	"storage_option",
+}
+
+// ReplicationProblem describes one thing wrong with an active file's
+// storage records, as found by the most recent storage replication check.
+// Problem is one of constants.ReplicationProblems. Bucket is the bucket
+// that's missing a copy, holds a duplicate, or shouldn't hold a copy at
+// all. URL is the offending storage record's URL, and is empty for
+// missing copies.
+//
+// The replication check replaces all of these records each time it
+// runs. See VerifyStorageReplication.
+type ReplicationProblem struct {
+	ID                   int64     `json:"id" pg:"id"`
+	GenericFileID        int64     `json:"generic_file_id"`
+	IntellectualObjectID int64     `json:"intellectual_object_id"`
+	InstitutionID        int64     `json:"institution_id"`
+	Identifier           string    `json:"identifier"`
+	StorageOption        string    `json:"storage_option"`
+	Problem              string    `json:"problem"`
+	Bucket               string    `json:"bucket"`
+	URL                  string    `json:"url" pg:"url"`
+	VerifiedAt           time.Time `json:"verified_at"`
+}
+
+// replicationFile is an active file along with the URLs of its
+// storage records.
+type replicationFile struct {
+	ID                   int64
+	IntellectualObjectID int64
+	InstitutionID        int64
+	Identifier           string
+	StorageOption        string
+	URLs                 []string `pg:"urls,array"`
+}
+
+var replicationFileQuery = `select
+	gf.id,
+	gf.intellectual_object_id,
+	gf.institution_id,
+	gf.identifier,
+	gf.storage_option,
+	array_remove(array_agg(sr.url order by sr.id), null) as urls
+	from generic_files gf
+	left join storage_records sr on sr.generic_file_id = gf.id
+	where gf.state = ?
+	and gf.id > ?
+	group by gf.id
+	order by gf.id
+	limit ?`
+
+// ReplicationProblemByID returns the replication problem with the
+// specified id. Returns pg.ErrNoRows if there is no match.
+func ReplicationProblemByID(id int64) (*ReplicationProblem, error) {
+	query := NewQuery().Where("id", "=", id)
+	return ReplicationProblemGet(query)
+}
+
+// ReplicationProblemGet returns the first replication problem matching
+// the query.
+func ReplicationProblemGet(query *Query) (*ReplicationProblem, error) {
+	var problem ReplicationProblem
+	err := query.Select(&problem)
+	return &problem, err
+}
+
+// ReplicationProblemSelect returns all replication problems matching
+// the query.
+func ReplicationProblemSelect(query *Query) ([]*ReplicationProblem, error) {
+	var problems []*ReplicationProblem
+	err := query.Select(&problems)
+	return problems, err
+}
+
+// VerifyStorageReplication compares each active file's storage records
+// with the buckets configured for its storage option and replaces the
+// contents of the replication_problems table with what it finds. Files
+// in storage options that have no buckets configured are skipped.
+// This returns the number of problems found.
+//
+// The check runs in a single transaction, so the report never shows
+// a half-finished check.
+func VerifyStorageReplication() (int, error) {
+	buckets := common.Context().Config.Replication.Buckets
+	verifiedAt := time.Now().UTC()
+	count := 0
+	db := common.Context().DB
+	err := db.RunInTransaction(db.Context(), func(tx *pg.Tx) error {
+		_, err := tx.Exec("delete from replication_problems")
+		if err != nil {
+			return err
+		}
+		lastID := int64(0)
+		for {
+			var files []*replicationFile
+			_, err = tx.Query(&files, replicationFileQuery, constants.StateActive, lastID, replicationBatchSize)
+			if err != nil {
+				return err
+			}
+			problems := make([]*ReplicationProblem, 0)
+			for _, file := range files {
+				expected, ok := buckets[file.StorageOption]
+				if ok {
+					problems = append(problems, file.replicationProblems(expected, verifiedAt)...)
+				}
+			}
+			if len(problems) > 0 {
+				_, err = tx.Model(&problems).Insert()
+				if err != nil {
+					return err
+				}
+				count += len(problems)
+			}
+			if len(files) < replicationBatchSize {
+				return nil
+			}
+			lastID = files[len(files)-1].ID
+		}
+	})
+	return count, err
+}
+
+// replicationProblems returns the problems with this file's storage
+// records, given the buckets that should hold its copies.
+func (file *replicationFile) replicationProblems(expected []string, verifiedAt time.Time) []*ReplicationProblem {
+	problems := make([]*ReplicationProblem, 0)
+	copies := make(map[string]int)
+	for _, storageURL := range file.URLs {
+		bucket := BucketFromURL(storageURL)
+		copies[bucket]++
+		problem := ""
+		if !slice.Contains(expected, bucket) {
+			problem = constants.ReplicationUnexpectedCopy
+		} else if copies[bucket] > 1 {
+			problem = constants.ReplicationDuplicateCopy
+		}
+		if problem != "" {
+			problems = append(problems, file.newProblem(problem, bucket, storageURL, verifiedAt))
+		}
+	}
+	for _, bucket := range expected {
+		if copies[bucket] == 0 {
+			problems = append(problems, file.newProblem(constants.ReplicationMissingCopy, bucket, "", verifiedAt))
+		}
+	}
+	return problems
+}
+
+func (file *replicationFile) newProblem(problem, bucket, storageURL string, verifiedAt time.Time) *ReplicationProblem {
+	return &ReplicationProblem{
+		GenericFileID:        file.ID,
+		IntellectualObjectID: file.IntellectualObjectID,
+		InstitutionID:        file.InstitutionID,
+		Identifier:           file.Identifier,
+		StorageOption:        file.StorageOption,
+		Problem:              problem,
+		Bucket:               bucket,
+		URL:                  storageURL,
+		VerifiedAt:           verifiedAt,
+	}
+}
+
+// BucketFromURL returns the name of the bucket in a storage record URL.
+// Our preservation services record path-style URLs, in which the bucket
+// is the first segment of the path, as in
+// https://s3.amazonaws.com/bucket/key. This returns an empty string if
+// the URL can't be parsed.
+func BucketFromURL(storageURL string) string {
+	parsed, err := url.Parse(storageURL)
+	if err != nil {
+		return ""
+	}
+	return strings.SplitN(strings.TrimPrefix(parsed.Path, "/"), "/", 2)[0]
+}
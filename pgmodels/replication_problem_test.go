@@ -0,0 +1,72 @@
+package pgmodels_test
+
+import (
+	"testing"
+
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketFromURL(t *testing.T) {
+	assert.Equal(t, "preservation-va", pgmodels.BucketFromURL("https://localhost:9899/preservation-va/25452f41-1b18-47b7-b334-751dfd5d011e"))
+	assert.Equal(t, "aptrust.preservation.or", pgmodels.BucketFromURL("https://s3.us-west-2.localhost:9899/aptrust.preservation.or/2a3ad959"))
+	assert.Equal(t, "bucket", pgmodels.BucketFromURL("https://example.com/bucket"))
+	assert.Equal(t, "", pgmodels.BucketFromURL("https://example.com"))
+	assert.Equal(t, "", pgmodels.BucketFromURL("://no scheme"))
+}
+
+func TestVerifyStorageReplication(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+
+	// In the fixtures, files 28-49 are Standard files with no copy
+	// in Oregon, and files 53 and 54 are Glacier-OR files stored in
+	// the wrong bucket.
+	count, err := pgmodels.VerifyStorageReplication()
+	require.Nil(t, err)
+	assert.Equal(t, 26, count)
+
+	query := pgmodels.NewQuery().Where("generic_file_id", "=", 28)
+	problems, err := pgmodels.ReplicationProblemSelect(query)
+	require.Nil(t, err)
+	require.Len(t, problems, 1)
+	assert.Equal(t, constants.ReplicationMissingCopy, problems[0].Problem)
+	assert.Equal(t, "preservation-or", problems[0].Bucket)
+	assert.Empty(t, problems[0].URL)
+	assert.Equal(t, "institution2.edu/toads/toad5", problems[0].Identifier)
+	assert.EqualValues(t, 3, problems[0].InstitutionID)
+	assert.False(t, problems[0].VerifiedAt.IsZero())
+
+	query = pgmodels.NewQuery().Where("generic_file_id", "=", 53).OrderBy("problem", "asc")
+	problems, err = pgmodels.ReplicationProblemSelect(query)
+	require.Nil(t, err)
+	require.Len(t, problems, 2)
+	assert.Equal(t, constants.ReplicationMissingCopy, problems[0].Problem)
+	assert.Equal(t, "aptrust.preservation.glacier-or", problems[0].Bucket)
+	assert.Equal(t, constants.ReplicationUnexpectedCopy, problems[1].Problem)
+	assert.Equal(t, "aptrust.preservation.oregon", problems[1].Bucket)
+	assert.NotEmpty(t, problems[1].URL)
+
+	// A second copy in the same bucket is a duplicate. Running the
+	// check again replaces the old results.
+	sr := &pgmodels.StorageRecord{
+		GenericFileID: 1,
+		URL:           "https://localhost:9899/preservation-va/duplicate-copy",
+	}
+	require.Nil(t, sr.Save())
+	count, err = pgmodels.VerifyStorageReplication()
+	require.Nil(t, err)
+	assert.Equal(t, 27, count)
+
+	problem, err := pgmodels.ReplicationProblemGet(pgmodels.NewQuery().Where("generic_file_id", "=", 1))
+	require.Nil(t, err)
+	assert.Equal(t, constants.ReplicationDuplicateCopy, problem.Problem)
+	assert.Equal(t, sr.URL, problem.URL)
+
+	problem, err = pgmodels.ReplicationProblemByID(problem.ID)
+	require.Nil(t, err)
+	assert.EqualValues(t, 1, problem.GenericFileID)
+}
@@ -0,0 +1,331 @@
+package pgmodels
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+)
+
+const (
+	ErrRestorationBatchInstitutionID = "Restoration batch requires institution id."
+	ErrRestorationBatchRequesterID   = "Restoration batch requires requester id."
+	ErrRestorationBatchWrongInst     = "Restoration batch user belongs to wrong institution."
+	ErrRestorationBatchIllegalItem   = "Restoration batch cannot include items belonging to other institutions."
+)
+
+func init() {
+	orm.RegisterTable((*RestorationBatchesWorkItems)(nil))
+}
+
+// RestorationBatch groups the restoration WorkItems created when a
+// user asks to restore many objects at once. Each object gets its own
+// WorkItem, which Preserv processes independently. The batch lets us
+// show the user overall progress, and send a single Restoration
+// Completed alert when every item is done.
+//
+// CompletedAt is set when the last item in the batch completes.
+// It's zero until then.
+type RestorationBatch struct {
+	TimestampModel
+	InstitutionID int64       `json:"institution_id"`
+	RequestedByID int64       `json:"requested_by_id"`
+	CompletedAt   time.Time   `json:"completed_at"`
+	RequestedBy   *User       `json:"requested_by" pg:"rel:has-one"`
+	WorkItems     []*WorkItem `json:"work_items" pg:"many2many:restoration_batches_work_items"`
+}
+
+type RestorationBatchesWorkItems struct {
+	tableName          struct{} `pg:"restoration_batches_work_items"`
+	RestorationBatchID int64
+	WorkItemID         int64
+}
+
+// RestorationBatchProgress summarizes the state of the WorkItems
+// in a RestorationBatch.
+type RestorationBatchProgress struct {
+	Total     int
+	Completed int
+	Succeeded int
+	Failed    int
+	Cancelled int
+}
+
+// Pending returns the number of items that have not yet completed.
+func (p *RestorationBatchProgress) Pending() int {
+	return p.Total - p.Completed
+}
+
+// AllCancelled returns true if every item was cancelled, so
+// nothing was restored.
+func (p *RestorationBatchProgress) AllCancelled() bool {
+	return p.Total > 0 && p.Cancelled == p.Total
+}
+
+// PercentComplete returns the percentage of items that have completed,
+// whether successfully or not.
+func (p *RestorationBatchProgress) PercentComplete() int {
+	if p.Total == 0 {
+		return 0
+	}
+	return p.Completed * 100 / p.Total
+}
+
+// NewRestorationBatch returns a new, empty RestorationBatch requested
+// by the specified user.
+func NewRestorationBatch(user *User) *RestorationBatch {
+	return &RestorationBatch{
+		InstitutionID: user.InstitutionID,
+		RequestedByID: user.ID,
+		RequestedBy:   user,
+		WorkItems:     make([]*WorkItem, 0),
+	}
+}
+
+// RestorationBatchByID returns the RestorationBatch with the specified
+// id, along with its requester and WorkItems.
+// Returns pg.ErrNoRows if there is no match.
+func RestorationBatchByID(id int64) (*RestorationBatch, error) {
+	query := NewQuery().Relations("RequestedBy", "WorkItems").Where(`"restoration_batch"."id"`, "=", id)
+	return RestorationBatchGet(query)
+}
+
+// RestorationBatchGet returns the first RestorationBatch matching
+// the query.
+func RestorationBatchGet(query *Query) (*RestorationBatch, error) {
+	var batch RestorationBatch
+	err := query.Select(&batch)
+	return &batch, err
+}
+
+// RestorationBatchSelect returns all RestorationBatches matching
+// the query.
+func RestorationBatchSelect(query *Query) ([]*RestorationBatch, error) {
+	var batches []*RestorationBatch
+	err := query.Select(&batches)
+	return batches, err
+}
+
+// RestorationBatchForWorkItem returns the RestorationBatch that includes
+// the specified WorkItem, or nil if the WorkItem is not part of a batch.
+// Most restorations are not.
+func RestorationBatchForWorkItem(workItemID int64) (*RestorationBatch, error) {
+	var batchID int64
+	_, err := common.Context().DB.QueryOne(pg.Scan(&batchID),
+		"select restoration_batch_id from restoration_batches_work_items where work_item_id = ? limit 1",
+		workItemID)
+	if IsNoRowError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return RestorationBatchByID(batchID)
+}
+
+// AddWorkItem adds a restoration WorkItem to this batch. If the
+// WorkItem is new, it will be inserted when the batch is saved.
+func (batch *RestorationBatch) AddWorkItem(item *WorkItem) {
+	if batch.WorkItems == nil {
+		batch.WorkItems = make([]*WorkItem, 0)
+	}
+	batch.WorkItems = append(batch.WorkItems, item)
+}
+
+// Save saves this batch and links its WorkItems to it. This will
+// perform an insert if RestorationBatch.ID is zero. Otherwise,
+// it updates. New WorkItems are inserted in the same transaction,
+// so if anything fails, we're left with no batch and no orphaned
+// WorkItems. This does not update WorkItems that were already saved.
+func (batch *RestorationBatch) Save() error {
+	batch.SetTimestamps()
+	err := batch.Validate()
+	if err != nil {
+		return err
+	}
+	registryContext := common.Context()
+	db := registryContext.DB
+	return db.RunInTransaction(db.Context(), func(tx *pg.Tx) error {
+		var err error
+		if batch.ID == 0 {
+			_, err = tx.Model(batch).Insert()
+		} else {
+			_, err = tx.Model(batch).WherePK().Update()
+		}
+		if err != nil {
+			registryContext.Log.Error().Msgf("Transaction failed. Model: %v. Error: %v", batch, err)
+			return err
+		}
+		return batch.saveWorkItems(tx)
+	})
+}
+
+func (batch *RestorationBatch) saveWorkItems(tx *pg.Tx) error {
+	// Note: on conflict refers to unique index index_rbwi_unique
+	sql := "insert into restoration_batches_work_items (restoration_batch_id, work_item_id) values (?, ?) on conflict do nothing"
+	for _, item := range batch.WorkItems {
+		if item.ID == 0 {
+			item.SetTimestamps()
+			if err := item.Validate(); err != nil {
+				return err
+			}
+			if _, err := tx.Model(item).Insert(); err != nil {
+				return err
+			}
+		}
+		_, err := tx.Exec(sql, batch.ID, item.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate returns errors if this batch is not valid.
+func (batch *RestorationBatch) Validate() *common.ValidationError {
+	errors := make(map[string]string)
+	if batch.InstitutionID < 1 {
+		errors["InstitutionID"] = ErrRestorationBatchInstitutionID
+	}
+	if batch.RequestedByID < 1 {
+		errors["RequestedByID"] = ErrRestorationBatchRequesterID
+	} else if batch.RequestedBy != nil && batch.RequestedBy.InstitutionID != batch.InstitutionID {
+		errors["RequestedByID"] = ErrRestorationBatchWrongInst
+	}
+	for _, item := range batch.WorkItems {
+		if item.InstitutionID != batch.InstitutionID {
+			errors["WorkItems"] = ErrRestorationBatchIllegalItem
+			break
+		}
+	}
+	if len(errors) > 0 {
+		return &common.ValidationError{Errors: errors}
+	}
+	return nil
+}
+
+// Progress returns a summary of the WorkItems in this batch.
+func (batch *RestorationBatch) Progress() *RestorationBatchProgress {
+	progress := &RestorationBatchProgress{Total: len(batch.WorkItems)}
+	for _, item := range batch.WorkItems {
+		if !item.HasCompleted() {
+			continue
+		}
+		progress.Completed++
+		switch item.Status {
+		case constants.StatusSuccess:
+			progress.Succeeded++
+		case constants.StatusFailed:
+			progress.Failed++
+		case constants.StatusCancelled:
+			progress.Cancelled++
+		}
+	}
+	return progress
+}
+
+// IsComplete returns true if every WorkItem in this batch has
+// completed, successfully or otherwise.
+func (batch *RestorationBatch) IsComplete() bool {
+	progress := batch.Progress()
+	return progress.Total > 0 && progress.Pending() == 0
+}
+
+// AlertIfComplete sends a single Restoration Completed alert to the
+// user who requested this batch and to their institution's admins,
+// if every WorkItem in the batch has completed. If every item was
+// cancelled, the alert says the batch was cancelled instead.
+//
+// We create the alert and mark the batch complete in one transaction,
+// which locks the batch row. That way, we send the alert only once,
+// even if the last two items finish at the same time, and if creating
+// the alert fails, the batch stays incomplete so the next completed
+// item can try again.
+//
+// This returns the alert if it created one, or nil if the batch is not
+// yet complete or was already marked complete.
+func (batch *RestorationBatch) AlertIfComplete() (*Alert, error) {
+	if !batch.IsComplete() {
+		return nil, nil
+	}
+	var alert *Alert
+	db := common.Context().DB
+	err := db.RunInTransaction(db.Context(), func(tx *pg.Tx) error {
+		// Only one caller gets to mark the batch complete. Others
+		// wait here until we commit, then find it already complete.
+		var id int64
+		_, err := tx.QueryOne(pg.Scan(&id),
+			"select id from restoration_batches where id = ? and completed_at is null for update",
+			batch.ID)
+		if IsNoRowError(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		alert, err = batch.createCompletionAlert()
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("update restoration_batches set completed_at = now(), updated_at = now() where id = ?", batch.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if alert != nil {
+		batch.CompletedAt = time.Now().UTC()
+	}
+	return alert, nil
+}
+
+// createCompletionAlert creates and sends the alert for AlertIfComplete.
+func (batch *RestorationBatch) createCompletionAlert() (*Alert, error) {
+	recipients, err := ActiveInstAdmins(batch.InstitutionID)
+	if err != nil {
+		return nil, err
+	}
+	requester := batch.RequestedBy
+	if requester == nil {
+		requester, err = UserByID(batch.RequestedByID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	alreadyIncluded := false
+	for _, user := range recipients {
+		if user.ID == requester.ID {
+			alreadyIncluded = true
+			break
+		}
+	}
+	if !alreadyIncluded && requester.DeactivatedAt.IsZero() {
+		recipients = append(recipients, requester)
+	}
+
+	ctx := common.Context()
+	progress := batch.Progress()
+	registryURL := fmt.Sprintf("%s://%s", ctx.Config.HTTPScheme(), ctx.Config.Cookies.Domain)
+	alertData := map[string]interface{}{
+		"RequesterName": requester.Name,
+		"Progress":      progress,
+		"WorkItems":     batch.WorkItems,
+		"BatchURL":      fmt.Sprintf("%s/restoration_batches/show/%d", registryURL, batch.ID),
+	}
+	subject := "Batch Restoration Completed"
+	if progress.AllCancelled() {
+		subject = "Batch Restoration Cancelled"
+	}
+	alert := &Alert{
+		InstitutionID: batch.InstitutionID,
+		Type:          constants.AlertRestorationCompleted,
+		Subject:       subject,
+		CreatedAt:     time.Now().UTC(),
+		Users:         recipients,
+		WorkItems:     batch.WorkItems,
+	}
+	return CreateAlert(alert, "alerts/restoration_batch_completed.txt", alertData)
+}
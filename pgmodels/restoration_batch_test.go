@@ -0,0 +1,206 @@
+package pgmodels_test
+
+import (
+	"testing"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestorationBatchValidate(t *testing.T) {
+	batch := &pgmodels.RestorationBatch{}
+	err := batch.Validate()
+	require.NotNil(t, err)
+	assert.Equal(t, pgmodels.ErrRestorationBatchInstitutionID, err.Errors["InstitutionID"])
+	assert.Equal(t, pgmodels.ErrRestorationBatchRequesterID, err.Errors["RequestedByID"])
+
+	user := &pgmodels.User{InstitutionID: 2}
+	user.ID = 3
+	batch = pgmodels.NewRestorationBatch(user)
+	assert.Nil(t, batch.Validate())
+
+	batch.RequestedBy.InstitutionID = 3
+	err = batch.Validate()
+	require.NotNil(t, err)
+	assert.Equal(t, pgmodels.ErrRestorationBatchWrongInst, err.Errors["RequestedByID"])
+
+	batch.RequestedBy.InstitutionID = 2
+	batch.AddWorkItem(pgmodels.RandomWorkItem("bag.tar", constants.ActionRestoreObject, 1, 0))
+	err = batch.Validate()
+	require.NotNil(t, err)
+	assert.Equal(t, pgmodels.ErrRestorationBatchIllegalItem, err.Errors["WorkItems"])
+}
+
+func TestRestorationBatchProgress(t *testing.T) {
+	batch := &pgmodels.RestorationBatch{}
+	assert.False(t, batch.IsComplete())
+	assert.Equal(t, 0, batch.Progress().PercentComplete())
+
+	statuses := []string{
+		constants.StatusSuccess,
+		constants.StatusSuccess,
+		constants.StatusFailed,
+		constants.StatusCancelled,
+		constants.StatusStarted,
+	}
+	for _, status := range statuses {
+		item := pgmodels.RandomWorkItem("bag.tar", constants.ActionRestoreObject, 1, 0)
+		item.Status = status
+		batch.AddWorkItem(item)
+	}
+	progress := batch.Progress()
+	assert.Equal(t, 5, progress.Total)
+	assert.Equal(t, 4, progress.Completed)
+	assert.Equal(t, 2, progress.Succeeded)
+	assert.Equal(t, 1, progress.Failed)
+	assert.Equal(t, 1, progress.Cancelled)
+	assert.Equal(t, 1, progress.Pending())
+	assert.Equal(t, 80, progress.PercentComplete())
+	assert.False(t, progress.AllCancelled())
+	assert.False(t, batch.IsComplete())
+
+	batch.WorkItems[4].Status = constants.StatusSuccess
+	assert.True(t, batch.IsComplete())
+}
+
+func TestRestorationBatchSaveAndAlert(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+	user, err := pgmodels.UserByEmail("user@inst1.edu")
+	require.Nil(t, err)
+
+	countItems := func() int {
+		count, err := common.Context().DB.Model((*pgmodels.WorkItem)(nil)).Count()
+		require.Nil(t, err)
+		return count
+	}
+	itemCount := countItems()
+
+	// The batch inserts new WorkItems in the same transaction as
+	// the batch itself. If any of them is invalid, nothing is saved.
+	failedBatch := pgmodels.NewRestorationBatch(user)
+	valid := pgmodels.RandomWorkItem("photos.tar", constants.ActionRestoreObject, 1, 0)
+	valid.InstitutionID = user.InstitutionID
+	failedBatch.AddWorkItem(valid)
+	failedBatch.AddWorkItem(&pgmodels.WorkItem{})
+	require.NotNil(t, failedBatch.Save())
+	assert.Equal(t, itemCount, countItems())
+
+	// Batches can include both new and existing WorkItems.
+	batch := pgmodels.NewRestorationBatch(user)
+	existing := pgmodels.RandomWorkItem("photos.tar", constants.ActionRestoreObject, 1, 0)
+	existing.InstitutionID = user.InstitutionID
+	require.Nil(t, existing.Save())
+	batch.AddWorkItem(existing)
+	newItem := pgmodels.RandomWorkItem("pdfs.tar", constants.ActionRestoreObject, 2, 0)
+	newItem.InstitutionID = user.InstitutionID
+	batch.AddWorkItem(newItem)
+	require.Nil(t, batch.Save())
+	assert.True(t, newItem.ID > 0)
+	assert.Equal(t, itemCount+2, countItems())
+	require.True(t, batch.ID > 0)
+
+	reloaded, err := pgmodels.RestorationBatchByID(batch.ID)
+	require.Nil(t, err)
+	assert.Equal(t, 2, len(reloaded.WorkItems))
+	assert.Equal(t, user.ID, reloaded.RequestedBy.ID)
+	assert.True(t, reloaded.CompletedAt.IsZero())
+
+	found, err := pgmodels.RestorationBatchForWorkItem(batch.WorkItems[1].ID)
+	require.Nil(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, batch.ID, found.ID)
+
+	found, err = pgmodels.RestorationBatchForWorkItem(999999)
+	require.Nil(t, err)
+	assert.Nil(t, found)
+
+	// Completing the first item should not trigger an alert.
+	first := batch.WorkItems[0]
+	first.Status = constants.StatusSuccess
+	require.Nil(t, first.Save())
+	assert.Nil(t, first.AlertOnCompletedRestorationBatch())
+
+	// Completing the last one should, but only once.
+	last := batch.WorkItems[1]
+	last.Status = constants.StatusFailed
+	require.Nil(t, last.Save())
+
+	reloaded, err = pgmodels.RestorationBatchByID(batch.ID)
+	require.Nil(t, err)
+	assert.False(t, reloaded.CompletedAt.IsZero())
+	assert.Nil(t, last.AlertOnCompletedRestorationBatch())
+
+	query := pgmodels.NewQuery().
+		Where("type", "=", constants.AlertRestorationCompleted).
+		Where("subject", "=", "Batch Restoration Completed").
+		Where("institution_id", "=", user.InstitutionID)
+	alerts, err := pgmodels.AlertSelect(query)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(alerts))
+	assert.Contains(t, alerts[0].Content, "1 were restored successfully, 1 failed")
+	assert.Contains(t, alerts[0].Content, "/restoration_batches/show/")
+}
+
+func TestRestorationBatchAlertRetry(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+	user, err := pgmodels.UserByEmail("user@inst1.edu")
+	require.Nil(t, err)
+
+	batch := pgmodels.NewRestorationBatch(user)
+	for _, objID := range []int64{1, 2} {
+		item := pgmodels.RandomWorkItem("bag.tar", constants.ActionRestoreObject, objID, 0)
+		item.InstitutionID = user.InstitutionID
+		item.Status = constants.StatusSuccess
+		batch.AddWorkItem(item)
+	}
+	require.Nil(t, batch.Save())
+
+	// If we can't create the alert, the batch should not be
+	// marked complete, so we can try again.
+	batch.RequestedBy = nil
+	batch.RequestedByID = 999999
+	alert, err := batch.AlertIfComplete()
+	require.NotNil(t, err)
+	assert.Nil(t, alert)
+	reloaded, err := pgmodels.RestorationBatchByID(batch.ID)
+	require.Nil(t, err)
+	assert.True(t, reloaded.CompletedAt.IsZero())
+
+	alert, err = reloaded.AlertIfComplete()
+	require.Nil(t, err)
+	require.NotNil(t, alert)
+	assert.Equal(t, "Batch Restoration Completed", alert.Subject)
+	reloaded, err = pgmodels.RestorationBatchByID(batch.ID)
+	require.Nil(t, err)
+	assert.False(t, reloaded.CompletedAt.IsZero())
+}
+
+func TestRestorationBatchAlertAllCancelled(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+	user, err := pgmodels.UserByEmail("user@inst1.edu")
+	require.Nil(t, err)
+
+	batch := pgmodels.NewRestorationBatch(user)
+	for _, objID := range []int64{1, 2} {
+		item := pgmodels.RandomWorkItem("bag.tar", constants.ActionRestoreObject, objID, 0)
+		item.InstitutionID = user.InstitutionID
+		item.Status = constants.StatusCancelled
+		batch.AddWorkItem(item)
+	}
+	require.Nil(t, batch.Save())
+	assert.True(t, batch.Progress().AllCancelled())
+
+	alert, err := batch.AlertIfComplete()
+	require.Nil(t, err)
+	require.NotNil(t, alert)
+	assert.Equal(t, "Batch Restoration Cancelled", alert.Subject)
+	assert.Contains(t, alert.Content, "was cancelled. None of the 2 objects")
+	assert.NotContains(t, alert.Content, "is complete")
+}
@@ -0,0 +1,351 @@
+package pgmodels
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/stretchr/stew/slice"
+)
+
+const (
+	ErrSavedSearchUserID        = "UserID is required."
+	ErrSavedSearchInstitutionID = "InstitutionID is required."
+	ErrSavedSearchName          = "Please give this search a name."
+	ErrSavedSearchDuplicate     = "You already have a saved search with this name."
+	ErrSavedSearchResourceType  = "Searches on this page can't be saved."
+	ErrSavedSearchQueryString   = "Choose at least one filter before saving this search."
+)
+
+// SavedSearchPaths maps the resource types users can save searches
+// for to the index pages that list those resources.
+var SavedSearchPaths = map[string]string{
+	"GenericFile":        "/files",
+	"IntellectualObject": "/objects",
+	"PremisEvent":        "/events",
+	"WorkItem":           "/work_items",
+}
+
+// SavedSearch is a named set of filters for one of the index pages
+// in SavedSearchPaths. The owner can pin it to their side nav, share
+// it with the other users at their institution, and subscribe to an
+// email that tells them when the number of matching records changes.
+//
+// QueryString holds the filter and sort params from the index page,
+// without paging params, so the search always starts on page one.
+type SavedSearch struct {
+	TimestampModel
+	UserID        int64     `json:"user_id"`
+	InstitutionID int64     `json:"institution_id"`
+	Name          string    `json:"name"`
+	ResourceType  string    `json:"resource_type"`
+	QueryString   string    `json:"query_string"`
+	Pinned        bool      `json:"pinned" pg:",use_zero"`
+	Shared        bool      `json:"shared" pg:",use_zero"`
+	Notify        bool      `json:"notify" pg:",use_zero"`
+	LastCount     int64     `json:"last_count"`
+	LastCheckedAt time.Time `json:"last_checked_at"`
+	User          *User     `json:"-" pg:"rel:has-one"`
+}
+
+// NewSavedSearch returns a new, unsaved search for user on the index
+// page for resourceType. Param rawQuery is the index page's query
+// string. We keep only the params that page knows how to filter and
+// sort on.
+func NewSavedSearch(user *User, resourceType, rawQuery string) *SavedSearch {
+	return &SavedSearch{
+		UserID:        user.ID,
+		InstitutionID: user.InstitutionID,
+		ResourceType:  resourceType,
+		QueryString:   savedSearchQueryString(resourceType, rawQuery),
+	}
+}
+
+// savedSearchQueryString returns the filter and sort params from
+// rawQuery that apply to resourceType. This drops empty filters
+// and paging params.
+func savedSearchQueryString(resourceType, rawQuery string) string {
+	params, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return ""
+	}
+	allowed := FiltersFor(resourceType)
+	values := url.Values{}
+	for key, vals := range params {
+		if key != "sort" && !slice.Contains(allowed, key) {
+			continue
+		}
+		for _, val := range vals {
+			if strings.TrimSpace(val) != "" {
+				values.Add(key, val)
+			}
+		}
+	}
+	return values.Encode()
+}
+
+// SavedSearchByID returns the saved search with the specified id.
+// Returns pg.ErrNoRows if there is no match.
+func SavedSearchByID(id int64) (*SavedSearch, error) {
+	query := NewQuery().Where("id", "=", id)
+	return SavedSearchGet(query)
+}
+
+// SavedSearchGet returns the first saved search matching the query.
+func SavedSearchGet(query *Query) (*SavedSearch, error) {
+	var search SavedSearch
+	err := query.Select(&search)
+	return &search, err
+}
+
+// SavedSearchSelect returns all saved searches matching the query.
+func SavedSearchSelect(query *Query) ([]*SavedSearch, error) {
+	var searches []*SavedSearch
+	err := query.Select(&searches)
+	return searches, err
+}
+
+// PinnedSearchesFor returns the searches the user has pinned to
+// their side nav, in alphabetical order.
+func PinnedSearchesFor(userID int64) ([]*SavedSearch, error) {
+	query := NewQuery().
+		Where("user_id", "=", userID).
+		Where("pinned", "=", true).
+		OrderBy("name", "asc")
+	return SavedSearchSelect(query)
+}
+
+// SavedSearchesVisibleTo returns the user's own saved searches, plus
+// searches that other users at the same institution have shared,
+// in alphabetical order.
+func SavedSearchesVisibleTo(user *User) ([]*SavedSearch, error) {
+	var searches []*SavedSearch
+	err := common.Context().DB.Model(&searches).
+		Relation("User").
+		Where(`"saved_search"."user_id" = ? or ("saved_search"."shared" = true and "saved_search"."institution_id" = ?)`, user.ID, user.InstitutionID).
+		Order("saved_search.name", "saved_search.id").
+		Select()
+	return searches, err
+}
+
+// URL returns the index page URL that runs this search.
+func (search *SavedSearch) URL() string {
+	return fmt.Sprintf("%s?%s", SavedSearchPaths[search.ResourceType], search.QueryString)
+}
+
+// Params returns this search's filter and sort params.
+func (search *SavedSearch) Params() url.Values {
+	params, _ := url.ParseQuery(search.QueryString)
+	return params
+}
+
+// IsVisibleTo returns true if user may run this search. Users can
+// run their own searches and searches shared at their institution.
+// APTrust admins can run any search.
+func (search *SavedSearch) IsVisibleTo(user *User) bool {
+	return search.UserID == user.ID ||
+		user.IsAdmin() ||
+		(search.Shared && search.InstitutionID == user.InstitutionID)
+}
+
+// Count returns the number of records this search matches when user
+// runs it. Like the index pages, this limits non-admins to their own
+// institution's records.
+func (search *SavedSearch) Count(user *User) (int, error) {
+	params, err := url.ParseQuery(search.QueryString)
+	if err != nil {
+		return 0, err
+	}
+	fc := NewFilterCollection()
+	for _, key := range FiltersFor(search.ResourceType) {
+		if values, ok := params[key]; ok {
+			_, err = fc.Add(key, values)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	query, err := fc.ToQuery()
+	if err != nil {
+		return 0, err
+	}
+	if !user.IsAdmin() {
+		query.Where("institution_id", "=", user.InstitutionID)
+	}
+	var items interface{}
+	switch search.ResourceType {
+	case "GenericFile":
+		items = &[]*GenericFile{}
+	case "IntellectualObject":
+		items = &[]*IntellectualObjectView{}
+	case "PremisEvent":
+		items = &[]*PremisEventView{}
+	case "WorkItem":
+		items = &[]*WorkItemView{}
+	default:
+		return 0, common.ErrInvalidParam
+	}
+	if CanCountFromView(query, items) {
+		return GetCountFromView(query, items)
+	}
+	return query.Count(items)
+}
+
+// Save saves this search to the database. This will peform an insert
+// if SavedSearch.ID is zero. Otherwise, it updates.
+func (search *SavedSearch) Save() error {
+	search.Name = strings.TrimSpace(search.Name)
+	search.SetTimestamps()
+	err := search.Validate()
+	if err != nil {
+		return err
+	}
+	if search.ID == int64(0) {
+		return insert(search)
+	}
+	return update(search)
+}
+
+// Delete deletes this saved search.
+func (search *SavedSearch) Delete() error {
+	_, err := common.Context().DB.Model(search).WherePK().Delete()
+	return err
+}
+
+// Validate validates the model. This is called automatically on insert
+// and update.
+func (search *SavedSearch) Validate() *common.ValidationError {
+	errors := make(map[string]string)
+	if search.UserID < 1 {
+		errors["UserID"] = ErrSavedSearchUserID
+	}
+	if search.InstitutionID < 1 {
+		errors["InstitutionID"] = ErrSavedSearchInstitutionID
+	}
+	if common.IsEmptyString(search.Name) {
+		errors["Name"] = ErrSavedSearchName
+	} else {
+		exists, err := common.Context().DB.Model((*SavedSearch)(nil)).
+			Where("user_id = ?", search.UserID).
+			Where("name = ?", search.Name).
+			Where("id != ?", search.ID).
+			Exists()
+		if err != nil || exists {
+			errors["Name"] = ErrSavedSearchDuplicate
+		}
+	}
+	if _, ok := SavedSearchPaths[search.ResourceType]; !ok {
+		errors["ResourceType"] = ErrSavedSearchResourceType
+	}
+	if common.IsEmptyString(search.QueryString) {
+		errors["QueryString"] = ErrSavedSearchQueryString
+	}
+	if len(errors) > 0 {
+		return &common.ValidationError{Errors: errors}
+	}
+	return nil
+}
+
+// CheckCount counts this search's results for its owner, and records
+// the count and the time of the check. If the count changed since the
+// last check, and the owner subscribed to notifications, this emails
+// the owner. The first check only records a baseline.
+//
+// We record the new count only after the email goes out. If sending
+// fails, we keep the old count, so the next check notices the change
+// and tries again.
+//
+// Returns true if we sent an email.
+func (search *SavedSearch) CheckCount() (bool, error) {
+	if search.User == nil {
+		user, err := UserByID(search.UserID)
+		if err != nil {
+			return false, err
+		}
+		search.User = user
+	}
+	count, err := search.Count(search.User)
+	if err != nil {
+		return false, err
+	}
+	isFirstCheck := search.LastCheckedAt.IsZero()
+	oldCount := search.LastCount
+	search.LastCount = int64(count)
+	emailed := false
+	if !isFirstCheck && oldCount != search.LastCount && search.Notify {
+		err = search.sendNotification(oldCount)
+		if err != nil {
+			search.LastCount = oldCount
+			return false, err
+		}
+		emailed = true
+	}
+	search.LastCheckedAt = time.Now().UTC()
+	_, err = common.Context().DB.Model(search).
+		Column("last_count", "last_checked_at").
+		WherePK().
+		Update()
+	return emailed, err
+}
+
+// sendNotification emails the owner of this search to say that its
+// result count changed from oldCount to LastCount.
+func (search *SavedSearch) sendNotification(oldCount int64) error {
+	body, err := search.notificationBody(oldCount)
+	if err != nil {
+		return err
+	}
+	subject := fmt.Sprintf("Saved search \"%s\" now matches %d records", search.Name, search.LastCount)
+	return common.Context().SESClient.Send(search.User.Email, subject, body)
+}
+
+func (search *SavedSearch) notificationBody(oldCount int64) (string, error) {
+	ctx := common.Context()
+	registryURL := fmt.Sprintf("%s://%s", ctx.Config.HTTPScheme(), ctx.Config.Cookies.Domain)
+	data := map[string]interface{}{
+		"UserName":    search.User.Name,
+		"SearchName":  search.Name,
+		"OldCount":    oldCount,
+		"NewCount":    search.LastCount,
+		"SearchURL":   registryURL + search.URL(),
+		"RegistryURL": registryURL,
+	}
+	var buf bytes.Buffer
+	err := common.TextTemplates["alerts/saved_search_changed.txt"].Execute(&buf, data)
+	return buf.String(), err
+}
+
+// SendSavedSearchNotifications checks the result count of every saved
+// search whose owner asked to be notified of changes, and emails the
+// owners of searches whose counts changed. It returns the number of
+// emails sent.
+//
+// Searches belonging to deactivated users are skipped. A failure on
+// one search does not stop us from checking the others.
+func SendSavedSearchNotifications() (int, error) {
+	var searches []*SavedSearch
+	err := common.Context().DB.Model(&searches).
+		Relation("User").
+		Where(`"saved_search"."notify" = true`).
+		Where(`"user"."deactivated_at" is null`).
+		Order("saved_search.id").
+		Select()
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, search := range searches {
+		emailed, err := search.CheckCount()
+		if err != nil {
+			common.Context().Log.Error().Msgf("Error checking saved search %d for %s: %v", search.ID, search.User.Email, err)
+			continue
+		}
+		if emailed {
+			sent++
+		}
+	}
+	return sent, nil
+}
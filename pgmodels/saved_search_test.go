@@ -0,0 +1,188 @@
+package pgmodels_test
+
+import (
+	"testing"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/network"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ses"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSavedSearch(t *testing.T) {
+	user := &pgmodels.User{InstitutionID: 2}
+	user.ID = 5
+	search := pgmodels.NewSavedSearch(user, "WorkItem", "action=Ingest&status=&page=3&per_page=50&sort=name__asc&bogus=1")
+	assert.EqualValues(t, 5, search.UserID)
+	assert.EqualValues(t, 2, search.InstitutionID)
+	assert.Equal(t, "action=Ingest&sort=name__asc", search.QueryString)
+	assert.Equal(t, "/work_items?action=Ingest&sort=name__asc", search.URL())
+	assert.Equal(t, []string{"Ingest"}, search.Params()["action"])
+
+	search = pgmodels.NewSavedSearch(user, "Alert", "type=Restoration+Completed")
+	err := search.Validate()
+	require.NotNil(t, err)
+	assert.Equal(t, pgmodels.ErrSavedSearchResourceType, err.Errors["ResourceType"])
+}
+
+func TestSavedSearchValidate(t *testing.T) {
+	db.LoadFixtures()
+	search := &pgmodels.SavedSearch{}
+	err := search.Validate()
+	require.NotNil(t, err)
+	assert.Equal(t, pgmodels.ErrSavedSearchUserID, err.Errors["UserID"])
+	assert.Equal(t, pgmodels.ErrSavedSearchInstitutionID, err.Errors["InstitutionID"])
+	assert.Equal(t, pgmodels.ErrSavedSearchName, err.Errors["Name"])
+	assert.Equal(t, pgmodels.ErrSavedSearchResourceType, err.Errors["ResourceType"])
+	assert.Equal(t, pgmodels.ErrSavedSearchQueryString, err.Errors["QueryString"])
+}
+
+func TestSavedSearchSaveAndSelect(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+
+	user, err := pgmodels.UserByEmail("user@inst1.edu")
+	require.Nil(t, err)
+	admin, err := pgmodels.UserByEmail("admin@inst1.edu")
+	require.Nil(t, err)
+	otherInst, err := pgmodels.UserByEmail("user@inst2.edu")
+	require.Nil(t, err)
+
+	mine := pgmodels.NewSavedSearch(user, "WorkItem", "action=Ingest")
+	mine.Name = "Ingests"
+	mine.Pinned = true
+	require.Nil(t, mine.Save())
+	assert.True(t, mine.ID > 0)
+
+	// Names must be unique per user.
+	duplicate := pgmodels.NewSavedSearch(user, "WorkItem", "action=Delete")
+	duplicate.Name = "Ingests"
+	valErr := duplicate.Validate()
+	require.NotNil(t, valErr)
+	assert.Equal(t, pgmodels.ErrSavedSearchDuplicate, valErr.Errors["Name"])
+
+	shared := pgmodels.NewSavedSearch(admin, "IntellectualObject", "state=A")
+	shared.Name = "Active objects"
+	shared.Shared = true
+	require.Nil(t, shared.Save())
+
+	private := pgmodels.NewSavedSearch(admin, "PremisEvent", "outcome=Failed")
+	private.Name = "Failed events"
+	require.Nil(t, private.Save())
+
+	pinned, err := pgmodels.PinnedSearchesFor(user.ID)
+	require.Nil(t, err)
+	require.Len(t, pinned, 1)
+	assert.Equal(t, mine.ID, pinned[0].ID)
+
+	// Users see their own searches and searches shared at
+	// their institution.
+	visible, err := pgmodels.SavedSearchesVisibleTo(user)
+	require.Nil(t, err)
+	require.Len(t, visible, 2)
+	assert.Equal(t, shared.ID, visible[0].ID)
+	assert.Equal(t, admin.Name, visible[0].User.Name)
+	assert.Equal(t, mine.ID, visible[1].ID)
+	assert.True(t, shared.IsVisibleTo(user))
+	assert.False(t, private.IsVisibleTo(user))
+	assert.False(t, shared.IsVisibleTo(otherInst))
+
+	visible, err = pgmodels.SavedSearchesVisibleTo(otherInst)
+	require.Nil(t, err)
+	assert.Empty(t, visible)
+
+	require.Nil(t, private.Delete())
+	_, err = pgmodels.SavedSearchByID(private.ID)
+	assert.True(t, pgmodels.IsNoRowError(err))
+}
+
+func TestSavedSearchCount(t *testing.T) {
+	db.LoadFixtures()
+	user, err := pgmodels.UserByEmail("user@inst1.edu")
+	require.Nil(t, err)
+	sysAdmin, err := pgmodels.UserByEmail("system@aptrust.org")
+	require.Nil(t, err)
+
+	// Non-admins count only their own institution's records.
+	// The status filter means this can't be counted from
+	// the work_item_counts view.
+	search := pgmodels.NewSavedSearch(user, "WorkItem", "action=Ingest&status=Pending")
+	count, err := search.Count(user)
+	require.Nil(t, err)
+	assert.Equal(t, 10, count)
+
+	count, err = search.Count(sysAdmin)
+	require.Nil(t, err)
+	assert.Equal(t, 23, count)
+}
+
+func TestSavedSearchCheckCount(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+
+	user, err := pgmodels.UserByEmail("user@inst1.edu")
+	require.Nil(t, err)
+	search := pgmodels.NewSavedSearch(user, "WorkItem", "action=Ingest&status=Pending")
+	search.Name = "Pending ingests"
+	search.Notify = true
+	require.Nil(t, search.Save())
+
+	// The first check records a baseline without sending email.
+	sent, err := pgmodels.SendSavedSearchNotifications()
+	require.Nil(t, err)
+	assert.Equal(t, 0, sent)
+	search, err = pgmodels.SavedSearchByID(search.ID)
+	require.Nil(t, err)
+	assert.EqualValues(t, 10, search.LastCount)
+	assert.False(t, search.LastCheckedAt.IsZero())
+
+	// No change, no email.
+	sent, err = pgmodels.SendSavedSearchNotifications()
+	require.Nil(t, err)
+	assert.Equal(t, 0, sent)
+
+	// If the email can't be sent, we keep the old count, so the
+	// next check tries again.
+	_, err = common.Context().DB.Exec("update work_items set status = 'Success' where id = 1")
+	require.Nil(t, err)
+	ctx := common.Context()
+	sesClient := ctx.SESClient
+	defer func() { ctx.SESClient = sesClient }()
+	ctx.SESClient = unreachableSESClient()
+	sent, err = pgmodels.SendSavedSearchNotifications()
+	require.Nil(t, err)
+	assert.Equal(t, 0, sent)
+	search, err = pgmodels.SavedSearchByID(search.ID)
+	require.Nil(t, err)
+	assert.EqualValues(t, 10, search.LastCount)
+
+	// Email when the count changes.
+	ctx.SESClient = sesClient
+	sent, err = pgmodels.SendSavedSearchNotifications()
+	require.Nil(t, err)
+	assert.Equal(t, 1, sent)
+	search, err = pgmodels.SavedSearchByID(search.ID)
+	require.Nil(t, err)
+	assert.EqualValues(t, 9, search.LastCount)
+}
+
+// unreachableSESClient returns an email client whose sends always fail.
+func unreachableSESClient() *network.SESClient {
+	ctx := common.Context()
+	client := network.NewSESClient(false, "", "", "", ctx.SESClient.FromAddress, ctx.Log)
+	client.ServiceEnabled = true
+	client.Session = session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Endpoint:    aws.String("http://127.0.0.1:1"),
+		Credentials: credentials.NewStaticCredentials("user", "password", ""),
+		MaxRetries:  aws.Int(0),
+	}))
+	client.Service = ses.New(client.Session)
+	return client
+}
@@ -0,0 +1,118 @@
+package pgmodels
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/go-pg/pg/v10"
+	"github.com/stretchr/stew/slice"
+)
+
+var SearchFilters = []string{
+	"institution_id",
+	"q",
+	"type",
+}
+
+// SearchTypePermissions maps each kind of search result to the
+// permission a user needs to see it.
+var SearchTypePermissions = map[string]constants.Permission{
+	constants.SearchTypeEvent:  constants.EventRead,
+	constants.SearchTypeFile:   constants.FileRead,
+	constants.SearchTypeObject: constants.IntellectualObjectRead,
+}
+
+// searchSelects are the queries for each kind of search result. Each
+// selects from its table as t, and joins tsq, the search query. See
+// the notes in db/migrations/027_full_text_search.sql.
+var searchSelects = map[string]string{
+	constants.SearchTypeObject: `select 'object' as "type", t.id, t.institution_id,
+		t.id as intellectual_object_id, t.identifier, coalesce(t.title, '') as summary,
+		t.state, t.updated_at, ts_rank(t.search_vector, tsq.query) as rank
+		from intellectual_objects t, tsq where t.search_vector @@ tsq.query`,
+	constants.SearchTypeFile: `select 'file' as "type", t.id, t.institution_id,
+		t.intellectual_object_id, t.identifier, coalesce(t.file_format, '') as summary,
+		t.state, t.updated_at, ts_rank(t.search_vector, tsq.query) as rank
+		from generic_files t, tsq where t.search_vector @@ tsq.query`,
+	constants.SearchTypeEvent: `select 'event' as "type", t.id, coalesce(t.institution_id, 0) as institution_id,
+		coalesce(t.intellectual_object_id, 0) as intellectual_object_id, t.identifier,
+		coalesce(t.event_type, '') as summary, coalesce(t.outcome, '') as state,
+		t.updated_at, ts_rank(t.search_vector, tsq.query) as rank
+		from premis_events t, tsq where t.search_vector @@ tsq.query`,
+}
+
+// SearchParams describes a full-text search.
+//
+// Terms uses web search syntax: words are and-ed together, "quoted
+// phrases" must match in order, "or" between words matches either,
+// and a leading dash excludes a word. Types lists the kinds of results
+// to include, from constants.SearchTypes. InstitutionID limits results
+// to one institution, or may be zero to search all institutions.
+type SearchParams struct {
+	Terms         string
+	Types         []string
+	InstitutionID int64
+	Limit         int
+	Offset        int
+}
+
+// SearchResult is an object, file or event that matched a full-text
+// search. Type is one of constants.SearchTypes. Summary is the object's
+// title, the file's format or the event's type. State is the event's
+// outcome. IntellectualObjectID is zero for events that don't belong
+// to an object.
+type SearchResult struct {
+	Type                 string    `json:"type"`
+	ID                   int64     `json:"id"`
+	InstitutionID        int64     `json:"institution_id"`
+	IntellectualObjectID int64     `json:"intellectual_object_id"`
+	Identifier           string    `json:"identifier"`
+	Summary              string    `json:"summary"`
+	State                string    `json:"state"`
+	UpdatedAt            time.Time `json:"updated_at"`
+	Rank                 float64   `json:"rank"`
+	TotalCount           int       `json:"-"`
+}
+
+// Search returns one page of the objects, files and events matching
+// params, best matches first, along with the total number of matches.
+// This returns an empty list if params has no search terms or types,
+// and common.ErrInvalidParam if it has a type not in
+// constants.SearchTypes.
+//
+// Callers are responsible for authorization. They should limit Types
+// to the kinds of records the user can see, and non-admins to their
+// own institution.
+func Search(params *SearchParams) ([]*SearchResult, int, error) {
+	results := make([]*SearchResult, 0)
+	terms := strings.TrimSpace(params.Terms)
+	if terms == "" || len(params.Types) == 0 {
+		return results, 0, nil
+	}
+	args := []interface{}{terms, terms}
+	selects := make([]string, 0, len(params.Types))
+	for _, searchType := range params.Types {
+		if !slice.Contains(constants.SearchTypes, searchType) {
+			return results, 0, common.ErrInvalidParam
+		}
+		sql := searchSelects[searchType]
+		if params.InstitutionID > 0 {
+			sql += " and t.institution_id = ?"
+			args = append(args, params.InstitutionID)
+		}
+		selects = append(selects, sql)
+	}
+	args = append(args, pg.Array(constants.SearchTypes), params.Limit, params.Offset)
+	query := fmt.Sprintf(`with tsq as (select websearch_to_tsquery('english', ?) || websearch_to_tsquery('simple', ?) as query)
+		select results.*, count(*) over () as total_count from (%s) results
+		order by rank desc, updated_at desc, array_position(?::varchar[], "type"), id
+		limit ? offset ?`, strings.Join(selects, " union all "))
+	_, err := common.Context().DB.Query(&results, query, args...)
+	if err != nil || len(results) == 0 {
+		return results, 0, err
+	}
+	return results, results[0].TotalCount, nil
+}
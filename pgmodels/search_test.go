@@ -0,0 +1,124 @@
+package pgmodels_test
+
+import (
+	"testing"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearch(t *testing.T) {
+	db.LoadFixtures()
+	params := &pgmodels.SearchParams{
+		Terms:         "photos",
+		Types:         constants.SearchTypes,
+		InstitutionID: 2,
+		Limit:         20,
+	}
+	results, count, err := pgmodels.Search(params)
+	require.Nil(t, err)
+	require.NotEmpty(t, results)
+	assert.Equal(t, len(results), count)
+
+	// The object matches on identifier, alt identifier, bag name and
+	// description, so it should outrank its files.
+	assert.Equal(t, constants.SearchTypeObject, results[0].Type)
+	assert.Equal(t, "institution1.edu/photos", results[0].Identifier)
+	assert.Equal(t, "First Object for Institution One", results[0].Summary)
+	fileCount := 0
+	for i, result := range results {
+		assert.EqualValues(t, 2, result.InstitutionID)
+		if i > 0 {
+			assert.True(t, result.Rank <= results[i-1].Rank)
+		}
+		if result.Type == constants.SearchTypeFile {
+			assert.Contains(t, result.Identifier, "institution1.edu/photos/")
+			assert.EqualValues(t, 1, result.IntellectualObjectID)
+			fileCount++
+		}
+	}
+	assert.Equal(t, 3, fileCount)
+
+	// Across all institutions, "photos" also finds
+	// institution2.edu/deleted/photo.jpg, because search terms
+	// are stemmed.
+	params.InstitutionID = 0
+	allResults, allCount, err := pgmodels.Search(params)
+	require.Nil(t, err)
+	assert.Equal(t, count+1, allCount)
+	identifiers := make([]string, len(allResults))
+	for i, result := range allResults {
+		identifiers[i] = result.Identifier
+	}
+	assert.Contains(t, identifiers, "institution2.edu/deleted/photo.jpg")
+	params.InstitutionID = 2
+
+	// Paging
+	params.Limit = 1
+	params.Offset = 1
+	page, pageCount, err := pgmodels.Search(params)
+	require.Nil(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, count, pageCount)
+	assert.Equal(t, results[1].Identifier, page[0].Identifier)
+
+	// Types
+	params = &pgmodels.SearchParams{
+		Terms:         "photos",
+		Types:         []string{constants.SearchTypeFile},
+		InstitutionID: 2,
+		Limit:         20,
+	}
+	results, count, err = pgmodels.Search(params)
+	require.Nil(t, err)
+	assert.Equal(t, fileCount, count)
+	for _, result := range results {
+		assert.Equal(t, constants.SearchTypeFile, result.Type)
+	}
+
+	// Words in titles and descriptions are stemmed.
+	params.Terms = "chocolates"
+	params.Types = []string{constants.SearchTypeObject}
+	params.InstitutionID = 0
+	results, _, err = pgmodels.Search(params)
+	require.Nil(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "institution2.edu/chocolate", results[0].Identifier)
+
+	// Institution scope
+	params.InstitutionID = 2
+	results, count, err = pgmodels.Search(params)
+	require.Nil(t, err)
+	assert.Empty(t, results)
+	assert.Equal(t, 0, count)
+}
+
+func TestSearchEmptyAndInvalid(t *testing.T) {
+	db.LoadFixtures()
+	results, count, err := pgmodels.Search(&pgmodels.SearchParams{
+		Terms: "   ",
+		Types: constants.SearchTypes,
+		Limit: 20,
+	})
+	require.Nil(t, err)
+	assert.Empty(t, results)
+	assert.Equal(t, 0, count)
+
+	results, _, err = pgmodels.Search(&pgmodels.SearchParams{
+		Terms: "photos",
+		Limit: 20,
+	})
+	require.Nil(t, err)
+	assert.Empty(t, results)
+
+	_, _, err = pgmodels.Search(&pgmodels.SearchParams{
+		Terms: "photos",
+		Types: []string{"work_item"},
+		Limit: 20,
+	})
+	assert.Equal(t, common.ErrInvalidParam, err)
+}
@@ -0,0 +1,185 @@
+package pgmodels
+
+import (
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/go-pg/pg/v10"
+)
+
+const (
+	ErrStoragePriceOption    = "Please choose a storage option."
+	ErrStoragePriceCost      = "Price must be greater than zero."
+	ErrStoragePriceEffective = "New prices must take effect on the first day of a future month."
+	ErrStoragePriceDuplicate = "This storage option already has a price starting on that date."
+)
+
+// StoragePrice is the cost per GB per month of a storage option
+// during a period of time. EffectiveFrom is always the first day of
+// a month. EffectiveTo is the date the option's next price takes
+// effect, or the zero time if this is the option's latest price.
+// Prices are in effect from EffectiveFrom up to, but not including,
+// EffectiveTo.
+//
+// Deposit stats and invoices use the price that was in effect during
+// the month they cover. See the storage_price_on function in the
+// database, and StoragePricesOn.
+//
+// Prices can be scheduled for future months and deleted until they
+// take effect. After that, they're part of the billing record and
+// can't be changed.
+type StoragePrice struct {
+	TimestampModel
+	StorageOptionID int64          `json:"storage_option_id"`
+	CostGBPerMonth  float64        `json:"cost_gb_per_month" pg:"cost_gb_per_month"`
+	EffectiveFrom   time.Time      `json:"effective_from"`
+	EffectiveTo     time.Time      `json:"effective_to"`
+	Comment         string         `json:"comment"`
+	StorageOption   *StorageOption `json:"storage_option" pg:"rel:has-one"`
+}
+
+// StoragePriceByID returns the price with the specified id.
+// Returns pg.ErrNoRows if there is no match.
+func StoragePriceByID(id int64) (*StoragePrice, error) {
+	query := NewQuery().Where(`"storage_price"."id"`, "=", id).Relations("StorageOption")
+	return StoragePriceGet(query)
+}
+
+// StoragePriceGet returns the first price matching the query.
+func StoragePriceGet(query *Query) (*StoragePrice, error) {
+	var price StoragePrice
+	err := query.Select(&price)
+	return &price, err
+}
+
+// StoragePriceSelect returns all prices matching the query.
+func StoragePriceSelect(query *Query) ([]*StoragePrice, error) {
+	var prices []*StoragePrice
+	err := query.Select(&prices)
+	return prices, err
+}
+
+// StoragePricesOn returns the cost per GB per month of each storage
+// option on the specified date, keyed by storage option name.
+func StoragePricesOn(date time.Time) (map[string]float64, error) {
+	var rows []struct {
+		Name           string
+		CostGBPerMonth float64 `pg:"cost_gb_per_month"`
+	}
+	_, err := common.Context().DB.Query(&rows,
+		`select so.name, storage_price_on(so.name, ?::date) as cost_gb_per_month from storage_options so`,
+		date.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	prices := make(map[string]float64)
+	for _, row := range rows {
+		prices[row.Name] = row.CostGBPerMonth
+	}
+	return prices, nil
+}
+
+// ApplyStoragePrices copies each storage option's current price into
+// StorageOption.CostGBPerMonth, so the option always shows the price
+// in effect today. This returns the number of options whose price
+// changed. The apply_storage_prices job runs this daily, so scheduled
+// prices show up on the day they take effect.
+func ApplyStoragePrices() (int, error) {
+	result, err := common.Context().DB.Exec(`update storage_options
+		set cost_gb_per_month = storage_price_on("name", current_date), updated_at = now()
+		where cost_gb_per_month != storage_price_on("name", current_date)`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// IsScheduled returns true if this price hasn't taken effect yet.
+func (price *StoragePrice) IsScheduled() bool {
+	return price.EffectiveFrom.After(today())
+}
+
+// Save inserts a new price and updates the effective dates of the
+// option's other prices to make room for it. Prices can't be changed
+// once saved, so this returns common.ErrNotSupported for prices that
+// already have an ID. To change a scheduled price, delete it and add
+// a new one.
+func (price *StoragePrice) Save() error {
+	if price.ID != 0 {
+		return common.ErrNotSupported
+	}
+	price.SetTimestamps()
+	err := price.Validate()
+	if err != nil {
+		return err
+	}
+	db := common.Context().DB
+	return db.RunInTransaction(db.Context(), func(tx *pg.Tx) error {
+		_, err := tx.Model(price).Insert()
+		if err != nil {
+			return err
+		}
+		return price.updateEffectiveDates(tx)
+	})
+}
+
+// Delete deletes a scheduled price, and extends the price before it
+// to cover the deleted price's dates. This returns
+// common.ErrNotSupported if the price has already taken effect.
+func (price *StoragePrice) Delete() error {
+	if !price.IsScheduled() {
+		return common.ErrNotSupported
+	}
+	db := common.Context().DB
+	return db.RunInTransaction(db.Context(), func(tx *pg.Tx) error {
+		_, err := tx.Model(price).WherePK().Delete()
+		if err != nil {
+			return err
+		}
+		return price.updateEffectiveDates(tx)
+	})
+}
+
+// updateEffectiveDates sets the effective_to date of each of this
+// price's storage option's prices to the effective_from date of the
+// next one.
+func (price *StoragePrice) updateEffectiveDates(tx *pg.Tx) error {
+	_, err := tx.Exec(`update storage_prices sp set effective_to = (
+			select min(next.effective_from) from storage_prices next
+			where next.storage_option_id = sp.storage_option_id
+			and next.effective_from > sp.effective_from)
+		where sp.storage_option_id = ?`, price.StorageOptionID)
+	return err
+}
+
+// Validate validates the model. This is called automatically on insert.
+func (price *StoragePrice) Validate() *common.ValidationError {
+	errors := make(map[string]string)
+	if price.StorageOptionID < 1 {
+		errors["StorageOptionID"] = ErrStoragePriceOption
+	}
+	if price.CostGBPerMonth <= 0.0 {
+		errors["CostGBPerMonth"] = ErrStoragePriceCost
+	}
+	if price.EffectiveFrom.Day() != 1 || !price.IsScheduled() {
+		errors["EffectiveFrom"] = ErrStoragePriceEffective
+	} else if price.StorageOptionID > 0 {
+		exists, err := common.Context().DB.Model((*StoragePrice)(nil)).
+			Where("storage_option_id = ?", price.StorageOptionID).
+			Where("effective_from = ?", price.EffectiveFrom.Format("2006-01-02")).
+			Exists()
+		if err != nil || exists {
+			errors["EffectiveFrom"] = ErrStoragePriceDuplicate
+		}
+	}
+	if len(errors) > 0 {
+		return &common.ValidationError{Errors: errors}
+	}
+	return nil
+}
+
+// today returns midnight UTC at the start of the current day.
+func today() time.Time {
+	now := time.Now().UTC()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,173 @@
+package pgmodels_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// firstOfMonth returns the first day of the month that is offset
+// months from the current month.
+func firstOfMonth(offset int) time.Time {
+	now := time.Now().UTC()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, offset, 0)
+}
+
+func TestStoragePriceByID(t *testing.T) {
+	db.LoadFixtures()
+	price, err := pgmodels.StoragePriceByID(1)
+	require.Nil(t, err)
+	require.NotNil(t, price)
+	assert.Equal(t, int64(1), price.StorageOptionID)
+	assert.Equal(t, 0.027, price.CostGBPerMonth)
+	assert.Equal(t, time.Date(2014, 1, 1, 0, 0, 0, 0, time.UTC), price.EffectiveFrom.UTC())
+	assert.True(t, price.EffectiveTo.IsZero())
+	require.NotNil(t, price.StorageOption)
+	assert.Equal(t, constants.StorageOptionStandard, price.StorageOption.Name)
+	assert.False(t, price.IsScheduled())
+}
+
+func TestStoragePricesOn(t *testing.T) {
+	db.LoadFixtures()
+	prices, err := pgmodels.StoragePricesOn(time.Now())
+	require.Nil(t, err)
+	assert.Equal(t, 12, len(prices))
+	assert.Equal(t, 0.027, prices[constants.StorageOptionStandard])
+	assert.Equal(t, 0.00099, prices[constants.StorageOptionGlacierDeepOR])
+	assert.Equal(t, 0.0059, prices[constants.StorageOptionWasabiVA])
+}
+
+func TestStoragePricePastMonths(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+
+	// Prices can't be scheduled in the past through Save, so insert
+	// this one directly, as if it had been scheduled long ago.
+	raise := &pgmodels.StoragePrice{
+		StorageOptionID: 1,
+		CostGBPerMonth:  0.03,
+		EffectiveFrom:   time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC),
+	}
+	raise.SetTimestamps()
+	_, err := common.Context().DB.Model(raise).Insert()
+	require.Nil(t, err)
+
+	prices, err := pgmodels.StoragePricesOn(time.Date(2022, 6, 30, 0, 0, 0, 0, time.UTC))
+	require.Nil(t, err)
+	assert.Equal(t, 0.027, prices[constants.StorageOptionStandard])
+
+	prices, err = pgmodels.StoragePricesOn(time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC))
+	require.Nil(t, err)
+	assert.Equal(t, 0.03, prices[constants.StorageOptionStandard])
+
+	// The invoice for January 2023 uses the new price, even though
+	// storage_options hasn't been updated yet.
+	defer deleteInvoiceStats(t)
+	inst, err := pgmodels.InstitutionByID(2)
+	require.Nil(t, err)
+	addInvoiceStats(t, inst.ID, 0, inst.Name, constants.StorageOptionStandard, 20480)
+	invoice, err := pgmodels.GenerateInvoice(inst, invoiceMonth)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(invoice.BilledLines()))
+	assert.Equal(t, 0.03, invoice.BilledLines()[0].CostGBPerMonth)
+	assert.Equal(t, 307.2, invoice.AmountDue)
+
+	// Applying prices brings storage_options up to date.
+	count, err := pgmodels.ApplyStoragePrices()
+	require.Nil(t, err)
+	assert.Equal(t, 1, count)
+	option, err := pgmodels.StorageOptionByID(1)
+	require.Nil(t, err)
+	assert.Equal(t, 0.03, option.CostGBPerMonth)
+
+	count, err = pgmodels.ApplyStoragePrices()
+	require.Nil(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestStoragePriceSaveAndDelete(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+
+	nextMonth := firstOfMonth(1)
+	price := &pgmodels.StoragePrice{
+		StorageOptionID: 1,
+		CostGBPerMonth:  0.025,
+		EffectiveFrom:   nextMonth,
+		Comment:         "Price drop",
+	}
+	require.Nil(t, price.Save())
+	assert.True(t, price.ID > 0)
+	assert.True(t, price.IsScheduled())
+
+	// Saved prices can't be changed.
+	assert.Equal(t, common.ErrNotSupported, price.Save())
+
+	// The old price now ends when the new one starts.
+	oldPrice, err := pgmodels.StoragePriceByID(1)
+	require.Nil(t, err)
+	assert.Equal(t, nextMonth, oldPrice.EffectiveTo.UTC())
+
+	// The new price doesn't apply until next month.
+	prices, err := pgmodels.StoragePricesOn(time.Now())
+	require.Nil(t, err)
+	assert.Equal(t, 0.027, prices[constants.StorageOptionStandard])
+	prices, err = pgmodels.StoragePricesOn(nextMonth)
+	require.Nil(t, err)
+	assert.Equal(t, 0.025, prices[constants.StorageOptionStandard])
+
+	// Prices in effect can't be deleted.
+	assert.Equal(t, common.ErrNotSupported, oldPrice.Delete())
+
+	// Deleting the scheduled price extends the old one again.
+	require.Nil(t, price.Delete())
+	oldPrice, err = pgmodels.StoragePriceByID(1)
+	require.Nil(t, err)
+	assert.True(t, oldPrice.EffectiveTo.IsZero())
+}
+
+func TestStoragePriceValidate(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+
+	price := &pgmodels.StoragePrice{}
+	err := price.Validate()
+	require.NotNil(t, err)
+	assert.Equal(t, pgmodels.ErrStoragePriceOption, err.Errors["StorageOptionID"])
+	assert.Equal(t, pgmodels.ErrStoragePriceCost, err.Errors["CostGBPerMonth"])
+	assert.Equal(t, pgmodels.ErrStoragePriceEffective, err.Errors["EffectiveFrom"])
+
+	// Must be the first of a month...
+	price.StorageOptionID = 1
+	price.CostGBPerMonth = 0.025
+	price.EffectiveFrom = firstOfMonth(1).AddDate(0, 0, 1)
+	err = price.Validate()
+	require.NotNil(t, err)
+	assert.Equal(t, pgmodels.ErrStoragePriceEffective, err.Errors["EffectiveFrom"])
+
+	// ...and in the future.
+	price.EffectiveFrom = firstOfMonth(0)
+	err = price.Validate()
+	require.NotNil(t, err)
+	assert.Equal(t, pgmodels.ErrStoragePriceEffective, err.Errors["EffectiveFrom"])
+
+	price.EffectiveFrom = firstOfMonth(2)
+	assert.Nil(t, price.Validate())
+	require.Nil(t, price.Save())
+
+	// Only one price per option per date.
+	duplicate := &pgmodels.StoragePrice{
+		StorageOptionID: 1,
+		CostGBPerMonth:  0.02,
+		EffectiveFrom:   firstOfMonth(2),
+	}
+	err = duplicate.Validate()
+	require.NotNil(t, err)
+	assert.Equal(t, pgmodels.ErrStoragePriceDuplicate, err.Errors["EffectiveFrom"])
+}
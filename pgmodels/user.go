@@ -8,6 +8,7 @@ import (
 	"github.com/APTrust/registry/common"
 	"github.com/APTrust/registry/constants"
 	v "github.com/asaskevich/govalidator"
+	"github.com/pquerna/otp"
 )
 
 // Phone: +1234567890 (10 digits)
@@ -26,6 +27,7 @@ const (
 	ErrUserInvalidAdmin = "Sys Admin role is not valid for this institution."
 	ErrUserPwdMissing   = "Encrypted password is missing."
 	ErrUserPwdIncorrect = "Incorrect Password."
+	ErrUserAlertPrefs   = "Please choose a valid delivery option for each alert type."
 )
 
 // User is a person who can log in and do stuff.
@@ -112,11 +114,24 @@ type User struct {
 	// we're waiting for a user to enter a text/SMS OTP.
 	EncryptedOTPSentAt time.Time `json:"-" form:"-" pg:"encrypted_otp_sent_at"`
 
-	// ConsumedTimestep is a legacy field from Devise, which used it
-	// for time-based one-time passwords. Not used.
-	// TODO: Delete this.
+	// ConsumedTimestep is the TOTP time step of the last authenticator
+	// app code this user entered successfully. We reject codes from this
+	// step or earlier, so each code can be used only once.
 	ConsumedTimestep int `json:"-" form:"-" pg:"consumed_timestep"`
 
+	// EncryptedTOTPSecret is the user's authenticator app provisioning
+	// URI, which includes the TOTP secret, encrypted with the
+	// TOTP_ENCRYPTION_KEY from the config. This is the confirmed
+	// secret we check login codes against when AuthyStatus is
+	// TwoFactorTOTP.
+	EncryptedTOTPSecret string `json:"-" form:"-" pg:"encrypted_totp_secret"`
+
+	// PendingTOTPSecret is like EncryptedTOTPSecret, for an
+	// authenticator app setup the user hasn't confirmed yet.
+	// ConfirmTOTP moves it into EncryptedTOTPSecret, so starting
+	// setup never breaks the user's current authenticator app.
+	PendingTOTPSecret string `json:"-" form:"-" pg:"pending_totp_secret"`
+
 	// OTPRequiredForLogin indicates whether, as a matter of policy, the
 	// user must use some form of OTP to log in. If true, the user should
 	// be allowed to log in only with Authy one-touch, six-digit SMS
@@ -155,10 +170,13 @@ type User struct {
 	// successful sign-in with Authy.
 	LastSignInWithAuthy time.Time `json:"last_sign_in_with_authy" form:"-" pg:"last_sign_in_with_authy"`
 
-	// AuthyStatus indicates how the user wants to authenticate with
-	// Authy. If it's constants.TwoFactorAuthy, we should send them a
-	// push, so they can login with one-touch. Anything else means SMS,
-	// but call IsTwoFactorUser() to make sure they're actually require
+	// AuthyStatus indicates how the user wants to complete two-factor
+	// login. If it's constants.TwoFactorAuthy, we should send them a
+	// push, so they can login with one-touch. If it's
+	// constants.TwoFactorTOTP, they'll enter a code from their
+	// authenticator app. If it's constants.TwoFactorWebAuthn, they'll
+	// use a security key or passkey. Anything else means SMS, but call
+	// IsTwoFactorUser() to make sure they're actually require
 	// two-factor auth before trying to text them.
 	AuthyStatus string `json:"authy_status" pg:"authy_status"`
 
@@ -183,17 +201,28 @@ type User struct {
 	// unused.
 	GracePeriod time.Time `json:"grace_period" time_format:"2006-01-02" pg:"grace_period"`
 
-	// AwaitingSecondFactor indicates that the user has logged in with
-	// email and password, but has not yet completed the second login
-	// step (Authy, SMS OTP, or backup code). This flag is only set on
-	// users going through the two-factor process. Middleware checks it
-	// to prevent partially logged-in users from accessing any pages other
-	// than those required to complete the two-factor login process.
-	AwaitingSecondFactor bool `json:"-" pg:"awaiting_second_factor,use_zero"`
-
 	// Role is the user's role.
 	Role string `json:"role" pg:"role"`
 
+	// AlertPreferences maps alert type to delivery mode, which is one of
+	// constants.AlertDeliveryModes. Alert types that aren't in the map
+	// are emailed immediately. Use AlertDeliveryFor to look these up.
+	AlertPreferences map[string]string `json:"alert_preferences" form:"-" pg:"alert_preferences"`
+
+	// FailedLoginAttempts is the number of consecutive failed password
+	// and two-factor attempts since the user last signed in successfully.
+	FailedLoginAttempts int `json:"failed_login_attempts" form:"-" pg:"failed_login_attempts,use_zero"`
+
+	// LastFailedLoginAt is the time of the user's most recent failed
+	// password or two-factor attempt. We use this to calculate how long
+	// they must wait before trying again.
+	LastFailedLoginAt time.Time `json:"last_failed_login_at" form:"-" pg:"last_failed_login_at"`
+
+	// LockedUntil is set when FailedLoginAttempts reaches the limit
+	// in the config. The user can't sign in until this time passes or
+	// an admin unlocks their account.
+	LockedUntil time.Time `json:"locked_until" form:"-" pg:"locked_until"`
+
 	// Institution is where they lock you up after you've spent too much
 	// time trying to figure out the old Rails code.
 	Institution *Institution `json:"institution" pg:"rel:has-one"`
@@ -227,6 +256,26 @@ func UserSelect(query *Query) ([]*User, error) {
 	return users, err
 }
 
+// ActiveAPTrustAdmins returns all APTrust sys admins whose accounts
+// have not been deactivated. These are the people who should hear
+// about system-level problems, such as stalled work items.
+func ActiveAPTrustAdmins() ([]*User, error) {
+	query := NewQuery().
+		Where("role", "=", constants.RoleSysAdmin).
+		IsNull("deactivated_at")
+	return UserSelect(query)
+}
+
+// ActiveInstAdmins returns all institutional admins at the specified
+// institution whose accounts have not been deactivated.
+func ActiveInstAdmins(institutionID int64) ([]*User, error) {
+	query := NewQuery().
+		Where("institution_id", "=", institutionID).
+		Where("role", "=", constants.RoleInstAdmin).
+		IsNull("deactivated_at")
+	return UserSelect(query)
+}
+
 // UserSignIn signs a user in. If successful, it returns the User
 // record with User.Institution properly set. If it fails, check
 // the error.
@@ -240,10 +289,82 @@ func UserSignIn(email, password, ipAddr string) (*User, error) {
 	if !user.DeactivatedAt.IsZero() {
 		return nil, common.ErrAccountDeactivated
 	}
+	// Don't even check the password if the user has to wait,
+	// so there's no way to guess while locked out.
+	if err = user.CheckLoginThrottle(); err != nil {
+		return nil, err
+	}
 	if !common.ComparePasswords(user.EncryptedPassword, password) {
 		common.Context().Log.Warn().Msgf("Wrong password for user %s", email)
 		return nil, common.ErrInvalidLogin
 	}
+	// Check this only after the password, so we don't reveal
+	// which accounts exist.
+	if user.Role != constants.RoleSysAdmin {
+		idp, err := IdentityProviderForInstitution(user.InstitutionID)
+		if err == nil && idp.IsRequired() {
+			common.Context().Log.Warn().Msgf("User %s tried to sign in with a password, but their institution requires single sign-on", email)
+			return nil, common.ErrSSORequired
+		} else if err != nil && !IsNoRowError(err) {
+			return nil, err
+		}
+	}
+	user.recordSignIn(ipAddr)
+	err = user.Save()
+	return user, err
+}
+
+// UserSSOSignIn signs in a user whom an institution's identity provider
+// has just authenticated. We match the identity to an existing, active
+// user at the provider's institution by email address. If the provider
+// manages roles, we update the user's role to match the IdP's attributes,
+// though we never change a sysadmin's role.
+//
+// This returns common.ErrSSONoAccount if no user at the institution has
+// the identity's email address.
+func UserSSOSignIn(idp *IdentityProvider, identity *common.SSOIdentity, ipAddr string) (*User, error) {
+	log := common.Context().Log
+	if identity.Email == "" {
+		log.Warn().Msgf("Identity provider for institution %d signed in subject %s without an email address", idp.InstitutionID, identity.Subject)
+		return nil, common.ErrSSONoAccount
+	}
+	user, err := UserByEmail(identity.Email)
+	if IsNoRowError(err) {
+		log.Warn().Msgf("Identity provider for institution %d signed in %s, who has no Registry account", idp.InstitutionID, identity.Email)
+		return nil, common.ErrSSONoAccount
+	} else if err != nil {
+		return nil, err
+	}
+	if user.InstitutionID != idp.InstitutionID {
+		log.Warn().Msgf("Identity provider for institution %d signed in %s, who belongs to institution %d", idp.InstitutionID, identity.Email, user.InstitutionID)
+		return nil, common.ErrSSONoAccount
+	}
+	if !user.DeactivatedAt.IsZero() {
+		return nil, common.ErrAccountDeactivated
+	}
+	if user.IsLocked() {
+		return nil, common.ErrAccountLocked
+	}
+	role := idp.RoleFor(identity)
+	if role != "" && role != user.Role && user.Role != constants.RoleSysAdmin {
+		log.Info().Msgf("Changing role of %s from %s to %s, based on identity provider attributes", user.Email, user.Role, role)
+		user.Role = role
+	}
+	user.recordSignIn(ipAddr)
+	err = user.Save()
+	return user, err
+}
+
+// recordSignIn updates the user's sign-in count, times, and IP
+// addresses. The caller must save the user.
+//
+// Two-factor users aren't fully signed in until they complete their
+// second factor, so we don't clear their failed attempts here. If we
+// did, anyone who knew the password could keep guessing codes.
+func (user *User) recordSignIn(ipAddr string) {
+	if !user.IsTwoFactorUser() {
+		user.ClearFailedLogins()
+	}
 	user.SignInCount = user.SignInCount + 1
 	if user.CurrentSignInIP != "" {
 		user.LastSignInIP = user.CurrentSignInIP
@@ -253,8 +374,65 @@ func UserSignIn(email, password, ipAddr string) (*User, error) {
 	}
 	user.CurrentSignInIP = ipAddr
 	user.CurrentSignInAt = time.Now().UTC()
-	err = user.Save()
-	return user, err
+}
+
+// IsLocked returns true if this user's account is locked after too many
+// failed sign-in attempts.
+func (user *User) IsLocked() bool {
+	return !user.LockedUntil.IsZero() && time.Now().UTC().Before(user.LockedUntil)
+}
+
+// CheckLoginThrottle returns common.ErrAccountLocked if this user's
+// account is locked, or common.ErrTooManyAttempts if they're trying
+// again too soon after a failed attempt. It returns nil if the user
+// may try to sign in or enter a second factor.
+func (user *User) CheckLoginThrottle() error {
+	if user.IsLocked() {
+		return common.ErrAccountLocked
+	}
+	lockout := common.Context().Config.Lockout
+	delay := lockout.Delay(user.FailedLoginAttempts, lockout.LockoutDuration)
+	if delay > 0 && time.Now().UTC().Before(user.LastFailedLoginAt.Add(delay)) {
+		return common.ErrTooManyAttempts
+	}
+	return nil
+}
+
+// RecordFailedLogin records a failed password or two-factor attempt,
+// locking the account if the user has reached the limit. It returns
+// true if this failure locked the account, in which case the caller
+// should let the user know.
+func (user *User) RecordFailedLogin() (bool, error) {
+	lockout := common.Context().Config.Lockout
+	now := time.Now().UTC()
+	user.FailedLoginAttempts++
+	user.LastFailedLoginAt = now
+	locked := false
+	if lockout.MaxFailures > 0 && user.FailedLoginAttempts >= lockout.MaxFailures && !user.IsLocked() {
+		user.LockedUntil = now.Add(lockout.LockoutDuration)
+		locked = true
+		common.Context().Log.Warn().Msgf("Locked account %s until %s after %d failed sign-in attempts", user.Email, user.LockedUntil.Format(time.RFC3339), user.FailedLoginAttempts)
+	}
+	_, err := common.Context().DB.Model(user).
+		Column("failed_login_attempts", "last_failed_login_at", "locked_until").
+		WherePK().
+		Update()
+	return locked, err
+}
+
+// ClearFailedLogins resets the user's failed attempt count and lock.
+// The caller must save the user.
+func (user *User) ClearFailedLogins() {
+	user.FailedLoginAttempts = 0
+	user.LastFailedLoginAt = time.Time{}
+	user.LockedUntil = time.Time{}
+}
+
+// Unlock unlocks an account that was locked after too many failed
+// sign-in attempts.
+func (user *User) Unlock() error {
+	user.ClearFailedLogins()
+	return user.Save()
 }
 
 // UserSignOut signs a user out.
@@ -273,6 +451,9 @@ func (user *User) SignOut() error {
 func (user *User) Save() error {
 	user.SetTimestamps()
 	user.reformatPhone()
+	if user.AlertPreferences == nil {
+		user.AlertPreferences = make(map[string]string)
+	}
 	err := user.Validate()
 	if err != nil {
 		return err
@@ -324,12 +505,31 @@ func (user *User) Validate() *common.ValidationError {
 	if user.EncryptedPassword == "" {
 		errors["EncryptedPassword"] = ErrUserPwdMissing
 	}
+	for alertType, mode := range user.AlertPreferences {
+		if !v.IsIn(alertType, constants.AlertTypes...) || !v.IsIn(mode, constants.AlertDeliveryModes...) {
+			errors["AlertPreferences"] = ErrUserAlertPrefs
+		}
+	}
 	if len(errors) > 0 {
 		return &common.ValidationError{Errors: errors}
 	}
 	return nil
 }
 
+// AlertDeliveryFor returns the user's preferred delivery mode for
+// the specified alert type. This will be one of the values in
+// constants.AlertDeliveryModes. Types in constants.ImmediateAlertTypes
+// are always delivered immediately.
+func (user *User) AlertDeliveryFor(alertType string) string {
+	if v.IsIn(alertType, constants.ImmediateAlertTypes...) {
+		return constants.AlertDeliveryImmediate
+	}
+	if mode, ok := user.AlertPreferences[alertType]; ok && mode != "" {
+		return mode
+	}
+	return constants.AlertDeliveryImmediate
+}
+
 func (user *User) reformatPhone() {
 	digitsOnly := reNumeric.ReplaceAllString(user.PhoneNumber, "")
 	if len(digitsOnly) > 0 {
@@ -377,6 +577,19 @@ func (user *User) IsAuthyOneTouchUser() bool {
 	return user.IsTwoFactorUser() && (user.AuthyStatus == constants.TwoFactorAuthy)
 }
 
+// IsTOTPUser returns true if this user has enabled two-factor
+// authentication with an authenticator app.
+func (user *User) IsTOTPUser() bool {
+	return user.IsTwoFactorUser() && user.AuthyStatus == constants.TwoFactorTOTP
+}
+
+// IsWebAuthnUser returns true if this user has chosen a security key
+// or passkey as their primary two-factor method. Users with other
+// methods may also have security keys. See ActiveWebAuthnCredentials.
+func (user *User) IsWebAuthnUser() bool {
+	return user.IsTwoFactorUser() && user.AuthyStatus == constants.TwoFactorWebAuthn
+}
+
 // IsTwoFactorUser returns true if this user has enabled and confirmed
 // two factor authentication.
 //
@@ -394,6 +607,10 @@ func (user *User) IsTwoFactorUser() bool {
 //
 // constants.TwoFactorSMS if the user receives two-factor OTP code via
 // text/SMS
+//
+// constants.TwoFactorTOTP if the user uses an authenticator app.
+//
+// constants.TwoFactorWebAuthn if the user uses a security key or passkey.
 func (user *User) TwoFactorMethod() string {
 	if !user.IsTwoFactorUser() {
 		return constants.TwoFactorNone
@@ -401,6 +618,12 @@ func (user *User) TwoFactorMethod() string {
 	if user.IsSMSUser() {
 		return constants.TwoFactorSMS
 	}
+	if user.IsTOTPUser() {
+		return constants.TwoFactorTOTP
+	}
+	if user.IsWebAuthnUser() {
+		return constants.TwoFactorWebAuthn
+	}
 	return constants.TwoFactorAuthy
 }
 
@@ -426,6 +649,97 @@ func (user *User) CreateOTPToken() (string, error) {
 	return token, err
 }
 
+// InitTOTP generates a new authenticator app secret for this user and
+// saves it, encrypted, as PendingTOTPSecret. This does not change the
+// user's two-factor method or current secret. The new secret takes
+// effect only after the user proves their app works by entering a
+// code in ConfirmTOTP, so a user who abandons setup can still log in
+// with their old method.
+//
+// The returned key's URL() is the provisioning URI to show the user
+// as a QR code, and its Secret() is for manual entry.
+func (user *User) InitTOTP() (*otp.Key, error) {
+	config := common.Context().Config.TwoFactor
+	if !config.TOTPEnabled {
+		return nil, common.ErrTOTPNotEnabled
+	}
+	key, err := common.NewTOTPKey(user.Email)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := common.EncryptSecret(config.TOTPEncryptionKey, key.String())
+	if err != nil {
+		return nil, err
+	}
+	user.PendingTOTPSecret = encrypted
+	return key, user.Save()
+}
+
+// TOTPKey returns the user's decrypted authenticator app key.
+func (user *User) TOTPKey() (*otp.Key, error) {
+	return decryptTOTPKey(user.EncryptedTOTPSecret)
+}
+
+// PendingTOTPKey returns the decrypted key for the authenticator
+// app setup the user hasn't confirmed yet.
+func (user *User) PendingTOTPKey() (*otp.Key, error) {
+	return decryptTOTPKey(user.PendingTOTPSecret)
+}
+
+func decryptTOTPKey(encrypted string) (*otp.Key, error) {
+	config := common.Context().Config.TwoFactor
+	if !config.TOTPEnabled {
+		return nil, common.ErrTOTPNotEnabled
+	}
+	if encrypted == "" {
+		return nil, common.ErrNoTOTPSecret
+	}
+	provisioningURI, err := common.DecryptSecret(config.TOTPEncryptionKey, encrypted)
+	if err != nil {
+		return nil, err
+	}
+	return otp.NewKeyFromURL(provisioningURI)
+}
+
+// VerifyTOTP returns true if code is a valid authenticator app code
+// for this user, allowing for common.TOTPSkew steps of clock drift.
+// On success, this records the code's time step and saves the user,
+// so the same code can't be used again.
+func (user *User) VerifyTOTP(code string) (bool, error) {
+	key, err := user.TOTPKey()
+	if err != nil {
+		return false, err
+	}
+	step, ok := common.TOTPStep(key, strings.TrimSpace(code), time.Now(), int64(user.ConsumedTimestep))
+	if !ok {
+		return false, nil
+	}
+	user.ConsumedTimestep = int(step)
+	return true, user.Save()
+}
+
+// ConfirmTOTP completes authenticator app setup. If code is valid for
+// the pending secret, this makes the pending secret the user's current
+// secret, makes the authenticator app the user's two-factor method,
+// and saves the user.
+func (user *User) ConfirmTOTP(code string) (bool, error) {
+	key, err := user.PendingTOTPKey()
+	if err != nil {
+		return false, err
+	}
+	step, ok := common.TOTPStep(key, strings.TrimSpace(code), time.Now(), 0)
+	if !ok {
+		return false, nil
+	}
+	user.EncryptedTOTPSecret = user.PendingTOTPSecret
+	user.PendingTOTPSecret = ""
+	user.ConsumedTimestep = int(step)
+	user.AuthyStatus = constants.TwoFactorTOTP
+	user.EnabledTwoFactor = true
+	user.ConfirmedTwoFactor = true
+	return true, user.Save()
+}
+
 // ClearOTPSecret deletes the user's EncryptedOTPSecret.
 func (user *User) ClearOTPSecret() error {
 	user.EncryptedOTPSecret = ""
@@ -0,0 +1,208 @@
+package pgmodels
+
+import (
+	"time"
+
+	"github.com/APTrust/registry/common"
+)
+
+const (
+	ErrUserSessionUserID = "UserID is required."
+	ErrUserSessionToken  = "Token digest is missing."
+)
+
+// UserSessionTouchInterval is how often we update a session's
+// last_seen_at time. Updating on every request would mean a database
+// write for every page view, and users don't need to know to the
+// second when a session was last used.
+const UserSessionTouchInterval = time.Minute
+
+// UserSession is a browser session belonging to a signed-in user. The
+// session cookie holds a random token, and every request must match
+// an existing session. Deleting a session signs that browser out.
+//
+// We store a one-way hash of the token, never the token itself.
+// Token is set only on new sessions, so the caller can put it in
+// the session cookie.
+type UserSession struct {
+	tableName struct{} `pg:"user_sessions"`
+	BaseModel
+	UserID      int64     `json:"user_id"`
+	TokenDigest string    `json:"-"`
+	IPAddress   string    `json:"ip_address" pg:"ip_address"`
+	UserAgent   string    `json:"user_agent"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+
+	// AwaitingSecondFactor is true if a two-factor user signed in to
+	// this session with a password or single sign-on, but hasn't yet
+	// completed their second factor. Middleware checks it to keep
+	// the session away from everything but the two-factor pages.
+	// This belongs to the session, not the user, so completing the
+	// second factor in one browser doesn't complete it in others.
+	AwaitingSecondFactor bool `json:"-" pg:"awaiting_second_factor,use_zero"`
+
+	Token string `json:"-" pg:"-"`
+	User  *User  `json:"-" pg:"rel:has-one"`
+}
+
+// NewUserSession returns a new, unsaved session for the specified user,
+// signing in from the specified IP address and user agent.
+func NewUserSession(userID int64, ipAddress, userAgent string) *UserSession {
+	token := common.RandomToken()
+	now := time.Now().UTC()
+	return &UserSession{
+		UserID:      userID,
+		TokenDigest: common.Hash(token),
+		IPAddress:   ipAddress,
+		UserAgent:   userAgent,
+		CreatedAt:   now,
+		LastSeenAt:  now,
+		Token:       token,
+	}
+}
+
+// UserSessionByID returns the session with the specified id.
+// Returns pg.ErrNoRows if there is no match.
+func UserSessionByID(id int64) (*UserSession, error) {
+	query := NewQuery().Where("id", "=", id)
+	return UserSessionGet(query)
+}
+
+// UserSessionByToken returns the session whose token matches the
+// token from a session cookie. Returns pg.ErrNoRows if there is
+// no match.
+func UserSessionByToken(token string) (*UserSession, error) {
+	query := NewQuery().Where("token_digest", "=", common.Hash(token))
+	return UserSessionGet(query)
+}
+
+// UserSessionGet returns the first session matching the query.
+func UserSessionGet(query *Query) (*UserSession, error) {
+	var session UserSession
+	err := query.Select(&session)
+	return &session, err
+}
+
+// UserSessionSelect returns all sessions matching the query.
+func UserSessionSelect(query *Query) ([]*UserSession, error) {
+	var sessions []*UserSession
+	err := query.Select(&sessions)
+	return sessions, err
+}
+
+// UserSessionsForUser returns the user's unexpired sessions, most
+// recently used first.
+func UserSessionsForUser(userID int64) ([]*UserSession, error) {
+	query := NewQuery().
+		Where("user_id", "=", userID).
+		OrderBy("last_seen_at", "desc").
+		OrderBy("id", "desc")
+	if maxAge := userSessionMaxAge(); maxAge > 0 {
+		query.Where("last_seen_at", ">=", time.Now().UTC().Add(-maxAge))
+	}
+	return UserSessionSelect(query)
+}
+
+// UserSessionDeleteAll deletes all of the user's sessions, signing
+// them out everywhere. Returns the number of sessions deleted.
+func UserSessionDeleteAll(userID int64) (int, error) {
+	result, err := common.Context().DB.Model((*UserSession)(nil)).
+		Where("user_id = ?", userID).
+		Delete()
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// UserSessionDeleteExpired deletes the user's expired sessions. We call
+// this when the user signs in, so old sessions don't pile up.
+func UserSessionDeleteExpired(userID int64) error {
+	maxAge := userSessionMaxAge()
+	if maxAge <= 0 {
+		return nil
+	}
+	_, err := common.Context().DB.Model((*UserSession)(nil)).
+		Where("user_id = ?", userID).
+		Where("last_seen_at < ?", time.Now().UTC().Add(-maxAge)).
+		Delete()
+	return err
+}
+
+// userSessionMaxAge returns how long a session can sit idle before it
+// expires. This matches the session cookie's max age.
+func userSessionMaxAge() time.Duration {
+	return time.Duration(common.Context().Config.Cookies.MaxAge) * time.Second
+}
+
+// Save saves this session to the database. This will peform an insert
+// if UserSession.ID is zero. Otherwise, it updates.
+func (session *UserSession) Save() error {
+	err := session.Validate()
+	if err != nil {
+		return err
+	}
+	if session.ID == int64(0) {
+		return insert(session)
+	}
+	return update(session)
+}
+
+// Validate validates the model. This is called automatically on insert
+// and update.
+func (session *UserSession) Validate() *common.ValidationError {
+	errors := make(map[string]string)
+	if session.UserID < 1 {
+		errors["UserID"] = ErrUserSessionUserID
+	}
+	if common.IsEmptyString(session.TokenDigest) {
+		errors["TokenDigest"] = ErrUserSessionToken
+	}
+	if len(errors) > 0 {
+		return &common.ValidationError{Errors: errors}
+	}
+	return nil
+}
+
+// Delete deletes this session, signing out the browser that holds it.
+func (session *UserSession) Delete() error {
+	_, err := common.Context().DB.Model(session).WherePK().Delete()
+	return err
+}
+
+// IsExpired returns true if this session has been idle for longer
+// than the session cookie's max age.
+func (session *UserSession) IsExpired() bool {
+	maxAge := userSessionMaxAge()
+	return maxAge > 0 && session.LastSeenAt.Add(maxAge).Before(time.Now().UTC())
+}
+
+// CompleteSecondFactor records that the user verified their second
+// factor in this session.
+func (session *UserSession) CompleteSecondFactor() error {
+	session.AwaitingSecondFactor = false
+	_, err := common.Context().DB.Model(session).
+		Column("awaiting_second_factor").
+		WherePK().
+		Update()
+	return err
+}
+
+// Touch records that this session was just used from the specified
+// IP address. To save database writes, this updates the session only
+// if the IP address changed or the session hasn't been touched in
+// the last UserSessionTouchInterval.
+func (session *UserSession) Touch(ipAddress string) error {
+	now := time.Now().UTC()
+	if ipAddress == session.IPAddress && now.Sub(session.LastSeenAt) < UserSessionTouchInterval {
+		return nil
+	}
+	session.LastSeenAt = now
+	session.IPAddress = ipAddress
+	_, err := common.Context().DB.Model(session).
+		Column("last_seen_at", "ip_address").
+		WherePK().
+		Update()
+	return err
+}
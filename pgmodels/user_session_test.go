@@ -0,0 +1,112 @@
+package pgmodels_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUserSession(t *testing.T) {
+	session := pgmodels.NewUserSession(3, "10.0.0.1", "Test Agent")
+	require.NotNil(t, session)
+	assert.Equal(t, 32, len(session.Token))
+	assert.Equal(t, common.Hash(session.Token), session.TokenDigest)
+	assert.NotEqual(t, session.Token, session.TokenDigest)
+	assert.False(t, session.CreatedAt.IsZero())
+	assert.Equal(t, session.CreatedAt, session.LastSeenAt)
+	assert.False(t, session.IsExpired())
+	assert.Nil(t, session.Validate())
+}
+
+func TestUserSessionValidate(t *testing.T) {
+	session := &pgmodels.UserSession{}
+	err := session.Validate()
+	require.NotNil(t, err)
+	assert.Equal(t, pgmodels.ErrUserSessionUserID, err.Errors["UserID"])
+	assert.Equal(t, pgmodels.ErrUserSessionToken, err.Errors["TokenDigest"])
+}
+
+func TestUserSessionIsExpired(t *testing.T) {
+	maxAge := time.Duration(common.Context().Config.Cookies.MaxAge) * time.Second
+	session := pgmodels.NewUserSession(3, "10.0.0.1", "Test Agent")
+	session.LastSeenAt = time.Now().UTC().Add(-maxAge + time.Minute)
+	assert.False(t, session.IsExpired())
+	session.LastSeenAt = time.Now().UTC().Add(-maxAge - time.Minute)
+	assert.True(t, session.IsExpired())
+}
+
+func TestUserSessionLifecycle(t *testing.T) {
+	db.LoadFixtures()
+	defer db.ForceFixtureReload()
+
+	user, err := pgmodels.UserByEmail(InstUser)
+	require.Nil(t, err)
+
+	first := pgmodels.NewUserSession(user.ID, "10.0.0.1", "Agent One")
+	first.AwaitingSecondFactor = true
+	require.Nil(t, first.Save())
+	second := pgmodels.NewUserSession(user.ID, "10.0.0.2", "Agent Two")
+	second.AwaitingSecondFactor = true
+	require.Nil(t, second.Save())
+
+	// Completing the second factor in one session doesn't
+	// complete it in the other.
+	require.Nil(t, first.CompleteSecondFactor())
+	reloaded, err := pgmodels.UserSessionByID(first.ID)
+	require.Nil(t, err)
+	assert.False(t, reloaded.AwaitingSecondFactor)
+	reloaded, err = pgmodels.UserSessionByID(second.ID)
+	require.Nil(t, err)
+	assert.True(t, reloaded.AwaitingSecondFactor)
+
+	// Look up by the token from the cookie.
+	session, err := pgmodels.UserSessionByToken(first.Token)
+	require.Nil(t, err)
+	assert.Equal(t, first.ID, session.ID)
+	assert.Equal(t, "Agent One", session.UserAgent)
+	assert.Empty(t, session.Token)
+
+	_, err = pgmodels.UserSessionByToken("not-a-real-token")
+	assert.True(t, pgmodels.IsNoRowError(err))
+
+	// Touch updates IP address right away, but last seen time
+	// only after the touch interval.
+	require.Nil(t, session.Touch("10.0.0.3"))
+	session, err = pgmodels.UserSessionByID(first.ID)
+	require.Nil(t, err)
+	assert.Equal(t, "10.0.0.3", session.IPAddress)
+
+	// Expired sessions aren't listed, and are deleted at next sign-in.
+	maxAge := time.Duration(common.Context().Config.Cookies.MaxAge) * time.Second
+	expired := pgmodels.NewUserSession(user.ID, "10.0.0.4", "Old Agent")
+	expired.LastSeenAt = time.Now().UTC().Add(-maxAge - time.Hour)
+	require.Nil(t, expired.Save())
+
+	sessions, err := pgmodels.UserSessionsForUser(user.ID)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(sessions))
+	assert.Equal(t, first.ID, sessions[0].ID)
+
+	require.Nil(t, pgmodels.UserSessionDeleteExpired(user.ID))
+	_, err = pgmodels.UserSessionByID(expired.ID)
+	assert.True(t, pgmodels.IsNoRowError(err))
+
+	// Delete one session.
+	require.Nil(t, second.Delete())
+	sessions, err = pgmodels.UserSessionsForUser(user.ID)
+	require.Nil(t, err)
+	assert.Equal(t, 1, len(sessions))
+
+	// Sign out everywhere.
+	count, err := pgmodels.UserSessionDeleteAll(user.ID)
+	require.Nil(t, err)
+	assert.Equal(t, 1, count)
+	sessions, err = pgmodels.UserSessionsForUser(user.ID)
+	require.Nil(t, err)
+	assert.Empty(t, sessions)
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/APTrust/registry/constants"
 	"github.com/APTrust/registry/db"
 	"github.com/APTrust/registry/pgmodels"
+	"github.com/pquerna/otp/totp"
 	"github.com/stretchr/stew/slice"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -232,6 +233,57 @@ func TestUserSignIn_Deactivated(t *testing.T) {
 	assert.Equal(t, common.ErrAccountDeactivated, err)
 }
 
+func TestUserLoginThrottle(t *testing.T) {
+	db.LoadFixtures()
+	defer db.ForceFixtureReload()
+
+	lockout := common.Context().Config.Lockout
+	user, err := pgmodels.UserByEmail(InstUser)
+	require.Nil(t, err)
+	require.Nil(t, user.CheckLoginThrottle())
+
+	// Each failure counts, and the last one locks the account.
+	for i := 1; i <= lockout.MaxFailures; i++ {
+		locked, err := user.RecordFailedLogin()
+		require.Nil(t, err)
+		assert.Equal(t, i == lockout.MaxFailures, locked, i)
+	}
+	user, err = pgmodels.UserByEmail(InstUser)
+	require.Nil(t, err)
+	assert.Equal(t, lockout.MaxFailures, user.FailedLoginAttempts)
+	assert.True(t, user.IsLocked())
+	assert.Equal(t, common.ErrAccountLocked, user.CheckLoginThrottle())
+
+	// Locked users can't sign in, even with the right password.
+	_, err = pgmodels.UserSignIn(InstUser, Password, "1.1.1.1")
+	assert.Equal(t, common.ErrAccountLocked, err)
+
+	// Unlocking clears the count.
+	require.Nil(t, user.Unlock())
+	user, err = pgmodels.UserByEmail(InstUser)
+	require.Nil(t, err)
+	assert.Equal(t, 0, user.FailedLoginAttempts)
+	assert.False(t, user.IsLocked())
+
+	// With a progressive delay, users have to wait after a failure.
+	baseDelay := lockout.BaseDelay
+	lockout.BaseDelay = time.Minute
+	defer func() { lockout.BaseDelay = baseDelay }()
+	_, err = user.RecordFailedLogin()
+	require.Nil(t, err)
+	assert.Equal(t, common.ErrTooManyAttempts, user.CheckLoginThrottle())
+	_, err = pgmodels.UserSignIn(InstUser, Password, "1.1.1.1")
+	assert.Equal(t, common.ErrTooManyAttempts, err)
+	user.LastFailedLoginAt = time.Now().UTC().Add(-2 * time.Minute)
+	assert.Nil(t, user.CheckLoginThrottle())
+	lockout.BaseDelay = baseDelay
+
+	// A successful sign-in clears the count.
+	user, err = pgmodels.UserSignIn(InstUser, Password, "1.1.1.1")
+	require.Nil(t, err)
+	assert.Equal(t, 0, user.FailedLoginAttempts)
+}
+
 func TestUserSaveDeleteUndelete(t *testing.T) {
 	db.LoadFixtures()
 	pwd, err := common.EncryptPassword("Duff Beer")
@@ -339,6 +391,36 @@ func TestUserValidate(t *testing.T) {
 
 	err = user.Validate()
 	require.Nil(t, err)
+
+	user.AlertPreferences = map[string]string{constants.AlertFailedFixity: "hourly"}
+	err = user.Validate()
+	require.NotNil(t, err)
+	assert.Equal(t, pgmodels.ErrUserAlertPrefs, err.Errors["AlertPreferences"])
+
+	user.AlertPreferences = map[string]string{"No Such Alert": constants.AlertDeliveryDaily}
+	err = user.Validate()
+	require.NotNil(t, err)
+	assert.Equal(t, pgmodels.ErrUserAlertPrefs, err.Errors["AlertPreferences"])
+
+	user.AlertPreferences = map[string]string{constants.AlertFailedFixity: constants.AlertDeliveryWeekly}
+	require.Nil(t, user.Validate())
+}
+
+func TestUserAlertDeliveryFor(t *testing.T) {
+	user := &pgmodels.User{}
+	assert.Equal(t, constants.AlertDeliveryImmediate, user.AlertDeliveryFor(constants.AlertFailedFixity))
+
+	user.AlertPreferences = map[string]string{
+		constants.AlertFailedFixity:    constants.AlertDeliveryDaily,
+		constants.AlertStalledItems:    constants.AlertDeliveryInApp,
+		constants.AlertPasswordChanged: constants.AlertDeliveryWeekly,
+	}
+	assert.Equal(t, constants.AlertDeliveryDaily, user.AlertDeliveryFor(constants.AlertFailedFixity))
+	assert.Equal(t, constants.AlertDeliveryInApp, user.AlertDeliveryFor(constants.AlertStalledItems))
+	assert.Equal(t, constants.AlertDeliveryImmediate, user.AlertDeliveryFor(constants.AlertDeletionCompleted))
+
+	// Security-related alerts always go out immediately.
+	assert.Equal(t, constants.AlertDeliveryImmediate, user.AlertDeliveryFor(constants.AlertPasswordChanged))
 }
 
 func TestIsSMSUser(t *testing.T) {
@@ -415,6 +497,14 @@ func TestTwoFactorMethod(t *testing.T) {
 	user.AuthyStatus = constants.TwoFactorAuthy
 	assert.Equal(t, constants.TwoFactorAuthy, user.TwoFactorMethod())
 
+	user.AuthyStatus = constants.TwoFactorTOTP
+	assert.Equal(t, constants.TwoFactorTOTP, user.TwoFactorMethod())
+	assert.True(t, user.IsTOTPUser())
+
+	user.AuthyStatus = constants.TwoFactorWebAuthn
+	assert.Equal(t, constants.TwoFactorWebAuthn, user.TwoFactorMethod())
+	assert.True(t, user.IsWebAuthnUser())
+
 	user.EnabledTwoFactor = false
 	assert.Equal(t, constants.TwoFactorNone, user.TwoFactorMethod())
 
@@ -423,6 +513,75 @@ func TestTwoFactorMethod(t *testing.T) {
 	assert.Equal(t, constants.TwoFactorNone, user.TwoFactorMethod())
 }
 
+func TestUserTOTP(t *testing.T) {
+	db.LoadFixtures()
+	user, err := pgmodels.UserByEmail(InstUser)
+	require.Nil(t, err)
+	require.False(t, user.IsTOTPUser())
+
+	_, err = user.TOTPKey()
+	assert.Equal(t, common.ErrNoTOTPSecret, err)
+
+	key, err := user.InitTOTP()
+	require.Nil(t, err)
+	require.NotNil(t, key)
+	assert.Equal(t, user.Email, key.AccountName())
+	assert.NotEmpty(t, user.PendingTOTPSecret)
+	assert.NotContains(t, user.PendingTOTPSecret, key.Secret())
+
+	// The new secret stays pending until the user confirms it.
+	_, err = user.TOTPKey()
+	assert.Equal(t, common.ErrNoTOTPSecret, err)
+
+	// Secret should survive the round trip to the DB.
+	reloadedUser, err := pgmodels.UserByID(user.ID)
+	require.Nil(t, err)
+	reloadedKey, err := reloadedUser.PendingTOTPKey()
+	require.Nil(t, err)
+	assert.Equal(t, key.Secret(), reloadedKey.Secret())
+
+	// Wrong code should not confirm.
+	ok, err := user.ConfirmTOTP("000000x")
+	require.Nil(t, err)
+	assert.False(t, ok)
+	assert.False(t, user.IsTOTPUser())
+
+	code, err := totp.GenerateCode(key.Secret(), time.Now())
+	require.Nil(t, err)
+	ok, err = user.ConfirmTOTP(code)
+	require.Nil(t, err)
+	assert.True(t, ok)
+	assert.True(t, user.IsTOTPUser())
+	assert.Equal(t, constants.TwoFactorTOTP, user.TwoFactorMethod())
+
+	// Same code can't be used twice, even after reload.
+	reloadedUser, err = pgmodels.UserByID(user.ID)
+	require.Nil(t, err)
+	assert.True(t, reloadedUser.IsTOTPUser())
+	ok, err = reloadedUser.VerifyTOTP(code)
+	require.Nil(t, err)
+	assert.False(t, ok)
+	assert.Empty(t, reloadedUser.PendingTOTPSecret)
+
+	// Starting setup again doesn't replace the working secret
+	// until the user confirms the new one.
+	newKey, err := reloadedUser.InitTOTP()
+	require.Nil(t, err)
+	currentKey, err := reloadedUser.TOTPKey()
+	require.Nil(t, err)
+	assert.Equal(t, key.Secret(), currentKey.Secret())
+	assert.NotEqual(t, key.Secret(), newKey.Secret())
+
+	code, err = totp.GenerateCode(newKey.Secret(), time.Now())
+	require.Nil(t, err)
+	ok, err = reloadedUser.ConfirmTOTP(code)
+	require.Nil(t, err)
+	assert.True(t, ok)
+	currentKey, err = reloadedUser.TOTPKey()
+	require.Nil(t, err)
+	assert.Equal(t, newKey.Secret(), currentKey.Secret())
+}
+
 func TestCreateOTPToken(t *testing.T) {
 	user, err := pgmodels.UserByEmail(InstUser)
 	require.Nil(t, err)
@@ -460,3 +619,23 @@ func TestUserCountryCodeAndPhone(t *testing.T) {
 	assert.EqualValues(t, 1, countryCode)
 	assert.Equal(t, "3135551234", phone)
 }
+
+func TestActiveAPTrustAdmins(t *testing.T) {
+	db.LoadFixtures()
+	admins, err := pgmodels.ActiveAPTrustAdmins()
+	require.Nil(t, err)
+	require.NotEmpty(t, admins)
+	for _, admin := range admins {
+		assert.Equal(t, constants.RoleSysAdmin, admin.Role)
+		assert.True(t, admin.DeactivatedAt.IsZero())
+	}
+}
+
+func TestActiveInstAdmins(t *testing.T) {
+	db.LoadFixtures()
+	admins, err := pgmodels.ActiveInstAdmins(2)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(admins))
+	assert.Equal(t, "admin@inst1.edu", admins[0].Email)
+	assert.Equal(t, constants.RoleInstAdmin, admins[0].Role)
+}
@@ -0,0 +1,263 @@
+package pgmodels
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+const (
+	ErrWebAuthnUserID       = "UserID is required."
+	ErrWebAuthnName         = "Please give this security key a name."
+	ErrWebAuthnCredentialID = "Credential ID is missing."
+	ErrWebAuthnPublicKey    = "Public key is missing."
+)
+
+// WebAuthnCredential is a hardware security key or platform passkey
+// that a user has registered as a second factor. Users can register
+// several, and can rename or revoke each one individually.
+//
+// CredentialID is the base64url-encoded ID the authenticator assigned
+// to this credential. PublicKey is the COSE-encoded key we use to
+// verify the authenticator's signatures.
+type WebAuthnCredential struct {
+	tableName struct{} `pg:"webauthn_credentials,alias:webauthn_credential"`
+	TimestampModel
+	UserID          int64     `json:"user_id"`
+	Name            string    `json:"name"`
+	CredentialID    string    `json:"credential_id"`
+	PublicKey       []byte    `json:"-"`
+	AttestationType string    `json:"attestation_type"`
+	Transports      []string  `json:"transports" pg:"transports,array"`
+	AAGUID          []byte    `json:"-" pg:"aaguid"`
+	SignCount       int64     `json:"sign_count" pg:",use_zero"`
+	CloneWarning    bool      `json:"clone_warning" pg:",use_zero"`
+	LastUsedAt      time.Time `json:"last_used_at"`
+	RevokedAt       time.Time `json:"revoked_at"`
+	User            *User     `json:"-" pg:"rel:has-one"`
+}
+
+// NewWebAuthnCredential returns a new, unsaved WebAuthnCredential for
+// the specified user, built from a credential that the WebAuthn library
+// has just verified during registration.
+func NewWebAuthnCredential(userID int64, name string, credential *webauthn.Credential) *WebAuthnCredential {
+	transports := make([]string, len(credential.Transport))
+	for i, transport := range credential.Transport {
+		transports[i] = string(transport)
+	}
+	return &WebAuthnCredential{
+		UserID:          userID,
+		Name:            name,
+		CredentialID:    base64.RawURLEncoding.EncodeToString(credential.ID),
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      transports,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       int64(credential.Authenticator.SignCount),
+	}
+}
+
+// WebAuthnCredentialByID returns the credential with the specified id.
+// Returns pg.ErrNoRows if there is no match.
+func WebAuthnCredentialByID(id int64) (*WebAuthnCredential, error) {
+	query := NewQuery().Where("id", "=", id)
+	return WebAuthnCredentialGet(query)
+}
+
+// WebAuthnCredentialGet returns the first credential matching the query.
+func WebAuthnCredentialGet(query *Query) (*WebAuthnCredential, error) {
+	var credential WebAuthnCredential
+	err := query.Select(&credential)
+	return &credential, err
+}
+
+// WebAuthnCredentialSelect returns all credentials matching the query.
+func WebAuthnCredentialSelect(query *Query) ([]*WebAuthnCredential, error) {
+	var credentials []*WebAuthnCredential
+	err := query.Select(&credentials)
+	return credentials, err
+}
+
+// WebAuthnCredentialsForUser returns all of the user's security keys,
+// including revoked keys, newest first.
+func WebAuthnCredentialsForUser(userID int64) ([]*WebAuthnCredential, error) {
+	query := NewQuery().
+		Where("user_id", "=", userID).
+		OrderBy("created_at", "desc").
+		OrderBy("id", "desc")
+	return WebAuthnCredentialSelect(query)
+}
+
+// ActiveWebAuthnCredentials returns the user's security keys that have
+// not been revoked, oldest first.
+func ActiveWebAuthnCredentials(userID int64) ([]*WebAuthnCredential, error) {
+	query := NewQuery().
+		Where("user_id", "=", userID).
+		IsNull("revoked_at").
+		OrderBy("created_at", "asc").
+		OrderBy("id", "asc")
+	return WebAuthnCredentialSelect(query)
+}
+
+// Save saves this credential to the database. This will peform an insert
+// if WebAuthnCredential.ID is zero. Otherwise, it updates.
+func (cred *WebAuthnCredential) Save() error {
+	cred.SetTimestamps()
+	err := cred.Validate()
+	if err != nil {
+		return err
+	}
+	if cred.ID == int64(0) {
+		return insert(cred)
+	}
+	return update(cred)
+}
+
+// Validate validates the model. This is called automatically on insert
+// and update.
+func (cred *WebAuthnCredential) Validate() *common.ValidationError {
+	errors := make(map[string]string)
+	if cred.UserID < 1 {
+		errors["UserID"] = ErrWebAuthnUserID
+	}
+	if common.IsEmptyString(cred.Name) {
+		errors["Name"] = ErrWebAuthnName
+	}
+	if common.IsEmptyString(cred.CredentialID) {
+		errors["CredentialID"] = ErrWebAuthnCredentialID
+	}
+	if len(cred.PublicKey) == 0 {
+		errors["PublicKey"] = ErrWebAuthnPublicKey
+	}
+	if len(errors) > 0 {
+		return &common.ValidationError{Errors: errors}
+	}
+	return nil
+}
+
+// IsRevoked returns true if this credential has been revoked.
+func (cred *WebAuthnCredential) IsRevoked() bool {
+	return !cred.RevokedAt.IsZero()
+}
+
+// Revoke revokes this credential, so it can no longer be used to
+// log in. Revoking a credential that's already revoked is a no-op.
+func (cred *WebAuthnCredential) Revoke() error {
+	if cred.IsRevoked() {
+		return nil
+	}
+	cred.RevokedAt = time.Now().UTC()
+	return cred.Save()
+}
+
+// RecordUse records a successful login with this credential, along
+// with the authenticator's new signature count and clone warning.
+func (cred *WebAuthnCredential) RecordUse(authenticator webauthn.Authenticator) error {
+	cred.SignCount = int64(authenticator.SignCount)
+	cred.CloneWarning = cred.CloneWarning || authenticator.CloneWarning
+	cred.LastUsedAt = time.Now().UTC()
+	return cred.Save()
+}
+
+// ToCredential converts this record to the form the WebAuthn
+// library uses to verify logins.
+func (cred *WebAuthnCredential) ToCredential() webauthn.Credential {
+	id, _ := base64.RawURLEncoding.DecodeString(cred.CredentialID)
+	transports := make([]protocol.AuthenticatorTransport, len(cred.Transports))
+	for i, transport := range cred.Transports {
+		transports[i] = protocol.AuthenticatorTransport(transport)
+	}
+	return webauthn.Credential{
+		ID:              id,
+		PublicKey:       cred.PublicKey,
+		AttestationType: cred.AttestationType,
+		Transport:       transports,
+		Authenticator: webauthn.Authenticator{
+			AAGUID:    cred.AAGUID,
+			SignCount: uint32(cred.SignCount),
+		},
+	}
+}
+
+// WebAuthnUser wraps a User and their active security keys so the
+// WebAuthn library can use them during registration and login.
+type WebAuthnUser struct {
+	*User
+	Credentials []*WebAuthnCredential
+}
+
+// NewWebAuthnUser returns a WebAuthnUser for user, with the user's
+// active security keys.
+func NewWebAuthnUser(user *User) (*WebAuthnUser, error) {
+	credentials, err := ActiveWebAuthnCredentials(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &WebAuthnUser{
+		User:        user,
+		Credentials: credentials,
+	}, nil
+}
+
+// WebAuthnID returns the user handle we send to authenticators. This
+// is the user's ID as an 8-byte big-endian integer. The spec says the
+// handle must not contain personally identifying information, so we
+// don't use the user's email address.
+func (wu *WebAuthnUser) WebAuthnID() []byte {
+	id := make([]byte, 8)
+	binary.BigEndian.PutUint64(id, uint64(wu.User.ID))
+	return id
+}
+
+// WebAuthnName returns the user's email address, which browsers
+// show when the user has more than one passkey for the Registry.
+func (wu *WebAuthnUser) WebAuthnName() string {
+	return wu.User.Email
+}
+
+// WebAuthnDisplayName returns the user's name.
+func (wu *WebAuthnUser) WebAuthnDisplayName() string {
+	return wu.User.Name
+}
+
+// WebAuthnIcon returns an empty string. Icons are deprecated in
+// the WebAuthn spec.
+func (wu *WebAuthnUser) WebAuthnIcon() string {
+	return ""
+}
+
+// WebAuthnCredentials returns the user's active security keys.
+func (wu *WebAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, len(wu.Credentials))
+	for i, cred := range wu.Credentials {
+		credentials[i] = cred.ToCredential()
+	}
+	return credentials
+}
+
+// CredentialDescriptors returns descriptors of the user's active
+// security keys. We send these during registration so the browser
+// won't register the same authenticator twice.
+func (wu *WebAuthnUser) CredentialDescriptors() []protocol.CredentialDescriptor {
+	descriptors := make([]protocol.CredentialDescriptor, len(wu.Credentials))
+	for i, cred := range wu.Credentials {
+		descriptors[i] = cred.ToCredential().Descriptor()
+	}
+	return descriptors
+}
+
+// Find returns the user's active security key with the specified
+// raw credential ID, or nil if there's no match.
+func (wu *WebAuthnUser) Find(credentialID []byte) *WebAuthnCredential {
+	encodedID := base64.RawURLEncoding.EncodeToString(credentialID)
+	for _, cred := range wu.Credentials {
+		if cred.CredentialID == encodedID {
+			return cred
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package pgmodels_test
+
+import (
+	"testing"
+
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCredential(userID int64, name string, id byte) *pgmodels.WebAuthnCredential {
+	return pgmodels.NewWebAuthnCredential(userID, name, &webauthn.Credential{
+		ID:              []byte{id, 0xfb, 0xff, 0x01},
+		PublicKey:       []byte{0xa5, 0x01, 0x02},
+		AttestationType: "none",
+		Transport:       []protocol.AuthenticatorTransport{protocol.USB, protocol.NFC},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:    make([]byte, 16),
+			SignCount: 7,
+		},
+	})
+}
+
+func TestNewWebAuthnCredential(t *testing.T) {
+	cred := newTestCredential(3, "YubiKey", 0x01)
+	assert.Equal(t, int64(3), cred.UserID)
+	assert.Equal(t, "YubiKey", cred.Name)
+	assert.Equal(t, "Afv_AQ", cred.CredentialID)
+	assert.Equal(t, []string{"usb", "nfc"}, cred.Transports)
+	assert.Equal(t, int64(7), cred.SignCount)
+	assert.Nil(t, cred.Validate())
+
+	// Converting back should give us what we started with.
+	converted := cred.ToCredential()
+	assert.Equal(t, []byte{0x01, 0xfb, 0xff, 0x01}, converted.ID)
+	assert.Equal(t, cred.PublicKey, converted.PublicKey)
+	assert.Equal(t, []protocol.AuthenticatorTransport{protocol.USB, protocol.NFC}, converted.Transport)
+	assert.Equal(t, uint32(7), converted.Authenticator.SignCount)
+}
+
+func TestWebAuthnCredentialValidate(t *testing.T) {
+	cred := &pgmodels.WebAuthnCredential{}
+	err := cred.Validate()
+	require.NotNil(t, err)
+	assert.Equal(t, pgmodels.ErrWebAuthnUserID, err.Errors["UserID"])
+	assert.Equal(t, pgmodels.ErrWebAuthnName, err.Errors["Name"])
+	assert.Equal(t, pgmodels.ErrWebAuthnCredentialID, err.Errors["CredentialID"])
+	assert.Equal(t, pgmodels.ErrWebAuthnPublicKey, err.Errors["PublicKey"])
+}
+
+func TestWebAuthnUser(t *testing.T) {
+	user := &pgmodels.User{Email: "user@example.com", Name: "Example User"}
+	user.ID = 258
+	wu := &pgmodels.WebAuthnUser{
+		User: user,
+		Credentials: []*pgmodels.WebAuthnCredential{
+			newTestCredential(258, "One", 0x01),
+			newTestCredential(258, "Two", 0x02),
+		},
+	}
+	assert.Equal(t, []byte{0, 0, 0, 0, 0, 0, 1, 2}, wu.WebAuthnID())
+	assert.Equal(t, "user@example.com", wu.WebAuthnName())
+	assert.Equal(t, "Example User", wu.WebAuthnDisplayName())
+	assert.Equal(t, 2, len(wu.WebAuthnCredentials()))
+	assert.Equal(t, 2, len(wu.CredentialDescriptors()))
+
+	assert.Equal(t, "Two", wu.Find([]byte{0x02, 0xfb, 0xff, 0x01}).Name)
+	assert.Nil(t, wu.Find([]byte{0x03}))
+}
+
+func TestWebAuthnCredentialSaveAndRevoke(t *testing.T) {
+	db.LoadFixtures()
+	user, err := pgmodels.UserByEmail(InstUser)
+	require.Nil(t, err)
+
+	first := newTestCredential(user.ID, "First Key", 0x01)
+	require.Nil(t, first.Save())
+	assert.True(t, first.ID > 0)
+	second := newTestCredential(user.ID, "Second Key", 0x02)
+	require.Nil(t, second.Save())
+
+	reloaded, err := pgmodels.WebAuthnCredentialByID(first.ID)
+	require.Nil(t, err)
+	assert.Equal(t, first.CredentialID, reloaded.CredentialID)
+	assert.Equal(t, first.PublicKey, reloaded.PublicKey)
+	assert.Equal(t, first.Transports, reloaded.Transports)
+
+	// Credential IDs must be unique.
+	dupe := newTestCredential(user.ID, "Dupe", 0x01)
+	assert.NotNil(t, dupe.Save())
+
+	wu, err := pgmodels.NewWebAuthnUser(user)
+	require.Nil(t, err)
+	assert.Equal(t, 2, len(wu.Credentials))
+
+	require.Nil(t, first.Revoke())
+	assert.True(t, first.IsRevoked())
+	revokedAt := first.RevokedAt
+	require.Nil(t, first.Revoke())
+	assert.Equal(t, revokedAt, first.RevokedAt)
+
+	active, err := pgmodels.ActiveWebAuthnCredentials(user.ID)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(active))
+	assert.Equal(t, second.ID, active[0].ID)
+
+	all, err := pgmodels.WebAuthnCredentialsForUser(user.ID)
+	require.Nil(t, err)
+	assert.Equal(t, 2, len(all))
+
+	require.Nil(t, second.RecordUse(webauthn.Authenticator{SignCount: 9, CloneWarning: true}))
+	reloaded, err = pgmodels.WebAuthnCredentialByID(second.ID)
+	require.Nil(t, err)
+	assert.Equal(t, int64(9), reloaded.SignCount)
+	assert.True(t, reloaded.CloneWarning)
+	assert.False(t, reloaded.LastUsedAt.IsZero())
+
+	instID, err := pgmodels.InstIDFor("WebAuthnCredential", second.ID)
+	require.Nil(t, err)
+	assert.Equal(t, user.InstitutionID, instID)
+}
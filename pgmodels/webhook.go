@@ -0,0 +1,243 @@
+package pgmodels
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/stretchr/stew/slice"
+)
+
+const (
+	ErrWebhookInstitutionID = "InstitutionID is required."
+	ErrWebhookURL           = "URL must be an absolute https URL on a public host."
+	ErrWebhookHost          = "URL must point to a public host. Webhooks can't be delivered to loopback, private or link-local addresses."
+	ErrWebhookSecret        = "Secret must be at least 16 characters."
+	ErrWebhookEventTypes    = "Choose at least one valid event type."
+)
+
+// ErrWebhookAddress is the error we return when a webhook delivery
+// tries to connect to an address that isn't publicly routable.
+var ErrWebhookAddress = errors.New("webhook host resolves to a loopback, private or link-local address")
+
+// AllowInsecureWebhooks lets webhooks use plain http and deliver to
+// loopback and private addresses. Tests set this so they can deliver
+// to local httptest servers. Never set it anywhere else: it would let
+// institutional admins make us send requests to internal services.
+var AllowInsecureWebhooks = false
+
+// Webhook is an endpoint to which we POST notifications about events
+// at an institution, such as completed ingests and restorations, new
+// alerts, and changes to deletion requests. Each payload is signed
+// with the webhook's secret so the receiver can verify it came from
+// us. See WebhookDelivery for the delivery and retry logic.
+type Webhook struct {
+	TimestampModel
+	InstitutionID int64    `json:"institution_id"`
+	URL           string   `json:"url" pg:"url"`
+	Secret        string   `json:"-"`
+	EventTypes    []string `json:"event_types" pg:"event_types,array"`
+	Enabled       bool     `json:"enabled" pg:",use_zero"`
+}
+
+// NewWebhook returns a new, enabled webhook for the specified
+// institution with a randomly generated secret.
+func NewWebhook(institutionID int64) *Webhook {
+	return &Webhook{
+		InstitutionID: institutionID,
+		Secret:        common.RandomToken(),
+		EventTypes:    make([]string, 0),
+		Enabled:       true,
+	}
+}
+
+// WebhookByID returns the webhook with the specified id.
+// Returns pg.ErrNoRows if there is no match.
+func WebhookByID(id int64) (*Webhook, error) {
+	query := NewQuery().Where("id", "=", id)
+	return WebhookGet(query)
+}
+
+// WebhookGet returns the first webhook matching the query.
+func WebhookGet(query *Query) (*Webhook, error) {
+	var webhook Webhook
+	err := query.Select(&webhook)
+	return &webhook, err
+}
+
+// WebhookSelect returns all webhooks matching the query.
+func WebhookSelect(query *Query) ([]*Webhook, error) {
+	var webhooks []*Webhook
+	err := query.Select(&webhooks)
+	return webhooks, err
+}
+
+// WebhooksFor returns the enabled webhooks at the specified institution
+// that subscribe to eventType.
+func WebhooksFor(institutionID int64, eventType string) ([]*Webhook, error) {
+	query := NewQuery().
+		Where("institution_id", "=", institutionID).
+		Where("enabled", "=", true).
+		OrderBy("id", "asc")
+	webhooks, err := WebhookSelect(query)
+	if err != nil {
+		return nil, err
+	}
+	subscribers := make([]*Webhook, 0)
+	for _, webhook := range webhooks {
+		if webhook.SubscribesTo(eventType) {
+			subscribers = append(subscribers, webhook)
+		}
+	}
+	return subscribers, nil
+}
+
+// Save saves this webhook to the database. This will peform an insert
+// if Webhook.ID is zero. Otherwise, it updates. If the secret is blank,
+// this generates a new one.
+func (webhook *Webhook) Save() error {
+	if webhook.Secret == "" {
+		webhook.Secret = common.RandomToken()
+	}
+	webhook.SetTimestamps()
+	err := webhook.Validate()
+	if err != nil {
+		return err
+	}
+	if webhook.ID == int64(0) {
+		return insert(webhook)
+	}
+	return update(webhook)
+}
+
+// Delete deletes this webhook and its delivery history.
+func (webhook *Webhook) Delete() error {
+	_, err := common.Context().DB.Model(webhook).WherePK().Delete()
+	return err
+}
+
+// Validate validates the model. This is called automatically on insert
+// and update.
+func (webhook *Webhook) Validate() *common.ValidationError {
+	errors := make(map[string]string)
+	if webhook.InstitutionID < 1 {
+		errors["InstitutionID"] = ErrWebhookInstitutionID
+	}
+	u, err := url.Parse(webhook.URL)
+	if err != nil || u.Host == "" || !isAllowedWebhookScheme(u.Scheme) {
+		errors["URL"] = ErrWebhookURL
+	} else if CheckWebhookHost(u.Hostname()) != nil {
+		errors["URL"] = ErrWebhookHost
+	}
+	if len(webhook.Secret) < 16 {
+		errors["Secret"] = ErrWebhookSecret
+	}
+	if len(webhook.EventTypes) == 0 {
+		errors["EventTypes"] = ErrWebhookEventTypes
+	}
+	for _, eventType := range webhook.EventTypes {
+		if !slice.Contains(constants.WebhookEvents, eventType) {
+			errors["EventTypes"] = ErrWebhookEventTypes
+		}
+	}
+	if len(errors) > 0 {
+		return &common.ValidationError{Errors: errors}
+	}
+	return nil
+}
+
+// SubscribesTo returns true if this webhook wants to hear about
+// eventType.
+func (webhook *Webhook) SubscribesTo(eventType string) bool {
+	return slice.Contains(webhook.EventTypes, eventType)
+}
+
+// Sign returns the signature for payload, which we send in the
+// X-Registry-Signature header. The signature is the hex-encoded
+// HMAC-SHA256 of the request body, keyed with the webhook's secret,
+// and prefixed with "sha256=". Receivers should compute the same
+// value and compare it in constant time.
+func (webhook *Webhook) Sign(payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(webhook.Secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func isAllowedWebhookScheme(scheme string) bool {
+	return scheme == "https" || (AllowInsecureWebhooks && scheme == "http")
+}
+
+// CheckWebhookHost returns ErrWebhookAddress if host is, or resolves
+// to, an address that isn't publicly routable. Hosts that don't
+// resolve pass this check. We can't deliver to them now, and
+// webhookDialControl checks every address we actually connect to,
+// so DNS changes can't be used to get around this check later.
+func CheckWebhookHost(host string) error {
+	if AllowInsecureWebhooks {
+		return nil
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrWebhookAddress
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if !IsPublicIP(ip) {
+			return ErrWebhookAddress
+		}
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if !IsPublicIP(addr.IP) {
+			return ErrWebhookAddress
+		}
+	}
+	return nil
+}
+
+// IsPublicIP returns false for loopback, private, link-local,
+// multicast and unspecified addresses, including the cloud metadata
+// address 169.254.169.254.
+func IsPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified())
+}
+
+// webhookDialControl runs after DNS resolution and before we connect
+// to a webhook endpoint. It refuses to connect to addresses that
+// aren't publicly routable, so a host that resolved to a public
+// address when the webhook was saved can't later be pointed at an
+// internal service.
+func webhookDialControl(network, address string, conn syscall.RawConn) error {
+	if AllowInsecureWebhooks {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !IsPublicIP(ip) {
+		return ErrWebhookAddress
+	}
+	return nil
+}
@@ -0,0 +1,319 @@
+package pgmodels
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/stretchr/stew/slice"
+)
+
+const (
+	ErrWebhookDeliveryWebhookID = "WebhookID is required."
+	ErrWebhookDeliveryEvent     = "Event type is missing or invalid."
+	ErrWebhookDeliveryKey       = "Event key is required."
+	ErrWebhookDeliveryPayload   = "Payload cannot be empty."
+	ErrWebhookDeliveryStatus    = "Status must be Pending, Success, or Failed."
+)
+
+// MaxWebhookAttempts is the number of times we'll try to deliver
+// a webhook payload before giving up and marking the delivery failed.
+const MaxWebhookAttempts = 8
+
+// WebhookRetryDelay is the time we wait after the first failed
+// delivery attempt. The delay doubles after each subsequent failure,
+// so with MaxWebhookAttempts = 8, we keep trying for a little over
+// four hours.
+const WebhookRetryDelay = time.Minute
+
+// WebhookClient is the HTTP client we use to deliver webhook payloads.
+// It connects only to public addresses, doesn't use a proxy, and
+// doesn't follow redirects, which could point anywhere.
+var WebhookClient = &http.Client{
+	Timeout: 15 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: webhookDialControl,
+		}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// WebhookDeliveryStatuses lists the valid values for
+// WebhookDelivery.Status.
+var WebhookDeliveryStatuses = []string{
+	constants.StatusFailed,
+	constants.StatusPending,
+	constants.StatusSuccess,
+}
+
+// WebhookDelivery records one event sent (or to be sent) to one webhook.
+// EventKey identifies the event, and is unique per webhook, so saving
+// a WorkItem or DeletionRequest multiple times does not send multiple
+// notifications about the same status change.
+type WebhookDelivery struct {
+	TimestampModel
+	WebhookID     int64     `json:"webhook_id"`
+	EventType     string    `json:"event_type"`
+	EventKey      string    `json:"event_key"`
+	Payload       string    `json:"payload"`
+	Status        string    `json:"status"`
+	Attempts      int       `json:"attempts" pg:",use_zero"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LastAttemptAt time.Time `json:"last_attempt_at"`
+	ResponseCode  int       `json:"response_code" pg:",use_zero"`
+	ErrorMessage  string    `json:"error_message"`
+	Webhook       *Webhook  `json:"-" pg:"rel:has-one"`
+}
+
+// WebhookPayload is the JSON body we POST to webhook endpoints.
+type WebhookPayload struct {
+	Event         string      `json:"event"`
+	InstitutionID int64       `json:"institution_id"`
+	CreatedAt     time.Time   `json:"created_at"`
+	Data          interface{} `json:"data"`
+}
+
+// WebhookDeliveryByID returns the delivery with the specified id,
+// along with its webhook. Returns pg.ErrNoRows if there is no match.
+func WebhookDeliveryByID(id int64) (*WebhookDelivery, error) {
+	query := NewQuery().Relations("Webhook").Where(`"webhook_delivery"."id"`, "=", id)
+	return WebhookDeliveryGet(query)
+}
+
+// WebhookDeliveryGet returns the first delivery matching the query.
+func WebhookDeliveryGet(query *Query) (*WebhookDelivery, error) {
+	var delivery WebhookDelivery
+	err := query.Select(&delivery)
+	return &delivery, err
+}
+
+// WebhookDeliverySelect returns all deliveries matching the query.
+func WebhookDeliverySelect(query *Query) ([]*WebhookDelivery, error) {
+	var deliveries []*WebhookDelivery
+	err := query.Select(&deliveries)
+	return deliveries, err
+}
+
+// QueueWebhookEvent creates a pending delivery of the specified event
+// for each of the institution's enabled webhooks that subscribe to
+// eventType. Param data is the event-specific part of the payload.
+// The scheduled webhook_deliveries job sends pending deliveries.
+//
+// If a webhook already has a delivery with the same eventKey, this
+// does not create another one.
+//
+// Callers should generally log errors rather than return them, since
+// a failure to notify should not prevent the underlying record from
+// being saved.
+func QueueWebhookEvent(institutionID int64, eventType, eventKey string, data interface{}) ([]*WebhookDelivery, error) {
+	webhooks, err := WebhooksFor(institutionID, eventType)
+	if err != nil || len(webhooks) == 0 {
+		return nil, err
+	}
+	payload, err := json.Marshal(&WebhookPayload{
+		Event:         eventType,
+		InstitutionID: institutionID,
+		CreatedAt:     time.Now().UTC(),
+		Data:          data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	deliveries := make([]*WebhookDelivery, 0)
+	for _, webhook := range webhooks {
+		delivery := &WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventType:     eventType,
+			EventKey:      eventKey,
+			Payload:       string(payload),
+			Status:        constants.StatusPending,
+			NextAttemptAt: time.Now().UTC(),
+			Webhook:       webhook,
+		}
+		delivery.SetTimestamps()
+		if valErr := delivery.Validate(); valErr != nil {
+			return deliveries, valErr
+		}
+		result, err := common.Context().DB.Model(delivery).
+			OnConflict("(webhook_id, event_key) DO NOTHING").
+			Insert()
+		if err != nil {
+			return deliveries, err
+		}
+		if result.RowsAffected() > 0 {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries, nil
+}
+
+// DeliverPendingWebhooks sends up to limit pending deliveries whose
+// next attempt is due, oldest first. It returns the number of
+// deliveries attempted. Failed attempts are recorded on the delivery
+// and retried later, so they're not errors here. If we can't record
+// an attempt, we log the error and go on to the next delivery.
+//
+// Deliveries for disabled webhooks stay pending, and go out if the
+// webhook is enabled again.
+func DeliverPendingWebhooks(client *http.Client, limit int) (int, error) {
+	query := NewQuery().
+		Relations("Webhook").
+		Where(`"webhook_delivery"."status"`, "=", constants.StatusPending).
+		Where(`"webhook_delivery"."next_attempt_at"`, "<=", time.Now().UTC()).
+		Where(`"webhook"."enabled"`, "=", true).
+		OrderBy(`"webhook_delivery"."next_attempt_at"`, "asc").
+		Limit(limit)
+	deliveries, err := WebhookDeliverySelect(query)
+	if err != nil {
+		return 0, err
+	}
+	attempted := 0
+	for _, delivery := range deliveries {
+		err = delivery.Deliver(client)
+		if err != nil {
+			common.Context().Log.Error().Msgf("Error saving webhook delivery %d: %v", delivery.ID, err)
+			continue
+		}
+		attempted++
+	}
+	return attempted, nil
+}
+
+// Save saves this delivery to the database. This will peform an insert
+// if WebhookDelivery.ID is zero. Otherwise, it updates.
+func (delivery *WebhookDelivery) Save() error {
+	delivery.SetTimestamps()
+	err := delivery.Validate()
+	if err != nil {
+		return err
+	}
+	if delivery.ID == int64(0) {
+		return insert(delivery)
+	}
+	return update(delivery)
+}
+
+// Validate validates the model. This is called automatically on insert
+// and update.
+func (delivery *WebhookDelivery) Validate() *common.ValidationError {
+	errors := make(map[string]string)
+	if delivery.WebhookID < 1 {
+		errors["WebhookID"] = ErrWebhookDeliveryWebhookID
+	}
+	if !slice.Contains(constants.WebhookEvents, delivery.EventType) {
+		errors["EventType"] = ErrWebhookDeliveryEvent
+	}
+	if common.IsEmptyString(delivery.EventKey) {
+		errors["EventKey"] = ErrWebhookDeliveryKey
+	}
+	if common.IsEmptyString(delivery.Payload) {
+		errors["Payload"] = ErrWebhookDeliveryPayload
+	}
+	if !slice.Contains(WebhookDeliveryStatuses, delivery.Status) {
+		errors["Status"] = ErrWebhookDeliveryStatus
+	}
+	if len(errors) > 0 {
+		return &common.ValidationError{Errors: errors}
+	}
+	return nil
+}
+
+// Deliver POSTs this delivery's payload to its webhook and records the
+// outcome. Any 2xx response counts as success. On failure, this schedules
+// the next attempt with exponential backoff, or marks the delivery failed
+// once it has been attempted MaxWebhookAttempts times.
+//
+// This returns an error only if it can't load the webhook or save the
+// delivery. Check Status, ResponseCode and ErrorMessage for the result
+// of the attempt itself.
+func (delivery *WebhookDelivery) Deliver(client *http.Client) error {
+	if delivery.Webhook == nil {
+		webhook, err := WebhookByID(delivery.WebhookID)
+		if err != nil {
+			return err
+		}
+		delivery.Webhook = webhook
+	}
+	now := time.Now().UTC()
+	delivery.Attempts++
+	delivery.LastAttemptAt = now
+	delivery.ResponseCode = 0
+	delivery.ErrorMessage = ""
+
+	err := delivery.post(client)
+	if err == nil {
+		delivery.Status = constants.StatusSuccess
+		delivery.NextAttemptAt = time.Time{}
+	} else {
+		delivery.ErrorMessage = err.Error()
+		if delivery.Attempts >= MaxWebhookAttempts {
+			delivery.Status = constants.StatusFailed
+			delivery.NextAttemptAt = time.Time{}
+		} else {
+			delivery.Status = constants.StatusPending
+			delivery.NextAttemptAt = now.Add(delivery.RetryDelay())
+		}
+	}
+	return delivery.Save()
+}
+
+// RetryDelay returns the time to wait before the next attempt, based
+// on the number of attempts made so far.
+func (delivery *WebhookDelivery) RetryDelay() time.Duration {
+	if delivery.Attempts < 1 {
+		return 0
+	}
+	return WebhookRetryDelay * time.Duration(1<<(delivery.Attempts-1))
+}
+
+func (delivery *WebhookDelivery) post(client *http.Client) error {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, delivery.Webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "APTrust-Registry-Webhook")
+	req.Header.Set(constants.WebhookEventHeader, delivery.EventType)
+	req.Header.Set(constants.WebhookDeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(constants.WebhookSignatureHeader, delivery.Webhook.Sign(body))
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	delivery.ResponseCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Replay creates and saves a new pending delivery with the same
+// payload as this one, so the event will be sent again. The original
+// delivery is left as is, for the record. The new delivery's EventKey
+// is the original key plus a replay suffix, since event keys must be
+// unique per webhook.
+func (delivery *WebhookDelivery) Replay() (*WebhookDelivery, error) {
+	replay := &WebhookDelivery{
+		WebhookID:     delivery.WebhookID,
+		EventType:     delivery.EventType,
+		EventKey:      fmt.Sprintf("%s/replay/%d", delivery.EventKey, time.Now().UnixNano()),
+		Payload:       delivery.Payload,
+		Status:        constants.StatusPending,
+		NextAttemptAt: time.Now().UTC(),
+		Webhook:       delivery.Webhook,
+	}
+	return replay, replay.Save()
+}
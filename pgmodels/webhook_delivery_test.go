@@ -0,0 +1,192 @@
+package pgmodels_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookDeliveryValidate(t *testing.T) {
+	delivery := &pgmodels.WebhookDelivery{}
+	err := delivery.Validate()
+	require.NotNil(t, err)
+	assert.Equal(t, pgmodels.ErrWebhookDeliveryWebhookID, err.Errors["WebhookID"])
+	assert.Equal(t, pgmodels.ErrWebhookDeliveryEvent, err.Errors["EventType"])
+	assert.Equal(t, pgmodels.ErrWebhookDeliveryKey, err.Errors["EventKey"])
+	assert.Equal(t, pgmodels.ErrWebhookDeliveryPayload, err.Errors["Payload"])
+	assert.Equal(t, pgmodels.ErrWebhookDeliveryStatus, err.Errors["Status"])
+}
+
+func TestWebhookDeliveryRetryDelay(t *testing.T) {
+	delivery := &pgmodels.WebhookDelivery{}
+	assert.Equal(t, time.Duration(0), delivery.RetryDelay())
+	delivery.Attempts = 1
+	assert.Equal(t, pgmodels.WebhookRetryDelay, delivery.RetryDelay())
+	delivery.Attempts = 4
+	assert.Equal(t, 8*pgmodels.WebhookRetryDelay, delivery.RetryDelay())
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+
+	// Our test server is on localhost, over http.
+	pgmodels.AllowInsecureWebhooks = true
+	defer func() { pgmodels.AllowInsecureWebhooks = false }()
+
+	responseCode := http.StatusOK
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(responseCode)
+	}))
+	defer server.Close()
+
+	webhook := pgmodels.NewWebhook(2)
+	webhook.URL = server.URL
+	webhook.EventTypes = []string{constants.WebhookAlertCreated}
+	require.Nil(t, webhook.Save())
+
+	data := map[string]interface{}{"id": 99}
+	deliveries, err := pgmodels.QueueWebhookEvent(2, constants.WebhookAlertCreated, "alert/99", data)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(deliveries))
+	delivery := deliveries[0]
+	assert.Equal(t, constants.StatusPending, delivery.Status)
+
+	// Same event key should not create a second delivery.
+	deliveries, err = pgmodels.QueueWebhookEvent(2, constants.WebhookAlertCreated, "alert/99", data)
+	require.Nil(t, err)
+	assert.Empty(t, deliveries)
+
+	// Nobody subscribes to this event.
+	deliveries, err = pgmodels.QueueWebhookEvent(2, constants.WebhookWorkItemCompleted, "work_item/1/Success", data)
+	require.Nil(t, err)
+	assert.Empty(t, deliveries)
+
+	// Successful delivery
+	count, err := pgmodels.DeliverPendingWebhooks(server.Client(), 100)
+	require.Nil(t, err)
+	assert.Equal(t, 1, count)
+	require.NotNil(t, received)
+	assert.Equal(t, webhook.Sign(body), received.Header.Get(constants.WebhookSignatureHeader))
+	assert.Equal(t, constants.WebhookAlertCreated, received.Header.Get(constants.WebhookEventHeader))
+	assert.Equal(t, strconv.FormatInt(delivery.ID, 10), received.Header.Get(constants.WebhookDeliveryHeader))
+
+	var payload pgmodels.WebhookPayload
+	require.Nil(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, constants.WebhookAlertCreated, payload.Event)
+	assert.Equal(t, int64(2), payload.InstitutionID)
+
+	delivery, err = pgmodels.WebhookDeliveryByID(delivery.ID)
+	require.Nil(t, err)
+	assert.Equal(t, constants.StatusSuccess, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, http.StatusOK, delivery.ResponseCode)
+
+	// Nothing left to deliver.
+	count, err = pgmodels.DeliverPendingWebhooks(server.Client(), 100)
+	require.Nil(t, err)
+	assert.Equal(t, 0, count)
+
+	// Failed replay is rescheduled with backoff.
+	responseCode = http.StatusInternalServerError
+	replay, err := delivery.Replay()
+	require.Nil(t, err)
+	assert.NotEqual(t, delivery.ID, replay.ID)
+	assert.Equal(t, delivery.Payload, replay.Payload)
+	require.Nil(t, replay.Deliver(server.Client()))
+	assert.Equal(t, constants.StatusPending, replay.Status)
+	assert.Equal(t, http.StatusInternalServerError, replay.ResponseCode)
+	assert.NotEmpty(t, replay.ErrorMessage)
+	assert.True(t, replay.NextAttemptAt.After(time.Now().UTC()))
+
+	// After too many attempts, we give up.
+	replay.Attempts = pgmodels.MaxWebhookAttempts - 1
+	require.Nil(t, replay.Deliver(server.Client()))
+	assert.Equal(t, constants.StatusFailed, replay.Status)
+	assert.True(t, replay.NextAttemptAt.IsZero())
+}
+
+func TestWebhookDeliveryWorkItemRequeue(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+
+	webhook := pgmodels.NewWebhook(2)
+	webhook.URL = "https://example.com/hooks"
+	webhook.EventTypes = []string{constants.WebhookWorkItemCompleted}
+	require.Nil(t, webhook.Save())
+
+	countDeliveries := func() int {
+		query := pgmodels.NewQuery().Where("webhook_id", "=", webhook.ID)
+		deliveries, err := pgmodels.WebhookDeliverySelect(query)
+		require.Nil(t, err)
+		return len(deliveries)
+	}
+
+	item, err := pgmodels.WorkItemByID(1)
+	require.Nil(t, err)
+	require.EqualValues(t, 2, item.InstitutionID)
+	item.Status = constants.StatusSuccess
+	require.Nil(t, item.Save())
+	assert.Equal(t, 1, countDeliveries())
+
+	// Saving the completed item again doesn't notify again.
+	item.Note = "Updated note"
+	require.Nil(t, item.Save())
+	assert.Equal(t, 1, countDeliveries())
+
+	// An item that's requeued and succeeds again does.
+	require.Nil(t, item.SetForRequeue(constants.StageReceive))
+	item.Status = constants.StatusSuccess
+	require.Nil(t, item.Save())
+	assert.Equal(t, 2, countDeliveries())
+}
+
+func TestWebhookDeliveryDisabled(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+
+	pgmodels.AllowInsecureWebhooks = true
+	defer func() { pgmodels.AllowInsecureWebhooks = false }()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	webhook := pgmodels.NewWebhook(2)
+	webhook.URL = server.URL
+	webhook.EventTypes = []string{constants.WebhookAlertCreated}
+	require.Nil(t, webhook.Save())
+	deliveries, err := pgmodels.QueueWebhookEvent(2, constants.WebhookAlertCreated, "alert/99", map[string]interface{}{"id": 99})
+	require.Nil(t, err)
+	require.Len(t, deliveries, 1)
+
+	// Deliveries for a disabled webhook wait until it's enabled again.
+	webhook.Enabled = false
+	require.Nil(t, webhook.Save())
+	count, err := pgmodels.DeliverPendingWebhooks(server.Client(), 100)
+	require.Nil(t, err)
+	assert.Equal(t, 0, count)
+	delivery, err := pgmodels.WebhookDeliveryByID(deliveries[0].ID)
+	require.Nil(t, err)
+	assert.Equal(t, constants.StatusPending, delivery.Status)
+
+	webhook.Enabled = true
+	require.Nil(t, webhook.Save())
+	count, err = pgmodels.DeliverPendingWebhooks(server.Client(), 100)
+	require.Nil(t, err)
+	assert.Equal(t, 1, count)
+}
@@ -0,0 +1,136 @@
+package pgmodels_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookValidate(t *testing.T) {
+	webhook := &pgmodels.Webhook{}
+	err := webhook.Validate()
+	require.NotNil(t, err)
+	assert.Equal(t, pgmodels.ErrWebhookInstitutionID, err.Errors["InstitutionID"])
+	assert.Equal(t, pgmodels.ErrWebhookURL, err.Errors["URL"])
+	assert.Equal(t, pgmodels.ErrWebhookSecret, err.Errors["Secret"])
+	assert.Equal(t, pgmodels.ErrWebhookEventTypes, err.Errors["EventTypes"])
+
+	webhook = pgmodels.NewWebhook(2)
+	webhook.URL = "https://example.com/hook"
+	webhook.EventTypes = []string{constants.WebhookAlertCreated}
+	assert.Nil(t, webhook.Validate())
+
+	for _, url := range []string{"example.com/hook", "ftp://example.com/hook", "/hook", "https://"} {
+		webhook.URL = url
+		err = webhook.Validate()
+		require.NotNil(t, err, url)
+		assert.Equal(t, pgmodels.ErrWebhookURL, err.Errors["URL"])
+	}
+
+	// Webhooks must use https and point to public hosts.
+	for _, url := range []string{"http://example.com/hook", "https://127.0.0.1/hook", "https://169.254.169.254/latest/meta-data", "https://10.0.0.5/hook", "https://[::1]/hook", "https://0.0.0.0/hook", "https://localhost:8080/hook"} {
+		webhook.URL = url
+		err = webhook.Validate()
+		require.NotNil(t, err, url)
+		assert.NotEmpty(t, err.Errors["URL"], url)
+	}
+
+	webhook.URL = "https://example.com/hook"
+	webhook.EventTypes = []string{constants.WebhookAlertCreated, "object.exploded"}
+	err = webhook.Validate()
+	require.NotNil(t, err)
+	assert.Equal(t, pgmodels.ErrWebhookEventTypes, err.Errors["EventTypes"])
+}
+
+func TestIsPublicIP(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "0.0.0.0", "::1", "fe80::1", "fd00::1", "::ffff:127.0.0.1"} {
+		assert.False(t, pgmodels.IsPublicIP(net.ParseIP(addr)), addr)
+	}
+	for _, addr := range []string{"8.8.8.8", "93.184.216.34", "2606:4700:4700::1111"} {
+		assert.True(t, pgmodels.IsPublicIP(net.ParseIP(addr)), addr)
+	}
+}
+
+func TestWebhookClientRefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// The host check happens when we connect, so it applies
+	// even if a webhook's host resolves differently than it
+	// did when the webhook was saved.
+	_, err := pgmodels.WebhookClient.Get(server.URL)
+	require.NotNil(t, err)
+	assert.True(t, errors.Is(err, pgmodels.ErrWebhookAddress))
+}
+
+func TestWebhookSign(t *testing.T) {
+	webhook := &pgmodels.Webhook{Secret: "0123456789abcdef"}
+	payload := []byte(`{"event":"alert.created"}`)
+	mac := hmac.New(sha256.New, []byte(webhook.Secret))
+	mac.Write(payload)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	assert.Equal(t, expected, webhook.Sign(payload))
+
+	webhook.Secret = "fedcba9876543210"
+	assert.NotEqual(t, expected, webhook.Sign(payload))
+}
+
+func TestWebhookSubscribesTo(t *testing.T) {
+	webhook := &pgmodels.Webhook{EventTypes: []string{constants.WebhookAlertCreated}}
+	assert.True(t, webhook.SubscribesTo(constants.WebhookAlertCreated))
+	assert.False(t, webhook.SubscribesTo(constants.WebhookWorkItemCompleted))
+}
+
+func TestWebhookSaveAndSelect(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+
+	webhook := pgmodels.NewWebhook(2)
+	webhook.URL = "https://example.com/hook"
+	webhook.EventTypes = []string{constants.WebhookAlertCreated, constants.WebhookWorkItemCompleted}
+	require.Nil(t, webhook.Save())
+	assert.True(t, webhook.ID > 0)
+
+	disabled := pgmodels.NewWebhook(2)
+	disabled.URL = "https://example.com/disabled"
+	disabled.EventTypes = []string{constants.WebhookAlertCreated}
+	disabled.Enabled = false
+	require.Nil(t, disabled.Save())
+
+	saved, err := pgmodels.WebhookByID(webhook.ID)
+	require.Nil(t, err)
+	assert.Equal(t, webhook.URL, saved.URL)
+	assert.Equal(t, webhook.Secret, saved.Secret)
+	assert.Equal(t, webhook.EventTypes, saved.EventTypes)
+	assert.True(t, saved.Enabled)
+
+	webhooks, err := pgmodels.WebhooksFor(2, constants.WebhookAlertCreated)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(webhooks))
+	assert.Equal(t, webhook.ID, webhooks[0].ID)
+
+	webhooks, err = pgmodels.WebhooksFor(2, constants.WebhookDeletionRequest)
+	require.Nil(t, err)
+	assert.Empty(t, webhooks)
+
+	webhooks, err = pgmodels.WebhooksFor(3, constants.WebhookAlertCreated)
+	require.Nil(t, err)
+	assert.Empty(t, webhooks)
+
+	require.Nil(t, webhook.Delete())
+	_, err = pgmodels.WebhookByID(webhook.ID)
+	assert.True(t, pgmodels.IsNoRowError(err))
+}
@@ -139,6 +139,7 @@ func (item *WorkItem) Save() error {
 	if validationErr != nil {
 		return validationErr
 	}
+	justCompleted := item.HasCompleted() && item.statusChanged()
 	var err error
 	if item.ID == int64(0) {
 		err = insert(item)
@@ -148,9 +149,84 @@ func (item *WorkItem) Save() error {
 	if err == nil && item.Action == constants.ActionRestoreObject && item.Status == constants.StatusSuccess {
 		item.AlertOnSuccessfulSpotTest()
 	}
+	if err == nil && item.IsRestoration() && item.HasCompleted() {
+		item.AlertOnCompletedRestorationBatch()
+	}
+	if err == nil && justCompleted {
+		item.NotifyWebhooks()
+	}
 	return err
 }
 
+// statusChanged returns true if this item's status differs from the
+// status saved in the database, or if the item hasn't been saved yet.
+// If we can't tell, this returns true.
+func (item *WorkItem) statusChanged() bool {
+	if item.ID == int64(0) {
+		return true
+	}
+	var savedStatus string
+	err := common.Context().DB.Model((*WorkItem)(nil)).
+		Column("status").
+		Where("id = ?", item.ID).
+		Select(&savedStatus)
+	if err != nil {
+		common.Context().Log.Error().Msgf("Error loading saved status of WorkItem %d: %v", item.ID, err)
+		return true
+	}
+	return savedStatus != item.Status
+}
+
+// IsRestoration returns true if this is an object, file,
+// or Glacier restoration.
+func (item *WorkItem) IsRestoration() bool {
+	return item.Action == constants.ActionRestoreObject ||
+		item.Action == constants.ActionRestoreFile ||
+		item.Action == constants.ActionGlacierRestore
+}
+
+// AlertOnCompletedRestorationBatch sends a Restoration Completed alert
+// if this item is the last one to complete in a restoration batch.
+// Most restorations are not part of a batch, in which case this does
+// nothing. See RestorationBatch.AlertIfComplete.
+//
+// This returns the alert if one was created, nil otherwise. Errors are
+// logged rather than returned, because they should not prevent the
+// WorkItem from being saved.
+func (item *WorkItem) AlertOnCompletedRestorationBatch() *Alert {
+	ctx := common.Context()
+	batch, err := RestorationBatchForWorkItem(item.ID)
+	if err != nil {
+		ctx.Log.Error().Msgf("AlertOnCompletedRestorationBatch: Error getting batch for WorkItem %d: %v", item.ID, err)
+		return nil
+	}
+	if batch == nil {
+		return nil
+	}
+	alert, err := batch.AlertIfComplete()
+	if err != nil {
+		ctx.Log.Error().Msgf("AlertOnCompletedRestorationBatch: Error creating alert for restoration batch %d: %v", batch.ID, err)
+	} else if alert != nil {
+		ctx.Log.Info().Msgf("Created restoration batch alert %d for batch %d going to %d users", alert.ID, batch.ID, len(alert.Users))
+	}
+	return alert
+}
+
+// NotifyWebhooks queues a work_item.completed event for the institution's
+// webhooks. Save calls this when the item moves into a terminal status,
+// but not when an item that's already complete is saved again. The
+// event key includes the status and the time of the change, so an item
+// that is requeued and reaches the same status again sends a new event.
+// Errors are logged rather than returned, because they should not
+// prevent the WorkItem from being saved.
+func (item *WorkItem) NotifyWebhooks() {
+	key := fmt.Sprintf("work_item/%d/%s/%s", item.ID, item.Status, item.UpdatedAt.Format(time.RFC3339Nano))
+	_, err := QueueWebhookEvent(item.InstitutionID, constants.WebhookWorkItemCompleted, key, item)
+	if err != nil {
+		common.Context().Log.Error().Msgf("Error queueing webhook event for WorkItem %d: %v", item.ID, err)
+	}
+}
+
 // SetForRequeue sets properies so this item can be requeued.
 // Note that it saves the object. It will return
 // constants.ErrInvalidRequeue if the stage is not valid, and
@@ -339,6 +415,174 @@ func (item *WorkItem) AlertOnSuccessfulSpotTest() *Alert {
 	return restorationAlert
 }
 
+// StalledItemThresholds describes how long a WorkItem can sit in each
+// stage before we consider it stalled. Stages that move large amounts
+// of data (storage, storage validation, Glacier restoration) get more
+// time than stages that only inspect metadata. Stages not listed here
+// use DefaultStalledItemThreshold.
+var StalledItemThresholds = map[string]time.Duration{
+	constants.StageReceive:              6 * time.Hour,
+	constants.StageValidate:             12 * time.Hour,
+	constants.StageReingestCheck:        6 * time.Hour,
+	constants.StageCopyToStaging:        12 * time.Hour,
+	constants.StageFormatIdentification: 12 * time.Hour,
+	constants.StageStore:                24 * time.Hour,
+	constants.StageStorageValidation:    24 * time.Hour,
+	constants.StageRecord:               6 * time.Hour,
+	constants.StageCleanup:              6 * time.Hour,
+	constants.StageRequested:            24 * time.Hour,
+	constants.StageRestoring:            72 * time.Hour,
+}
+
+// DefaultStalledItemThreshold is the threshold for stages that don't
+// appear in StalledItemThresholds.
+const DefaultStalledItemThreshold = 24 * time.Hour
+
+// StalledItemThreshold returns the amount of time a WorkItem may
+// spend in the specified stage before we consider it stalled.
+func StalledItemThreshold(stage string) time.Duration {
+	if threshold, ok := StalledItemThresholds[stage]; ok {
+		return threshold
+	}
+	return DefaultStalledItemThreshold
+}
+
+// minStalledItemThreshold returns the shortest of all stalled item
+// thresholds. We use this to narrow the initial database query.
+func minStalledItemThreshold() time.Duration {
+	min := DefaultStalledItemThreshold
+	for _, threshold := range StalledItemThresholds {
+		if threshold < min {
+			min = threshold
+		}
+	}
+	return min
+}
+
+// InCurrentStageSince returns the time at which this item entered its
+// current state. For started items, that's StageStartedAt. For pending
+// items, it's QueuedAt. For all other items, this returns a zero time.
+func (item *WorkItem) InCurrentStageSince() time.Time {
+	switch item.Status {
+	case constants.StatusStarted:
+		return item.StageStartedAt
+	case constants.StatusPending:
+		return item.QueuedAt
+	}
+	return time.Time{}
+}
+
+// IsStalled returns true if this item is started or pending and has
+// been sitting in its current stage longer than the threshold for that
+// stage, as of time asOf. Items that were never queued or started are
+// not considered stalled, because they haven't been picked up by the
+// preservation services workers yet.
+func (item *WorkItem) IsStalled(asOf time.Time) bool {
+	since := item.InCurrentStageSince()
+	if since.IsZero() {
+		return false
+	}
+	return since.Add(StalledItemThreshold(item.Stage)).Before(asOf)
+}
+
+// WorkItemsStalled returns all WorkItems that were stalled as of time
+// asOf, and for which we have not already created a stalled items alert.
+// If an item has moved into a new stage since the last alert and stalled
+// again, it will be included in the results. As in InCurrentStageSince,
+// pending items entered their stage when they were queued, even if
+// stage_started_at is left over from an earlier stage.
+func WorkItemsStalled(asOf time.Time) ([]*WorkItem, error) {
+	var candidates []*WorkItem
+	cutoff := asOf.Add(-1 * minStalledItemThreshold())
+	sql := `select wi.* from work_items wi
+		where ((wi.status = ? and wi.stage_started_at < ?)
+		or (wi.status = ? and wi.queued_at < ?))
+		and not exists (
+			select 1 from alerts_work_items awi
+			inner join alerts a on a.id = awi.alert_id
+			where awi.work_item_id = wi.id
+			and a.type = ?
+			and a.created_at > case when wi.status = ? then wi.stage_started_at else wi.queued_at end)
+		order by wi.institution_id, wi.id`
+	_, err := common.Context().DB.Query(&candidates, sql,
+		constants.StatusStarted, cutoff,
+		constants.StatusPending, cutoff,
+		constants.AlertStalledItems, constants.StatusStarted)
+	if err != nil {
+		return nil, err
+	}
+	stalled := make([]*WorkItem, 0)
+	for _, item := range candidates {
+		if item.IsStalled(asOf) {
+			stalled = append(stalled, item)
+		}
+	}
+	return stalled, nil
+}
+
+// AlertOnStalledWorkItems finds all WorkItems that have stalled and
+// creates one Stalled Work Items alert per institution. The alerts go
+// to APTrust admins and are linked to the stalled items through the
+// alerts_work_items table, so we won't alert on the same items twice.
+//
+// This returns the alerts it created, which may be an empty list if
+// nothing is stalled.
+func AlertOnStalledWorkItems() ([]*Alert, error) {
+	ctx := common.Context()
+	alerts := make([]*Alert, 0)
+	items, err := WorkItemsStalled(time.Now().UTC())
+	if err != nil {
+		return alerts, err
+	}
+	if len(items) == 0 {
+		return alerts, nil
+	}
+	admins, err := ActiveAPTrustAdmins()
+	if err != nil {
+		return alerts, err
+	}
+
+	// Group items by institution, preserving the order in which
+	// the institutions appear.
+	instIDs := make([]int64, 0)
+	itemsByInst := make(map[int64][]*WorkItem)
+	for _, item := range items {
+		if _, ok := itemsByInst[item.InstitutionID]; !ok {
+			instIDs = append(instIDs, item.InstitutionID)
+		}
+		itemsByInst[item.InstitutionID] = append(itemsByInst[item.InstitutionID], item)
+	}
+
+	registryURL := fmt.Sprintf("%s://%s", ctx.Config.HTTPScheme(), ctx.Config.Cookies.Domain)
+	for _, instID := range instIDs {
+		inst, err := InstitutionByID(instID)
+		if err != nil {
+			ctx.Log.Error().Msgf("AlertOnStalledWorkItems: Error getting institution %d: %v", instID, err)
+			return alerts, err
+		}
+		alertData := map[string]interface{}{
+			"InstitutionName": inst.Name,
+			"WorkItems":       itemsByInst[instID],
+			"RegistryURL":     registryURL,
+		}
+		alert := &Alert{
+			InstitutionID: instID,
+			Type:          constants.AlertStalledItems,
+			Subject:       fmt.Sprintf("Stalled Work Items at %s", inst.Identifier),
+			Users:         admins,
+			WorkItems:     itemsByInst[instID],
+		}
+		stalledAlert, err := CreateAlert(alert, "alerts/stalled_work_items.txt", alertData)
+		if err != nil {
+			ctx.Log.Error().Msgf("AlertOnStalledWorkItems: CreateAlert returned error for institution %s: %v", inst.Identifier, err)
+			return alerts, err
+		}
+		ctx.Log.Info().Msgf("Created stalled items alert %d for %d WorkItems at %s", stalledAlert.ID, len(itemsByInst[instID]), inst.Identifier)
+		alerts = append(alerts, stalledAlert)
+	}
+	return alerts, nil
+}
+
 // LastSuccessfulIngest returns the last successful
 // ingest WorkItem for the specified intellectual object id.
 func LastSuccessfulIngest(objID int64) (*WorkItem, error) {
@@ -410,6 +654,18 @@ func NewItemFromLastSuccessfulIngest(objID int64) (*WorkItem, error) {
 // that the object and file have no pending work items. See
 // WorkItemsPendingForObject() and WorkItemsPendinForFile().
 func NewRestorationItem(obj *IntellectualObject, gf *GenericFile, user *User) (*WorkItem, error) {
+	restorationItem, err := RestorationItemFor(obj, gf, user)
+	if err != nil {
+		return nil, err
+	}
+	err = restorationItem.Save()
+	return restorationItem, err
+}
+
+// RestorationItemFor returns a new, unsaved restoration WorkItem. It's
+// like NewRestorationItem, for callers that need to save the WorkItem
+// as part of a larger transaction.
+func RestorationItemFor(obj *IntellectualObject, gf *GenericFile, user *User) (*WorkItem, error) {
 	if obj == nil {
 		return nil, common.ErrInvalidParam
 	}
@@ -439,8 +695,7 @@ func NewRestorationItem(obj *IntellectualObject, gf *GenericFile, user *User) (*
 		restorationItem.GenericFileID = gf.ID
 	}
 	restorationItem.User = user.Email
-	err = restorationItem.Save()
-	return restorationItem, err
+	return restorationItem, nil
 }
 
 // NewDeletionItem creates a new work item to delete a file or object.
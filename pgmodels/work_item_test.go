@@ -516,3 +516,93 @@ func testAlertOnSuccessfulSpotTest(t *testing.T, item *pgmodels.WorkItem) {
 	require.Equal(t, 1, len(alert.WorkItems))
 	assert.Equal(t, item.ID, alert.WorkItems[0].ID)
 }
+
+func TestWorkItemIsStalled(t *testing.T) {
+	now := time.Now().UTC()
+	item := &pgmodels.WorkItem{
+		Stage:  constants.StageReceive,
+		Status: constants.StatusPending,
+	}
+
+	// Never queued, so not stalled
+	assert.False(t, item.IsStalled(now))
+
+	item.QueuedAt = now.Add(-1 * time.Hour)
+	assert.False(t, item.IsStalled(now))
+
+	item.QueuedAt = now.Add(-1 * (pgmodels.StalledItemThreshold(constants.StageReceive) + time.Minute))
+	assert.True(t, item.IsStalled(now))
+
+	// Started items are measured from StageStartedAt
+	item.Status = constants.StatusStarted
+	assert.False(t, item.IsStalled(now))
+	item.StageStartedAt = item.QueuedAt
+	assert.True(t, item.IsStalled(now))
+
+	// Completed items are never stalled
+	item.Status = constants.StatusSuccess
+	assert.False(t, item.IsStalled(now))
+
+	// Stages without an explicit threshold use the default
+	assert.Equal(t, pgmodels.DefaultStalledItemThreshold, pgmodels.StalledItemThreshold(constants.StageAvailableInS3))
+}
+
+func TestAlertOnStalledWorkItems(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+
+	// Most of our pending fixtures were queued years ago,
+	// so they should all be stalled.
+	stalled, err := pgmodels.WorkItemsStalled(time.Now().UTC())
+	require.Nil(t, err)
+	require.NotEmpty(t, stalled)
+	for _, item := range stalled {
+		assert.True(t, item.IsStalled(time.Now().UTC()))
+	}
+	requeuedID := stalled[0].ID
+
+	alerts, err := pgmodels.AlertOnStalledWorkItems()
+	require.Nil(t, err)
+	require.NotEmpty(t, alerts)
+
+	itemCount := 0
+	for _, alert := range alerts {
+		assert.Equal(t, constants.AlertStalledItems, alert.Type)
+		assert.Contains(t, alert.Content, "/work_items/show/")
+		require.NotEmpty(t, alert.Users)
+		for _, user := range alert.Users {
+			assert.Equal(t, constants.RoleSysAdmin, user.Role)
+		}
+		for _, item := range alert.WorkItems {
+			assert.Equal(t, alert.InstitutionID, item.InstitutionID)
+		}
+		itemCount += len(alert.WorkItems)
+	}
+	assert.Equal(t, len(stalled), itemCount)
+
+	// Items that are already part of a stalled items alert
+	// should not generate another alert.
+	stalled, err = pgmodels.WorkItemsStalled(time.Now().UTC())
+	require.Nil(t, err)
+	assert.Empty(t, stalled)
+
+	alerts, err = pgmodels.AlertOnStalledWorkItems()
+	require.Nil(t, err)
+	assert.Empty(t, alerts)
+
+	// An item that was requeued after its last alert can stall
+	// again. For pending items, stage_started_at is left over from
+	// the earlier stage, so we measure from queued_at.
+	now := time.Now().UTC()
+	_, err = common.Context().DB.Exec(`update work_items
+		set status = ?, stage_started_at = ?, queued_at = ? where id = ?`,
+		constants.StatusPending, now.AddDate(-1, 0, 0), now.Add(time.Minute), requeuedID)
+	require.Nil(t, err)
+	stalled, err = pgmodels.WorkItemsStalled(now.AddDate(0, 1, 0))
+	require.Nil(t, err)
+	stalledIDs := make([]int64, len(stalled))
+	for i, item := range stalled {
+		stalledIDs[i] = item.ID
+	}
+	assert.Contains(t, stalledIDs, requeuedID)
+}
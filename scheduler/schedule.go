@@ -0,0 +1,206 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression. We support the standard
+// five fields (minute, hour, day of month, month, day of week),
+// each of which may be a wildcard (*), a number, a range (1-5),
+// a step (*/15 or 1-30/5), or a comma-separated list of any of
+// these. Day of week runs from 0 (Sunday) to 6 (Saturday), and
+// 7 is accepted as Sunday as well.
+//
+// We also support the shorthand @hourly, @daily, @weekly, and
+// @monthly.
+//
+// As in standard cron, if both day of month and day of week are
+// restricted (i.e. neither is *), the schedule matches days that
+// satisfy either field.
+type Schedule struct {
+	Expression string
+	minute     uint64
+	hour       uint64
+	dom        uint64
+	month      uint64
+	dow        uint64
+	domStar    bool
+	dowStar    bool
+}
+
+type fieldBounds struct {
+	name string
+	min  int
+	max  int
+}
+
+var (
+	minuteBounds = fieldBounds{"minute", 0, 59}
+	hourBounds   = fieldBounds{"hour", 0, 23}
+	domBounds    = fieldBounds{"day of month", 1, 31}
+	monthBounds  = fieldBounds{"month", 1, 12}
+	dowBounds    = fieldBounds{"day of week", 0, 7}
+)
+
+var shorthand = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// ParseSchedule parses a cron expression, returning an error if
+// the expression is not valid.
+func ParseSchedule(expression string) (*Schedule, error) {
+	expr := strings.TrimSpace(expression)
+	if expanded, ok := shorthand[expr]; ok {
+		expr = expanded
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression '%s' should have five fields", expression)
+	}
+	schedule := &Schedule{
+		Expression: expression,
+		domStar:    fields[2] == "*",
+		dowStar:    fields[4] == "*",
+	}
+	var err error
+	if schedule.minute, err = parseField(fields[0], minuteBounds); err != nil {
+		return nil, err
+	}
+	if schedule.hour, err = parseField(fields[1], hourBounds); err != nil {
+		return nil, err
+	}
+	if schedule.dom, err = parseField(fields[2], domBounds); err != nil {
+		return nil, err
+	}
+	if schedule.month, err = parseField(fields[3], monthBounds); err != nil {
+		return nil, err
+	}
+	if schedule.dow, err = parseField(fields[4], dowBounds); err != nil {
+		return nil, err
+	}
+	// Sunday can be 0 or 7.
+	if schedule.dow&(1<<7) != 0 {
+		schedule.dow |= 1
+	}
+	return schedule, nil
+}
+
+// parseField parses a single cron field into a bitmask, where bit n
+// is set if value n matches.
+func parseField(field string, bounds fieldBounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		rangePart := part
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			var err error
+			step, err = strconv.Atoi(part[idx+1:])
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step in %s field '%s'", bounds.name, field)
+			}
+			rangePart = part[:idx]
+		}
+		start, end := bounds.min, bounds.max
+		if rangePart != "*" {
+			var err error
+			if idx := strings.Index(rangePart, "-"); idx >= 0 {
+				start, err = strconv.Atoi(rangePart[:idx])
+				if err == nil {
+					end, err = strconv.Atoi(rangePart[idx+1:])
+				}
+			} else {
+				start, err = strconv.Atoi(rangePart)
+				end = start
+				// 5/10 means start at 5, then every 10 thereafter.
+				if step > 1 {
+					end = bounds.max
+				}
+			}
+			if err != nil {
+				return 0, fmt.Errorf("invalid value in %s field '%s'", bounds.name, field)
+			}
+		}
+		if start < bounds.min || end > bounds.max || start > end {
+			return 0, fmt.Errorf("%s field '%s' is out of range %d-%d", bounds.name, field, bounds.min, bounds.max)
+		}
+		for i := start; i <= end; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+	return bits, nil
+}
+
+// Next returns the first time after t that matches this schedule.
+// The result is in the same location as t and is truncated to the
+// minute.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	// A valid schedule will always match within five years
+	// (Feb. 29 on a specific weekday may take a while).
+	// Bail out if we don't find a match by then.
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// Prev returns the most recent time at or before t that matches this
+// schedule. The scheduler uses this to find missed runs.
+func (s *Schedule) Prev(t time.Time) time.Time {
+	t = t.Truncate(time.Minute)
+	limit := t.AddDate(-5, 0, 0)
+	for t.After(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			// Last minute of the previous month.
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()).Add(-time.Minute)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()).Add(-time.Minute)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location()).Add(-time.Minute)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(-time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -0,0 +1,115 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/APTrust/registry/scheduler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func utc(year int, month time.Month, day, hour, min int) time.Time {
+	return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+}
+
+func TestParseSchedule(t *testing.T) {
+	valid := []string{
+		"* * * * *",
+		"0 * * * *",
+		"*/15 * * * *",
+		"0 6 * * 1-5",
+		"0,30 8-17/2 1,15 * *",
+		"5/10 * * * *",
+		"0 0 * * 7",
+		"@hourly",
+		"@daily",
+		"@weekly",
+		"@monthly",
+	}
+	for _, expr := range valid {
+		schedule, err := scheduler.ParseSchedule(expr)
+		require.Nil(t, err, expr)
+		assert.Equal(t, expr, schedule.Expression)
+	}
+
+	invalid := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@yearly",
+	}
+	for _, expr := range invalid {
+		_, err := scheduler.ParseSchedule(expr)
+		assert.NotNil(t, err, expr)
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	testCases := []struct {
+		expr     string
+		from     time.Time
+		expected time.Time
+	}{
+		{"0 * * * *", utc(2023, 5, 10, 14, 0), utc(2023, 5, 10, 15, 0)},
+		{"0 * * * *", utc(2023, 5, 10, 14, 59), utc(2023, 5, 10, 15, 0)},
+		{"*/15 * * * *", utc(2023, 5, 10, 14, 16), utc(2023, 5, 10, 14, 30)},
+		{"12 * * * *", utc(2023, 5, 10, 23, 30), utc(2023, 5, 11, 0, 12)},
+		{"0 6 * * *", utc(2023, 12, 31, 7, 0), utc(2024, 1, 1, 6, 0)},
+		{"30 0 1 * *", utc(2023, 1, 31, 12, 0), utc(2023, 2, 1, 0, 30)},
+		{"30 0 1 * *", utc(2023, 2, 1, 0, 30), utc(2023, 3, 1, 0, 30)},
+		{"0 0 29 2 *", utc(2023, 3, 1, 0, 0), utc(2024, 2, 29, 0, 0)},
+		// 2023-05-13 is a Saturday. Next weekday is Monday.
+		{"0 9 * * 1-5", utc(2023, 5, 13, 10, 0), utc(2023, 5, 15, 9, 0)},
+		// 7 is Sunday, same as 0.
+		{"0 0 * * 7", utc(2023, 5, 10, 0, 0), utc(2023, 5, 14, 0, 0)},
+		// Day of month and day of week both restricted: match either.
+		// 2023-05-12 is a Friday.
+		{"0 0 15 * 5", utc(2023, 5, 10, 0, 0), utc(2023, 5, 12, 0, 0)},
+		{"0 0 15 * 5", utc(2023, 5, 12, 0, 0), utc(2023, 5, 15, 0, 0)},
+		{"@monthly", utc(2023, 5, 10, 0, 0), utc(2023, 6, 1, 0, 0)},
+	}
+	for _, tc := range testCases {
+		schedule, err := scheduler.ParseSchedule(tc.expr)
+		require.Nil(t, err, tc.expr)
+		assert.Equal(t, tc.expected, schedule.Next(tc.from), "%s from %s", tc.expr, tc.from)
+	}
+
+	// Seconds should be truncated.
+	schedule, err := scheduler.ParseSchedule("* * * * *")
+	require.Nil(t, err)
+	from := time.Date(2023, 5, 10, 14, 0, 45, 500, time.UTC)
+	assert.Equal(t, utc(2023, 5, 10, 14, 1), schedule.Next(from))
+}
+
+func TestSchedulePrev(t *testing.T) {
+	testCases := []struct {
+		expr     string
+		from     time.Time
+		expected time.Time
+	}{
+		{"0 * * * *", utc(2023, 5, 10, 14, 0), utc(2023, 5, 10, 14, 0)},
+		{"0 * * * *", utc(2023, 5, 10, 14, 59), utc(2023, 5, 10, 14, 0)},
+		{"12 * * * *", utc(2023, 5, 11, 0, 5), utc(2023, 5, 10, 23, 12)},
+		{"0 6 * * *", utc(2024, 1, 1, 5, 0), utc(2023, 12, 31, 6, 0)},
+		// This is the case that used to get skipped. If Registry was
+		// down on the first of the month, the most recent scheduled
+		// run is still the first.
+		{"30 0 1 * *", utc(2023, 2, 3, 12, 0), utc(2023, 2, 1, 0, 30)},
+		{"30 0 1 * *", utc(2023, 2, 1, 0, 29), utc(2023, 1, 1, 0, 30)},
+		{"0 0 29 2 *", utc(2023, 3, 1, 0, 0), utc(2020, 2, 29, 0, 0)},
+	}
+	for _, tc := range testCases {
+		schedule, err := scheduler.ParseSchedule(tc.expr)
+		require.Nil(t, err, tc.expr)
+		assert.Equal(t, tc.expected, schedule.Prev(tc.from), "%s from %s", tc.expr, tc.from)
+	}
+}
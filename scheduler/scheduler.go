@@ -0,0 +1,302 @@
+// Package scheduler runs Registry's periodic housekeeping jobs,
+// such as refreshing count views, updating deposit stats, and
+// scheduling restoration spot tests.
+//
+// Each job has a name and a cron-style schedule. We may have several
+// Registry containers running at once, and each one runs a scheduler,
+// so the scheduler takes a Postgres advisory lock on the job name
+// before running a job. Only the instance holding the lock runs the
+// job. Each scheduled run also claims its time slot in the job_runs
+// table, so a fast job won't run twice when a second container gets
+// the lock after the first has released it.
+//
+// Every run is recorded in the job_runs table, along with its start
+// and end time, duration, and error. Admins can see jobs and their
+// recent runs, and can trigger a run manually, at /jobs.
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/go-pg/pg/v10"
+)
+
+// ErrJobNotFound means the scheduler has no job with the requested name.
+var ErrJobNotFound = errors.New("job not found")
+
+// JobFunc is the function a job runs. If it returns an error, the
+// error is recorded in the job's run history.
+type JobFunc func(ctx *common.APTContext) error
+
+// Job is a named task that runs on a cron-style schedule.
+type Job struct {
+	// Name uniquely identifies the job. The scheduler uses this
+	// as the key for the job's advisory lock and run history,
+	// so don't change it casually.
+	Name string
+
+	// Description tells admins what the job does.
+	Description string
+
+	// Schedule is a cron expression. See ParseSchedule.
+	Schedule string
+
+	// CatchUp tells the scheduler to run this job at startup if
+	// the job missed its most recent scheduled run (e.g. because
+	// no Registry instance was running at the time or the run
+	// failed). Jobs that run infrequently, such as monthly jobs,
+	// should set this.
+	CatchUp bool
+
+	// Run is the function that does the work.
+	Run JobFunc
+
+	schedule *Schedule
+}
+
+// NextRun returns the first time after t at which this job is
+// scheduled to run.
+func (job *Job) NextRun(t time.Time) time.Time {
+	return job.schedule.Next(t)
+}
+
+// Scheduler runs registered jobs according to their schedules.
+type Scheduler struct {
+	ctx     *common.APTContext
+	host    string
+	jobs    map[string]*Job
+	started bool
+	mutex   sync.RWMutex
+}
+
+var defaultScheduler *Scheduler
+var defaultOnce sync.Once
+
+// Default returns the scheduler shared by the application.
+func Default() *Scheduler {
+	defaultOnce.Do(func() {
+		defaultScheduler = New(common.Context())
+	})
+	return defaultScheduler
+}
+
+// New returns a new Scheduler with no jobs.
+func New(ctx *common.APTContext) *Scheduler {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return &Scheduler{
+		ctx:  ctx,
+		host: host,
+		jobs: make(map[string]*Job),
+	}
+}
+
+// Register adds a job to the scheduler. It returns an error if the
+// job's schedule is invalid, if it has no Run function, or if a job
+// with the same name is already registered.
+func (s *Scheduler) Register(job *Job) error {
+	if job.Name == "" {
+		return fmt.Errorf("job name is required")
+	}
+	if job.Run == nil {
+		return fmt.Errorf("job %s has no run function", job.Name)
+	}
+	schedule, err := ParseSchedule(job.Schedule)
+	if err != nil {
+		return fmt.Errorf("job %s: %v", job.Name, err)
+	}
+	job.schedule = schedule
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, exists := s.jobs[job.Name]; exists {
+		return fmt.Errorf("job %s is already registered", job.Name)
+	}
+	s.jobs[job.Name] = job
+	if s.started {
+		go s.loop(job)
+	}
+	return nil
+}
+
+// Job returns the job with the specified name, or nil if there is
+// no such job.
+func (s *Scheduler) Job(name string) *Job {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.jobs[name]
+}
+
+// Jobs returns all registered jobs, sorted by name.
+func (s *Scheduler) Jobs() []*Job {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+	return jobs
+}
+
+// Start starts running all registered jobs on their schedules. Jobs
+// registered after Start is called start running as soon as they're
+// registered. Calling Start more than once has no effect.
+func (s *Scheduler) Start() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.started {
+		return
+	}
+	s.ctx.Log.Info().Msgf("scheduler: starting %d jobs", len(s.jobs))
+	for _, job := range s.jobs {
+		go s.loop(job)
+	}
+	s.started = true
+}
+
+// RunNow runs the named job immediately in the background, outside
+// of its regular schedule. The run is still subject to the job's
+// advisory lock, so it won't run if the job is already running
+// elsewhere.
+func (s *Scheduler) RunNow(name string) error {
+	job := s.Job(name)
+	if job == nil {
+		return ErrJobNotFound
+	}
+	go func() {
+		_, err := s.Run(job, constants.JobTriggerManual, nil)
+		if err != nil {
+			s.ctx.Log.Error().Msgf("scheduler: manual run of %s: %v", job.Name, err)
+		}
+	}()
+	return nil
+}
+
+// Run runs a job once, if it can get the job's lock, and records
+// the run in the job_runs table. Param scheduledFor should be the
+// scheduled time slot for scheduled runs, and nil otherwise.
+//
+// Run returns a nil JobRun if the job did not run because another
+// instance holds the lock, because another instance has already
+// claimed this time slot, or because a catch-up run turned out to
+// be unnecessary. If the job runs and fails, the job's error is
+// recorded in JobRun.ErrorMessage. The error returned by this
+// function is for problems with locking and recording the run.
+func (s *Scheduler) Run(job *Job, trigger string, scheduledFor *time.Time) (*pgmodels.JobRun, error) {
+	conn := s.ctx.DB.Conn()
+	defer conn.Close()
+
+	locked, err := s.lock(conn, job)
+	if err != nil {
+		return nil, err
+	}
+	if !locked {
+		s.ctx.Log.Info().Msgf("scheduler: skipping %s because it's running elsewhere", job.Name)
+		return nil, nil
+	}
+	defer s.unlock(conn, job)
+
+	if trigger == constants.JobTriggerCatchUp {
+		missed, err := s.missedRun(job, time.Now().UTC())
+		if err != nil || !missed {
+			return nil, err
+		}
+	}
+
+	run := pgmodels.NewJobRun(job.Name, trigger, s.host, scheduledFor)
+	claimed, err := run.Start()
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		s.ctx.Log.Info().Msgf("scheduler: skipping %s because another instance already ran it for %s", job.Name, scheduledFor.Format(time.RFC3339))
+		return nil, nil
+	}
+
+	s.ctx.Log.Info().Msgf("scheduler: starting %s (%s)", job.Name, trigger)
+	jobErr := s.execute(job)
+	err = run.Finish(jobErr)
+	if jobErr != nil {
+		s.ctx.Log.Error().Msgf("scheduler: %s failed after %s: %v", job.Name, run.Duration(), jobErr)
+	} else {
+		s.ctx.Log.Info().Msgf("scheduler: %s completed after %s", job.Name, run.Duration())
+	}
+	return run, err
+}
+
+// loop runs a single job on its schedule, forever.
+func (s *Scheduler) loop(job *Job) {
+	if job.CatchUp {
+		_, err := s.Run(job, constants.JobTriggerCatchUp, nil)
+		if err != nil {
+			s.ctx.Log.Error().Msgf("scheduler: catch-up run of %s: %v", job.Name, err)
+		}
+	}
+	for {
+		next := job.NextRun(time.Now().UTC())
+		time.Sleep(time.Until(next))
+		_, err := s.Run(job, constants.JobTriggerSchedule, &next)
+		if err != nil {
+			s.ctx.Log.Error().Msgf("scheduler: scheduled run of %s: %v", job.Name, err)
+		}
+	}
+}
+
+// execute calls the job's run function, converting panics to errors
+// so one bad job can't take down the whole application.
+func (s *Scheduler) execute(job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return job.Run(s.ctx)
+}
+
+// missedRun returns true if the job has not completed successfully
+// since its most recent scheduled time.
+func (s *Scheduler) missedRun(job *Job, now time.Time) (bool, error) {
+	lastRun, err := pgmodels.LastJobRun(job.Name, true)
+	if err != nil {
+		return false, err
+	}
+	if lastRun == nil {
+		return true, nil
+	}
+	return lastRun.StartedAt.Before(job.schedule.Prev(now)), nil
+}
+
+// lock tries to get a session-level advisory lock for the job on the
+// given connection. The lock is released by unlock, or by Postgres if
+// the connection dies.
+func (s *Scheduler) lock(conn *pg.Conn, job *Job) (bool, error) {
+	var locked bool
+	_, err := conn.QueryOne(pg.Scan(&locked), "select pg_try_advisory_lock(?)", LockKey(job.Name))
+	return locked, err
+}
+
+func (s *Scheduler) unlock(conn *pg.Conn, job *Job) {
+	_, err := conn.Exec("select pg_advisory_unlock(?)", LockKey(job.Name))
+	if err != nil {
+		s.ctx.Log.Error().Msgf("scheduler: error releasing lock for %s: %v", job.Name, err)
+	}
+}
+
+// LockKey returns the Postgres advisory lock key for the named job.
+func LockKey(jobName string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte("registry job: " + jobName))
+	return int64(hash.Sum64())
+}
@@ -0,0 +1,133 @@
+package scheduler_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/scheduler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func noop(ctx *common.APTContext) error {
+	return nil
+}
+
+func TestSchedulerRegister(t *testing.T) {
+	s := scheduler.New(common.Context())
+
+	err := s.Register(&scheduler.Job{Name: "job_b", Schedule: "@hourly", Run: noop})
+	require.Nil(t, err)
+	err = s.Register(&scheduler.Job{Name: "job_a", Schedule: "0 6 * * *", Run: noop})
+	require.Nil(t, err)
+
+	// Duplicate name
+	err = s.Register(&scheduler.Job{Name: "job_a", Schedule: "@daily", Run: noop})
+	assert.NotNil(t, err)
+
+	// Missing name, schedule, or run function
+	assert.NotNil(t, s.Register(&scheduler.Job{Schedule: "@daily", Run: noop}))
+	assert.NotNil(t, s.Register(&scheduler.Job{Name: "job_c", Schedule: "whenever", Run: noop}))
+	assert.NotNil(t, s.Register(&scheduler.Job{Name: "job_d", Schedule: "@daily"}))
+
+	jobs := s.Jobs()
+	require.Equal(t, 2, len(jobs))
+	assert.Equal(t, "job_a", jobs[0].Name)
+	assert.Equal(t, "job_b", jobs[1].Name)
+
+	assert.NotNil(t, s.Job("job_a"))
+	assert.Nil(t, s.Job("job_c"))
+
+	from := time.Date(2023, 5, 10, 7, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2023, 5, 11, 6, 0, 0, 0, time.UTC), s.Job("job_a").NextRun(from))
+
+	assert.Equal(t, scheduler.ErrJobNotFound, s.RunNow("no_such_job"))
+}
+
+func TestLockKey(t *testing.T) {
+	assert.Equal(t, scheduler.LockKey("update_counts"), scheduler.LockKey("update_counts"))
+	assert.NotEqual(t, scheduler.LockKey("update_counts"), scheduler.LockKey("update_current_deposit_stats"))
+}
+
+func TestSchedulerRun(t *testing.T) {
+	db.LoadFixtures()
+	s := scheduler.New(common.Context())
+	runCount := 0
+	job := &scheduler.Job{
+		Name:     fmt.Sprintf("test_job_%d", time.Now().UnixNano()),
+		Schedule: "30 0 1 * *",
+		CatchUp:  true,
+		Run: func(ctx *common.APTContext) error {
+			runCount++
+			return nil
+		},
+	}
+	require.Nil(t, s.Register(job))
+
+	// Job has never run, so catch-up should run it.
+	run, err := s.Run(job, constants.JobTriggerCatchUp, nil)
+	require.Nil(t, err)
+	require.NotNil(t, run)
+	assert.True(t, run.ID > 0)
+	assert.True(t, run.Succeeded())
+	assert.Equal(t, constants.JobTriggerCatchUp, run.Trigger)
+	assert.Equal(t, 1, runCount)
+
+	// Now it's caught up, so catch-up should not run it again.
+	run, err = s.Run(job, constants.JobTriggerCatchUp, nil)
+	require.Nil(t, err)
+	assert.Nil(t, run)
+	assert.Equal(t, 1, runCount)
+
+	// Only one instance should get to run each scheduled slot.
+	slot := time.Date(2023, 6, 1, 0, 30, 0, 0, time.UTC)
+	run, err = s.Run(job, constants.JobTriggerSchedule, &slot)
+	require.Nil(t, err)
+	require.NotNil(t, run)
+	assert.Equal(t, 2, runCount)
+
+	otherInstance := scheduler.New(common.Context())
+	run, err = otherInstance.Run(job, constants.JobTriggerSchedule, &slot)
+	require.Nil(t, err)
+	assert.Nil(t, run)
+	assert.Equal(t, 2, runCount)
+
+	// Manual runs always run.
+	run, err = s.Run(job, constants.JobTriggerManual, nil)
+	require.Nil(t, err)
+	require.NotNil(t, run)
+	assert.Equal(t, 3, runCount)
+
+	// Errors and panics should be recorded.
+	failingJob := &scheduler.Job{
+		Name:     fmt.Sprintf("failing_job_%d", time.Now().UnixNano()),
+		Schedule: "@daily",
+		Run: func(ctx *common.APTContext) error {
+			return fmt.Errorf("oops")
+		},
+	}
+	require.Nil(t, s.Register(failingJob))
+	run, err = s.Run(failingJob, constants.JobTriggerManual, nil)
+	require.Nil(t, err)
+	require.NotNil(t, run)
+	assert.False(t, run.Succeeded())
+	assert.Equal(t, "oops", run.ErrorMessage)
+	assert.False(t, run.FinishedAt.IsZero())
+
+	panickyJob := &scheduler.Job{
+		Name:     fmt.Sprintf("panicky_job_%d", time.Now().UnixNano()),
+		Schedule: "@daily",
+		Run: func(ctx *common.APTContext) error {
+			panic("aaaagh")
+		},
+	}
+	require.Nil(t, s.Register(panickyJob))
+	run, err = s.Run(panickyJob, constants.JobTriggerManual, nil)
+	require.Nil(t, err)
+	require.NotNil(t, run)
+	assert.Equal(t, "panic: aaaagh", run.ErrorMessage)
+}
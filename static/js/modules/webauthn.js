@@ -0,0 +1,121 @@
+// webauthn.js
+//
+// Register security keys and passkeys, and use them to complete
+// two-factor login.
+//
+// The server sends binary values (challenges, user and credential IDs)
+// as base64 or base64url strings, and expects base64url back. The
+// browser's WebAuthn API works with ArrayBuffers, so we convert in
+// both directions.
+//
+
+function toBuffer(value) {
+    let base64 = value.replace(/-/g, "+").replace(/_/g, "/");
+    while (base64.length % 4) {
+        base64 += "=";
+    }
+    return Uint8Array.from(atob(base64), c => c.charCodeAt(0));
+}
+
+function toBase64URL(buffer) {
+    let binary = String.fromCharCode.apply(null, new Uint8Array(buffer));
+    return btoa(binary).replace(/\+/g, "-").replace(/\//g, "_").replace(/=+$/, "");
+}
+
+function csrfToken() {
+    return document.querySelector('meta[name="csrf_token"]').content;
+}
+
+// post sends body as JSON, with the CSRF token in the header, and
+// returns a promise of the JSON response. Non-2xx responses reject
+// with the server's error message.
+function post(url, body) {
+    return fetch(url, {
+        method: "POST",
+        credentials: "same-origin",
+        headers: {
+            "Content-Type": "application/json",
+            "X-CSRF-Token": csrfToken()
+        },
+        body: body ? JSON.stringify(body) : null
+    }).then(function (response) {
+        return response.json().then(function (data) {
+            if (!response.ok) {
+                return Promise.reject(new Error(data.Error || response.statusText));
+            }
+            return data;
+        });
+    });
+}
+
+function supported() {
+    if (!window.PublicKeyCredential) {
+        return Promise.reject(new Error("This browser does not support security keys."));
+    }
+    return Promise.resolve();
+}
+
+// registerSecurityKey registers a new security key or passkey under
+// the specified name. If primary is true, the server makes security
+// keys the user's two-factor method. Returns a promise of the URL to
+// go to next.
+export function registerSecurityKey(name, primary) {
+    let query = new URLSearchParams({ name: name, primary: primary ? "true" : "false" });
+    return supported().then(function () {
+        return post("/users/webauthn_register/begin");
+    }).then(function (options) {
+        options.publicKey.challenge = toBuffer(options.publicKey.challenge);
+        options.publicKey.user.id = toBuffer(options.publicKey.user.id);
+        (options.publicKey.excludeCredentials || []).forEach(function (cred) {
+            cred.id = toBuffer(cred.id);
+        });
+        return navigator.credentials.create(options);
+    }).then(function (credential) {
+        let transports = [];
+        if (typeof credential.response.getTransports === "function") {
+            transports = credential.response.getTransports();
+        }
+        return post("/users/webauthn_register/finish?" + query.toString(), {
+            id: credential.id,
+            rawId: toBase64URL(credential.rawId),
+            type: credential.type,
+            transports: transports,
+            response: {
+                attestationObject: toBase64URL(credential.response.attestationObject),
+                clientDataJSON: toBase64URL(credential.response.clientDataJSON)
+            }
+        });
+    }).then(function (data) {
+        return data.location;
+    });
+}
+
+// useSecurityKey asks the browser to sign the server's challenge with
+// one of the user's registered security keys, to complete two-factor
+// login. Returns a promise of the URL to go to next.
+export function useSecurityKey() {
+    return supported().then(function () {
+        return post("/users/2fa_webauthn/begin");
+    }).then(function (options) {
+        options.publicKey.challenge = toBuffer(options.publicKey.challenge);
+        (options.publicKey.allowCredentials || []).forEach(function (cred) {
+            cred.id = toBuffer(cred.id);
+        });
+        return navigator.credentials.get(options);
+    }).then(function (assertion) {
+        let userHandle = assertion.response.userHandle;
+        return post("/users/2fa_webauthn/finish", {
+            id: assertion.id,
+            rawId: toBase64URL(assertion.rawId),
+            type: assertion.type,
+            response: {
+                authenticatorData: toBase64URL(assertion.response.authenticatorData),
+                clientDataJSON: toBase64URL(assertion.response.clientDataJSON),
+                signature: toBase64URL(assertion.response.signature),
+                userHandle: userHandle ? toBase64URL(userHandle) : ""
+            }
+        });
+    }).then(function (data) {
+        return data.location;
+    });
+}
@@ -5,11 +5,14 @@ import { initToggles } from "./modules/toggle.js";
 import { initSidebar } from "./modules/sidebar.js";
 import { initFiltersGrid } from "./modules/filters-grid.js";
 import { chartColors } from "./modules/charts.js";
+import { registerSecurityKey, useSecurityKey } from "./modules/webauthn.js";
 
 let APT = {};
 APT.chartColors = chartColors;
 APT.loadIntoElement = loadIntoElement;
 APT.modalPost = modalPost;
+APT.registerSecurityKey = registerSecurityKey;
+APT.useSecurityKey = useSecurityKey;
 
 window.addEventListener("load", (event) => {
   initXHR();
@@ -0,0 +1,41 @@
+package admin_api
+
+import (
+	"net/http"
+
+	"github.com/APTrust/registry/helpers"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/APTrust/registry/web/api"
+	"github.com/gin-gonic/gin"
+)
+
+// AuditEventIndex returns a list of security audit events. Filters are
+// the same as on the web audit log page.
+//
+// GET /admin-api/v3/audit_events
+// GET /admin-api/v3/audit_events?format=csv|jsonl
+func AuditEventIndex(c *gin.Context) {
+	req := api.NewRequest(c)
+	var events []*pgmodels.AuditEvent
+	if format := helpers.ExportFormat(c.Request); format != "" {
+		api.AbortIfError(c, req.ExportResourceList(&events, "created_at", "desc", format))
+		return
+	}
+	pager, err := req.LoadResourceList(&events, "created_at", "desc")
+	if api.AbortIfError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, api.NewJsonList(events, pager))
+}
+
+// AuditEventShow returns the audit event with the specified id.
+//
+// GET /admin-api/v3/audit_events/show/:id
+func AuditEventShow(c *gin.Context) {
+	req := api.NewRequest(c)
+	event, err := pgmodels.AuditEventByID(req.Auth.ResourceID)
+	if api.AbortIfError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, event)
+}
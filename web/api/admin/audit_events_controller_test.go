@@ -0,0 +1,49 @@
+package admin_api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/web/api"
+	tu "github.com/APTrust/registry/web/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditEventIndexAndShow(t *testing.T) {
+	tu.InitHTTPTests(t)
+
+	// Signing in records a successful sign-in.
+	tu.InitClient(t, "admin@inst2.edu")
+	resp := tu.SysAdminClient.GET("/admin-api/v3/audit_events").
+		WithQuery("action", constants.AuditSignIn).
+		WithQuery("outcome", constants.OutcomeSuccess).
+		WithQuery("user_email__contains", "admin@inst2.edu").
+		Expect().Status(http.StatusOK)
+
+	list := api.AuditEventList{}
+	err := json.Unmarshal([]byte(resp.Body().Raw()), &list)
+	require.Nil(t, err)
+	require.True(t, list.Count > 0)
+	for _, event := range list.Results {
+		assert.Equal(t, "admin@inst2.edu", event.UserEmail)
+		assert.Equal(t, tu.Inst2Admin.InstitutionID, event.InstitutionID)
+		assert.Equal(t, constants.AuditSignIn, event.Action)
+	}
+
+	tu.SysAdminClient.GET("/admin-api/v3/audit_events/show/{id}", list.Results[0].ID).
+		Expect().Status(http.StatusOK).
+		JSON().Object().Value("user_email").String().Equal("admin@inst2.edu")
+
+	// Non sys-admins can't access the admin API.
+	for _, client := range tu.AllClients {
+		if client == tu.SysAdminClient {
+			continue
+		}
+		client.GET("/admin-api/v3/audit_events").
+			Expect().
+			Status(http.StatusForbidden)
+	}
+}
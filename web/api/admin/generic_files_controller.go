@@ -6,8 +6,10 @@ import (
 
 	"github.com/APTrust/registry/common"
 	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/helpers"
 	"github.com/APTrust/registry/pgmodels"
 	"github.com/APTrust/registry/web/api"
+	"github.com/APTrust/registry/web/webui"
 	"github.com/gin-gonic/gin"
 )
 
@@ -17,9 +19,19 @@ import (
 // and storage records. Some of our API workers depend on the
 // related records.
 //
+// Exports (format=csv or format=jsonl) return GenericFileView
+// records, like the member API, because the related records don't
+// fit in a flat export.
+//
 // GET /admin-api/v3/files
+// GET /admin-api/v3/files?format=csv|jsonl
 func GenericFileIndex(c *gin.Context) {
 	req := api.NewRequest(c)
+	if format := helpers.ExportFormat(c.Request); format != "" {
+		var fileViews []*pgmodels.GenericFileView
+		api.AbortIfError(c, req.ExportResourceList(&fileViews, "updated_at", "desc", format))
+		return
+	}
 	var files []*pgmodels.GenericFile
 	pager, err := req.LoadResourceList(&files, "updated_at", "desc")
 	if api.AbortIfError(c, err) {
@@ -28,6 +40,53 @@ func GenericFileIndex(c *gin.Context) {
 	c.JSON(http.StatusOK, api.NewJsonList(files, pager))
 }
 
+// FixityCheckResponse lists the WorkItems created by a bulk fixity
+// check request, along with the identifiers of files that were
+// skipped and the reasons they were skipped.
+type FixityCheckResponse struct {
+	WorkItems []*pgmodels.WorkItem `json:"work_items"`
+	Skipped   map[string]string    `json:"skipped"`
+}
+
+// GenericFileInitFixity queues fixity checks for all active files
+// matching the filters in the query string. This takes the same filters
+// as GenericFileIndex, and requires at least one of them, so no one
+// queues every file in the system by accident. A single request can
+// match up to pgmodels.MaxBulkFixityFiles files.
+//
+// POST /admin-api/v3/files/init_fixity?institution_id=2&last_fixity_check__lteq=2021-01-01
+func GenericFileInitFixity(c *gin.Context) {
+	req := api.NewRequest(c)
+	err := req.ValidateFilters()
+	if api.AbortIfError(c, err) {
+		return
+	}
+	if len(c.Request.URL.Query()) == 0 {
+		api.AbortIfError(c, common.ErrInvalidParam)
+		return
+	}
+	query, err := req.GetFilterCollection().ToQuery()
+	if api.AbortIfError(c, err) {
+		return
+	}
+	query.Where("state", "=", constants.StateActive).
+		OrderBy("id", "asc").
+		Limit(pgmodels.MaxBulkFixityFiles + 1)
+	var files []*pgmodels.GenericFile
+	err = query.Select(&files)
+	if api.AbortIfError(c, err) {
+		return
+	}
+	workItems, skipped, err := webui.InitBulkFixityCheck(files, req.CurrentUser)
+	if api.AbortIfError(c, err) {
+		return
+	}
+	c.JSON(http.StatusCreated, &FixityCheckResponse{
+		WorkItems: workItems,
+		Skipped:   skipped,
+	})
+}
+
 // GenericFileDelete marks a generic file record as deleted.
 // It also creates a deletion premis event. Before it does any of
 // that, it checks a number of pre-conditions. See the
@@ -348,3 +348,49 @@ func TestCoerceFileStorageOption(t *testing.T) {
 	admin_api.CoerceFileStorageOption(existingFile, submittedFile)
 	assert.NotEqual(t, existingFile.StorageOption, submittedFile.StorageOption)
 }
+
+func TestGenericFileInitFixity(t *testing.T) {
+	err := db.ForceFixtureReload()
+	require.Nil(t, err)
+	defer db.ForceFixtureReload()
+	tu.InitHTTPTests(t)
+
+	// Files 1-3 belong to object 1, and files 4-6 to object 2.
+	// Queue a check on object 2, then check files 1-6. The bulk
+	// request should skip files 4-6.
+	tu.SysAdminClient.POST("/admin-api/v3/objects/init_fixity/{id}", 2).
+		WithHeader(constants.APIUserHeader, tu.SysAdmin.Email).
+		WithHeader(constants.APIKeyHeader, "password").
+		Expect().Status(http.StatusCreated)
+
+	resp := tu.SysAdminClient.POST("/admin-api/v3/files/init_fixity").
+		WithHeader(constants.APIUserHeader, tu.SysAdmin.Email).
+		WithHeader(constants.APIKeyHeader, "password").
+		WithQuery("intellectual_object_id", 1).
+		WithQuery("intellectual_object_id", 2).
+		Expect().Status(http.StatusCreated)
+	result := &admin_api.FixityCheckResponse{}
+	err = json.Unmarshal([]byte(resp.Body().Raw()), result)
+	require.Nil(t, err)
+	require.Len(t, result.WorkItems, 3)
+	for i, item := range result.WorkItems {
+		assert.EqualValues(t, i+1, item.GenericFileID)
+		assert.Equal(t, constants.ActionFixityCheck, item.Action)
+		assert.False(t, item.QueuedAt.IsZero())
+	}
+	assert.Len(t, result.Skipped, 3)
+	assert.Equal(t, common.ErrFixityCheckPending.Error(), result.Skipped["institution1.edu/pdfs/doc1"])
+
+	// Requests must include at least one filter.
+	tu.SysAdminClient.POST("/admin-api/v3/files/init_fixity").
+		WithHeader(constants.APIUserHeader, tu.SysAdmin.Email).
+		WithHeader(constants.APIKeyHeader, "password").
+		Expect().Status(http.StatusBadRequest)
+
+	// Non-admins can't use the admin API.
+	tu.Inst1AdminClient.POST("/admin-api/v3/files/init_fixity").
+		WithHeader(constants.APIUserHeader, tu.Inst1Admin.Email).
+		WithHeader(constants.APIKeyHeader, "password").
+		WithQuery("intellectual_object_id", 1).
+		Expect().Status(http.StatusForbidden)
+}
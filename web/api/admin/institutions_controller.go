@@ -3,6 +3,7 @@ package admin_api
 import (
 	"net/http"
 
+	"github.com/APTrust/registry/helpers"
 	"github.com/APTrust/registry/pgmodels"
 	"github.com/APTrust/registry/web/api"
 	"github.com/gin-gonic/gin"
@@ -11,9 +12,14 @@ import (
 // InstitutionIndex shows list of institutions.
 //
 // GET /admin-api/v3/institutions
+// GET /admin-api/v3/institutions?format=csv|jsonl
 func InstitutionIndex(c *gin.Context) {
 	req := api.NewRequest(c)
 	var institutions []*pgmodels.InstitutionView
+	if format := helpers.ExportFormat(c.Request); format != "" {
+		api.AbortIfError(c, req.ExportResourceList(&institutions, "name", "asc", format))
+		return
+	}
 	pager, err := req.LoadResourceList(&institutions, "name", "asc")
 	if api.AbortIfError(c, err) {
 		return
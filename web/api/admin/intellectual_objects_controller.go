@@ -53,6 +53,23 @@ func IntellectualObjectInitRestore(c *gin.Context) {
 	c.JSON(http.StatusCreated, workItem)
 }
 
+// IntellectualObjectInitFixity queues fixity checks for all of an
+// object's active files. Files that already have a fixity check in
+// the queue are not queued again.
+// POST /admin-api/v3/objects/init_fixity/:id
+func IntellectualObjectInitFixity(c *gin.Context) {
+	req := api.NewRequest(c)
+	obj, err := pgmodels.IntellectualObjectByID(req.Auth.ResourceID)
+	if api.AbortIfError(c, err) {
+		return
+	}
+	workItem, err := webui.InitObjectFixityCheck(obj, req.CurrentUser)
+	if api.AbortIfError(c, err) {
+		return
+	}
+	c.JSON(http.StatusCreated, workItem)
+}
+
 // IntellectualObjectDelete marks an object record as deleted.
 // It also creates a deletion premis event. Before it does any of
 // that, it checks a number of pre-conditions. See the
@@ -291,3 +291,27 @@ func TestObjectInitRestore(t *testing.T) {
 		WithHeader(constants.APIKeyHeader, "password").
 		Expect().Status(http.StatusForbidden)
 }
+
+func TestObjectInitFixity(t *testing.T) {
+	err := db.ForceFixtureReload()
+	require.Nil(t, err)
+	defer db.ForceFixtureReload()
+	tu.InitHTTPTests(t)
+
+	tu.SysAdminClient.POST("/admin-api/v3/objects/init_fixity/{id}", 6).
+		WithHeader(constants.APIUserHeader, tu.SysAdmin.Email).
+		WithHeader(constants.APIKeyHeader, "password").
+		Expect().Status(http.StatusCreated).
+		JSON().Object().Value("action").String().Equal(constants.ActionFixityCheck)
+
+	// Don't queue a second check while the first is pending.
+	tu.SysAdminClient.POST("/admin-api/v3/objects/init_fixity/{id}", 6).
+		WithHeader(constants.APIUserHeader, tu.SysAdmin.Email).
+		WithHeader(constants.APIKeyHeader, "password").
+		Expect().Status(http.StatusConflict)
+
+	tu.Inst1AdminClient.POST("/admin-api/v3/objects/init_fixity/{id}", 6).
+		WithHeader(constants.APIUserHeader, tu.Inst1Admin.Email).
+		WithHeader(constants.APIKeyHeader, "password").
+		Expect().Status(http.StatusForbidden)
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/APTrust/registry/common"
 	"github.com/APTrust/registry/constants"
 	"github.com/APTrust/registry/pgmodels"
 	"github.com/APTrust/registry/web/api"
@@ -31,19 +32,30 @@ func PremisEventCreate(c *gin.Context) {
 		return
 	}
 	if event.EventType == constants.EventFixityCheck {
-		err = setLastFixity(event.GenericFileID, event.DateTime)
+		gf, err := setLastFixity(event.GenericFileID, event.DateTime)
 		if api.AbortIfError(c, err) {
 			return
 		}
+		// The event has already been saved, so don't return an error
+		// here. That would cause the caller to retry and record a
+		// duplicate event. Just log the problem.
+		_, err = event.AlertOnFailedFixity()
+		if err != nil {
+			common.Context().Log.Error().Msgf("Error creating failed fixity alert for event %d: %v", event.ID, err)
+		}
+		_, err = pgmodels.CompleteFixityCheckItems(gf, event.Outcome)
+		if err != nil {
+			common.Context().Log.Error().Msgf("Error completing fixity WorkItems for file %d: %v", gf.ID, err)
+		}
 	}
 	c.JSON(http.StatusCreated, event)
 }
 
-func setLastFixity(gfID int64, checkDate time.Time) error {
+func setLastFixity(gfID int64, checkDate time.Time) (*pgmodels.GenericFile, error) {
 	gf, err := pgmodels.GenericFileByID(gfID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	gf.LastFixityCheck = checkDate
-	return gf.Save()
+	return gf, gf.Save()
 }
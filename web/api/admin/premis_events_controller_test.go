@@ -110,3 +110,49 @@ func TestPremisEventFixityCheck(t *testing.T) {
 	require.NotNil(t, gf)
 	assert.InDelta(t, now.Unix(), gf.LastFixityCheck.Unix(), 1)
 }
+
+func TestPremisEventFailedFixityCheck(t *testing.T) {
+	tu.InitHTTPTests(t)
+
+	gf, err := pgmodels.GenericFileByID(23)
+	require.Nil(t, err)
+	require.NotNil(t, gf)
+
+	event := pgmodels.RandomPremisEvent(constants.EventFixityCheck)
+	event.GenericFileID = gf.ID
+	event.IntellectualObjectID = gf.IntellectualObjectID
+	event.InstitutionID = gf.InstitutionID
+	event.Outcome = constants.OutcomeFailure
+
+	jsonData, err := json.Marshal(event)
+	require.Nil(t, err)
+
+	resp := tu.SysAdminClient.POST("/admin-api/v3/events/create").
+		WithHeader(constants.APIUserHeader, tu.SysAdmin.Email).
+		WithHeader(constants.APIKeyHeader, "password").
+		WithBytes(jsonData).
+		Expect()
+	resp.Status(http.StatusCreated)
+	savedEvent := &pgmodels.PremisEvent{}
+	err = json.Unmarshal([]byte(resp.Body().Raw()), savedEvent)
+	require.Nil(t, err)
+	require.True(t, savedEvent.ID > 0)
+
+	// The registry should have created a failed fixity alert
+	// for this event.
+	query := pgmodels.NewQuery().
+		Where("type", "=", constants.AlertFailedFixity).
+		Where("institution_id", "=", gf.InstitutionID).
+		Relations("PremisEvents")
+	alerts, err := pgmodels.AlertSelect(query)
+	require.Nil(t, err)
+	found := false
+	for _, alert := range alerts {
+		for _, alertEvent := range alert.PremisEvents {
+			if alertEvent.ID == savedEvent.ID {
+				found = true
+			}
+		}
+	}
+	assert.True(t, found)
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/helpers"
 	"github.com/APTrust/registry/pgmodels"
 	"github.com/APTrust/registry/web/api"
 	"github.com/gin-gonic/gin"
@@ -17,9 +18,14 @@ import (
 // StorageRecordIndex shows list of objects.
 //
 // GET /admin-api/v3/storage_records
+// GET /admin-api/v3/storage_records?format=csv|jsonl
 func StorageRecordIndex(c *gin.Context) {
 	req := api.NewRequest(c)
 	var storageRecords []*pgmodels.StorageRecord
+	if format := helpers.ExportFormat(c.Request); format != "" {
+		api.AbortIfError(c, req.ExportResourceList(&storageRecords, "id", "desc", format))
+		return
+	}
 	pager, err := req.LoadResourceList(&storageRecords, "id", "desc")
 	if api.AbortIfError(c, err) {
 		return
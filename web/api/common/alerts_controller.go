@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/helpers"
 	"github.com/APTrust/registry/pgmodels"
 	"github.com/APTrust/registry/web/api"
 	"github.com/gin-gonic/gin"
@@ -30,10 +31,16 @@ func AlertShow(c *gin.Context) {
 // that user is sys admin, who can see all alerts.
 //
 // GET /member-api/v3/alerts
+// GET /member-api/v3/alerts?format=csv|jsonl
 // GET /admin-api/v3/alerts
+// GET /admin-api/v3/alerts?format=csv|jsonl
 func AlertIndex(c *gin.Context) {
 	req := api.NewRequest(c)
 	var alerts []*pgmodels.AlertView
+	if format := helpers.ExportFormat(c.Request); format != "" {
+		api.AbortIfError(c, req.ExportResourceList(&alerts, "created_at", "desc", format))
+		return
+	}
 	pager, err := req.LoadResourceList(&alerts, "created_at", "desc")
 	if api.AbortIfError(c, err) {
 		return
@@ -3,6 +3,7 @@ package common_api
 import (
 	"net/http"
 
+	"github.com/APTrust/registry/helpers"
 	"github.com/APTrust/registry/pgmodels"
 	"github.com/APTrust/registry/web/api"
 	"github.com/gin-gonic/gin"
@@ -11,10 +12,16 @@ import (
 // ChecksumIndex shows list of objects.
 //
 // GET /member-api/v3/checksums
+// GET /member-api/v3/checksums?format=csv|jsonl
 // GET /admin-api/v3/checksums
+// GET /admin-api/v3/checksums?format=csv|jsonl
 func ChecksumIndex(c *gin.Context) {
 	req := api.NewRequest(c)
 	var checksums []*pgmodels.ChecksumView
+	if format := helpers.ExportFormat(c.Request); format != "" {
+		api.AbortIfError(c, req.ExportResourceList(&checksums, "datetime", "desc", format))
+		return
+	}
 	pager, err := req.LoadResourceList(&checksums, "datetime", "desc")
 	if api.AbortIfError(c, err) {
 		return
@@ -3,6 +3,7 @@ package common_api
 import (
 	"net/http"
 
+	"github.com/APTrust/registry/helpers"
 	"github.com/APTrust/registry/pgmodels"
 	"github.com/APTrust/registry/web/api"
 	"github.com/gin-gonic/gin"
@@ -33,10 +34,16 @@ func DeletionRequestShow(c *gin.Context) {
 // DeletionRequestIndex shows list of deletion requests.
 //
 // GET /member-api/v3/deletions
+// GET /member-api/v3/deletions?format=csv|jsonl
 // GET /admin-api/v3/deletions
+// GET /admin-api/v3/deletions?format=csv|jsonl
 func DeletionRequestIndex(c *gin.Context) {
 	req := api.NewRequest(c)
 	var deletions []*pgmodels.DeletionRequestView
+	if format := helpers.ExportFormat(c.Request); format != "" {
+		api.AbortIfError(c, req.ExportResourceList(&deletions, "requested_at", "desc", format))
+		return
+	}
 	pager, err := req.LoadResourceList(&deletions, "requested_at", "desc")
 	if api.AbortIfError(c, err) {
 		return
@@ -3,6 +3,7 @@ package common_api
 import (
 	"net/http"
 
+	"github.com/APTrust/registry/helpers"
 	"github.com/APTrust/registry/pgmodels"
 	"github.com/APTrust/registry/web/api"
 	"github.com/gin-gonic/gin"
@@ -12,9 +13,14 @@ import (
 // the member API version returns a list of GenericFileView objects.
 //
 // GET /member-api/v3/files
+// GET /member-api/v3/files?format=csv|jsonl
 func GenericFileIndex(c *gin.Context) {
 	req := api.NewRequest(c)
 	var files []*pgmodels.GenericFileView
+	if format := helpers.ExportFormat(c.Request); format != "" {
+		api.AbortIfError(c, req.ExportResourceList(&files, "updated_at", "desc", format))
+		return
+	}
 	pager, err := req.LoadResourceList(&files, "updated_at", "desc")
 	if api.AbortIfError(c, err) {
 		return
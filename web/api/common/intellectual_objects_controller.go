@@ -3,6 +3,7 @@ package common_api
 import (
 	"net/http"
 
+	"github.com/APTrust/registry/helpers"
 	"github.com/APTrust/registry/pgmodels"
 	"github.com/APTrust/registry/web/api"
 	"github.com/gin-gonic/gin"
@@ -11,10 +12,16 @@ import (
 // IntellectualObjectIndex shows list of objects.
 //
 // GET /member-api/v3/objects
+// GET /member-api/v3/objects?format=csv|jsonl
 // GET /admin-api/v3/objects
+// GET /admin-api/v3/objects?format=csv|jsonl
 func IntellectualObjectIndex(c *gin.Context) {
 	req := api.NewRequest(c)
 	var objs []*pgmodels.IntellectualObjectView
+	if format := helpers.ExportFormat(c.Request); format != "" {
+		api.AbortIfError(c, req.ExportResourceList(&objs, "updated_at", "desc", format))
+		return
+	}
 	pager, err := req.LoadResourceList(&objs, "updated_at", "desc")
 	if api.AbortIfError(c, err) {
 		return
@@ -1,8 +1,10 @@
 package common_api_test
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/APTrust/registry/constants"
@@ -123,3 +125,49 @@ func TestIntellectualObjectIndex(t *testing.T) {
 		Expect().Status(http.StatusForbidden)
 
 }
+
+func TestIntellectualObjectIndexExport(t *testing.T) {
+	tu.InitHTTPTests(t)
+
+	// CSV export includes all matching objects, with no paging.
+	resp := tu.SysAdminClient.GET("/member-api/v3/objects").
+		WithQuery("format", "csv").
+		WithQuery("per_page", 5).
+		Expect().Status(http.StatusOK)
+	resp.Header("Content-Type").Equal("text/csv; charset=utf-8")
+	resp.Header("Content-Disposition").Contains(`filename="objects-`)
+	records, err := csv.NewReader(strings.NewReader(resp.Body().Raw())).ReadAll()
+	require.Nil(t, err)
+	require.Equal(t, 15, len(records)) // header + 14 objects
+	assert.Equal(t, "id", records[0][0])
+
+	// Exports honor filters.
+	resp = tu.SysAdminClient.GET("/member-api/v3/objects").
+		WithQuery("format", "csv").
+		WithQuery("access", constants.AccessConsortia).
+		WithQuery("state", "A").
+		Expect().Status(http.StatusOK)
+	records, err = csv.NewReader(strings.NewReader(resp.Body().Raw())).ReadAll()
+	require.Nil(t, err)
+	assert.Equal(t, 3, len(records))
+
+	// Accept header selects JSON lines, and non-admins get only
+	// their own institution's objects.
+	resp = tu.Inst1UserClient.GET("/member-api/v3/objects").
+		WithHeader("Accept", "application/x-ndjson").
+		Expect().Status(http.StatusOK)
+	resp.Header("Content-Type").Equal("application/x-ndjson; charset=utf-8")
+	lines := strings.Split(strings.TrimSpace(resp.Body().Raw()), "\n")
+	require.Equal(t, 6, len(lines))
+	for _, line := range lines {
+		obj := &pgmodels.IntellectualObjectView{}
+		require.Nil(t, json.Unmarshal([]byte(line), obj))
+		assert.Equal(t, tu.Inst1User.InstitutionID, obj.InstitutionID)
+	}
+
+	// Exports get the same authorization checks as regular lists.
+	tu.Inst2UserClient.GET("/member-api/v3/objects").
+		WithQuery("format", "jsonl").
+		WithQuery("institution_id", tu.Inst1Admin.InstitutionID).
+		Expect().Status(http.StatusForbidden)
+}
@@ -3,6 +3,7 @@ package common_api
 import (
 	"net/http"
 
+	"github.com/APTrust/registry/helpers"
 	"github.com/APTrust/registry/pgmodels"
 	"github.com/APTrust/registry/web/api"
 	"github.com/gin-gonic/gin"
@@ -11,10 +12,16 @@ import (
 // PremisEventIndex shows list of objects.
 //
 // GET /member-api/v3/events
+// GET /member-api/v3/events?format=csv|jsonl
 // GET /admin-api/v3/events
+// GET /admin-api/v3/events?format=csv|jsonl
 func PremisEventIndex(c *gin.Context) {
 	req := api.NewRequest(c)
 	var events []*pgmodels.PremisEventView
+	if format := helpers.ExportFormat(c.Request); format != "" {
+		api.AbortIfError(c, req.ExportResourceList(&events, "date_time", "desc", format))
+		return
+	}
 	pager, err := req.LoadResourceList(&events, "date_time", "desc")
 	if api.AbortIfError(c, err) {
 		return
@@ -132,3 +132,74 @@ func TestPremisEventIndex(t *testing.T) {
 		Expect().Status(http.StatusForbidden)
 
 }
+
+func TestPremisEventIndexCursor(t *testing.T) {
+	tu.InitHTTPTests(t)
+
+	// Get the full list the old way, for comparison.
+	resp := tu.Inst1AdminClient.GET("/member-api/v3/events").
+		WithQuery("per_page", 1000).
+		Expect().Status(http.StatusOK)
+	all := api.PremisEventViewList{}
+	require.Nil(t, json.Unmarshal([]byte(resp.Body().Raw()), &all))
+	require.True(t, all.Count > 5)
+	assert.Empty(t, all.NextCursor)
+
+	// An empty cursor starts cursor pagination.
+	resp = tu.Inst1AdminClient.GET("/member-api/v3/events").
+		WithQuery("cursor", "").
+		WithQuery("per_page", 5).
+		Expect().Status(http.StatusOK)
+	page := api.PremisEventViewList{}
+	require.Nil(t, json.Unmarshal([]byte(resp.Body().Raw()), &page))
+	assert.Equal(t, all.Count, page.Count)
+	assert.Equal(t, 5, len(page.Results))
+	assert.NotEmpty(t, page.NextCursor)
+	assert.Empty(t, page.PreviousCursor)
+	assert.Empty(t, page.Previous)
+	assert.Contains(t, page.Next, "cursor="+page.NextCursor)
+
+	// Follow next_cursor through the whole list.
+	ids := make([]int64, 0)
+	for _, event := range page.Results {
+		ids = append(ids, event.ID)
+	}
+	for page.NextCursor != "" {
+		resp = tu.Inst1AdminClient.GET("/member-api/v3/events").
+			WithQuery("cursor", page.NextCursor).
+			WithQuery("per_page", 5).
+			Expect().Status(http.StatusOK)
+		page = api.PremisEventViewList{}
+		require.Nil(t, json.Unmarshal([]byte(resp.Body().Raw()), &page))
+		assert.Equal(t, all.Count, page.Count)
+		assert.NotEmpty(t, page.PreviousCursor)
+		for _, event := range page.Results {
+			assert.Equal(t, tu.Inst1Admin.InstitutionID, event.InstitutionID)
+			ids = append(ids, event.ID)
+		}
+	}
+	// Cursor pages break ties on id, so events with the same
+	// timestamp may come back in a different order.
+	allIDs := make([]int64, len(all.Results))
+	for i, event := range all.Results {
+		allIDs[i] = event.ID
+	}
+	assert.ElementsMatch(t, allIDs, ids)
+
+	// Bad cursors are rejected.
+	tu.Inst1AdminClient.GET("/member-api/v3/events").
+		WithQuery("cursor", "this-is-not-a-cursor").
+		Expect().Status(http.StatusBadRequest)
+
+	// So are cursors from a list with a different sort order.
+	resp = tu.Inst1AdminClient.GET("/member-api/v3/events").
+		WithQuery("cursor", "").
+		WithQuery("per_page", 2).
+		Expect().Status(http.StatusOK)
+	page = api.PremisEventViewList{}
+	require.Nil(t, json.Unmarshal([]byte(resp.Body().Raw()), &page))
+	tu.Inst1AdminClient.GET("/member-api/v3/events").
+		WithQuery("cursor", page.NextCursor).
+		WithQuery("sort", "event_type__asc").
+		Expect().Status(http.StatusBadRequest)
+}
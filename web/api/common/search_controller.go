@@ -0,0 +1,38 @@
+package common_api
+
+import (
+	"net/http"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/APTrust/registry/web/api"
+	"github.com/gin-gonic/gin"
+)
+
+// SearchIndex returns the objects, files and events matching a
+// full-text search, best matches first. See api.SearchParamsFor for
+// the params and pgmodels.SearchParams for the search syntax.
+//
+// GET /member-api/v3/search?q=photos&type=object
+// GET /admin-api/v3/search?q=photos&institution_id=2
+func SearchIndex(c *gin.Context) {
+	req := api.NewRequest(c)
+	err := req.ValidateFilters()
+	if api.AbortIfError(c, err) {
+		return
+	}
+	pager, err := common.NewPager(c, req.PathAndQuery, 20)
+	if api.AbortIfError(c, err) {
+		return
+	}
+	params, err := api.SearchParamsFor(c, req.Auth, pager)
+	if api.AbortIfError(c, err) {
+		return
+	}
+	results, count, err := pgmodels.Search(params)
+	if api.AbortIfError(c, err) {
+		return
+	}
+	pager.SetCounts(count, len(results))
+	c.JSON(http.StatusOK, api.NewJsonList(results, pager))
+}
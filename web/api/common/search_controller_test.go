@@ -0,0 +1,88 @@
+package common_api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/web/api"
+	tu "github.com/APTrust/registry/web/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchIndex(t *testing.T) {
+	tu.InitHTTPTests(t)
+
+	// Sys Admin searches all institutions, through either API.
+	for _, prefix := range []string{"/member-api/v3", "/admin-api/v3"} {
+		resp := tu.SysAdminClient.GET(prefix+"/search").
+			WithQuery("q", "photos").
+			Expect().Status(http.StatusOK)
+		list := api.SearchResultList{}
+		err := json.Unmarshal([]byte(resp.Body().Raw()), &list)
+		require.Nil(t, err)
+		require.NotEmpty(t, list.Results)
+		assert.Equal(t, len(list.Results), list.Count)
+		assert.Equal(t, constants.SearchTypeObject, list.Results[0].Type)
+		assert.Equal(t, "institution1.edu/photos", list.Results[0].Identifier)
+		instIDs := make(map[int64]bool)
+		for _, result := range list.Results {
+			instIDs[result.InstitutionID] = true
+		}
+		assert.True(t, instIDs[tu.Inst1User.InstitutionID])
+		assert.True(t, instIDs[tu.Inst2User.InstitutionID])
+	}
+
+	// Admin can limit by type and institution, and page through results.
+	resp := tu.SysAdminClient.GET("/admin-api/v3/search").
+		WithQuery("q", "photos").
+		WithQuery("type", constants.SearchTypeFile).
+		WithQuery("institution_id", tu.Inst1User.InstitutionID).
+		WithQuery("per_page", 2).
+		Expect().Status(http.StatusOK)
+	list := api.SearchResultList{}
+	err := json.Unmarshal([]byte(resp.Body().Raw()), &list)
+	require.Nil(t, err)
+	assert.Equal(t, 3, list.Count)
+	assert.Len(t, list.Results, 2)
+	assert.NotEmpty(t, list.Next)
+	for _, result := range list.Results {
+		assert.Equal(t, constants.SearchTypeFile, result.Type)
+		assert.Equal(t, tu.Inst1User.InstitutionID, result.InstitutionID)
+	}
+
+	// Non-admins see only their own institution's results.
+	resp = tu.Inst2UserClient.GET("/member-api/v3/search").
+		WithQuery("q", "photos").
+		Expect().Status(http.StatusOK)
+	list = api.SearchResultList{}
+	err = json.Unmarshal([]byte(resp.Body().Raw()), &list)
+	require.Nil(t, err)
+	require.NotEmpty(t, list.Results)
+	for _, result := range list.Results {
+		assert.Equal(t, tu.Inst2User.InstitutionID, result.InstitutionID)
+	}
+
+	// And can't search other institutions.
+	tu.Inst2UserClient.GET("/member-api/v3/search").
+		WithQuery("q", "photos").
+		WithQuery("institution_id", tu.Inst1User.InstitutionID).
+		Expect().Status(http.StatusForbidden)
+
+	// Non-admins can't use the admin API.
+	tu.Inst1AdminClient.GET("/admin-api/v3/search").
+		WithQuery("q", "photos").
+		Expect().Status(http.StatusForbidden)
+
+	// Invalid types and cursors are bad requests.
+	tu.Inst1UserClient.GET("/member-api/v3/search").
+		WithQuery("q", "photos").
+		WithQuery("type", "work_item").
+		Expect().Status(http.StatusBadRequest)
+	tu.Inst1UserClient.GET("/member-api/v3/search").
+		WithQuery("q", "photos").
+		WithQuery("cursor", "").
+		Expect().Status(http.StatusBadRequest)
+}
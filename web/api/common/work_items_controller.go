@@ -3,6 +3,7 @@ package common_api
 import (
 	"net/http"
 
+	"github.com/APTrust/registry/helpers"
 	"github.com/APTrust/registry/pgmodels"
 	"github.com/APTrust/registry/web/api"
 	"github.com/gin-gonic/gin"
@@ -11,10 +12,16 @@ import (
 // WorkItemIndex shows list of objects.
 //
 // GET /member-api/v3/items
+// GET /member-api/v3/items?format=csv|jsonl
 // GET /admin-api/v3/items
+// GET /admin-api/v3/items?format=csv|jsonl
 func WorkItemIndex(c *gin.Context) {
 	req := api.NewRequest(c)
 	var items []*pgmodels.WorkItemView
+	if format := helpers.ExportFormat(c.Request); format != "" {
+		api.AbortIfError(c, req.ExportResourceList(&items, "updated_at", "desc", format))
+		return
+	}
 	pager, err := req.LoadResourceList(&items, "updated_at", "desc")
 	if api.AbortIfError(c, err) {
 		return
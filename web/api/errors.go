@@ -59,9 +59,11 @@ func StatusCodeForError(err error) (status int) {
 		status = http.StatusMethodNotAllowed
 	case common.ErrInternal:
 		status = http.StatusInternalServerError
-	case common.ErrPendingWorkItems:
+	case common.ErrPendingWorkItems, common.ErrFixityCheckPending:
 		status = http.StatusConflict
-	case common.ErrWrongDataType, common.ErrIDMismatch, common.ErrInstIDChange, common.ErrIdentifierChange, common.ErrStorageOptionChange, common.ErrDecodeCookie:
+	case common.ErrTooManyAttempts, common.ErrAccountLocked:
+		status = http.StatusTooManyRequests
+	case common.ErrWrongDataType, common.ErrIDMismatch, common.ErrInstIDChange, common.ErrIdentifierChange, common.ErrStorageOptionChange, common.ErrDecodeCookie, common.ErrInvalidCursor, common.ErrWebAuthnOrigin, common.ErrWebAuthnVerification, common.ErrNoWebAuthnCredentials, common.ErrGlacierFixity, common.ErrTooManyItems, common.ErrInvalidParam:
 		status = http.StatusBadRequest
 	default:
 		status = http.StatusInternalServerError
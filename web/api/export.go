@@ -0,0 +1,88 @@
+package api
+
+import (
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/helpers"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/gin-gonic/gin"
+)
+
+// StreamExport writes every record matching query to the response as
+// CSV or JSON lines, depending on format. Param items should be a
+// pointer to a slice of pointers to the type of record to export.
+//
+// Records come from a server-side cursor, a batch at a time, and each
+// batch is flushed to the client as soon as it's written, so exports
+// of hundreds of thousands of records don't have to fit in memory.
+//
+// This returns an error only if it fails before writing anything,
+// in which case the caller can still send an error response. Errors
+// after the export has started are logged, and the response ends
+// early, since the status code has already gone out.
+func StreamExport(c *gin.Context, query *pgmodels.Query, items interface{}, format string) error {
+	writer, err := helpers.NewExportWriter(c.Writer, format)
+	if err != nil {
+		return err
+	}
+	c.Header("Content-Type", helpers.ExportContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, ExportFileName(c, format)))
+	err = query.Stream(items, pgmodels.StreamBatchSize, func() error {
+		err := writer.WriteList(items)
+		if err == nil {
+			err = writer.Flush()
+		}
+		c.Writer.Flush()
+		return err
+	})
+	if err == nil {
+		// Items is empty after the last fetch. This writes the CSV
+		// header if the result set was empty.
+		err = writer.WriteList(items)
+		if err == nil {
+			err = writer.Flush()
+		}
+		c.Writer.WriteHeaderNow()
+	}
+	if err == nil {
+		return nil
+	}
+	if !c.Writer.Written() {
+		c.Header("Content-Disposition", "")
+		return err
+	}
+	common.Context().Log.Error().Msgf("Export of %s stopped early: %v", c.Request.URL.Path, err)
+	c.Abort()
+	return nil
+}
+
+// WriteExport writes items to the response as CSV or JSON lines. Use
+// this for short lists that are already in memory, such as report
+// summaries. Use StreamExport for query results.
+func WriteExport(c *gin.Context, items interface{}, format string) error {
+	writer, err := helpers.NewExportWriter(c.Writer, format)
+	if err != nil {
+		return err
+	}
+	c.Header("Content-Type", helpers.ExportContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, ExportFileName(c, format)))
+	err = writer.WriteList(items)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		c.Writer.WriteHeaderNow()
+	}
+	return err
+}
+
+// ExportFileName returns the name of the file the client should save
+// an export to, based on the last segment of the request path. For
+// example, an export from /member-api/v3/objects on Oct. 18, 2026 is
+// objects-2026-10-18.csv.
+func ExportFileName(c *gin.Context, format string) string {
+	return fmt.Sprintf("%s-%s.%s", path.Base(c.Request.URL.Path), time.Now().UTC().Format("2006-01-02"), format)
+}
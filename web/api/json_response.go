@@ -16,6 +16,13 @@ type JsonList struct {
 	Next string `json:"next"`
 	// Previous is the URL for the previous page of results.
 	Previous string `json:"previous"`
+	// NextCursor is the cursor token for the next page of results.
+	// This appears only when the request used cursor pagination
+	// (i.e. included a cursor param).
+	NextCursor string `json:"next_cursor,omitempty"`
+	// PreviousCursor is the cursor token for the previous page of
+	// results, when the request used cursor pagination.
+	PreviousCursor string `json:"previous_cursor,omitempty"`
 	// Results is the list of items on this page of the result set.
 	Results interface{} `json:"results"`
 }
@@ -23,10 +30,12 @@ type JsonList struct {
 // NewJsonList creates a new json list response structure.
 func NewJsonList(items interface{}, pager *common.Pager) *JsonList {
 	return &JsonList{
-		Count:    pager.TotalItems,
-		Next:     pager.NextLink,
-		Previous: pager.PreviousLink,
-		Results:  items,
+		Count:          pager.TotalItems,
+		Next:           pager.NextLink,
+		Previous:       pager.PreviousLink,
+		NextCursor:     pager.NextCursor,
+		PreviousCursor: pager.PreviousCursor,
+		Results:        items,
 	}
 }
 
@@ -41,89 +50,131 @@ func NewListFromJson(jsonStr string) (*JsonList, error) {
 // AlertViewList is used in testing to convert a generic
 // JsonList into a typed list that we can test with assertions.
 type AlertViewList struct {
-	Count    int                   `json:"count"`
-	Next     string                `json:"next"`
-	Previous string                `json:"previous"`
-	Results  []*pgmodels.AlertView `json:"results"`
+	Count          int                   `json:"count"`
+	Next           string                `json:"next"`
+	Previous       string                `json:"previous"`
+	NextCursor     string                `json:"next_cursor"`
+	PreviousCursor string                `json:"previous_cursor"`
+	Results        []*pgmodels.AlertView `json:"results"`
+}
+
+// AuditEventList is used in testing to convert a generic
+// JsonList into a typed list that we can test with assertions.
+type AuditEventList struct {
+	Count          int                    `json:"count"`
+	Next           string                 `json:"next"`
+	Previous       string                 `json:"previous"`
+	NextCursor     string                 `json:"next_cursor"`
+	PreviousCursor string                 `json:"previous_cursor"`
+	Results        []*pgmodels.AuditEvent `json:"results"`
 }
 
 // ChecksumViewList is used in testing to convert a generic
 // JsonList into a typed list that we can test with assertions.
 type ChecksumViewList struct {
-	Count    int                      `json:"count"`
-	Next     string                   `json:"next"`
-	Previous string                   `json:"previous"`
-	Results  []*pgmodels.ChecksumView `json:"results"`
+	Count          int                      `json:"count"`
+	Next           string                   `json:"next"`
+	Previous       string                   `json:"previous"`
+	NextCursor     string                   `json:"next_cursor"`
+	PreviousCursor string                   `json:"previous_cursor"`
+	Results        []*pgmodels.ChecksumView `json:"results"`
 }
 
 // DeletionRequestViewList is used in testing to convert a generic
 // JsonList into a typed list that we can test with assertions.
 type DeletionRequestViewList struct {
-	Count    int                             `json:"count"`
-	Next     string                          `json:"next"`
-	Previous string                          `json:"previous"`
-	Results  []*pgmodels.DeletionRequestView `json:"results"`
+	Count          int                             `json:"count"`
+	Next           string                          `json:"next"`
+	Previous       string                          `json:"previous"`
+	NextCursor     string                          `json:"next_cursor"`
+	PreviousCursor string                          `json:"previous_cursor"`
+	Results        []*pgmodels.DeletionRequestView `json:"results"`
 }
 
 // GenericFileList is used in testing to convert a generic
 // JsonList into a typed list that we can test with assertions.
 type GenericFileList struct {
-	Count    int                     `json:"count"`
-	Next     string                  `json:"next"`
-	Previous string                  `json:"previous"`
-	Results  []*pgmodels.GenericFile `json:"results"`
+	Count          int                     `json:"count"`
+	Next           string                  `json:"next"`
+	Previous       string                  `json:"previous"`
+	NextCursor     string                  `json:"next_cursor"`
+	PreviousCursor string                  `json:"previous_cursor"`
+	Results        []*pgmodels.GenericFile `json:"results"`
 }
 
 // GenericFileViewList is used in testing to convert a generic
 // JsonList into a typed list that we can test with assertions.
 type GenericFileViewList struct {
-	Count    int                         `json:"count"`
-	Next     string                      `json:"next"`
-	Previous string                      `json:"previous"`
-	Results  []*pgmodels.GenericFileView `json:"results"`
+	Count          int                         `json:"count"`
+	Next           string                      `json:"next"`
+	Previous       string                      `json:"previous"`
+	NextCursor     string                      `json:"next_cursor"`
+	PreviousCursor string                      `json:"previous_cursor"`
+	Results        []*pgmodels.GenericFileView `json:"results"`
 }
 
 // InstitutionViewList is used in testing to convert a generic
 // JsonList into a typed list that we can test with assertions.
 type InstitutionViewList struct {
-	Count    int                         `json:"count"`
-	Next     string                      `json:"next"`
-	Previous string                      `json:"previous"`
-	Results  []*pgmodels.InstitutionView `json:"results"`
+	Count          int                         `json:"count"`
+	Next           string                      `json:"next"`
+	Previous       string                      `json:"previous"`
+	NextCursor     string                      `json:"next_cursor"`
+	PreviousCursor string                      `json:"previous_cursor"`
+	Results        []*pgmodels.InstitutionView `json:"results"`
 }
 
 // IntellectualObjectList is used in testing to convert a generic
 // JsonList into a typed list that we can test with assertions.
 type IntellectualObjectList struct {
-	Count    int                                `json:"count"`
-	Next     string                             `json:"next"`
-	Previous string                             `json:"previous"`
-	Results  []*pgmodels.IntellectualObjectView `json:"results"`
+	Count          int                                `json:"count"`
+	Next           string                             `json:"next"`
+	Previous       string                             `json:"previous"`
+	NextCursor     string                             `json:"next_cursor"`
+	PreviousCursor string                             `json:"previous_cursor"`
+	Results        []*pgmodels.IntellectualObjectView `json:"results"`
 }
 
 // PremisEventViewList is used in testing to convert a generic
 // JsonList into a typed list that we can test with assertions.
 type PremisEventViewList struct {
-	Count    int                         `json:"count"`
-	Next     string                      `json:"next"`
-	Previous string                      `json:"previous"`
-	Results  []*pgmodels.PremisEventView `json:"results"`
+	Count          int                         `json:"count"`
+	Next           string                      `json:"next"`
+	Previous       string                      `json:"previous"`
+	NextCursor     string                      `json:"next_cursor"`
+	PreviousCursor string                      `json:"previous_cursor"`
+	Results        []*pgmodels.PremisEventView `json:"results"`
+}
+
+// SearchResultList is used in testing to convert a generic
+// JsonList into a typed list that we can test with assertions.
+type SearchResultList struct {
+	Count          int                      `json:"count"`
+	Next           string                   `json:"next"`
+	Previous       string                   `json:"previous"`
+	NextCursor     string                   `json:"next_cursor"`
+	PreviousCursor string                   `json:"previous_cursor"`
+	Results        []*pgmodels.SearchResult `json:"results"`
 }
 
 // StorageRecordList is used in testing to convert a generic
 // JsonList into a typed list that we can test with assertions.
 type StorageRecordList struct {
-	Count    int                       `json:"count"`
-	Next     string                    `json:"next"`
-	Previous string                    `json:"previous"`
-	Results  []*pgmodels.StorageRecord `json:"results"`
+	Count          int                       `json:"count"`
+	Next           string                    `json:"next"`
+	Previous       string                    `json:"previous"`
+	NextCursor     string                    `json:"next_cursor"`
+	PreviousCursor string                    `json:"previous_cursor"`
+	Results        []*pgmodels.StorageRecord `json:"results"`
 }
 
 // WorkItemViewList is used in testing to convert a generic
 // JsonList into a typed list that we can test with assertions.
 type WorkItemViewList struct {
-	Count    int                      `json:"count"`
-	Next     string                   `json:"next"`
-	Previous string                   `json:"previous"`
-	Results  []*pgmodels.WorkItemView `json:"results"`
+	Count          int                      `json:"count"`
+	Next           string                   `json:"next"`
+	Previous       string                   `json:"previous"`
+	NextCursor     string                   `json:"next_cursor"`
+	PreviousCursor string                   `json:"previous_cursor"`
+	Results        []*pgmodels.WorkItemView `json:"results"`
 }
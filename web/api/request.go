@@ -52,7 +52,7 @@ func NewRequest(c *gin.Context) *Request {
 // the issues in preservation services.
 func (req *Request) ValidateFilters() error {
 	allowedFilters := pgmodels.FiltersFor(req.Auth.ResourceType)
-	allowedParams := append(allowedFilters, "sort", "page", "per_page")
+	allowedParams := append(allowedFilters, "sort", "page", "per_page", "cursor", "format")
 	invalid := make([]string, 0)
 	for paramName, _ := range req.GinContext.Request.URL.Query() {
 		if !slice.Contains(allowedParams, paramName) {
@@ -104,57 +104,114 @@ func (req *Request) LoadResourceList(items interface{}, orderByColumn, direction
 		return nil, common.ErrInvalidParam
 	}
 
-	err := req.ValidateFilters()
+	query, err := req.listQuery(items, orderByColumn, direction)
 	if err != nil {
 		return nil, err
 	}
-
-	filterCollection := req.GetFilterCollection()
-	query, err := filterCollection.ToQuery()
+	pager, err := common.NewPager(req.GinContext, req.PathAndQuery, 20)
 	if err != nil {
 		return nil, err
 	}
-	if !req.CurrentUser.IsAdmin() {
-		query.Where("institution_id", "=", req.CurrentUser.InstitutionID)
-		objType := reflect.ValueOf(items).Elem().Type()
-		if objType == reflect.TypeOf([]*pgmodels.AlertView{}) || objType == reflect.TypeOf([]*pgmodels.Alert{}) {
-			query.Where("user_id", "=", req.CurrentUser.ID)
-		}
+	// This sucks. Maybe there's a way to call the underlying
+	// type's select method, because that would handle this.
+	if reflect.ValueOf(items).Elem().Type() == reflect.TypeOf([]*pgmodels.GenericFile{}) {
+		query.Relations("Checksums", "PremisEvents", "StorageRecords")
 	}
-	if !filterCollection.HasExplicitSorting() {
-		query.OrderBy(orderByColumn, direction)
+	if pager.UsesCursor {
+		err = req.loadCursorPage(items, query, pager)
+		return pager, err
 	}
-	pager, err := common.NewPager(req.GinContext, req.PathAndQuery, 20)
+
+	query.Offset(pager.QueryOffset).Limit(pager.PerPage)
+	err = query.Select(items)
 	if err != nil {
 		return nil, err
 	}
-	query.Offset(pager.QueryOffset).Limit(pager.PerPage)
+	count, err := countResources(query, items)
+	if err != nil {
+		return nil, err
+	}
+	pager.SetCounts(count, reflect.ValueOf(items).Elem().Len())
+	return pager, err
+}
 
-	// This sucks. Maybe there's a way to call the underlying
-	// type's select method, because that would handle this.
-	if reflect.ValueOf(items).Elem().Type() == reflect.TypeOf([]*pgmodels.GenericFile{}) {
-		err = query.Relations("Checksums", "PremisEvents", "StorageRecords").Select(items)
+// loadCursorPage loads one page of a cursor-paged list. Cursor
+// pagination uses the first sort column plus id to find where the
+// page starts, so deep pages of large tables load as quickly as the
+// first page. We count matching records only for the first page.
+// Later pages carry the count forward in the cursor.
+func (req *Request) loadCursorPage(items interface{}, query *pgmodels.Query, pager *common.Pager) error {
+	column, direction := query.SortColumn()
+	cursor := pgmodels.NewCursor(column, direction)
+	if pager.Cursor == "" {
+		count, err := countResources(query, items)
+		if err != nil {
+			return err
+		}
+		cursor.Total = count
 	} else {
-		err = query.Select(items)
+		requestedCursor, err := pgmodels.DecodeCursor(pager.Cursor)
+		if err != nil {
+			return err
+		}
+		// The client changed the sort order between pages.
+		if requestedCursor.Column != cursor.Column || requestedCursor.Direction != cursor.Direction {
+			return common.ErrInvalidCursor
+		}
+		cursor = requestedCursor
 	}
+	next, previous, err := query.SelectPage(items, cursor, pager.PerPage)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	pager.SetCounts(cursor.Total, reflect.ValueOf(items).Elem().Len())
+	pager.SetCursors(cursorToken(next), cursorToken(previous))
+	return nil
+}
 
-	var count int
+func countResources(query *pgmodels.Query, items interface{}) (int, error) {
 	if pgmodels.CanCountFromView(query, items) {
 		common.Context().Log.Info().Msgf("API: Using view to count query '%s'", query.WhereClause())
-		count, err = pgmodels.GetCountFromView(query, items)
-	} else {
-		common.Context().Log.Info().Msgf("API: Using standard count query for '%s'", query.WhereClause())
-		count, err = query.Count(items)
+		return pgmodels.GetCountFromView(query, items)
+	}
+	common.Context().Log.Info().Msgf("API: Using standard count query for '%s'", query.WhereClause())
+	return query.Count(items)
+}
+
+func cursorToken(cursor *pgmodels.Cursor) string {
+	if cursor == nil {
+		return ""
 	}
+	return cursor.Encode()
+}
+
+// ExportResourceList streams all resources matching the request's
+// filters to the client as CSV or JSON lines, depending on param
+// format. It applies the same filters, sorting and institution
+// restrictions as LoadResourceList, but it ignores paging. Params
+// items, orderByColumn and direction are the same as for
+// LoadResourceList. See StreamExport for error handling.
+func (req *Request) ExportResourceList(items interface{}, orderByColumn, direction, format string) error {
+	if items == nil || !strings.HasPrefix(reflect.TypeOf(items).String(), "*[]*pgmodels.") {
+		common.Context().Log.Error().Msgf("Request.ExportResourceList: Param items should be pointer to slice of pointers.")
+		return common.ErrInvalidParam
+	}
+	query, err := req.listQuery(items, orderByColumn, direction)
+	if err != nil {
+		return err
+	}
+	return StreamExport(req.GinContext, query, items, format)
+}
 
+// listQuery returns a query for the resources requested in the
+// query string, restricted to the current user's institution for
+// non-admins.
+func (req *Request) listQuery(items interface{}, orderByColumn, direction string) (*pgmodels.Query, error) {
+	err := req.ValidateFilters()
 	if err != nil {
 		return nil, err
 	}
-	pager.SetCounts(count, reflect.ValueOf(items).Elem().Len())
-	return pager, err
+	return req.GetFilterCollection().ToQueryFor(req.CurrentUser, items, orderByColumn, direction)
 }
 
 // AssertValidIDs returns an error if resource or institution ID in an
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"strconv"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/middleware"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/stew/slice"
+)
+
+// SearchParamsFor returns the full-text search described by the query
+// string params q, type and institution_id, for one page of results.
+// The web UI and both APIs use this, so they scope searches the same
+// way.
+//
+// Results include only the kinds of records the current user, and the
+// API key they authenticated with, if any, can read. Non-admins see
+// only their own institution. This returns common.ErrInvalidParam if
+// type is not one of constants.SearchTypes, or if the request uses
+// cursor pagination, which doesn't work with ranked results.
+func SearchParamsFor(c *gin.Context, auth *middleware.ResourceAuthorization, pager *common.Pager) (*pgmodels.SearchParams, error) {
+	if pager.UsesCursor {
+		return nil, common.ErrInvalidParam
+	}
+	requestedType := c.Query("type")
+	if requestedType != "" && !slice.Contains(constants.SearchTypes, requestedType) {
+		return nil, common.ErrInvalidParam
+	}
+	params := &pgmodels.SearchParams{
+		Terms:  c.Query("q"),
+		Types:  make([]string, 0, len(constants.SearchTypes)),
+		Limit:  pager.PerPage,
+		Offset: pager.QueryOffset,
+	}
+	for _, searchType := range constants.SearchTypes {
+		if requestedType != "" && searchType != requestedType {
+			continue
+		}
+		if auth.HasPermission(pgmodels.SearchTypePermissions[searchType]) {
+			params.Types = append(params.Types, searchType)
+		}
+	}
+	params.InstitutionID, _ = strconv.ParseInt(c.Query("institution_id"), 10, 64)
+	if currentUser := auth.CurrentUser(); !currentUser.IsAdmin() {
+		params.InstitutionID = currentUser.InstitutionID
+	}
+	return params, nil
+}
@@ -0,0 +1,305 @@
+package testutil
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/crewjam/saml"
+	"github.com/crewjam/saml/logger"
+)
+
+// StubIdP is a minimal OpenID Connect and SAML 2.0 identity provider
+// for testing single sign-on. It runs on a local httptest server, so
+// the Registry can fetch its OIDC discovery document and keys and
+// exchange authorization codes, just as it would with a campus IdP.
+//
+// The stub never shows a login page. Tests call OIDCLogin or SAMLLogin
+// with the URL the Registry redirected to, and the stub signs in
+// whatever identity the test specifies.
+type StubIdP struct {
+	Server       *httptest.Server
+	ClientID     string
+	ClientSecret string
+	key          *rsa.PrivateKey
+	saml         *saml.IdentityProvider
+	grants       map[string]stubGrant
+	mutex        sync.Mutex
+}
+
+type stubGrant struct {
+	claims      map[string]interface{}
+	redirectURI string
+}
+
+// NewStubIdP starts a stub identity provider. The Registry's SAML
+// service provider metadata is built from registryBaseURL. Call Close
+// when you're done with it.
+func NewStubIdP(registryBaseURL string) (*StubIdP, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Stub IdP"},
+		NotBefore:    time.Now().Add(-1 * time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(certBytes)
+	if err != nil {
+		return nil, err
+	}
+	sp, err := common.NewSAMLServiceProvider(registryBaseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	idp := &StubIdP{
+		ClientID:     "registry-test-client",
+		ClientSecret: common.RandomToken(),
+		key:          key,
+		grants:       make(map[string]stubGrant),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.serveDiscovery)
+	mux.HandleFunc("/keys", idp.serveKeys)
+	mux.HandleFunc("/token", idp.serveToken)
+	idp.Server = httptest.NewServer(mux)
+
+	metadataURL, _ := url.Parse(idp.Server.URL + "/saml/metadata")
+	ssoURL, _ := url.Parse(idp.Server.URL + "/saml/sso")
+	idp.saml = &saml.IdentityProvider{
+		Key:                     key,
+		Certificate:             cert,
+		Logger:                  logger.DefaultLogger,
+		MetadataURL:             *metadataURL,
+		SSOURL:                  *ssoURL,
+		ServiceProviderProvider: &stubServiceProviders{sp: sp.Metadata()},
+	}
+	return idp, nil
+}
+
+// Close shuts down the stub's HTTP server.
+func (idp *StubIdP) Close() {
+	idp.Server.Close()
+}
+
+// IssuerURL returns the stub's OIDC issuer URL.
+func (idp *StubIdP) IssuerURL() string {
+	return idp.Server.URL
+}
+
+// SAMLMetadata returns the stub's SAML IdP metadata, which admins would
+// paste into the Registry's single sign-on settings.
+func (idp *StubIdP) SAMLMetadata() string {
+	data, _ := xml.MarshalIndent(idp.saml.Metadata(), "", "  ")
+	return string(data)
+}
+
+// OIDCLogin signs in a user with the specified ID token claims, which
+// should include at least sub and email. Param authURL is the URL the
+// Registry redirected the user to. This returns the URL to which the
+// IdP would send the user back, which includes the authorization code.
+func (idp *StubIdP) OIDCLogin(authURL string, claims map[string]interface{}) (string, error) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		return "", err
+	}
+	params := u.Query()
+	if params.Get("client_id") != idp.ClientID {
+		return "", fmt.Errorf("unknown client_id %s", params.Get("client_id"))
+	}
+	grantClaims := make(map[string]interface{})
+	for name, value := range claims {
+		grantClaims[name] = value
+	}
+	grantClaims["nonce"] = params.Get("nonce")
+	code := common.RandomToken()
+	idp.mutex.Lock()
+	idp.grants[code] = stubGrant{
+		claims:      grantClaims,
+		redirectURI: params.Get("redirect_uri"),
+	}
+	idp.mutex.Unlock()
+	callback := url.Values{}
+	callback.Set("code", code)
+	callback.Set("state", params.Get("state"))
+	return params.Get("redirect_uri") + "?" + callback.Encode(), nil
+}
+
+// SAMLLogin signs in a user with the specified NameID and attributes.
+// Param authURL is the URL the Registry redirected the user to. This
+// returns the form values (SAMLResponse and RelayState) the IdP would
+// have the user's browser post to the Registry's ACS URL.
+func (idp *StubIdP) SAMLLogin(authURL, nameID string, attributes map[string][]string) (url.Values, error) {
+	httpReq, err := http.NewRequest(http.MethodGet, authURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req, err := saml.NewIdpAuthnRequest(idp.saml, httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if err = req.Validate(); err != nil {
+		return nil, err
+	}
+	session := &saml.Session{
+		ID:           common.RandomToken(),
+		CreateTime:   time.Now(),
+		ExpireTime:   time.Now().Add(time.Hour),
+		Index:        common.RandomToken(),
+		NameID:       nameID,
+		NameIDFormat: string(saml.EmailAddressNameIDFormat),
+	}
+	for name, values := range attributes {
+		attr := saml.Attribute{
+			FriendlyName: name,
+			Name:         name,
+			NameFormat:   "urn:oasis:names:tc:SAML:2.0:attrname-format:basic",
+		}
+		for _, value := range values {
+			attr.Values = append(attr.Values, saml.AttributeValue{Type: "xs:string", Value: value})
+		}
+		session.CustomAttributes = append(session.CustomAttributes, attr)
+	}
+	if err = (saml.DefaultAssertionMaker{}).MakeAssertion(req, session); err != nil {
+		return nil, err
+	}
+	form, err := req.PostBinding()
+	if err != nil {
+		return nil, err
+	}
+	values := url.Values{}
+	values.Set("SAMLResponse", form.SAMLResponse)
+	values.Set("RelayState", form.RelayState)
+	return values, nil
+}
+
+func (idp *StubIdP) serveDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeStubJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                idp.Server.URL,
+		"authorization_endpoint":                idp.Server.URL + "/authorize",
+		"token_endpoint":                        idp.Server.URL + "/token",
+		"jwks_uri":                              idp.Server.URL + "/keys",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (idp *StubIdP) serveKeys(w http.ResponseWriter, r *http.Request) {
+	e := big.NewInt(int64(idp.key.PublicKey.E)).Bytes()
+	writeStubJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA",
+				"alg": "RS256",
+				"use": "sig",
+				"kid": "stub",
+				"n":   base64.RawURLEncoding.EncodeToString(idp.key.PublicKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(e),
+			},
+		},
+	})
+}
+
+// serveToken exchanges an authorization code for a signed ID token.
+// Codes can be used only once.
+func (idp *StubIdP) serveToken(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.PostFormValue("client_id")
+		clientSecret = r.PostFormValue("client_secret")
+	}
+	if clientID != idp.ClientID || clientSecret != idp.ClientSecret {
+		writeStubJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	code := r.PostFormValue("code")
+	idp.mutex.Lock()
+	grant, ok := idp.grants[code]
+	delete(idp.grants, code)
+	idp.mutex.Unlock()
+	if !ok || grant.redirectURI != r.PostFormValue("redirect_uri") {
+		writeStubJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	now := time.Now().Unix()
+	claims := map[string]interface{}{
+		"iss": idp.Server.URL,
+		"aud": idp.ClientID,
+		"iat": now,
+		"exp": now + 300,
+	}
+	for name, value := range grant.claims {
+		claims[name] = value
+	}
+	idToken, err := idp.signJWT(claims)
+	if err != nil {
+		writeStubJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeStubJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": common.RandomToken(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func (idp *StubIdP) signJWT(claims map[string]interface{}) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "kid": "stub", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, idp.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func writeStubJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+// stubServiceProviders tells the stub SAML IdP about the Registry,
+// which is the only service provider it knows.
+type stubServiceProviders struct {
+	sp *saml.EntityDescriptor
+}
+
+func (p *stubServiceProviders) GetServiceProvider(r *http.Request, serviceProviderID string) (*saml.EntityDescriptor, error) {
+	if serviceProviderID != p.sp.EntityID {
+		return nil, os.ErrNotExist
+	}
+	return p.sp, nil
+}
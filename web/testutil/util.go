@@ -21,6 +21,7 @@ import (
 var appEngine *gin.Engine
 var BaseURL = "http://localhost"
 var fixturesReloaded = false
+var clientsSignedInAt = -1
 var SysAdminClient *httpexpect.Expect
 var Inst1AdminClient *httpexpect.Expect
 var Inst1UserClient *httpexpect.Expect
@@ -60,7 +61,13 @@ func InitHTTPTests(t *testing.T) {
 	}
 	if appEngine == nil {
 		appEngine = app.InitAppEngine(true)
+	}
+	// Reloading fixtures deletes all sessions, which signs out
+	// our clients. Tests that reload fixtures call this afterward
+	// to sign the clients in again.
+	if clientsSignedInAt != db.FixtureLoadCount() {
 		initAllClients(t)
+		clientsSignedInAt = db.FixtureLoadCount()
 	}
 }
 
@@ -91,3 +91,27 @@ func CreateNewAccountAlert(req *Request, newUser *pgmodels.User, token string) (
 
 	return alert, err
 }
+
+// CreateAccountLockedAlert creates an alert telling a user that their
+// account has been locked after too many failed sign-in attempts. It
+// says where the last attempt came from, in case it wasn't the user.
+func CreateAccountLockedAlert(req *Request, lockedUser *pgmodels.User) (*pgmodels.Alert, error) {
+	templateName := "alerts/account_locked.txt"
+	alertData := map[string]interface{}{
+		"registryURL":    req.BaseURL(),
+		"lockDate":       time.Now().Format(time.RFC3339),
+		"lockedUntil":    lockedUser.LockedUntil.Format(time.RFC3339),
+		"failedAttempts": lockedUser.FailedLoginAttempts,
+		"userAgent":      req.GinContext.GetHeader("User-Agent"),
+		"ipAddress":      req.GinContext.ClientIP(),
+	}
+	recipients := []*pgmodels.User{lockedUser}
+	alert := &pgmodels.Alert{
+		InstitutionID: lockedUser.InstitutionID,
+		Type:          constants.AlertAccountLocked,
+		Subject:       "Your APTrust account has been locked",
+		CreatedAt:     time.Now().UTC(),
+		Users:         recipients,
+	}
+	return pgmodels.CreateAlert(alert, templateName, alertData)
+}
@@ -0,0 +1,97 @@
+package webui
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/forms"
+	"github.com/APTrust/registry/helpers"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyNew shows a form for creating a new API key.
+//
+// GET /api_keys/new
+func APIKeyNew(c *gin.Context) {
+	req := NewRequest(c)
+	key := &pgmodels.APIKey{
+		UserID: req.CurrentUser.ID,
+		Scope:  constants.APIKeyScopeReadOnly,
+	}
+	form := forms.NewAPIKeyForm(key, req.CurrentUser.Role)
+	req.TemplateData["form"] = form
+	c.HTML(http.StatusOK, form.Template, req.TemplateData)
+}
+
+// APIKeyCreate creates a new API key for the current user and displays
+// it. This is the only time the user will see the key, since we store
+// only its hash. Creating a key does not affect the user's other keys,
+// so users can rotate keys by creating a new one, switching their
+// clients over to it, and then revoking the old one.
+//
+// POST /api_keys/new
+func APIKeyCreate(c *gin.Context) {
+	req := NewRequest(c)
+	var expiresAt time.Time
+	var dateErr error
+	if expiresAtStr := strings.TrimSpace(c.PostForm("ExpiresAt")); expiresAtStr != "" {
+		expiresAt, dateErr = time.Parse("2006-01-02", expiresAtStr)
+	}
+	key, plaintext, err := pgmodels.NewAPIKey(
+		req.CurrentUser.ID,
+		strings.TrimSpace(c.PostForm("Name")),
+		c.PostForm("Scope"),
+		c.PostFormArray("Permissions"),
+		expiresAt,
+	)
+	if AbortIfError(c, err) {
+		return
+	}
+	for _, perm := range key.Permissions {
+		if !constants.CheckPermission(req.CurrentUser.Role, constants.Permission(perm)) {
+			AbortIfError(c, common.ErrPermissionDenied)
+			return
+		}
+	}
+	form := forms.NewAPIKeyForm(key, req.CurrentUser.Role)
+	req.TemplateData["form"] = form
+	if dateErr != nil {
+		form.HandleError(&common.ValidationError{Errors: map[string]string{"ExpiresAt": pgmodels.ErrAPIKeyExpiresAt}})
+	}
+	if dateErr != nil || !form.Save() {
+		req.TemplateData["FormError"] = form.Error
+		c.HTML(form.Status, form.Template, req.TemplateData)
+		return
+	}
+	req.TemplateData["apiKey"] = plaintext
+	req.TemplateData["key"] = key
+	c.HTML(http.StatusCreated, "api_keys/show_key.html", req.TemplateData)
+}
+
+// APIKeyRevoke revokes one of the current user's API keys. Users can
+// revoke only their own keys.
+//
+// POST /api_keys/revoke/:id
+func APIKeyRevoke(c *gin.Context) {
+	req := NewRequest(c)
+	key, err := pgmodels.APIKeyByID(req.Auth.ResourceID)
+	if AbortIfError(c, err) {
+		return
+	}
+	if key.UserID != req.CurrentUser.ID {
+		common.Context().Log.Warn().Msgf("Permission denied: User %d tried to revoke API key %d belonging to user %d", req.CurrentUser.ID, key.ID, key.UserID)
+		AbortIfError(c, common.ErrPermissionDenied)
+		return
+	}
+	err = key.Revoke()
+	if AbortIfError(c, err) {
+		return
+	}
+	helpers.SetFlashCookie(c, fmt.Sprintf("Revoked API key %s", key.Name))
+	c.Redirect(http.StatusSeeOther, "/users/my_account")
+}
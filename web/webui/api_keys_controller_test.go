@@ -0,0 +1,102 @@
+package webui_test
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/APTrust/registry/web/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+	testutil.InitHTTPTests(t)
+
+	for _, client := range testutil.AllClients {
+		client.GET("/api_keys/new").Expect().Status(http.StatusOK)
+	}
+
+	html := testutil.Inst1UserClient.POST("/api_keys/new").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1UserToken).
+		WithFormField("Name", "Work item monitor").
+		WithFormField("Scope", constants.APIKeyScopeReadOnly).
+		WithFormField("Permissions", constants.WorkItemRead).
+		WithFormField("ExpiresAt", "2099-12-31").
+		Expect().Status(http.StatusCreated).Body().Raw()
+	assert.Contains(t, html, "Work item monitor")
+	match := regexp.MustCompile(`Your new API key is <b>([0-9a-f]{32})</b>`).FindStringSubmatch(html)
+	require.Equal(t, 2, len(match))
+	plaintext := match[1]
+
+	keys, err := pgmodels.APIKeysForUser(testutil.Inst1User.ID)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(keys))
+	key := keys[0]
+	assert.Equal(t, []string{constants.WorkItemRead}, key.Permissions)
+	assert.Equal(t, 2099, key.ExpiresAt.Year())
+
+	// Key appears on My Account page, but not the key itself.
+	html = testutil.Inst1UserClient.GET("/users/my_account").
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "Work item monitor")
+	assert.Contains(t, html, key.KeyPrefix)
+	assert.NotContains(t, html, plaintext)
+
+	// Key works only for the permissions it was granted.
+	testutil.Inst1UserClient.GET("/member-api/v3/items").
+		WithHeader(constants.APIUserHeader, testutil.Inst1User.Email).
+		WithHeader(constants.APIKeyHeader, plaintext).
+		Expect().Status(http.StatusOK)
+	testutil.Inst1UserClient.GET("/member-api/v3/objects").
+		WithHeader(constants.APIUserHeader, testutil.Inst1User.Email).
+		WithHeader(constants.APIKeyHeader, plaintext).
+		Expect().Status(http.StatusForbidden)
+
+	key, err = pgmodels.APIKeyByID(key.ID)
+	require.Nil(t, err)
+	assert.False(t, key.LastUsedAt.IsZero())
+	assert.NotEmpty(t, key.LastUsedIP)
+
+	// Users can't ask for permissions their role doesn't have.
+	testutil.Inst1UserClient.POST("/api_keys/new").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1UserToken).
+		WithFormField("Name", "Sneaky").
+		WithFormField("Scope", constants.APIKeyScopeReadWrite).
+		WithFormField("Permissions", constants.UserCreate).
+		Expect().Status(http.StatusForbidden)
+
+	// Missing name re-displays the form.
+	testutil.Inst1UserClient.POST("/api_keys/new").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1UserToken).
+		WithFormField("Scope", constants.APIKeyScopeReadOnly).
+		Expect().Status(http.StatusBadRequest)
+
+	// Users can't revoke other people's keys.
+	testutil.Inst1AdminClient.POST("/api_keys/revoke/{id}", key.ID).
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1AdminToken).
+		Expect().Status(http.StatusForbidden)
+
+	testutil.Inst1UserClient.POST("/api_keys/revoke/{id}", key.ID).
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1UserToken).
+		Expect().Status(http.StatusOK)
+
+	key, err = pgmodels.APIKeyByID(key.ID)
+	require.Nil(t, err)
+	assert.True(t, key.IsRevoked())
+
+	testutil.Inst1UserClient.GET("/member-api/v3/items").
+		WithHeader(constants.APIUserHeader, testutil.Inst1User.Email).
+		WithHeader(constants.APIKeyHeader, plaintext).
+		Expect().Status(http.StatusUnauthorized)
+}
@@ -0,0 +1,30 @@
+package webui
+
+import (
+	"net/http"
+
+	"github.com/APTrust/registry/forms"
+	"github.com/APTrust/registry/helpers"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/gin-gonic/gin"
+)
+
+// AuditEventIndex shows the security audit log. Institutional admins
+// see only events concerning their own institution's users.
+//
+// GET /audit_events
+// GET /audit_events?format=csv|jsonl
+func AuditEventIndex(c *gin.Context) {
+	req := NewRequest(c)
+	template := "audit_events/index.html"
+	var events []*pgmodels.AuditEvent
+	if format := helpers.ExportFormat(c.Request); format != "" {
+		AbortIfError(c, req.ExportResourceList(&events, "created_at", "desc", format))
+		return
+	}
+	err := req.LoadResourceList(&events, "created_at", "desc", forms.NewAuditEventFilterForm)
+	if AbortIfError(c, err) {
+		return
+	}
+	c.HTML(http.StatusOK, template, req.TemplateData)
+}
@@ -0,0 +1,78 @@
+package webui_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/APTrust/registry/web/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditEventIndex(t *testing.T) {
+	testutil.InitHTTPTests(t)
+
+	// Other tests may reload fixtures, which clears the audit
+	// log, so sign in again to be sure there's an inst 2 event.
+	testutil.InitClient(t, "admin@inst2.edu")
+
+	// A failed sign-in is logged against the user's institution.
+	client := testutil.GetAnonymousClient(t)
+	client.POST("/users/sign_in").
+		WithForm(map[string]string{"email": "user@inst1.edu", "password": "wrong"}).
+		Expect().Status(http.StatusBadRequest)
+	query := pgmodels.NewQuery().
+		Where("user_email", "=", "user@inst1.edu").
+		Where("action", "=", constants.AuditSignIn).
+		Where("outcome", "=", constants.OutcomeFailure)
+	event, err := pgmodels.AuditEventGet(query)
+	require.Nil(t, err)
+	assert.Equal(t, testutil.Inst1User.InstitutionID, event.InstitutionID)
+
+	// Inst users can't read the audit log, and trying is
+	// itself logged.
+	testutil.Inst1UserClient.GET("/audit_events").
+		Expect().Status(http.StatusForbidden)
+	query = pgmodels.NewQuery().
+		Where("user_id", "=", testutil.Inst1User.ID).
+		Where("action", "=", constants.AuditAccessDenied)
+	event, err = pgmodels.AuditEventGet(query)
+	require.Nil(t, err)
+	assert.Contains(t, event.Detail, "/audit_events")
+
+	// Sys admin sees events from all institutions.
+	html := testutil.SysAdminClient.GET("/audit_events").
+		WithQuery("per_page", 100).
+		Expect().Status(http.StatusOK).Body().Raw()
+	testutil.AssertMatchesAll(t, html, []string{
+		"Security Audit Log",
+		"user@inst1.edu",
+		"admin@inst2.edu",
+		`select name="institution_id" id="institution_id"`,
+	})
+
+	// Inst admin sees only their own institution's events,
+	// even if they ask for another institution's.
+	html = testutil.Inst1AdminClient.GET("/audit_events").
+		WithQuery("per_page", 100).
+		WithQuery("institution_id", testutil.Inst2Admin.InstitutionID).
+		Expect().Status(http.StatusOK).Body().Raw()
+	testutil.AssertMatchesNone(t, html, []string{
+		"admin@inst2.edu",
+		`select name="institution_id" id="institution_id"`,
+	})
+	html = testutil.Inst1AdminClient.GET("/audit_events").
+		WithQuery("per_page", 100).
+		Expect().Status(http.StatusOK).Body().Raw()
+	testutil.AssertMatchesAll(t, html, []string{"user@inst1.edu"})
+	testutil.AssertMatchesNone(t, html, []string{"admin@inst2.edu"})
+
+	// Export honors the same restrictions.
+	csv := testutil.Inst1AdminClient.GET("/audit_events").
+		WithQuery("format", "csv").
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, csv, "user@inst1.edu")
+	assert.NotContains(t, csv, "admin@inst2.edu")
+}
@@ -2,6 +2,7 @@ package webui
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/APTrust/registry/common"
@@ -9,6 +10,10 @@ import (
 	"github.com/APTrust/registry/pgmodels"
 )
 
+// MaxBulkDeletionItems is the maximum number of objects or files a
+// user can include in a single bulk deletion request.
+const MaxBulkDeletionItems = 1000
+
 // Deletion is a helper object for web requests involving the review,
 // approval, and cancellation of deletion requests. This object simply
 // encapsulates a lot of the grunt work required by the
@@ -22,6 +27,12 @@ type Deletion struct {
 	// or cancel the request before we move forward.
 	InstAdmins []*pgmodels.User
 
+	// Skipped lists the objects or files in an approved bulk request
+	// for which we did not create deletion WorkItems, because they
+	// were deleted or picked up pending WorkItems after the request
+	// was made. Keys are identifiers. Values describe the problem.
+	Skipped map[string]string
+
 	baseURL     string
 	currentUser *pgmodels.User
 }
@@ -84,6 +95,167 @@ func NewDeletionForObject(objID int64, currentUser *pgmodels.User, baseURL strin
 	return del, err
 }
 
+// NewBulkDeletionForObjects creates a single DeletionRequest covering
+// all of the objects with the specified identifiers and returns the
+// Deletion object. This is for depositors who want to delete a whole
+// collection without filing a separate request for each object.
+//
+// Every object must belong to the current user's institution, must
+// be in a deletable state, and must have no pending WorkItems. If any
+// object fails these checks, this creates no DeletionRequest. Instead,
+// it returns a map of identifier -> problem, so the user can fix the
+// list and try again.
+func NewBulkDeletionForObjects(identifiers []string, currentUser *pgmodels.User, baseURL string) (*Deletion, map[string]string, error) {
+	identifiers = uniqueIdentifiers(identifiers)
+	if len(identifiers) == 0 || len(identifiers) > MaxBulkDeletionItems {
+		return nil, nil, common.ErrInvalidParam
+	}
+
+	problems := make(map[string]string)
+	objects := make([]*pgmodels.IntellectualObject, 0, len(identifiers))
+	for _, identifier := range identifiers {
+		obj, err := pgmodels.IntellectualObjectByIdentifier(identifier)
+		if pgmodels.IsNoRowError(err) || (err == nil && obj.InstitutionID != currentUser.InstitutionID) {
+			// Don't tell users whether other institutions'
+			// identifiers exist.
+			problems[identifier] = "Object not found."
+			continue
+		} else if err != nil {
+			return nil, nil, err
+		}
+		problem, err := objectDeletionProblem(obj)
+		if err != nil {
+			return nil, nil, err
+		}
+		if problem != "" {
+			problems[identifier] = problem
+			continue
+		}
+		objects = append(objects, obj)
+	}
+	if len(problems) > 0 {
+		return nil, problems, nil
+	}
+	del, err := newBulkDeletion(objects, nil, currentUser, baseURL)
+	return del, nil, err
+}
+
+// NewBulkDeletionForFiles is like NewBulkDeletionForObjects, for files.
+// It creates a single DeletionRequest covering all of the files with
+// the specified identifiers, or, if any file can't be deleted, returns
+// a map of identifier -> problem and creates nothing.
+func NewBulkDeletionForFiles(identifiers []string, currentUser *pgmodels.User, baseURL string) (*Deletion, map[string]string, error) {
+	identifiers = uniqueIdentifiers(identifiers)
+	if len(identifiers) == 0 || len(identifiers) > MaxBulkDeletionItems {
+		return nil, nil, common.ErrInvalidParam
+	}
+
+	problems := make(map[string]string)
+	files := make([]*pgmodels.GenericFile, 0, len(identifiers))
+	for _, identifier := range identifiers {
+		gf, err := pgmodels.GenericFileByIdentifier(identifier)
+		if pgmodels.IsNoRowError(err) || (err == nil && gf.InstitutionID != currentUser.InstitutionID) {
+			problems[identifier] = "File not found."
+			continue
+		} else if err != nil {
+			return nil, nil, err
+		}
+		problem, err := fileDeletionProblem(gf)
+		if err != nil {
+			return nil, nil, err
+		}
+		if problem != "" {
+			problems[identifier] = problem
+			continue
+		}
+		files = append(files, gf)
+	}
+	if len(problems) > 0 {
+		return nil, problems, nil
+	}
+	del, err := newBulkDeletion(nil, files, currentUser, baseURL)
+	return del, nil, err
+}
+
+// newBulkDeletion saves a new DeletionRequest covering the specified
+// objects and files, which the caller has already checked, and returns
+// the Deletion object.
+func newBulkDeletion(objects []*pgmodels.IntellectualObject, files []*pgmodels.GenericFile, currentUser *pgmodels.User, baseURL string) (*Deletion, error) {
+	del := &Deletion{
+		baseURL:     baseURL,
+		currentUser: currentUser,
+	}
+	deletionRequest, err := pgmodels.NewDeletionRequest()
+	if err != nil {
+		return nil, err
+	}
+	deletionRequest.InstitutionID = currentUser.InstitutionID
+	deletionRequest.RequestedByID = currentUser.ID
+	deletionRequest.RequestedAt = time.Now().UTC()
+	for _, obj := range objects {
+		deletionRequest.AddObject(obj)
+	}
+	for _, gf := range files {
+		deletionRequest.AddFile(gf)
+	}
+	err = deletionRequest.Save()
+	if err != nil {
+		return nil, err
+	}
+	del.DeletionRequest = deletionRequest
+	err = del.loadInstAdmins()
+	return del, err
+}
+
+// objectDeletionProblem returns a description of why obj can't be
+// deleted, or an empty string if it can. Objects that have already
+// been deleted, or that have pending WorkItems, can't be deleted.
+// The error is for failed database lookups.
+func objectDeletionProblem(obj *pgmodels.IntellectualObject) (string, error) {
+	if err := obj.AssertCanRequestDeletion(); err != nil {
+		return err.Error(), nil
+	}
+	pendingWorkItems, err := pgmodels.WorkItemsPendingForObject(obj.InstitutionID, obj.BagName)
+	if err != nil {
+		return "", err
+	}
+	if len(pendingWorkItems) > 0 {
+		return common.ErrPendingWorkItems.Error(), nil
+	}
+	return "", nil
+}
+
+// fileDeletionProblem is like objectDeletionProblem, for files.
+func fileDeletionProblem(gf *pgmodels.GenericFile) (string, error) {
+	if gf.State != constants.StateActive {
+		return "File has already been deleted.", nil
+	}
+	pendingWorkItems, err := pgmodels.WorkItemsPendingForFile(gf.ID)
+	if err != nil {
+		return "", err
+	}
+	if len(pendingWorkItems) > 0 {
+		return common.ErrPendingWorkItems.Error(), nil
+	}
+	return "", nil
+}
+
+// uniqueIdentifiers trims whitespace from identifiers and removes
+// blanks and duplicates, preserving the original order.
+func uniqueIdentifiers(identifiers []string) []string {
+	seen := make(map[string]bool)
+	unique := make([]string, 0, len(identifiers))
+	for _, identifier := range identifiers {
+		identifier = strings.TrimSpace(identifier)
+		if identifier == "" || seen[identifier] {
+			continue
+		}
+		seen[identifier] = true
+		unique = append(unique, identifier)
+	}
+	return unique
+}
+
 // NewDeletionForReview pulls up information about an existing deletion
 // request that an institutional admin will review before deciding whether
 // to approve or cancel the request.
@@ -243,6 +415,143 @@ func (del *Deletion) CreateAndQueueWorkItem() (*pgmodels.WorkItem, error) {
 	return workItem, err
 }
 
+// CreateAndQueueWorkItems creates and queues the deletion WorkItems
+// for this request. Single-item requests get one WorkItem, as in
+// CreateAndQueueWorkItem. Bulk requests get one WorkItem for each
+// object or, if the request includes no objects, for each file.
+// If queueing fails partway through, the bulk WorkItems we couldn't
+// queue are cancelled, so they don't sit in the list as pending.
+// We call this only if the admin approves the DeletionRequest.
+func (del *Deletion) CreateAndQueueWorkItems() ([]*pgmodels.WorkItem, error) {
+	if !del.DeletionRequest.IsBulk() {
+		workItem, err := del.CreateAndQueueWorkItem()
+		if err != nil {
+			return nil, err
+		}
+		return []*pgmodels.WorkItem{workItem}, nil
+	}
+	workItems, err := del.CreateBulkWorkItems()
+	if err != nil {
+		return nil, err
+	}
+	ctx := common.Context()
+	for i, workItem := range workItems {
+		ctx.Log.Info().Msgf("Queueing deletion WorkItem %d", workItem.ID)
+		err = queueWorkItem(workItem)
+		if err != nil {
+			cancelUnqueuedItems(workItems[i:], "Deletion", err)
+			return workItems, err
+		}
+	}
+	return workItems, nil
+}
+
+// NothingToDelete returns true if every object or file in this bulk
+// request has been deleted or picked up pending WorkItems since the
+// request was made, so approving it would delete nothing. It lists
+// the problems in del.Skipped. Single-item requests always return
+// false, because we check them when creating the WorkItem.
+func (del *Deletion) NothingToDelete() (bool, error) {
+	if !del.DeletionRequest.IsBulk() {
+		return false, nil
+	}
+	objects, files, err := del.checkBulkItems()
+	if err != nil {
+		return false, err
+	}
+	return len(objects) == 0 && len(files) == 0, nil
+}
+
+// CreateBulkWorkItems creates one deletion WorkItem for each object
+// in a bulk deletion request, or for each file if the request includes
+// no objects, and links them to the DeletionRequest. Items that can
+// no longer be deleted get no WorkItem. See checkBulkItems.
+func (del *Deletion) CreateBulkWorkItems() ([]*pgmodels.WorkItem, error) {
+	request := del.DeletionRequest
+	objects, files, err := del.checkBulkItems()
+	if err != nil {
+		return nil, err
+	}
+	workItems := make([]*pgmodels.WorkItem, 0)
+	for _, obj := range objects {
+		workItem, err := pgmodels.NewDeletionItem(obj, nil, request.RequestedBy, request.ConfirmedBy)
+		if err != nil {
+			return nil, err
+		}
+		workItems = append(workItems, workItem)
+	}
+	for _, gf := range files {
+		obj, err := pgmodels.IntellectualObjectByID(gf.IntellectualObjectID)
+		if err != nil {
+			return nil, err
+		}
+		workItem, err := pgmodels.NewDeletionItem(obj, gf, request.RequestedBy, request.ConfirmedBy)
+		if err != nil {
+			return nil, err
+		}
+		workItems = append(workItems, workItem)
+	}
+	for _, workItem := range workItems {
+		request.AddWorkItem(workItem)
+	}
+	err = request.Save()
+	return workItems, err
+}
+
+// checkBulkItems returns the objects in a bulk deletion request that
+// can still be deleted or, if the request includes no objects, the
+// files that can still be deleted.
+//
+// Objects and files can change between request and approval, so we
+// reload and check each one again here. Those that have been deleted,
+// or that now have pending WorkItems, are listed in del.Skipped
+// instead.
+func (del *Deletion) checkBulkItems() ([]*pgmodels.IntellectualObject, []*pgmodels.GenericFile, error) {
+	request := del.DeletionRequest
+	objects := make([]*pgmodels.IntellectualObject, 0)
+	files := make([]*pgmodels.GenericFile, 0)
+	del.Skipped = make(map[string]string)
+	if len(request.IntellectualObjects) > 0 {
+		for _, obj := range request.IntellectualObjects {
+			current, err := pgmodels.IntellectualObjectByID(obj.ID)
+			if err != nil {
+				return nil, nil, err
+			}
+			problem, err := objectDeletionProblem(current)
+			if err != nil {
+				return nil, nil, err
+			}
+			if problem != "" {
+				del.skip(current.Identifier, problem)
+				continue
+			}
+			objects = append(objects, current)
+		}
+	} else {
+		for _, gf := range request.GenericFiles {
+			current, err := pgmodels.GenericFileByID(gf.ID)
+			if err != nil {
+				return nil, nil, err
+			}
+			problem, err := fileDeletionProblem(current)
+			if err != nil {
+				return nil, nil, err
+			}
+			if problem != "" {
+				del.skip(current.Identifier, problem)
+				continue
+			}
+			files = append(files, current)
+		}
+	}
+	return objects, files, nil
+}
+
+func (del *Deletion) skip(identifier, problem string) {
+	common.Context().Log.Warn().Msgf("Skipping deletion of %s in deletion request %d: %s", identifier, del.DeletionRequest.ID, problem)
+	del.Skipped[identifier] = problem
+}
+
 // CreateRequestAlert creates an alert saying that a user has requested
 // a deletion. This alert goes via email to all admins at the institution
 // that owns the file or object to be deleted. This method is supported
@@ -271,9 +580,15 @@ func (del *Deletion) CreateRequestAlert() (*pgmodels.Alert, error) {
 func (del *Deletion) CreateApprovalAlert() (*pgmodels.Alert, error) {
 	templateName := "alerts/deletion_confirmed.txt"
 	alertType := constants.AlertDeletionConfirmed
-	workItemURL, err := del.WorkItemURL()
-	if err != nil {
-		return nil, err
+	// Bulk deletions have one WorkItem per item, so there's
+	// no single WorkItem to link to.
+	workItemURL := ""
+	if !del.DeletionRequest.IsBulk() {
+		var err error
+		workItemURL, err = del.WorkItemURL()
+		if err != nil {
+			return nil, err
+		}
 	}
 	alertData := map[string]interface{}{
 		"deletionRequest":     del.DeletionRequest,
@@ -284,7 +599,8 @@ func (del *Deletion) CreateApprovalAlert() (*pgmodels.Alert, error) {
 }
 
 // CreateCancellationAlert creates an alert saying that an admin has
-// rejected a deletion request. This alert goes via email to all admins
+// rejected a deletion request, or that we cancelled a bulk request
+// because none of its items could still be deleted. This alert goes via email to all admins
 // at the institution that owns the file or object to be deleted.
 func (del *Deletion) CreateCancellationAlert() (*pgmodels.Alert, error) {
 	templateName := "alerts/deletion_cancelled.txt"
@@ -292,6 +608,7 @@ func (del *Deletion) CreateCancellationAlert() (*pgmodels.Alert, error) {
 	alertData := map[string]interface{}{
 		"deletionRequest":     del.DeletionRequest,
 		"deletionReadOnlyURL": del.ReadOnlyURL(),
+		"skipped":             del.Skipped,
 	}
 	return del.createDeletionAlert(templateName, alertType, alertData)
 }
@@ -77,7 +77,17 @@ func DeletionRequestReview(c *gin.Context) {
 	req.TemplateData["deletionRequest"] = del.DeletionRequest
 	req.TemplateData["token"] = c.Query("token")
 
-	if len(del.DeletionRequest.IntellectualObjects) > 0 {
+	if del.DeletionRequest.IsBulk() {
+		itemCount := len(del.DeletionRequest.IntellectualObjects) + len(del.DeletionRequest.GenericFiles)
+		firstIdentifier := ""
+		if len(del.DeletionRequest.IntellectualObjects) > 0 {
+			firstIdentifier = del.DeletionRequest.IntellectualObjects[0].Identifier
+		} else {
+			firstIdentifier = del.DeletionRequest.GenericFiles[0].Identifier
+		}
+		req.TemplateData["itemType"] = "bulk"
+		req.TemplateData["itemIdentifier"] = fmt.Sprintf("%s and %d other items", firstIdentifier, itemCount-1)
+	} else if len(del.DeletionRequest.IntellectualObjects) > 0 {
 		req.TemplateData["itemType"] = "object"
 		req.TemplateData["itemIdentifier"] = del.DeletionRequest.IntellectualObjects[0].Identifier
 		req.TemplateData["object"] = del.DeletionRequest.IntellectualObjects[0]
@@ -111,12 +121,23 @@ func DeletionRequestApprove(c *gin.Context) {
 	if AbortIfError(c, err) {
 		return
 	}
+	// If every item in a bulk request was deleted or picked up pending
+	// WorkItems since the request was made, there's nothing left to
+	// approve, so we cancel the request instead.
+	nothingToDelete, err := del.NothingToDelete()
+	if AbortIfError(c, err) {
+		return
+	}
+	if nothingToDelete {
+		cancelDeletionRequest(c, req, del)
+		return
+	}
 	del.DeletionRequest.Confirm(req.CurrentUser)
 	err = del.DeletionRequest.Save()
 	if AbortIfError(c, err) {
 		return
 	}
-	_, err = del.CreateAndQueueWorkItem()
+	_, err = del.CreateAndQueueWorkItems()
 	if AbortIfError(c, err) {
 		return
 	}
@@ -125,8 +146,11 @@ func DeletionRequestApprove(c *gin.Context) {
 		return
 	}
 	req.TemplateData["deletionRequest"] = del.DeletionRequest
+	req.TemplateData["skipped"] = del.Skipped
 	template := "deletions/approved_file.html"
-	if len(del.DeletionRequest.IntellectualObjects) > 0 {
+	if del.DeletionRequest.IsBulk() {
+		template = "deletions/approved_bulk.html"
+	} else if len(del.DeletionRequest.IntellectualObjects) > 0 {
 		template = "deletions/approved_object.html"
 	}
 	c.HTML(http.StatusOK, template, req.TemplateData)
@@ -143,8 +167,14 @@ func DeletionRequestCancel(c *gin.Context) {
 	if AbortIfError(c, err) {
 		return
 	}
+	cancelDeletionRequest(c, req, del)
+}
+
+// cancelDeletionRequest cancels the request, alerts the institution's
+// admins, and shows the cancellation page.
+func cancelDeletionRequest(c *gin.Context, req *Request, del *Deletion) {
 	del.DeletionRequest.Cancel(req.CurrentUser)
-	err = del.DeletionRequest.Save()
+	err := del.DeletionRequest.Save()
 	if AbortIfError(c, err) {
 		return
 	}
@@ -153,8 +183,11 @@ func DeletionRequestCancel(c *gin.Context) {
 		return
 	}
 	req.TemplateData["deletionRequest"] = del.DeletionRequest
+	req.TemplateData["skipped"] = del.Skipped
 	template := "deletions/cancelled_file.html"
-	if len(del.DeletionRequest.IntellectualObjects) > 0 {
+	if del.DeletionRequest.IsBulk() {
+		template = "deletions/cancelled_bulk.html"
+	} else if len(del.DeletionRequest.IntellectualObjects) > 0 {
 		template = "deletions/cancelled_object.html"
 	}
 	c.HTML(http.StatusOK, template, req.TemplateData)
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/APTrust/registry/common"
 	"github.com/APTrust/registry/constants"
 	"github.com/APTrust/registry/db"
 	"github.com/APTrust/registry/pgmodels"
@@ -190,3 +191,45 @@ func TestDeletionRequestCancel(t *testing.T) {
 	require.NotNil(t, alert)
 	assert.Equal(t, constants.AlertDeletionCancelled, alert.Type)
 }
+
+func TestDeletionRequestApproveNothingToDelete(t *testing.T) {
+	testutil.InitHTTPTests(t)
+	defer db.ForceFixtureReload()
+
+	request, err := pgmodels.NewDeletionRequest()
+	require.Nil(t, err)
+	request.InstitutionID = testutil.Inst1Admin.InstitutionID
+	request.RequestedByID = testutil.Inst1Admin.ID
+	request.RequestedAt = time.Now().UTC()
+	for _, id := range []int64{1, 2} {
+		gf, err := pgmodels.GenericFileByID(id)
+		require.Nil(t, err)
+		request.AddFile(gf)
+	}
+	require.Nil(t, request.Save())
+
+	// Both files were deleted after the request was made, so
+	// approving the request should cancel it instead.
+	_, err = common.Context().DB.Exec("update generic_files set state = ? where id in (1, 2)", constants.StateDeleted)
+	require.Nil(t, err)
+
+	html := testutil.Inst1AdminClient.POST("/deletions/approve/{id}", request.ID).
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField("token", request.ConfirmationToken).
+		WithFormField("csrf_token", testutil.Inst1AdminToken).
+		Expect().Status(http.StatusOK).Body().Raw()
+	testutil.AssertMatchesAll(t, html, []string{"Deletion Cancelled", "institution1.edu/photos/picture1", "institution1.edu/photos/picture2"})
+
+	req, err := pgmodels.DeletionRequestByID(request.ID)
+	require.Nil(t, err)
+	assert.Equal(t, testutil.Inst1Admin.ID, req.CancelledByID)
+	assert.Equal(t, int64(0), req.ConfirmedByID)
+	assert.True(t, req.ConfirmedAt.IsZero())
+	assert.Empty(t, req.WorkItems)
+
+	alerts, err := pgmodels.AlertSelect(pgmodels.NewQuery().Where("deletion_request_id", "=", req.ID))
+	require.Nil(t, err)
+	require.Equal(t, 1, len(alerts))
+	assert.Equal(t, constants.AlertDeletionCancelled, alerts[0].Type)
+	assert.Contains(t, alerts[0].Content, "none of its items could still be deleted")
+}
@@ -189,3 +189,171 @@ func testCreateCancellationAlert(t *testing.T, del *webui.Deletion) {
 	}
 	assert.Contains(t, alert.Content, del.ReadOnlyURL())
 }
+
+func TestNewBulkDeletionForObjects(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+	admin, err := pgmodels.UserByEmail("admin@inst1.edu")
+	require.Nil(t, err)
+
+	identifiers := make([]string, 0)
+	for _, id := range []int64{1, 2, 3, 6, 14} {
+		obj, err := pgmodels.IntellectualObjectByID(id)
+		require.Nil(t, err)
+		identifiers = append(identifiers, obj.Identifier)
+	}
+
+	// Empty lists and lists that are too long are invalid.
+	_, _, err = webui.NewBulkDeletionForObjects([]string{" ", ""}, admin, exampleURL)
+	assert.Equal(t, common.ErrInvalidParam, err)
+	tooMany := make([]string, webui.MaxBulkDeletionItems+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("institution1.edu/bag-%d", i)
+	}
+	_, _, err = webui.NewBulkDeletionForObjects(tooMany, admin, exampleURL)
+	assert.Equal(t, common.ErrInvalidParam, err)
+
+	// Object 3 has a pending WorkItem, object 6 belongs to another
+	// institution, and object 14 has already been deleted. We should
+	// get a problem for each of these, plus one for the non-existent
+	// identifier, and no deletion request.
+	withBadItems := append(identifiers, "institution1.edu/does-not-exist")
+	del, problems, err := webui.NewBulkDeletionForObjects(withBadItems, admin, exampleURL)
+	require.Nil(t, err)
+	assert.Nil(t, del)
+	assert.Equal(t, 4, len(problems))
+	assert.Equal(t, common.ErrPendingWorkItems.Error(), problems[identifiers[2]])
+	assert.Equal(t, "Object not found.", problems[identifiers[3]])
+	assert.NotEmpty(t, problems[identifiers[4]])
+	assert.Equal(t, "Object not found.", problems["institution1.edu/does-not-exist"])
+
+	// Objects 1 and 2 are fine. Duplicates should be ignored.
+	goodItems := []string{identifiers[0], identifiers[1], " " + identifiers[0] + " "}
+	del, problems, err = webui.NewBulkDeletionForObjects(goodItems, admin, exampleURL)
+	require.Nil(t, err)
+	assert.Empty(t, problems)
+	require.NotNil(t, del)
+	require.NotNil(t, del.DeletionRequest)
+	assert.True(t, del.DeletionRequest.ID > 0)
+	assert.True(t, del.DeletionRequest.IsBulk())
+	assert.Equal(t, 2, len(del.DeletionRequest.IntellectualObjects))
+	assert.Equal(t, admin.ID, del.DeletionRequest.RequestedByID)
+	assert.NotEmpty(t, del.InstAdmins)
+
+	testCreateRequestAlert(t, del)
+
+	// On approval, we should get one WorkItem per object,
+	// and the approval alert should not link to any single
+	// WorkItem. Object 2 was deleted after the request was
+	// made, so we should skip it.
+	_, err = common.Context().DB.Exec("update intellectual_objects set state = ? where id = 2", constants.StateDeleted)
+	require.Nil(t, err)
+	del.DeletionRequest.Confirm(admin)
+	require.Nil(t, del.DeletionRequest.Save())
+	workItems, err := del.CreateAndQueueWorkItems()
+	require.Nil(t, err)
+	require.Equal(t, 1, len(workItems))
+	assert.True(t, workItems[0].ID > 0)
+	assert.Equal(t, constants.ActionDelete, workItems[0].Action)
+	assert.EqualValues(t, 1, workItems[0].IntellectualObjectID)
+	assert.Equal(t, int64(0), workItems[0].GenericFileID)
+	require.Equal(t, 1, len(del.Skipped))
+	assert.NotEmpty(t, del.Skipped[identifiers[1]])
+	reloaded, err := pgmodels.DeletionRequestByID(del.DeletionRequest.ID)
+	require.Nil(t, err)
+	assert.Equal(t, 1, len(reloaded.WorkItems))
+
+	alert, err := del.CreateApprovalAlert()
+	require.Nil(t, err)
+	require.NotNil(t, alert)
+	assert.Contains(t, alert.Content, del.ReadOnlyURL())
+	assert.NotContains(t, alert.Content, "/work_items/show/")
+}
+
+func TestNewBulkDeletionForFiles(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+	admin, err := pgmodels.UserByEmail("admin@inst1.edu")
+	require.Nil(t, err)
+
+	identifiers := make([]string, 0)
+	for _, id := range []int64{1, 2, 7, 10, 11} {
+		gf, err := pgmodels.GenericFileByID(id)
+		require.Nil(t, err)
+		identifiers = append(identifiers, gf.Identifier)
+	}
+
+	_, _, err = webui.NewBulkDeletionForFiles([]string{" ", ""}, admin, exampleURL)
+	assert.Equal(t, common.ErrInvalidParam, err)
+
+	// File 7 belongs to an object with pending WorkItems, file 10
+	// has already been deleted, and file 11 belongs to another
+	// institution.
+	withBadItems := append(identifiers, "institution1.edu/photos/does-not-exist")
+	del, problems, err := webui.NewBulkDeletionForFiles(withBadItems, admin, exampleURL)
+	require.Nil(t, err)
+	assert.Nil(t, del)
+	assert.Equal(t, 4, len(problems))
+	assert.Equal(t, common.ErrPendingWorkItems.Error(), problems[identifiers[2]])
+	assert.Equal(t, "File has already been deleted.", problems[identifiers[3]])
+	assert.Equal(t, "File not found.", problems[identifiers[4]])
+	assert.Equal(t, "File not found.", problems["institution1.edu/photos/does-not-exist"])
+
+	// Files 1 and 2 are fine.
+	del, problems, err = webui.NewBulkDeletionForFiles(identifiers[0:2], admin, exampleURL)
+	require.Nil(t, err)
+	assert.Empty(t, problems)
+	require.NotNil(t, del)
+	assert.True(t, del.DeletionRequest.ID > 0)
+	assert.True(t, del.DeletionRequest.IsBulk())
+	assert.Empty(t, del.DeletionRequest.IntellectualObjects)
+	assert.Equal(t, 2, len(del.DeletionRequest.GenericFiles))
+	assert.NotEmpty(t, del.InstAdmins)
+
+	testCreateRequestAlert(t, del)
+
+	// If queueing fails on approval, the WorkItems we created
+	// should be cancelled rather than left pending.
+	nsqClient := common.Context().NSQClient
+	nsqURL := nsqClient.URL
+	nsqClient.URL = "http://127.0.0.1:1"
+	defer func() { nsqClient.URL = nsqURL }()
+
+	del.DeletionRequest.Confirm(admin)
+	require.Nil(t, del.DeletionRequest.Save())
+	workItems, err := del.CreateAndQueueWorkItems()
+	require.NotNil(t, err)
+	require.Equal(t, 2, len(workItems))
+	for _, workItem := range workItems {
+		reloaded, err := pgmodels.WorkItemByID(workItem.ID)
+		require.Nil(t, err)
+		assert.Equal(t, constants.ActionDelete, reloaded.Action)
+		assert.Equal(t, constants.StatusCancelled, reloaded.Status)
+		assert.False(t, reloaded.Retry)
+		assert.Contains(t, reloaded.Note, "Deletion cancelled")
+	}
+}
+
+func TestBulkDeletionNothingToDelete(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+	admin, err := pgmodels.UserByEmail("admin@inst1.edu")
+	require.Nil(t, err)
+
+	identifiers := []string{"institution1.edu/photos/picture1", "institution1.edu/photos/picture2"}
+	del, problems, err := webui.NewBulkDeletionForFiles(identifiers, admin, exampleURL)
+	require.Nil(t, err)
+	require.Empty(t, problems)
+
+	nothingToDelete, err := del.NothingToDelete()
+	require.Nil(t, err)
+	assert.False(t, nothingToDelete)
+	assert.Empty(t, del.Skipped)
+
+	_, err = common.Context().DB.Exec("update generic_files set state = ? where id in (1, 2)", constants.StateDeleted)
+	require.Nil(t, err)
+	nothingToDelete, err = del.NothingToDelete()
+	require.Nil(t, err)
+	assert.True(t, nothingToDelete)
+	assert.Equal(t, 2, len(del.Skipped))
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/scheduler"
 	"github.com/gin-gonic/gin"
 )
 
@@ -70,9 +71,9 @@ func StatusCodeForError(err error) (status int) {
 		status = http.StatusForbidden
 	case common.ErrPermissionDenied, common.ErrMustCompleteReset:
 		status = http.StatusForbidden
-	case common.ErrParentRecordNotFound:
+	case common.ErrParentRecordNotFound, scheduler.ErrJobNotFound:
 		status = http.StatusNotFound
-	case common.ErrWrongDataType:
+	case common.ErrWrongDataType, common.ErrGlacierFixity:
 		status = http.StatusBadRequest
 	case common.ErrDecodeCookie:
 		status = http.StatusBadRequest
@@ -80,7 +81,7 @@ func StatusCodeForError(err error) (status int) {
 		status = http.StatusMethodNotAllowed
 	case common.ErrInternal:
 		status = http.StatusInternalServerError
-	case common.ErrPendingWorkItems:
+	case common.ErrPendingWorkItems, common.ErrFixityCheckPending:
 		status = http.StatusConflict
 	default:
 		status = http.StatusInternalServerError
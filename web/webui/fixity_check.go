@@ -0,0 +1,146 @@
+package webui
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/pgmodels"
+)
+
+// InitFileFixityCheck creates a fixity check WorkItem for a single file
+// and queues the file for the fixity checker. This returns
+// common.ErrFixityCheckPending if the file or its object already has a
+// fixity check in the queue, and common.ErrGlacierFixity if the file is
+// stored only in Glacier.
+func InitFileFixityCheck(gf *pgmodels.GenericFile, user *pgmodels.User) (*pgmodels.WorkItem, error) {
+	if gf.State != constants.StateActive {
+		return nil, common.ErrInvalidParam
+	}
+	if gf.IsGlacierOnly() {
+		return nil, common.ErrGlacierFixity
+	}
+	pending, err := pgmodels.FixityCheckPending(gf)
+	if err != nil {
+		return nil, err
+	}
+	if pending {
+		return nil, common.ErrFixityCheckPending
+	}
+	obj, err := pgmodels.IntellectualObjectByID(gf.IntellectualObjectID)
+	if err != nil {
+		return nil, err
+	}
+	workItem, err := pgmodels.NewFixityCheckItem(obj, gf, user)
+	if err != nil {
+		return nil, err
+	}
+	err = queueFixityCheck(workItem, []int64{gf.ID})
+	return workItem, err
+}
+
+// InitObjectFixityCheck creates a fixity check WorkItem for an object
+// and queues all of its active files for the fixity checker. Files that
+// already have a fixity check in the queue are not queued again. This
+// returns common.ErrFixityCheckPending if the whole object is already
+// queued, or if every one of its files is.
+func InitObjectFixityCheck(obj *pgmodels.IntellectualObject, user *pgmodels.User) (*pgmodels.WorkItem, error) {
+	if obj.State != constants.StateActive {
+		return nil, common.ErrInvalidParam
+	}
+	if obj.IsGlacierOnly() {
+		return nil, common.ErrGlacierFixity
+	}
+	pending, err := pgmodels.FixityCheckPendingForObject(obj.ID)
+	if err != nil {
+		return nil, err
+	}
+	if pending {
+		return nil, common.ErrFixityCheckPending
+	}
+	fileIDs, err := pgmodels.FixityCheckableFileIDs(obj.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(fileIDs) == 0 {
+		return nil, common.ErrFixityCheckPending
+	}
+	workItem, err := pgmodels.NewFixityCheckItem(obj, nil, user)
+	if err != nil {
+		return nil, err
+	}
+	err = queueFixityCheck(workItem, fileIDs)
+	return workItem, err
+}
+
+// InitBulkFixityCheck queues fixity checks for a list of files, creating
+// one WorkItem per file. Files that are deleted, stored only in Glacier,
+// or already queued are skipped. This returns the WorkItems it created
+// and a map of file identifier -> reason for the files it skipped.
+func InitBulkFixityCheck(files []*pgmodels.GenericFile, user *pgmodels.User) ([]*pgmodels.WorkItem, map[string]string, error) {
+	if len(files) > pgmodels.MaxBulkFixityFiles {
+		return nil, nil, common.ErrTooManyItems
+	}
+	workItems := make([]*pgmodels.WorkItem, 0, len(files))
+	skipped := make(map[string]string)
+	for _, gf := range files {
+		workItem, err := InitFileFixityCheck(gf, user)
+		switch err {
+		case nil:
+			workItems = append(workItems, workItem)
+		case common.ErrInvalidParam:
+			skipped[gf.Identifier] = "File has been deleted."
+		case common.ErrGlacierFixity, common.ErrFixityCheckPending:
+			skipped[gf.Identifier] = err.Error()
+		default:
+			return workItems, skipped, err
+		}
+	}
+	return workItems, skipped, nil
+}
+
+// queueFixityCheck records on the WorkItem when its files were queued,
+// then sends their IDs into the fixity_check topic. The fixity checker
+// reads GenericFile IDs from this topic, not WorkItem IDs.
+//
+// We save QueuedAt before queueing the first file, because the fixity
+// checker may finish a file before we're done queueing the rest, and
+// CompleteFixityCheckItems ignores checks from before QueuedAt. For the
+// same reason, we don't save the WorkItem again after queueing, since
+// that could overwrite the status of a check that already completed.
+//
+// If we can't queue all of the files, we cancel the WorkItem, so it
+// doesn't sit in the pending list forever, blocking new fixity
+// requests for the same file or object.
+func queueFixityCheck(workItem *pgmodels.WorkItem, fileIDs []int64) error {
+	ctx := common.Context()
+	workItem.QueuedAt = time.Now().UTC()
+	workItem.Note = fmt.Sprintf("Queued %d file(s) for fixity check", len(fileIDs))
+	err := workItem.Save()
+	if err != nil {
+		return err
+	}
+	for i, fileID := range fileIDs {
+		err := ctx.NSQClient.EnqueueString(constants.TopicFixity, strconv.FormatInt(fileID, 10))
+		if err != nil {
+			ctx.Log.Error().Msgf("Error queueing file %d for fixity check (WorkItem %d): %v", fileID, workItem.ID, err)
+			cancelFixityCheck(workItem, i, len(fileIDs), err)
+			return err
+		}
+	}
+	return nil
+}
+
+// cancelFixityCheck marks a fixity WorkItem as cancelled when we could
+// queue only some of its files, noting how many and why.
+func cancelFixityCheck(workItem *pgmodels.WorkItem, queued, total int, queueErr error) {
+	workItem.Status = constants.StatusCancelled
+	workItem.Note = fmt.Sprintf("Fixity check cancelled because only %d of %d file(s) could be queued: %v", queued, total, queueErr)
+	workItem.Retry = false
+	err := workItem.Save()
+	if err != nil {
+		common.Context().Log.Error().Msgf("Error cancelling unqueued fixity WorkItem %d: %v", workItem.ID, err)
+	}
+}
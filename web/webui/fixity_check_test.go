@@ -0,0 +1,47 @@
+package webui_test
+
+import (
+	"testing"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/APTrust/registry/web/webui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitFileFixityCheckQueueFailure(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+	admin, err := pgmodels.UserByEmail("admin@inst1.edu")
+	require.Nil(t, err)
+	gf, err := pgmodels.GenericFileByID(2)
+	require.Nil(t, err)
+
+	// Point the NSQ client at a port where nothing is listening,
+	// so queueing fails.
+	nsqClient := common.Context().NSQClient
+	nsqURL := nsqClient.URL
+	nsqClient.URL = "http://127.0.0.1:1"
+	defer func() { nsqClient.URL = nsqURL }()
+
+	_, err = webui.InitFileFixityCheck(gf, admin)
+	require.NotNil(t, err)
+
+	query := pgmodels.NewQuery().
+		Where("action", "=", constants.ActionFixityCheck).
+		Where("generic_file_id", "=", gf.ID)
+	items, err := pgmodels.WorkItemSelect(query)
+	require.Nil(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, constants.StatusCancelled, items[0].Status)
+	assert.False(t, items[0].Retry)
+	assert.Contains(t, items[0].Note, "0 of 1 file(s) could be queued")
+
+	// The cancelled item doesn't block a new request.
+	pending, err := pgmodels.FixityCheckPending(gf)
+	require.Nil(t, err)
+	assert.False(t, pending)
+}
@@ -0,0 +1,99 @@
+package webui
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/APTrust/registry/forms"
+	"github.com/APTrust/registry/helpers"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/gin-gonic/gin"
+)
+
+// FormatRiskIndex lists the formats in the format registry.
+//
+// GET /format_risks
+func FormatRiskIndex(c *gin.Context) {
+	req := NewRequest(c)
+	var formatRisks []*pgmodels.FormatRisk
+	err := req.LoadResourceList(&formatRisks, "mime_type", "asc", forms.NewFormatRiskFilterForm)
+	if AbortIfError(c, err) {
+		return
+	}
+	c.HTML(http.StatusOK, "format_risks/index.html", req.TemplateData)
+}
+
+// FormatRiskNew shows a form for adding a format to the registry.
+//
+// GET /format_risks/new
+func FormatRiskNew(c *gin.Context) {
+	req := NewRequest(c)
+	form := forms.NewFormatRiskForm(&pgmodels.FormatRisk{})
+	req.TemplateData["form"] = form
+	c.HTML(http.StatusOK, form.Template, req.TemplateData)
+}
+
+// FormatRiskCreate adds a format to the registry.
+//
+// POST /format_risks/new
+func FormatRiskCreate(c *gin.Context) {
+	saveFormatRiskForm(c, NewRequest(c), &pgmodels.FormatRisk{})
+}
+
+// FormatRiskEdit shows a form for changing a format's risk level.
+//
+// GET /format_risks/edit/:id
+func FormatRiskEdit(c *gin.Context) {
+	req := NewRequest(c)
+	formatRisk, err := pgmodels.FormatRiskByID(req.Auth.ResourceID)
+	if AbortIfError(c, err) {
+		return
+	}
+	form := forms.NewFormatRiskForm(formatRisk)
+	req.TemplateData["form"] = form
+	c.HTML(http.StatusOK, form.Template, req.TemplateData)
+}
+
+// FormatRiskUpdate saves changes to a format in the registry.
+//
+// PUT or POST /format_risks/edit/:id
+func FormatRiskUpdate(c *gin.Context) {
+	req := NewRequest(c)
+	formatRisk, err := pgmodels.FormatRiskByID(req.Auth.ResourceID)
+	if AbortIfError(c, err) {
+		return
+	}
+	saveFormatRiskForm(c, req, formatRisk)
+}
+
+// FormatRiskDelete removes a format from the registry.
+//
+// DELETE or POST /format_risks/delete/:id
+func FormatRiskDelete(c *gin.Context) {
+	req := NewRequest(c)
+	formatRisk, err := pgmodels.FormatRiskByID(req.Auth.ResourceID)
+	if AbortIfError(c, err) {
+		return
+	}
+	err = formatRisk.Delete()
+	if AbortIfError(c, err) {
+		return
+	}
+	helpers.SetFlashCookie(c, fmt.Sprintf("Removed %s from the format registry.", formatRisk.MimeType))
+	c.Redirect(http.StatusSeeOther, "/format_risks")
+}
+
+func saveFormatRiskForm(c *gin.Context, req *Request, formatRisk *pgmodels.FormatRisk) {
+	formatRisk.MimeType = c.PostForm("MimeType")
+	formatRisk.RiskLevel = c.PostForm("RiskLevel")
+	formatRisk.Notes = c.PostForm("Notes")
+	form := forms.NewFormatRiskForm(formatRisk)
+	req.TemplateData["form"] = form
+	if form.Save() {
+		helpers.SetFlashCookie(c, fmt.Sprintf("Saved %s as %s.", formatRisk.MimeType, formatRisk.RiskLevel))
+		c.Redirect(form.Status, form.PostSaveURL())
+	} else {
+		req.TemplateData["FormError"] = form.Error
+		c.HTML(form.Status, form.Template, req.TemplateData)
+	}
+}
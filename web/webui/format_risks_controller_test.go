@@ -0,0 +1,105 @@
+package webui_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/APTrust/registry/web/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatRiskCRUD(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+	testutil.InitHTTPTests(t)
+
+	// Only APTrust admins can see or change the registry.
+	for _, client := range testutil.AllClients {
+		if client == testutil.SysAdminClient {
+			continue
+		}
+		client.GET("/format_risks").Expect().Status(http.StatusForbidden)
+		client.GET("/format_risks/new").Expect().Status(http.StatusForbidden)
+	}
+
+	html := testutil.SysAdminClient.GET("/format_risks").
+		WithQuery("risk_level", constants.FormatRiskObsolete).
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "text/sgml")
+	assert.NotContains(t, html, "application/pdf")
+	testutil.SysAdminClient.GET("/format_risks/new").
+		Expect().Status(http.StatusOK)
+
+	// Invalid MIME type re-displays the form.
+	testutil.SysAdminClient.POST("/format_risks/new").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.SysAdminToken).
+		WithFormField("MimeType", "binary").
+		WithFormField("RiskLevel", constants.FormatRiskObsolete).
+		Expect().Status(http.StatusBadRequest)
+
+	html = testutil.SysAdminClient.POST("/format_risks/new").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.SysAdminToken).
+		WithFormField("MimeType", "application/binary").
+		WithFormField("RiskLevel", constants.FormatRiskObsolete).
+		WithFormField("Notes", "Unidentified binary").
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "Unidentified binary")
+
+	formatRisk := getFormatRisk(t, "application/binary")
+	assert.Equal(t, constants.FormatRiskObsolete, formatRisk.RiskLevel)
+
+	// Institutional admins can't change it.
+	testutil.Inst1AdminClient.GET("/format_risks/edit/{id}", formatRisk.ID).
+		Expect().Status(http.StatusForbidden)
+	testutil.Inst1AdminClient.POST("/format_risks/edit/{id}", formatRisk.ID).
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1AdminToken).
+		WithFormField("MimeType", "application/binary").
+		WithFormField("RiskLevel", constants.FormatRiskOpen).
+		Expect().Status(http.StatusForbidden)
+
+	testutil.SysAdminClient.GET("/format_risks/edit/{id}", formatRisk.ID).
+		Expect().Status(http.StatusOK)
+	testutil.SysAdminClient.POST("/format_risks/edit/{id}", formatRisk.ID).
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.SysAdminToken).
+		WithFormField("MimeType", "application/binary").
+		WithFormField("RiskLevel", constants.FormatRiskProprietary).
+		Expect().Status(http.StatusOK)
+	formatRisk = getFormatRisk(t, "application/binary")
+	assert.Equal(t, constants.FormatRiskProprietary, formatRisk.RiskLevel)
+
+	// The report picks up the change when its stats are refreshed.
+	html = testutil.Inst1AdminClient.GET("/reports/format_risk_objects").
+		WithQuery("risk_level", constants.FormatRiskProprietary).
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.NotContains(t, html, "application/binary")
+	require.Nil(t, pgmodels.RefreshFormatRiskStats())
+	html = testutil.Inst1AdminClient.GET("/reports/format_risk_objects").
+		WithQuery("risk_level", constants.FormatRiskProprietary).
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "application/binary")
+
+	testutil.Inst1AdminClient.POST("/format_risks/delete/{id}", formatRisk.ID).
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1AdminToken).
+		Expect().Status(http.StatusForbidden)
+	testutil.SysAdminClient.POST("/format_risks/delete/{id}", formatRisk.ID).
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.SysAdminToken).
+		Expect().Status(http.StatusOK)
+	_, err := pgmodels.FormatRiskByID(formatRisk.ID)
+	assert.True(t, pgmodels.IsNoRowError(err))
+}
+
+func getFormatRisk(t *testing.T, mimeType string) *pgmodels.FormatRisk {
+	formatRisk, err := pgmodels.FormatRiskGet(pgmodels.NewQuery().Where("mime_type", "=", mimeType))
+	require.Nil(t, err)
+	return formatRisk
+}
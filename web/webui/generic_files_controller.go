@@ -29,14 +29,29 @@ func GenericFileRequestDelete(c *gin.Context) {
 
 // GenericFileIndex shows list of objects.
 // GET /files
+// GET /files?format=csv|jsonl
 func GenericFileIndex(c *gin.Context) {
 	req := NewRequest(c)
 	template := "files/index.html"
+	if format := helpers.ExportFormat(c.Request); format != "" {
+		var fileViews []*pgmodels.GenericFileView
+		AbortIfError(c, req.ExportResourceList(&fileViews, "updated_at", "desc", format))
+		return
+	}
 	var files []*pgmodels.GenericFile
 	err := req.LoadResourceList(&files, "updated_at", "desc", forms.NewFileFilterForm)
 	if AbortIfError(c, err) {
 		return
 	}
+	// Let admins check fixity on all of the files matching
+	// the current filters.
+	hasFilters := len(req.TemplateData["filterChips"].([]*pgmodels.ParamFilter)) > 0
+	if hasFilters && req.CurrentUser.HasPermission(constants.FileRequestFixity, req.CurrentUser.InstitutionID) {
+		req.TemplateData["bulkFixityURL"] = "/files/request_bulk_fixity?" + c.Request.URL.RawQuery
+	}
+	if req.CurrentUser.HasPermission(constants.FileRequestDelete, req.CurrentUser.InstitutionID) {
+		req.TemplateData["bulkDeleteURL"] = "/files/request_bulk_delete"
+	}
 	c.HTML(http.StatusOK, template, req.TemplateData)
 }
 
@@ -51,6 +66,8 @@ func GenericFileShow(c *gin.Context) {
 	}
 	pendingFileWorkItems, _ := pgmodels.WorkItemsPendingForFile(file.ID)
 	req.TemplateData["hasPendingWorkItems"] = len(pendingFileWorkItems) > 0
+	hasPendingFixity, _ := pgmodels.FixityCheckPending(file)
+	req.TemplateData["hasPendingFixity"] = hasPendingFixity
 
 	c.HTML(http.StatusOK, "files/show.html", req.TemplateData)
 }
@@ -87,6 +104,166 @@ func GenericFileInitRestore(c *gin.Context) {
 	c.HTML(http.StatusCreated, "files/restoration_requested.html", req.TemplateData)
 }
 
+// GenericFileRequestFixity shows a message asking whether
+// user really wants to run a fixity check on a file.
+// GET /files/request_fixity/:id
+func GenericFileRequestFixity(c *gin.Context) {
+	req := NewRequest(c)
+	gf, err := pgmodels.GenericFileByID(req.Auth.ResourceID)
+	if AbortIfError(c, err) {
+		return
+	}
+	req.TemplateData["file"] = gf
+	c.HTML(http.StatusOK, "files/_request_fixity.html", req.TemplateData)
+}
+
+// GenericFileInitFixity creates a fixity check WorkItem for a file
+// and queues the file for the fixity checker.
+// POST /files/init_fixity/:id
+func GenericFileInitFixity(c *gin.Context) {
+	req := NewRequest(c)
+	c.Set("showErrorInModal", true)
+	gf, err := pgmodels.GenericFileByID(req.Auth.ResourceID)
+	if AbortIfError(c, err) {
+		return
+	}
+	_, err = InitFileFixityCheck(gf, req.CurrentUser)
+	if AbortIfError(c, err) {
+		return
+	}
+	req.TemplateData["fileIdentifier"] = gf.Identifier
+	c.HTML(http.StatusCreated, "files/fixity_requested.html", req.TemplateData)
+}
+
+// GenericFileRequestBulkFixity asks whether the user really wants to
+// run fixity checks on all active files matching the filters in the
+// query string. This takes the same filters as the files index page.
+// Inst admins can check only their own institution's files.
+//
+// GET /files/request_bulk_fixity
+func GenericFileRequestBulkFixity(c *gin.Context) {
+	req := NewRequest(c)
+	_, err := loadFilesForBulkFixity(req)
+	if AbortIfError(c, err) {
+		return
+	}
+	c.HTML(http.StatusOK, "files/request_bulk_fixity.html", req.TemplateData)
+}
+
+// GenericFileInitBulkFixity queues fixity checks for all active files
+// matching the filters in the query string, creating one WorkItem per
+// file. Files stored only in Glacier or already queued are skipped.
+//
+// POST /files/init_bulk_fixity
+func GenericFileInitBulkFixity(c *gin.Context) {
+	req := NewRequest(c)
+	files, err := loadFilesForBulkFixity(req)
+	if AbortIfError(c, err) {
+		return
+	}
+	if files == nil {
+		c.HTML(http.StatusBadRequest, "files/request_bulk_fixity.html", req.TemplateData)
+		return
+	}
+	workItems, skipped, err := InitBulkFixityCheck(files, req.CurrentUser)
+	if AbortIfError(c, err) {
+		return
+	}
+	req.TemplateData["workItems"] = workItems
+	req.TemplateData["skipped"] = skipped
+	c.HTML(http.StatusCreated, "files/bulk_fixity_requested.html", req.TemplateData)
+}
+
+// loadFilesForBulkFixity returns the active files matching the filters
+// in the query string, and sets the template data for the bulk fixity
+// pages. Non-admins get only their own institution's files. If there
+// are no filters, or the filters match too many files, this sets a
+// form error and returns nil, so no one queues every file in the
+// system by accident.
+func loadFilesForBulkFixity(req *Request) ([]*pgmodels.GenericFile, error) {
+	rawQuery := req.GinContext.Request.URL.RawQuery
+	req.TemplateData["maxItems"] = pgmodels.MaxBulkFixityFiles
+	req.TemplateData["filesURL"] = "/files?" + rawQuery
+	req.TemplateData["initURL"] = "/files/init_bulk_fixity?" + rawQuery
+	filterCollection := req.GetFilterCollection()
+	if len(req.TemplateData["filterChips"].([]*pgmodels.ParamFilter)) == 0 {
+		req.TemplateData["formError"] = "Please filter the file list to choose which files to check."
+		return nil, nil
+	}
+	var files []*pgmodels.GenericFile
+	query, err := filterCollection.ToQueryFor(req.CurrentUser, &files, "id", "asc")
+	if err != nil {
+		return nil, err
+	}
+	query.Where("state", "=", constants.StateActive).
+		Limit(pgmodels.MaxBulkFixityFiles + 1)
+	err = query.Select(&files)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) > pgmodels.MaxBulkFixityFiles {
+		req.TemplateData["formError"] = fmt.Sprintf("Your filters match more than %d files. Please narrow your search.", pgmodels.MaxBulkFixityFiles)
+		return nil, nil
+	}
+	if len(files) == 0 {
+		req.TemplateData["formError"] = "Your filters don't match any active files."
+		return nil, nil
+	}
+	req.TemplateData["fileCount"] = len(files)
+	return files, nil
+}
+
+// GenericFileRequestBulkDelete shows a form on which the user can
+// list the identifiers of files to delete.
+//
+// GET /files/request_bulk_delete
+func GenericFileRequestBulkDelete(c *gin.Context) {
+	req := NewRequest(c)
+	req.TemplateData["identifiers"] = ""
+	req.TemplateData["maxItems"] = MaxBulkDeletionItems
+	req.TemplateData["identifiersLabel"] = "File Identifiers"
+	c.HTML(http.StatusOK, "files/request_bulk_delete.html", req.TemplateData)
+}
+
+// GenericFileInitBulkDelete creates a single deletion request covering
+// all of the files listed in the identifiers field and/or the uploaded
+// identifiers file (one identifier per line). Like
+// IntellectualObjectInitBulkDelete, this creates no request if any file
+// can't be deleted, and instead shows the form again with a list of
+// problems.
+//
+// POST /files/init_bulk_delete
+func GenericFileInitBulkDelete(c *gin.Context) {
+	req := NewRequest(c)
+	template := "files/request_bulk_delete.html"
+	req.TemplateData["identifiersLabel"] = "File Identifiers"
+	identifiers, err := readIdentifiersFromForm(req, "file", MaxBulkDeletionItems)
+	if AbortIfError(c, err) {
+		return
+	}
+	if identifiers == nil {
+		c.HTML(http.StatusBadRequest, template, req.TemplateData)
+		return
+	}
+
+	del, problems, err := NewBulkDeletionForFiles(identifiers, req.CurrentUser, req.BaseURL())
+	if AbortIfError(c, err) {
+		return
+	}
+	if len(problems) > 0 {
+		req.TemplateData["formError"] = "The following files cannot be deleted. Please remove them from the list and try again."
+		req.TemplateData["problems"] = problems
+		c.HTML(http.StatusBadRequest, template, req.TemplateData)
+		return
+	}
+	_, err = del.CreateRequestAlert()
+	if AbortIfError(c, err) {
+		return
+	}
+	req.TemplateData["deletionRequest"] = del.DeletionRequest
+	c.HTML(http.StatusCreated, "files/bulk_deletion_requested.html", req.TemplateData)
+}
+
 // GenericFileInitDelete occurs when user clicks the button confirming
 // they want to delete a file. This creates a deletion confirmation message,
 // which will be emailed to institutional admins for approval.
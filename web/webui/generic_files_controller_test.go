@@ -262,3 +262,164 @@ func TestGenericFileInitRestore(t *testing.T) {
 		WithFormField(constants.CSRFTokenName, testutil.Inst1UserToken).
 		Expect().Status(http.StatusForbidden)
 }
+
+func TestGenericFileInitFixity(t *testing.T) {
+	// Force fixture reload so no fixity checks are pending,
+	// and reload again so the checks we queue don't block
+	// restorations and deletions in other tests.
+	err := db.ForceFixtureReload()
+	require.Nil(t, err)
+	defer db.ForceFixtureReload()
+	testutil.InitHTTPTests(t)
+
+	html := testutil.Inst1AdminClient.GET("/files/request_fixity/2").
+		Expect().Status(http.StatusOK).Body().Raw()
+	testutil.AssertMatchesAll(t, html, []string{"fixity check", "Confirm"})
+
+	html = testutil.Inst1AdminClient.POST("/files/init_fixity/2").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1AdminToken).
+		Expect().Status(http.StatusCreated).Body().Raw()
+	testutil.AssertMatchesAll(t, html, []string{"File <b>institution1.edu/photos/picture2</b> has been queued for a fixity check"})
+
+	query := pgmodels.NewQuery().
+		Where("action", "=", constants.ActionFixityCheck).
+		Where("generic_file_id", "=", 2).
+		Limit(1)
+	workItem, err := pgmodels.WorkItemGet(query)
+	require.Nil(t, err)
+	assert.False(t, workItem.QueuedAt.IsZero())
+
+	// Don't queue a second check while the first is pending.
+	testutil.Inst1AdminClient.POST("/files/init_fixity/2").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1AdminToken).
+		Expect().Status(http.StatusConflict)
+
+	// Inst users can't request fixity checks, and inst admins
+	// can't request them for other institutions' files.
+	testutil.Inst1UserClient.POST("/files/init_fixity/3").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1UserToken).
+		Expect().Status(http.StatusForbidden)
+	testutil.Inst1AdminClient.POST("/files/init_fixity/18").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1AdminToken).
+		Expect().Status(http.StatusForbidden)
+	testutil.SysAdminClient.POST("/files/init_fixity/18").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.SysAdminToken).
+		Expect().Status(http.StatusCreated)
+}
+
+func TestGenericFileBulkFixity(t *testing.T) {
+	err := db.ForceFixtureReload()
+	require.Nil(t, err)
+	defer db.ForceFixtureReload()
+	testutil.InitHTTPTests(t)
+
+	// Admins can check fixity on the files matching their filters.
+	html := testutil.Inst1AdminClient.GET("/files").
+		WithQuery("intellectual_object_id", 1).
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, `href="/files/request_bulk_fixity?intellectual_object_id=1"`)
+
+	html = testutil.Inst1AdminClient.GET("/files/request_bulk_fixity").
+		WithQuery("intellectual_object_id", 1).
+		Expect().Status(http.StatusOK).Body().Raw()
+	testutil.AssertMatchesAll(t, html, []string{
+		"Request Fixity Checks",
+		"these 3 active files",
+		`action="/files/init_bulk_fixity?intellectual_object_id=1"`,
+	})
+
+	// Filters are required.
+	html = testutil.Inst1AdminClient.GET("/files/request_bulk_fixity").
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "Please filter the file list")
+	assert.NotContains(t, html, "bulkFixityForm")
+
+	html = testutil.Inst1AdminClient.POST("/files/init_bulk_fixity").
+		WithQuery("intellectual_object_id", 1).
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1AdminToken).
+		Expect().Status(http.StatusCreated).Body().Raw()
+	assert.Contains(t, html, "3 files have been queued for fixity checks")
+
+	query := pgmodels.NewQuery().
+		Where("action", "=", constants.ActionFixityCheck).
+		Where("intellectual_object_id", "=", 1)
+	count, err := query.Count(&pgmodels.WorkItem{})
+	require.Nil(t, err)
+	assert.Equal(t, 3, count)
+
+	// Inst admins get only their own institution's files, even if
+	// their filters match other institutions' files.
+	html = testutil.Inst1AdminClient.POST("/files/init_bulk_fixity").
+		WithQuery("intellectual_object_id", 4).
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1AdminToken).
+		Expect().Status(http.StatusBadRequest).Body().Raw()
+	assert.Contains(t, html, "match any active files")
+	testutil.Inst1AdminClient.POST("/files/init_bulk_fixity").
+		WithQuery("institution_id", testutil.Inst2Admin.InstitutionID).
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1AdminToken).
+		Expect().Status(http.StatusForbidden)
+
+	// Inst users can't request fixity checks.
+	testutil.Inst1UserClient.POST("/files/init_bulk_fixity").
+		WithQuery("intellectual_object_id", 2).
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1UserToken).
+		Expect().Status(http.StatusForbidden)
+}
+
+func TestGenericFileBulkDelete(t *testing.T) {
+	err := db.ForceFixtureReload()
+	require.Nil(t, err)
+	defer db.ForceFixtureReload()
+	testutil.InitHTTPTests(t)
+
+	html := testutil.Inst1AdminClient.GET("/files").
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, `href="/files/request_bulk_delete"`)
+	html = testutil.Inst1UserClient.GET("/files").
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.NotContains(t, html, `href="/files/request_bulk_delete"`)
+
+	// Only inst admins can request deletions.
+	testutil.Inst1UserClient.GET("/files/request_bulk_delete").
+		Expect().Status(http.StatusForbidden)
+	html = testutil.Inst1AdminClient.GET("/files/request_bulk_delete").
+		Expect().Status(http.StatusOK).Body().Raw()
+	testutil.AssertMatchesAll(t, html, []string{"Delete Files", "File Identifiers", "Request Deletion"})
+
+	testutil.Inst1AdminClient.POST("/files/init_bulk_delete").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1AdminToken).
+		WithFormField("identifiers", "  \n ").
+		Expect().Status(http.StatusBadRequest)
+
+	// File 7 belongs to an object with pending work items, so
+	// the whole request should fail.
+	html = testutil.Inst1AdminClient.POST("/files/init_bulk_delete").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1AdminToken).
+		WithFormField("identifiers", "institution1.edu/photos/picture1\ninstitution1.edu/glass/shard1").
+		Expect().Status(http.StatusBadRequest).Body().Raw()
+	testutil.AssertMatchesAll(t, html, []string{"cannot be deleted", "institution1.edu/glass/shard1", "pending work items"})
+
+	html = testutil.Inst1AdminClient.POST("/files/init_bulk_delete").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1AdminToken).
+		WithFormField("identifiers", "institution1.edu/photos/picture1\r\ninstitution1.edu/photos/picture2\r\n").
+		Expect().Status(http.StatusCreated).Body().Raw()
+	testutil.AssertMatchesAll(t, html, []string{"Deletion Requested", "institution1.edu/photos/picture1", "institution1.edu/photos/picture2"})
+
+	testutil.Inst1UserClient.POST("/files/init_bulk_delete").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1UserToken).
+		WithFormField("identifiers", "institution1.edu/photos/picture3").
+		Expect().Status(http.StatusForbidden)
+}
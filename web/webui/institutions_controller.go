@@ -3,9 +3,12 @@ package webui
 import (
 	"fmt"
 	"net/http"
+	"strings"
 
+	"github.com/APTrust/registry/common"
 	"github.com/APTrust/registry/constants"
 	"github.com/APTrust/registry/forms"
+	"github.com/APTrust/registry/helpers"
 	"github.com/APTrust/registry/pgmodels"
 	"github.com/gin-gonic/gin"
 )
@@ -68,7 +71,10 @@ func InstitutionIndex(c *gin.Context) {
 // GET /institutions/new
 func InstitutionNew(c *gin.Context) {
 	req := NewRequest(c)
-	form, err := forms.NewInstitutionForm(&pgmodels.Institution{State: constants.StateActive})
+	form, err := forms.NewInstitutionForm(&pgmodels.Institution{
+		State:           constants.StateActive,
+		FreeAllowanceTB: pgmodels.DefaultFreeAllowanceTB,
+	})
 	if AbortIfError(c, err) {
 		return
 	}
@@ -99,6 +105,16 @@ func InstitutionShow(c *gin.Context) {
 		return
 	}
 	req.TemplateData["users"] = users
+
+	idp, err := pgmodels.IdentityProviderForInstitution(institution.ID)
+	if err != nil && !pgmodels.IsNoRowError(err) {
+		AbortIfError(c, err)
+		return
+	}
+	if err == nil {
+		req.TemplateData["identityProvider"] = idp
+	}
+	req.TemplateData["ssoEnabled"] = common.Context().Config.SSO.Enabled
 	c.HTML(http.StatusOK, "institutions/show.html", req.TemplateData)
 }
 
@@ -136,7 +152,13 @@ func InstitutionEditPrefs(c *gin.Context) {
 	if AbortIfError(c, err) {
 		return
 	}
+	webhooks, err := pgmodels.WebhookSelect(pgmodels.NewQuery().Where("institution_id", "=", institution.ID).OrderBy("id", "asc"))
+	if AbortIfError(c, err) {
+		return
+	}
 	req.TemplateData["form"] = form
+	req.TemplateData["institution"] = institution
+	req.TemplateData["webhooks"] = webhooks
 	c.HTML(http.StatusOK, form.Template, req.TemplateData)
 
 }
@@ -159,8 +181,15 @@ func saveInstitutionForm(c *gin.Context) {
 	}
 	// Bind submitted form values in case we have to
 	// re-display the form with an error message.
+	freeAllowanceTB := institution.FreeAllowanceTB
 	c.ShouldBind(institution)
 
+	// The free allowance determines what the institution is billed,
+	// so only APTrust admins may change it.
+	if !req.CurrentUser.IsAdmin() {
+		institution.FreeAllowanceTB = freeAllowanceTB
+	}
+
 	// Make sure state has valid value: https://trello.com/c/S5Oss7e0
 	// This is only an issue when creating new institutions.
 	if institution.ID == 0 && institution.State == "" {
@@ -179,3 +208,88 @@ func saveInstitutionForm(c *gin.Context) {
 		c.HTML(form.Status, form.Template, req.TemplateData)
 	}
 }
+
+// InstitutionSSOEdit shows a form to configure an institution's single
+// sign-on identity provider. If the institution doesn't have one yet,
+// the form starts with a disabled OpenID Connect provider.
+// GET /institutions/sso/:id
+func InstitutionSSOEdit(c *gin.Context) {
+	req := NewRequest(c)
+	idp, institution, err := identityProviderFor(req)
+	if AbortIfError(c, err) {
+		return
+	}
+	form := forms.NewIdentityProviderForm(idp)
+	setSSOFormData(req, form, institution)
+	c.HTML(http.StatusOK, form.Template, req.TemplateData)
+}
+
+// InstitutionSSOUpdate saves an institution's single sign-on settings.
+// POST /institutions/sso/:id
+func InstitutionSSOUpdate(c *gin.Context) {
+	req := NewRequest(c)
+	idp, institution, err := identityProviderFor(req)
+	if AbortIfError(c, err) {
+		return
+	}
+	idp.Protocol = c.PostForm("Protocol")
+	idp.Enabled = c.PostForm("Enabled") == "true"
+	idp.Required = c.PostForm("Required") == "true"
+	idp.IssuerURL = strings.TrimSpace(c.PostForm("IssuerURL"))
+	idp.ClientID = strings.TrimSpace(c.PostForm("ClientID"))
+	idp.MetadataXML = strings.TrimSpace(c.PostForm("MetadataXML"))
+	idp.EmailAttribute = strings.TrimSpace(c.PostForm("EmailAttribute"))
+	idp.RoleAttribute = strings.TrimSpace(c.PostForm("RoleAttribute"))
+	idp.AdminRoleValues = make([]string, 0)
+	for _, value := range strings.Split(c.PostForm("AdminRoleValues"), ",") {
+		if strings.TrimSpace(value) != "" {
+			idp.AdminRoleValues = append(idp.AdminRoleValues, strings.TrimSpace(value))
+		}
+	}
+	form := forms.NewIdentityProviderForm(idp)
+	setSSOFormData(req, form, institution)
+	if secret := c.PostForm("ClientSecret"); secret != "" {
+		err = idp.SetClientSecret(secret)
+		if err != nil {
+			form.HandleError(err)
+			req.TemplateData["FormError"] = form.Error
+			c.HTML(form.Status, form.Template, req.TemplateData)
+			return
+		}
+	}
+	if form.Save() {
+		helpers.SetFlashCookie(c, "Single sign-on settings saved.")
+		c.Redirect(form.Status, form.PostSaveURL())
+	} else {
+		req.TemplateData["FormError"] = form.Error
+		c.HTML(form.Status, form.Template, req.TemplateData)
+	}
+}
+
+// identityProviderFor returns the identity provider for the institution
+// in the request, or a new, unsaved one if the institution has none.
+func identityProviderFor(req *Request) (*pgmodels.IdentityProvider, *pgmodels.Institution, error) {
+	institution, err := pgmodels.InstitutionByID(req.Auth.ResourceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	idp, err := pgmodels.IdentityProviderForInstitution(institution.ID)
+	if pgmodels.IsNoRowError(err) {
+		return pgmodels.NewIdentityProvider(institution.ID), institution, nil
+	}
+	return idp, institution, err
+}
+
+// setSSOFormData sets the template data for the single sign-on form,
+// including the Registry URLs the institution's IT staff will need to
+// register the Registry with their identity provider.
+func setSSOFormData(req *Request, form *forms.IdentityProviderForm, institution *pgmodels.Institution) {
+	req.TemplateData["form"] = form
+	req.TemplateData["institution"] = institution
+	req.TemplateData["ssoEnabled"] = common.Context().Config.SSO.Enabled
+	req.TemplateData["ssoURLs"] = map[string]string{
+		"metadata": req.BaseURL() + common.SAMLMetadataPath,
+		"acs":      req.BaseURL() + common.SAMLACSPath,
+		"callback": req.BaseURL() + common.OIDCCallbackPath,
+	}
+}
@@ -2,13 +2,16 @@ package webui
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"strconv"
 	"strings"
-	"time"
 
 	"github.com/APTrust/registry/common"
 	"github.com/APTrust/registry/constants"
 	"github.com/APTrust/registry/forms"
+	"github.com/APTrust/registry/helpers"
 	"github.com/APTrust/registry/pgmodels"
 	"github.com/gin-gonic/gin"
 )
@@ -47,6 +50,196 @@ func IntellectualObjectInitDelete(c *gin.Context) {
 	c.HTML(http.StatusCreated, "objects/deletion_requested.html", req.TemplateData)
 }
 
+// IntellectualObjectRequestBulkDelete shows a form on which the user
+// can list the identifiers of objects to delete. If the query string
+// includes object filters (as on the objects index page), we pre-fill
+// the form with the identifiers of the user's active objects that match
+// those filters.
+//
+// GET /objects/request_bulk_delete
+func IntellectualObjectRequestBulkDelete(c *gin.Context) {
+	req := NewRequest(c)
+	err := loadIdentifiersFromFilters(req, MaxBulkDeletionItems)
+	if AbortIfError(c, err) {
+		return
+	}
+	c.HTML(http.StatusOK, "objects/request_bulk_delete.html", req.TemplateData)
+}
+
+// IntellectualObjectInitBulkDelete creates a single deletion request
+// covering all of the objects listed in the identifiers field and/or
+// the uploaded identifiers file (one identifier per line). As with
+// single-object deletions, an administrator at the depositing institution
+// must approve the request before any deletions are queued.
+//
+// If any object can't be deleted, this creates no request, and instead
+// shows the form again with a list of problems.
+//
+// POST /objects/init_bulk_delete
+func IntellectualObjectInitBulkDelete(c *gin.Context) {
+	req := NewRequest(c)
+	template := "objects/request_bulk_delete.html"
+	identifiers, err := readIdentifiersFromForm(req, "object", MaxBulkDeletionItems)
+	if AbortIfError(c, err) {
+		return
+	}
+	if identifiers == nil {
+		c.HTML(http.StatusBadRequest, template, req.TemplateData)
+		return
+	}
+
+	del, problems, err := NewBulkDeletionForObjects(identifiers, req.CurrentUser, req.BaseURL())
+	if AbortIfError(c, err) {
+		return
+	}
+	if len(problems) > 0 {
+		req.TemplateData["formError"] = "The following objects cannot be deleted. Please remove them from the list and try again."
+		req.TemplateData["problems"] = problems
+		c.HTML(http.StatusBadRequest, template, req.TemplateData)
+		return
+	}
+	_, err = del.CreateRequestAlert()
+	if AbortIfError(c, err) {
+		return
+	}
+	req.TemplateData["deletionRequest"] = del.DeletionRequest
+	c.HTML(http.StatusCreated, "objects/bulk_deletion_requested.html", req.TemplateData)
+}
+
+// IntellectualObjectRequestBulkRestore shows a form on which the user
+// can list the identifiers of objects to restore. As with bulk deletion,
+// object filters in the query string pre-fill the list.
+//
+// GET /objects/request_bulk_restore
+func IntellectualObjectRequestBulkRestore(c *gin.Context) {
+	req := NewRequest(c)
+	err := loadIdentifiersFromFilters(req, MaxBulkRestorationItems)
+	if AbortIfError(c, err) {
+		return
+	}
+	c.HTML(http.StatusOK, "objects/request_bulk_restore.html", req.TemplateData)
+}
+
+// IntellectualObjectInitBulkRestore creates a restoration batch with
+// one restoration WorkItem for each object listed in the identifiers
+// field and/or the uploaded identifiers file, and queues the WorkItems.
+//
+// If any object can't be restored, this creates no WorkItems, and instead
+// shows the form again with a list of problems.
+//
+// POST /objects/init_bulk_restore
+func IntellectualObjectInitBulkRestore(c *gin.Context) {
+	req := NewRequest(c)
+	template := "objects/request_bulk_restore.html"
+	identifiers, err := readIdentifiersFromForm(req, "object", MaxBulkRestorationItems)
+	if AbortIfError(c, err) {
+		return
+	}
+	if identifiers == nil {
+		c.HTML(http.StatusBadRequest, template, req.TemplateData)
+		return
+	}
+
+	batch, problems, err := InitRestorationBatch(identifiers, req.CurrentUser)
+	if AbortIfError(c, err) {
+		return
+	}
+	if len(problems) > 0 {
+		req.TemplateData["formError"] = "The following objects cannot be restored. Please remove them from the list and try again."
+		req.TemplateData["problems"] = problems
+		c.HTML(http.StatusBadRequest, template, req.TemplateData)
+		return
+	}
+	helpers.SetFlashCookie(c, fmt.Sprintf("Queued %d objects for restoration. We'll send you an email when they're all done.", len(batch.WorkItems)))
+	c.Redirect(http.StatusSeeOther, fmt.Sprintf("/restoration_batches/show/%d", batch.ID))
+}
+
+// loadIdentifiersFromFilters pre-fills the bulk deletion and restoration
+// forms with the identifiers of the user's active objects matching the
+// object filters in the query string. If there are no filters, the list
+// is empty. If the filters match more than maxItems objects, this sets a
+// form error instead.
+//
+// The state filter alone doesn't count, because the objects page always
+// includes it, and we don't want to pre-fill the list with every object
+// the institution owns.
+func loadIdentifiersFromFilters(req *Request, maxItems int) error {
+	req.TemplateData["identifiers"] = ""
+	req.TemplateData["maxItems"] = maxItems
+	filterCollection := req.GetFilterCollection()
+	hasFilters := false
+	for _, filter := range req.TemplateData["filterChips"].([]*pgmodels.ParamFilter) {
+		if filter.Key != "state" {
+			hasFilters = true
+			break
+		}
+	}
+	if !hasFilters {
+		return nil
+	}
+	query, err := filterCollection.ToQuery()
+	if err != nil {
+		return err
+	}
+	query.Columns("identifier").
+		Where("institution_id", "=", req.CurrentUser.InstitutionID).
+		Where("state", "=", constants.StateActive).
+		OrderBy("identifier", "asc").
+		Limit(maxItems + 1)
+	var objects []*pgmodels.IntellectualObjectView
+	err = query.Select(&objects)
+	if err != nil {
+		return err
+	}
+	if len(objects) > maxItems {
+		req.TemplateData["formError"] = fmt.Sprintf("Your filters match more than %d objects. Please narrow your search.", maxItems)
+		return nil
+	}
+	list := make([]string, len(objects))
+	for i, obj := range objects {
+		list[i] = obj.Identifier
+	}
+	req.TemplateData["identifiers"] = strings.Join(list, "\n")
+	return nil
+}
+
+// readIdentifiersFromForm returns the identifiers listed in the
+// identifiers field of the bulk deletion and restoration forms, plus
+// those in the optional identifiersFile upload (one per line), with
+// blanks and duplicates removed. If the list is empty or has more than
+// maxItems identifiers, this sets a form error and returns nil.
+// itemType is "object" or "file", for the error messages.
+func readIdentifiersFromForm(req *Request, itemType string, maxItems int) ([]string, error) {
+	c := req.GinContext
+	text := c.PostForm("identifiers")
+	fileHeader, err := c.FormFile("identifiersFile")
+	if err == nil {
+		file, err := fileHeader.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		data, err := ioutil.ReadAll(io.LimitReader(file, 4*1024*1024))
+		if err != nil {
+			return nil, err
+		}
+		text = text + "\n" + string(data)
+	}
+	identifiers := uniqueIdentifiers(strings.Split(strings.ReplaceAll(text, "\r", ""), "\n"))
+	req.TemplateData["identifiers"] = strings.Join(identifiers, "\n")
+	req.TemplateData["maxItems"] = maxItems
+
+	if len(identifiers) == 0 {
+		req.TemplateData["formError"] = fmt.Sprintf("Please list at least one %s identifier.", itemType)
+		return nil, nil
+	}
+	if len(identifiers) > maxItems {
+		req.TemplateData["formError"] = fmt.Sprintf("You can include up to %d %ss at a time. Your list has %d.", maxItems, itemType, len(identifiers))
+		return nil, nil
+	}
+	return identifiers, nil
+}
+
 // IntellectualObjectRequestRestore shows a message asking if the user
 // really wants to delete this object.
 // GET /objects/request_restore/:id
@@ -83,12 +276,48 @@ func IntellectualObjectInitRestore(c *gin.Context) {
 	c.HTML(http.StatusCreated, "objects/restoration_requested.html", req.TemplateData)
 }
 
+// IntellectualObjectRequestFixity shows a message asking if the user
+// really wants to run a fixity check on all of this object's files.
+// GET /objects/request_fixity/:id
+func IntellectualObjectRequestFixity(c *gin.Context) {
+	req := NewRequest(c)
+	obj, err := pgmodels.IntellectualObjectViewByID(req.Auth.ResourceID)
+	if AbortIfError(c, err) {
+		return
+	}
+	req.TemplateData["object"] = obj
+	c.HTML(http.StatusOK, "objects/_request_fixity.html", req.TemplateData)
+}
+
+// IntellectualObjectInitFixity creates a fixity check WorkItem for an
+// object and queues all of its active files for the fixity checker.
+// POST /objects/init_fixity/:id
+func IntellectualObjectInitFixity(c *gin.Context) {
+	req := NewRequest(c)
+	c.Set("showErrorInModal", true)
+	obj, err := pgmodels.IntellectualObjectByID(req.Auth.ResourceID)
+	if AbortIfError(c, err) {
+		return
+	}
+	_, err = InitObjectFixityCheck(obj, req.CurrentUser)
+	if AbortIfError(c, err) {
+		return
+	}
+	req.TemplateData["objIdentifier"] = obj.Identifier
+	c.HTML(http.StatusCreated, "objects/fixity_requested.html", req.TemplateData)
+}
+
 // IntellectualObjectIndex shows list of objects.
 // GET /objects
+// GET /objects?format=csv|jsonl
 func IntellectualObjectIndex(c *gin.Context) {
 	req := NewRequest(c)
 	template := "objects/index.html"
 	var objects []*pgmodels.IntellectualObjectView
+	if format := helpers.ExportFormat(c.Request); format != "" {
+		AbortIfError(c, req.ExportResourceList(&objects, "updated_at", "desc", format))
+		return
+	}
 	err := req.LoadResourceList(&objects, "updated_at", "desc", forms.NewObjectFilterForm)
 	if AbortIfError(c, err) {
 		return
@@ -100,6 +329,10 @@ func IntellectualObjectIndex(c *gin.Context) {
 	if len(objects) == 1 && c.Query("identifier") != "" {
 		c.Redirect(http.StatusFound, fmt.Sprintf("/objects/show/%d", objects[0].ID))
 	}
+	// Bulk deletion and restoration forms pre-fill identifiers
+	// using current filters.
+	req.TemplateData["bulkDeleteURL"] = "/objects/request_bulk_delete?" + c.Request.URL.RawQuery
+	req.TemplateData["bulkRestoreURL"] = "/objects/request_bulk_restore?" + c.Request.URL.RawQuery
 	c.HTML(http.StatusOK, template, req.TemplateData)
 }
 
@@ -126,8 +359,16 @@ func IntellectualObjectShow(c *gin.Context) {
 	}
 	req.TemplateData["depositFormatStats"] = stats
 
+	versions, err := pgmodels.ObjectVersionsFor(object.ID)
+	if AbortIfError(c, err) {
+		return
+	}
+	req.TemplateData["versions"] = versions
+
 	pendingWorkItems, _ := pgmodels.WorkItemsPendingForObject(object.InstitutionID, object.BagName)
 	req.TemplateData["hasPendingWorkItems"] = len(pendingWorkItems) > 0
+	hasPendingFixity, _ := pgmodels.FixityCheckPendingForObject(object.ID)
+	req.TemplateData["hasPendingFixity"] = hasPendingFixity
 
 	c.HTML(http.StatusOK, "objects/show.html", req.TemplateData)
 }
@@ -160,6 +401,91 @@ func IntellectualObjectFiles(c *gin.Context) {
 	c.HTML(http.StatusOK, "objects/_file_list.html", req.TemplateData)
 }
 
+// IntellectualObjectVersions shows the files that changed between two
+// recorded versions of an object. Param to defaults to the latest
+// version, and param from defaults to the version before to. This
+// returns an HTML fragment for the version history on the object
+// detail page.
+//
+// GET /objects/versions/:id?from=:version_id&to=:version_id (intellectual object id)
+func IntellectualObjectVersions(c *gin.Context) {
+	req := NewRequest(c)
+	diff, err := loadVersionDiff(req, req.Auth.ResourceID)
+	if AbortIfError(c, err) {
+		return
+	}
+	req.TemplateData["diff"] = diff
+	c.HTML(http.StatusOK, "objects/_version_diff.html", req.TemplateData)
+}
+
+// MaxVersionDiffFiles is the maximum number of added, replaced or
+// removed files we list in a version diff. Large objects may have
+// hundreds of thousands of files.
+const MaxVersionDiffFiles = 500
+
+func loadVersionDiff(req *Request, objID int64) (*pgmodels.ObjectVersionDiff, error) {
+	versionFor := func(param string) (*pgmodels.ObjectVersion, error) {
+		id, err := strconv.ParseInt(req.GinContext.Query(param), 10, 64)
+		if err != nil {
+			return nil, common.ErrInvalidParam
+		}
+		version, err := pgmodels.ObjectVersionByID(id)
+		if err != nil {
+			return nil, err
+		}
+		if version.IntellectualObjectID != objID {
+			return nil, common.ErrInvalidParam
+		}
+		return version, nil
+	}
+	var to, from *pgmodels.ObjectVersion
+	var err error
+	if req.GinContext.Query("to") != "" {
+		to, err = versionFor("to")
+	} else {
+		var versions []*pgmodels.ObjectVersion
+		versions, err = pgmodels.ObjectVersionsFor(objID)
+		if err == nil && len(versions) == 0 {
+			err = common.ErrInvalidParam
+		}
+		if err == nil {
+			to = versions[0]
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if req.GinContext.Query("from") != "" {
+		from, err = versionFor("from")
+	} else {
+		from, err = to.Previous()
+	}
+	if err != nil {
+		return nil, err
+	}
+	diff, err := pgmodels.DiffObjectVersions(from, to)
+	if err != nil {
+		return nil, err
+	}
+	req.TemplateData["truncated"] = len(diff.Added) > MaxVersionDiffFiles ||
+		len(diff.Replaced) > MaxVersionDiffFiles ||
+		len(diff.Removed) > MaxVersionDiffFiles
+	req.TemplateData["addedCount"] = len(diff.Added)
+	req.TemplateData["replacedCount"] = len(diff.Replaced)
+	req.TemplateData["removedCount"] = len(diff.Removed)
+	diff.Added = truncateChanges(diff.Added)
+	diff.Replaced = truncateChanges(diff.Replaced)
+	diff.Removed = truncateChanges(diff.Removed)
+	return diff, nil
+}
+
+func truncateChanges(changes []*pgmodels.FileChange) []*pgmodels.FileChange {
+	if len(changes) > MaxVersionDiffFiles {
+		return changes[:MaxVersionDiffFiles]
+	}
+	return changes
+}
+
 // Select max 20 files to start. Some objects have > 100k files, and
 // we definitely don't want that many results. Let the user page through.
 func loadFiles(req *Request, objID int64) error {
@@ -245,18 +571,6 @@ func InitObjectRestoration(obj *pgmodels.IntellectualObject, user *pgmodels.User
 	}
 
 	// Queue the new work item in NSQ
-	topic, err := constants.TopicFor(workItem.Action, workItem.Stage)
-	if err != nil {
-		return nil, err
-	}
-
-	ctx := common.Context()
-	err = ctx.NSQClient.Enqueue(topic, workItem.ID)
-	if err != nil {
-		return nil, err
-	}
-
-	workItem.QueuedAt = time.Now().UTC()
-	err = workItem.Save()
+	err = queueWorkItem(workItem)
 	return workItem, err
 }
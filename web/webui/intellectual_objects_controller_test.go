@@ -10,6 +10,7 @@ import (
 	"github.com/APTrust/registry/db"
 	"github.com/APTrust/registry/pgmodels"
 	"github.com/APTrust/registry/web/testutil"
+	"github.com/gavv/httpexpect/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -40,6 +41,7 @@ func TestObjectShow(t *testing.T) {
 		"institution1.edu/photos/picture2",
 		"institution1.edu/photos/picture3",
 		"/files/request_restore/1",
+		"Version History",
 	}
 
 	// Only admins see deletion links
@@ -274,6 +276,102 @@ func TestObjectInitDelete(t *testing.T) {
 
 }
 
+func TestObjectRequestBulkDelete(t *testing.T) {
+	testutil.InitHTTPTests(t)
+
+	// Only inst admins can request deletions.
+	testutil.SysAdminClient.GET("/objects/request_bulk_delete").
+		Expect().Status(http.StatusForbidden)
+	testutil.Inst1UserClient.GET("/objects/request_bulk_delete").
+		Expect().Status(http.StatusForbidden)
+
+	html := testutil.Inst1AdminClient.GET("/objects/request_bulk_delete").
+		Expect().Status(http.StatusOK).Body().Raw()
+	testutil.AssertMatchesAll(t, html, []string{"Delete Objects", "Object Identifiers", "Request Deletion"})
+
+	// Filters from the objects page should pre-fill the list
+	// with matching identifiers from the user's institution.
+	html = testutil.Inst1AdminClient.GET("/objects/request_bulk_delete").
+		WithQuery("access", "institution").
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "institution1.edu/photos")
+	assert.NotContains(t, html, "institution1.edu/pdfs")
+	assert.NotContains(t, html, "institution2.edu/chocolate")
+}
+
+func TestObjectInitBulkDelete(t *testing.T) {
+	err := db.ForceFixtureReload()
+	require.Nil(t, err)
+	defer db.ForceFixtureReload()
+	testutil.InitHTTPTests(t)
+
+	// Empty list
+	testutil.Inst1AdminClient.POST("/objects/init_bulk_delete").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1AdminToken).
+		WithFormField("identifiers", "  \n ").
+		Expect().Status(http.StatusBadRequest)
+
+	// Object 3 (glass) has pending work items, so the whole
+	// request should fail, and we should tell the user why.
+	html := testutil.Inst1AdminClient.POST("/objects/init_bulk_delete").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1AdminToken).
+		WithFormField("identifiers", "institution1.edu/photos\ninstitution1.edu/glass").
+		Expect().Status(http.StatusBadRequest).Body().Raw()
+	testutil.AssertMatchesAll(t, html, []string{"cannot be deleted", "institution1.edu/glass", "pending work items"})
+
+	html = testutil.Inst1AdminClient.POST("/objects/init_bulk_delete").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1AdminToken).
+		WithFormField("identifiers", "institution1.edu/photos\r\ninstitution1.edu/pdfs\r\n").
+		Expect().Status(http.StatusCreated).Body().Raw()
+	testutil.AssertMatchesAll(t, html, []string{"Deletion Requested", "institution1.edu/photos", "institution1.edu/pdfs"})
+}
+
+func TestObjectRequestBulkRestore(t *testing.T) {
+	testutil.InitHTTPTests(t)
+
+	for _, client := range []*httpexpect.Expect{testutil.Inst1AdminClient, testutil.Inst1UserClient} {
+		html := client.GET("/objects/request_bulk_restore").
+			Expect().Status(http.StatusOK).Body().Raw()
+		testutil.AssertMatchesAll(t, html, []string{"Restore Objects", "Object Identifiers", "Restoration Batches"})
+	}
+
+	// The state filter alone shouldn't pre-fill the list.
+	html := testutil.Inst1UserClient.GET("/objects/request_bulk_restore").
+		WithQuery("state", "A").
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.NotContains(t, html, "institution1.edu/photos")
+
+	html = testutil.Inst1UserClient.GET("/objects/request_bulk_restore").
+		WithQuery("state", "A").
+		WithQuery("access", "institution").
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "institution1.edu/photos")
+}
+
+func TestObjectInitBulkRestore(t *testing.T) {
+	err := db.ForceFixtureReload()
+	require.Nil(t, err)
+	defer db.ForceFixtureReload()
+	testutil.InitHTTPTests(t)
+
+	html := testutil.Inst1UserClient.POST("/objects/init_bulk_restore").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1UserToken).
+		WithFormField("identifiers", "institution1.edu/photos\ninstitution1.edu/glass").
+		Expect().Status(http.StatusBadRequest).Body().Raw()
+	testutil.AssertMatchesAll(t, html, []string{"cannot be restored", "institution1.edu/glass", "pending work items"})
+
+	// On success, we redirect to the batch page.
+	testutil.Inst1UserClient.POST("/objects/init_bulk_restore").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1UserToken).
+		WithFormField("identifiers", "institution1.edu/photos\ninstitution1.edu/pdfs").
+		Expect().Status(http.StatusOK).Body().Contains("Restoration Batch #")
+}
+
 func TestObjectRequestRestore(t *testing.T) {
 	testutil.InitHTTPTests(t)
 
@@ -367,3 +465,116 @@ func TestIntellectualObjectFiles(t *testing.T) {
 	}
 	testutil.AssertMatchesAll(t, html, expected)
 }
+
+func TestIntellectualObjectVersions(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+	testutil.InitHTTPTests(t)
+
+	item := &pgmodels.WorkItem{
+		Name:                 "photos.tar",
+		ETag:                 "12345678901234567890123456789001",
+		InstitutionID:        2,
+		IntellectualObjectID: 1,
+		User:                 "system@aptrust.org",
+		Bucket:               "aptrust.receiving.test.inst1.edu",
+		Action:               constants.ActionIngest,
+		Stage:                constants.StageCleanup,
+		Status:               constants.StatusSuccess,
+		Note:                 "Ingest complete.",
+		Outcome:              "Ingest complete",
+		BagDate:              time.Now().UTC(),
+		DateProcessed:        time.Now().UTC(),
+	}
+	require.Nil(t, item.Save())
+	_, err := pgmodels.RecordPendingObjectVersions(100)
+	require.Nil(t, err)
+
+	html := testutil.Inst1UserClient.GET("/objects/show/1").Expect().
+		Status(http.StatusOK).Body().Raw()
+	testutil.AssertMatchesAll(t, html, []string{
+		"Version History",
+		"/objects/versions/1?to=",
+	})
+
+	html = testutil.Inst1UserClient.GET("/objects/versions/1").Expect().
+		Status(http.StatusOK).Body().Raw()
+	testutil.AssertMatchesAll(t, html, []string{
+		"the first recorded version",
+		"3 added, 0 replaced, 0 removed",
+		"institution1.edu/photos/picture1",
+		"institution1.edu/photos/picture3",
+	})
+
+	// Other institutions can't see this object's versions.
+	testutil.Inst2UserClient.GET("/objects/versions/1").Expect().
+		Status(http.StatusForbidden)
+}
+
+func TestObjectListExport(t *testing.T) {
+	testutil.InitHTTPTests(t)
+
+	// Index page links to downloads with the current filters.
+	html := testutil.Inst1UserClient.GET("/objects").
+		WithQuery("access", constants.AccessInstitution).
+		WithQuery("page", 1).
+		Expect().Status(http.StatusOK).Body().Raw()
+	testutil.AssertMatchesAll(t, html, []string{
+		"/objects?access=institution&amp;format=csv",
+		"/objects?access=institution&amp;format=jsonl",
+	})
+
+	for _, client := range testutil.AllClients {
+		resp := client.GET("/objects").
+			WithQuery("format", "csv").
+			Expect().Status(http.StatusOK)
+		resp.Header("Content-Type").Equal("text/csv; charset=utf-8")
+		lines := strings.Split(strings.TrimSpace(resp.Body().Raw()), "\n")
+		if client == testutil.SysAdminClient {
+			assert.Equal(t, 15, len(lines)) // header + 14 objects
+		} else {
+			assert.Equal(t, 7, len(lines)) // header + 6 objects
+		}
+	}
+}
+
+func TestObjectInitFixity(t *testing.T) {
+	err := db.ForceFixtureReload()
+	require.Nil(t, err)
+	defer db.ForceFixtureReload()
+	testutil.InitHTTPTests(t)
+
+	html := testutil.Inst1AdminClient.GET("/objects/request_fixity/2").
+		Expect().Status(http.StatusOK).Body().Raw()
+	testutil.AssertMatchesAll(t, html, []string{"queued for a fixity check", "Confirm"})
+
+	html = testutil.Inst1AdminClient.POST("/objects/init_fixity/2").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1AdminToken).
+		Expect().Status(http.StatusCreated).Body().Raw()
+	testutil.AssertMatchesAll(t, html, []string{"object <b>institution1.edu/pdfs</b> have been queued for fixity checks"})
+
+	query := pgmodels.NewQuery().
+		Where("action", "=", constants.ActionFixityCheck).
+		Where("intellectual_object_id", "=", 2).
+		IsNull("generic_file_id")
+	workItems, err := pgmodels.WorkItemSelect(query)
+	require.Nil(t, err)
+	require.Len(t, workItems, 1)
+	assert.Equal(t, "Queued 3 file(s) for fixity check", workItems[0].Note)
+
+	// Don't queue a second check while the first is pending.
+	testutil.Inst1AdminClient.POST("/objects/init_fixity/2").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1AdminToken).
+		Expect().Status(http.StatusConflict)
+
+	testutil.Inst1UserClient.POST("/objects/init_fixity/1").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1UserToken).
+		Expect().Status(http.StatusForbidden)
+	testutil.Inst1AdminClient.POST("/objects/init_fixity/6").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1AdminToken).
+		Expect().Status(http.StatusForbidden)
+}
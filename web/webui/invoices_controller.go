@@ -0,0 +1,72 @@
+package webui
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/forms"
+	"github.com/APTrust/registry/helpers"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/gin-gonic/gin"
+)
+
+// InvoiceIndex lists monthly storage invoices, newest first.
+// Institutional admins see only their own institution's invoices.
+//
+// GET /invoices
+// GET /invoices?format=csv|jsonl
+func InvoiceIndex(c *gin.Context) {
+	req := NewRequest(c)
+	template := "invoices/index.html"
+	var invoices []*pgmodels.Invoice
+	if format := helpers.ExportFormat(c.Request); format != "" {
+		AbortIfError(c, req.ExportResourceList(&invoices, "billing_month", "desc", format))
+		return
+	}
+	err := req.LoadResourceList(&invoices, "billing_month", "desc", forms.NewInvoiceFilterForm)
+	if AbortIfError(c, err) {
+		return
+	}
+	c.HTML(http.StatusOK, template, req.TemplateData)
+}
+
+// InvoiceShow shows an invoice and its line items.
+//
+// GET /invoices/show/:id
+func InvoiceShow(c *gin.Context) {
+	req := NewRequest(c)
+	invoice, err := pgmodels.InvoiceByID(req.Auth.ResourceID)
+	if AbortIfError(c, err) {
+		return
+	}
+	req.TemplateData["invoice"] = invoice
+	c.HTML(http.StatusOK, "invoices/show.html", req.TemplateData)
+}
+
+// InvoiceDownload returns an invoice as a PDF or CSV file. The
+// format defaults to PDF.
+//
+// GET /invoices/download/:id?format=pdf|csv
+func InvoiceDownload(c *gin.Context) {
+	req := NewRequest(c)
+	invoice, err := pgmodels.InvoiceByID(req.Auth.ResourceID)
+	if AbortIfError(c, err) {
+		return
+	}
+	switch c.DefaultQuery("format", "pdf") {
+	case "pdf":
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pdf"`, invoice.InvoiceNumber))
+		c.Data(http.StatusOK, "application/pdf", helpers.InvoicePDF(invoice))
+	case "csv":
+		var buf bytes.Buffer
+		if AbortIfError(c, helpers.WriteInvoiceCSV(&buf, invoice)) {
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.csv"`, invoice.InvoiceNumber))
+		c.Data(http.StatusOK, helpers.ExportContentType(helpers.ExportFormatCSV), buf.Bytes())
+	default:
+		AbortIfError(c, common.ErrInvalidParam)
+	}
+}
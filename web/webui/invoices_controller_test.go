@@ -0,0 +1,96 @@
+package webui_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/APTrust/registry/web/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func generateTestInvoices(t *testing.T) (inst1Invoice, inst2Invoice *pgmodels.Invoice) {
+	month := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	_, err := pgmodels.GenerateInvoices(month)
+	require.Nil(t, err)
+	inst1Invoice, err = pgmodels.InvoiceByNumber(pgmodels.InvoiceNumberFor(testutil.Inst1Admin.InstitutionID, month))
+	require.Nil(t, err)
+	inst2Invoice, err = pgmodels.InvoiceByNumber(pgmodels.InvoiceNumberFor(testutil.Inst2Admin.InstitutionID, month))
+	require.Nil(t, err)
+	return inst1Invoice, inst2Invoice
+}
+
+func TestInvoiceIndex(t *testing.T) {
+	testutil.InitHTTPTests(t)
+	inst1Invoice, inst2Invoice := generateTestInvoices(t)
+
+	html := testutil.SysAdminClient.GET("/invoices").
+		Expect().
+		Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, inst1Invoice.InvoiceNumber)
+	assert.Contains(t, html, inst2Invoice.InvoiceNumber)
+
+	html = testutil.Inst1AdminClient.GET("/invoices").
+		Expect().
+		Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, inst1Invoice.InvoiceNumber)
+	assert.NotContains(t, html, inst2Invoice.InvoiceNumber)
+
+	testutil.Inst1AdminClient.GET("/invoices").
+		WithQuery("institution_id", testutil.Inst2Admin.InstitutionID).
+		Expect().
+		Status(http.StatusForbidden)
+
+	// Regular users can't see invoices.
+	testutil.Inst1UserClient.GET("/invoices").
+		Expect().
+		Status(http.StatusForbidden)
+}
+
+func TestInvoiceShow(t *testing.T) {
+	testutil.InitHTTPTests(t)
+	inst1Invoice, inst2Invoice := generateTestInvoices(t)
+
+	html := testutil.Inst1AdminClient.GET("/invoices/show/{id}", inst1Invoice.ID).
+		Expect().
+		Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, inst1Invoice.InvoiceNumber)
+	assert.Contains(t, html, "January 2023")
+	assert.Contains(t, html, fmt.Sprintf("/invoices/download/%d?format=pdf", inst1Invoice.ID))
+
+	testutil.SysAdminClient.GET("/invoices/show/{id}", inst2Invoice.ID).
+		Expect().
+		Status(http.StatusOK)
+	testutil.Inst1AdminClient.GET("/invoices/show/{id}", inst2Invoice.ID).
+		Expect().
+		Status(http.StatusForbidden)
+	testutil.Inst1UserClient.GET("/invoices/show/{id}", inst1Invoice.ID).
+		Expect().
+		Status(http.StatusForbidden)
+}
+
+func TestInvoiceDownload(t *testing.T) {
+	testutil.InitHTTPTests(t)
+	inst1Invoice, inst2Invoice := generateTestInvoices(t)
+
+	resp := testutil.Inst1AdminClient.GET("/invoices/download/{id}", inst1Invoice.ID).
+		Expect().
+		Status(http.StatusOK)
+	resp.Header("Content-Type").Equal("application/pdf")
+	resp.Header("Content-Disposition").Contains(inst1Invoice.InvoiceNumber + ".pdf")
+	assert.Contains(t, resp.Body().Raw(), "%PDF-1.4")
+
+	resp = testutil.Inst1AdminClient.GET("/invoices/download/{id}", inst1Invoice.ID).
+		WithQuery("format", "csv").
+		Expect().
+		Status(http.StatusOK)
+	resp.Header("Content-Type").Contains("text/csv")
+	assert.Contains(t, resp.Body().Raw(), inst1Invoice.InvoiceNumber)
+
+	testutil.Inst1AdminClient.GET("/invoices/download/{id}", inst2Invoice.ID).
+		Expect().
+		Status(http.StatusForbidden)
+}
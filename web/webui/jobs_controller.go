@@ -0,0 +1,64 @@
+package webui
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/APTrust/registry/helpers"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/APTrust/registry/scheduler"
+	"github.com/gin-gonic/gin"
+)
+
+// JobSummary describes a scheduled job for the jobs page.
+type JobSummary struct {
+	Job     *scheduler.Job
+	NextRun time.Time
+	LastRun *pgmodels.JobRun
+}
+
+// JobIndex shows all scheduled jobs, with their next and last runs,
+// and a history of recent runs.
+//
+// GET /jobs
+func JobIndex(c *gin.Context) {
+	req := NewRequest(c)
+	now := time.Now().UTC()
+	jobs := scheduler.Default().Jobs()
+	summaries := make([]*JobSummary, len(jobs))
+	for i, job := range jobs {
+		lastRun, err := pgmodels.LastJobRun(job.Name, false)
+		if AbortIfError(c, err) {
+			return
+		}
+		summaries[i] = &JobSummary{
+			Job:     job,
+			NextRun: job.NextRun(now),
+			LastRun: lastRun,
+		}
+	}
+	req.TemplateData["jobs"] = summaries
+
+	query := pgmodels.NewQuery().OrderBy("started_at", "desc").Limit(50)
+	runs, err := pgmodels.JobRunSelect(query)
+	if AbortIfError(c, err) {
+		return
+	}
+	req.TemplateData["runs"] = runs
+
+	c.HTML(http.StatusOK, "jobs/index.html", req.TemplateData)
+}
+
+// JobRunNow runs the named job immediately, in the background.
+//
+// POST /jobs/run/:name
+func JobRunNow(c *gin.Context) {
+	name := c.Param("name")
+	err := scheduler.Default().RunNow(name)
+	if AbortIfError(c, err) {
+		return
+	}
+	helpers.SetFlashCookie(c, fmt.Sprintf("Started %s. Reload this page to see its status.", name))
+	c.Redirect(http.StatusSeeOther, "/jobs")
+}
@@ -0,0 +1,59 @@
+package webui_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/web/testutil"
+)
+
+func TestJobIndex(t *testing.T) {
+	testutil.InitHTTPTests(t)
+	for _, client := range testutil.AllClients {
+		if client == testutil.SysAdminClient {
+			html := client.GET("/jobs").Expect().Status(http.StatusOK).Body().Raw()
+			testutil.AssertMatchesAll(t, html, []string{
+				"update_counts",
+				"update_current_deposit_stats",
+				"update_format_risk_stats",
+				"populate_all_historical_deposit_stats",
+				"apply_storage_prices",
+				"restoration_spot_tests",
+				"stalled_work_item_alerts",
+				"webhook_deliveries",
+				"daily_alert_digests",
+				"weekly_alert_digests",
+				"saved_search_notifications",
+				"record_object_versions",
+			})
+		} else {
+			client.GET("/jobs").Expect().Status(http.StatusForbidden)
+		}
+	}
+}
+
+func TestJobRunNow(t *testing.T) {
+	testutil.InitHTTPTests(t)
+
+	testutil.SysAdminClient.POST("/jobs/run/update_counts").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.SysAdminToken).
+		Expect().
+		Status(http.StatusOK)
+
+	testutil.SysAdminClient.POST("/jobs/run/no_such_job").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.SysAdminToken).
+		Expect().
+		Status(http.StatusNotFound)
+
+	for _, client := range testutil.AllClients {
+		if client != testutil.SysAdminClient {
+			client.POST("/jobs/run/update_counts").
+				WithHeader("Referer", testutil.BaseURL).
+				WithFormField(constants.CSRFTokenName, testutil.TokenFor[client]).
+				Expect().Status(http.StatusForbidden)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package webui
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/helpers"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/gin-gonic/gin"
+)
+
+// errIPThrottled means the client's IP address has too many recent
+// failed attempts. Clients see this as common.ErrTooManyAttempts.
+// See signInClientError.
+var errIPThrottled = errors.New("too many failed sign-in attempts from this IP address")
+
+// checkIPThrottle returns errIPThrottled if there have been
+// too many recent failed sign-in or two-factor attempts from the
+// client's IP address. The failure counts come from the audit log.
+//
+// Many users may share an IP address behind a campus NAT, so an IP gets
+// as many free attempts as a single account before delays begin, and
+// it's blocked outright only after MaxFailuresPerIP failures.
+func checkIPThrottle(c *gin.Context) error {
+	lockout := common.Context().Config.Lockout
+	if lockout.MaxFailuresPerIP < 1 {
+		return nil
+	}
+	now := time.Now().UTC()
+	count, latest, err := pgmodels.AuditFailuresFromIP(c.ClientIP(), now.Add(-lockout.IPWindow))
+	if err != nil {
+		common.Context().Log.Error().Msgf("Could not count failed sign-ins from %s: %v", c.ClientIP(), err)
+		return nil
+	}
+	if count >= lockout.MaxFailuresPerIP {
+		common.Context().Log.Warn().Msgf("Blocking sign-in from %s after %d failures in %s", c.ClientIP(), count, lockout.IPWindow)
+		return errIPThrottled
+	}
+	delay := lockout.Delay(count-lockout.MaxFailures+1, lockout.IPWindow)
+	if delay > 0 && now.Before(latest.Add(delay)) {
+		return errIPThrottled
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failed password or two-factor attempt
+// against the user's account. If that locks the account, we log it in
+// the audit log and alert the account owner.
+func recordLoginFailure(req *Request, user *pgmodels.User) {
+	log := common.Context().Log
+	locked, err := user.RecordFailedLogin()
+	if err != nil {
+		log.Error().Msgf("Could not record failed sign-in for %s: %v", user.Email, err)
+		return
+	}
+	if !locked {
+		return
+	}
+	detail := fmt.Sprintf("Locked until %s after %d failed attempts.", user.LockedUntil.Format(time.RFC3339), user.FailedLoginAttempts)
+	helpers.Audit(req.GinContext, constants.AuditAccountLocked, constants.OutcomeSuccess, user, detail)
+	_, err = CreateAccountLockedAlert(req, user)
+	if err != nil {
+		log.Error().Msgf("Could not create account locked alert for %s: %v", user.Email, err)
+	}
+}
+
+// isSignInBlocked returns true if err means we rejected a sign-in
+// without checking the password, because the account is locked or
+// the client is throttled.
+func isSignInBlocked(err error) bool {
+	return err == errIPThrottled || err == common.ErrTooManyAttempts || err == common.ErrAccountLocked
+}
+
+// signInErrorStatus returns the HTTP status code for a failed sign-in.
+// Only the IP address throttle gets its own status. It says nothing
+// about any one account, and it tells users behind a busy NAT why
+// they have to wait.
+func signInErrorStatus(err error) int {
+	if err == errIPThrottled {
+		return http.StatusTooManyRequests
+	}
+	return http.StatusBadRequest
+}
+
+// signInClientError returns the error to show a user whose password
+// sign-in failed. A locked or throttled account gets the same message
+// as a wrong password, so no one can learn which accounts exist or
+// are locked by guessing. The audit log records the real reason, and
+// the account owner gets an alert when their account is locked.
+func signInClientError(err error) error {
+	if err == common.ErrAccountLocked || err == common.ErrTooManyAttempts {
+		return common.ErrInvalidLogin
+	}
+	if err == errIPThrottled {
+		return common.ErrTooManyAttempts
+	}
+	return err
+}
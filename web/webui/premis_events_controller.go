@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/APTrust/registry/forms"
+	"github.com/APTrust/registry/helpers"
 	"github.com/APTrust/registry/pgmodels"
 	"github.com/gin-gonic/gin"
 )
@@ -27,10 +28,15 @@ func PremisEventShowXHR(c *gin.Context) {
 
 // PremisEventIndex shows list of objects.
 // GET /events
+// GET /events?format=csv|jsonl
 func PremisEventIndex(c *gin.Context) {
 	req := NewRequest(c)
 	template := "events/index.html"
 	var events []*pgmodels.PremisEventView
+	if format := helpers.ExportFormat(c.Request); format != "" {
+		AbortIfError(c, req.ExportResourceList(&events, "date_time", "desc", format))
+		return
+	}
 	err := req.LoadResourceList(&events, "date_time", "desc", forms.NewPremisEventFilterForm)
 	if AbortIfError(c, err) {
 		return
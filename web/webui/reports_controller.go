@@ -5,8 +5,11 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/APTrust/registry/common"
 	"github.com/APTrust/registry/forms"
+	"github.com/APTrust/registry/helpers"
 	"github.com/APTrust/registry/pgmodels"
+	"github.com/APTrust/registry/web/api"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/stew/slice"
 )
@@ -157,3 +160,161 @@ func getDepositReportParams(c *gin.Context) DepositReportParams {
 		EndDate:       endDate,
 	}
 }
+
+// FixityReportShow shows how many of each institution's active files
+// in each storage option were checked for fixity in the last 30, 60
+// and 90 days, and how many are overdue.
+//
+// GET /reports/fixity
+func FixityReportShow(c *gin.Context) {
+	req := NewRequest(c)
+	institutionID, storageOption := getFixityReportParams(req)
+	stats, err := pgmodels.FixityStatsSelect(institutionID, storageOption)
+	if AbortIfError(c, err) {
+		return
+	}
+	filterForm, err := forms.NewFixityReportFilterForm(req.GetFilterCollection(), req.CurrentUser)
+	if AbortIfError(c, err) {
+		return
+	}
+	req.TemplateData["stats"] = stats
+	req.TemplateData["filterForm"] = filterForm
+	req.TemplateData["overdueDays"] = common.Context().Config.Fixity.OverdueDays
+	c.HTML(http.StatusOK, "reports/fixity.html", req.TemplateData)
+}
+
+// FixityOverdueReportShow lists active files whose last fixity check
+// is overdue, oldest first. This is the drill-down from the fixity
+// report.
+//
+// GET /reports/fixity_overdue
+// GET /reports/fixity_overdue?format=csv|jsonl
+func FixityOverdueReportShow(c *gin.Context) {
+	req := NewRequest(c)
+	institutionID, storageOption := getFixityReportParams(req)
+	query := pgmodels.FixityOverdueQuery(institutionID, storageOption)
+	var files []*pgmodels.GenericFileView
+	if format := helpers.ExportFormat(c.Request); format != "" {
+		AbortIfError(c, api.StreamExport(c, query, &files, format))
+		return
+	}
+	pager, err := common.NewPager(c, req.PathAndQuery, 20)
+	if AbortIfError(c, err) {
+		return
+	}
+	// Count from the table rather than the view, which computes
+	// checksums we don't need here.
+	count, err := query.Count((*pgmodels.GenericFile)(nil))
+	if AbortIfError(c, err) {
+		return
+	}
+	query.Offset(pager.QueryOffset).Limit(pager.PerPage)
+	err = query.Select(&files)
+	if AbortIfError(c, err) {
+		return
+	}
+	pager.SetCounts(count, len(files))
+	filterForm, err := forms.NewFixityReportFilterForm(req.GetFilterCollection(), req.CurrentUser)
+	if AbortIfError(c, err) {
+		return
+	}
+	req.TemplateData["items"] = files
+	req.TemplateData["pager"] = pager
+	req.TemplateData["filterForm"] = filterForm
+	req.TemplateData["overdueDays"] = common.Context().Config.Fixity.OverdueDays
+	c.HTML(http.StatusOK, "reports/fixity_overdue.html", req.TemplateData)
+}
+
+// getFixityReportParams returns the institution id and storage option
+// from the query string. Non-admins see only their own institution.
+func getFixityReportParams(req *Request) (int64, string) {
+	institutionID, _ := strconv.ParseInt(req.GinContext.Query("institution_id"), 10, 64)
+	if !req.CurrentUser.IsAdmin() {
+		institutionID = req.CurrentUser.InstitutionID
+	}
+	return institutionID, req.GinContext.Query("storage_option")
+}
+
+// ReplicationReportShow lists the problems the most recent storage
+// replication check found with active files' storage records: missing
+// copies, duplicate copies, and copies in buckets that don't match the
+// file's storage option. The verify_storage_replication job rebuilds
+// this list every night.
+//
+// GET /reports/replication
+// GET /reports/replication?format=csv|jsonl
+func ReplicationReportShow(c *gin.Context) {
+	req := NewRequest(c)
+	var problems []*pgmodels.ReplicationProblem
+	if format := helpers.ExportFormat(c.Request); format != "" {
+		AbortIfError(c, req.ExportResourceList(&problems, "identifier", "asc", format))
+		return
+	}
+	err := req.LoadResourceList(&problems, "identifier", "asc", forms.NewReplicationReportFilterForm)
+	if AbortIfError(c, err) {
+		return
+	}
+	lastRun, err := pgmodels.LastJobRun("verify_storage_replication", true)
+	if AbortIfError(c, err) {
+		return
+	}
+	req.TemplateData["lastRun"] = lastRun
+	c.HTML(http.StatusOK, "reports/replication.html", req.TemplateData)
+}
+
+// FormatRiskReportShow shows how many active files, and how many bytes,
+// each institution has at each format risk level. Risk levels come from
+// the format registry. For a single institution, this also lists each
+// file format and its risk level.
+//
+// GET /reports/format_risk
+// GET /reports/format_risk?format=csv|jsonl
+func FormatRiskReportShow(c *gin.Context) {
+	req := NewRequest(c)
+	institutionID, _ := strconv.ParseInt(c.Query("institution_id"), 10, 64)
+	if !req.CurrentUser.IsAdmin() {
+		institutionID = req.CurrentUser.InstitutionID
+	}
+	stats, err := pgmodels.FormatRiskStatsSelect(institutionID)
+	if AbortIfError(c, err) {
+		return
+	}
+	if format := helpers.ExportFormat(c.Request); format != "" {
+		AbortIfError(c, api.WriteExport(c, stats, format))
+		return
+	}
+	if institutionID > 0 {
+		formatStats, err := pgmodels.FormatRiskStatsByFormat(institutionID)
+		if AbortIfError(c, err) {
+			return
+		}
+		req.TemplateData["formatStats"] = formatStats
+	}
+	filterForm, err := forms.NewFormatRiskReportFilterForm(req.GetFilterCollection(), req.CurrentUser)
+	if AbortIfError(c, err) {
+		return
+	}
+	req.TemplateData["stats"] = stats
+	req.TemplateData["filterForm"] = filterForm
+	c.HTML(http.StatusOK, "reports/format_risk.html", req.TemplateData)
+}
+
+// FormatRiskObjectsReportShow lists objects with active files at a
+// given risk level, largest first. This is the drill-down from the
+// format risk report.
+//
+// GET /reports/format_risk_objects
+// GET /reports/format_risk_objects?format=csv|jsonl
+func FormatRiskObjectsReportShow(c *gin.Context) {
+	req := NewRequest(c)
+	var objects []*pgmodels.FormatRiskObjectView
+	if format := helpers.ExportFormat(c.Request); format != "" {
+		AbortIfError(c, req.ExportResourceList(&objects, "total_bytes", "desc", format))
+		return
+	}
+	err := req.LoadResourceList(&objects, "total_bytes", "desc", forms.NewFormatRiskReportFilterForm)
+	if AbortIfError(c, err) {
+		return
+	}
+	c.HTML(http.StatusOK, "reports/format_risk_objects.html", req.TemplateData)
+}
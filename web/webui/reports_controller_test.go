@@ -6,7 +6,11 @@ import (
 	"time"
 
 	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/pgmodels"
 	"github.com/APTrust/registry/web/testutil"
+	"github.com/gavv/httpexpect/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDepositReportShow(t *testing.T) {
@@ -71,3 +75,174 @@ func TestDepositReportShow(t *testing.T) {
 		Status(http.StatusOK).Body().Raw()
 	testutil.AssertMatchesAll(t, html, expectedForInst0)
 }
+
+func TestFixityReportShow(t *testing.T) {
+	testutil.InitHTTPTests(t)
+
+	// Fixture files haven't been checked since 2000,
+	// so they're all overdue.
+	for _, client := range []*httpexpect.Expect{testutil.Inst1UserClient, testutil.Inst1AdminClient} {
+		html := client.GET("/reports/fixity").
+			WithQuery("storage_option", constants.StorageOptionStandard).
+			Expect().
+			Status(http.StatusOK).Body().Raw()
+		testutil.AssertMatchesAll(t, html, []string{
+			"Institution One</td>",
+			"Standard</td>",
+			"/reports/fixity_overdue?institution_id=2",
+		})
+		assert.NotContains(t, html, "Institution Two</td>")
+	}
+
+	// Non-admins can't see other institutions' stats.
+	testutil.Inst1AdminClient.GET("/reports/fixity").
+		WithQuery("institution_id", testutil.Inst2Admin.InstitutionID).
+		Expect().
+		Status(http.StatusForbidden)
+
+	// SysAdmin sees all institutions.
+	html := testutil.SysAdminClient.GET("/reports/fixity").
+		Expect().
+		Status(http.StatusOK).Body().Raw()
+	testutil.AssertMatchesAll(t, html, []string{
+		"Institution One</td>",
+		"Institution Two</td>",
+	})
+}
+
+func TestFixityOverdueReportShow(t *testing.T) {
+	testutil.InitHTTPTests(t)
+
+	html := testutil.Inst1AdminClient.GET("/reports/fixity_overdue").
+		WithQuery("storage_option", constants.StorageOptionStandard).
+		Expect().
+		Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "Overdue Fixity Checks")
+	assert.Contains(t, html, "institution1.edu/photos/picture1")
+	assert.NotContains(t, html, "institution2.edu/")
+
+	testutil.Inst1AdminClient.GET("/reports/fixity_overdue").
+		WithQuery("institution_id", testutil.Inst2Admin.InstitutionID).
+		Expect().
+		Status(http.StatusForbidden)
+
+	resp := testutil.Inst1AdminClient.GET("/reports/fixity_overdue").
+		WithQuery("storage_option", constants.StorageOptionStandard).
+		WithQuery("format", "csv").
+		Expect().
+		Status(http.StatusOK)
+	resp.Header("Content-Type").Contains("text/csv")
+	csv := resp.Body().Raw()
+	assert.Contains(t, csv, "last_fixity_check")
+	assert.Contains(t, csv, "institution1.edu/photos/picture1")
+	assert.NotContains(t, csv, "institution2.edu/")
+}
+
+func TestReplicationReportShow(t *testing.T) {
+	testutil.InitHTTPTests(t)
+	_, err := pgmodels.VerifyStorageReplication()
+	require.Nil(t, err)
+
+	html := testutil.Inst1UserClient.GET("/reports/replication").
+		Expect().
+		Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "Storage Replication")
+	assert.Contains(t, html, "institution1.edu/gl-or/file1.epub")
+	assert.Contains(t, html, constants.ReplicationUnexpectedCopy)
+	assert.NotContains(t, html, "institution2.edu/")
+
+	html = testutil.SysAdminClient.GET("/reports/replication").
+		WithQuery("institution_id", testutil.Inst2Admin.InstitutionID).
+		WithQuery("problem", constants.ReplicationMissingCopy).
+		Expect().
+		Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "institution2.edu/toads/toad5")
+	assert.NotContains(t, html, "institution1.edu/")
+
+	testutil.Inst1UserClient.GET("/reports/replication").
+		WithQuery("institution_id", testutil.Inst2Admin.InstitutionID).
+		Expect().
+		Status(http.StatusForbidden)
+
+	resp := testutil.Inst1AdminClient.GET("/reports/replication").
+		WithQuery("format", "csv").
+		Expect().
+		Status(http.StatusOK)
+	resp.Header("Content-Type").Contains("text/csv")
+	csv := resp.Body().Raw()
+	assert.Contains(t, csv, "storage_option")
+	assert.Contains(t, csv, "institution1.edu/gl-or/file2.azw")
+	assert.NotContains(t, csv, "institution2.edu/")
+}
+
+func TestFormatRiskReportShow(t *testing.T) {
+	testutil.InitHTTPTests(t)
+
+	html := testutil.Inst1UserClient.GET("/reports/format_risk").
+		Expect().
+		Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "Format Risk")
+	assert.Contains(t, html, "Institution One")
+	assert.Contains(t, html, "Obsolete")
+	assert.Contains(t, html, "text/sgml")
+	assert.NotContains(t, html, "Institution Two")
+	assert.NotContains(t, html, "/format_risks")
+
+	html = testutil.SysAdminClient.GET("/reports/format_risk").
+		Expect().
+		Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "Institution One")
+	assert.Contains(t, html, "Institution Two")
+	assert.Contains(t, html, "/format_risks")
+
+	testutil.Inst1UserClient.GET("/reports/format_risk").
+		WithQuery("institution_id", testutil.Inst2Admin.InstitutionID).
+		Expect().
+		Status(http.StatusForbidden)
+
+	resp := testutil.Inst1AdminClient.GET("/reports/format_risk").
+		WithQuery("format", "csv").
+		Expect().
+		Status(http.StatusOK)
+	resp.Header("Content-Type").Contains("text/csv")
+	csv := resp.Body().Raw()
+	assert.Contains(t, csv, "risk_level")
+	assert.Contains(t, csv, constants.FormatRiskProprietary)
+	assert.NotContains(t, csv, "Institution Two")
+}
+
+func TestFormatRiskObjectsReportShow(t *testing.T) {
+	testutil.InitHTTPTests(t)
+
+	html := testutil.Inst1UserClient.GET("/reports/format_risk_objects").
+		WithQuery("risk_level", constants.FormatRiskObsolete).
+		Expect().
+		Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "institution1.edu/glass")
+	assert.Contains(t, html, "institution1.edu/gl-dp-oh")
+	assert.NotContains(t, html, "institution1.edu/gl-or")
+
+	html = testutil.SysAdminClient.GET("/reports/format_risk_objects").
+		WithQuery("institution_id", testutil.Inst2Admin.InstitutionID).
+		WithQuery("risk_level", constants.FormatRiskUnknown).
+		Expect().
+		Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "application/binary")
+	assert.NotContains(t, html, "institution1.edu/")
+
+	testutil.Inst1UserClient.GET("/reports/format_risk_objects").
+		WithQuery("institution_id", testutil.Inst2Admin.InstitutionID).
+		Expect().
+		Status(http.StatusForbidden)
+
+	resp := testutil.Inst1AdminClient.GET("/reports/format_risk_objects").
+		WithQuery("risk_level", constants.FormatRiskProprietary).
+		WithQuery("format", "csv").
+		Expect().
+		Status(http.StatusOK)
+	resp.Header("Content-Type").Contains("text/csv")
+	csv := resp.Body().Raw()
+	assert.Contains(t, csv, "file_formats")
+	assert.Contains(t, csv, "video/x-msvideo")
+	assert.NotContains(t, csv, "text/sgml")
+}
@@ -3,6 +3,7 @@ package webui
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"reflect"
 	"strings"
 
@@ -12,6 +13,7 @@ import (
 	"github.com/APTrust/registry/helpers"
 	"github.com/APTrust/registry/middleware"
 	"github.com/APTrust/registry/pgmodels"
+	"github.com/APTrust/registry/web/api"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/stew/slice"
 )
@@ -51,6 +53,13 @@ func NewRequest(c *gin.Context) *Request {
 			constants.CSRFTokenName: csrfToken,
 		},
 	}
+	if currentUser != nil {
+		pinnedSearches, err := pgmodels.PinnedSearchesFor(currentUser.ID)
+		if err != nil {
+			ctx.Log.Error().Msgf("Error loading pinned searches for user %d: %v", currentUser.ID, err)
+		}
+		req.TemplateData["pinnedSearches"] = pinnedSearches
+	}
 	helpers.DeleteFlashCookie(c)
 	return req
 }
@@ -118,20 +127,10 @@ func (req *Request) LoadResourceList(items interface{}, orderByColumn, direction
 	}
 
 	filterCollection := req.GetFilterCollection()
-	query, err := filterCollection.ToQuery()
+	query, err := filterCollection.ToQueryFor(req.CurrentUser, items, orderByColumn, direction)
 	if err != nil {
 		return err
 	}
-	if !req.CurrentUser.IsAdmin() {
-		query.Where("institution_id", "=", req.CurrentUser.InstitutionID)
-		objType := reflect.ValueOf(items).Elem().Type()
-		if objType == reflect.TypeOf([]*pgmodels.AlertView{}) || objType == reflect.TypeOf([]*pgmodels.Alert{}) {
-			query.Where("user_id", "=", req.CurrentUser.ID)
-		}
-	}
-	if !filterCollection.HasExplicitSorting() {
-		query.OrderBy(orderByColumn, direction)
-	}
 	pager, err := common.NewPager(req.GinContext, req.PathAndQuery, 20)
 	if err != nil {
 		return err
@@ -161,5 +160,28 @@ func (req *Request) LoadResourceList(items interface{}, orderByColumn, direction
 	req.TemplateData["pager"] = pager
 	req.TemplateData["filterForm"] = form
 
+	// Let users save the filters on index pages that support it.
+	if _, ok := pgmodels.SavedSearchPaths[req.Auth.ResourceType]; ok && req.CurrentUser.HasPermission(constants.SavedSearchCreate, req.CurrentUser.InstitutionID) {
+		req.TemplateData["saveSearchURL"] = fmt.Sprintf("/saved_searches/new?resource_type=%s&query=%s",
+			req.Auth.ResourceType, url.QueryEscape(req.GinContext.Request.URL.RawQuery))
+	}
+
 	return err
 }
+
+// ExportResourceList streams all resources matching the request's
+// filters to the browser as a CSV or JSON-lines download, for the
+// Download buttons on index pages. It applies the same filters,
+// sorting and institution restrictions as LoadResourceList, but it
+// ignores paging.
+func (req *Request) ExportResourceList(items interface{}, orderByColumn, direction, format string) error {
+	if items == nil || !strings.HasPrefix(reflect.TypeOf(items).String(), "*[]*pgmodels.") {
+		common.Context().Log.Error().Msgf("Request.ExportResourceList: Param items should be pointer to slice of pointers.")
+		return common.ErrInvalidParam
+	}
+	query, err := req.GetFilterCollection().ToQueryFor(req.CurrentUser, items, orderByColumn, direction)
+	if err != nil {
+		return err
+	}
+	return api.StreamExport(req.GinContext, query, items, format)
+}
@@ -0,0 +1,123 @@
+package webui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/pgmodels"
+)
+
+// MaxBulkRestorationItems is the maximum number of objects a user can
+// include in a single restoration batch.
+const MaxBulkRestorationItems = 1000
+
+// InitRestorationBatch creates a restoration WorkItem for each of the
+// objects with the specified identifiers, links them all to a new
+// RestorationBatch, and queues them. Each WorkItem goes into the object
+// restoration or Glacier restoration topic, depending on where the
+// object is stored.
+//
+// Every object must belong to the user's institution, must be active,
+// and must have no pending WorkItems. If any object fails these checks,
+// this creates nothing. Instead, it returns a map of identifier -> problem,
+// so the user can fix the list and try again.
+func InitRestorationBatch(identifiers []string, user *pgmodels.User) (*pgmodels.RestorationBatch, map[string]string, error) {
+	identifiers = uniqueIdentifiers(identifiers)
+	if len(identifiers) == 0 || len(identifiers) > MaxBulkRestorationItems {
+		return nil, nil, common.ErrInvalidParam
+	}
+
+	problems := make(map[string]string)
+	objects := make([]*pgmodels.IntellectualObject, 0, len(identifiers))
+	for _, identifier := range identifiers {
+		obj, err := pgmodels.IntellectualObjectByIdentifier(identifier)
+		if pgmodels.IsNoRowError(err) || (err == nil && obj.InstitutionID != user.InstitutionID) {
+			problems[identifier] = "Object not found."
+			continue
+		} else if err != nil {
+			return nil, nil, err
+		}
+		if obj.State != constants.StateActive {
+			problems[identifier] = "Object has been deleted."
+			continue
+		}
+		pendingWorkItems, err := pgmodels.WorkItemsPendingForObject(obj.InstitutionID, obj.BagName)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(pendingWorkItems) > 0 {
+			problems[identifier] = common.ErrPendingWorkItems.Error()
+			continue
+		}
+		objects = append(objects, obj)
+	}
+	if len(problems) > 0 {
+		return nil, problems, nil
+	}
+
+	// The batch saves its new WorkItems and links them in a single
+	// transaction, so a failure here leaves nothing behind.
+	batch := pgmodels.NewRestorationBatch(user)
+	for _, obj := range objects {
+		workItem, err := pgmodels.RestorationItemFor(obj, nil, user)
+		if err != nil {
+			return nil, nil, err
+		}
+		batch.AddWorkItem(workItem)
+	}
+	err := batch.Save()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Queue items only after the batch is saved, so the batch can
+	// track every item from the start. If queueing fails, cancel
+	// the items we couldn't queue, so they don't sit in the batch
+	// as pending forever.
+	for i, workItem := range batch.WorkItems {
+		err = queueWorkItem(workItem)
+		if err != nil {
+			cancelUnqueuedItems(batch.WorkItems[i:], "Restoration", err)
+			return batch, nil, err
+		}
+	}
+	return batch, nil, nil
+}
+
+// cancelUnqueuedItems marks WorkItems that we couldn't queue as
+// cancelled, noting the reason. Items that made it into the queue
+// have a QueuedAt time, and are left alone. operation describes the
+// items in the note, e.g. "Restoration" or "Deletion".
+func cancelUnqueuedItems(workItems []*pgmodels.WorkItem, operation string, queueErr error) {
+	ctx := common.Context()
+	for _, workItem := range workItems {
+		if !workItem.QueuedAt.IsZero() {
+			continue
+		}
+		workItem.Status = constants.StatusCancelled
+		workItem.Note = fmt.Sprintf("%s cancelled because the item could not be queued: %v", operation, queueErr)
+		workItem.Retry = false
+		err := workItem.Save()
+		if err != nil {
+			ctx.Log.Error().Msgf("Error cancelling unqueued WorkItem %d: %v", workItem.ID, err)
+		}
+	}
+}
+
+// queueWorkItem sends a WorkItem into the NSQ topic for its action
+// and records the time it was queued.
+func queueWorkItem(workItem *pgmodels.WorkItem) error {
+	topic, err := constants.TopicFor(workItem.Action, workItem.Stage)
+	if err != nil {
+		return err
+	}
+	ctx := common.Context()
+	err = ctx.NSQClient.Enqueue(topic, workItem.ID)
+	if err != nil {
+		return err
+	}
+	workItem.QueuedAt = time.Now().UTC()
+	return workItem.Save()
+}
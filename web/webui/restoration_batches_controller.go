@@ -0,0 +1,44 @@
+package webui
+
+import (
+	"net/http"
+
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/gin-gonic/gin"
+)
+
+// RestorationBatchIndex shows the most recent restoration batches.
+// Non-admins see only batches from their own institution.
+//
+// GET /restoration_batches
+func RestorationBatchIndex(c *gin.Context) {
+	req := NewRequest(c)
+	query := pgmodels.NewQuery().
+		Relations("RequestedBy", "WorkItems").
+		OrderBy(`"restoration_batch"."created_at"`, "desc").
+		Limit(50)
+	if !req.CurrentUser.IsAdmin() {
+		query.Where(`"restoration_batch"."institution_id"`, "=", req.CurrentUser.InstitutionID)
+	}
+	batches, err := pgmodels.RestorationBatchSelect(query)
+	if AbortIfError(c, err) {
+		return
+	}
+	req.TemplateData["batches"] = batches
+	c.HTML(http.StatusOK, "restoration_batches/index.html", req.TemplateData)
+}
+
+// RestorationBatchShow shows the progress of a restoration batch,
+// with the status of each of its WorkItems.
+//
+// GET /restoration_batches/show/:id
+func RestorationBatchShow(c *gin.Context) {
+	req := NewRequest(c)
+	batch, err := pgmodels.RestorationBatchByID(req.Auth.ResourceID)
+	if AbortIfError(c, err) {
+		return
+	}
+	req.TemplateData["batch"] = batch
+	req.TemplateData["progress"] = batch.Progress()
+	c.HTML(http.StatusOK, "restoration_batches/show.html", req.TemplateData)
+}
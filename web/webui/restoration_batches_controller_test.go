@@ -0,0 +1,45 @@
+package webui_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/APTrust/registry/web/testutil"
+	"github.com/APTrust/registry/web/webui"
+	"github.com/gavv/httpexpect/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestorationBatchIndexAndShow(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+	testutil.InitHTTPTests(t)
+
+	user, err := pgmodels.UserByEmail("user@inst1.edu")
+	require.Nil(t, err)
+	batch, problems, err := webui.InitRestorationBatch([]string{"institution1.edu/photos", "institution1.edu/pdfs"}, user)
+	require.Nil(t, err)
+	require.Empty(t, problems)
+
+	for _, client := range []*httpexpect.Expect{testutil.SysAdminClient, testutil.Inst1AdminClient, testutil.Inst1UserClient} {
+		html := client.GET("/restoration_batches").Expect().Status(http.StatusOK).Body().Raw()
+		testutil.AssertMatchesAll(t, html, []string{"Restoration Batches", fmt.Sprintf("/restoration_batches/show/%d", batch.ID)})
+
+		html = client.GET("/restoration_batches/show/{id}", batch.ID).Expect().Status(http.StatusOK).Body().Raw()
+		testutil.AssertMatchesAll(t, html, []string{
+			fmt.Sprintf("Restoration Batch #%d", batch.ID),
+			"photos.tar",
+			"pdfs.tar",
+			"0 of 2 complete",
+		})
+	}
+
+	// Users at other institutions can't see this batch.
+	testutil.Inst2AdminClient.GET("/restoration_batches/show/{id}", batch.ID).
+		Expect().Status(http.StatusForbidden)
+	html := testutil.Inst2AdminClient.GET("/restoration_batches").Expect().Status(http.StatusOK).Body().Raw()
+	require.NotContains(t, html, fmt.Sprintf("/restoration_batches/show/%d", batch.ID))
+}
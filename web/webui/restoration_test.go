@@ -0,0 +1,62 @@
+package webui_test
+
+import (
+	"testing"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/APTrust/registry/web/webui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitRestorationBatch(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+	user, err := pgmodels.UserByEmail("user@inst1.edu")
+	require.Nil(t, err)
+
+	_, _, err = webui.InitRestorationBatch([]string{"", " "}, user)
+	assert.Equal(t, common.ErrInvalidParam, err)
+
+	// Glass has a pending WorkItem, chocolate belongs to another
+	// institution, and the last one doesn't exist.
+	identifiers := []string{
+		"institution1.edu/photos",
+		"institution1.edu/glass",
+		"institution2.edu/chocolate",
+		"institution1.edu/does-not-exist",
+	}
+	batch, problems, err := webui.InitRestorationBatch(identifiers, user)
+	require.Nil(t, err)
+	assert.Nil(t, batch)
+	assert.Equal(t, 3, len(problems))
+	assert.Equal(t, common.ErrPendingWorkItems.Error(), problems["institution1.edu/glass"])
+	assert.Equal(t, "Object not found.", problems["institution2.edu/chocolate"])
+	assert.Equal(t, "Object not found.", problems["institution1.edu/does-not-exist"])
+
+	identifiers = []string{"institution1.edu/photos", "institution1.edu/pdfs", "institution1.edu/photos"}
+	batch, problems, err = webui.InitRestorationBatch(identifiers, user)
+	require.Nil(t, err)
+	assert.Empty(t, problems)
+	require.NotNil(t, batch)
+	assert.True(t, batch.ID > 0)
+	require.Equal(t, 2, len(batch.WorkItems))
+	for _, item := range batch.WorkItems {
+		assert.True(t, item.ID > 0)
+		assert.False(t, item.QueuedAt.IsZero())
+		assert.Contains(t, []string{constants.ActionRestoreObject, constants.ActionGlacierRestore}, item.Action)
+	}
+
+	reloaded, err := pgmodels.RestorationBatchByID(batch.ID)
+	require.Nil(t, err)
+	assert.Equal(t, 2, len(reloaded.WorkItems))
+
+	// Now that these objects have pending restorations,
+	// we can't restore them again until they're done.
+	_, problems, err = webui.InitRestorationBatch(identifiers, user)
+	require.Nil(t, err)
+	assert.Equal(t, 2, len(problems))
+}
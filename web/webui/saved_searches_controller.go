@@ -0,0 +1,149 @@
+package webui
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/forms"
+	"github.com/APTrust/registry/helpers"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/gin-gonic/gin"
+)
+
+// SavedSearchIndex lists the current user's saved searches, along
+// with searches other users at their institution have shared.
+//
+// GET /saved_searches
+func SavedSearchIndex(c *gin.Context) {
+	req := NewRequest(c)
+	searches, err := pgmodels.SavedSearchesVisibleTo(req.CurrentUser)
+	if AbortIfError(c, err) {
+		return
+	}
+	req.TemplateData["searches"] = searches
+	c.HTML(http.StatusOK, "saved_searches/index.html", req.TemplateData)
+}
+
+// SavedSearchNew shows a form for saving the filters on an index page.
+// The Save Search button on the index page fills in resource_type and
+// query, which is the page's query string.
+//
+// GET /saved_searches/new?resource_type=:type&query=:query
+func SavedSearchNew(c *gin.Context) {
+	req := NewRequest(c)
+	search := pgmodels.NewSavedSearch(req.CurrentUser, c.Query("resource_type"), c.Query("query"))
+	form := forms.NewSavedSearchForm(search)
+	req.TemplateData["form"] = form
+	req.TemplateData["search"] = search
+	c.HTML(http.StatusOK, form.Template, req.TemplateData)
+}
+
+// SavedSearchCreate saves a new search for the current user.
+//
+// POST /saved_searches/new
+func SavedSearchCreate(c *gin.Context) {
+	req := NewRequest(c)
+	search := pgmodels.NewSavedSearch(req.CurrentUser, c.PostForm("ResourceType"), c.PostForm("QueryString"))
+	saveSavedSearchForm(c, req, search)
+}
+
+// SavedSearchShow runs a saved search by redirecting to the index
+// page with the search's filters. Users can run their own searches
+// and searches shared at their institution.
+//
+// GET /saved_searches/show/:id
+func SavedSearchShow(c *gin.Context) {
+	req := NewRequest(c)
+	search, err := pgmodels.SavedSearchByID(req.Auth.ResourceID)
+	if AbortIfError(c, err) {
+		return
+	}
+	if !search.IsVisibleTo(req.CurrentUser) {
+		common.Context().Log.Warn().Msgf("Permission denied: User %d tried to run saved search %d belonging to user %d", req.CurrentUser.ID, search.ID, search.UserID)
+		AbortIfError(c, common.ErrPermissionDenied)
+		return
+	}
+	c.Redirect(http.StatusFound, search.URL())
+}
+
+// SavedSearchEdit shows a form for renaming a saved search and
+// changing its settings. Users can edit only their own searches.
+//
+// GET /saved_searches/edit/:id
+func SavedSearchEdit(c *gin.Context) {
+	req := NewRequest(c)
+	search, err := savedSearchForOwner(req)
+	if AbortIfError(c, err) {
+		return
+	}
+	form := forms.NewSavedSearchForm(search)
+	req.TemplateData["form"] = form
+	req.TemplateData["search"] = search
+	c.HTML(http.StatusOK, form.Template, req.TemplateData)
+}
+
+// SavedSearchUpdate saves changes to a saved search.
+//
+// PUT or POST /saved_searches/edit/:id
+func SavedSearchUpdate(c *gin.Context) {
+	req := NewRequest(c)
+	search, err := savedSearchForOwner(req)
+	if AbortIfError(c, err) {
+		return
+	}
+	saveSavedSearchForm(c, req, search)
+}
+
+// SavedSearchDelete deletes a saved search. Users can delete only
+// their own searches.
+//
+// DELETE or POST /saved_searches/delete/:id
+func SavedSearchDelete(c *gin.Context) {
+	req := NewRequest(c)
+	search, err := savedSearchForOwner(req)
+	if AbortIfError(c, err) {
+		return
+	}
+	err = search.Delete()
+	if AbortIfError(c, err) {
+		return
+	}
+	helpers.SetFlashCookie(c, fmt.Sprintf("Deleted saved search %s", search.Name))
+	c.Redirect(http.StatusSeeOther, "/saved_searches")
+}
+
+// savedSearchForOwner returns the saved search in the request, or
+// common.ErrPermissionDenied if it doesn't belong to the current
+// user. The auth middleware only checks that the search belongs to
+// the user's institution.
+func savedSearchForOwner(req *Request) (*pgmodels.SavedSearch, error) {
+	search, err := pgmodels.SavedSearchByID(req.Auth.ResourceID)
+	if err != nil {
+		return nil, err
+	}
+	if search.UserID != req.CurrentUser.ID {
+		common.Context().Log.Warn().Msgf("Permission denied: User %d tried to change saved search %d belonging to user %d", req.CurrentUser.ID, search.ID, search.UserID)
+		return nil, common.ErrPermissionDenied
+	}
+	return search, nil
+}
+
+// saveSavedSearchForm copies submitted values into the search and saves
+// it. Users can't change a search's owner, institution or filters here.
+func saveSavedSearchForm(c *gin.Context, req *Request, search *pgmodels.SavedSearch) {
+	search.Name = c.PostForm("Name")
+	search.Pinned = c.PostForm("Pinned") == "true"
+	search.Shared = c.PostForm("Shared") == "true"
+	search.Notify = c.PostForm("Notify") == "true"
+	form := forms.NewSavedSearchForm(search)
+	req.TemplateData["form"] = form
+	req.TemplateData["search"] = search
+	if form.Save() {
+		helpers.SetFlashCookie(c, fmt.Sprintf("Saved search %s", search.Name))
+		c.Redirect(form.Status, form.PostSaveURL())
+	} else {
+		req.TemplateData["FormError"] = form.Error
+		c.HTML(form.Status, form.Template, req.TemplateData)
+	}
+}
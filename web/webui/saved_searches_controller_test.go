@@ -0,0 +1,111 @@
+package webui_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/APTrust/registry/web/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSavedSearchCRUD(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+	testutil.InitHTTPTests(t)
+
+	// Filtered index pages have a Save Search button.
+	html := testutil.Inst1UserClient.GET("/work_items").
+		WithQuery("action", "Ingest").
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "/saved_searches/new?resource_type=WorkItem")
+
+	html = testutil.Inst1UserClient.GET("/saved_searches/new").
+		WithQuery("resource_type", "WorkItem").
+		WithQuery("query", "action=Ingest&page=2").
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, `value="action=Ingest"`)
+
+	html = testutil.Inst1UserClient.POST("/saved_searches/new").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1UserToken).
+		WithFormField("Name", "Ingests").
+		WithFormField("ResourceType", "WorkItem").
+		WithFormField("QueryString", "action=Ingest").
+		WithFormField("Pinned", "true").
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "Ingests")
+
+	searches, err := pgmodels.PinnedSearchesFor(testutil.Inst1User.ID)
+	require.Nil(t, err)
+	require.Len(t, searches, 1)
+	search := searches[0]
+	assert.Equal(t, "action=Ingest", search.QueryString)
+	assert.False(t, search.Shared)
+
+	// Pinned searches appear in the side nav.
+	html = testutil.Inst1UserClient.GET("/dashboard").
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, fmt.Sprintf(`href="/saved_searches/show/%d"`, search.ID))
+
+	// Searches with no filters can't be saved.
+	testutil.Inst1UserClient.POST("/saved_searches/new").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1UserToken).
+		WithFormField("Name", "Everything").
+		WithFormField("ResourceType", "WorkItem").
+		WithFormField("QueryString", "page=2").
+		Expect().Status(http.StatusBadRequest)
+
+	// Unshared searches are private to their owner.
+	testutil.Inst1UserClient.GET("/saved_searches/show/{id}", search.ID).
+		Expect().Status(http.StatusOK)
+	testutil.Inst1AdminClient.GET("/saved_searches/show/{id}", search.ID).
+		Expect().Status(http.StatusForbidden)
+	testutil.Inst2UserClient.GET("/saved_searches/show/{id}", search.ID).
+		Expect().Status(http.StatusForbidden)
+
+	// Share it.
+	testutil.Inst1UserClient.POST("/saved_searches/edit/{id}", search.ID).
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1UserToken).
+		WithFormField("Name", "Ingests").
+		WithFormField("QueryString", "action=Delete").
+		WithFormField("Shared", "true").
+		Expect().Status(http.StatusOK)
+	search, err = pgmodels.SavedSearchByID(search.ID)
+	require.Nil(t, err)
+	assert.True(t, search.Shared)
+	assert.False(t, search.Pinned)
+	assert.Equal(t, "action=Ingest", search.QueryString)
+
+	// Now others at the institution can see and run it,
+	// but only the owner can change it.
+	html = testutil.Inst1AdminClient.GET("/saved_searches").
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "Ingests")
+	testutil.Inst1AdminClient.GET("/saved_searches/show/{id}", search.ID).
+		Expect().Status(http.StatusOK)
+	testutil.Inst1AdminClient.GET("/saved_searches/edit/{id}", search.ID).
+		Expect().Status(http.StatusForbidden)
+	testutil.Inst1AdminClient.POST("/saved_searches/delete/{id}", search.ID).
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1AdminToken).
+		Expect().Status(http.StatusForbidden)
+	testutil.Inst2UserClient.GET("/saved_searches/show/{id}", search.ID).
+		Expect().Status(http.StatusForbidden)
+	html = testutil.Inst2UserClient.GET("/saved_searches").
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.NotContains(t, html, "Ingests")
+
+	testutil.Inst1UserClient.POST("/saved_searches/delete/{id}", search.ID).
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1UserToken).
+		Expect().Status(http.StatusOK)
+	_, err = pgmodels.SavedSearchByID(search.ID)
+	assert.True(t, pgmodels.IsNoRowError(err))
+}
@@ -0,0 +1,41 @@
+package webui
+
+import (
+	"net/http"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/forms"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/APTrust/registry/web/api"
+	"github.com/gin-gonic/gin"
+)
+
+// SearchIndex shows the objects, files and events matching the search
+// box at the top of every page, best matches first.
+//
+// GET /search?q=photos
+func SearchIndex(c *gin.Context) {
+	req := NewRequest(c)
+	pager, err := common.NewPager(c, req.PathAndQuery, 20)
+	if AbortIfError(c, err) {
+		return
+	}
+	params, err := api.SearchParamsFor(c, req.Auth, pager)
+	if AbortIfError(c, err) {
+		return
+	}
+	results, count, err := pgmodels.Search(params)
+	if AbortIfError(c, err) {
+		return
+	}
+	pager.SetCounts(count, len(results))
+	filterForm, err := forms.NewSearchFilterForm(req.GetFilterCollection(), req.CurrentUser)
+	if AbortIfError(c, err) {
+		return
+	}
+	req.TemplateData["items"] = results
+	req.TemplateData["pager"] = pager
+	req.TemplateData["filterForm"] = filterForm
+	req.TemplateData["searchTerms"] = params.Terms
+	c.HTML(http.StatusOK, "search/index.html", req.TemplateData)
+}
@@ -0,0 +1,57 @@
+package webui_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/web/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchIndex(t *testing.T) {
+	testutil.InitHTTPTests(t)
+
+	// Everyone can search, and the top nav has a search box.
+	for _, client := range testutil.AllClients {
+		html := client.GET("/search").Expect().Status(http.StatusOK).Body().Raw()
+		assert.Contains(t, html, `action="/search"`)
+	}
+
+	// Sys Admin sees results from all institutions.
+	html := testutil.SysAdminClient.GET("/search").
+		WithQuery("q", "photos").
+		Expect().Status(http.StatusOK).Body().Raw()
+	testutil.AssertMatchesAll(t, html, []string{
+		"institution1.edu/photos</td>",
+		"institution1.edu/photos/picture1</td>",
+		"institution2.edu/deleted/photo.jpg</td>",
+		"First Object for Institution One</td>",
+	})
+
+	// Non-admins see only their own.
+	html = testutil.Inst1UserClient.GET("/search").
+		WithQuery("q", "photos").
+		WithQuery("type", constants.SearchTypeFile).
+		Expect().Status(http.StatusOK).Body().Raw()
+	testutil.AssertMatchesAll(t, html, []string{
+		"institution1.edu/photos/picture1</td>",
+		"institution1.edu/photos/picture2</td>",
+	})
+	assert.NotContains(t, html, "institution1.edu/photos</td>")
+	assert.NotContains(t, html, "institution2.edu/deleted/photo.jpg")
+
+	html = testutil.Inst2AdminClient.GET("/search").
+		WithQuery("q", "chocolate").
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "institution2.edu/chocolate</td>")
+	html = testutil.Inst1AdminClient.GET("/search").
+		WithQuery("q", "chocolate").
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "Nothing matched")
+
+	testutil.Inst1AdminClient.GET("/search").
+		WithQuery("q", "chocolate").
+		WithQuery("institution_id", testutil.Inst2Admin.InstitutionID).
+		Expect().Status(http.StatusForbidden)
+}
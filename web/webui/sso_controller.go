@@ -0,0 +1,279 @@
+package webui
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/helpers"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/gin-gonic/gin"
+)
+
+// SSOSessionTimeout is how long a user has to sign in at their
+// institution's identity provider after we send them there.
+const SSOSessionTimeout = 10 * time.Minute
+
+// ssoSession is what we store in the SSO cookie between sending the
+// user to their identity provider and handling the provider's response.
+// State must come back from the IdP unchanged. Nonce (OIDC) must appear
+// in the ID token, and RequestID (SAML) must match the response's
+// InResponseTo.
+type ssoSession struct {
+	IdentityProviderID int64
+	State              string
+	Nonce              string
+	RequestID          string
+	CreatedAt          time.Time
+}
+
+// UserSSOStart sends the user to the login page of the identity provider
+// for their institution, which we find from the domain of the email
+// address they entered.
+//
+// POST /users/sso
+func UserSSOStart(c *gin.Context) {
+	req := NewRequest(c)
+	if !common.Context().Config.SSO.Enabled {
+		renderSSOError(c, http.StatusBadRequest, common.ErrSSONotEnabled)
+		return
+	}
+	idp, err := pgmodels.IdentityProviderForEmail(c.PostForm("email"))
+	if err != nil {
+		renderSSOError(c, http.StatusBadRequest, err)
+		return
+	}
+	session := &ssoSession{
+		IdentityProviderID: idp.ID,
+		State:              common.RandomToken(),
+		CreatedAt:          time.Now().UTC(),
+	}
+	location := ""
+	if idp.Protocol == constants.SSOProtocolSAML {
+		sp, err := idp.SAMLServiceProvider(req.BaseURL())
+		if err == nil {
+			location, session.RequestID, err = common.SAMLAuthRequestURL(sp, session.State)
+		}
+		if err != nil {
+			logSSOError(idp, err)
+			renderSSOError(c, http.StatusInternalServerError, common.ErrSSOFailed)
+			return
+		}
+	} else {
+		client, err := idp.OIDCClient(c.Request.Context(), req.BaseURL())
+		if err != nil {
+			logSSOError(idp, err)
+			renderSSOError(c, http.StatusBadGateway, common.ErrSSOFailed)
+			return
+		}
+		session.Nonce = common.RandomToken()
+		location = client.AuthCodeURL(session.State, session.Nonce)
+	}
+	err = setSSOSession(c, session)
+	if err != nil {
+		renderSSOError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.Redirect(http.StatusFound, location)
+}
+
+// UserSSOOIDCCallback receives the authorization code from an OpenID
+// Connect provider. This is a cross-site request, so the browser won't
+// send our SameSite=Strict cookies with it. We render a page that posts
+// the code back to UserSSOComplete from our own site, which will
+// include the cookies.
+//
+// GET /users/sso/oidc/callback
+func UserSSOOIDCCallback(c *gin.Context) {
+	if c.Query("error") != "" {
+		common.Context().Log.Warn().Msgf("OIDC provider returned error %s: %s", c.Query("error"), c.Query("error_description"))
+		renderSSOError(c, http.StatusBadRequest, common.ErrSSOFailed)
+		return
+	}
+	c.HTML(http.StatusOK, "users/sso_continue.html", gin.H{
+		"fields": map[string]string{
+			"code":  c.Query("code"),
+			"state": c.Query("state"),
+		},
+	})
+}
+
+// UserSSOSAMLACS is our SAML assertion consumer service. It receives
+// the identity provider's response via the HTTP-POST binding. Like
+// UserSSOOIDCCallback, this is a cross-site request, so we post the
+// response back to UserSSOComplete from our own site.
+//
+// POST /users/sso/saml/acs
+func UserSSOSAMLACS(c *gin.Context) {
+	c.HTML(http.StatusOK, "users/sso_continue.html", gin.H{
+		"fields": map[string]string{
+			"SAMLResponse": c.PostForm("SAMLResponse"),
+			"RelayState":   c.PostForm("RelayState"),
+		},
+	})
+}
+
+// UserSSOComplete verifies the identity provider's response, matches
+// the identity it describes to a Registry user, and signs that user in.
+// Two-factor users still have to complete their second factor.
+//
+// POST /users/sso/complete
+func UserSSOComplete(c *gin.Context) {
+	req := NewRequest(c)
+	session, err := getSSOSession(c)
+	if err != nil {
+		renderSSOError(c, http.StatusBadRequest, common.ErrSSOFailed)
+		return
+	}
+	idp, err := pgmodels.IdentityProviderByID(session.IdentityProviderID)
+	if err != nil || !idp.Enabled {
+		renderSSOError(c, http.StatusBadRequest, common.ErrSSONotConfigured)
+		return
+	}
+	var identity *common.SSOIdentity
+	if idp.Protocol == constants.SSOProtocolSAML {
+		if !ssoStateMatches(session, c.PostForm("RelayState")) {
+			auditSSOFailure(c, idp, "", "State mismatch")
+			renderSSOError(c, http.StatusBadRequest, common.ErrSSOFailed)
+			return
+		}
+		sp, err := idp.SAMLServiceProvider(req.BaseURL())
+		if err == nil {
+			identity, err = common.SAMLIdentityFromResponse(sp, c.PostForm("SAMLResponse"), session.RequestID, idp.EmailAttribute)
+		}
+		if err != nil {
+			logSSOError(idp, err)
+			auditSSOFailure(c, idp, "", err.Error())
+			renderSSOError(c, http.StatusBadRequest, common.ErrSSOFailed)
+			return
+		}
+	} else {
+		if !ssoStateMatches(session, c.PostForm("state")) {
+			auditSSOFailure(c, idp, "", "State mismatch")
+			renderSSOError(c, http.StatusBadRequest, common.ErrSSOFailed)
+			return
+		}
+		client, err := idp.OIDCClient(c.Request.Context(), req.BaseURL())
+		if err == nil {
+			identity, err = client.Exchange(c.Request.Context(), c.PostForm("code"), session.Nonce, idp.EmailAttribute)
+		}
+		if err != nil {
+			logSSOError(idp, err)
+			auditSSOFailure(c, idp, "", err.Error())
+			renderSSOError(c, http.StatusBadRequest, common.ErrSSOFailed)
+			return
+		}
+	}
+	oldRole := ""
+	if existingUser, err := pgmodels.UserByEmail(identity.Email); err == nil {
+		oldRole = existingUser.Role
+	}
+	user, err := pgmodels.UserSSOSignIn(idp, identity, c.ClientIP())
+	if err != nil {
+		helpers.DeleteSessionCookie(c)
+		auditSSOFailure(c, idp, identity.Email, err.Error())
+		renderSSOError(c, http.StatusBadRequest, err)
+		return
+	}
+	if oldRole != "" && oldRole != user.Role {
+		detail := fmt.Sprintf("Role changed from %s to %s, based on identity provider attributes.", oldRole, user.Role)
+		helpers.Audit(c, constants.AuditRoleChanged, constants.OutcomeSuccess, user, detail)
+	}
+	status, redirectTo, err := startUserSession(c, user)
+	if err != nil {
+		renderSSOError(c, status, err)
+		return
+	}
+	helpers.Audit(c, constants.AuditSignIn, constants.OutcomeSuccess, user, fmt.Sprintf("Single sign-on (%s)", idp.Protocol))
+	c.Redirect(status, redirectTo)
+}
+
+// UserSSOSAMLMetadata returns our SAML service provider metadata.
+// Institutions give this to their IdP to register the Registry.
+//
+// GET /users/sso/saml/metadata
+func UserSSOSAMLMetadata(c *gin.Context) {
+	req := NewRequest(c)
+	sp, err := common.NewSAMLServiceProvider(req.BaseURL(), nil)
+	if AbortIfError(c, err) {
+		return
+	}
+	data, err := xml.MarshalIndent(sp.Metadata(), "", "  ")
+	if AbortIfError(c, err) {
+		return
+	}
+	c.Data(http.StatusOK, "application/samlmetadata+xml", data)
+}
+
+// renderSSOError shows err on the sign-in page.
+func renderSSOError(c *gin.Context, status int, err error) {
+	c.HTML(status, "users/sign_in.html", gin.H{
+		"error":      err.Error(),
+		"cover":      helpers.GetCover(),
+		"ssoEnabled": common.Context().Config.SSO.Enabled,
+	})
+}
+
+// logSSOError logs the details of a failed sign-in, which we don't
+// show to the user.
+func logSSOError(idp *pgmodels.IdentityProvider, err error) {
+	common.Context().Log.Warn().Msgf("Single sign-on through %s provider for institution %d failed: %v", idp.Protocol, idp.InstitutionID, err)
+}
+
+// auditSSOFailure records a failed single sign-on. The event belongs to
+// the identity provider's institution. Param email is empty if we failed
+// before learning who the provider authenticated.
+func auditSSOFailure(c *gin.Context, idp *pgmodels.IdentityProvider, email, detail string) {
+	event := helpers.NewAuditEvent(c, constants.AuditSignIn, constants.OutcomeFailure, nil, fmt.Sprintf("Single sign-on (%s): %s", idp.Protocol, detail))
+	event.UserEmail = email
+	event.InstitutionID = idp.InstitutionID
+	if email != "" {
+		user, err := pgmodels.UserByEmail(email)
+		if err == nil && user.InstitutionID == idp.InstitutionID {
+			event.UserID = user.ID
+		}
+	}
+	helpers.RecordAuditEvent(event)
+}
+
+func ssoStateMatches(session *ssoSession, state string) bool {
+	return state != "" && subtle.ConstantTimeCompare([]byte(session.State), []byte(state)) == 1
+}
+
+// setSSOSession saves SSO session data in an encrypted cookie, so we
+// can check the identity provider's response.
+func setSSOSession(c *gin.Context, session *ssoSession) error {
+	jsonBytes, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return helpers.SetCookie(c, constants.SSOCookieName, string(jsonBytes))
+}
+
+// getSSOSession returns the SSO session data saved by setSSOSession and
+// deletes the cookie, so each response can be used only once. This
+// returns common.ErrDecodeCookie if the session is missing, invalid,
+// or more than SSOSessionTimeout old.
+func getSSOSession(c *gin.Context) (*ssoSession, error) {
+	defer helpers.DeleteCookie(c, constants.SSOCookieName)
+	cookie, err := c.Cookie(constants.SSOCookieName)
+	if err != nil {
+		return nil, common.ErrDecodeCookie
+	}
+	value := ""
+	err = common.Context().Config.Cookies.Secure.Decode(constants.SSOCookieName, cookie, &value)
+	if err != nil {
+		return nil, common.ErrDecodeCookie
+	}
+	session := &ssoSession{}
+	err = json.Unmarshal([]byte(value), session)
+	if err != nil || time.Since(session.CreatedAt) > SSOSessionTimeout {
+		return nil, common.ErrDecodeCookie
+	}
+	return session, nil
+}
@@ -0,0 +1,325 @@
+package webui_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/APTrust/registry/web/testutil"
+	"github.com/gavv/httpexpect/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ssoInstID is the test.edu institution. Its users' email
+// addresses match its identifier.
+const ssoInstID = int64(4)
+
+func newTestIdentityProvider(t *testing.T, stub *testutil.StubIdP, protocol string) *pgmodels.IdentityProvider {
+	idp := pgmodels.NewIdentityProvider(ssoInstID)
+	idp.Protocol = protocol
+	idp.Enabled = true
+	idp.RoleAttribute = "groups"
+	idp.AdminRoleValues = []string{"registry-admins"}
+	if protocol == constants.SSOProtocolSAML {
+		idp.MetadataXML = stub.SAMLMetadata()
+		idp.EmailAttribute = "mail"
+	} else {
+		idp.IssuerURL = stub.IssuerURL()
+		idp.ClientID = stub.ClientID
+		require.Nil(t, idp.SetClientSecret(stub.ClientSecret))
+	}
+	require.Nil(t, idp.Save())
+	return idp
+}
+
+// restoreSSOUser undoes the role change SSO sign-in makes.
+func restoreSSOUser(t *testing.T, email, role string) {
+	user, err := pgmodels.UserByEmail(email)
+	require.Nil(t, err)
+	user.Role = role
+	require.Nil(t, user.Save())
+}
+
+// startSSO posts the email address to the sign-in page's SSO button
+// and returns the identity provider URL to which we were redirected.
+func startSSO(client *httpexpect.Expect, email string) string {
+	return client.POST("/users/sso").
+		WithRedirectPolicy(httpexpect.DontFollowRedirects).
+		WithFormField("email", email).
+		Expect().Status(http.StatusFound).
+		Header("Location").Raw()
+}
+
+func expectedSSORedirect(t *testing.T, email string) string {
+	user, err := pgmodels.UserByEmail(email)
+	require.Nil(t, err)
+	if user.IsTwoFactorUser() {
+		return "/users/2fa_choose"
+	}
+	return "/dashboard"
+}
+
+func TestUserSSOOIDC(t *testing.T) {
+	testutil.InitHTTPTests(t)
+	stub, err := testutil.NewStubIdP(testutil.BaseURL)
+	require.Nil(t, err)
+	defer stub.Close()
+	idp := newTestIdentityProvider(t, stub, constants.SSOProtocolOIDC)
+	defer idp.Delete()
+	defer restoreSSOUser(t, "user@test.edu", constants.RoleInstUser)
+
+	client := testutil.GetAnonymousClient(t)
+	authURL := startSSO(client, "user@test.edu")
+	assert.True(t, strings.HasPrefix(authURL, stub.IssuerURL()+"/authorize?"))
+
+	callback, err := stub.OIDCLogin(authURL, map[string]interface{}{
+		"sub":    "user",
+		"email":  "user@test.edu",
+		"groups": []string{"registry-admins"},
+	})
+	require.Nil(t, err)
+	u, err := url.Parse(callback)
+	require.Nil(t, err)
+	assert.Equal(t, testutil.BaseURL+common.OIDCCallbackPath, u.Scheme+"://"+u.Host+u.Path)
+
+	// Callback renders a page that posts the code back to us.
+	html := client.GET(common.OIDCCallbackPath).
+		WithQueryString(u.RawQuery).
+		Expect().Status(http.StatusOK).Body().Raw()
+	testutil.AssertMatchesAll(t, html, []string{
+		`action="/users/sso/complete"`,
+		u.Query().Get("code"),
+		u.Query().Get("state"),
+	})
+
+	client.POST("/users/sso/complete").
+		WithRedirectPolicy(httpexpect.DontFollowRedirects).
+		WithFormField("code", u.Query().Get("code")).
+		WithFormField("state", u.Query().Get("state")).
+		Expect().Status(http.StatusFound).
+		Header("Location").Equal(expectedSSORedirect(t, "user@test.edu"))
+
+	// Role comes from the groups claim.
+	user, err := pgmodels.UserByEmail("user@test.edu")
+	require.Nil(t, err)
+	assert.Equal(t, constants.RoleInstAdmin, user.Role)
+
+	// The SSO session can be used only once.
+	client.POST("/users/sso/complete").
+		WithFormField("code", u.Query().Get("code")).
+		WithFormField("state", u.Query().Get("state")).
+		Expect().Status(http.StatusBadRequest).
+		Body().Contains(common.ErrSSOFailed.Error())
+
+	// State must match.
+	client = testutil.GetAnonymousClient(t)
+	authURL = startSSO(client, "user@test.edu")
+	callback, err = stub.OIDCLogin(authURL, map[string]interface{}{
+		"sub":   "user",
+		"email": "user@test.edu",
+	})
+	require.Nil(t, err)
+	u, _ = url.Parse(callback)
+	client.POST("/users/sso/complete").
+		WithFormField("code", u.Query().Get("code")).
+		WithFormField("state", "not-the-state").
+		Expect().Status(http.StatusBadRequest).
+		Body().Contains(common.ErrSSOFailed.Error())
+
+	// The IdP may sign in people who have no Registry account.
+	client = testutil.GetAnonymousClient(t)
+	authURL = startSSO(client, "nobody@test.edu")
+	callback, err = stub.OIDCLogin(authURL, map[string]interface{}{
+		"sub":   "nobody",
+		"email": "nobody@test.edu",
+	})
+	require.Nil(t, err)
+	u, _ = url.Parse(callback)
+	client.POST("/users/sso/complete").
+		WithFormField("code", u.Query().Get("code")).
+		WithFormField("state", u.Query().Get("state")).
+		Expect().Status(http.StatusBadRequest).
+		Body().Contains("you don&#39;t have a Registry account")
+	client.GET("/dashboard").Expect().Status(http.StatusUnauthorized)
+}
+
+func TestUserSSOSAML(t *testing.T) {
+	testutil.InitHTTPTests(t)
+	stub, err := testutil.NewStubIdP(testutil.BaseURL)
+	require.Nil(t, err)
+	defer stub.Close()
+	idp := newTestIdentityProvider(t, stub, constants.SSOProtocolSAML)
+	defer idp.Delete()
+	defer restoreSSOUser(t, "admin@test.edu", constants.RoleInstAdmin)
+
+	client := testutil.GetAnonymousClient(t)
+	authURL := startSSO(client, "admin@test.edu")
+	assert.True(t, strings.HasPrefix(authURL, stub.IssuerURL()+"/saml/sso?"))
+
+	form, err := stub.SAMLLogin(authURL, "admin@test.edu", map[string][]string{
+		"mail":   {"admin@test.edu"},
+		"groups": {"staff"},
+	})
+	require.Nil(t, err)
+
+	html := client.POST(common.SAMLACSPath).
+		WithFormField("SAMLResponse", form.Get("SAMLResponse")).
+		WithFormField("RelayState", form.Get("RelayState")).
+		Expect().Status(http.StatusOK).Body().Raw()
+	testutil.AssertMatchesAll(t, html, []string{
+		`action="/users/sso/complete"`,
+		`name="SAMLResponse"`,
+		form.Get("RelayState"),
+	})
+
+	client.POST("/users/sso/complete").
+		WithRedirectPolicy(httpexpect.DontFollowRedirects).
+		WithFormField("SAMLResponse", form.Get("SAMLResponse")).
+		WithFormField("RelayState", form.Get("RelayState")).
+		Expect().Status(http.StatusFound).
+		Header("Location").Equal(expectedSSORedirect(t, "admin@test.edu"))
+
+	// User isn't in the admin group, so they're demoted.
+	user, err := pgmodels.UserByEmail("admin@test.edu")
+	require.Nil(t, err)
+	assert.Equal(t, constants.RoleInstUser, user.Role)
+
+	// RelayState must match the state we sent.
+	client = testutil.GetAnonymousClient(t)
+	authURL = startSSO(client, "admin@test.edu")
+	form, err = stub.SAMLLogin(authURL, "admin@test.edu", map[string][]string{
+		"mail": {"admin@test.edu"},
+	})
+	require.Nil(t, err)
+	client.POST("/users/sso/complete").
+		WithFormField("SAMLResponse", form.Get("SAMLResponse")).
+		WithFormField("RelayState", "not-the-state").
+		Expect().Status(http.StatusBadRequest).
+		Body().Contains(common.ErrSSOFailed.Error())
+}
+
+func TestUserSSONotConfigured(t *testing.T) {
+	testutil.InitHTTPTests(t)
+	client := testutil.GetAnonymousClient(t)
+	for _, email := range []string{"user@inst1.edu", "user@example.com", "not an email"} {
+		client.POST("/users/sso").
+			WithFormField("email", email).
+			Expect().Status(http.StatusBadRequest).
+			Body().Contains(common.ErrSSONotConfigured.Error())
+	}
+	client.POST("/users/sso/complete").
+		Expect().Status(http.StatusBadRequest).
+		Body().Contains(common.ErrSSOFailed.Error())
+}
+
+func TestUserSSORequired(t *testing.T) {
+	testutil.InitHTTPTests(t)
+	stub, err := testutil.NewStubIdP(testutil.BaseURL)
+	require.Nil(t, err)
+	defer stub.Close()
+	idp := newTestIdentityProvider(t, stub, constants.SSOProtocolOIDC)
+	defer idp.Delete()
+	idp.Required = true
+	require.Nil(t, idp.Save())
+
+	client := testutil.GetAnonymousClient(t)
+	client.GET("/users/sign_in").Expect().Status(http.StatusOK).
+		Body().Contains("Sign In with Your Institution")
+	client.POST("/users/sign_in").
+		WithFormField("email", "user@test.edu").
+		WithFormField("password", "password").
+		Expect().Status(http.StatusBadRequest).
+		Body().Contains("requires you to sign in through your institution")
+
+	// Users at other institutions are unaffected.
+	testutil.InitClient(t, testutil.Inst1User.Email)
+}
+
+func TestUserSSOSAMLMetadata(t *testing.T) {
+	testutil.InitHTTPTests(t)
+	client := testutil.GetAnonymousClient(t)
+	xml := client.GET(common.SAMLMetadataPath).
+		Expect().Status(http.StatusOK).Body().Raw()
+	testutil.AssertMatchesAll(t, xml, []string{
+		"EntityDescriptor",
+		testutil.BaseURL + common.SAMLMetadataPath,
+		testutil.BaseURL + common.SAMLACSPath,
+	})
+}
+
+func TestInstitutionSSOEditAndUpdate(t *testing.T) {
+	testutil.InitHTTPTests(t)
+	stub, err := testutil.NewStubIdP(testutil.BaseURL)
+	require.Nil(t, err)
+	defer stub.Close()
+
+	ssoURL := fmt.Sprintf("/institutions/sso/%d", ssoInstID)
+	html := testutil.SysAdminClient.GET(ssoURL).
+		Expect().Status(http.StatusOK).Body().Raw()
+	testutil.AssertMatchesAll(t, html, []string{
+		"Single Sign-On for",
+		testutil.BaseURL + common.OIDCCallbackPath,
+		testutil.BaseURL + common.SAMLACSPath,
+		"IssuerURL",
+		"MetadataXML",
+	})
+
+	// Bad settings re-display the form.
+	testutil.SysAdminClient.POST(ssoURL).
+		WithFormField(constants.CSRFTokenName, testutil.SysAdminToken).
+		WithFormField("Protocol", constants.SSOProtocolSAML).
+		WithFormField("EmailAttribute", "mail").
+		WithFormField("MetadataXML", "not xml").
+		Expect().Status(http.StatusBadRequest).
+		Body().Contains(pgmodels.ErrIdPMetadataXML)
+
+	testutil.SysAdminClient.POST(ssoURL).
+		WithFormField(constants.CSRFTokenName, testutil.SysAdminToken).
+		WithFormField("Protocol", constants.SSOProtocolOIDC).
+		WithFormField("Enabled", "true").
+		WithFormField("IssuerURL", stub.IssuerURL()).
+		WithFormField("ClientID", stub.ClientID).
+		WithFormField("ClientSecret", stub.ClientSecret).
+		WithFormField("EmailAttribute", "email").
+		WithFormField("RoleAttribute", "groups").
+		WithFormField("AdminRoleValues", "registry-admins, it-staff").
+		Expect().Status(http.StatusOK).
+		Body().Contains("OpenID Connect - Enabled")
+
+	idp, err := pgmodels.IdentityProviderForInstitution(ssoInstID)
+	require.Nil(t, err)
+	defer idp.Delete()
+	assert.Equal(t, []string{"registry-admins", "it-staff"}, idp.AdminRoleValues)
+	secret, err := idp.ClientSecret()
+	require.Nil(t, err)
+	assert.Equal(t, stub.ClientSecret, secret)
+
+	// Blank secret keeps the old one.
+	testutil.SysAdminClient.POST(ssoURL).
+		WithFormField(constants.CSRFTokenName, testutil.SysAdminToken).
+		WithFormField("Protocol", constants.SSOProtocolOIDC).
+		WithFormField("IssuerURL", stub.IssuerURL()).
+		WithFormField("ClientID", stub.ClientID).
+		WithFormField("EmailAttribute", "email").
+		Expect().Status(http.StatusOK).
+		Body().Contains("OpenID Connect - Disabled")
+	idp, err = pgmodels.IdentityProviderForInstitution(ssoInstID)
+	require.Nil(t, err)
+	secret, err = idp.ClientSecret()
+	require.Nil(t, err)
+	assert.Equal(t, stub.ClientSecret, secret)
+
+	// Only APTrust admins can configure SSO.
+	testutil.Inst1AdminClient.GET(fmt.Sprintf("/institutions/sso/%d", testutil.Inst1Admin.InstitutionID)).
+		Expect().Status(http.StatusForbidden)
+	testutil.Inst1AdminClient.POST(fmt.Sprintf("/institutions/sso/%d", testutil.Inst1Admin.InstitutionID)).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1AdminToken).
+		WithFormField("Protocol", constants.SSOProtocolOIDC).
+		Expect().Status(http.StatusForbidden)
+}
@@ -0,0 +1,86 @@
+package webui
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/APTrust/registry/forms"
+	"github.com/APTrust/registry/helpers"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/gin-gonic/gin"
+)
+
+// StoragePriceIndex lists each storage option's price history,
+// including prices scheduled to take effect in future months.
+//
+// GET /storage_prices
+func StoragePriceIndex(c *gin.Context) {
+	req := NewRequest(c)
+	query := pgmodels.NewQuery().
+		Relations("StorageOption").
+		OrderBy(`"storage_option"."name"`, "asc").
+		OrderBy(`"storage_price"."effective_from"`, "desc")
+	prices, err := pgmodels.StoragePriceSelect(query)
+	if AbortIfError(c, err) {
+		return
+	}
+	req.TemplateData["prices"] = prices
+	c.HTML(http.StatusOK, "storage_prices/index.html", req.TemplateData)
+}
+
+// StoragePriceNew shows a form for scheduling a new storage price.
+//
+// GET /storage_prices/new
+func StoragePriceNew(c *gin.Context) {
+	req := NewRequest(c)
+	form, err := forms.NewStoragePriceForm(&pgmodels.StoragePrice{})
+	if AbortIfError(c, err) {
+		return
+	}
+	req.TemplateData["form"] = form
+	c.HTML(http.StatusOK, form.Template, req.TemplateData)
+}
+
+// StoragePriceCreate schedules a new storage price.
+//
+// POST /storage_prices/new
+func StoragePriceCreate(c *gin.Context) {
+	req := NewRequest(c)
+	price := &pgmodels.StoragePrice{
+		Comment: c.PostForm("Comment"),
+	}
+	price.StorageOptionID, _ = strconv.ParseInt(c.PostForm("StorageOptionID"), 10, 64)
+	price.CostGBPerMonth, _ = strconv.ParseFloat(c.PostForm("CostGBPerMonth"), 64)
+	price.EffectiveFrom, _ = time.Parse("2006-01-02", c.PostForm("EffectiveFrom"))
+	form, err := forms.NewStoragePriceForm(price)
+	if AbortIfError(c, err) {
+		return
+	}
+	req.TemplateData["form"] = form
+	if form.Save() {
+		helpers.SetFlashCookie(c, fmt.Sprintf("Scheduled new price starting %s.", price.EffectiveFrom.Format("January 2, 2006")))
+		c.Redirect(form.Status, "/storage_prices")
+	} else {
+		req.TemplateData["FormError"] = form.Error
+		c.HTML(form.Status, form.Template, req.TemplateData)
+	}
+}
+
+// StoragePriceDelete deletes a price that hasn't taken effect yet.
+//
+// DELETE or POST /storage_prices/delete/:id
+func StoragePriceDelete(c *gin.Context) {
+	req := NewRequest(c)
+	price, err := pgmodels.StoragePriceByID(req.Auth.ResourceID)
+	if AbortIfError(c, err) {
+		return
+	}
+	err = price.Delete()
+	if AbortIfError(c, err) {
+		return
+	}
+	helpers.SetFlashCookie(c, fmt.Sprintf("Deleted %s price starting %s.", price.StorageOption.Name, price.EffectiveFrom.Format("January 2, 2006")))
+	c.Redirect(http.StatusSeeOther, "/storage_prices")
+}
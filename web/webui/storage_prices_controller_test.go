@@ -0,0 +1,84 @@
+package webui_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/APTrust/registry/web/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoragePrices(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+	testutil.InitHTTPTests(t)
+
+	// Only APTrust admins can see or change prices.
+	for _, client := range testutil.AllClients {
+		if client == testutil.SysAdminClient {
+			continue
+		}
+		client.GET("/storage_prices").Expect().Status(http.StatusForbidden)
+		client.GET("/storage_prices/new").Expect().Status(http.StatusForbidden)
+	}
+
+	html := testutil.SysAdminClient.GET("/storage_prices").
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "Price as of July 29, 2021")
+	assert.Contains(t, html, constants.StorageOptionGlacierDeepOR)
+	testutil.SysAdminClient.GET("/storage_prices/new").
+		Expect().Status(http.StatusOK)
+
+	now := time.Now().UTC()
+	nextMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+
+	// Prices can't start in the middle of a month.
+	testutil.SysAdminClient.POST("/storage_prices/new").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.SysAdminToken).
+		WithFormField("StorageOptionID", 1).
+		WithFormField("CostGBPerMonth", "0.025").
+		WithFormField("EffectiveFrom", nextMonth.AddDate(0, 0, 14).Format("2006-01-02")).
+		Expect().Status(http.StatusBadRequest)
+
+	html = testutil.SysAdminClient.POST("/storage_prices/new").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.SysAdminToken).
+		WithFormField("StorageOptionID", 1).
+		WithFormField("CostGBPerMonth", "0.025").
+		WithFormField("EffectiveFrom", nextMonth.Format("2006-01-02")).
+		WithFormField("Comment", "Lower Standard price").
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "Lower Standard price")
+	assert.Contains(t, html, "Scheduled")
+
+	query := pgmodels.NewQuery().Where("comment", "=", "Lower Standard price")
+	price, err := pgmodels.StoragePriceGet(query)
+	require.Nil(t, err)
+	assert.Equal(t, 0.025, price.CostGBPerMonth)
+	assert.Equal(t, nextMonth, price.EffectiveFrom.UTC())
+
+	// Institutional admins can't delete it.
+	testutil.Inst1AdminClient.POST("/storage_prices/delete/{id}", price.ID).
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1AdminToken).
+		Expect().Status(http.StatusForbidden)
+
+	// Prices already in effect can't be deleted.
+	testutil.SysAdminClient.POST("/storage_prices/delete/{id}", 1).
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.SysAdminToken).
+		Expect().Status(http.StatusMethodNotAllowed)
+
+	testutil.SysAdminClient.POST("/storage_prices/delete/{id}", price.ID).
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.SysAdminToken).
+		Expect().Status(http.StatusOK)
+	_, err = pgmodels.StoragePriceByID(price.ID)
+	assert.True(t, pgmodels.IsNoRowError(err))
+}
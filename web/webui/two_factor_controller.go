@@ -1,7 +1,9 @@
 package webui
 
 import (
+	"bytes"
 	"fmt"
+	"image/png"
 	"net/http"
 	"time"
 
@@ -9,12 +11,14 @@ import (
 	"github.com/APTrust/registry/constants"
 	"github.com/APTrust/registry/forms"
 	"github.com/APTrust/registry/helpers"
+	"github.com/APTrust/registry/pgmodels"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/stew/slice"
 )
 
 // UserTwoFactorChoose shows a list of radio button options so a user
-// can choose their two-factor auth method (Authy, Backup Code, SMS).
+// can choose their two-factor auth method (Authy, Authenticator App,
+// Backup Code, SMS, Security Key).
 // We show this page after a user has entered their email and password,
 // if they have two-factor enabled. This is part of the login process,
 // not part of the setup process.
@@ -22,6 +26,11 @@ import (
 // GET /users/2fa_choose/
 func UserTwoFactorChoose(c *gin.Context) {
 	req := NewRequest(c)
+	credentials, err := pgmodels.ActiveWebAuthnCredentials(req.CurrentUser.ID)
+	if AbortIfError(c, err) {
+		return
+	}
+	req.TemplateData["hasSecurityKeys"] = len(credentials) > 0
 	c.HTML(http.StatusOK, "users/choose_second_factor.html", req.TemplateData)
 }
 
@@ -35,6 +44,17 @@ func UserTwoFactorBackup(c *gin.Context) {
 	c.HTML(http.StatusOK, "users/enter_auth_token.html", req.TemplateData)
 }
 
+// UserTwoFactorTOTP shows the form on which the user can enter a
+// code from their authenticator app to complete two-factor
+// authentication.
+//
+// GET /users/2fa_totp/
+func UserTwoFactorTOTP(c *gin.Context) {
+	req := NewRequest(c)
+	req.TemplateData["twoFactorMethod"] = constants.TwoFactorTOTP
+	c.HTML(http.StatusOK, "users/enter_auth_token.html", req.TemplateData)
+}
+
 // UserTwoFactorGenerateSMS generates an OTP and sends it via SMS
 // the user.
 //
@@ -78,14 +98,16 @@ func UserTwoFactorPush(c *gin.Context) {
 		return
 	}
 	if approved {
+		helpers.Audit(c, constants.AuditSecondFactor, constants.OutcomeSuccess, req.CurrentUser, "Authy push")
 		c.Redirect(http.StatusFound, "/dashboard")
 		return
 	}
+	helpers.Audit(c, constants.AuditSecondFactor, constants.OutcomeFailure, req.CurrentUser, "Authy push not approved")
 	c.Redirect(http.StatusFound, "/users/sign_out")
 }
 
-// UserTwoFactorVerify verifies the SMS or backup code that the user
-// entered on TwoFactorEnter.
+// UserTwoFactorVerify verifies the SMS, authenticator app, or backup
+// code that the user entered on TwoFactorEnter.
 //
 // POST /users/2fa_verify/
 func UserTwoFactorVerify(c *gin.Context) {
@@ -99,13 +121,31 @@ func UserTwoFactorVerify(c *gin.Context) {
 
 	user := req.CurrentUser
 
+	// Check throttling before we look at the code, so there's
+	// no way to keep guessing while locked out.
+	err = checkIPThrottle(c)
+	if err == nil {
+		err = user.CheckLoginThrottle()
+	}
+	if err == common.ErrAccountLocked {
+		signOutLockedUser(c, user)
+		return
+	} else if err != nil {
+		req.TemplateData["flash"] = "Too many failed attempts. Please wait a moment and try again."
+		c.HTML(http.StatusTooManyRequests, "users/enter_auth_token.html", req.TemplateData)
+		return
+	}
+
 	if method == constants.TwoFactorSMS {
 		if OTPTokenIsExpired(user.EncryptedOTPSentAt) {
+			helpers.Audit(c, constants.AuditSecondFactor, constants.OutcomeFailure, user, "SMS code expired")
 			helpers.SetFlashCookie(c, "Your one-time password expired. Please sign in again.")
 			c.Redirect(http.StatusFound, "/users/sign_out")
 			return
 		}
 		tokenIsValid = common.ComparePasswords(user.EncryptedOTPSecret, otp)
+	} else if method == constants.TwoFactorTOTP {
+		tokenIsValid, err = user.VerifyTOTP(otp)
 	} else {
 		tokenIsValid, err = userVerifyBackupCode(req, otp)
 	}
@@ -113,22 +153,32 @@ func UserTwoFactorVerify(c *gin.Context) {
 		return
 	}
 	if !tokenIsValid {
-		// TODO: increment failed login attempt count
-		// User model needs FailedLogins and LockoutUntil.
-		// Then we need logic to enforce the lockout.
+		helpers.Audit(c, constants.AuditSecondFactor, constants.OutcomeFailure, user, secondFactorDescription(method))
+		recordLoginFailure(req, user)
+		if user.IsLocked() {
+			signOutLockedUser(c, user)
+			return
+		}
 		msg := "Backup code is incorrect. Try again."
 		if method == constants.TwoFactorSMS {
 			msg = "One-time password is incorrect. Try again."
+		} else if method == constants.TwoFactorTOTP {
+			msg = "Authenticator app code is incorrect. Try again."
 		}
 		req.TemplateData["flash"] = msg
 		c.HTML(http.StatusBadRequest, "users/enter_auth_token.html", req.TemplateData)
 	} else {
 		// Note that call to ClearOTPSecret saves user record to db.
-		user.AwaitingSecondFactor = false
+		user.ClearFailedLogins()
 		err := user.ClearOTPSecret()
 		if AbortIfError(c, err) {
 			return
 		}
+		err = completeSecondFactor(c)
+		if AbortIfError(c, err) {
+			return
+		}
+		helpers.Audit(c, constants.AuditSecondFactor, constants.OutcomeSuccess, user, secondFactorDescription(method))
 		c.Redirect(http.StatusFound, "/dashboard")
 	}
 }
@@ -170,7 +220,7 @@ func UserComplete2FASetup(c *gin.Context) {
 		return
 	}
 
-	if prefs.PhoneChanged() {
+	if prefs.PhoneMethodChanged() {
 		user.ConfirmedTwoFactor = false
 	}
 
@@ -181,11 +231,46 @@ func UserComplete2FASetup(c *gin.Context) {
 		return
 	}
 
+	// Authenticator app setup doesn't take effect until the user
+	// confirms it with a valid code, so keep the current method
+	// for now. See UserConfirmTOTP. Likewise, security keys don't
+	// take effect until the user registers one. See
+	// UserWebAuthnRegisterFinish.
+	if prefs.NeedsTOTPConfirmation() || prefs.NeedsWebAuthnRegistration() {
+		user.AuthyStatus = prefs.OldMethod
+	} else if prefs.UseWebAuthn() {
+		// User already has a security key, so nothing to confirm.
+		user.EnabledTwoFactor = true
+		user.ConfirmedTwoFactor = true
+	}
+
 	err = user.Save()
 	if AbortIfError(c, err) {
 		return
 	}
 
+	if prefs.NeedsTOTPConfirmation() {
+		key, err := user.InitTOTP()
+		if AbortIfError(c, err) {
+			return
+		}
+		req.TemplateData["totpSecret"] = key.Secret()
+		c.HTML(http.StatusOK, "users/confirm_totp.html", req.TemplateData)
+		return
+	}
+
+	if prefs.NeedsWebAuthnRegistration() {
+		req.TemplateData["primary"] = true
+		c.HTML(http.StatusOK, "users/webauthn_register.html", req.TemplateData)
+		return
+	}
+
+	if prefs.UseWebAuthn() {
+		helpers.SetFlashCookie(c, "Your two-factor setup is complete. Next time you log in, you'll use your security key to complete the sign-in process.")
+		c.Redirect(http.StatusFound, "/users/my_account")
+		return
+	}
+
 	if prefs.UseAuthy() {
 		ok, err := userCompleteAuthySetup(req, prefs)
 		if AbortIfError(c, err) {
@@ -242,6 +327,62 @@ func UserConfirmPhone(c *gin.Context) {
 	}
 }
 
+// UserTOTPQRCode returns a PNG image of the QR code for the user's
+// pending authenticator app setup. We serve this as an image rather
+// than embedding it in the page because our content security policy
+// doesn't allow data URLs. Once setup is complete, we no longer
+// show the code, since it contains the user's TOTP secret.
+//
+// GET /users/2fa_totp_qr
+func UserTOTPQRCode(c *gin.Context) {
+	req := NewRequest(c)
+	if req.CurrentUser.PendingTOTPSecret == "" {
+		AbortIfError(c, common.ErrPermissionDenied)
+		return
+	}
+	key, err := req.CurrentUser.PendingTOTPKey()
+	if AbortIfError(c, err) {
+		return
+	}
+	img, err := key.Image(240, 240)
+	if AbortIfError(c, err) {
+		return
+	}
+	var buf bytes.Buffer
+	err = png.Encode(&buf, img)
+	if AbortIfError(c, err) {
+		return
+	}
+	c.Data(http.StatusOK, "image/png", buf.Bytes())
+}
+
+// UserConfirmTOTP accepts the form from UserComplete2FASetup on which
+// the user enters the first code from their authenticator app. If the
+// code is valid, the authenticator app becomes the user's two-factor
+// method.
+//
+// POST /users/confirm_totp
+func UserConfirmTOTP(c *gin.Context) {
+	req := NewRequest(c)
+	user := req.CurrentUser
+	ok, err := user.ConfirmTOTP(c.PostForm("otp"))
+	if AbortIfError(c, err) {
+		return
+	}
+	if ok {
+		helpers.SetFlashCookie(c, "Your authenticator app is set up. Next time you log in, you'll enter a code from the app to complete the login process.")
+		c.Redirect(http.StatusFound, "/users/my_account")
+		return
+	}
+	key, err := user.PendingTOTPKey()
+	if AbortIfError(c, err) {
+		return
+	}
+	req.TemplateData["totpSecret"] = key.Secret()
+	req.TemplateData["flash"] = "Oops! That wasn't the right code. Try again."
+	c.HTML(http.StatusBadRequest, "users/confirm_totp.html", req.TemplateData)
+}
+
 // UserRegisterWithAuthy registers a user with Authy.
 func UserAuthyRegister(req *Request) error {
 	user := req.CurrentUser
@@ -306,12 +447,16 @@ func userSendAuthyOneTouch(req *Request) (bool, error) {
 	}
 	if ok {
 		// User approved login request
-		req.CurrentUser.AwaitingSecondFactor = false
 		req.CurrentUser.EncryptedOTPSecret = ""
+		req.CurrentUser.ClearFailedLogins()
 		err := req.CurrentUser.Save()
 		if err != nil {
 			return false, err
 		}
+		err = completeSecondFactor(req.GinContext)
+		if err != nil {
+			return false, err
+		}
 	}
 	return ok, err
 }
@@ -382,6 +527,33 @@ func UserCompleteSMSSetup(req *Request) error {
 	return user.Save()
 }
 
+// signOutLockedUser ends the session of a user whose account is locked
+// partway through two-factor sign-in and tells them why.
+func signOutLockedUser(c *gin.Context, user *pgmodels.User) {
+	err := user.SignOut()
+	if err != nil {
+		common.Context().Log.Error().Msgf("Error signing out locked user %s: %v", user.Email, err)
+	}
+	endUserSession(c)
+	c.HTML(http.StatusTooManyRequests, "users/sign_in.html", gin.H{
+		"error":      common.ErrAccountLocked.Error(),
+		"cover":      helpers.GetCover(),
+		"ssoEnabled": common.Context().Config.SSO.Enabled,
+	})
+}
+
+// secondFactorDescription describes a two-factor method for the audit log.
+func secondFactorDescription(method string) string {
+	switch method {
+	case constants.TwoFactorSMS:
+		return "SMS code"
+	case constants.TwoFactorTOTP:
+		return "Authenticator app code"
+	default:
+		return "Backup code"
+	}
+}
+
 func OTPTokenIsExpired(tokenSentAt time.Time) bool {
 	expiration := tokenSentAt.Add(common.Context().Config.TwoFactor.OTPExpiration)
 	return time.Now().After(expiration)
@@ -14,6 +14,8 @@ import (
 	"github.com/APTrust/registry/pgmodels"
 	"github.com/APTrust/registry/web/testutil"
 	"github.com/APTrust/registry/web/webui"
+	"github.com/gavv/httpexpect/v2"
+	"github.com/pquerna/otp/totp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -28,7 +30,6 @@ func TestOTPTokenIsExpired(t *testing.T) {
 
 func TestUserCompleteSMSSetup(t *testing.T) {
 	defer func() {
-		testutil.Inst1User.AwaitingSecondFactor = false
 		testutil.Inst1User.ClearOTPSecret()
 	}()
 
@@ -116,7 +117,6 @@ func TestUserTwoFactorGenerateSMS(t *testing.T) {
 	oldToken := testutil.Inst1User.EncryptedOTPSecret
 	oldTimestamp := testutil.Inst1User.EncryptedOTPSentAt
 	defer func() {
-		testutil.Inst1User.AwaitingSecondFactor = false
 		testutil.Inst1User.ClearOTPSecret()
 	}()
 
@@ -264,7 +264,7 @@ func TestUserConfirmPhone(t *testing.T) {
 	// user goes after successful confirmation.
 	successStrings := []string{
 		testutil.Inst1User.Name,
-		"getAPIKey()",
+		"New API Key",
 	}
 	testSMSVerify(t, targetURL, successStrings, failureStrings)
 }
@@ -321,7 +321,6 @@ func extractBackupCodes(t *testing.T, html string) []string {
 func testSMSVerify(t *testing.T, targetURL string, successStrings, failureStrings []string) {
 	testutil.InitHTTPTests(t)
 	defer func() {
-		testutil.Inst1User.AwaitingSecondFactor = false
 		testutil.Inst1User.ClearOTPSecret()
 	}()
 	otp, err := testutil.Inst1User.CreateOTPToken()
@@ -368,5 +367,154 @@ func testSMSVerify(t *testing.T, targetURL string, successStrings, failureString
 	require.Nil(t, err)
 	assert.Empty(t, reloadedUser.EncryptedOTPSecret)
 	assert.Empty(t, reloadedUser.EncryptedOTPSentAt)
-	assert.False(t, reloadedUser.AwaitingSecondFactor)
+}
+
+func TestSecondFactorCompletesOnlyOneSession(t *testing.T) {
+	testutil.InitHTTPTests(t)
+	user, err := pgmodels.UserByEmail(testutil.Inst2User.Email)
+	require.Nil(t, err)
+	defer func() {
+		user.AuthyStatus = testutil.Inst2User.AuthyStatus
+		user.EnabledTwoFactor = testutil.Inst2User.EnabledTwoFactor
+		user.ConfirmedTwoFactor = testutil.Inst2User.ConfirmedTwoFactor
+		require.Nil(t, user.ClearOTPSecret())
+	}()
+	user.AuthyStatus = constants.TwoFactorSMS
+	user.EnabledTwoFactor = true
+	user.ConfirmedTwoFactor = true
+	require.Nil(t, user.Save())
+
+	// Sign in from two browsers.
+	clientA, tokenA := testutil.InitClient(t, user.Email)
+	clientB, _ := testutil.InitClient(t, user.Email)
+
+	otp, err := user.CreateOTPToken()
+	require.Nil(t, err)
+	user.EncryptedOTPSentAt = time.Now()
+	require.Nil(t, user.Save())
+
+	clientA.POST("/users/2fa_verify").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, tokenA).
+		WithFormField("otp", otp).
+		WithFormField("two_factor_method", constants.TwoFactorSMS).
+		Expect().Status(http.StatusOK)
+
+	// The session that verified the code can get in, but the
+	// other one still has to complete its own second factor.
+	clientA.GET("/dashboard").
+		WithRedirectPolicy(httpexpect.DontFollowRedirects).
+		Expect().Status(http.StatusOK)
+	clientB.GET("/dashboard").
+		WithRedirectPolicy(httpexpect.DontFollowRedirects).
+		Expect().Status(http.StatusFound).
+		Header("Location").Equal("/users/2fa_choose/")
+}
+
+func TestUserTOTPSetupAndVerify(t *testing.T) {
+	testutil.InitHTTPTests(t)
+	user, err := pgmodels.UserByEmail(testutil.Inst1User.Email)
+	require.Nil(t, err)
+	defer func() {
+		user.AuthyStatus = testutil.Inst1User.AuthyStatus
+		user.EnabledTwoFactor = testutil.Inst1User.EnabledTwoFactor
+		user.ConfirmedTwoFactor = testutil.Inst1User.ConfirmedTwoFactor
+		user.PhoneNumber = testutil.Inst1User.PhoneNumber
+		user.EncryptedTOTPSecret = ""
+		user.PendingTOTPSecret = ""
+		user.ConsumedTimestep = 0
+		require.Nil(t, user.Save())
+	}()
+
+	// Choosing an authenticator app shows the QR code and
+	// manual entry key, but doesn't change the user's method
+	// until they confirm.
+	html := testutil.Inst1UserClient.POST("/users/2fa_setup").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1UserToken).
+		WithFormField("PhoneNumber", "+15556662888").
+		WithFormField("AuthyStatus", constants.TwoFactorTOTP).
+		Expect().Status(http.StatusOK).Body().Raw()
+	testutil.AssertMatchesAll(t, html, []string{
+		"Set Up Your Authenticator App",
+		`src="/users/2fa_totp_qr"`,
+		`action="/users/confirm_totp"`,
+	})
+
+	user, err = pgmodels.UserByEmail(testutil.Inst1User.Email)
+	require.Nil(t, err)
+	assert.False(t, user.IsTOTPUser())
+	key, err := user.PendingTOTPKey()
+	require.Nil(t, err)
+	assert.Contains(t, html, key.Secret())
+
+	testutil.Inst1UserClient.GET("/users/2fa_totp_qr").
+		Expect().Status(http.StatusOK).
+		ContentType("image/png")
+
+	// Wrong code
+	html = testutil.Inst1UserClient.POST("/users/confirm_totp").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1UserToken).
+		WithFormField("otp", "12345x").
+		Expect().Status(http.StatusBadRequest).Body().Raw()
+	assert.Contains(t, html, "wasn't the right code")
+
+	// Right code
+	code, err := totp.GenerateCode(key.Secret(), time.Now())
+	require.Nil(t, err)
+	testutil.Inst1UserClient.POST("/users/confirm_totp").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1UserToken).
+		WithFormField("otp", code).
+		Expect().Status(http.StatusOK)
+
+	user, err = pgmodels.UserByEmail(testutil.Inst1User.Email)
+	require.Nil(t, err)
+	assert.True(t, user.IsTOTPUser())
+
+	// Once set up, the QR code is no longer available.
+	testutil.Inst1UserClient.GET("/users/2fa_totp_qr").
+		Expect().Status(http.StatusForbidden)
+
+	html = testutil.Inst1UserClient.GET("/users/2fa_totp/").
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "Authenticator App Code")
+
+	// Changing only the phone number keeps the current
+	// authenticator app.
+	testutil.Inst1UserClient.POST("/users/2fa_setup").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1UserToken).
+		WithFormField("PhoneNumber", "+15556662999").
+		WithFormField("AuthyStatus", constants.TwoFactorTOTP).
+		Expect().Status(http.StatusOK)
+	user, err = pgmodels.UserByEmail(testutil.Inst1User.Email)
+	require.Nil(t, err)
+	assert.True(t, user.IsTOTPUser())
+	assert.Empty(t, user.PendingTOTPSecret)
+	currentKey, err := user.TOTPKey()
+	require.Nil(t, err)
+	assert.Equal(t, key.Secret(), currentKey.Secret())
+
+	// Asking for a new app starts setup again, but the current
+	// app keeps working until the user confirms the new one.
+	html = testutil.Inst1UserClient.POST("/users/2fa_setup").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1UserToken).
+		WithFormField("PhoneNumber", "+15556662999").
+		WithFormField("AuthyStatus", constants.TwoFactorTOTP).
+		WithFormField("ResetTOTP", "true").
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, `action="/users/confirm_totp"`)
+	user, err = pgmodels.UserByEmail(testutil.Inst1User.Email)
+	require.Nil(t, err)
+	assert.True(t, user.IsTOTPUser())
+	assert.NotEmpty(t, user.PendingTOTPSecret)
+	currentKey, err = user.TOTPKey()
+	require.Nil(t, err)
+	assert.Equal(t, key.Secret(), currentKey.Secret())
+	testutil.Inst1UserClient.GET("/users/2fa_totp_qr").
+		Expect().Status(http.StatusOK).
+		ContentType("image/png")
 }
@@ -12,6 +12,15 @@ type TwoFactorPreferences struct {
 	OldMethod string
 	NewMethod string
 	User      *pgmodels.User
+
+	// ResetTOTP is true if a user who already uses an authenticator
+	// app asked to set up a new one.
+	ResetTOTP bool
+
+	// HasSecurityKeys is true if the user has at least one active
+	// WebAuthn credential. We check this only if the user chose
+	// security keys as their new method.
+	HasSecurityKeys bool
 }
 
 func NewTwoFactorPreferences(req *Request) (*TwoFactorPreferences, error) {
@@ -34,6 +43,15 @@ func NewTwoFactorPreferences(req *Request) (*TwoFactorPreferences, error) {
 		OldMethod: oldMethod,
 		NewMethod: user.AuthyStatus,
 		User:      user,
+		ResetTOTP: req.GinContext.PostForm("ResetTOTP") == "true",
+	}
+
+	if prefs.UseWebAuthn() {
+		credentials, err := pgmodels.ActiveWebAuthnCredentials(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		prefs.HasSecurityKeys = len(credentials) > 0
 	}
 
 	return prefs, nil
@@ -52,7 +70,15 @@ func (p *TwoFactorPreferences) NeedsConfirmation() bool {
 }
 
 func (p *TwoFactorPreferences) NothingChanged() bool {
-	return !p.PhoneChanged() && !p.MethodChanged()
+	return !p.PhoneChanged() && !p.MethodChanged() && !p.NeedsTOTPConfirmation()
+}
+
+// PhoneMethodChanged is true if the user's phone changed and their
+// new method sends codes or push notifications to that phone. Other
+// methods don't use the phone, so a new number doesn't need to be
+// confirmed.
+func (p *TwoFactorPreferences) PhoneMethodChanged() bool {
+	return p.PhoneChanged() && (p.UseSMS() || p.UseAuthy())
 }
 
 func (p *TwoFactorPreferences) DoNotUseTwoFactor() bool {
@@ -67,6 +93,14 @@ func (p *TwoFactorPreferences) UseSMS() bool {
 	return p.NewMethod == constants.TwoFactorSMS
 }
 
+func (p *TwoFactorPreferences) UseTOTP() bool {
+	return p.NewMethod == constants.TwoFactorTOTP
+}
+
+func (p *TwoFactorPreferences) UseWebAuthn() bool {
+	return p.NewMethod == constants.TwoFactorWebAuthn
+}
+
 func (p *TwoFactorPreferences) NeedsAuthyRegistration() bool {
 	return p.NewMethod == constants.TwoFactorAuthy && p.User.AuthyID == ""
 }
@@ -78,3 +112,14 @@ func (p *TwoFactorPreferences) NeedsAuthyConfirmation() bool {
 func (p *TwoFactorPreferences) NeedsSMSConfirmation() bool {
 	return p.NeedsConfirmation() && p.NewMethod == constants.TwoFactorSMS
 }
+
+// NeedsTOTPConfirmation is true if the user is switching to an
+// authenticator app or asked to set up a new one. Changing only the
+// phone number keeps the user's current authenticator app.
+func (p *TwoFactorPreferences) NeedsTOTPConfirmation() bool {
+	return p.UseTOTP() && (p.MethodChanged() || p.ResetTOTP)
+}
+
+func (p *TwoFactorPreferences) NeedsWebAuthnRegistration() bool {
+	return p.NeedsConfirmation() && p.NewMethod == constants.TwoFactorWebAuthn && !p.HasSecurityKeys
+}
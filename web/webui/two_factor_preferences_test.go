@@ -58,4 +58,17 @@ func TestTwoFactorPreferences(t *testing.T) {
 	assert.False(t, prefs.NeedsAuthyConfirmation())
 
 	assert.True(t, prefs.NeedsSMSConfirmation())
+	assert.True(t, prefs.PhoneMethodChanged())
+
+	// Authenticator app users who change only their phone
+	// keep their current app.
+	prefs.OldMethod = constants.TwoFactorTOTP
+	prefs.NewMethod = constants.TwoFactorTOTP
+	assert.False(t, prefs.PhoneMethodChanged())
+	assert.False(t, prefs.NeedsTOTPConfirmation())
+	prefs.ResetTOTP = true
+	assert.True(t, prefs.NeedsTOTPConfirmation())
+	prefs.ResetTOTP = false
+	prefs.OldMethod = constants.TwoFactorSMS
+	assert.True(t, prefs.NeedsTOTPConfirmation())
 }
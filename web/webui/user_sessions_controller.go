@@ -0,0 +1,98 @@
+package webui
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/helpers"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/gin-gonic/gin"
+)
+
+// UserSessionRevoke signs out one of the current user's sessions.
+// Users can revoke only their own sessions. Revoking the current
+// session is the same as signing out.
+//
+// POST /user_sessions/revoke/:id
+func UserSessionRevoke(c *gin.Context) {
+	req := NewRequest(c)
+	session, err := pgmodels.UserSessionByID(req.Auth.ResourceID)
+	if AbortIfError(c, err) {
+		return
+	}
+	if session.UserID != req.CurrentUser.ID {
+		common.Context().Log.Warn().Msgf("Permission denied: User %d tried to revoke session %d belonging to user %d", req.CurrentUser.ID, session.ID, session.UserID)
+		AbortIfError(c, common.ErrPermissionDenied)
+		return
+	}
+	err = session.Delete()
+	if AbortIfError(c, err) {
+		return
+	}
+	helpers.Audit(c, constants.AuditSignOut, constants.OutcomeSuccess, req.CurrentUser, fmt.Sprintf("Revoked session from %s", session.IPAddress))
+	if current := helpers.CurrentSession(c); current != nil && current.ID == session.ID {
+		helpers.DeleteSessionCookie(c)
+		helpers.DeleteCSRFCookie(c)
+		c.Redirect(http.StatusSeeOther, "/")
+		return
+	}
+	helpers.SetFlashCookie(c, fmt.Sprintf("Signed out the session from %s", session.IPAddress))
+	c.Redirect(http.StatusSeeOther, "/users/my_account")
+}
+
+// createUserSession starts a new session for a user who has just
+// signed in and sets the session cookie. If awaitingSecondFactor is
+// true, the user has to complete their second factor before they can
+// use the session. See completeSecondFactor.
+func createUserSession(c *gin.Context, user *pgmodels.User, awaitingSecondFactor bool) error {
+	session := pgmodels.NewUserSession(user.ID, c.ClientIP(), c.Request.UserAgent())
+	session.AwaitingSecondFactor = awaitingSecondFactor
+	err := session.Save()
+	if err != nil {
+		return err
+	}
+	err = pgmodels.UserSessionDeleteExpired(user.ID)
+	if err != nil {
+		common.Context().Log.Warn().Msgf("Could not delete expired sessions for user %s: %v", user.Email, err)
+	}
+	c.Set("UserSession", session)
+	return helpers.SetSessionCookie(c, session)
+}
+
+// completeSecondFactor lets the current session past the two-factor
+// pages after the user verifies their second factor. Other sessions
+// waiting on a second factor, such as one where someone else signed
+// in with the user's password, still have to complete their own.
+func completeSecondFactor(c *gin.Context) error {
+	session := helpers.CurrentSession(c)
+	if session == nil {
+		return common.ErrNotSignedIn
+	}
+	return session.CompleteSecondFactor()
+}
+
+// endUserSession deletes the session named in the session cookie and
+// clears the session and CSRF cookies. This returns the session's
+// user, or nil if the request had no valid session.
+func endUserSession(c *gin.Context) *pgmodels.User {
+	var user *pgmodels.User
+	token, err := helpers.SessionToken(c)
+	if err == nil {
+		session, err := pgmodels.UserSessionByToken(token)
+		if err == nil {
+			if err = session.Delete(); err != nil {
+				common.Context().Log.Error().Msgf("Could not delete session %d: %v", session.ID, err)
+			}
+			user, err = pgmodels.UserByID(session.UserID)
+			if err != nil {
+				common.Context().Log.Error().Msgf("Could not load user %d for session %d: %v", session.UserID, session.ID, err)
+				user = nil
+			}
+		}
+	}
+	helpers.DeleteSessionCookie(c)
+	helpers.DeleteCSRFCookie(c)
+	return user
+}
@@ -0,0 +1,84 @@
+package webui_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/APTrust/registry/web/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserSessionRevoke(t *testing.T) {
+	testutil.InitHTTPTests(t)
+
+	// Sign the same user in from two browsers.
+	user := testutil.InitUser(t, "user@test.edu")
+	firstClient, firstToken := testutil.InitClient(t, user.Email)
+	secondClient, _ := testutil.InitClient(t, user.Email)
+	defer pgmodels.UserSessionDeleteAll(user.ID)
+
+	sessions, err := pgmodels.UserSessionsForUser(user.ID)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(sessions))
+
+	html := firstClient.GET("/users/my_account").
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "Your Sessions")
+	assert.Contains(t, html, "This browser")
+
+	// Other users can't revoke this user's sessions.
+	for _, session := range sessions {
+		testutil.Inst1UserClient.POST("/user_sessions/revoke/{id}", session.ID).
+			WithFormField(constants.CSRFTokenName, testutil.Inst1UserToken).
+			Expect().Status(http.StatusForbidden)
+	}
+
+	// Sessions are listed most recent first, so the second
+	// browser's session is sessions[0]. Signing it out from the
+	// first browser leaves the first browser signed in.
+	firstClient.POST("/user_sessions/revoke/{id}", sessions[0].ID).
+		WithFormField(constants.CSRFTokenName, firstToken).
+		Expect().Status(http.StatusOK)
+	secondClient.GET("/dashboard").Expect().Status(http.StatusUnauthorized)
+	firstClient.GET("/dashboard").Expect().Status(http.StatusOK)
+
+	sessions, err = pgmodels.UserSessionsForUser(user.ID)
+	require.Nil(t, err)
+	assert.Equal(t, 1, len(sessions))
+}
+
+func TestUserSignOutEverywhere(t *testing.T) {
+	testutil.InitHTTPTests(t)
+
+	user := testutil.InitUser(t, "user@test.edu")
+	firstClient, _ := testutil.InitClient(t, user.Email)
+	secondClient, _ := testutil.InitClient(t, user.Email)
+	defer pgmodels.UserSessionDeleteAll(user.ID)
+
+	html := testutil.SysAdminClient.GET("/users/show/{id}", user.ID).
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "userSignOutEverywhereForm")
+
+	// Institutional admins can't sign out users at other institutions,
+	// and regular users can't sign anyone out.
+	testutil.Inst1AdminClient.POST("/users/sign_out_everywhere/{id}", user.ID).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1AdminToken).
+		Expect().Status(http.StatusForbidden)
+	testutil.Inst1UserClient.POST("/users/sign_out_everywhere/{id}", testutil.Inst1User.ID).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1UserToken).
+		Expect().Status(http.StatusForbidden)
+
+	testutil.SysAdminClient.POST("/users/sign_out_everywhere/{id}", user.ID).
+		WithFormField(constants.CSRFTokenName, testutil.SysAdminToken).
+		Expect().Status(http.StatusOK)
+
+	firstClient.GET("/dashboard").Expect().Status(http.StatusUnauthorized)
+	secondClient.GET("/dashboard").Expect().Status(http.StatusUnauthorized)
+
+	sessions, err := pgmodels.UserSessionsForUser(user.ID)
+	require.Nil(t, err)
+	assert.Empty(t, sessions)
+}
@@ -55,6 +55,56 @@ func UserUndelete(c *gin.Context) {
 	c.Redirect(http.StatusFound, location)
 }
 
+// UserUnlock unlocks an account that was locked after too many failed
+// sign-in attempts.
+// POST /users/unlock/:id
+func UserUnlock(c *gin.Context) {
+	req := NewRequest(c)
+	user, err := pgmodels.UserByID(req.Auth.ResourceID)
+	if AbortIfError(c, err) {
+		return
+	}
+	err = user.Unlock()
+	if AbortIfError(c, err) {
+		return
+	}
+	helpers.Audit(c, constants.AuditAccountUnlocked, constants.OutcomeSuccess, user, changedBy(req, user))
+	helpers.SetFlashCookie(c, fmt.Sprintf("%s's account has been unlocked.", user.Name))
+	location := fmt.Sprintf("/users/show/%d", user.ID)
+	c.Redirect(http.StatusFound, location)
+}
+
+// UserSignOutEverywhere ends all of a user's sessions, signing them
+// out of every browser. Admins use this when they suspect a user's
+// session has been compromised.
+//
+// POST /users/sign_out_everywhere/:id
+func UserSignOutEverywhere(c *gin.Context) {
+	req := NewRequest(c)
+	user, err := pgmodels.UserByID(req.Auth.ResourceID)
+	if AbortIfError(c, err) {
+		return
+	}
+	count, err := pgmodels.UserSessionDeleteAll(user.ID)
+	if AbortIfError(c, err) {
+		return
+	}
+	err = user.SignOut()
+	if AbortIfError(c, err) {
+		return
+	}
+	helpers.Audit(c, constants.AuditSignOut, constants.OutcomeSuccess, user, fmt.Sprintf("Signed out of %d sessions by %s", count, req.CurrentUser.Email))
+	if user.ID == req.CurrentUser.ID {
+		helpers.DeleteSessionCookie(c)
+		helpers.DeleteCSRFCookie(c)
+		c.Redirect(http.StatusFound, "/")
+		return
+	}
+	helpers.SetFlashCookie(c, fmt.Sprintf("%s has been signed out of %d sessions.", user.Name, count))
+	location := fmt.Sprintf("/users/show/%d", user.ID)
+	c.Redirect(http.StatusFound, location)
+}
+
 // UserIndex shows list of users.
 // GET /users
 func UserIndex(c *gin.Context) {
@@ -88,7 +138,12 @@ func UserShow(c *gin.Context) {
 	if AbortIfError(c, err) {
 		return
 	}
+	sessions, err := pgmodels.UserSessionsForUser(user.ID)
+	if AbortIfError(c, err) {
+		return
+	}
 	req.TemplateData["user"] = user
+	req.TemplateData["sessions"] = sessions
 	c.HTML(http.StatusOK, "users/show.html", req.TemplateData)
 }
 
@@ -138,6 +193,7 @@ func UserSignInShow(c *gin.Context) {
 	c.HTML(200, "users/sign_in.html", gin.H{
 		"cover":             helpers.GetCover(),
 		"preFillTestLogins": preFillTestLogins,
+		"ssoEnabled":        common.Context().Config.SSO.Enabled,
 	})
 }
 
@@ -149,8 +205,9 @@ func UserSignIn(c *gin.Context) {
 		c.Redirect(status, redirectTo)
 	} else {
 		c.HTML(status, "users/sign_in.html", gin.H{
-			"error": err.Error(),
-			"cover": helpers.GetCover(),
+			"error":      err.Error(),
+			"cover":      helpers.GetCover(),
+			"ssoEnabled": common.Context().Config.SSO.Enabled,
 		})
 	}
 }
@@ -159,15 +216,18 @@ func UserSignIn(c *gin.Context) {
 // GET /users/sign_out
 func UserSignOut(c *gin.Context) {
 	req := NewRequest(c)
-	if req.CurrentUser != nil {
-		req.CurrentUser.SignOut()
+	// This route is exempt from authentication, so req.CurrentUser
+	// is always nil. Get the user from the session we're ending.
+	user := endUserSession(c)
+	if user != nil {
+		user.SignOut()
+		helpers.Audit(c, constants.AuditSignOut, constants.OutcomeSuccess, user, "")
 	}
-	helpers.DeleteSessionCookie(c)
-	helpers.DeleteCSRFCookie(c)
 	c.HTML(http.StatusOK, "users/sign_in.html", gin.H{
 		"cover":             helpers.GetCover(),
 		"preFillTestLogins": common.Context().Config.EnvName == "test",
 		"error":             req.TemplateData["flash"],
+		"ssoEnabled":        common.Context().Config.SSO.Enabled,
 	})
 }
 
@@ -237,6 +297,7 @@ func UserChangePassword(c *gin.Context) {
 	if AbortIfError(c, err) {
 		return
 	}
+	helpers.Audit(c, constants.AuditPasswordChanged, constants.OutcomeSuccess, userToEdit, changedBy(req, userToEdit))
 
 	// Create a password changed alert, so we know this
 	// happened and user knows too. If user gets a suspicious
@@ -312,6 +373,7 @@ func UserInitPasswordReset(c *gin.Context) {
 	if AbortIfError(c, err) {
 		return
 	}
+	helpers.Audit(c, constants.AuditPasswordResetRequest, constants.OutcomeSuccess, userToEdit, changedBy(req, userToEdit))
 	req.TemplateData["user"] = userToEdit
 	c.HTML(http.StatusOK, "users/reset_password_initiated.html", req.TemplateData)
 }
@@ -386,6 +448,7 @@ func UserCompletePasswordReset(c *gin.Context) {
 	// But we don't want to tell hackers that, so we'll just let them fail.
 	if !common.ComparePasswords(user.ResetPasswordToken, token) {
 		common.Context().Log.Error().Msgf("POST /users/complete_password_reset/%d got wrong token", userID)
+		helpers.Audit(c, constants.AuditPasswordReset, constants.OutcomeFailure, user, "Invalid reset token")
 		AbortIfError(c, common.ErrInvalidToken)
 		return
 	}
@@ -404,10 +467,11 @@ func UserCompletePasswordReset(c *gin.Context) {
 		return
 	}
 
-	err = helpers.SetSessionCookie(c, user)
+	err = createUserSession(c, user, false)
 	if AbortIfError(c, err) {
 		return
 	}
+	helpers.Audit(c, constants.AuditPasswordReset, constants.OutcomeSuccess, user, "Reset token accepted")
 	c.Set("CurrentUser", user)
 	pageURL := fmt.Sprintf("/users/change_password/%d", user.ID)
 	c.Redirect(http.StatusFound, pageURL)
@@ -441,14 +505,59 @@ func UserGetAPIKey(c *gin.Context) {
 }
 
 // UserMyAccount displays the user's account info. From this page, they
-// can see account details, change their password, and get an API key.
+// can see account details, change their password, and manage their
+// API keys.
 //
 // GET /users/my_account
 func UserMyAccount(c *gin.Context) {
 	req := NewRequest(c)
+	apiKeys, err := pgmodels.APIKeysForUser(req.CurrentUser.ID)
+	if AbortIfError(c, err) {
+		return
+	}
+	req.TemplateData["apiKeys"] = apiKeys
+	securityKeys, err := pgmodels.WebAuthnCredentialsForUser(req.CurrentUser.ID)
+	if AbortIfError(c, err) {
+		return
+	}
+	req.TemplateData["securityKeys"] = securityKeys
+	sessions, err := pgmodels.UserSessionsForUser(req.CurrentUser.ID)
+	if AbortIfError(c, err) {
+		return
+	}
+	req.TemplateData["sessions"] = sessions
+	currentSessionID := int64(0)
+	if current := helpers.CurrentSession(c); current != nil {
+		currentSessionID = current.ID
+	}
+	req.TemplateData["currentSessionID"] = currentSessionID
+	req.TemplateData["alertPrefsForm"] = forms.NewAlertPreferencesForm(req.CurrentUser)
 	c.HTML(http.StatusOK, "users/my_account.html", req.TemplateData)
 }
 
+// UserUpdateAlertPreferences saves the current user's choices about
+// how to receive each type of alert. Users can change only their own
+// preferences.
+//
+// POST /users/alert_preferences
+func UserUpdateAlertPreferences(c *gin.Context) {
+	req := NewRequest(c)
+	user, err := pgmodels.UserByID(req.CurrentUser.ID)
+	if AbortIfError(c, err) {
+		return
+	}
+	user.AlertPreferences = c.PostFormMap("AlertPreferences")
+	form := forms.NewAlertPreferencesForm(user)
+	req.TemplateData["alertPrefsForm"] = form
+	if form.Save() {
+		helpers.SetFlashCookie(c, "Your alert preferences have been saved.")
+		c.Redirect(form.Status, form.PostSaveURL())
+	} else {
+		req.TemplateData["FormError"] = form.Error
+		c.HTML(form.Status, form.Template, req.TemplateData)
+	}
+}
+
 // GET /users/forgot_password
 func UserShowForgotPasswordForm(c *gin.Context) {
 	req := NewRequest(c)
@@ -470,10 +579,14 @@ func UserSendForgotPasswordMessage(c *gin.Context) {
 	email := c.PostForm("email")
 	userToEdit, err := pgmodels.UserByEmail(email)
 	if userToEdit == nil || userToEdit.ID == 0 || pgmodels.IsNoRowError(err) {
+		event := helpers.NewAuditEvent(c, constants.AuditPasswordResetRequest, constants.OutcomeFailure, nil, "Unknown user")
+		event.UserEmail = email
+		helpers.RecordAuditEvent(event)
 		AbortIfError(c, fmt.Errorf("We have no account associated with that email address."))
 		return
 	}
 	if !userToEdit.DeactivatedAt.IsZero() {
+		helpers.Audit(c, constants.AuditPasswordResetRequest, constants.OutcomeFailure, userToEdit, "Account deactivated")
 		AbortIfError(c, fmt.Errorf("That account has been deactivated. Contact your local APTrust administrator."))
 		return
 	}
@@ -485,6 +598,7 @@ func UserSendForgotPasswordMessage(c *gin.Context) {
 	if AbortIfError(c, err) {
 		return
 	}
+	helpers.Audit(c, constants.AuditPasswordResetRequest, constants.OutcomeSuccess, userToEdit, "Forgot password")
 	c.HTML(http.StatusOK, "users/forgot_password_confirmation.html", req.TemplateData)
 }
 
@@ -492,26 +606,90 @@ func UserSendForgotPasswordMessage(c *gin.Context) {
 // If user has two-factor auth, this is the first step of login.
 func SignInUser(c *gin.Context) (int, string, error) {
 	redirectTo := "/users/sign_in"
-	user, err := pgmodels.UserSignIn(
-		c.PostForm("email"),
-		c.PostForm("password"),
-		c.ClientIP(),
-	)
+	var user *pgmodels.User
+	err := checkIPThrottle(c)
+	if err == nil {
+		user, err = pgmodels.UserSignIn(
+			c.PostForm("email"),
+			c.PostForm("password"),
+			c.ClientIP(),
+		)
+	}
 	if err != nil {
 		c.Error(err)
 		helpers.DeleteSessionCookie(c)
-		return http.StatusBadRequest, redirectTo, err
+		signInFailure(c, c.PostForm("email"), err)
+		return signInErrorStatus(err), redirectTo, signInClientError(err)
 	}
+	status, redirectTo, err := startUserSession(c, user)
+	if err == nil {
+		helpers.Audit(c, constants.AuditSignIn, constants.OutcomeSuccess, user, "Password")
+	}
+	return status, redirectTo, err
+}
 
-	// Set this flag for two factor users.
-	// Be sure to save this, or user can bypass 2fa on next request.
-	user.AwaitingSecondFactor = user.IsTwoFactorUser()
-	err = user.Save()
-	if err != nil {
-		return http.StatusInternalServerError, redirectTo, err
+// signInFailure records a failed sign-in in the audit log. If the email
+// address belongs to a known user, the event goes to that user's
+// institution, and a wrong password counts toward locking their account.
+// Otherwise, the event is visible only to APTrust admins.
+//
+// Attempts we rejected because the account was locked or the client was
+// throttled are recorded as Sign In Blocked, not Sign In. Only Sign In
+// failures count toward the IP throttle, so a client that keeps trying
+// while blocked doesn't extend its own block.
+func signInFailure(c *gin.Context, email string, err error) {
+	user, _ := pgmodels.UserByEmail(email)
+	if user != nil && user.ID == 0 {
+		user = nil
+	}
+	action := constants.AuditSignIn
+	if isSignInBlocked(err) {
+		action = constants.AuditSignInBlocked
+	}
+	event := helpers.NewAuditEvent(c, action, constants.OutcomeFailure, user, err.Error())
+	event.UserEmail = email
+	helpers.RecordAuditEvent(event)
+	if user != nil && err == common.ErrInvalidLogin {
+		recordLoginFailure(NewRequest(c), user)
+	}
+}
+
+// changedBy returns a note for the audit log saying who changed the
+// subject user's account, if it wasn't the subject themselves.
+func changedBy(req *Request, subject *pgmodels.User) string {
+	if req.CurrentUser == nil || req.CurrentUser.ID == subject.ID {
+		return ""
 	}
+	return fmt.Sprintf("Changed by %s", req.CurrentUser.Email)
+}
+
+// auditRoleChange records a change to a user's role, including the
+// role given to a newly created user.
+func auditRoleChange(req *Request, user *pgmodels.User, oldRole string) {
+	if oldRole == user.Role {
+		return
+	}
+	detail := fmt.Sprintf("Role changed from %s to %s.", oldRole, user.Role)
+	if oldRole == "" {
+		detail = fmt.Sprintf("New user with role %s.", user.Role)
+	}
+	if note := changedBy(req, user); note != "" {
+		detail = fmt.Sprintf("%s %s.", detail, note)
+	}
+	helpers.Audit(req.GinContext, constants.AuditRoleChanged, constants.OutcomeSuccess, user, detail)
+}
+
+// startUserSession sets the session and CSRF cookies for a user who
+// has just signed in with a password or through their institution's
+// identity provider. Two-factor users still have to complete their
+// second factor. This returns the status code and URL for the
+// redirect that follows sign-in.
+func startUserSession(c *gin.Context, user *pgmodels.User) (int, string, error) {
+	redirectTo := "/users/sign_in"
 
-	err = helpers.SetSessionCookie(c, user)
+	// Two-factor users can't use this session for anything but the
+	// two-factor pages until they complete their second factor.
+	err := createUserSession(c, user, user.IsTwoFactorUser())
 	if err != nil {
 		return http.StatusInternalServerError, redirectTo, err
 	}
@@ -544,6 +722,7 @@ func UserUpdateXHR(c *gin.Context) {
 	if api.AbortIfError(c, err) {
 		return
 	}
+	oldRole := userToEdit.Role
 	if strings.TrimSpace(c.PostForm("Name")) != "" {
 		userToEdit.Name = strings.TrimSpace(c.PostForm("Name"))
 	}
@@ -580,6 +759,7 @@ func UserUpdateXHR(c *gin.Context) {
 	if api.AbortIfError(c, userToEdit.Save()) {
 		return
 	}
+	auditRoleChange(req, userToEdit, oldRole)
 	returnValue := map[string]interface{}{
 		"StatusCode": http.StatusOK,
 		"Message":    "Update succeeded.",
@@ -590,6 +770,7 @@ func UserUpdateXHR(c *gin.Context) {
 func saveUserForm(c *gin.Context) {
 	req := NewRequest(c)
 	userToEdit := &pgmodels.User{}
+	oldRole := ""
 	var err error
 	if req.Auth.ResourceID > 0 {
 		// Load existing user.
@@ -597,6 +778,7 @@ func saveUserForm(c *gin.Context) {
 		if AbortIfError(c, err) {
 			return
 		}
+		oldRole = userToEdit.Role
 	} else {
 		// Assign random password to new user. They'll get an email
 		// asking them to reset their password.
@@ -623,6 +805,7 @@ func saveUserForm(c *gin.Context) {
 				return
 			}
 		}
+		auditRoleChange(req, userToEdit, oldRole)
 		c.Redirect(form.Status, form.PostSaveURL())
 	} else {
 		req.TemplateData["FormError"] = form.Error
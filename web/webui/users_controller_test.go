@@ -4,9 +4,11 @@ import (
 	"net/http"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/APTrust/registry/common"
 	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/db"
 	"github.com/APTrust/registry/pgmodels"
 	"github.com/APTrust/registry/web/testutil"
 	"github.com/stretchr/testify/assert"
@@ -493,8 +495,13 @@ func TestUserMyAccount(t *testing.T) {
 	testutil.InitHTTPTests(t)
 
 	items := []string{
-		"Get API Key",
+		"New API Key",
+		"API Keys",
+		"Add Security Key",
+		"Security Keys",
 		"Change Password",
+		"Alert Notifications",
+		`name="AlertPreferences[Failed Fixity Check]"`,
 	}
 
 	for _, client := range testutil.AllClients {
@@ -504,6 +511,33 @@ func TestUserMyAccount(t *testing.T) {
 	}
 }
 
+func TestUserUpdateAlertPreferences(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+	testutil.InitHTTPTests(t)
+
+	html := testutil.Inst1UserClient.POST("/users/alert_preferences").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1UserToken).
+		WithFormField("AlertPreferences[Failed Fixity Check]", constants.AlertDeliveryWeekly).
+		WithFormField("AlertPreferences[Deletion Completed]", constants.AlertDeliveryInApp).
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "Your alert preferences have been saved.")
+
+	user, err := pgmodels.UserByID(testutil.Inst1User.ID)
+	require.Nil(t, err)
+	assert.Equal(t, constants.AlertDeliveryWeekly, user.AlertDeliveryFor(constants.AlertFailedFixity))
+	assert.Equal(t, constants.AlertDeliveryInApp, user.AlertDeliveryFor(constants.AlertDeletionCompleted))
+	assert.Equal(t, constants.AlertDeliveryImmediate, user.AlertDeliveryFor(constants.AlertDeletionRequested))
+
+	// Invalid choices are rejected.
+	testutil.Inst1UserClient.POST("/users/alert_preferences").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1UserToken).
+		WithFormField("AlertPreferences[Failed Fixity Check]", "hourly").
+		Expect().Status(http.StatusBadRequest)
+}
+
 func TestUserForgotPassword(t *testing.T) {
 	testutil.InitHTTPTests(t)
 
@@ -595,3 +629,86 @@ func TestUserSignInShow(t *testing.T) {
 		assert.Contains(t, html, "Deposits by Storage Option")
 	}
 }
+
+func TestUserLockoutAndUnlock(t *testing.T) {
+	testutil.InitHTTPTests(t)
+	defer func() {
+		user, err := pgmodels.UserByID(testutil.Inst1User.ID)
+		require.Nil(t, err)
+		require.Nil(t, user.Unlock())
+	}()
+
+	client := testutil.GetAnonymousClient(t)
+	maxFailures := common.Context().Config.Lockout.MaxFailures
+	for i := 0; i < maxFailures; i++ {
+		client.POST("/users/sign_in").
+			WithHeader("Referer", testutil.BaseURL).
+			WithFormField("email", testutil.Inst1User.Email).
+			WithFormField("password", "invalid-password").
+			Expect().Status(http.StatusBadRequest)
+	}
+
+	// Account is now locked, so even the right password fails.
+	// The client sees the same error as for a wrong password,
+	// while the audit log records the real reason.
+	html := client.POST("/users/sign_in").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField("email", testutil.Inst1User.Email).
+		WithFormField("password", "password").
+		Expect().Status(http.StatusBadRequest).Body().Raw()
+	assert.Contains(t, html, common.ErrInvalidLogin.Error())
+	assert.NotContains(t, html, "locked")
+
+	query := pgmodels.NewQuery().
+		Where("user_email", "=", testutil.Inst1User.Email).
+		Where("action", "=", constants.AuditSignInBlocked).
+		Where("detail", "=", common.ErrAccountLocked.Error())
+	event, err := pgmodels.AuditEventGet(query)
+	require.Nil(t, err)
+	require.NotNil(t, event)
+
+	// Blocked attempts don't count toward the IP throttle.
+	count, _, err := pgmodels.AuditFailuresFromIP(event.IPAddress, time.Now().Add(-time.Hour))
+	require.Nil(t, err)
+	client.POST("/users/sign_in").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField("email", testutil.Inst1User.Email).
+		WithFormField("password", "password").
+		Expect().Status(http.StatusBadRequest)
+	newCount, _, err := pgmodels.AuditFailuresFromIP(event.IPAddress, time.Now().Add(-time.Hour))
+	require.Nil(t, err)
+	assert.Equal(t, count, newCount)
+
+	// User should get an alert about the lock.
+	query = pgmodels.NewQuery().
+		Where("type", "=", constants.AlertAccountLocked).
+		Where("user_id", "=", testutil.Inst1User.ID)
+	alertView, err := pgmodels.AlertViewGet(query)
+	require.Nil(t, err)
+	require.NotNil(t, alertView)
+
+	// Inst admin sees the lock and can release it.
+	html = testutil.Inst1AdminClient.GET("/users/show/{id}", testutil.Inst1User.ID).
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "userUnlockForm")
+
+	// Regular users can't unlock accounts.
+	testutil.Inst1UserClient.POST("/users/unlock/{id}", testutil.Inst1User.ID).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1UserToken).
+		Expect().Status(http.StatusForbidden)
+
+	testutil.Inst1AdminClient.POST("/users/unlock/{id}", testutil.Inst1User.ID).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1AdminToken).
+		Expect().Status(http.StatusOK)
+
+	user, err := pgmodels.UserByID(testutil.Inst1User.ID)
+	require.Nil(t, err)
+	assert.False(t, user.IsLocked())
+	assert.Equal(t, 0, user.FailedLoginAttempts)
+
+	client.POST("/users/sign_in").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField("email", testutil.Inst1User.Email).
+		WithFormField("password", "password").
+		Expect().Status(http.StatusOK)
+}
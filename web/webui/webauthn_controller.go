@@ -0,0 +1,361 @@
+package webui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/helpers"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/APTrust/registry/web/api"
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// WebAuthnSessionTimeout is how long a user has to complete a
+// security key registration or login after we issue the challenge.
+const WebAuthnSessionTimeout = 5 * time.Minute
+
+// webAuthnSession is what we store in the WebAuthn session cookie
+// between the begin and finish steps of a registration or login.
+type webAuthnSession struct {
+	Data      webauthn.SessionData
+	CreatedAt time.Time
+}
+
+// UserWebAuthnRegisterShow shows the page on which users name and
+// register a new security key or passkey. If query param primary is
+// true, the new key becomes the user's two-factor method.
+//
+// GET /users/webauthn_register
+func UserWebAuthnRegisterShow(c *gin.Context) {
+	req := NewRequest(c)
+	req.TemplateData["primary"] = c.Query("primary") == "true"
+	c.HTML(http.StatusOK, "users/webauthn_register.html", req.TemplateData)
+}
+
+// UserWebAuthnRegisterBegin returns the options the browser needs to
+// create a new credential. The browser passes these to
+// navigator.credentials.create().
+//
+// POST /users/webauthn_register/begin
+func UserWebAuthnRegisterBegin(c *gin.Context) {
+	req := NewRequest(c)
+	web, wu, err := webAuthnFor(req)
+	if api.AbortIfError(c, err) {
+		return
+	}
+	options, session, err := web.BeginRegistration(wu, webauthn.WithExclusions(wu.CredentialDescriptors()))
+	if api.AbortIfError(c, err) {
+		return
+	}
+	err = setWebAuthnSession(c, session)
+	if api.AbortIfError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, options)
+}
+
+// UserWebAuthnRegisterFinish verifies the new credential the browser
+// created and saves it. Query param name is the name the user gave
+// the key. If query param primary is true, or if the user has no
+// two-factor method yet, security keys become the user's two-factor
+// method.
+//
+// POST /users/webauthn_register/finish
+func UserWebAuthnRegisterFinish(c *gin.Context) {
+	req := NewRequest(c)
+	web, wu, err := webAuthnFor(req)
+	if api.AbortIfError(c, err) {
+		return
+	}
+	session, err := getWebAuthnSession(c)
+	if api.AbortIfError(c, err) {
+		return
+	}
+	credential, err := web.FinishRegistration(wu, session, c.Request)
+	if err != nil {
+		common.Context().Log.Warn().Msgf("Security key registration failed for user %s: %v", req.CurrentUser.Email, err)
+		api.AbortIfError(c, common.ErrWebAuthnVerification)
+		return
+	}
+	name := strings.TrimSpace(c.Query("name"))
+	if name == "" {
+		name = "Security Key"
+	}
+	cred := pgmodels.NewWebAuthnCredential(req.CurrentUser.ID, name, credential)
+	err = cred.Save()
+	if api.AbortIfError(c, err) {
+		return
+	}
+
+	user := req.CurrentUser
+	msg := fmt.Sprintf("Registered security key %s. You can use it to complete two-factor login.", cred.Name)
+	if c.Query("primary") == "true" || !user.IsTwoFactorUser() {
+		user.AuthyStatus = constants.TwoFactorWebAuthn
+		user.EnabledTwoFactor = true
+		user.ConfirmedTwoFactor = true
+		err = user.Save()
+		if api.AbortIfError(c, err) {
+			return
+		}
+		msg = fmt.Sprintf("Registered security key %s. Next time you log in, you'll use it to complete the login process.", cred.Name)
+	}
+	helpers.SetFlashCookie(c, msg)
+	c.JSON(http.StatusOK, gin.H{"location": "/users/my_account"})
+}
+
+// UserTwoFactorWebAuthn shows the page on which the user completes
+// two-factor login with a security key or passkey.
+//
+// GET /users/2fa_webauthn/
+func UserTwoFactorWebAuthn(c *gin.Context) {
+	req := NewRequest(c)
+	c.HTML(http.StatusOK, "users/enter_security_key.html", req.TemplateData)
+}
+
+// UserTwoFactorWebAuthnBegin returns the challenge the user's security
+// key must sign to complete login. The browser passes these options to
+// navigator.credentials.get().
+//
+// POST /users/2fa_webauthn/begin
+func UserTwoFactorWebAuthnBegin(c *gin.Context) {
+	req := NewRequest(c)
+	web, wu, err := webAuthnFor(req)
+	if api.AbortIfError(c, err) {
+		return
+	}
+	if len(wu.Credentials) == 0 {
+		api.AbortIfError(c, common.ErrNoWebAuthnCredentials)
+		return
+	}
+	options, session, err := web.BeginLogin(wu)
+	if api.AbortIfError(c, err) {
+		return
+	}
+	err = setWebAuthnSession(c, session)
+	if api.AbortIfError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, options)
+}
+
+// UserTwoFactorWebAuthnFinish verifies the security key's signature
+// and, if it's valid, completes the user's login. Failed attempts
+// count toward the account lockout and the IP throttle, just like
+// wrong codes in UserTwoFactorVerify.
+//
+// POST /users/2fa_webauthn/finish
+func UserTwoFactorWebAuthnFinish(c *gin.Context) {
+	req := NewRequest(c)
+	user := req.CurrentUser
+
+	// As in UserTwoFactorVerify, check throttling before we look at
+	// the signature, so there's no way to keep trying while locked out.
+	err := checkIPThrottle(c)
+	if err == nil {
+		err = user.CheckLoginThrottle()
+	}
+	if err == common.ErrAccountLocked {
+		signOutLockedWebAuthnUser(c, user)
+		return
+	} else if err != nil {
+		api.AbortIfError(c, common.ErrTooManyAttempts)
+		return
+	}
+
+	web, wu, err := webAuthnFor(req)
+	if api.AbortIfError(c, err) {
+		return
+	}
+	session, err := getWebAuthnSession(c)
+	if api.AbortIfError(c, err) {
+		return
+	}
+	credential, err := web.FinishLogin(wu, session, c.Request)
+	if err != nil {
+		common.Context().Log.Warn().Msgf("Security key login failed for user %s: %v", user.Email, err)
+		webAuthnLoginFailed(req, "Security key verification failed")
+		return
+	}
+	cred := wu.Find(credential.ID)
+	if cred == nil {
+		webAuthnLoginFailed(req, "Unknown security key")
+		return
+	}
+	if credential.Authenticator.CloneWarning {
+		common.Context().Log.Warn().Msgf("Security key %d for user %s may have been cloned: signature count did not increase", cred.ID, req.CurrentUser.Email)
+	}
+	err = cred.RecordUse(credential.Authenticator)
+	if api.AbortIfError(c, err) {
+		return
+	}
+	user.ClearFailedLogins()
+	err = user.Save()
+	if api.AbortIfError(c, err) {
+		return
+	}
+	err = completeSecondFactor(c)
+	if api.AbortIfError(c, err) {
+		return
+	}
+	event := helpers.NewAuditEvent(c, constants.AuditSecondFactor, constants.OutcomeSuccess, user, fmt.Sprintf("Security key %s", cred.Name))
+	event.ResourceType = "WebAuthnCredential"
+	event.ResourceID = cred.ID
+	helpers.RecordAuditEvent(event)
+	c.JSON(http.StatusOK, gin.H{"location": "/dashboard"})
+}
+
+// webAuthnLoginFailed records a failed security key login in the audit
+// log and against the user's account, as UserTwoFactorVerify does for
+// a wrong code. If that locks the account, we sign the user out.
+func webAuthnLoginFailed(req *Request, detail string) {
+	c := req.GinContext
+	user := req.CurrentUser
+	helpers.Audit(c, constants.AuditSecondFactor, constants.OutcomeFailure, user, detail)
+	recordLoginFailure(req, user)
+	if user.IsLocked() {
+		signOutLockedWebAuthnUser(c, user)
+		return
+	}
+	api.AbortIfError(c, common.ErrWebAuthnVerification)
+}
+
+// signOutLockedWebAuthnUser is like signOutLockedUser, but it responds
+// with JSON, since the browser calls the security key endpoints from
+// JavaScript.
+func signOutLockedWebAuthnUser(c *gin.Context, user *pgmodels.User) {
+	err := user.SignOut()
+	if err != nil {
+		common.Context().Log.Error().Msgf("Error signing out locked user %s: %v", user.Email, err)
+	}
+	endUserSession(c)
+	api.AbortIfError(c, common.ErrAccountLocked)
+}
+
+// WebAuthnCredentialRename renames one of the current user's security
+// keys. Users can rename only their own keys.
+//
+// POST /webauthn_credentials/rename/:id
+func WebAuthnCredentialRename(c *gin.Context) {
+	req := NewRequest(c)
+	cred, err := webAuthnCredentialForCurrentUser(req)
+	if AbortIfError(c, err) {
+		return
+	}
+	oldName := cred.Name
+	cred.Name = strings.TrimSpace(c.PostForm("Name"))
+	err = cred.Save()
+	if valErr, ok := err.(*common.ValidationError); ok {
+		helpers.SetFlashCookie(c, valErr.Errors["Name"])
+	} else if AbortIfError(c, err) {
+		return
+	} else {
+		helpers.SetFlashCookie(c, fmt.Sprintf("Renamed security key %s to %s", oldName, cred.Name))
+	}
+	c.Redirect(http.StatusSeeOther, "/users/my_account")
+}
+
+// WebAuthnCredentialRevoke revokes one of the current user's security
+// keys. Users can revoke only their own keys. If the user's two-factor
+// method is security keys and they revoke their last one, this turns
+// off two-factor authentication, since they'd otherwise be locked out.
+//
+// POST /webauthn_credentials/revoke/:id
+func WebAuthnCredentialRevoke(c *gin.Context) {
+	req := NewRequest(c)
+	cred, err := webAuthnCredentialForCurrentUser(req)
+	if AbortIfError(c, err) {
+		return
+	}
+	err = cred.Revoke()
+	if AbortIfError(c, err) {
+		return
+	}
+	msg := fmt.Sprintf("Revoked security key %s", cred.Name)
+	user := req.CurrentUser
+	if user.IsWebAuthnUser() {
+		remaining, err := pgmodels.ActiveWebAuthnCredentials(user.ID)
+		if AbortIfError(c, err) {
+			return
+		}
+		if len(remaining) == 0 {
+			user.AuthyStatus = constants.TwoFactorNone
+			user.EnabledTwoFactor = false
+			err = user.Save()
+			if AbortIfError(c, err) {
+				return
+			}
+			msg = fmt.Sprintf("Revoked security key %s. That was your last security key, so two-factor authentication has been turned off for your account.", cred.Name)
+		}
+	}
+	helpers.SetFlashCookie(c, msg)
+	c.Redirect(http.StatusSeeOther, "/users/my_account")
+}
+
+// webAuthnFor returns a WebAuthn relying party for the current request,
+// along with the current user and their active security keys.
+func webAuthnFor(req *Request) (*webauthn.WebAuthn, *pgmodels.WebAuthnUser, error) {
+	web, err := common.NewWebAuthn(req.BaseURL())
+	if err != nil {
+		return nil, nil, err
+	}
+	wu, err := pgmodels.NewWebAuthnUser(req.CurrentUser)
+	return web, wu, err
+}
+
+// webAuthnCredentialForCurrentUser returns the credential whose ID is
+// in the request URL, or ErrPermissionDenied if it belongs to someone
+// other than the current user.
+func webAuthnCredentialForCurrentUser(req *Request) (*pgmodels.WebAuthnCredential, error) {
+	cred, err := pgmodels.WebAuthnCredentialByID(req.Auth.ResourceID)
+	if err != nil {
+		return nil, err
+	}
+	if cred.UserID != req.CurrentUser.ID {
+		common.Context().Log.Warn().Msgf("Permission denied: User %d tried to change security key %d belonging to user %d", req.CurrentUser.ID, cred.ID, cred.UserID)
+		return nil, common.ErrPermissionDenied
+	}
+	return cred, nil
+}
+
+// setWebAuthnSession saves WebAuthn session data in an encrypted cookie
+// so we can check the browser's response in the finish step.
+func setWebAuthnSession(c *gin.Context, data *webauthn.SessionData) error {
+	session := &webAuthnSession{
+		Data:      *data,
+		CreatedAt: time.Now().UTC(),
+	}
+	jsonBytes, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return helpers.SetCookie(c, constants.WebAuthnCookieName, string(jsonBytes))
+}
+
+// getWebAuthnSession returns the WebAuthn session data saved by
+// setWebAuthnSession and deletes the cookie, so each challenge can be
+// used only once. This returns common.ErrDecodeCookie if the session
+// is missing, invalid, or more than WebAuthnSessionTimeout old.
+func getWebAuthnSession(c *gin.Context) (webauthn.SessionData, error) {
+	defer helpers.DeleteCookie(c, constants.WebAuthnCookieName)
+	session := &webAuthnSession{}
+	cookie, err := c.Cookie(constants.WebAuthnCookieName)
+	if err != nil {
+		return session.Data, common.ErrDecodeCookie
+	}
+	value := ""
+	err = common.Context().Config.Cookies.Secure.Decode(constants.WebAuthnCookieName, cookie, &value)
+	if err != nil {
+		return session.Data, common.ErrDecodeCookie
+	}
+	err = json.Unmarshal([]byte(value), session)
+	if err != nil || time.Since(session.CreatedAt) > WebAuthnSessionTimeout {
+		return session.Data, common.ErrDecodeCookie
+	}
+	return session.Data, nil
+}
@@ -0,0 +1,356 @@
+package webui_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/APTrust/registry/web/testutil"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebAuthnRegisterAndLogin(t *testing.T) {
+	testutil.InitHTTPTests(t)
+	user, err := pgmodels.UserByEmail(testutil.Inst2User.Email)
+	require.Nil(t, err)
+	defer func() {
+		user.AuthyStatus = testutil.Inst2User.AuthyStatus
+		user.EnabledTwoFactor = testutil.Inst2User.EnabledTwoFactor
+		user.ConfirmedTwoFactor = testutil.Inst2User.ConfirmedTwoFactor
+		require.Nil(t, user.Save())
+	}()
+	require.False(t, user.IsTwoFactorUser())
+
+	client, token := testutil.InitClient(t, user.Email)
+	html := client.GET("/users/webauthn_register").
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "Register a Security Key")
+
+	// Register a key. Since the user has no other second
+	// factor, it becomes their two-factor method.
+	auth := newSoftAuthenticator(t)
+	options := client.POST("/users/webauthn_register/begin").
+		WithHeader(constants.CSRFHeaderName, token).
+		Expect().Status(http.StatusOK).Body().Raw()
+	client.POST("/users/webauthn_register/finish").
+		WithQuery("name", "My YubiKey").
+		WithHeader(constants.CSRFHeaderName, token).
+		WithBytes(auth.register(t, []byte(options), testutil.BaseURL)).
+		WithHeader("Content-Type", "application/json").
+		Expect().Status(http.StatusOK).
+		JSON().Object().Value("location").Equal("/users/my_account")
+
+	creds, err := pgmodels.ActiveWebAuthnCredentials(user.ID)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(creds))
+	assert.Equal(t, "My YubiKey", creds[0].Name)
+
+	user, err = pgmodels.UserByID(user.ID)
+	require.Nil(t, err)
+	assert.True(t, user.IsWebAuthnUser())
+
+	html = client.GET("/users/my_account").
+		Expect().Status(http.StatusOK).Body().Raw()
+	testutil.AssertMatchesAll(t, html, []string{
+		"Security Keys",
+		"My YubiKey",
+		fmt.Sprintf("/webauthn_credentials/revoke/%d", creds[0].ID),
+	})
+
+	// Now sign in again. User should have to use the key.
+	client, token = testutil.InitClient(t, user.Email)
+	html = client.GET("/users/2fa_choose").
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "submitSecondFactor('webauthn')")
+	client.GET("/dashboard").Expect().Status(http.StatusOK).
+		Body().Contains("Multi-Factor Authentication Required")
+
+	html = client.GET("/users/2fa_webauthn").
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "Use Your Security Key")
+
+	// Wrong origin should fail.
+	options = client.POST("/users/2fa_webauthn/begin").
+		WithHeader(constants.CSRFHeaderName, token).
+		Expect().Status(http.StatusOK).Body().Raw()
+	client.POST("/users/2fa_webauthn/finish").
+		WithHeader(constants.CSRFHeaderName, token).
+		WithBytes(auth.login(t, []byte(options), "https://example.com")).
+		WithHeader("Content-Type", "application/json").
+		Expect().Status(http.StatusBadRequest)
+
+	// Each challenge can be used only once.
+	client.POST("/users/2fa_webauthn/finish").
+		WithHeader(constants.CSRFHeaderName, token).
+		WithBytes(auth.login(t, []byte(options), testutil.BaseURL)).
+		WithHeader("Content-Type", "application/json").
+		Expect().Status(http.StatusBadRequest)
+
+	options = client.POST("/users/2fa_webauthn/begin").
+		WithHeader(constants.CSRFHeaderName, token).
+		Expect().Status(http.StatusOK).Body().Raw()
+	client.POST("/users/2fa_webauthn/finish").
+		WithHeader(constants.CSRFHeaderName, token).
+		WithBytes(auth.login(t, []byte(options), testutil.BaseURL)).
+		WithHeader("Content-Type", "application/json").
+		Expect().Status(http.StatusOK).
+		JSON().Object().Value("location").Equal("/dashboard")
+
+	client.GET("/dashboard").Expect().Status(http.StatusOK).
+		Body().NotContains("Multi-Factor Authentication Required")
+
+	cred, err := pgmodels.WebAuthnCredentialByID(creds[0].ID)
+	require.Nil(t, err)
+	assert.False(t, cred.LastUsedAt.IsZero())
+	assert.True(t, cred.SignCount > 0)
+
+	// Rename
+	client.POST(fmt.Sprintf("/webauthn_credentials/rename/%d", cred.ID)).
+		WithFormField(constants.CSRFTokenName, token).
+		WithFormField("Name", "Keychain Key").
+		Expect().Status(http.StatusOK)
+	cred, err = pgmodels.WebAuthnCredentialByID(cred.ID)
+	require.Nil(t, err)
+	assert.Equal(t, "Keychain Key", cred.Name)
+
+	// Other users can't rename or revoke this key.
+	testutil.Inst1AdminClient.POST(fmt.Sprintf("/webauthn_credentials/revoke/%d", cred.ID)).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1AdminToken).
+		Expect().Status(http.StatusForbidden)
+	testutil.Inst2AdminClient.POST(fmt.Sprintf("/webauthn_credentials/rename/%d", cred.ID)).
+		WithFormField(constants.CSRFTokenName, testutil.Inst2AdminToken).
+		WithFormField("Name", "Mine Now").
+		Expect().Status(http.StatusForbidden)
+
+	// Revoking the last key turns off two-factor auth.
+	client.POST(fmt.Sprintf("/webauthn_credentials/revoke/%d", cred.ID)).
+		WithFormField(constants.CSRFTokenName, token).
+		Expect().Status(http.StatusOK).
+		Body().Contains("two-factor authentication has been turned off")
+	cred, err = pgmodels.WebAuthnCredentialByID(cred.ID)
+	require.Nil(t, err)
+	assert.True(t, cred.IsRevoked())
+	user, err = pgmodels.UserByID(user.ID)
+	require.Nil(t, err)
+	assert.False(t, user.IsTwoFactorUser())
+
+	client.POST("/users/2fa_webauthn/begin").
+		WithHeader(constants.CSRFHeaderName, token).
+		Expect().Status(http.StatusBadRequest)
+}
+
+func TestWebAuthnLoginLockout(t *testing.T) {
+	testutil.InitHTTPTests(t)
+	user, err := pgmodels.UserByEmail(testutil.Inst2User.Email)
+	require.Nil(t, err)
+	defer func() {
+		user, err := pgmodels.UserByID(testutil.Inst2User.ID)
+		require.Nil(t, err)
+		require.Nil(t, user.Unlock())
+		creds, err := pgmodels.ActiveWebAuthnCredentials(user.ID)
+		require.Nil(t, err)
+		for _, cred := range creds {
+			require.Nil(t, cred.Revoke())
+		}
+		user.AuthyStatus = testutil.Inst2User.AuthyStatus
+		user.EnabledTwoFactor = testutil.Inst2User.EnabledTwoFactor
+		user.ConfirmedTwoFactor = testutil.Inst2User.ConfirmedTwoFactor
+		require.Nil(t, user.Save())
+	}()
+
+	client, token := testutil.InitClient(t, user.Email)
+	auth := newSoftAuthenticator(t)
+	options := client.POST("/users/webauthn_register/begin").
+		WithHeader(constants.CSRFHeaderName, token).
+		Expect().Status(http.StatusOK).Body().Raw()
+	client.POST("/users/webauthn_register/finish").
+		WithQuery("name", "Lockout Key").
+		WithHeader(constants.CSRFHeaderName, token).
+		WithBytes(auth.register(t, []byte(options), testutil.BaseURL)).
+		WithHeader("Content-Type", "application/json").
+		Expect().Status(http.StatusOK)
+
+	// Failed assertions count against the account, like wrong
+	// codes. The last one locks the account and signs the user out.
+	client, token = testutil.InitClient(t, user.Email)
+	maxFailures := common.Context().Config.Lockout.MaxFailures
+	for i := 1; i <= maxFailures; i++ {
+		options = client.POST("/users/2fa_webauthn/begin").
+			WithHeader(constants.CSRFHeaderName, token).
+			Expect().Status(http.StatusOK).Body().Raw()
+		expect := client.POST("/users/2fa_webauthn/finish").
+			WithHeader(constants.CSRFHeaderName, token).
+			WithBytes(auth.login(t, []byte(options), "https://example.com")).
+			WithHeader("Content-Type", "application/json").
+			Expect()
+		if i < maxFailures {
+			expect.Status(http.StatusBadRequest)
+		} else {
+			expect.Status(http.StatusTooManyRequests).
+				JSON().Object().Value("Error").String().Contains("locked")
+		}
+	}
+
+	user, err = pgmodels.UserByID(user.ID)
+	require.Nil(t, err)
+	assert.True(t, user.IsLocked())
+	assert.Equal(t, maxFailures, user.FailedLoginAttempts)
+
+	query := pgmodels.NewQuery().
+		Where("user_email", "=", user.Email).
+		Where("action", "=", constants.AuditAccountLocked)
+	event, err := pgmodels.AuditEventGet(query)
+	require.Nil(t, err)
+	require.NotNil(t, event)
+}
+
+// softAuthenticator is a software stand-in for a security key. It
+// creates an ES256 credential and signs login challenges, so we can
+// test registration and login without a browser.
+type softAuthenticator struct {
+	key       *ecdsa.PrivateKey
+	credID    []byte
+	signCount uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+	credID := make([]byte, 16)
+	_, err = rand.Read(credID)
+	require.Nil(t, err)
+	return &softAuthenticator{key: key, credID: credID}
+}
+
+// register returns the JSON the browser would send to finish
+// registration, given the options from the begin step.
+func (a *softAuthenticator) register(t *testing.T, optionsJSON []byte, origin string) []byte {
+	var options protocol.CredentialCreation
+	require.Nil(t, json.Unmarshal(optionsJSON, &options))
+	clientData := a.clientData("webauthn.create", options.Response.Challenge.String(), origin)
+
+	coseKey := cborMap(
+		cborInt(1), cborInt(2), // kty: EC2
+		cborInt(3), cborInt(-7), // alg: ES256
+		cborInt(-1), cborInt(1), // crv: P-256
+		cborInt(-2), cborBytes(pad32(a.key.X.Bytes())),
+		cborInt(-3), cborBytes(pad32(a.key.Y.Bytes())),
+	)
+	var authData bytes.Buffer
+	authData.Write(a.authDataPrefix(options.Response.RelyingParty.ID, 0x41))
+	authData.Write(make([]byte, 16)) // AAGUID
+	binary.Write(&authData, binary.BigEndian, uint16(len(a.credID)))
+	authData.Write(a.credID)
+	authData.Write(coseKey)
+	attestationObject := cborMap(
+		cborString("fmt"), cborString("none"),
+		cborString("attStmt"), cborMap(),
+		cborString("authData"), cborBytes(authData.Bytes()),
+	)
+	return a.response(t, map[string]string{
+		"attestationObject": b64(attestationObject),
+		"clientDataJSON":    b64(clientData),
+	})
+}
+
+// login returns the JSON the browser would send to finish login,
+// given the options from the begin step.
+func (a *softAuthenticator) login(t *testing.T, optionsJSON []byte, origin string) []byte {
+	var options protocol.CredentialAssertion
+	require.Nil(t, json.Unmarshal(optionsJSON, &options))
+	clientData := a.clientData("webauthn.get", options.Response.Challenge.String(), origin)
+	a.signCount++
+	authData := a.authDataPrefix(options.Response.RelyingPartyID, 0x01)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	require.Nil(t, err)
+	return a.response(t, map[string]string{
+		"authenticatorData": b64(authData),
+		"clientDataJSON":    b64(clientData),
+		"signature":         b64(signature),
+	})
+}
+
+func (a *softAuthenticator) clientData(ceremony, challenge, origin string) []byte {
+	data, _ := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": challenge,
+		"origin":    origin,
+	})
+	return data
+}
+
+func (a *softAuthenticator) authDataPrefix(rpID string, flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append(rpIDHash[:], flags)
+	counter := make([]byte, 4)
+	binary.BigEndian.PutUint32(counter, a.signCount)
+	return append(data, counter...)
+}
+
+func (a *softAuthenticator) response(t *testing.T, response map[string]string) []byte {
+	data, err := json.Marshal(map[string]interface{}{
+		"id":       b64(a.credID),
+		"rawId":    b64(a.credID),
+		"type":     "public-key",
+		"response": response,
+	})
+	require.Nil(t, err)
+	return data
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func pad32(b []byte) []byte {
+	return append(make([]byte, 32-len(b)), b...)
+}
+
+// Just enough CBOR to encode attestation objects and COSE keys.
+func cborHead(major byte, n int) []byte {
+	if n < 24 {
+		return []byte{major<<5 | byte(n)}
+	}
+	if n < 256 {
+		return []byte{major<<5 | 24, byte(n)}
+	}
+	return []byte{major<<5 | 25, byte(n >> 8), byte(n)}
+}
+
+func cborInt(n int) []byte {
+	if n < 0 {
+		return cborHead(1, -1-n)
+	}
+	return cborHead(0, n)
+}
+
+func cborBytes(b []byte) []byte {
+	return append(cborHead(2, len(b)), b...)
+}
+
+func cborString(s string) []byte {
+	return append(cborHead(3, len(s)), s...)
+}
+
+func cborMap(items ...[]byte) []byte {
+	data := cborHead(5, len(items)/2)
+	for _, item := range items {
+		data = append(data, item...)
+	}
+	return data
+}
@@ -0,0 +1,165 @@
+package webui
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/APTrust/registry/common"
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/forms"
+	"github.com/APTrust/registry/helpers"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookNew shows a form for registering a new webhook.
+//
+// GET /webhooks/new?institution_id=:institution_id
+func WebhookNew(c *gin.Context) {
+	req := NewRequest(c)
+	webhook := pgmodels.NewWebhook(req.Auth.ResourceInstID)
+	form := forms.NewWebhookForm(webhook)
+	req.TemplateData["form"] = form
+	req.TemplateData["webhook"] = webhook
+	c.HTML(http.StatusOK, form.Template, req.TemplateData)
+}
+
+// WebhookCreate registers a new webhook.
+//
+// POST /webhooks/new
+func WebhookCreate(c *gin.Context) {
+	req := NewRequest(c)
+	webhook := pgmodels.NewWebhook(req.Auth.ResourceInstID)
+	saveWebhookForm(c, req, webhook)
+}
+
+// WebhookShow shows a webhook's settings, its secret, and its
+// most recent deliveries.
+//
+// GET /webhooks/show/:id
+func WebhookShow(c *gin.Context) {
+	req := NewRequest(c)
+	webhook, err := pgmodels.WebhookByID(req.Auth.ResourceID)
+	if AbortIfError(c, err) {
+		return
+	}
+	query := pgmodels.NewQuery().
+		Where("webhook_id", "=", webhook.ID).
+		OrderBy("created_at", "desc").
+		OrderBy("id", "desc").
+		Limit(50)
+	deliveries, err := pgmodels.WebhookDeliverySelect(query)
+	if AbortIfError(c, err) {
+		return
+	}
+	req.TemplateData["webhook"] = webhook
+	req.TemplateData["deliveries"] = deliveries
+	c.HTML(http.StatusOK, "webhooks/show.html", req.TemplateData)
+}
+
+// WebhookEdit shows a form for editing a webhook.
+//
+// GET /webhooks/edit/:id
+func WebhookEdit(c *gin.Context) {
+	req := NewRequest(c)
+	webhook, err := pgmodels.WebhookByID(req.Auth.ResourceID)
+	if AbortIfError(c, err) {
+		return
+	}
+	form := forms.NewWebhookForm(webhook)
+	req.TemplateData["form"] = form
+	req.TemplateData["webhook"] = webhook
+	c.HTML(http.StatusOK, form.Template, req.TemplateData)
+}
+
+// WebhookUpdate saves changes to a webhook.
+//
+// PUT or POST /webhooks/edit/:id
+func WebhookUpdate(c *gin.Context) {
+	req := NewRequest(c)
+	webhook, err := pgmodels.WebhookByID(req.Auth.ResourceID)
+	if AbortIfError(c, err) {
+		return
+	}
+	saveWebhookForm(c, req, webhook)
+}
+
+// WebhookDelete deletes a webhook and its delivery history.
+//
+// DELETE or POST /webhooks/delete/:id
+func WebhookDelete(c *gin.Context) {
+	req := NewRequest(c)
+	webhook, err := pgmodels.WebhookByID(req.Auth.ResourceID)
+	if AbortIfError(c, err) {
+		return
+	}
+	err = webhook.Delete()
+	if AbortIfError(c, err) {
+		return
+	}
+	helpers.SetFlashCookie(c, fmt.Sprintf("Deleted webhook %s", webhook.URL))
+	c.Redirect(http.StatusSeeOther, fmt.Sprintf("/institutions/edit_preferences/%d", webhook.InstitutionID))
+}
+
+// WebhookDeliveryShow shows the payload and outcome of a single
+// webhook delivery.
+//
+// GET /webhook_deliveries/show/:id
+func WebhookDeliveryShow(c *gin.Context) {
+	req := NewRequest(c)
+	delivery, err := pgmodels.WebhookDeliveryByID(req.Auth.ResourceID)
+	if AbortIfError(c, err) {
+		return
+	}
+	req.TemplateData["delivery"] = delivery
+	c.HTML(http.StatusOK, "webhooks/delivery.html", req.TemplateData)
+}
+
+// WebhookDeliveryReplay sends a copy of a delivery's payload to its
+// webhook right away, and records the attempt as a new delivery.
+// If this attempt fails, the new delivery will be retried on the
+// usual schedule.
+//
+// POST /webhook_deliveries/replay/:id
+func WebhookDeliveryReplay(c *gin.Context) {
+	req := NewRequest(c)
+	delivery, err := pgmodels.WebhookDeliveryByID(req.Auth.ResourceID)
+	if AbortIfError(c, err) {
+		return
+	}
+	replay, err := delivery.Replay()
+	if AbortIfError(c, err) {
+		return
+	}
+	err = replay.Deliver(pgmodels.WebhookClient)
+	if AbortIfError(c, err) {
+		return
+	}
+	if replay.Status == constants.StatusSuccess {
+		helpers.SetFlashCookie(c, "Replayed delivery successfully.")
+	} else {
+		helpers.SetFlashCookie(c, fmt.Sprintf("Replay failed: %s. We'll retry it later.", replay.ErrorMessage))
+	}
+	c.Redirect(http.StatusSeeOther, fmt.Sprintf("/webhook_deliveries/show/%d", replay.ID))
+}
+
+// saveWebhookForm copies submitted values into the webhook and saves it.
+// We copy fields explicitly rather than binding the whole model, so users
+// can't change the institution or secret by posting extra form fields.
+func saveWebhookForm(c *gin.Context, req *Request, webhook *pgmodels.Webhook) {
+	webhook.URL = c.PostForm("URL")
+	webhook.EventTypes = c.PostFormArray("EventTypes")
+	webhook.Enabled = c.PostForm("Enabled") == "true"
+	if c.PostForm("RegenerateSecret") == "true" {
+		webhook.Secret = common.RandomToken()
+	}
+	form := forms.NewWebhookForm(webhook)
+	req.TemplateData["form"] = form
+	req.TemplateData["webhook"] = webhook
+	if form.Save() {
+		c.Redirect(form.Status, form.PostSaveURL())
+	} else {
+		req.TemplateData["FormError"] = form.Error
+		c.HTML(form.Status, form.Template, req.TemplateData)
+	}
+}
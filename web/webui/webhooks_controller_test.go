@@ -0,0 +1,149 @@
+package webui_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/APTrust/registry/constants"
+	"github.com/APTrust/registry/db"
+	"github.com/APTrust/registry/pgmodels"
+	"github.com/APTrust/registry/web/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookCRUD(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+	testutil.InitHTTPTests(t)
+
+	// Only admins can register webhooks.
+	testutil.Inst1AdminClient.GET("/webhooks/new").
+		WithQuery("institution_id", 2).
+		Expect().Status(http.StatusOK)
+	testutil.Inst1UserClient.GET("/webhooks/new").
+		WithQuery("institution_id", 2).
+		Expect().Status(http.StatusForbidden)
+	testutil.Inst2AdminClient.GET("/webhooks/new").
+		WithQuery("institution_id", 2).
+		Expect().Status(http.StatusForbidden)
+
+	html := testutil.Inst1AdminClient.POST("/webhooks/new").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1AdminToken).
+		WithFormField("institution_id", 2).
+		WithFormField("URL", "https://example.com/hook").
+		WithFormField("EventTypes", constants.WebhookAlertCreated).
+		WithFormField("EventTypes", constants.WebhookWorkItemCompleted).
+		WithFormField("Enabled", "true").
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "https://example.com/hook")
+
+	webhooks, err := pgmodels.WebhookSelect(pgmodels.NewQuery().Where("institution_id", "=", 2))
+	require.Nil(t, err)
+	require.Equal(t, 1, len(webhooks))
+	webhook := webhooks[0]
+	assert.Equal(t, []string{constants.WebhookAlertCreated, constants.WebhookWorkItemCompleted}, webhook.EventTypes)
+	assert.True(t, webhook.Enabled)
+
+	// Invalid URL re-displays the form.
+	testutil.Inst1AdminClient.POST("/webhooks/new").
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1AdminToken).
+		WithFormField("institution_id", 2).
+		WithFormField("URL", "not a url").
+		WithFormField("EventTypes", constants.WebhookAlertCreated).
+		Expect().Status(http.StatusBadRequest)
+
+	// Webhook appears on the institution preferences page.
+	html = testutil.Inst1AdminClient.GET("/institutions/edit_preferences/2").
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, fmt.Sprintf("/webhooks/show/%d", webhook.ID))
+
+	// Other institutions can't see or change it.
+	for _, client := range testutil.AllClients {
+		if client == testutil.SysAdminClient || client == testutil.Inst1AdminClient {
+			client.GET("/webhooks/show/{id}", webhook.ID).Expect().Status(http.StatusOK)
+		} else {
+			client.GET("/webhooks/show/{id}", webhook.ID).Expect().Status(http.StatusForbidden)
+			client.GET("/webhooks/edit/{id}", webhook.ID).Expect().Status(http.StatusForbidden)
+		}
+	}
+
+	// Update, and make sure we can't move it to another institution.
+	secret := webhook.Secret
+	testutil.Inst1AdminClient.POST("/webhooks/edit/{id}", webhook.ID).
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1AdminToken).
+		WithFormField("InstitutionID", 3).
+		WithFormField("URL", "https://example.com/hook2").
+		WithFormField("EventTypes", constants.WebhookDeletionRequest).
+		WithFormField("RegenerateSecret", "true").
+		Expect().Status(http.StatusOK)
+	webhook, err = pgmodels.WebhookByID(webhook.ID)
+	require.Nil(t, err)
+	assert.Equal(t, int64(2), webhook.InstitutionID)
+	assert.Equal(t, "https://example.com/hook2", webhook.URL)
+	assert.Equal(t, []string{constants.WebhookDeletionRequest}, webhook.EventTypes)
+	assert.False(t, webhook.Enabled)
+	assert.NotEqual(t, secret, webhook.Secret)
+
+	testutil.Inst2AdminClient.POST("/webhooks/delete/{id}", webhook.ID).
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst2AdminToken).
+		Expect().Status(http.StatusForbidden)
+	testutil.Inst1AdminClient.POST("/webhooks/delete/{id}", webhook.ID).
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1AdminToken).
+		Expect().Status(http.StatusOK)
+	_, err = pgmodels.WebhookByID(webhook.ID)
+	assert.True(t, pgmodels.IsNoRowError(err))
+}
+
+func TestWebhookDeliveryShowAndReplay(t *testing.T) {
+	db.ForceFixtureReload()
+	defer db.ForceFixtureReload()
+	testutil.InitHTTPTests(t)
+
+	// Our test server is on localhost, over http.
+	pgmodels.AllowInsecureWebhooks = true
+	defer func() { pgmodels.AllowInsecureWebhooks = false }()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	webhook := pgmodels.NewWebhook(2)
+	webhook.URL = server.URL
+	webhook.EventTypes = []string{constants.WebhookAlertCreated}
+	require.Nil(t, webhook.Save())
+	deliveries, err := pgmodels.QueueWebhookEvent(2, constants.WebhookAlertCreated, "alert/1", map[string]int{"id": 1})
+	require.Nil(t, err)
+	require.Equal(t, 1, len(deliveries))
+	delivery := deliveries[0]
+
+	for _, client := range testutil.AllClients {
+		if client == testutil.SysAdminClient || client == testutil.Inst1AdminClient {
+			html := client.GET("/webhook_deliveries/show/{id}", delivery.ID).Expect().Status(http.StatusOK).Body().Raw()
+			testutil.AssertMatchesAll(t, html, []string{"alert/1", constants.StatusPending, "Replay"})
+		} else {
+			client.GET("/webhook_deliveries/show/{id}", delivery.ID).Expect().Status(http.StatusForbidden)
+		}
+	}
+
+	testutil.Inst1UserClient.POST("/webhook_deliveries/replay/{id}", delivery.ID).
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1UserToken).
+		Expect().Status(http.StatusForbidden)
+	html := testutil.Inst1AdminClient.POST("/webhook_deliveries/replay/{id}", delivery.ID).
+		WithHeader("Referer", testutil.BaseURL).
+		WithFormField(constants.CSRFTokenName, testutil.Inst1AdminToken).
+		Expect().Status(http.StatusOK).Body().Raw()
+	assert.Contains(t, html, "alert/1/replay/")
+	assert.Equal(t, 1, requests)
+}
@@ -15,9 +15,14 @@ import (
 
 // WorkItemIndex shows list of work items.
 // GET /work_items
+// GET /work_items?format=csv|jsonl
 func WorkItemIndex(c *gin.Context) {
 	req := NewRequest(c)
 	var items []*pgmodels.WorkItemView
+	if format := helpers.ExportFormat(c.Request); format != "" {
+		AbortIfError(c, req.ExportResourceList(&items, "updated_at", "desc", format))
+		return
+	}
 	err := req.LoadResourceList(&items, "updated_at", "desc", forms.NewWorkItemFilterForm)
 	if AbortIfError(c, err) {
 		return